@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 
 	"net/http"
@@ -46,6 +47,8 @@ func main() {
 		log.Fatalf("failed to init application. Reason: %s", err)
 	}
 
+	handler.StartScheduler(context.Background())
+
 	s := &http.Server{
 		Addr:           config.Server().ListenAddress(),
 		Handler:        handler.Router(),
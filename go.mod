@@ -24,7 +24,9 @@ require (
 
 require (
 	github.com/ayush6624/go-chatgpt v0.3.0
+	github.com/golang-jwt/jwt/v4 v4.5.2
 	github.com/rs/zerolog v1.31.0
+	golang.org/x/crypto v0.13.0
 )
 
 require (
@@ -62,7 +64,7 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/sirupsen/logrus v1.7.0 // indirect
 	go.opencensus.io v0.22.4 // indirect
-	golang.org/x/net v0.0.0-20210614182718-04defd469f4e // indirect
+	golang.org/x/net v0.10.0 // indirect
 	golang.org/x/sys v0.12.0 // indirect
 	google.golang.org/genproto v0.0.0-20201110150050-8816d57aaa9a // indirect
 	google.golang.org/grpc v1.33.2 // indirect
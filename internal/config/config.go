@@ -15,17 +15,32 @@ type Config struct {
 	server ServerConfig
 	db     DBConfig
 	gpt    GptConfig
+	imp    ImportConfig
+	record RecordConfig
+	user   UserConfig
+	alert  AlertConfig
+	auth   AuthConfig
 }
 
 func NewConfig(
 	serverConfig ServerConfig,
 	dbConfig DBConfig,
 	gptConfig GptConfig,
+	importConfig ImportConfig,
+	recordConfig RecordConfig,
+	userConfig UserConfig,
+	alertConfig AlertConfig,
+	authConfig AuthConfig,
 ) (*Config, error) {
 	config := &Config{
 		server: serverConfig,
 		db:     dbConfig,
 		gpt:    gptConfig,
+		imp:    importConfig,
+		record: recordConfig,
+		user:   userConfig,
+		alert:  alertConfig,
+		auth:   authConfig,
 	}
 
 	errors := validate.Validate(
@@ -37,6 +52,12 @@ func NewConfig(
 		&validators.StringLengthInRange{Name: "Database Name", Field: config.db.host, Min: 1, Max: 0, Message: "Database name is required"},
 		&validators.StringInclusion{Name: "Database SSL Mode", Field: config.db.sslMode, List: []string{"disable", "require", "verify-ca", "verify-full"}, Message: "Database SSL Mode is required"},
 		&validators.StringLengthInRange{Name: "Migration Directory", Field: config.db.host, Min: 1, Max: 0, Message: "Migration Directory path is required"},
+		&validators.StringInclusion{Name: "Alert Notifier", Field: config.alert.Notifier(), List: []string{AlertNotifierLog, AlertNotifierMemory}, Message: "Alert notifier must be log or memory"},
+		&validators.StringInclusion{Name: "Password Reset Notifier", Field: config.auth.PasswordResetNotifier(), List: []string{"", PasswordResetNotifierLog, PasswordResetNotifierMemory}, Message: "Password reset notifier must be empty, log or memory"},
+		&validators.FuncValidator{Name: "Password Reset Notifier", Field: config.auth.PasswordResetNotifier(), Fn: func() bool {
+			return config.auth.PasswordResetNotifier() != PasswordResetNotifierLog || config.auth.DevUserIdHeader()
+		}, Message: "Password reset notifier %s writes reset tokens to the log and is only accepted with dev_user_id_header"},
+		&validators.StringLengthInRange{Name: "Auth Signing Key", Field: config.auth.signingKey, Min: minSigningKeyLength, Max: 0, Message: fmt.Sprintf("Auth signing key must be at least %d characters long", minSigningKeyLength)},
 	)
 
 	if errors.HasAny() {
@@ -58,6 +79,26 @@ func (c Config) Gpt() GptConfig {
 	return c.gpt
 }
 
+func (c Config) Import() ImportConfig {
+	return c.imp
+}
+
+func (c Config) Record() RecordConfig {
+	return c.record
+}
+
+func (c Config) User() UserConfig {
+	return c.user
+}
+
+func (c Config) Alert() AlertConfig {
+	return c.alert
+}
+
+func (c Config) Auth() AuthConfig {
+	return c.auth
+}
+
 func readToml(bytes []byte) (*Config, error) {
 	var mutableConfig struct {
 		Server struct {
@@ -65,6 +106,7 @@ func readToml(bytes []byte) (*Config, error) {
 			WriteTimeoutSeconds int64 `toml:"write_timeout"`
 			ReadTimeoutSeconds  int64 `toml:"read_timeout"`
 			MaxHeaderBytes      int   `toml:"max_header_bytes"`
+			SchedulerInterval   int64 `toml:"scheduler_interval"`
 		}
 		Database struct {
 			Username     string
@@ -74,10 +116,30 @@ func readToml(bytes []byte) (*Config, error) {
 			Name         string
 			SSLMode      string
 			MigrationDir string `toml:"migration_dir"`
+			UniqueIdSalt string `toml:"unique_id_salt"`
 		}
 		Gpt struct {
 			ApiKey string `toml:"api_key"`
 		}
+		Import struct {
+			UncategorizedCategory string `toml:"uncategorized_category"`
+		}
+		Records struct {
+			DuplicateWindowDays int64 `toml:"duplicate_window_days"`
+		}
+		Users struct {
+			DeletionGracePeriodDays int64 `toml:"deletion_grace_period_days"`
+		}
+		Alerts struct {
+			Notifier string
+		}
+		Auth struct {
+			SigningKey            string `toml:"signing_key"`
+			AccessTokenTtlMinutes int64  `toml:"access_token_ttl_minutes"`
+			RefreshTokenTtlDays   int64  `toml:"refresh_token_ttl_days"`
+			DevUserIdHeader       bool   `toml:"dev_user_id_header"`
+			PasswordResetNotifier string `toml:"password_reset_notifier"`
+		}
 	}
 
 	err := toml.Unmarshal(bytes, &mutableConfig)
@@ -87,10 +149,11 @@ func readToml(bytes []byte) (*Config, error) {
 
 	return NewConfig(
 		ServerConfig{
-			port:           mutableConfig.Server.Port,
-			readTimeout:    time.Duration(mutableConfig.Server.ReadTimeoutSeconds) * time.Second,
-			writeTimeout:   time.Duration(mutableConfig.Server.WriteTimeoutSeconds) * time.Second,
-			maxHeaderBytes: mutableConfig.Server.MaxHeaderBytes,
+			port:              mutableConfig.Server.Port,
+			readTimeout:       time.Duration(mutableConfig.Server.ReadTimeoutSeconds) * time.Second,
+			writeTimeout:      time.Duration(mutableConfig.Server.WriteTimeoutSeconds) * time.Second,
+			maxHeaderBytes:    mutableConfig.Server.MaxHeaderBytes,
+			schedulerInterval: time.Duration(mutableConfig.Server.SchedulerInterval) * time.Second,
 		},
 		DBConfig{
 			username:     mutableConfig.Database.Username,
@@ -100,10 +163,30 @@ func readToml(bytes []byte) (*Config, error) {
 			name:         mutableConfig.Database.Name,
 			sslMode:      mutableConfig.Database.SSLMode,
 			migrationDir: mutableConfig.Database.MigrationDir,
+			uniqueIdSalt: mutableConfig.Database.UniqueIdSalt,
 		},
 		GptConfig{
 			apiKey: mutableConfig.Gpt.ApiKey,
 		},
+		ImportConfig{
+			uncategorizedCategory: mutableConfig.Import.UncategorizedCategory,
+		},
+		RecordConfig{
+			duplicateWindow: time.Duration(mutableConfig.Records.DuplicateWindowDays) * 24 * time.Hour,
+		},
+		UserConfig{
+			deletionGracePeriod: time.Duration(mutableConfig.Users.DeletionGracePeriodDays) * 24 * time.Hour,
+		},
+		AlertConfig{
+			notifier: mutableConfig.Alerts.Notifier,
+		},
+		AuthConfig{
+			signingKey:            mutableConfig.Auth.SigningKey,
+			accessTokenTtl:        time.Duration(mutableConfig.Auth.AccessTokenTtlMinutes) * time.Minute,
+			refreshTokenTtl:       time.Duration(mutableConfig.Auth.RefreshTokenTtlDays) * 24 * time.Hour,
+			devUserIdHeader:       mutableConfig.Auth.DevUserIdHeader,
+			passwordResetNotifier: mutableConfig.Auth.PasswordResetNotifier,
+		},
 	)
 }
 
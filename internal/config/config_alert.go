@@ -0,0 +1,51 @@
+package config
+
+const (
+	// AlertNotifierLog writes budget alerts to the application log
+	AlertNotifierLog = "log"
+	// AlertNotifierMemory keeps budget alerts in memory, so that they can be inspected in tests
+	AlertNotifierMemory = "memory"
+)
+
+// AlertConfig represents the configuration for budget alerts.
+type AlertConfig struct {
+	notifier string
+}
+
+// NewAlertConfig creates a new AlertConfig with the provided notifier.
+func NewAlertConfig(notifier string) *AlertConfig {
+	return &AlertConfig{
+		notifier: notifier,
+	}
+}
+
+// Notifier is the name of the notifier that delivers budget alerts; "log" by default.
+func (a AlertConfig) Notifier() string {
+	if len(a.notifier) == 0 {
+		return AlertNotifierLog
+	}
+	return a.notifier
+}
+
+// AlertConfigBuilder is a builder for AlertConfig.
+type AlertConfigBuilder struct {
+	notifier string
+}
+
+// NewAlertConfigBuilder creates a new AlertConfigBuilder.
+func NewAlertConfigBuilder() *AlertConfigBuilder {
+	return &AlertConfigBuilder{}
+}
+
+// SetNotifier sets the notifier for the AlertConfigBuilder.
+func (b *AlertConfigBuilder) SetNotifier(notifier string) *AlertConfigBuilder {
+	b.notifier = notifier
+	return b
+}
+
+// Build creates a new AlertConfig using the current configuration of AlertConfigBuilder.
+func (b *AlertConfigBuilder) Build() *AlertConfig {
+	return &AlertConfig{
+		notifier: b.notifier,
+	}
+}
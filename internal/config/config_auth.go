@@ -0,0 +1,118 @@
+package config
+
+import "time"
+
+// minSigningKeyLength is the shortest HMAC-SHA256 key accepted; shorter keys are weaker than the hash itself.
+const minSigningKeyLength = 32
+
+const (
+	// PasswordResetNotifierLog writes password reset tokens to the application log.
+	// Anyone who can read the log can reset any password, so it is only accepted with DevUserIdHeader.
+	PasswordResetNotifierLog = "log"
+	// PasswordResetNotifierMemory keeps password reset tokens in memory, so that they can be inspected in tests
+	PasswordResetNotifierMemory = "memory"
+)
+
+// AuthConfig represents the configuration for authenticating users.
+type AuthConfig struct {
+	signingKey            string
+	accessTokenTtl        time.Duration
+	refreshTokenTtl       time.Duration
+	devUserIdHeader       bool
+	passwordResetNotifier string
+}
+
+// NewAuthConfig creates a new AuthConfig with the provided signingKey.
+func NewAuthConfig(signingKey string) *AuthConfig {
+	return &AuthConfig{
+		signingKey: signingKey,
+	}
+}
+
+// SigningKey is the HMAC-SHA256 key with which access and refresh tokens are signed.
+func (a AuthConfig) SigningKey() []byte {
+	return []byte(a.signingKey)
+}
+
+// AccessTokenTtl is how long an access token can be used before it must be refreshed; 15 minutes by default.
+func (a AuthConfig) AccessTokenTtl() time.Duration {
+	if a.accessTokenTtl <= 0 {
+		return 15 * time.Minute
+	}
+	return a.accessTokenTtl
+}
+
+// RefreshTokenTtl is how long a refresh token can be used to get new access tokens; 30 days by default.
+func (a AuthConfig) RefreshTokenTtl() time.Duration {
+	if a.refreshTokenTtl <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return a.refreshTokenTtl
+}
+
+// DevUserIdHeader is true when the Authorization header can also be the numeric id of a user, as it was before access tokens.
+// Anyone can impersonate anyone in this mode, so it must only be enabled in development and tests.
+func (a AuthConfig) DevUserIdHeader() bool {
+	return a.devUserIdHeader
+}
+
+// PasswordResetNotifier is the name of the notifier that delivers password reset tokens.
+// There is no default: password resets are disabled when it is empty.
+func (a AuthConfig) PasswordResetNotifier() string {
+	return a.passwordResetNotifier
+}
+
+// AuthConfigBuilder is a builder for AuthConfig.
+type AuthConfigBuilder struct {
+	signingKey            string
+	accessTokenTtl        time.Duration
+	refreshTokenTtl       time.Duration
+	devUserIdHeader       bool
+	passwordResetNotifier string
+}
+
+// NewAuthConfigBuilder creates a new AuthConfigBuilder.
+func NewAuthConfigBuilder() *AuthConfigBuilder {
+	return &AuthConfigBuilder{}
+}
+
+// SetSigningKey sets the signingKey for the AuthConfigBuilder.
+func (b *AuthConfigBuilder) SetSigningKey(signingKey string) *AuthConfigBuilder {
+	b.signingKey = signingKey
+	return b
+}
+
+// SetAccessTokenTtl sets the accessTokenTtl for the AuthConfigBuilder.
+func (b *AuthConfigBuilder) SetAccessTokenTtl(accessTokenTtl time.Duration) *AuthConfigBuilder {
+	b.accessTokenTtl = accessTokenTtl
+	return b
+}
+
+// SetRefreshTokenTtl sets the refreshTokenTtl for the AuthConfigBuilder.
+func (b *AuthConfigBuilder) SetRefreshTokenTtl(refreshTokenTtl time.Duration) *AuthConfigBuilder {
+	b.refreshTokenTtl = refreshTokenTtl
+	return b
+}
+
+// SetDevUserIdHeader sets the devUserIdHeader for the AuthConfigBuilder.
+func (b *AuthConfigBuilder) SetDevUserIdHeader(devUserIdHeader bool) *AuthConfigBuilder {
+	b.devUserIdHeader = devUserIdHeader
+	return b
+}
+
+// SetPasswordResetNotifier sets the passwordResetNotifier for the AuthConfigBuilder.
+func (b *AuthConfigBuilder) SetPasswordResetNotifier(passwordResetNotifier string) *AuthConfigBuilder {
+	b.passwordResetNotifier = passwordResetNotifier
+	return b
+}
+
+// Build creates a new AuthConfig using the current configuration of AuthConfigBuilder.
+func (b *AuthConfigBuilder) Build() *AuthConfig {
+	return &AuthConfig{
+		signingKey:            b.signingKey,
+		accessTokenTtl:        b.accessTokenTtl,
+		refreshTokenTtl:       b.refreshTokenTtl,
+		devUserIdHeader:       b.devUserIdHeader,
+		passwordResetNotifier: b.passwordResetNotifier,
+	}
+}
@@ -12,6 +12,7 @@ type DBConfig struct {
 	name         string
 	sslMode      string
 	migrationDir string
+	uniqueIdSalt string
 }
 
 func (d DBConfig) Username() string {
@@ -53,6 +54,14 @@ func (d DBConfig) MigrationDirectory() string {
 	return d.migrationDir
 }
 
+// UniqueIdSalt is mixed into the ids created by the timestamp_id function of the database
+func (d DBConfig) UniqueIdSalt() string {
+	if len(d.uniqueIdSalt) == 0 {
+		return "simple-budget-tracker"
+	}
+	return d.uniqueIdSalt
+}
+
 func (d DBConfig) ConnectionString() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		d.Host(),
@@ -72,6 +81,7 @@ type dbConfigBuilder struct {
 	name         string
 	sslMode      string
 	migrationDir string
+	uniqueIdSalt string
 }
 
 func NewDBConfigBuilder() *dbConfigBuilder {
@@ -116,6 +126,11 @@ func (b *dbConfigBuilder) SetMigrationDirectory(migrationDir string) *dbConfigBu
 	return b
 }
 
+func (b *dbConfigBuilder) SetUniqueIdSalt(uniqueIdSalt string) *dbConfigBuilder {
+	b.uniqueIdSalt = uniqueIdSalt
+	return b
+}
+
 func (b *dbConfigBuilder) Build() DBConfig {
 	return DBConfig{
 		b.username,
@@ -125,5 +140,6 @@ func (b *dbConfigBuilder) Build() DBConfig {
 		b.name,
 		b.sslMode,
 		b.migrationDir,
+		b.uniqueIdSalt,
 	}
 }
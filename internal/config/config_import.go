@@ -0,0 +1,46 @@
+package config
+
+import "strings"
+
+// ImportConfig represents the configuration for importing bank statements.
+type ImportConfig struct {
+	uncategorizedCategory string
+}
+
+// NewImportConfig creates a new ImportConfig with the provided uncategorizedCategory.
+func NewImportConfig(uncategorizedCategory string) *ImportConfig {
+	return &ImportConfig{
+		uncategorizedCategory: uncategorizedCategory,
+	}
+}
+
+// UncategorizedCategory is the name of the category of imported transactions whose category is not found
+func (i ImportConfig) UncategorizedCategory() string {
+	if len(strings.TrimSpace(i.uncategorizedCategory)) == 0 {
+		return "Uncategorized"
+	}
+	return strings.TrimSpace(i.uncategorizedCategory)
+}
+
+// ImportConfigBuilder is a builder for ImportConfig.
+type ImportConfigBuilder struct {
+	uncategorizedCategory string
+}
+
+// NewImportConfigBuilder creates a new ImportConfigBuilder.
+func NewImportConfigBuilder() *ImportConfigBuilder {
+	return &ImportConfigBuilder{}
+}
+
+// SetUncategorizedCategory sets the uncategorizedCategory for the ImportConfigBuilder.
+func (b *ImportConfigBuilder) SetUncategorizedCategory(uncategorizedCategory string) *ImportConfigBuilder {
+	b.uncategorizedCategory = uncategorizedCategory
+	return b
+}
+
+// Build creates a new ImportConfig using the current configuration of ImportConfigBuilder.
+func (b *ImportConfigBuilder) Build() *ImportConfig {
+	return &ImportConfig{
+		uncategorizedCategory: b.uncategorizedCategory,
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import "time"
+
+// RecordConfig represents the configuration for records.
+type RecordConfig struct {
+	duplicateWindow time.Duration
+}
+
+// NewRecordConfig creates a new RecordConfig with the provided duplicateWindow.
+func NewRecordConfig(duplicateWindow time.Duration) *RecordConfig {
+	return &RecordConfig{
+		duplicateWindow: duplicateWindow,
+	}
+}
+
+// DuplicateWindow is how far apart the dates of two records can be for one to be considered a duplicate of the other
+func (r RecordConfig) DuplicateWindow() time.Duration {
+	if r.duplicateWindow <= 0 {
+		return 3 * 24 * time.Hour
+	}
+	return r.duplicateWindow
+}
+
+// RecordConfigBuilder is a builder for RecordConfig.
+type RecordConfigBuilder struct {
+	duplicateWindow time.Duration
+}
+
+// NewRecordConfigBuilder creates a new RecordConfigBuilder.
+func NewRecordConfigBuilder() *RecordConfigBuilder {
+	return &RecordConfigBuilder{}
+}
+
+// SetDuplicateWindow sets the duplicateWindow for the RecordConfigBuilder.
+func (b *RecordConfigBuilder) SetDuplicateWindow(duplicateWindow time.Duration) *RecordConfigBuilder {
+	b.duplicateWindow = duplicateWindow
+	return b
+}
+
+// Build creates a new RecordConfig using the current configuration of RecordConfigBuilder.
+func (b *RecordConfigBuilder) Build() *RecordConfig {
+	return &RecordConfig{
+		duplicateWindow: b.duplicateWindow,
+	}
+}
@@ -6,10 +6,11 @@ import (
 )
 
 type ServerConfig struct {
-	port           int
-	readTimeout    time.Duration
-	writeTimeout   time.Duration
-	maxHeaderBytes int
+	port              int
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	maxHeaderBytes    int
+	schedulerInterval time.Duration
 }
 
 func (s ServerConfig) Port() int {
@@ -37,15 +38,24 @@ func (s ServerConfig) WriteTimeout() time.Duration {
 	return s.writeTimeout
 }
 
+// SchedulerInterval is how often the scheduler checks for recurring records that are due
+func (s ServerConfig) SchedulerInterval() time.Duration {
+	if s.schedulerInterval <= 0 {
+		return time.Hour
+	}
+	return s.schedulerInterval
+}
+
 func (s ServerConfig) ListenAddress() string {
 	return fmt.Sprintf(":%d", s.port)
 }
 
 type serverConfigBuilder struct {
-	port           int
-	readTimeout    time.Duration
-	writeTimeout   time.Duration
-	maxHeaderBytes int
+	port              int
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	maxHeaderBytes    int
+	schedulerInterval time.Duration
 }
 
 func NewServerConfigBuilder() *serverConfigBuilder {
@@ -76,11 +86,17 @@ func (b *serverConfigBuilder) SetMaxHeaderBytes(maxHeaderBytes int) *serverConfi
 	return b
 }
 
+func (b *serverConfigBuilder) SetSchedulerInterval(interval time.Duration) *serverConfigBuilder {
+	b.schedulerInterval = interval
+	return b
+}
+
 func (b *serverConfigBuilder) Build() ServerConfig {
 	return ServerConfig{
 		b.port,
 		b.readTimeout,
 		b.writeTimeout,
 		b.maxHeaderBytes,
+		b.schedulerInterval,
 	}
 }
@@ -0,0 +1,47 @@
+package config
+
+import "time"
+
+// UserConfig represents the configuration for users.
+type UserConfig struct {
+	deletionGracePeriod time.Duration
+}
+
+// NewUserConfig creates a new UserConfig with the provided deletionGracePeriod.
+func NewUserConfig(deletionGracePeriod time.Duration) *UserConfig {
+	return &UserConfig{
+		deletionGracePeriod: deletionGracePeriod,
+	}
+}
+
+// DeletionGracePeriod is how long after a user confirms the deletion of their account that the user and all their data are deleted.
+// The deletion can be cancelled until then.
+func (u UserConfig) DeletionGracePeriod() time.Duration {
+	if u.deletionGracePeriod <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return u.deletionGracePeriod
+}
+
+// UserConfigBuilder is a builder for UserConfig.
+type UserConfigBuilder struct {
+	deletionGracePeriod time.Duration
+}
+
+// NewUserConfigBuilder creates a new UserConfigBuilder.
+func NewUserConfigBuilder() *UserConfigBuilder {
+	return &UserConfigBuilder{}
+}
+
+// SetDeletionGracePeriod sets the deletionGracePeriod for the UserConfigBuilder.
+func (b *UserConfigBuilder) SetDeletionGracePeriod(deletionGracePeriod time.Duration) *UserConfigBuilder {
+	b.deletionGracePeriod = deletionGracePeriod
+	return b
+}
+
+// Build creates a new UserConfig using the current configuration of UserConfigBuilder.
+func (b *UserConfigBuilder) Build() *UserConfig {
+	return &UserConfig{
+		deletionGracePeriod: b.deletionGracePeriod,
+	}
+}
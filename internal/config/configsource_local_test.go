@@ -35,6 +35,9 @@ name     = "overlook"
 host     = "localhost"
 port     = 5432
 sslmode  = "disable"
+
+[auth]
+signing_key = "the-overlook-hotel-room-237-signing-key"
 `
 
 func createTestConfigFile(content string, uri string) error {
@@ -79,6 +82,14 @@ func (suite *ConfigTestSuite) Test_GIVEN_configFilePathIsNotProvided_WHEN_loadin
 	assert.Equal(suite.T(), 1048576, config.Server().MaxHeaderBytes())
 	assert.Equal(suite.T(), time.Duration(10)*time.Second, config.Server().ReadTimeout())
 	assert.Equal(suite.T(), time.Duration(10)*time.Second, config.Server().WriteTimeout())
+	assert.Equal(suite.T(), time.Hour, config.Server().SchedulerInterval())
+	assert.Equal(suite.T(), "Uncategorized", config.Import().UncategorizedCategory())
+	assert.Equal(suite.T(), 72*time.Hour, config.Record().DuplicateWindow())
+	assert.Equal(suite.T(), 30*24*time.Hour, config.User().DeletionGracePeriod())
+	assert.Equal(suite.T(), []byte("the-overlook-hotel-room-237-signing-key"), config.Auth().SigningKey())
+	assert.Equal(suite.T(), 15*time.Minute, config.Auth().AccessTokenTtl())
+	assert.Equal(suite.T(), 30*24*time.Hour, config.Auth().RefreshTokenTtl())
+	assert.False(suite.T(), config.Auth().DevUserIdHeader())
 	assert.Equal(suite.T(), "postgres", config.Database().DriverName())
 	assert.Equal(suite.T(), "jack.torrence", config.Database().Username())
 	assert.Equal(suite.T(), "password", config.Database().Password())
@@ -124,6 +135,7 @@ port = 8085
 read_timeout = 5
 write_timeout = 3
 max_header_bytes = 2097152
+scheduler_interval = 300
 
 [database]
 username = "danny.torrence"
@@ -132,6 +144,23 @@ name     = "tony"
 host     = "localhost"
 port     = 5432
 sslmode  = "disable"
+unique_id_salt = "redrum"
+
+[import]
+uncategorized_category = "Other"
+
+[records]
+duplicate_window_days = 5
+
+[users]
+deletion_grace_period_days = 7
+
+[auth]
+signing_key = "all-work-and-no-play-makes-jack-a-dull-boy"
+access_token_ttl_minutes = 5
+refresh_token_ttl_days = 1
+dev_user_id_header = true
+password_reset_notifier = "memory"
 `
 	assert.Nil(suite.T(), createTestConfigFile(customConfigFileContents, testConfigFilePath()))
 
@@ -146,6 +175,7 @@ sslmode  = "disable"
 	assert.Equal(suite.T(), 2097152, config.Server().MaxHeaderBytes())
 	assert.Equal(suite.T(), time.Duration(5)*time.Second, config.Server().ReadTimeout())
 	assert.Equal(suite.T(), time.Duration(3)*time.Second, config.Server().WriteTimeout())
+	assert.Equal(suite.T(), time.Duration(5)*time.Minute, config.Server().SchedulerInterval())
 	assert.Equal(suite.T(), "postgres", config.Database().DriverName())
 	assert.Equal(suite.T(), "danny.torrence", config.Database().Username())
 	assert.Equal(suite.T(), "password", config.Database().Password())
@@ -153,6 +183,15 @@ sslmode  = "disable"
 	assert.Equal(suite.T(), 5432, config.Database().Port())
 	assert.Equal(suite.T(), "disable", config.Database().SslMode())
 	assert.Equal(suite.T(), "host=localhost port=5432 user=danny.torrence password=password dbname=tony sslmode=disable", config.Database().ConnectionString())
+	assert.Equal(suite.T(), "redrum", config.Database().UniqueIdSalt())
+	assert.Equal(suite.T(), "Other", config.Import().UncategorizedCategory())
+	assert.Equal(suite.T(), 5*24*time.Hour, config.Record().DuplicateWindow())
+	assert.Equal(suite.T(), 7*24*time.Hour, config.User().DeletionGracePeriod())
+	assert.Equal(suite.T(), []byte("all-work-and-no-play-makes-jack-a-dull-boy"), config.Auth().SigningKey())
+	assert.Equal(suite.T(), 5*time.Minute, config.Auth().AccessTokenTtl())
+	assert.Equal(suite.T(), 24*time.Hour, config.Auth().RefreshTokenTtl())
+	assert.True(suite.T(), config.Auth().DevUserIdHeader())
+	assert.Equal(suite.T(), "memory", config.Auth().PasswordResetNotifier())
 
 }
 
@@ -174,6 +213,7 @@ func (suite *ConfigTestSuite) Test_GIVEN_configFilePathIsProvided_WHEN_configFil
 	assert.Contains(suite.T(), err.Error(), "Database password is required")
 	assert.Contains(suite.T(), err.Error(), "Database port is required")
 	assert.Contains(suite.T(), err.Error(), "Database name is required")
+	assert.Contains(suite.T(), err.Error(), "Auth signing key must be at least 32 characters long")
 }
 
 func (suite *ConfigTestSuite) Test_GIVEN_configFilePathIsProvided_WHEN_configFileDoesNotContainValidToml_THEN_errorIsReturned() {
@@ -206,3 +246,34 @@ func (suite *ConfigTestSuite) Test_GIVEN_configFilePathIsNotPrefixedWithFileOrS3
 	assert.Nil(suite.T(), config)
 	assert.Equal(suite.T(), "Config file must start with file:// or s3://", err.Error())
 }
+
+func (suite *ConfigTestSuite) Test_GIVEN_anUnknownPasswordResetNotifierOrLogWithoutDevUserIdHeader_WHEN_configIsLoaded_THEN_errorIsReturned() {
+	for notifier, expected := range map[string]string{
+		"smtp": "Password reset notifier must be empty, log or memory",
+		"log":  "Password reset notifier log writes reset tokens to the log and is only accepted with dev_user_id_header",
+	} {
+		// GIVEN
+		assert.Nil(suite.T(), createTestConfigFile(configFileContents+fmt.Sprintf("password_reset_notifier = %q\n", notifier), testConfigFilePath()))
+
+		// WHEN
+		config, err := LoadConfig(testConfigFilePath(), "", "", "")
+
+		// THEN
+		assert.Nil(suite.T(), config, notifier)
+		if assert.NotNil(suite.T(), err, notifier) {
+			assert.Contains(suite.T(), err.Error(), expected, notifier)
+		}
+	}
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_noPasswordResetNotifier_WHEN_configIsLoaded_THEN_passwordResetsAreDisabled() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents, testConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig(testConfigFilePath(), "", "", "")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "", config.Auth().PasswordResetNotifier())
+}
@@ -0,0 +1,149 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+type DefaultBudgetAlertDao struct {
+	RootDao
+}
+
+func MustOpenBudgetAlertDao(db *sql.DB) dao.BudgetAlertDao {
+	return &DefaultBudgetAlertDao{RootDao{db}}
+}
+
+func (d *DefaultBudgetAlertDao) NewBudgetAlertId(tx *sql.Tx) (ledger.BudgetAlertId, error) {
+	var id ledger.BudgetAlertId
+	err := tx.QueryRow("SELECT nextval('budget.budget_alert_id')").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to assign budget alert id. Reason: %w", err)
+	}
+	return id, err
+}
+
+// SaveTx saves an alert unless an alert was already raised for the same threshold of the category budget in the same period.
+// false is returned if the alert had already been raised.
+func (d *DefaultBudgetAlertDao) SaveTx(ctx context.Context, a ledger.BudgetAlert, tx *sql.Tx) (bool, error) {
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO budget.budget_alert (
+			id,
+			user_id,
+			budget_id,
+			category_id,
+			period,
+			period_start,
+			period_end,
+			threshold,
+			currency,
+			spent_minor_units,
+			max_limit_minor_units,
+			created_at
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8,
+			$9,
+			$10,
+			$11,
+			$12
+		) ON CONFLICT ON CONSTRAINT uq_budget_alert_threshold_per_period DO NOTHING`,
+		a.Id(),
+		a.UserId(),
+		a.BudgetId(),
+		a.CategoryId(),
+		a.Period().PeriodType(),
+		a.Period().FirstDay().Format("2006-01-02"),
+		a.Period().LastDay().Format("2006-01-02"),
+		a.Threshold(),
+		a.MaxLimit().Currency().CurrencyCode(),
+		a.Spent().MustMinorUnits(),
+		a.MaxLimit().MustMinorUnits(),
+		a.CreatedAtUTC(),
+	)
+	if err != nil {
+		log.Printf("Failed to save budget alert %s. Reason: %s", a, err)
+		return false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save budget alert", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save budget alert", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// GetBudgetAlertsForUser returns the alerts raised for the budgets of a user, newest first.
+func (d *DefaultBudgetAlertDao) GetBudgetAlertsForUser(ctx context.Context, userId ledger.UserId, tx *sql.Tx) ([]ledger.BudgetAlert, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT 
+			a.id,
+			a.user_id,
+			a.budget_id,
+			a.category_id,
+			a.period,
+			a.period_start,
+			a.threshold,
+			a.currency,
+			a.spent_minor_units,
+			a.max_limit_minor_units,
+			a.created_at
+		FROM 
+			budget.budget_alert a
+		WHERE 
+			a.user_id = $1
+		ORDER BY 
+			a.created_at DESC,
+			a.id DESC`,
+		userId,
+	)
+	if err != nil {
+		log.Printf("Failed to load budget alerts for user %d. Reason: %s", userId, err)
+		return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load budget alerts", err)
+	}
+	defer rows.Close()
+
+	alerts := []ledger.BudgetAlert{}
+	for rows.Next() {
+		var (
+			ar    budgetAlertRecord
+			alert ledger.BudgetAlert
+		)
+		if err = rows.Scan(
+			&ar.id,
+			&ar.userId,
+			&ar.budgetId,
+			&ar.categoryId,
+			&ar.periodType,
+			&ar.periodStart,
+			&ar.threshold,
+			&ar.currency,
+			&ar.spentMinorUnits,
+			&ar.maxLimitMinorUnits,
+			&ar.createdAt,
+		); err != nil {
+			return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load budget alerts", err)
+		}
+		if alert, err = ledger.NewBudgetAlertFromRecord(ar); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load budget alerts", err)
+	}
+	return alerts, nil
+}
@@ -0,0 +1,66 @@
+package persistence
+
+import (
+	"log"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+type budgetAlertRecord struct {
+	id                 ledger.BudgetAlertId
+	userId             ledger.UserId
+	budgetId           ledger.BudgetId
+	categoryId         ledger.CategoryId
+	periodType         ledger.BudgetPeriodType
+	periodStart        time.Time
+	threshold          uint
+	currency           string
+	spentMinorUnits    int64
+	maxLimitMinorUnits int64
+	createdAt          time.Time
+}
+
+func (ar budgetAlertRecord) Id() ledger.BudgetAlertId {
+	return ar.id
+}
+
+func (ar budgetAlertRecord) UserId() ledger.UserId {
+	return ar.userId
+}
+
+func (ar budgetAlertRecord) BudgetId() ledger.BudgetId {
+	return ar.budgetId
+}
+
+func (ar budgetAlertRecord) CategoryId() ledger.CategoryId {
+	return ar.categoryId
+}
+
+func (ar budgetAlertRecord) Period() ledger.BudgetPeriod {
+	return ledger.BudgetPeriodStartingOn(ar.periodType, ar.periodStart)
+}
+
+func (ar budgetAlertRecord) Threshold() uint {
+	return ar.threshold
+}
+
+func (ar budgetAlertRecord) Spent() ledger.Money {
+	return ar.money(ar.spentMinorUnits)
+}
+
+func (ar budgetAlertRecord) MaxLimit() ledger.Money {
+	return ar.money(ar.maxLimitMinorUnits)
+}
+
+func (ar budgetAlertRecord) CreatedAtUTC() time.Time {
+	return ar.createdAt
+}
+
+func (ar budgetAlertRecord) money(minorUnits int64) ledger.Money {
+	money, err := ledger.NewMoney(ar.currency, minorUnits)
+	if err != nil {
+		log.Fatalf("Invalid amount persisted for budget alert %d: %s %d", ar.id, ar.currency, minorUnits)
+	}
+	return money
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/lib/pq"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
 	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
 	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
 )
@@ -33,6 +34,8 @@ func (d *DefaultBudgetDao) Save(
 			id, 
 			user_id,
 			period, 
+			period_anchor,
+			period_start_day,
 			created_by, 
 			created_at, 
 			last_modified_by, 
@@ -46,11 +49,15 @@ func (d *DefaultBudgetDao) Save(
 			$5,
 			$6, 
 			$7,
-			$8
+			$8,
+			$9,
+			$10
 		)`,
 		b.Id(),
 		userId,
 		b.PeriodType(),
+		nullPeriodAnchor(b.PeriodCalculator()),
+		nullPeriodStartDay(b.PeriodCalculator()),
 		b.CreatedBy().String(),
 		b.CreatedAtUTC(),
 		sql.NullString{
@@ -64,10 +71,82 @@ func (d *DefaultBudgetDao) Save(
 		b.Version(),
 	)
 	if err != nil {
-		return fmt.Errorf("Faiiled to save budget. Reason: %w", err)
+		return fmt.Errorf("Failed to save budget. Reason: %w", err)
 	}
 
-	log.Printf("insrrted budget")
+	return d.saveBudgetLinesTx(ctx, userId, b, tx)
+}
+
+func (d *DefaultBudgetDao) UpdateTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	b ledger.Budget,
+	tx *sql.Tx,
+) error {
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE budget.budget SET
+			period = $1,
+			period_anchor = $2,
+			period_start_day = $3,
+			last_modified_by = $4
+		WHERE
+			id = $5
+			AND user_id = $6
+			AND version = $7`,
+		b.PeriodType(),
+		nullPeriodAnchor(b.PeriodCalculator()),
+		nullPeriodStartDay(b.PeriodCalculator()),
+		b.ModifiedBy().String(),
+		b.Id(),
+		userId,
+		b.Version(),
+	)
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update budget", err)
+	}
+	if err = d.checkVersionedChange(result, b.Id()); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM budget.budget_per_category WHERE budget_id = $1`, b.Id()); err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update category budgets", err)
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM budget.account_budgets WHERE budget_id = $1`, b.Id()); err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update accounts of budget", err)
+	}
+
+	return d.saveBudgetLinesTx(ctx, userId, b, tx)
+}
+
+// DeleteTx deletes a budget. Its category budgets and accounts are deleted in cascade.
+func (d *DefaultBudgetDao) DeleteTx(
+	ctx context.Context,
+	id ledger.BudgetId,
+	userId ledger.UserId,
+	version ledger.Version,
+	tx *sql.Tx,
+) error {
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM budget.budget WHERE id = $1 AND user_id = $2 AND version = $3`,
+		id,
+		userId,
+		version,
+	)
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to delete budget", err)
+	}
+	return d.checkVersionedChange(result, id)
+}
+
+// saveBudgetLinesTx saves the category budgets and the accounts of a budget.
+func (d *DefaultBudgetDao) saveBudgetLinesTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	b ledger.Budget,
+	tx *sql.Tx,
+) error {
 	stmt, err := tx.PrepareContext(
 		ctx,
 		pq.CopyInSchema(
@@ -77,28 +156,39 @@ func (d *DefaultBudgetDao) Save(
 			"category_id",
 			"currency",
 			"amount_minor_units",
+			"alert_thresholds",
+			"rollover",
 		))
 	if err != nil {
 		return fmt.Errorf("Failed to prepare bulk statement for budget per category. Reason: %w", err)
 	}
-	defer stmt.Close()
-	log.Printf("created cb stmts")
 
 	for _, cb := range b.CategoryBudgets() {
+		alertThresholds := make([]int64, 0, len(cb.AlertThresholds()))
+		for _, threshold := range cb.AlertThresholds() {
+			alertThresholds = append(alertThresholds, int64(threshold))
+		}
 		_, err = stmt.ExecContext(
 			ctx,
 			b.Id(),
 			cb.CategoryId(),
 			cb.MaxLimit().Currency().CurrencyCode(),
 			cb.MaxLimit().MustMinorUnits(),
+			pq.Array(alertThresholds),
+			cb.Rollover(),
 		)
 		if err != nil {
+			stmt.Close()
 			return fmt.Errorf("Failed to save category budget %q for user id %d. Reason: %w", cb, userId, err)
 		}
 	}
-	if _, err := stmt.ExecContext(ctx); err != nil {
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
 		return fmt.Errorf("Failed to flush category budgets for user id %d. Reason: %w", userId, err)
 	}
+	if err = stmt.Close(); err != nil {
+		return fmt.Errorf("Failed to save category budgets for user id %d. Reason: %w", userId, err)
+	}
 
 	stmt, err = tx.PrepareContext(
 		ctx,
@@ -112,7 +202,6 @@ func (d *DefaultBudgetDao) Save(
 	if err != nil {
 		return fmt.Errorf("Failed to prepare bulk statement for account budget: %w", err)
 	}
-	log.Printf("created ab stmts")
 
 	for _, accountId := range b.AccountIds() {
 		_, err = stmt.ExecContext(
@@ -121,90 +210,395 @@ func (d *DefaultBudgetDao) Save(
 			b.Id(),
 		)
 		if err != nil {
+			stmt.Close()
 			return fmt.Errorf("Failed to save budget %q for account id %d. Reason: %w", b, accountId, err)
 		}
 	}
-	_, err = stmt.ExecContext(ctx)
-	log.Printf("exec ab stmts")
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return fmt.Errorf("Failed to flush accounts of budget %d. Reason: %w", b.Id(), err)
+	}
+	if err = stmt.Close(); err != nil {
+		return fmt.Errorf("Failed to save accounts of budget %d. Reason: %w", b.Id(), err)
+	}
+	return nil
+}
 
+func (d *DefaultBudgetDao) checkVersionedChange(result sql.Result, id ledger.BudgetId) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save budget", err)
+	}
+	if rowsAffected == 0 {
+		return pkg.ValidationErrorWithFields(pkg.ErrBudgetVersionConflict, fmt.Sprintf("Budget %d was changed by another request", id), nil, nil)
+	}
 	return nil
 }
 
+// GetBudgetById returns a budget of a user. ErrBudgetNotFound is returned if the user has no budget with the given id.
 func (d *DefaultBudgetDao) GetBudgetById(
 	ctx context.Context,
 	id ledger.BudgetId,
 	userId ledger.UserId,
 	tx *sql.Tx,
 ) (ledger.Budget, error) {
+	budgets, err := d.getBudgets(ctx, userId, &id, tx)
+	if err != nil {
+		return ledger.Budget{}, err
+	}
+	if len(budgets) == 0 {
+		return ledger.Budget{}, pkg.ValidationErrorWithError(pkg.ErrBudgetNotFound, fmt.Sprintf("Budget %d not found", id), sql.ErrNoRows)
+	}
+	return budgets[0], nil
+}
+
+// LockBudgetTx locks the budget of a user until the transaction ends. ErrBudgetNotFound is returned if the user has no budget with the given id.
+func (d *DefaultBudgetDao) LockBudgetTx(
+	ctx context.Context,
+	id ledger.BudgetId,
+	userId ledger.UserId,
+	tx *sql.Tx,
+) error {
+	var lockedId ledger.BudgetId
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT id FROM budget.budget WHERE id = $1 AND user_id = $2 FOR UPDATE",
+		id,
+		userId,
+	).Scan(&lockedId)
+	if err == sql.ErrNoRows {
+		return pkg.ValidationErrorWithError(pkg.ErrBudgetNotFound, fmt.Sprintf("Budget %d not found", id), err)
+	}
+	if err != nil {
+		log.Printf("Failed to lock budget %d. Reason: %s", id, err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to lock budget", err)
+	}
+	return nil
+}
+
+// GetBudgetsForUser returns the budgets of a user, oldest first.
+func (d *DefaultBudgetDao) GetBudgetsForUser(
+	ctx context.Context,
+	userId ledger.UserId,
+	tx *sql.Tx,
+) ([]ledger.Budget, error) {
+	return d.getBudgets(ctx, userId, nil, tx)
+}
+
+func (d *DefaultBudgetDao) GetSpentPerCategoryTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	budget ledger.Budget,
+	period ledger.BudgetPeriod,
+	tx *sql.Tx,
+) (map[ledger.CategoryId]ledger.Money, error) {
+	spentPerPeriod, err := d.GetSpentPerCategoryPerPeriodTx(ctx, userId, budget, []ledger.BudgetPeriod{period}, tx)
+	if err != nil {
+		return nil, err
+	}
+	return spentPerPeriod[0], nil
+}
+
+func (d *DefaultBudgetDao) GetSpentPerCategoryPerPeriodTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	budget ledger.Budget,
+	periods []ledger.BudgetPeriod,
+	tx *sql.Tx,
+) ([]map[ledger.CategoryId]ledger.Money, error) {
+	currencyOfCategory := map[ledger.CategoryId]string{}
+	categoryIds := make([]int64, 0, len(budget.CategoryBudgets()))
+	for _, categoryBudget := range budget.CategoryBudgets() {
+		currencyOfCategory[categoryBudget.CategoryId()] = categoryBudget.MaxLimit().Currency().CurrencyCode()
+		categoryIds = append(categoryIds, int64(categoryBudget.CategoryId()))
+	}
+
+	firstDays := make([]time.Time, 0, len(periods))
+	lastDays := make([]time.Time, 0, len(periods))
+	for _, period := range periods {
+		firstDays = append(firstDays, period.FirstDay())
+		lastDays = append(lastDays, period.LastDay())
+	}
+
+	spentPerPeriod, err := sumExpensesPerCategoryPerPeriodTx(ctx, userId, budget.AccountIds(), categoryIds, firstDays, lastDays, tx)
+	if err != nil {
+		log.Printf("Failed to sum expenses of budget %d. Reason: %s", budget.Id(), err)
+		return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to calculate budget progress", err)
+	}
+
+	spent := make([]map[ledger.CategoryId]ledger.Money, 0, len(periods))
+	for _, spentPerCategory := range spentPerPeriod {
+		spentInPeriod := map[ledger.CategoryId]ledger.Money{}
+		for categoryId, minorUnits := range spentPerCategory {
+			var money ledger.Money
+			if money, err = ledger.NewMoney(currencyOfCategory[categoryId], minorUnits); err != nil {
+				return nil, err
+			}
+			spentInPeriod[categoryId] = money
+		}
+		spent = append(spent, spentInPeriod)
+	}
+	return spent, nil
+}
+
+// sumExpensesPerCategoryTx returns the total expenses of each category, in minor units, across the accounts of a user, dated between the two days inclusive.
+// Only the given categories are summed, or all categories if categoryIds is nil.
+// Records that are split are counted by their lines, so that each line is added to its own category.
+func sumExpensesPerCategoryTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	accountIds ledger.AccountIds,
+	categoryIds []int64,
+	firstDay time.Time,
+	lastDay time.Time,
+	tx *sql.Tx,
+) (map[ledger.CategoryId]int64, error) {
+	spentPerPeriod, err := sumExpensesPerCategoryPerPeriodTx(ctx, userId, accountIds, categoryIds, []time.Time{firstDay}, []time.Time{lastDay}, tx)
+	if err != nil {
+		return nil, err
+	}
+	return spentPerPeriod[0], nil
+}
+
+// sumExpensesPerCategoryPerPeriodTx is sumExpensesPerCategoryTx for several periods at once, in a single query.
+// The period at index i is between firstDays[i] and lastDays[i] inclusive; the totals of each period are returned at the same index.
+// Periods should not overlap, otherwise a record is counted in each period that contains it.
+func sumExpensesPerCategoryPerPeriodTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	accountIds ledger.AccountIds,
+	categoryIds []int64,
+	firstDays []time.Time,
+	lastDays []time.Time,
+	tx *sql.Tx,
+) ([]map[ledger.CategoryId]int64, error) {
+	accounts := make([]int64, 0, len(accountIds))
+	for _, accountId := range accountIds {
+		accounts = append(accounts, int64(accountId))
+	}
+
+	formatDays := func(days []time.Time) []string {
+		formatted := make([]string, 0, len(days))
+		for _, day := range days {
+			formatted = append(formatted, day.Format("2006-01-02"))
+		}
+		return formatted
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`WITH period AS (
+			SELECT p.first_day, p.last_day, p.period_index 
+			FROM UNNEST($4::DATE[], $5::DATE[]) WITH ORDINALITY AS p(first_day, last_day, period_index)
+		),
+		expense AS (
+			SELECT p.period_index, r.id, r.category_id, r.amount_minor_units 
+			FROM budget.record r 
+			JOIN budget.account a ON a.id = r.account_id 
+			JOIN period p ON r.date >= p.first_day AND r.date <= p.last_day 
+			WHERE a.user_id = $1 
+			AND r.account_id = ANY($2) 
+			AND r.type = $3
+		)
+		SELECT e.period_index, e.category_id, SUM(ABS(e.amount_minor_units))
+		FROM (
+			SELECT x.period_index, x.category_id, x.amount_minor_units 
+			FROM expense x 
+			WHERE NOT EXISTS (SELECT 1 FROM budget.record_split s WHERE s.record_id = x.id)
+			UNION ALL
+			SELECT x.period_index, s.category_id, s.amount_minor_units 
+			FROM expense x 
+			JOIN budget.record_split s ON s.record_id = x.id
+		) e
+		WHERE $6::BIGINT[] IS NULL OR e.category_id = ANY($6)
+		GROUP BY e.period_index, e.category_id`,
+		userId,
+		pq.Array(accounts),
+		ledger.Expense,
+		pq.Array(formatDays(firstDays)),
+		pq.Array(formatDays(lastDays)),
+		pq.Array(categoryIds),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spentPerPeriod := make([]map[ledger.CategoryId]int64, len(firstDays))
+	for i := range spentPerPeriod {
+		spentPerPeriod[i] = map[ledger.CategoryId]int64{}
+	}
+	for rows.Next() {
+		var (
+			periodIndex int
+			categoryId  ledger.CategoryId
+			spent       int64
+		)
+		if err = rows.Scan(&periodIndex, &categoryId, &spent); err != nil {
+			return nil, err
+		}
+		spentPerPeriod[periodIndex-1][categoryId] = spent
+	}
+	return spentPerPeriod, rows.Err()
+}
+
+// getBudgets returns the budgets of a user, or only the budget with the given id if it is not nil.
+// The accounts and category budgets are loaded separately so that each budget is only loaded once.
+func (d *DefaultBudgetDao) getBudgets(
+	ctx context.Context,
+	userId ledger.UserId,
+	budgetId *ledger.BudgetId,
+	tx *sql.Tx,
+) ([]ledger.Budget, error) {
 
 	rows, err := tx.QueryContext(
 		ctx,
 		`SELECT 
 			b.id,
-			a.account_id,
-			b.period, 
-			bc.category_id,
-			c.name,
-			bc.currency,
-			bc.amount_minor_units,
+			b.period,
+			b.period_anchor,
+			b.period_start_day,
+			ARRAY(SELECT a.account_id FROM budget.account_budgets a WHERE a.budget_id = b.id ORDER BY a.account_id),
 			b.created_by,
 			b.created_at,
 			b.last_modified_by,
 			b.last_modified_at,
-			b.version
+			b.version,
+			u.week_start
 		FROM 
 			budget.budget b 
-		LEFT JOIN budget.user u ON b.user_id = u.id 
-		LEFT JOIN budget.account_budgets a ON a.budget_id = b.id
-		LEFT JOIN budget.budget_per_category bc ON bc.budget_id = b.id
-		LEFT JOIN budget.category c ON bc.category_id = c.id
+			JOIN budget.user u ON u.id = b.user_id 
 		WHERE 
-			u.id = $1
-		AND
-			b.id = $2`,
+			b.user_id = $1
+			AND ($2::BIGINT IS NULL OR b.id = $2)
+		ORDER BY 
+			b.id`,
 		userId,
-		id,
+		nullBudgetId(budgetId),
 	)
 	if err != nil {
-		return ledger.Budget{}, fmt.Errorf("Query execution failed", err)
+		return nil, fmt.Errorf("Failed to load budgets of user %d. Reason: %w", userId, err)
 	}
 	defer rows.Close()
 
-	br := budgetRecord{}
-
+	budgetRecords := []*budgetRecord{}
+	budgetRecordsById := map[ledger.BudgetId]*budgetRecord{}
 	for rows.Next() {
-		accountId := ledger.AccountId(0)
-		categoryId := uint64(0)
-		categoryName := ""
-		currency := ""
-		amountMinorUnits := int64(0)
-
+		var (
+			br         budgetRecord
+			accountIds []int64
+		)
 		if err := rows.Scan(
 			&br.id,
-			&accountId,
 			&br.periodType,
-			&categoryId,
-			&categoryName,
-			&currency,
-			&amountMinorUnits,
+			&br.periodAnchor,
+			&br.periodStartDay,
+			pq.Array(&accountIds),
 			&br.createdBy,
 			&br.createdAt,
 			&br.modifiedBy,
 			&br.modifiedAt,
 			&br.version,
+			&br.weekStart,
 		); err != nil {
-			return ledger.Budget{}, fmt.Errorf("Failed to scan row. Reason: %w", err)
+			return nil, fmt.Errorf("Failed to scan row. Reason: %w", err)
+		}
+		for _, accountId := range accountIds {
+			br.accountIds = append(br.accountIds, ledger.AccountId(accountId))
+		}
+		budgetRecords = append(budgetRecords, &br)
+		budgetRecordsById[br.id] = &br
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to load budgets of user %d. Reason: %w", userId, err)
+	}
+
+	categoryRows, err := tx.QueryContext(
+		ctx,
+		`SELECT 
+			bc.budget_id,
+			bc.category_id,
+			bc.currency,
+			bc.amount_minor_units,
+			bc.alert_thresholds,
+			bc.rollover
+		FROM 
+			budget.budget_per_category bc
+		JOIN budget.budget b ON b.id = bc.budget_id
+		WHERE 
+			b.user_id = $1
+			AND ($2::BIGINT IS NULL OR b.id = $2)
+		ORDER BY 
+			bc.category_id`,
+		userId,
+		nullBudgetId(budgetId),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load category budgets of user %d. Reason: %w", userId, err)
+	}
+	defer categoryRows.Close()
+
+	for categoryRows.Next() {
+		var (
+			budgetId         ledger.BudgetId
+			categoryId       ledger.CategoryId
+			currency         string
+			amountMinorUnits int64
+			thresholds       []int64
+			rollover         ledger.RolloverMode
+			amount           ledger.Money
+			categoryBudget   ledger.CategoryBudget
+		)
+		if err := categoryRows.Scan(&budgetId, &categoryId, &currency, &amountMinorUnits, pq.Array(&thresholds), &rollover); err != nil {
+			return nil, fmt.Errorf("Failed to scan row. Reason: %w", err)
+		}
+		if amount, err = ledger.NewMoney(currency, amountMinorUnits); err != nil {
+			return nil, err
 		}
+		alertThresholds := make(ledger.AlertThresholds, 0, len(thresholds))
+		for _, threshold := range thresholds {
+			alertThresholds = append(alertThresholds, uint(threshold))
+		}
+		if categoryBudget, err = ledger.NewCategoryBudgetWithRollover(categoryId, amount, alertThresholds, rollover); err != nil {
+			return nil, err
+		}
+		if br, ok := budgetRecordsById[budgetId]; ok {
+			br.categoryBudgets = append(br.categoryBudgets, categoryBudget)
+		}
+	}
+	if err = categoryRows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to load category budgets of user %d. Reason: %w", userId, err)
+	}
 
-		// TODO: remove duplicate accountIds
-		br.accountIds = append(br.accountIds, accountId)
-		br.categoryBudgets = append(
-			br.categoryBudgets,
-			ledger.MustCategoryBudget(ledger.NewCategoryBudget(
-				ledger.CategoryId(categoryId),
-				ledger.MustMoney(ledger.NewMoney(currency, amountMinorUnits)),
-			)))
+	budgets := make([]ledger.Budget, 0, len(budgetRecords))
+	for _, br := range budgetRecords {
+		budget, err := ledger.NewBudgetFromRecord(br)
+		if err != nil {
+			log.Printf("Error loading budget with id: %d from database. Reason: %s", br.id, err)
+			continue
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, nil
+}
+
+func nullBudgetId(id *ledger.BudgetId) sql.NullInt64 {
+	if id == nil {
+		return sql.NullInt64{}
 	}
+	return sql.NullInt64{Int64: int64(*id), Valid: true}
+}
 
-	return ledger.NewBudgetFromRecord(br)
+func nullPeriodAnchor(calculator ledger.PeriodCalculator) sql.NullString {
+	if calculator.AnchorDate().IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: calculator.AnchorDate().Format("2006-01-02"), Valid: true}
+}
+
+func nullPeriodStartDay(calculator ledger.PeriodCalculator) sql.NullInt64 {
+	if calculator.StartDay() == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(calculator.StartDay()), Valid: true}
 }
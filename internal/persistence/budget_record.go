@@ -12,12 +12,16 @@ type budgetRecord struct {
 	id              ledger.BudgetId
 	accountIds      ledger.AccountIds
 	periodType      ledger.BudgetPeriodType
+	periodAnchor    sql.NullTime
+	periodStartDay  sql.NullInt64
 	categoryBudgets ledger.CategoryBudgets
 	createdBy       string
 	createdAt       time.Time
 	modifiedBy      sql.NullString
 	modifiedAt      sql.NullTime
 	version         ledger.Version
+	// weekStart is the week start of the user of the budget, on which weekly periods start
+	weekStart string
 }
 
 func (br budgetRecord) Id() ledger.BudgetId {
@@ -28,8 +32,19 @@ func (br budgetRecord) AccountIds() ledger.AccountIds {
 	return br.accountIds
 }
 
-func (br budgetRecord) PeriodType() ledger.BudgetPeriodType {
-	return br.periodType
+func (br budgetRecord) PeriodCalculator() ledger.PeriodCalculator {
+	weekStart, ok := ledger.ParseWeekday(br.weekStart)
+	if !ok {
+		log.Fatalf("Invalid week start persisted for the user of budget %d: %s", br.id, br.weekStart)
+	}
+	if !br.periodAnchor.Valid && !br.periodStartDay.Valid {
+		return ledger.DefaultPeriodCalculator(br.periodType).WithWeekStart(weekStart)
+	}
+	calculator, err := ledger.NewPeriodCalculator(br.periodType, br.periodAnchor.Time, uint(br.periodStartDay.Int64))
+	if err != nil {
+		log.Fatalf("Invalid period persisted for budget %d: %s, anchor: %v, start day: %v. Reason: %s", br.id, br.periodType, br.periodAnchor, br.periodStartDay, err)
+	}
+	return calculator.WithWeekStart(weekStart)
 }
 
 func (br budgetRecord) CategoryBudgets() ledger.CategoryBudgets {
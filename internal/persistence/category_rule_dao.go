@@ -0,0 +1,183 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+type DefaultCategoryRuleDao struct {
+	RootDao
+}
+
+func MustOpenCategoryRuleDao(db *sql.DB) dao.CategoryRuleDao {
+	return &DefaultCategoryRuleDao{RootDao{db}}
+}
+
+func (d *DefaultCategoryRuleDao) NewCategoryRuleId(tx *sql.Tx) (ledger.CategoryRuleId, error) {
+	var id ledger.CategoryRuleId
+	err := tx.QueryRow("SELECT nextval('budget.category_rule_id')").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to assign category rule id. Reason: %w", err)
+	}
+	return id, err
+}
+
+func (d *DefaultCategoryRuleDao) SaveTx(ctx context.Context, userId ledger.UserId, r ledger.CategoryRule, tx *sql.Tx) error {
+	amount := func(a int64) sql.NullInt64 {
+		return sql.NullInt64{Int64: a, Valid: a != ledger.NoAmountLimit}
+	}
+
+	epoch := time.Time{}
+	conditions := r.Conditions()
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO budget.category_rule (
+			id,
+			user_id,
+			priority,
+			note_contains,
+			account_id,
+			min_amount_minor_units,
+			max_amount_minor_units,
+			category_id,
+			created_by,
+			created_at,
+			last_modified_by,
+			last_modified_at,
+			version
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8,
+			$9,
+			$10,
+			$11,
+			$12,
+			$13
+		)`,
+		r.Id(),
+		userId,
+		r.Priority(),
+		sql.NullString{
+			String: conditions.NoteContains,
+			Valid:  len(conditions.NoteContains) > 0,
+		},
+		sql.NullInt64{
+			Int64: int64(conditions.AccountId),
+			Valid: conditions.AccountId != ledger.AnyAccount,
+		},
+		amount(conditions.MinAmount),
+		amount(conditions.MaxAmount),
+		r.CategoryId(),
+		r.CreatedBy().String(),
+		r.CreatedAtUTC(),
+		sql.NullString{
+			String: r.ModifiedBy().String(),
+			Valid:  r.ModifiedBy() != ledger.UpdatedBy{},
+		},
+		sql.NullTime{
+			Time:  r.ModifiedAtUTC(),
+			Valid: epoch != r.ModifiedAtUTC(),
+		},
+		r.Version(),
+	)
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save category rule", err)
+	}
+	return nil
+}
+
+func (d *DefaultCategoryRuleDao) DeleteTx(ctx context.Context, id ledger.CategoryRuleId, userId ledger.UserId, tx *sql.Tx) error {
+	result, err := tx.ExecContext(ctx, `DELETE FROM budget.category_rule WHERE id = $1 AND user_id = $2`, id, userId)
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to delete category rule", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to delete category rule", err)
+	}
+	if rowsAffected == 0 {
+		return pkg.ValidationErrorWithError(pkg.ErrCategoryRuleNotFound, "Category rule not found", nil)
+	}
+	return nil
+}
+
+var categoryRuleColumns = []string{
+	"r.id",
+	"r.priority",
+	"r.note_contains",
+	"r.account_id",
+	"r.min_amount_minor_units",
+	"r.max_amount_minor_units",
+	"r.category_id",
+	"r.created_by",
+	"r.created_at",
+	"r.last_modified_by",
+	"r.last_modified_at",
+	"r.version",
+}
+
+func scanCategoryRule(row interface{ Scan(...interface{}) error }) (categoryRuleRecord, error) {
+	var cr categoryRuleRecord
+	err := row.Scan(
+		&cr.id,
+		&cr.priority,
+		&cr.noteContains,
+		&cr.accountId,
+		&cr.minAmountMinorUnits,
+		&cr.maxAmountMinorUnits,
+		&cr.categoryId,
+		&cr.createdBy,
+		&cr.createdAt,
+		&cr.modifiedBy,
+		&cr.modifiedAt,
+		&cr.version,
+	)
+	return cr, err
+}
+
+// GetCategoryRulesForUser returns the category rules of a user in the order they are tried.
+func (d *DefaultCategoryRuleDao) GetCategoryRulesForUser(ctx context.Context, userId ledger.UserId, tx *sql.Tx) (ledger.CategoryRules, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query := psql.Select(categoryRuleColumns...).
+		From("budget.category_rule r").
+		Where(sq.Eq{"r.user_id": userId}).
+		OrderBy("r.priority", "r.id")
+
+	rows, err := query.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		log.Printf("Failed to load category rules for user %d. Reason: %s", userId, err)
+		return ledger.CategoryRules{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load category rules", err)
+	}
+	defer rows.Close()
+
+	rules := ledger.CategoryRules{}
+	for rows.Next() {
+		var (
+			cr   categoryRuleRecord
+			rule ledger.CategoryRule
+		)
+		if cr, err = scanCategoryRule(rows); err != nil {
+			return ledger.CategoryRules{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load category rules", err)
+		}
+		if rule, err = ledger.NewCategoryRuleFromRecord(cr); err != nil {
+			return ledger.CategoryRules{}, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
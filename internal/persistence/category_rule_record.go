@@ -0,0 +1,93 @@
+package persistence
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+type categoryRuleRecord struct {
+	id                  ledger.CategoryRuleId
+	priority            int
+	noteContains        sql.NullString
+	accountId           sql.NullInt64
+	minAmountMinorUnits sql.NullInt64
+	maxAmountMinorUnits sql.NullInt64
+	categoryId          ledger.CategoryId
+	createdBy           string
+	createdAt           time.Time
+	modifiedBy          sql.NullString
+	modifiedAt          sql.NullTime
+	version             ledger.Version
+}
+
+func (cr categoryRuleRecord) Id() ledger.CategoryRuleId {
+	return cr.id
+}
+
+func (cr categoryRuleRecord) Priority() int {
+	return cr.priority
+}
+
+func (cr categoryRuleRecord) Conditions() ledger.CategoryRuleConditions {
+	amount := func(a sql.NullInt64) int64 {
+		if a.Valid {
+			return a.Int64
+		}
+		return ledger.NoAmountLimit
+	}
+
+	conditions := ledger.CategoryRuleConditions{
+		NoteContains: cr.noteContains.String,
+		AccountId:    ledger.AnyAccount,
+		MinAmount:    amount(cr.minAmountMinorUnits),
+		MaxAmount:    amount(cr.maxAmountMinorUnits),
+	}
+	if cr.accountId.Valid {
+		conditions.AccountId = ledger.AccountId(cr.accountId.Int64)
+	}
+	return conditions
+}
+
+func (cr categoryRuleRecord) CategoryId() ledger.CategoryId {
+	return cr.categoryId
+}
+
+func (cr categoryRuleRecord) CreatedBy() ledger.UpdatedBy {
+	updatedBy, err := ledger.ParseUpdatedBy(cr.createdBy)
+	if err != nil {
+		log.Fatalf("Invalid createdBy persisted for category rule %d: %s", cr.id, cr.createdBy)
+	}
+	return updatedBy
+}
+
+func (cr categoryRuleRecord) CreatedAtUTC() time.Time {
+	return cr.createdAt
+}
+
+func (cr categoryRuleRecord) ModifiedBy() ledger.UpdatedBy {
+	if !cr.modifiedBy.Valid {
+		return ledger.UpdatedBy{}
+	}
+	var (
+		updatedBy ledger.UpdatedBy
+		err       error
+	)
+	if updatedBy, err = ledger.ParseUpdatedBy(cr.modifiedBy.String); err != nil {
+		log.Fatalf("Invalid modifiedBy persisted for category rule %d: %s", cr.id, cr.modifiedBy.String)
+	}
+	return updatedBy
+}
+
+func (cr categoryRuleRecord) ModifiedAtUTC() time.Time {
+	if cr.modifiedAt.Valid {
+		return cr.modifiedAt.Time
+	}
+	return time.Time{}
+}
+
+func (cr categoryRuleRecord) Version() ledger.Version {
+	return cr.version
+}
@@ -0,0 +1,173 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+type DefaultEnvelopeTransferDao struct {
+	RootDao
+}
+
+func MustOpenEnvelopeTransferDao(db *sql.DB) dao.EnvelopeTransferDao {
+	return &DefaultEnvelopeTransferDao{RootDao{db}}
+}
+
+func (d *DefaultEnvelopeTransferDao) NewEnvelopeTransferId(tx *sql.Tx) (ledger.EnvelopeTransferId, error) {
+	var id ledger.EnvelopeTransferId
+	err := tx.QueryRow("SELECT nextval('budget.envelope_transfer_id')").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to assign envelope transfer id. Reason: %w", err)
+	}
+	return id, err
+}
+
+func (d *DefaultEnvelopeTransferDao) SaveTx(ctx context.Context, userId ledger.UserId, t ledger.EnvelopeTransfer, tx *sql.Tx) error {
+	epoch := time.Time{}
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO budget.envelope_transfer (
+			id,
+			user_id,
+			budget_id,
+			from_category_id,
+			to_category_id,
+			period,
+			period_start,
+			period_end,
+			currency,
+			amount_minor_units,
+			note,
+			created_by,
+			created_at,
+			last_modified_by,
+			last_modified_at,
+			version
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8,
+			$9,
+			$10,
+			$11,
+			$12,
+			$13,
+			$14,
+			$15,
+			$16
+		)`,
+		t.Id(),
+		userId,
+		t.BudgetId(),
+		t.FromCategoryId(),
+		t.ToCategoryId(),
+		t.Period().PeriodType(),
+		t.Period().FirstDay().Format("2006-01-02"),
+		t.Period().LastDay().Format("2006-01-02"),
+		t.Amount().Currency().CurrencyCode(),
+		t.Amount().MustMinorUnits(),
+		sql.NullString{
+			String: t.Note(),
+			Valid:  len(t.Note()) > 0,
+		},
+		t.CreatedBy().String(),
+		t.CreatedAtUTC(),
+		sql.NullString{
+			String: t.ModifiedBy().String(),
+			Valid:  t.ModifiedBy() != ledger.UpdatedBy{},
+		},
+		sql.NullTime{
+			Time:  t.ModifiedAtUTC(),
+			Valid: epoch != t.ModifiedAtUTC(),
+		},
+		t.Version(),
+	)
+	if err != nil {
+		log.Printf("Failed to save envelope transfer %s. Reason: %s", t, err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save envelope transfer", err)
+	}
+	return nil
+}
+
+// GetEnvelopeTransfersForBudget returns the transfers between the envelopes of a budget of a user, oldest first.
+func (d *DefaultEnvelopeTransferDao) GetEnvelopeTransfersForBudget(ctx context.Context, userId ledger.UserId, budgetId ledger.BudgetId, tx *sql.Tx) ([]ledger.EnvelopeTransfer, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT 
+			t.id,
+			t.budget_id,
+			t.from_category_id,
+			t.to_category_id,
+			t.period,
+			t.period_start,
+			t.currency,
+			t.amount_minor_units,
+			t.note,
+			t.created_by,
+			t.created_at,
+			t.last_modified_by,
+			t.last_modified_at,
+			t.version
+		FROM 
+			budget.envelope_transfer t
+		WHERE 
+			t.user_id = $1
+			AND t.budget_id = $2
+		ORDER BY 
+			t.created_at,
+			t.id`,
+		userId,
+		budgetId,
+	)
+	if err != nil {
+		log.Printf("Failed to load envelope transfers of budget %d. Reason: %s", budgetId, err)
+		return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load envelope transfers", err)
+	}
+	defer rows.Close()
+
+	transfers := []ledger.EnvelopeTransfer{}
+	for rows.Next() {
+		var (
+			tr       envelopeTransferRecord
+			transfer ledger.EnvelopeTransfer
+		)
+		if err = rows.Scan(
+			&tr.id,
+			&tr.budgetId,
+			&tr.fromCategoryId,
+			&tr.toCategoryId,
+			&tr.periodType,
+			&tr.periodStart,
+			&tr.currency,
+			&tr.amountMinorUnits,
+			&tr.note,
+			&tr.createdBy,
+			&tr.createdAt,
+			&tr.modifiedBy,
+			&tr.modifiedAt,
+			&tr.version,
+		); err != nil {
+			return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load envelope transfers", err)
+		}
+		if transfer, err = ledger.NewEnvelopeTransferFromRecord(tr); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load envelope transfers", err)
+	}
+	return transfers, nil
+}
@@ -0,0 +1,95 @@
+package persistence
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+type envelopeTransferRecord struct {
+	id               ledger.EnvelopeTransferId
+	budgetId         ledger.BudgetId
+	fromCategoryId   ledger.CategoryId
+	toCategoryId     ledger.CategoryId
+	periodType       ledger.BudgetPeriodType
+	periodStart      time.Time
+	currency         string
+	amountMinorUnits int64
+	note             sql.NullString
+	createdBy        string
+	createdAt        time.Time
+	modifiedBy       sql.NullString
+	modifiedAt       sql.NullTime
+	version          ledger.Version
+}
+
+func (tr envelopeTransferRecord) Id() ledger.EnvelopeTransferId {
+	return tr.id
+}
+
+func (tr envelopeTransferRecord) BudgetId() ledger.BudgetId {
+	return tr.budgetId
+}
+
+func (tr envelopeTransferRecord) FromCategoryId() ledger.CategoryId {
+	return tr.fromCategoryId
+}
+
+func (tr envelopeTransferRecord) ToCategoryId() ledger.CategoryId {
+	return tr.toCategoryId
+}
+
+func (tr envelopeTransferRecord) Period() ledger.BudgetPeriod {
+	return ledger.BudgetPeriodStartingOn(tr.periodType, tr.periodStart)
+}
+
+func (tr envelopeTransferRecord) Amount() ledger.Money {
+	money, err := ledger.NewMoney(tr.currency, tr.amountMinorUnits)
+	if err != nil {
+		log.Fatalf("Invalid amount persisted for envelope transfer %d: %s %d", tr.id, tr.currency, tr.amountMinorUnits)
+	}
+	return money
+}
+
+func (tr envelopeTransferRecord) Note() string {
+	return tr.note.String
+}
+
+func (tr envelopeTransferRecord) CreatedBy() ledger.UpdatedBy {
+	updatedBy, err := ledger.ParseUpdatedBy(tr.createdBy)
+	if err != nil {
+		log.Fatalf("Invalid createdBy persisted for envelope transfer %d: %s", tr.id, tr.createdBy)
+	}
+	return updatedBy
+}
+
+func (tr envelopeTransferRecord) CreatedAtUTC() time.Time {
+	return tr.createdAt
+}
+
+func (tr envelopeTransferRecord) ModifiedBy() ledger.UpdatedBy {
+	if !tr.modifiedBy.Valid {
+		return ledger.UpdatedBy{}
+	}
+	var (
+		updatedBy ledger.UpdatedBy
+		err       error
+	)
+	if updatedBy, err = ledger.ParseUpdatedBy(tr.modifiedBy.String); err != nil {
+		log.Fatalf("Invalid modifiedBy persisted for envelope transfer %d: %s", tr.id, tr.modifiedBy.String)
+	}
+	return updatedBy
+}
+
+func (tr envelopeTransferRecord) ModifiedAtUTC() time.Time {
+	if tr.modifiedAt.Valid {
+		return tr.modifiedAt.Time
+	}
+	return time.Time{}
+}
+
+func (tr envelopeTransferRecord) Version() ledger.Version {
+	return tr.version
+}
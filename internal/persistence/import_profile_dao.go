@@ -0,0 +1,215 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+type DefaultImportProfileDao struct {
+	RootDao
+}
+
+func MustOpenImportProfileDao(db *sql.DB) dao.ImportProfileDao {
+	return &DefaultImportProfileDao{RootDao{db}}
+}
+
+func (d *DefaultImportProfileDao) NewImportProfileId(tx *sql.Tx) (ledger.ImportProfileId, error) {
+	var id ledger.ImportProfileId
+	err := tx.QueryRow("SELECT nextval('budget.import_profile_id')").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to assign import profile id. Reason: %w", err)
+	}
+	return id, err
+}
+
+// SaveTx saves a new import profile of the given user. The names of the import profiles of a user are unique.
+func (d *DefaultImportProfileDao) SaveTx(ctx context.Context, userId ledger.UserId, p ledger.ImportProfile, tx *sql.Tx) error {
+	column := func(c int) sql.NullInt32 {
+		return sql.NullInt32{Int32: int32(c), Valid: c != ledger.NoColumn}
+	}
+
+	epoch := time.Time{}
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO budget.import_profile (
+			id,
+			user_id,
+			name,
+			date_column,
+			amount_column,
+			debit_column,
+			note_column,
+			category_column,
+			date_format,
+			decimal_separator,
+			debit_format,
+			has_header,
+			default_category_id,
+			created_by,
+			created_at,
+			last_modified_by,
+			last_modified_at,
+			version
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8,
+			$9,
+			$10,
+			$11,
+			$12,
+			$13,
+			$14,
+			$15,
+			$16,
+			$17,
+			$18
+		)`,
+		p.Id(),
+		userId,
+		p.Name(),
+		p.Columns().Date,
+		p.Columns().Amount,
+		column(p.Columns().Debit),
+		column(p.Columns().Note),
+		column(p.Columns().Category),
+		p.DateFormat(),
+		p.DecimalSeparator(),
+		p.DebitFormat(),
+		p.HasHeader(),
+		sql.NullInt64{
+			Int64: int64(p.DefaultCategoryId()),
+			Valid: p.DefaultCategoryId() != ledger.NoDefaultCategory,
+		},
+		p.CreatedBy().String(),
+		p.CreatedAtUTC(),
+		sql.NullString{
+			String: p.ModifiedBy().String(),
+			Valid:  p.ModifiedBy() != ledger.UpdatedBy{},
+		},
+		sql.NullTime{
+			Time:  p.ModifiedAtUTC(),
+			Valid: epoch != p.ModifiedAtUTC(),
+		},
+		p.Version(),
+	)
+	if _, duplicate := d.IsDuplicateKeyError(err); duplicate {
+		return pkg.ValidationErrorWithFields(
+			pkg.ErrImportProfileValidation,
+			fmt.Sprintf("Import profile named %q already exists", p.Name()),
+			err,
+			map[string]string{"name": "name must be unique"},
+		)
+	} else if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save import profile", err)
+	}
+	return nil
+}
+
+var importProfileColumns = []string{
+	"p.id",
+	"p.name",
+	"p.date_column",
+	"p.amount_column",
+	"p.debit_column",
+	"p.note_column",
+	"p.category_column",
+	"p.date_format",
+	"p.decimal_separator",
+	"p.debit_format",
+	"p.has_header",
+	"p.default_category_id",
+	"p.created_by",
+	"p.created_at",
+	"p.last_modified_by",
+	"p.last_modified_at",
+	"p.version",
+}
+
+func scanImportProfile(row interface{ Scan(...interface{}) error }) (importProfileRecord, error) {
+	var ir importProfileRecord
+	err := row.Scan(
+		&ir.id,
+		&ir.name,
+		&ir.dateColumn,
+		&ir.amountColumn,
+		&ir.debitColumn,
+		&ir.noteColumn,
+		&ir.categoryColumn,
+		&ir.dateFormat,
+		&ir.decimalSeparator,
+		&ir.debitFormat,
+		&ir.hasHeader,
+		&ir.defaultCategoryId,
+		&ir.createdBy,
+		&ir.createdAt,
+		&ir.modifiedBy,
+		&ir.modifiedAt,
+		&ir.version,
+	)
+	return ir, err
+}
+
+func (d *DefaultImportProfileDao) GetImportProfileByIdTx(ctx context.Context, id ledger.ImportProfileId, userId ledger.UserId, tx *sql.Tx) (ledger.ImportProfile, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query := psql.Select(importProfileColumns...).
+		From("budget.import_profile p").
+		Where(sq.Eq{
+			"p.id":      id,
+			"p.user_id": userId,
+		})
+
+	ir, err := scanImportProfile(query.RunWith(tx).QueryRowContext(ctx))
+	if err != nil {
+		log.Printf("Failed to load import profile id %d for user %d. Reason: %s", id, userId, err)
+		if err == sql.ErrNoRows {
+			return ledger.ImportProfile{}, pkg.ValidationErrorWithError(pkg.ErrImportProfileNotFound, "Import profile not found", err)
+		}
+		return ledger.ImportProfile{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Error loading import profile", err)
+	}
+	return ledger.NewImportProfileFromRecord(ir)
+}
+
+func (d *DefaultImportProfileDao) GetImportProfilesForUser(ctx context.Context, userId ledger.UserId, tx *sql.Tx) (ledger.ImportProfiles, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query := psql.Select(importProfileColumns...).
+		From("budget.import_profile p").
+		Where(sq.Eq{"p.user_id": userId}).
+		OrderBy("p.name")
+
+	rows, err := query.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		log.Printf("Failed to load import profiles for user %d. Reason: %s", userId, err)
+		return ledger.ImportProfiles{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load import profiles", err)
+	}
+	defer rows.Close()
+
+	profiles := ledger.ImportProfiles{}
+	for rows.Next() {
+		var (
+			ir      importProfileRecord
+			profile ledger.ImportProfile
+		)
+		if ir, err = scanImportProfile(rows); err != nil {
+			return ledger.ImportProfiles{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load import profiles", err)
+		}
+		if profile, err = ledger.NewImportProfileFromRecord(ir); err != nil {
+			return ledger.ImportProfiles{}, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
@@ -0,0 +1,113 @@
+package persistence
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+type importProfileRecord struct {
+	id                ledger.ImportProfileId
+	name              string
+	dateColumn        int
+	amountColumn      int
+	debitColumn       sql.NullInt32
+	noteColumn        sql.NullInt32
+	categoryColumn    sql.NullInt32
+	dateFormat        string
+	decimalSeparator  string
+	debitFormat       string
+	hasHeader         bool
+	defaultCategoryId sql.NullInt64
+	createdBy         string
+	createdAt         time.Time
+	modifiedBy        sql.NullString
+	modifiedAt        sql.NullTime
+	version           ledger.Version
+}
+
+func (ir importProfileRecord) Id() ledger.ImportProfileId {
+	return ir.id
+}
+
+func (ir importProfileRecord) Name() string {
+	return ir.name
+}
+
+func (ir importProfileRecord) Columns() ledger.ImportColumns {
+	column := func(c sql.NullInt32) int {
+		if c.Valid {
+			return int(c.Int32)
+		}
+		return ledger.NoColumn
+	}
+	return ledger.ImportColumns{
+		Date:     ir.dateColumn,
+		Amount:   ir.amountColumn,
+		Debit:    column(ir.debitColumn),
+		Note:     column(ir.noteColumn),
+		Category: column(ir.categoryColumn),
+	}
+}
+
+func (ir importProfileRecord) DateFormat() string {
+	return ir.dateFormat
+}
+
+func (ir importProfileRecord) DecimalSeparator() ledger.DecimalSeparator {
+	return ledger.DecimalSeparator(ir.decimalSeparator)
+}
+
+func (ir importProfileRecord) DebitFormat() ledger.DebitFormat {
+	return ledger.DebitFormat(ir.debitFormat)
+}
+
+func (ir importProfileRecord) HasHeader() bool {
+	return ir.hasHeader
+}
+
+func (ir importProfileRecord) DefaultCategoryId() ledger.CategoryId {
+	if ir.defaultCategoryId.Valid {
+		return ledger.CategoryId(ir.defaultCategoryId.Int64)
+	}
+	return ledger.NoDefaultCategory
+}
+
+func (ir importProfileRecord) CreatedBy() ledger.UpdatedBy {
+	updatedBy, err := ledger.ParseUpdatedBy(ir.createdBy)
+	if err != nil {
+		log.Fatalf("Invalid createdBy persisted for import profile %d: %s", ir.id, ir.createdBy)
+	}
+	return updatedBy
+}
+
+func (ir importProfileRecord) CreatedAtUTC() time.Time {
+	return ir.createdAt
+}
+
+func (ir importProfileRecord) ModifiedBy() ledger.UpdatedBy {
+	if !ir.modifiedBy.Valid {
+		return ledger.UpdatedBy{}
+	}
+	var (
+		updatedBy ledger.UpdatedBy
+		err       error
+	)
+	if updatedBy, err = ledger.ParseUpdatedBy(ir.modifiedBy.String); err != nil {
+		log.Fatalf("Invalid modifiedBy persisted for import profile %d: %s", ir.id, ir.modifiedBy.String)
+	}
+	return updatedBy
+}
+
+func (ir importProfileRecord) ModifiedAtUTC() time.Time {
+	if ir.modifiedAt.Valid {
+		return ir.modifiedAt.Time
+	}
+	return time.Time{}
+}
+
+func (ir importProfileRecord) Version() ledger.Version {
+	return ir.version
+}
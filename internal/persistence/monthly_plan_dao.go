@@ -0,0 +1,306 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+type DefaultMonthlyPlanDao struct {
+	RootDao
+}
+
+func MustOpenMonthlyPlanDao(db *sql.DB) dao.MonthlyPlanDao {
+	return &DefaultMonthlyPlanDao{RootDao{db}}
+}
+
+func (d *DefaultMonthlyPlanDao) NewMonthlyPlanId(tx *sql.Tx) (ledger.MonthlyPlanId, error) {
+	var id ledger.MonthlyPlanId
+	err := tx.QueryRow("SELECT nextval('budget.monthly_plan_id')").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to assign monthly plan id. Reason: %w", err)
+	}
+	return id, err
+}
+
+func (d *DefaultMonthlyPlanDao) SaveTx(ctx context.Context, userId ledger.UserId, p ledger.MonthlyPlan, tx *sql.Tx) error {
+	epoch := time.Time{}
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO budget.monthly_plan (
+			id,
+			user_id,
+			year,
+			month,
+			currency,
+			expected_income_minor_units,
+			created_by,
+			created_at,
+			last_modified_by,
+			last_modified_at,
+			version
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8,
+			$9,
+			$10,
+			$11
+		)`,
+		p.Id(),
+		userId,
+		p.Month().Year(),
+		int(p.Month().Month()),
+		p.ExpectedIncome().Currency().CurrencyCode(),
+		p.ExpectedIncome().MustMinorUnits(),
+		p.CreatedBy().String(),
+		p.CreatedAtUTC(),
+		sql.NullString{
+			String: p.ModifiedBy().String(),
+			Valid:  p.ModifiedBy() != ledger.UpdatedBy{},
+		},
+		sql.NullTime{
+			Time:  p.ModifiedAtUTC(),
+			Valid: epoch != p.ModifiedAtUTC(),
+		},
+		p.Version(),
+	)
+	if _, duplicate := d.IsDuplicateKeyError(err); duplicate {
+		return pkg.ValidationErrorWithError(pkg.ErrMonthlyPlanDuplicated, fmt.Sprintf("A plan for %s already exists", p.Month()), err)
+	} else if err != nil {
+		log.Printf("Failed to save monthly plan %s. Reason: %s", p, err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save monthly plan", err)
+	}
+
+	return d.savePlanLinesTx(ctx, p, tx)
+}
+
+func (d *DefaultMonthlyPlanDao) UpdateTx(ctx context.Context, userId ledger.UserId, p ledger.MonthlyPlan, tx *sql.Tx) error {
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE budget.monthly_plan SET
+			currency = $1,
+			expected_income_minor_units = $2,
+			last_modified_by = $3
+		WHERE
+			id = $4
+			AND user_id = $5
+			AND version = $6`,
+		p.ExpectedIncome().Currency().CurrencyCode(),
+		p.ExpectedIncome().MustMinorUnits(),
+		p.ModifiedBy().String(),
+		p.Id(),
+		userId,
+		p.Version(),
+	)
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update monthly plan", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update monthly plan", err)
+	}
+	if rowsAffected == 0 {
+		return pkg.ValidationErrorWithFields(pkg.ErrMonthlyPlanVersionConflict, fmt.Sprintf("Plan for %s was changed by another request", p.Month()), nil, nil)
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM budget.monthly_plan_assignment WHERE plan_id = $1`, p.Id()); err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update assignments of monthly plan", err)
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM budget.monthly_plan_account WHERE plan_id = $1`, p.Id()); err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update accounts of monthly plan", err)
+	}
+
+	return d.savePlanLinesTx(ctx, p, tx)
+}
+
+// savePlanLinesTx saves the accounts and the assignments of a plan.
+func (d *DefaultMonthlyPlanDao) savePlanLinesTx(ctx context.Context, p ledger.MonthlyPlan, tx *sql.Tx) error {
+	for _, accountId := range p.AccountIds() {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO budget.monthly_plan_account (plan_id, account_id) VALUES ($1, $2)`,
+			p.Id(),
+			accountId,
+		); err != nil {
+			return pkg.NewSystemError(pkg.ErrDatabaseState, fmt.Sprintf("Failed to save account %d of monthly plan", accountId), err)
+		}
+	}
+
+	for _, assignment := range p.Assignments() {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO budget.monthly_plan_assignment (plan_id, category_id, currency, amount_minor_units) VALUES ($1, $2, $3, $4)`,
+			p.Id(),
+			assignment.CategoryId(),
+			assignment.MaxLimit().Currency().CurrencyCode(),
+			assignment.MaxLimit().MustMinorUnits(),
+		); err != nil {
+			return pkg.NewSystemError(pkg.ErrDatabaseState, fmt.Sprintf("Failed to save assignment of category %d of monthly plan", assignment.CategoryId()), err)
+		}
+	}
+	return nil
+}
+
+func (d *DefaultMonthlyPlanDao) GetMonthlyPlanTx(ctx context.Context, userId ledger.UserId, month ledger.CalendarMonth, tx *sql.Tx) (ledger.MonthlyPlan, error) {
+	var (
+		pr          monthlyPlanRecord
+		monthNumber int
+		accountIds  []int64
+	)
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT 
+			p.id,
+			p.year,
+			p.month,
+			ARRAY(SELECT a.account_id FROM budget.monthly_plan_account a WHERE a.plan_id = p.id ORDER BY a.account_id),
+			p.currency,
+			p.expected_income_minor_units,
+			p.created_by,
+			p.created_at,
+			p.last_modified_by,
+			p.last_modified_at,
+			p.version
+		FROM 
+			budget.monthly_plan p
+		WHERE 
+			p.user_id = $1
+			AND p.year = $2
+			AND p.month = $3`,
+		userId,
+		month.Year(),
+		int(month.Month()),
+	).Scan(
+		&pr.id,
+		&pr.year,
+		&monthNumber,
+		pq.Array(&accountIds),
+		&pr.currency,
+		&pr.expectedIncomeMinorUnits,
+		&pr.createdBy,
+		&pr.createdAt,
+		&pr.modifiedBy,
+		&pr.modifiedAt,
+		&pr.version,
+	)
+	if err == sql.ErrNoRows {
+		return ledger.MonthlyPlan{}, pkg.ValidationErrorWithError(pkg.ErrMonthlyPlanNotFound, fmt.Sprintf("Plan for %s not found", month), err)
+	} else if err != nil {
+		log.Printf("Failed to load plan for %s of user %d. Reason: %s", month, userId, err)
+		return ledger.MonthlyPlan{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load monthly plan", err)
+	}
+	pr.month = time.Month(monthNumber)
+	for _, accountId := range accountIds {
+		pr.accountIds = append(pr.accountIds, ledger.AccountId(accountId))
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT 
+			pa.category_id,
+			pa.currency,
+			pa.amount_minor_units
+		FROM 
+			budget.monthly_plan_assignment pa
+		WHERE 
+			pa.plan_id = $1
+		ORDER BY 
+			pa.category_id`,
+		pr.id,
+	)
+	if err != nil {
+		return ledger.MonthlyPlan{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load assignments of monthly plan", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			categoryId       ledger.CategoryId
+			currency         string
+			amountMinorUnits int64
+			amount           ledger.Money
+			assignment       ledger.CategoryBudget
+		)
+		if err = rows.Scan(&categoryId, &currency, &amountMinorUnits); err != nil {
+			return ledger.MonthlyPlan{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load assignments of monthly plan", err)
+		}
+		if amount, err = ledger.NewMoney(currency, amountMinorUnits); err != nil {
+			return ledger.MonthlyPlan{}, err
+		}
+		if assignment, err = ledger.NewCategoryBudgetWithAlertThresholds(categoryId, amount, ledger.AlertThresholds{}); err != nil {
+			return ledger.MonthlyPlan{}, err
+		}
+		pr.assignments = append(pr.assignments, assignment)
+	}
+	if err = rows.Err(); err != nil {
+		return ledger.MonthlyPlan{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load assignments of monthly plan", err)
+	}
+
+	return ledger.NewMonthlyPlanFromRecord(pr)
+}
+
+func (d *DefaultMonthlyPlanDao) GetActualsTx(ctx context.Context, userId ledger.UserId, p ledger.MonthlyPlan, tx *sql.Tx) (ledger.Money, map[ledger.CategoryId]ledger.Money, error) {
+	currencyCode := p.ExpectedIncome().Currency().CurrencyCode()
+
+	accounts := make([]int64, 0, len(p.AccountIds()))
+	for _, accountId := range p.AccountIds() {
+		accounts = append(accounts, int64(accountId))
+	}
+
+	var incomeMinorUnits int64
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT COALESCE(SUM(ABS(r.amount_minor_units)), 0)
+		FROM budget.record r 
+		JOIN budget.account a ON a.id = r.account_id 
+		WHERE a.user_id = $1 
+		AND r.account_id = ANY($2) 
+		AND r.type = $3 
+		AND r.date >= $4 
+		AND r.date <= $5`,
+		userId,
+		pq.Array(accounts),
+		ledger.Income,
+		p.Month().FirstDay().Format("2006-01-02"),
+		p.Month().LastDay().Format("2006-01-02"),
+	).Scan(&incomeMinorUnits)
+	if err != nil {
+		log.Printf("Failed to sum income of plan %d. Reason: %s", p.Id(), err)
+		return nil, nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to calculate monthly plan summary", err)
+	}
+
+	income, err := ledger.NewMoney(currencyCode, incomeMinorUnits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	spentPerCategory, err := sumExpensesPerCategoryTx(ctx, userId, p.AccountIds(), nil, p.Month().FirstDay(), p.Month().LastDay(), tx)
+	if err != nil {
+		log.Printf("Failed to sum expenses of plan %d. Reason: %s", p.Id(), err)
+		return nil, nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to calculate monthly plan summary", err)
+	}
+
+	spent := map[ledger.CategoryId]ledger.Money{}
+	for categoryId, minorUnits := range spentPerCategory {
+		var money ledger.Money
+		if money, err = ledger.NewMoney(currencyCode, minorUnits); err != nil {
+			return nil, nil, err
+		}
+		spent[categoryId] = money
+	}
+	return income, spent, nil
+}
@@ -0,0 +1,85 @@
+package persistence
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+type monthlyPlanRecord struct {
+	id                       ledger.MonthlyPlanId
+	year                     uint
+	month                    time.Month
+	accountIds               ledger.AccountIds
+	currency                 string
+	expectedIncomeMinorUnits int64
+	assignments              ledger.CategoryBudgets
+	createdBy                string
+	createdAt                time.Time
+	modifiedBy               sql.NullString
+	modifiedAt               sql.NullTime
+	version                  ledger.Version
+}
+
+func (pr monthlyPlanRecord) Id() ledger.MonthlyPlanId {
+	return pr.id
+}
+
+func (pr monthlyPlanRecord) Month() ledger.CalendarMonth {
+	return ledger.MakeCalendarMonth(pr.year, pr.month)
+}
+
+func (pr monthlyPlanRecord) AccountIds() ledger.AccountIds {
+	return pr.accountIds
+}
+
+func (pr monthlyPlanRecord) ExpectedIncome() ledger.Money {
+	money, err := ledger.NewMoney(pr.currency, pr.expectedIncomeMinorUnits)
+	if err != nil {
+		log.Fatalf("Invalid expected income persisted for monthly plan %d: %s %d", pr.id, pr.currency, pr.expectedIncomeMinorUnits)
+	}
+	return money
+}
+
+func (pr monthlyPlanRecord) Assignments() ledger.CategoryBudgets {
+	return pr.assignments
+}
+
+func (pr monthlyPlanRecord) CreatedBy() ledger.UpdatedBy {
+	updatedBy, err := ledger.ParseUpdatedBy(pr.createdBy)
+	if err != nil {
+		log.Fatalf("Invalid createdBy persisted for monthly plan %d: %s", pr.id, pr.createdBy)
+	}
+	return updatedBy
+}
+
+func (pr monthlyPlanRecord) CreatedAtUTC() time.Time {
+	return pr.createdAt
+}
+
+func (pr monthlyPlanRecord) ModifiedBy() ledger.UpdatedBy {
+	if !pr.modifiedBy.Valid {
+		return ledger.UpdatedBy{}
+	}
+	var (
+		updatedBy ledger.UpdatedBy
+		err       error
+	)
+	if updatedBy, err = ledger.ParseUpdatedBy(pr.modifiedBy.String); err != nil {
+		log.Fatalf("Invalid modifiedBy persisted for monthly plan %d: %s", pr.id, pr.modifiedBy.String)
+	}
+	return updatedBy
+}
+
+func (pr monthlyPlanRecord) ModifiedAtUTC() time.Time {
+	if pr.modifiedAt.Valid {
+		return pr.modifiedAt.Time
+	}
+	return time.Time{}
+}
+
+func (pr monthlyPlanRecord) Version() ledger.Version {
+	return pr.version
+}
@@ -5,11 +5,12 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"sort"
 	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
 	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
 	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
 )
@@ -26,7 +27,7 @@ func (d *DefaultRecordDao) NewRecordId(tx *sql.Tx) (ledger.RecordId, error) {
 	var recordId ledger.RecordId
 	err := tx.QueryRow("SELECT nextval('budget.record_id')").Scan(&recordId)
 	if err != nil {
-		return 0, fmt.Errorf("Failed to assign record id", err)
+		return 0, fmt.Errorf("Failed to assign record id. Reason: %w", err)
 	}
 	return recordId, err
 }
@@ -45,7 +46,13 @@ func (d *DefaultRecordDao) Save(ctx context.Context, accountId ledger.AccountId,
 	return err
 }
 
+// SaveTx saves a new record in the given account.
+// The amount of the record must be in the currency of the account.
 func (d *DefaultRecordDao) SaveTx(ctx context.Context, accountId ledger.AccountId, r ledger.Record, tx *sql.Tx) error {
+	if err := d.checkAccountCurrency(ctx, accountId, r.Amount(), tx); err != nil {
+		return err
+	}
+
 	epoch := time.Time{}
 	amountMinorUnits, _ := r.Amount().MinorUnits()
 	_, err := tx.ExecContext(
@@ -58,10 +65,12 @@ func (d *DefaultRecordDao) SaveTx(ctx context.Context, accountId ledger.AccountI
 			currency, 
 			amount_minor_units, 
 			date, type, 
+			occurred_at,
 			source_account_id, 
 			beneficiary_id, 
 			beneficiary_type,
 			transfer_reference, 
+			exchange_rate,
 			created_by, 
 			created_at, 
 			last_modified_by, 
@@ -72,7 +81,7 @@ func (d *DefaultRecordDao) SaveTx(ctx context.Context, accountId ledger.AccountI
 			$2, 
 			$3, 
 			$4, 
-			(SELECT currency FROM budget.account WHERE id = $5), 
+			$5, 
 			$6, 
 			$7, 
 			$8, 
@@ -84,16 +93,19 @@ func (d *DefaultRecordDao) SaveTx(ctx context.Context, accountId ledger.AccountI
 			$14, 
 			$15, 
 			$16,
-			$17
+			$17,
+			$18,
+			$19
 		)`,
 		r.Id(),
 		accountId,
 		r.Category().Id(),
 		r.Note(),
-		accountId,
+		r.Amount().Currency().CurrencyCode(),
 		amountMinorUnits,
 		r.DateUTC(),
 		r.Type(),
+		r.OccurredAtUTC(),
 		sql.NullInt64{
 			Int64: int64(r.SourceAccountId()),
 			Valid: r.SourceAccountId() != 0,
@@ -110,6 +122,10 @@ func (d *DefaultRecordDao) SaveTx(ctx context.Context, accountId ledger.AccountI
 			String: string(r.TransferReference()),
 			Valid:  len(r.TransferReference()) != 0,
 		},
+		sql.NullString{
+			String: string(r.ExchangeRate()),
+			Valid:  len(r.ExchangeRate()) != 0,
+		},
 		r.CreatedBy().String(),
 		r.CreatedAtUTC(),
 		sql.NullString{
@@ -125,85 +141,466 @@ func (d *DefaultRecordDao) SaveTx(ctx context.Context, accountId ledger.AccountI
 	if err != nil {
 		return fmt.Errorf("Failed to save record. Reason: %w", err)
 	}
+	return d.saveSplitsTx(ctx, r, tx)
+}
+
+// saveSplitsTx replaces the lines of a split record.
+func (d *DefaultRecordDao) saveSplitsTx(ctx context.Context, r ledger.Record, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM budget.record_split WHERE record_id = $1`, r.Id()); err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save record splits", err)
+	}
+
+	for line, split := range r.Splits() {
+		amountMinorUnits, _ := split.Amount().MinorUnits()
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO budget.record_split (
+				record_id, 
+				line, 
+				category_id, 
+				note, 
+				amount_minor_units
+			) VALUES (
+				$1, 
+				$2, 
+				$3, 
+				$4, 
+				$5
+			)`,
+			r.Id(),
+			line+1,
+			split.Category().Id(),
+			split.Note(),
+			amountMinorUnits,
+		); err != nil {
+			return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save record splits", err)
+		}
+	}
 	return nil
 }
 
-func (d *DefaultRecordDao) Search(accountId ledger.AccountId, search dao.RecordSearch) (ledger.Records, error) {
-	checkError := func(err error) bool {
-		if err != nil {
-			log.Printf("Error searching for records with criteria %v account id: %d. Reason: %s", search, accountId, err)
-			return true
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// loadSplits loads the lines of the split records among the given records.
+// q is either the database or the transaction the records were loaded in.
+func (d *DefaultRecordDao) loadSplits(ctx context.Context, q queryer, records []recordRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	recordIds := make([]int64, 0, len(records))
+	indexes := make(map[ledger.RecordId]int, len(records))
+	for i, rr := range records {
+		recordIds = append(recordIds, int64(rr.id))
+		indexes[rr.id] = i
+	}
+
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT 
+			s.record_id, 
+			s.note, 
+			s.amount_minor_units, 
+			r.currency, 
+			c.id, 
+			c.name, 
+			c.created_by, 
+			c.created_at, 
+			c.last_modified_by, 
+			c.last_modified_at, 
+			c.version 
+		FROM budget.record_split s 
+		JOIN budget.record r ON r.id = s.record_id 
+		JOIN budget.category c ON c.id = s.category_id 
+		WHERE s.record_id = ANY($1) 
+		ORDER BY s.record_id, s.line`,
+		pq.Array(recordIds),
+	)
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load record splits", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			recordId         ledger.RecordId
+			note             string
+			amountMinorUnits int64
+			currency         string
+			cr               categoryRecord
+			category         ledger.Category
+			amount           ledger.Money
+			split            ledger.RecordSplit
+		)
+		if err = rows.Scan(
+			&recordId,
+			&note,
+			&amountMinorUnits,
+			&currency,
+			&cr.id,
+			&cr.name,
+			&cr.createdBy,
+			&cr.createdAt,
+			&cr.modifiedBy,
+			&cr.modifiedAt,
+			&cr.version,
+		); err != nil {
+			return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load record splits", err)
 		}
-		return false
+		if category, err = ledger.NewCategoryFromRecord(cr); err != nil {
+			return err
+		}
+		if amount, err = ledger.NewMoney(currency, amountMinorUnits); err != nil {
+			return err
+		}
+		if split, err = ledger.NewRecordSplit(note, category, amount); err != nil {
+			return err
+		}
+		i := indexes[recordId]
+		records[i].splits = append(records[i].splits, split)
 	}
+	return rows.Err()
+}
 
-	if search.FromDate == nil {
-		defaultFromDate := ledger.CurrentCalendarMonth().FirstDay()
-		search.FromDate = &defaultFromDate
+// checkAccountCurrency returns an error if the amount is not in the currency of the account it is recorded in.
+func (d *DefaultRecordDao) checkAccountCurrency(ctx context.Context, accountId ledger.AccountId, amount ledger.Money, tx *sql.Tx) error {
+	var accountCurrency string
+	err := tx.QueryRowContext(ctx, `SELECT currency FROM budget.account WHERE id = $1`, accountId).Scan(&accountCurrency)
+	if err != nil {
+		log.Printf("Failed to load currency of account id %d. Reason: %s", accountId, err)
+		if err == sql.ErrNoRows {
+			return pkg.ValidationErrorWithError(pkg.ErrAccountNotFound, "Account not found", err)
+		}
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Error loading account", err)
 	}
 
-	if search.ToDate == nil {
-		defaultToDate := time.Now().UTC()
-		search.ToDate = &defaultToDate
+	if amount.Currency().CurrencyCode() != accountCurrency {
+		return pkg.ValidationErrorWithFields(
+			pkg.ErrAmountMismatchingCurrencies,
+			fmt.Sprintf("Amount in %s can not be recorded in account %d", amount.Currency().CurrencyCode(), accountId),
+			nil,
+			map[string]string{"amount": fmt.Sprintf("amount must be in %s, the currency of the account", accountCurrency)},
+		)
 	}
+	return nil
+}
+
+var recordColumns = []string{
+	"r.id",
+	"r.category_id",
+	"c.name",
+	"c.created_by",
+	"c.created_at",
+	"c.last_modified_by",
+	"c.last_modified_at",
+	"c.version",
+	"r.note",
+	"r.currency",
+	"r.amount_minor_units",
+	"r.date",
+	"r.occurred_at",
+	"r.type",
+	"r.source_account_id",
+	"r.beneficiary_id",
+	"r.beneficiary_type",
+	"r.transfer_reference",
+	"r.exchange_rate",
+	"r.created_by",
+	"r.created_at",
+	"r.last_modified_by",
+	"r.last_modified_at",
+	"r.version",
+}
 
+// scanRecord reads a row selected with recordColumns
+func scanRecord(row interface{ Scan(...interface{}) error }) (recordRecord, error) {
+	var rr recordRecord
+	err := row.Scan(
+		&rr.id,
+		&rr.category.id,
+		&rr.category.name,
+		&rr.category.createdBy,
+		&rr.category.createdAt,
+		&rr.category.modifiedBy,
+		&rr.category.modifiedAt,
+		&rr.category.version,
+		&rr.note,
+		&rr.currency,
+		&rr.amountMinorUnits,
+		&rr.date,
+		&rr.occurredAt,
+		&rr.recordType,
+		&rr.sourceAccountId,
+		&rr.beneficiaryId,
+		&rr.beneficiaryType,
+		&rr.transferReference,
+		&rr.exchangeRate,
+		&rr.createdBy,
+		&rr.createdAt,
+		&rr.modifiedBy,
+		&rr.modifiedAt,
+		&rr.version,
+	)
+	return rr, err
+}
+
+func (d *DefaultRecordDao) GetRecordByIdTx(ctx context.Context, id ledger.RecordId, accountId ledger.AccountId, tx *sql.Tx) (ledger.Record, error) {
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
-	query := psql.Select(
-		"r.id",
-		"r.category_id",
-		"c.name",
-		"c.created_by",
-		"c.created_at",
-		"c.last_modified_by",
-		"c.last_modified_at",
-		"c.version",
-		"r.note",
-		"r.currency",
-		"r.amount_minor_units",
-		"r.date",
-		"r.type",
-		"r.source_account_id",
-		"r.beneficiary_id",
-		"r.beneficiary_type",
-		"r.transfer_reference",
-		"r.created_by",
-		"r.created_at",
-		"r.last_modified_by",
-		"r.last_modified_at",
-		"r.version",
-	).
+	query := psql.Select(recordColumns...).
 		From("budget.record r").
 		LeftJoin("budget.category c ON c.id = r.category_id").
 		Where(sq.Eq{
+			"r.id":         id,
 			"r.account_id": accountId,
 		})
 
-	if len(search.CategoryNames) != 0 {
-		query = query.Where(
-			sq.Eq{"c.name": search.CategoryNames},
+	rr, err := scanRecord(query.RunWith(tx).QueryRowContext(ctx))
+	if err != nil {
+		log.Printf("Failed to load record id %d for account %d. Reason: %s", id, accountId, err)
+		if err == sql.ErrNoRows {
+			return ledger.Record{}, pkg.ValidationErrorWithError(pkg.ErrRecordNotFound, "Record not found", err)
+		}
+		return ledger.Record{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Error loading record", err)
+	}
+
+	splitRecords := []recordRecord{rr}
+	if err = d.loadSplits(ctx, tx, splitRecords); err != nil {
+		return ledger.Record{}, err
+	}
+
+	return ledger.NewRecordFromRecord(splitRecords[0])
+}
+
+// UpdateTx saves the changes made to a record.
+// The record is only updated if its version in the database is still the version of r; otherwise a version conflict is returned.
+func (d *DefaultRecordDao) UpdateTx(ctx context.Context, accountId ledger.AccountId, r ledger.Record, tx *sql.Tx) error {
+	if r.Type() == ledger.Transfer {
+		return pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, "Both records of a transfer must be updated together", nil, nil)
+	}
+
+	if err := d.checkAccountCurrency(ctx, accountId, r.Amount(), tx); err != nil {
+		return err
+	}
+
+	amountMinorUnits, _ := r.Amount().MinorUnits()
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE budget.record SET 
+			category_id = $1, 
+			note = $2, 
+			amount_minor_units = $3, 
+			date = $4, 
+			type = $5, 
+			last_modified_by = $6, 
+			occurred_at = $10 
+		WHERE 
+			id = $7 
+			AND account_id = $8 
+			AND version = $9`,
+		r.Category().Id(),
+		r.Note(),
+		amountMinorUnits,
+		r.DateUTC(),
+		r.Type(),
+		r.ModifiedBy().String(),
+		r.Id(),
+		accountId,
+		r.Version(),
+		r.OccurredAtUTC(),
+	)
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update record", err)
+	}
+	if err = d.checkVersionedChange(result, r.Id()); err != nil {
+		return err
+	}
+	return d.saveSplitsTx(ctx, r, tx)
+}
+
+// DeleteTx deletes a record, provided its version in the database is still the given version.
+// The records of a transfer are not deleted; use DeleteTransferPairTx instead.
+func (d *DefaultRecordDao) DeleteTx(ctx context.Context, accountId ledger.AccountId, id ledger.RecordId, version ledger.Version, tx *sql.Tx) error {
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM budget.record WHERE id = $1 AND account_id = $2 AND version = $3 AND transfer_reference IS NULL`,
+		id,
+		accountId,
+		version,
+	)
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to delete record", err)
+	}
+	return d.checkVersionedChange(result, id)
+}
+
+// GetTransferPairTx loads both records of a transfer between accounts of the given user.
+func (d *DefaultRecordDao) GetTransferPairTx(ctx context.Context, reference ledger.TransferReference, userId ledger.UserId, tx *sql.Tx) (ledger.TransferPair, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query := psql.Select(recordColumns...).
+		From("budget.record r").
+		LeftJoin("budget.category c ON c.id = r.category_id").
+		Join("budget.account a ON a.id = r.account_id").
+		Where(sq.Eq{
+			"r.transfer_reference": reference,
+			"a.user_id":            userId,
+		}).
+		OrderBy("r.id")
+
+	rows, err := query.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		log.Printf("Failed to load transfer %q for user %d. Reason: %s", reference, userId, err)
+		return ledger.TransferPair{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Error loading transfer", err)
+	}
+	defer rows.Close()
+
+	legs := make([]ledger.Record, 0, 2)
+	for rows.Next() {
+		var (
+			rr     recordRecord
+			record ledger.Record
 		)
+		if rr, err = scanRecord(rows); err != nil {
+			return ledger.TransferPair{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Error loading transfer", err)
+		}
+		if record, err = ledger.NewRecordFromRecord(rr); err != nil {
+			return ledger.TransferPair{}, err
+		}
+		legs = append(legs, record)
 	}
 
-	if len(search.RecordTypes) != 0 {
-		query = query.Where(sq.Eq{"r.type": search.RecordTypes})
+	if len(legs) != 2 {
+		log.Printf("Expected 2 records for transfer %q of user %d. Found %d", reference, userId, len(legs))
+		return ledger.TransferPair{}, pkg.ValidationErrorWithError(pkg.ErrRecordNotFound, "Transfer not found", sql.ErrNoRows)
 	}
 
-	if len(search.SearchTerm) != 0 {
-		keywords := strings.Split(search.SearchTerm, " ")
+	return ledger.NewTransferPair(legs[0], legs[1])
+}
 
-		likes := make([]sq.Sqlizer, 0, len(keywords))
-		for _, keyword := range keywords {
-			if len(keyword) == 0 {
-				continue
-			}
-			if strings.Contains(keyword, "\"") || strings.Contains(keyword, ";") {
-				// Crappy check against sql injection
-				continue
-			}
-			likes = append(likes, sq.Like{"r.note": fmt.Sprintf("%%%s%%", keyword)})
+// UpdateTransferPairTx saves the changes made to both records of a transfer.
+// When the beneficiary changes, the credit is moved to the new beneficiary account.
+// The amount of each record must be in the currency of the account it is recorded in.
+func (d *DefaultRecordDao) UpdateTransferPairTx(ctx context.Context, transfer ledger.TransferPair, tx *sql.Tx) error {
+	for _, leg := range []struct {
+		record    ledger.Record
+		accountId ledger.AccountId
+	}{
+		{transfer.Debit(), transfer.SourceAccountId()},
+		{transfer.Credit(), transfer.BeneficiaryId()},
+	} {
+		if err := d.checkAccountCurrency(ctx, leg.accountId, leg.record.Amount(), tx); err != nil {
+			return err
 		}
 
-		query = query.Where(sq.Or(likes))
+		amountMinorUnits, _ := leg.record.Amount().MinorUnits()
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE budget.record SET 
+				account_id = $1, 
+				currency = $2, 
+				category_id = $3, 
+				note = $4, 
+				amount_minor_units = $5, 
+				date = $6, 
+				beneficiary_id = $7, 
+				beneficiary_type = $8, 
+				exchange_rate = $9, 
+				last_modified_by = $10, 
+				occurred_at = $14 
+			WHERE 
+				id = $11 
+				AND transfer_reference = $12 
+				AND version = $13`,
+			leg.accountId,
+			leg.record.Amount().Currency().CurrencyCode(),
+			leg.record.Category().Id(),
+			leg.record.Note(),
+			amountMinorUnits,
+			leg.record.DateUTC(),
+			leg.record.BeneficiaryId(),
+			leg.record.BeneficiaryType(),
+			sql.NullString{
+				String: string(leg.record.ExchangeRate()),
+				Valid:  len(leg.record.ExchangeRate()) != 0,
+			},
+			leg.record.ModifiedBy().String(),
+			leg.record.Id(),
+			leg.record.TransferReference(),
+			leg.record.Version(),
+			leg.record.OccurredAtUTC(),
+		)
+		if err != nil {
+			return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update transfer", err)
+		}
+		if err = d.checkVersionedChange(result, leg.record.Id()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteTransferPairTx deletes both records of a transfer, provided neither has changed since it was loaded.
+func (d *DefaultRecordDao) DeleteTransferPairTx(ctx context.Context, transfer ledger.TransferPair, tx *sql.Tx) error {
+	for _, leg := range []ledger.Record{transfer.Debit(), transfer.Credit()} {
+		result, err := tx.ExecContext(
+			ctx,
+			`DELETE FROM budget.record WHERE id = $1 AND transfer_reference = $2 AND version = $3`,
+			leg.Id(),
+			leg.TransferReference(),
+			leg.Version(),
+		)
+		if err != nil {
+			return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to delete transfer", err)
+		}
+		if err = d.checkVersionedChange(result, leg.Id()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DefaultRecordDao) checkVersionedChange(result sql.Result, id ledger.RecordId) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to change record", err)
+	}
+	if rowsAffected == 0 {
+		return pkg.ValidationErrorWithFields(pkg.ErrRecordVersionConflict, fmt.Sprintf("Record %d was changed by another request", id), nil, nil)
+	}
+	return nil
+}
+
+func (d *DefaultRecordDao) Search(accountId ledger.AccountId, search dao.RecordSearch) (ledger.Records, error) {
+	checkError := func(err error) bool {
+		if err != nil {
+			log.Printf("Error searching for records with criteria %v account id: %d. Reason: %s", search, accountId, err)
+			return true
+		}
+		return false
+	}
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query := withRecordSearch(psql.Select(recordColumns...), accountId, search)
+
+	// Records are listed newest first.
+	// When paging backwards, the records closest to the cursor are the oldest ones, so the order is reversed.
+	newestFirst := search.Before == nil
+	if search.After != nil {
+		query = query.Where(sq.Expr("(r.date, r.id) < (?, ?)", search.After.Date.Format("2006-01-02"), search.After.Id))
+	}
+	if search.Before != nil {
+		query = query.Where(sq.Expr("(r.date, r.id) > (?, ?)", search.Before.Date.Format("2006-01-02"), search.Before.Id))
+	}
+
+	if newestFirst {
+		query = query.OrderBy("r.date DESC", "r.id DESC")
+	} else {
+		query = query.OrderBy("r.date ASC", "r.id ASC")
+	}
+
+	if search.Limit > 0 {
+		query = query.Limit(uint64(search.Limit))
 	}
 
 	var (
@@ -211,49 +608,29 @@ func (d *DefaultRecordDao) Search(accountId ledger.AccountId, search dao.RecordS
 		err  error
 	)
 
-	if rows, err = query.OrderBy("r.date DESC").
-		RunWith(d.db).
-		Query(); checkError(err) {
-		return ledger.Records{}, nil
+	if rows, err = query.RunWith(d.db).Query(); checkError(err) {
+		return ledger.Records{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to search records", err)
 	}
 
 	defer rows.Close()
 
-	entities := make([]ledger.Record, 0)
+	recordRecords := make([]recordRecord, 0)
 	for rows.Next() {
-
-		var (
-			rr     recordRecord
-			record ledger.Record
-		)
-		if err = rows.Scan(
-			&rr.id,
-			&rr.category.id,
-			&rr.category.name,
-			&rr.category.createdBy,
-			&rr.category.createdAt,
-			&rr.category.modifiedBy,
-			&rr.category.modifiedAt,
-			&rr.category.version,
-			&rr.note,
-			&rr.currency,
-			&rr.amountMinorUnits,
-			&rr.date,
-			&rr.recordType,
-			&rr.sourceAccountId,
-			&rr.beneficiaryId,
-			&rr.beneficiaryType,
-			&rr.transferReference,
-			&rr.createdBy,
-			&rr.createdAt,
-			&rr.modifiedBy,
-			&rr.modifiedAt,
-			&rr.version,
-		); err != nil {
+		var rr recordRecord
+		if rr, err = scanRecord(rows); err != nil {
 			log.Printf("Error processing records for account %d. Reason: %s", accountId, err)
 			continue
 		}
+		recordRecords = append(recordRecords, rr)
+	}
 
+	if err = d.loadSplits(context.Background(), d.db, recordRecords); err != nil {
+		return ledger.Records{}, err
+	}
+
+	entities := make([]ledger.Record, 0, len(recordRecords))
+	for _, rr := range recordRecords {
+		var record ledger.Record
 		if record, err = ledger.NewRecordFromRecord(rr); err != nil {
 			log.Printf("Error loading record with id: %d from database. Reason: %s", rr.id, err)
 			continue
@@ -262,144 +639,334 @@ func (d *DefaultRecordDao) Search(accountId ledger.AccountId, search dao.RecordS
 		entities = append(entities, record)
 	}
 
-	records := ledger.Records(entities)
-	sort.Sort(records)
+	if !newestFirst {
+		for i, j := 0, len(entities)-1; i < j; i, j = i+1, j-1 {
+			entities[i], entities[j] = entities[j], entities[i]
+		}
+	}
 
-	return records, nil
+	return ledger.Records(entities), nil
 }
 
-func (d *DefaultRecordDao) GetRecordsForLastPeriod(ctx context.Context, accountId ledger.AccountId, tx *sql.Tx) (ledger.Records, error) {
-	checkError := func(err error) bool {
+// forEachRecordPageSize is the number of records loaded at a time by ForEachRecord when the search has no limit
+const forEachRecordPageSize = 500
+
+func (d *DefaultRecordDao) ForEachRecord(ctx context.Context, accountId ledger.AccountId, search dao.RecordSearch, fn func(ledger.Record) error) error {
+	if search.Limit == 0 {
+		search.Limit = forEachRecordPageSize
+	}
+	if search.FromDate == nil {
+		defaultFromDate := search.Profile.CurrentMonth(time.Now()).FirstDay()
+		search.FromDate = &defaultFromDate
+	}
+
+	// Records are paged oldest first by loading the records that come before (i.e. are newer than) the last record of the previous page.
+	// Record ids start from 1, so the first page starts with the first record on the from date.
+	// Each page is listed newest first, so it is visited from its last record.
+	search.After = nil
+	cursor := dao.RecordCursor{Date: *search.FromDate, Id: 0}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		search.Before = &cursor
+		records, err := d.Search(accountId, search)
 		if err != nil {
-			log.Printf("Error loading records for last period for account id: %d. Reason: %s", accountId, err)
-			return true
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+
+		for i := len(records) - 1; i >= 0; i-- {
+			record := records[i]
+			if err = fn(record); err != nil {
+				return err
+			}
+			if isAfterCursor(record, cursor) {
+				cursor = dao.RecordCursor{Date: record.DateUTC(), Id: record.Id()}
+			}
+		}
+	}
+}
+
+// isAfterCursor reports whether the record comes after the cursor in a listing of records, oldest first.
+func isAfterCursor(record ledger.Record, cursor dao.RecordCursor) bool {
+	recordDate, cursorDate := record.DateUTC().Format("2006-01-02"), cursor.Date.Format("2006-01-02")
+	return recordDate > cursorDate || (recordDate == cursorDate && record.Id() > cursor.Id)
+}
+
+func (d *DefaultRecordDao) Summarize(accountId ledger.AccountId, search dao.RecordSearch) (dao.RecordsSummary, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query := withRecordSearch(psql.Select(
+		fmt.Sprintf("COALESCE(SUM(CASE WHEN r.type = '%s' THEN r.amount_minor_units ELSE 0 END), 0)", ledger.Income),
+		fmt.Sprintf("COALESCE(SUM(CASE WHEN r.type = '%s' THEN ABS(r.amount_minor_units) ELSE 0 END), 0)", ledger.Expense),
+		fmt.Sprintf("COALESCE(SUM(CASE WHEN r.type = '%s' AND r.beneficiary_type = '%s' THEN ABS(r.amount_minor_units) ELSE 0 END), 0)", ledger.Transfer, ledger.AccountTypeSaving),
+	).Column(sq.Expr("(SELECT a.currency FROM budget.account a WHERE a.id = ?)", accountId)), accountId, search)
+
+	var (
+		totalIncome   int64
+		totalExpenses int64
+		totalSavings  int64
+		currency      sql.NullString
+		summary       dao.RecordsSummary
+		err           error
+	)
+
+	if err = query.RunWith(d.db).QueryRow().Scan(&totalIncome, &totalExpenses, &totalSavings, &currency); err != nil {
+		log.Printf("Error summarizing records with criteria %v account id: %d. Reason: %s", search, accountId, err)
+		return dao.RecordsSummary{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to summarize records", err)
+	}
+
+	if !currency.Valid {
+		return dao.RecordsSummary{}, pkg.ValidationErrorWithError(pkg.ErrAccountNotFound, "Account not found", sql.ErrNoRows)
+	}
+
+	if summary.TotalIncome, err = ledger.NewMoney(currency.String, totalIncome); err != nil {
+		return dao.RecordsSummary{}, err
+	}
+	if summary.TotalExpenses, err = ledger.NewMoney(currency.String, totalExpenses); err != nil {
+		return dao.RecordsSummary{}, err
+	}
+	if summary.TotalSavings, err = ledger.NewMoney(currency.String, totalSavings); err != nil {
+		return dao.RecordsSummary{}, err
+	}
+	return summary, nil
+}
+
+// withRecordSearch applies the filters of a search to a query on the records of an account.
+// The cursor and limit of the search are not applied.
+func withRecordSearch(query sq.SelectBuilder, accountId ledger.AccountId, search dao.RecordSearch) sq.SelectBuilder {
+	if search.FromDate == nil {
+		defaultFromDate := search.Profile.CurrentMonth(time.Now()).FirstDay()
+		search.FromDate = &defaultFromDate
+	}
+
+	if search.ToDate == nil {
+		defaultToDate := search.Profile.LocalDate(time.Now())
+		search.ToDate = &defaultToDate
+	}
+
+	query = query.
+		From("budget.record r").
+		LeftJoin("budget.category c ON c.id = r.category_id").
+		LeftJoin("budget.account b ON b.id = r.beneficiary_id").
+		Where(sq.Eq{
+			"r.account_id": accountId,
+		})
+
+	if len(search.CategoryNames) != 0 {
+		// Split records match when any of their lines is in one of the categories
+		query = query.Where(sq.Or{
+			sq.Eq{"c.name": search.CategoryNames},
+			sq.Expr(
+				"r.id IN (SELECT s.record_id FROM budget.record_split s JOIN budget.category sc ON sc.id = s.category_id WHERE sc.name = ANY(?))",
+				pq.Array(search.CategoryNames),
+			),
+		})
+	}
+
+	if len(search.RecordTypes) != 0 {
+		query = query.Where(sq.Eq{"r.type": search.RecordTypes})
+	}
+
+	if len(search.BeneficiaryAccountNames) != 0 {
+		query = query.Where(sq.Eq{"b.name": search.BeneficiaryAccountNames})
+	}
+
+	if len(search.CreatedByKinds) != 0 {
+		createdBy := make(sq.Or, 0, len(search.CreatedByKinds))
+		for _, kind := range search.CreatedByKinds {
+			createdBy = append(createdBy, createdByKindCondition(kind))
+		}
+		query = query.Where(createdBy)
+	}
+
+	query = query.Where(sq.GtOrEq{"r.date": search.FromDate.Format("2006-01-02")}).
+		Where(sq.LtOrEq{"r.date": search.ToDate.Format("2006-01-02")})
+
+	if len(search.SearchTerm) != 0 {
+		keywords := strings.Split(search.SearchTerm, " ")
+
+		likes := make([]sq.Sqlizer, 0, len(keywords))
+		for _, keyword := range keywords {
+			if len(keyword) == 0 {
+				continue
+			}
+			if strings.Contains(keyword, "\"") || strings.Contains(keyword, ";") {
+				// Crappy check against sql injection
+				continue
+			}
+			likes = append(likes, sq.Like{"r.note": fmt.Sprintf("%%%s%%", keyword)})
+		}
+
+		if len(likes) != 0 {
+			query = query.Where(sq.Or(likes))
 		}
-		return false
 	}
 
-	var rows *sql.Rows
-	var err error
-	if rows, err = d.db.QueryContext(ctx,
+	return query
+}
+
+// createdByKindCondition matches the created_by values written for a kind of actor (see ledger.UpdatedBy)
+func createdByKindCondition(kind ledger.UpdatedByKind) sq.Sqlizer {
+	switch kind {
+	case ledger.UpdatedByKindUser:
+		return sq.Like{"r.created_by": "UserId:%"}
+	case ledger.UpdatedByKindTask:
+		return sq.Like{"r.created_by": "CronTask:%"}
+	case ledger.UpdatedByKindImport:
+		return sq.Like{"r.created_by": "Import:%"}
+	case ledger.UpdatedByKindSystem:
+		return sq.Eq{"r.created_by": "System"}
+	}
+	return sq.Expr("FALSE")
+}
+
+func (d *DefaultRecordDao) GetLastPeriod(ctx context.Context, accountId ledger.AccountId, profile ledger.UserProfile, tx *sql.Tx) (ledger.Period, error) {
+	var max sql.NullTime
+	if err := d.db.QueryRowContext(ctx,
 		`SELECT 
 			MAX(r.date) 
 		FROM 
 			budget.record r 
 		WHERE 
-			r.account_id = $1`, accountId); checkError(err) {
-		return ledger.Records{}, nil
+			r.account_id = $1`, accountId).Scan(&max); err != nil {
+		log.Printf("Error loading last period for account id: %d. Reason: %s", accountId, err)
+		return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load last period", err)
 	}
 
-	defer rows.Close()
-	rows.Next()
+	if !max.Valid {
+		return profile.CurrentMonth(time.Now()), nil
+	}
+	return ledger.MakeCalendarMonthFromDate(max.Time), nil
+}
 
-	var max sql.NullTime
-	if err = rows.Scan(&max); max.Valid && checkError(err) {
+func (d *DefaultRecordDao) GetRecordsForLastPeriod(ctx context.Context, accountId ledger.AccountId, profile ledger.UserProfile, tx *sql.Tx) (ledger.Records, error) {
+	period, err := d.GetLastPeriod(ctx, accountId, profile, tx)
+	if err != nil {
 		return ledger.Records{}, err
 	}
+	return d.GetRecordsForPeriod(accountId, period)
+}
 
-	return d.GetRecordsForMonth(accountId, ledger.MakeCalendarMonthFromDate(max.Time))
+func (d *DefaultRecordDao) GetRecordsForPeriod(queryId ledger.AccountId, period ledger.Period) (ledger.Records, error) {
+	fromDate := period.FirstDay()
+	toDate := period.LastDay()
+	return d.Search(queryId, dao.RecordSearch{
+		FromDate: &fromDate,
+		ToDate:   &toDate,
+	})
 }
 
-func (d *DefaultRecordDao) GetRecordsForMonth(queryId ledger.AccountId, month ledger.CalendarMonth) (ledger.Records, error) {
-	fromDate := month.FirstDay()
-	toDate := month.LastDay()
-	rows, err := d.db.Query(`
-		SELECT 
-			r.id, 
-			r.category_id, 
-			c.name,
-			c.created_by,
-			c.created_at,
-			c.last_modified_by,
-			c.last_modified_at,
-			c.version,
-			r.note, 
-			r.currency, 
-			r.amount_minor_units, 
-			r.date, 
-			r.type, 
-			r.source_account_id,
-			r.beneficiary_id,
-			r.beneficiary_type,
-			r.transfer_reference,
-			r.created_by,
-			r.created_at,
-			r.last_modified_by,
-			r.last_modified_at,
-			r.version
-		FROM 
-			budget.record r 
-		LEFT JOIN 
-			budget.account a 
-		ON 
-			r.account_id = a.id 
-		LEFT JOIN 
-			budget.category c 
-		ON 
-			r.category_id = c.id 
-		WHERE 
-			a.id = $1 
-			AND r.date >= $2 
-			AND r.date <= $3 
-		ORDER 
-			BY r.date DESC`,
-		queryId,
-		fromDate.Format("2006-01-02"),
-		toDate.Format("2006-01-02"),
+func (d *DefaultRecordDao) SaveExternalIdTx(ctx context.Context, accountId ledger.AccountId, externalId string, recordId ledger.RecordId, tx *sql.Tx) (bool, error) {
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO budget.record_external_id (
+			account_id,
+			external_id,
+			record_id
+		) VALUES (
+			$1,
+			$2,
+			$3
+		) ON CONFLICT DO NOTHING`,
+		accountId,
+		externalId,
+		sql.NullInt64{
+			Int64: int64(recordId),
+			Valid: recordId != 0,
+		},
 	)
-	if err == sql.ErrNoRows {
-		return ledger.Records{}, nil
-	} else if err != nil {
-		return nil, fmt.Errorf("Records for account id %d not found. Reason: %w", queryId, err)
+	if err != nil {
+		return false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save external id of record", err)
 	}
-	defer rows.Close()
 
-	entities := make([]ledger.Record, 0)
-	for rows.Next() {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save external id of record", err)
+	}
+	return rowsAffected == 1, nil
+}
 
-		var (
-			rr     recordRecord
-			record ledger.Record
-		)
+func (d *DefaultRecordDao) GetDuplicateCandidatesTx(ctx context.Context, accountId ledger.AccountId, amount ledger.Money, from time.Time, to time.Time, tx *sql.Tx) (ledger.Records, error) {
+	minorUnits, err := amount.MinorUnits()
+	if err != nil {
+		return ledger.Records{}, err
+	}
 
-		if err = rows.Scan(
-			&rr.id,
-			&rr.category.id,
-			&rr.category.name,
-			&rr.category.createdBy,
-			&rr.category.createdAt,
-			&rr.category.modifiedBy,
-			&rr.category.modifiedAt,
-			&rr.category.version,
-			&rr.note,
-			&rr.currency,
-			&rr.amountMinorUnits,
-			&rr.date,
-			&rr.recordType,
-			&rr.sourceAccountId,
-			&rr.beneficiaryId,
-			&rr.beneficiaryType,
-			&rr.transferReference,
-			&rr.createdBy,
-			&rr.createdAt,
-			&rr.modifiedBy,
-			&rr.modifiedAt,
-			&rr.version,
-		); err != nil {
-			log.Printf("Error processing records for account %d. Reason: %s", queryId, err)
-			continue
-		}
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query := psql.Select(recordColumns...).
+		From("budget.record r").
+		LeftJoin("budget.category c ON c.id = r.category_id").
+		Where(sq.Eq{
+			"r.account_id":         accountId,
+			"r.currency":           amount.Currency().CurrencyCode(),
+			"r.amount_minor_units": minorUnits,
+		}).
+		Where(sq.GtOrEq{"r.date": from.Format("2006-01-02")}).
+		Where(sq.LtOrEq{"r.date": to.Format("2006-01-02")}).
+		OrderBy("r.date DESC", "r.id DESC")
+
+	records, err := d.queryRecordsTx(ctx, query, tx)
+	if err != nil {
+		log.Printf("Failed to load duplicate candidates for account %d. Reason: %s", accountId, err)
+		return ledger.Records{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load duplicate candidates", err)
+	}
+	return records, nil
+}
 
-		if record, err = ledger.NewRecordFromRecord(rr); err != nil {
-			log.Printf("Error loading record with id: %d from database. Reason: %s", rr.id, err)
-			continue
-		}
+// GetRecordsInCategoryTx returns the records of the account in the category, latest first.
+// Records whose splits are in the category are not returned.
+func (d *DefaultRecordDao) GetRecordsInCategoryTx(ctx context.Context, accountId ledger.AccountId, categoryId ledger.CategoryId, tx *sql.Tx) (ledger.Records, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query := psql.Select(recordColumns...).
+		From("budget.record r").
+		LeftJoin("budget.category c ON c.id = r.category_id").
+		Where(sq.Eq{
+			"r.account_id":  accountId,
+			"r.category_id": categoryId,
+		}).
+		OrderBy("r.date DESC", "r.id DESC")
 
-		entities = append(entities, record)
+	records, err := d.queryRecordsTx(ctx, query, tx)
+	if err != nil {
+		log.Printf("Failed to load records of category %d for account %d. Reason: %s", categoryId, accountId, err)
+		return ledger.Records{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load records of category", err)
+	}
+	return records, nil
+}
+
+// queryRecordsTx loads the records selected with recordColumns, along with their splits
+func (d *DefaultRecordDao) queryRecordsTx(ctx context.Context, query sq.SelectBuilder, tx *sql.Tx) (ledger.Records, error) {
+	rows, err := query.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		return ledger.Records{}, err
+	}
+
+	defer rows.Close()
+
+	recordRecords := make([]recordRecord, 0)
+	for rows.Next() {
+		var rr recordRecord
+		if rr, err = scanRecord(rows); err != nil {
+			return ledger.Records{}, err
+		}
+		recordRecords = append(recordRecords, rr)
 	}
 
-	records := ledger.Records(entities)
-	sort.Sort(records)
+	if err = d.loadSplits(ctx, tx, recordRecords); err != nil {
+		return ledger.Records{}, err
+	}
 
+	records := make(ledger.Records, 0, len(recordRecords))
+	for _, rr := range recordRecords {
+		var record ledger.Record
+		if record, err = ledger.NewRecordFromRecord(rr); err != nil {
+			return ledger.Records{}, err
+		}
+		records = append(records, record)
+	}
 	return records, nil
 }
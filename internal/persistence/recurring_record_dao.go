@@ -0,0 +1,454 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+type DefaultRecurringRecordDao struct {
+	RootDao
+}
+
+func MustOpenRecurringRecordDao(db *sql.DB) dao.RecurringRecordDao {
+	return &DefaultRecurringRecordDao{RootDao{db}}
+}
+
+func (d *DefaultRecurringRecordDao) NewRecurringRecordId(tx *sql.Tx) (ledger.RecurringRecordId, error) {
+	var id ledger.RecurringRecordId
+	err := tx.QueryRow("SELECT nextval('budget.recurring_record_id')").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to assign recurring record id. Reason: %w", err)
+	}
+	return id, err
+}
+
+// SaveTx saves a new recurring record of the given user.
+// The amount of the recurring record must be in the currency of its account.
+func (d *DefaultRecurringRecordDao) SaveTx(ctx context.Context, userId ledger.UserId, rr ledger.RecurringRecord, tx *sql.Tx) error {
+	if err := d.checkAccount(ctx, userId, rr, tx); err != nil {
+		return err
+	}
+
+	epoch := time.Time{}
+	amountMinorUnits, _ := rr.Amount().MinorUnits()
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO budget.recurring_record (
+			id,
+			user_id,
+			account_id,
+			category_id,
+			note,
+			currency,
+			amount_minor_units,
+			type,
+			beneficiary_id,
+			exchange_rate,
+			frequency,
+			day_of_month,
+			start_date,
+			end_date,
+			next_date,
+			paused,
+			created_by,
+			created_at,
+			last_modified_by,
+			last_modified_at,
+			version
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8,
+			$9,
+			$10,
+			$11,
+			$12,
+			$13,
+			$14,
+			$15,
+			$16,
+			$17,
+			$18,
+			$19,
+			$20,
+			$21
+		)`,
+		rr.Id(),
+		userId,
+		rr.AccountId(),
+		rr.Category().Id(),
+		rr.Note(),
+		rr.Amount().Currency().CurrencyCode(),
+		amountMinorUnits,
+		rr.RecordType(),
+		sql.NullInt64{
+			Int64: int64(rr.BeneficiaryId()),
+			Valid: rr.BeneficiaryId() != 0,
+		},
+		sql.NullString{
+			String: string(rr.ExchangeRate()),
+			Valid:  len(rr.ExchangeRate()) != 0,
+		},
+		rr.Recurrence().Frequency(),
+		sql.NullInt32{
+			Int32: int32(rr.Recurrence().DayOfMonth()),
+			Valid: rr.Recurrence().DayOfMonth() != 0,
+		},
+		rr.StartDate(),
+		sql.NullTime{
+			Time:  rr.EndDate(),
+			Valid: !rr.EndDate().IsZero(),
+		},
+		sql.NullTime{
+			Time:  rr.NextDate(),
+			Valid: !rr.NextDate().IsZero(),
+		},
+		rr.IsPaused(),
+		rr.CreatedBy().String(),
+		rr.CreatedAtUTC(),
+		sql.NullString{
+			String: rr.ModifiedBy().String(),
+			Valid:  rr.ModifiedBy() != ledger.UpdatedBy{},
+		},
+		sql.NullTime{
+			Time:  rr.ModifiedAtUTC(),
+			Valid: epoch != rr.ModifiedAtUTC(),
+		},
+		rr.Version(),
+	)
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save recurring record", err)
+	}
+	return nil
+}
+
+// UpdateTx saves the changes made to a recurring record.
+// The recurring record is only updated if its version in the database is still the version of rr; otherwise a version conflict is returned.
+func (d *DefaultRecurringRecordDao) UpdateTx(ctx context.Context, userId ledger.UserId, rr ledger.RecurringRecord, tx *sql.Tx) error {
+	if err := d.checkAccount(ctx, userId, rr, tx); err != nil {
+		return err
+	}
+
+	amountMinorUnits, _ := rr.Amount().MinorUnits()
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE budget.recurring_record SET
+			category_id = $1,
+			note = $2,
+			currency = $3,
+			amount_minor_units = $4,
+			type = $5,
+			beneficiary_id = $6,
+			exchange_rate = $7,
+			frequency = $8,
+			day_of_month = $9,
+			start_date = $10,
+			end_date = $11,
+			next_date = $12,
+			paused = $13,
+			last_modified_by = $14
+		WHERE
+			id = $15
+			AND user_id = $16
+			AND version = $17`,
+		rr.Category().Id(),
+		rr.Note(),
+		rr.Amount().Currency().CurrencyCode(),
+		amountMinorUnits,
+		rr.RecordType(),
+		sql.NullInt64{
+			Int64: int64(rr.BeneficiaryId()),
+			Valid: rr.BeneficiaryId() != 0,
+		},
+		sql.NullString{
+			String: string(rr.ExchangeRate()),
+			Valid:  len(rr.ExchangeRate()) != 0,
+		},
+		rr.Recurrence().Frequency(),
+		sql.NullInt32{
+			Int32: int32(rr.Recurrence().DayOfMonth()),
+			Valid: rr.Recurrence().DayOfMonth() != 0,
+		},
+		rr.StartDate(),
+		sql.NullTime{
+			Time:  rr.EndDate(),
+			Valid: !rr.EndDate().IsZero(),
+		},
+		sql.NullTime{
+			Time:  rr.NextDate(),
+			Valid: !rr.NextDate().IsZero(),
+		},
+		rr.IsPaused(),
+		rr.ModifiedBy().String(),
+		rr.Id(),
+		userId,
+		rr.Version(),
+	)
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update recurring record", err)
+	}
+	return d.checkVersionedChange(result, rr.Id())
+}
+
+// DeleteTx deletes a recurring record, provided its version in the database is still the given version.
+// The records that were created from the recurring record are not deleted.
+func (d *DefaultRecurringRecordDao) DeleteTx(ctx context.Context, id ledger.RecurringRecordId, userId ledger.UserId, version ledger.Version, tx *sql.Tx) error {
+	result, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM budget.recurring_record WHERE id = $1 AND user_id = $2 AND version = $3`,
+		id,
+		userId,
+		version,
+	)
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to delete recurring record", err)
+	}
+	return d.checkVersionedChange(result, id)
+}
+
+// checkAccount checks that the accounts of a recurring record belong to the user
+// and that the amount is in the currency of the account the records are created in.
+func (d *DefaultRecurringRecordDao) checkAccount(ctx context.Context, userId ledger.UserId, rr ledger.RecurringRecord, tx *sql.Tx) error {
+	accountIds := []ledger.AccountId{rr.AccountId()}
+	if rr.BeneficiaryId() != ledger.NoBeneficiaryAccount {
+		accountIds = append(accountIds, rr.BeneficiaryId())
+	}
+
+	for _, accountId := range accountIds {
+		var currency string
+		if err := tx.QueryRowContext(
+			ctx,
+			`SELECT a.currency FROM budget.account a WHERE a.id = $1 AND a.user_id = $2`,
+			accountId,
+			userId,
+		).Scan(&currency); err != nil {
+			if err == sql.ErrNoRows {
+				return pkg.ValidationErrorWithError(pkg.ErrAccountNotFound, fmt.Sprintf("Account %d not found", accountId), err)
+			}
+			return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load currency of account", err)
+		}
+
+		if accountId == rr.AccountId() && currency != rr.Amount().Currency().CurrencyCode() {
+			return pkg.ValidationErrorWithFields(
+				pkg.ErrAmountMismatchingCurrencies,
+				fmt.Sprintf("Amount in %s can not be recorded in account %d", rr.Amount().Currency().CurrencyCode(), accountId),
+				nil,
+				map[string]string{"amount": fmt.Sprintf("amount must be in %s, the currency of the account", currency)},
+			)
+		}
+	}
+	return nil
+}
+
+func (d *DefaultRecurringRecordDao) checkVersionedChange(result sql.Result, id ledger.RecurringRecordId) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save recurring record", err)
+	}
+	if rowsAffected == 0 {
+		return pkg.ValidationErrorWithFields(pkg.ErrRecurringRecordVersionConflict, fmt.Sprintf("Recurring record %d was changed by another request", id), nil, nil)
+	}
+	return nil
+}
+
+var recurringRecordColumns = []string{
+	"rr.id",
+	"rr.account_id",
+	"rr.category_id",
+	"c.name",
+	"c.created_by",
+	"c.created_at",
+	"c.last_modified_by",
+	"c.last_modified_at",
+	"c.version",
+	"rr.note",
+	"rr.currency",
+	"rr.amount_minor_units",
+	"rr.type",
+	"rr.beneficiary_id",
+	"rr.exchange_rate",
+	"rr.frequency",
+	"rr.day_of_month",
+	"rr.start_date",
+	"rr.end_date",
+	"rr.next_date",
+	"rr.paused",
+	"rr.created_by",
+	"rr.created_at",
+	"rr.last_modified_by",
+	"rr.last_modified_at",
+	"rr.version",
+}
+
+// scanRecurringRecord reads a row selected with recurringRecordColumns, followed by any extra columns
+func scanRecurringRecord(row interface{ Scan(...interface{}) error }, extra ...interface{}) (recurringRecordRecord, error) {
+	var rr recurringRecordRecord
+	dest := []interface{}{
+		&rr.id,
+		&rr.accountId,
+		&rr.category.id,
+		&rr.category.name,
+		&rr.category.createdBy,
+		&rr.category.createdAt,
+		&rr.category.modifiedBy,
+		&rr.category.modifiedAt,
+		&rr.category.version,
+		&rr.note,
+		&rr.currency,
+		&rr.amountMinorUnits,
+		&rr.recordType,
+		&rr.beneficiaryId,
+		&rr.exchangeRate,
+		&rr.frequency,
+		&rr.dayOfMonth,
+		&rr.startDate,
+		&rr.endDate,
+		&rr.nextDate,
+		&rr.paused,
+		&rr.createdBy,
+		&rr.createdAt,
+		&rr.modifiedBy,
+		&rr.modifiedAt,
+		&rr.version,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return rr, err
+}
+
+func (d *DefaultRecurringRecordDao) GetRecurringRecordByIdTx(ctx context.Context, id ledger.RecurringRecordId, userId ledger.UserId, tx *sql.Tx) (ledger.RecurringRecord, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query := psql.Select(recurringRecordColumns...).
+		From("budget.recurring_record rr").
+		Join("budget.category c ON c.id = rr.category_id").
+		Where(sq.Eq{
+			"rr.id":      id,
+			"rr.user_id": userId,
+		})
+
+	rr, err := scanRecurringRecord(query.RunWith(tx).QueryRowContext(ctx))
+	if err != nil {
+		log.Printf("Failed to load recurring record id %d for user %d. Reason: %s", id, userId, err)
+		if err == sql.ErrNoRows {
+			return ledger.RecurringRecord{}, pkg.ValidationErrorWithError(pkg.ErrRecurringRecordNotFound, "Recurring record not found", err)
+		}
+		return ledger.RecurringRecord{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Error loading recurring record", err)
+	}
+	return ledger.NewRecurringRecordFromRecord(rr)
+}
+
+func (d *DefaultRecurringRecordDao) GetRecurringRecordsForAccount(ctx context.Context, accountId ledger.AccountId, userId ledger.UserId, tx *sql.Tx) (ledger.RecurringRecords, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query := psql.Select(recurringRecordColumns...).
+		From("budget.recurring_record rr").
+		Join("budget.category c ON c.id = rr.category_id").
+		Where(sq.Eq{"rr.account_id": accountId, "rr.user_id": userId}).
+		OrderBy("rr.id")
+
+	rows, err := query.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		log.Printf("Failed to load recurring records for account %d. Reason: %s", accountId, err)
+		return ledger.RecurringRecords{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load recurring records", err)
+	}
+	defer rows.Close()
+
+	recurringRecords := ledger.RecurringRecords{}
+	for rows.Next() {
+		var (
+			rr              recurringRecordRecord
+			recurringRecord ledger.RecurringRecord
+		)
+		if rr, err = scanRecurringRecord(rows); err != nil {
+			return ledger.RecurringRecords{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load recurring records", err)
+		}
+		if recurringRecord, err = ledger.NewRecurringRecordFromRecord(rr); err != nil {
+			return ledger.RecurringRecords{}, err
+		}
+		recurringRecords = append(recurringRecords, recurringRecord)
+	}
+	return recurringRecords, nil
+}
+
+func (d *DefaultRecurringRecordDao) GetDueRecurringRecordsTx(ctx context.Context, now time.Time, limit uint, tx *sql.Tx) ([]dao.DueRecurringRecord, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	query := psql.Select(recurringRecordColumns...).
+		Column("rr.user_id").
+		From("budget.recurring_record rr").
+		Join("budget.category c ON c.id = rr.category_id").
+		Join("budget.user u ON u.id = rr.user_id").
+		Where("NOT rr.paused").
+		// A recurring record is due once its next date has started in the timezone of its user
+		Where("rr.next_date <= (?::TIMESTAMP WITH TIME ZONE AT TIME ZONE u.timezone)::DATE", now).
+		OrderBy("rr.next_date", "rr.id")
+
+	if limit > 0 {
+		query = query.Limit(uint64(limit))
+	}
+
+	rows, err := query.RunWith(tx).QueryContext(ctx)
+	if err != nil {
+		log.Printf("Failed to load due recurring records. Reason: %s", err)
+		return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load due recurring records", err)
+	}
+	defer rows.Close()
+
+	due := []dao.DueRecurringRecord{}
+	for rows.Next() {
+		var (
+			rr              recurringRecordRecord
+			userId          ledger.UserId
+			recurringRecord ledger.RecurringRecord
+		)
+		if rr, err = scanRecurringRecord(rows, &userId); err != nil {
+			return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load due recurring records", err)
+		}
+		if recurringRecord, err = ledger.NewRecurringRecordFromRecord(rr); err != nil {
+			log.Printf("Error loading recurring record with id: %d from database. Reason: %s", rr.id, err)
+			continue
+		}
+		due = append(due, dao.DueRecurringRecord{UserId: userId, RecurringRecord: recurringRecord})
+	}
+	return due, nil
+}
+
+func (d *DefaultRecurringRecordDao) SaveOccurrenceTx(ctx context.Context, id ledger.RecurringRecordId, occurrence time.Time, recordId ledger.RecordId, tx *sql.Tx) (bool, error) {
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO budget.recurring_record_occurrence (
+			recurring_record_id,
+			occurrence_date,
+			record_id
+		) VALUES (
+			$1,
+			$2,
+			$3
+		) ON CONFLICT DO NOTHING`,
+		id,
+		occurrence.Format("2006-01-02"),
+		sql.NullInt64{
+			Int64: int64(recordId),
+			Valid: recordId != 0,
+		},
+	)
+	if err != nil {
+		return false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save occurrence of recurring record", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save occurrence of recurring record", err)
+	}
+	return rowsAffected == 1, nil
+}
@@ -0,0 +1,145 @@
+package persistence
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+type recurringRecordRecord struct {
+	id               ledger.RecurringRecordId
+	accountId        ledger.AccountId
+	note             string
+	category         categoryRecord
+	currency         string
+	amountMinorUnits int64
+	recordType       ledger.RecordType
+	beneficiaryId    sql.NullInt64
+	exchangeRate     sql.NullString
+	frequency        string
+	dayOfMonth       sql.NullInt32
+	startDate        time.Time
+	endDate          sql.NullTime
+	nextDate         sql.NullTime
+	paused           bool
+	createdBy        string
+	createdAt        time.Time
+	modifiedBy       sql.NullString
+	modifiedAt       sql.NullTime
+	version          ledger.Version
+}
+
+func (rr recurringRecordRecord) Id() ledger.RecurringRecordId {
+	return rr.id
+}
+
+func (rr recurringRecordRecord) AccountId() ledger.AccountId {
+	return rr.accountId
+}
+
+func (rr recurringRecordRecord) Note() string {
+	return rr.note
+}
+
+func (rr recurringRecordRecord) Category() ledger.Category {
+	category, err := ledger.NewCategoryFromRecord(rr.category)
+	if err != nil {
+		log.Fatalf("Failed to parse category from database for recurring record id %d. Reason: %s", rr.id, err)
+	}
+	return category
+}
+
+func (rr recurringRecordRecord) Amount() ledger.Money {
+	amount, err := ledger.NewMoney(rr.currency, rr.amountMinorUnits)
+	if err != nil {
+		log.Fatalf("Failed to parse amount from database for recurring record id %d. Reason: %s", rr.id, err)
+	}
+	return amount
+}
+
+func (rr recurringRecordRecord) RecordType() ledger.RecordType {
+	return rr.recordType
+}
+
+func (rr recurringRecordRecord) BeneficiaryId() ledger.AccountId {
+	if rr.beneficiaryId.Valid {
+		return ledger.AccountId(rr.beneficiaryId.Int64)
+	}
+	return ledger.NoBeneficiaryAccount
+}
+
+func (rr recurringRecordRecord) ExchangeRate() ledger.ExchangeRate {
+	if rr.exchangeRate.Valid {
+		return ledger.ExchangeRate(rr.exchangeRate.String)
+	}
+	return ledger.NoExchangeRate
+}
+
+func (rr recurringRecordRecord) Recurrence() ledger.Recurrence {
+	recurrence, err := ledger.NewRecurrence(ledger.Frequency(rr.frequency), int(rr.dayOfMonth.Int32))
+	if err != nil {
+		log.Fatalf("Failed to parse recurrence from database for recurring record id %d. Reason: %s", rr.id, err)
+	}
+	return recurrence
+}
+
+func (rr recurringRecordRecord) StartDate() time.Time {
+	return rr.startDate
+}
+
+func (rr recurringRecordRecord) EndDate() time.Time {
+	if rr.endDate.Valid {
+		return rr.endDate.Time
+	}
+	return ledger.NoEndDate
+}
+
+func (rr recurringRecordRecord) NextDate() time.Time {
+	if rr.nextDate.Valid {
+		return rr.nextDate.Time
+	}
+	return time.Time{}
+}
+
+func (rr recurringRecordRecord) IsPaused() bool {
+	return rr.paused
+}
+
+func (rr recurringRecordRecord) CreatedBy() ledger.UpdatedBy {
+	updatedBy, err := ledger.ParseUpdatedBy(rr.createdBy)
+	if err != nil {
+		log.Fatalf("Invalid createdBy persisted for recurring record %d: %s", rr.id, rr.createdBy)
+	}
+	return updatedBy
+}
+
+func (rr recurringRecordRecord) CreatedAtUTC() time.Time {
+	return rr.createdAt
+}
+
+func (rr recurringRecordRecord) ModifiedBy() ledger.UpdatedBy {
+	if !rr.modifiedBy.Valid {
+		return ledger.UpdatedBy{}
+	}
+	var (
+		updatedBy ledger.UpdatedBy
+		err       error
+	)
+	if updatedBy, err = ledger.ParseUpdatedBy(rr.modifiedBy.String); err != nil {
+		log.Fatalf("Invalid modifiedBy persisted for recurring record %d: %s", rr.id, rr.modifiedBy.String)
+	}
+	return updatedBy
+}
+
+func (rr recurringRecordRecord) ModifiedAtUTC() time.Time {
+	if rr.modifiedAt.Valid {
+		return rr.modifiedAt.Time
+	}
+	return time.Time{}
+}
+
+func (rr recurringRecordRecord) Version() ledger.Version {
+	return rr.version
+}
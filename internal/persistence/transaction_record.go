@@ -15,11 +15,14 @@ type recordRecord struct {
 	currency          string
 	amountMinorUnits  int64
 	date              time.Time
+	occurredAt        time.Time
 	recordType        ledger.RecordType
 	sourceAccountId   sql.NullInt64
 	beneficiaryId     sql.NullInt64
 	beneficiaryType   sql.NullString
 	transferReference sql.NullString
+	exchangeRate      sql.NullString
+	splits            ledger.RecordSplits
 	createdBy         string
 	createdAt         time.Time
 	modifiedBy        sql.NullString
@@ -57,6 +60,10 @@ func (rr recordRecord) DateUTC() time.Time {
 	return rr.date
 }
 
+func (rr recordRecord) OccurredAtUTC() time.Time {
+	return rr.occurredAt.UTC()
+}
+
 func (rr recordRecord) RecordType() ledger.RecordType {
 	return rr.recordType
 }
@@ -89,6 +96,17 @@ func (rr recordRecord) TransferReference() ledger.TransferReference {
 	return ledger.NoTransferReference
 }
 
+func (rr recordRecord) ExchangeRate() ledger.ExchangeRate {
+	if rr.exchangeRate.Valid {
+		return ledger.ExchangeRate(rr.exchangeRate.String)
+	}
+	return ledger.NoExchangeRate
+}
+
+func (rr recordRecord) Splits() ledger.RecordSplits {
+	return rr.splits
+}
+
 func (rr recordRecord) CreatedBy() ledger.UpdatedBy {
 	updatedBy, err := ledger.ParseUpdatedBy(rr.createdBy)
 	if err != nil {
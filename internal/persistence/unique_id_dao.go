@@ -18,7 +18,7 @@ func MustOpenDefaultUniqueIdDao(db *sql.DB) *UniqueIdDao {
 
 func (u UniqueIdDao) GetId(tx *sql.Tx, tableName string, salt string) (uint64, error) {
 	var uid uint64
-	err := tx.QueryRow("SELECT timestamp_id(?, ?)", tableName, salt).Scan(&uid)
+	err := tx.QueryRow("SELECT timestamp_id($1, $2)", tableName, salt).Scan(&uid)
 	if err != nil {
 		log.Printf("Failed to get unique id for table name %q. Reason; %q", tableName, err)
 		return 0, fmt.Errorf("Failed to get unique id. Reason: %w", err)
@@ -1,6 +1,7 @@
 package persistence
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -34,9 +35,16 @@ func (d *DefaultUserDao) NewUserId() (ledger.UserId, error) {
 func (d *DefaultUserDao) SaveTx(u ledger.User, tx *sql.Tx) error {
 	epoch := time.Time{}
 	_, err := tx.Exec(
-		"INSERT INTO budget.user (id, email, created_by, created_at, last_modified_by, last_modified_at, version) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		"INSERT INTO budget.user (id, email, timezone, locale, week_start, display_name, currency, default_account_id, default_category_id, created_by, created_at, last_modified_by, last_modified_at, version) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)",
 		u.Id(),
 		u.Email().Address,
+		u.Profile().Timezone(),
+		u.Profile().Locale(),
+		u.Profile().WeekStart().String(),
+		nullString(u.Preferences().DisplayName()),
+		nullString(u.Preferences().Currency()),
+		nullId(uint64(u.Preferences().DefaultAccountId())),
+		nullId(uint64(u.Preferences().DefaultCategoryId())),
 		u.CreatedBy().String(),
 		u.CreatedAtUTC(),
 		sql.NullString{
@@ -55,10 +63,19 @@ func (d *DefaultUserDao) SaveTx(u ledger.User, tx *sql.Tx) error {
 	return nil
 }
 
+const selectUserById = "SELECT id, email, timezone, locale, week_start, display_name, currency, default_account_id, default_category_id, created_by, created_at, last_modified_by, last_modified_at, version FROM budget.user WHERE id = $1"
+
 func (d *DefaultUserDao) GetUserById(queryId ledger.UserId) (ledger.User, error) {
+	return scanUser(d.db.QueryRow(selectUserById, queryId), queryId)
+}
+
+func (d *DefaultUserDao) GetUserByIdTx(ctx context.Context, queryId ledger.UserId, tx *sql.Tx) (ledger.User, error) {
+	return scanUser(tx.QueryRowContext(ctx, selectUserById, queryId), queryId)
+}
+
+func scanUser(row *sql.Row, queryId ledger.UserId) (ledger.User, error) {
 	var ur userRecord
-	err := d.db.QueryRow("SELECT id, email, created_by, created_at, last_modified_by, last_modified_at, version FROM budget.user WHERE id = $1", queryId).
-		Scan(&ur.id, &ur.email, &ur.createdBy, &ur.createdAt, &ur.modifiedBy, &ur.modifiedAt, &ur.version)
+	err := row.Scan(&ur.id, &ur.email, &ur.timezone, &ur.locale, &ur.weekStart, &ur.displayName, &ur.currency, &ur.defaultAccountId, &ur.defaultCategoryId, &ur.createdBy, &ur.createdAt, &ur.modifiedBy, &ur.modifiedAt, &ur.version)
 
 	if err == sql.ErrNoRows {
 		return ledger.User{}, pkg.ValidationErrorWithError(pkg.ErrUserNotFound, fmt.Sprintf("User with id %d not found", queryId), err)
@@ -69,6 +86,49 @@ func (d *DefaultUserDao) GetUserById(queryId ledger.UserId) (ledger.User, error)
 	return ledger.NewUserFromRecord(ur)
 }
 
+// UpdateTx saves the profile and preferences of a user.
+// ErrUserVersionConflict is returned if the user was changed since it was read.
+func (d *DefaultUserDao) UpdateTx(ctx context.Context, u ledger.User, tx *sql.Tx) error {
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE budget.user SET
+			timezone = $1,
+			locale = $2,
+			week_start = $3,
+			display_name = $4,
+			currency = $5,
+			default_account_id = $6,
+			default_category_id = $7,
+			last_modified_by = $8
+		WHERE
+			id = $9
+			AND version = $10`,
+		u.Profile().Timezone(),
+		u.Profile().Locale(),
+		u.Profile().WeekStart().String(),
+		nullString(u.Preferences().DisplayName()),
+		nullString(u.Preferences().Currency()),
+		nullId(uint64(u.Preferences().DefaultAccountId())),
+		nullId(uint64(u.Preferences().DefaultCategoryId())),
+		u.ModifiedBy().String(),
+		u.Id(),
+		u.Version(),
+	)
+	if err != nil {
+		log.Printf("Failed to update user %d. Reason: %s", u.Id(), err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update user", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to update user", err)
+	}
+	if rowsAffected == 0 {
+		return pkg.ValidationErrorWithFields(pkg.ErrUserVersionConflict, fmt.Sprintf("User %d was changed by another request", u.Id()), nil, nil)
+	}
+	return nil
+}
+
 func (d *DefaultUserDao) Save(u ledger.User) error {
 	tx, err := d.db.Begin()
 	if err != nil {
@@ -82,3 +142,333 @@ func (d *DefaultUserDao) Save(u ledger.User) error {
 	}
 	return err
 }
+
+func (d *DefaultUserDao) SaveDeletionTx(ctx context.Context, deletion dao.UserDeletion, tx *sql.Tx) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO budget.user_deletion (
+			user_id,
+			token_hash,
+			token_expires_at,
+			requested_at,
+			confirmed_at,
+			delete_after
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET
+			token_hash = EXCLUDED.token_hash,
+			token_expires_at = EXCLUDED.token_expires_at,
+			requested_at = EXCLUDED.requested_at,
+			confirmed_at = EXCLUDED.confirmed_at,
+			delete_after = EXCLUDED.delete_after`,
+		deletion.UserId,
+		deletion.TokenHash,
+		deletion.TokenExpiresAt,
+		deletion.RequestedAt,
+		nullTime(deletion.ConfirmedAt),
+		nullTime(deletion.DeleteAfter),
+	)
+	if err != nil {
+		log.Printf("Failed to save deletion of user %d. Reason: %s", deletion.UserId, err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save deletion of user", err)
+	}
+	return nil
+}
+
+func (d *DefaultUserDao) GetDeletionTx(ctx context.Context, id ledger.UserId, tx *sql.Tx) (dao.UserDeletion, bool, error) {
+	var (
+		deletion    = dao.UserDeletion{UserId: id}
+		confirmedAt sql.NullTime
+		deleteAfter sql.NullTime
+	)
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT token_hash, token_expires_at, requested_at, confirmed_at, delete_after FROM budget.user_deletion WHERE user_id = $1",
+		id,
+	).Scan(&deletion.TokenHash, &deletion.TokenExpiresAt, &deletion.RequestedAt, &confirmedAt, &deleteAfter)
+
+	if err == sql.ErrNoRows {
+		return dao.UserDeletion{}, false, nil
+	} else if err != nil {
+		log.Printf("Failed to load deletion of user %d. Reason: %s", id, err)
+		return dao.UserDeletion{}, false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load deletion of user", err)
+	}
+
+	if confirmedAt.Valid {
+		deletion.ConfirmedAt = &confirmedAt.Time
+	}
+	if deleteAfter.Valid {
+		deletion.DeleteAfter = &deleteAfter.Time
+	}
+	return deletion, true, nil
+}
+
+func (d *DefaultUserDao) DeleteDeletionTx(ctx context.Context, id ledger.UserId, tx *sql.Tx) error {
+	result, err := tx.ExecContext(ctx, "DELETE FROM budget.user_deletion WHERE user_id = $1", id)
+	if err != nil {
+		log.Printf("Failed to delete deletion of user %d. Reason: %s", id, err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to cancel deletion of user", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return pkg.ValidationErrorWithError(pkg.ErrUserDeletionNotFound, fmt.Sprintf("Deletion of user %d has not been requested", id), nil)
+	}
+	return nil
+}
+
+func (d *DefaultUserDao) GetUsersDueForDeletionTx(ctx context.Context, now time.Time, tx *sql.Tx) ([]ledger.UserId, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT user_id FROM budget.user_deletion WHERE delete_after <= $1 ORDER BY delete_after, user_id",
+		now,
+	)
+	if err != nil {
+		log.Printf("Failed to load users due for deletion. Reason: %s", err)
+		return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load users due for deletion", err)
+	}
+	defer rows.Close()
+
+	userIds := []ledger.UserId{}
+	for rows.Next() {
+		var userId ledger.UserId
+		if err = rows.Scan(&userId); err != nil {
+			return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load users due for deletion", err)
+		}
+		userIds = append(userIds, userId)
+	}
+	return userIds, rows.Err()
+}
+
+func (d *DefaultUserDao) SaveCredentialTx(ctx context.Context, credential dao.UserCredential, tx *sql.Tx) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO budget.user_credential (
+			user_id,
+			password_hash,
+			updated_at
+		) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			password_hash = EXCLUDED.password_hash,
+			updated_at = EXCLUDED.updated_at`,
+		credential.UserId,
+		string(credential.PasswordHash),
+		credential.UpdatedAt,
+	)
+	if err != nil {
+		log.Printf("Failed to save credential of user %d. Reason: %s", credential.UserId, err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save credential of user", err)
+	}
+	return nil
+}
+
+func (d *DefaultUserDao) GetCredentialByEmailTx(ctx context.Context, email string, tx *sql.Tx) (dao.UserCredential, bool, error) {
+	var (
+		credential   dao.UserCredential
+		passwordHash string
+	)
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT c.user_id, c.password_hash, c.updated_at FROM budget.user_credential c JOIN budget.user u ON u.id = c.user_id WHERE u.email = $1",
+		email,
+	).Scan(&credential.UserId, &passwordHash, &credential.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return dao.UserCredential{}, false, nil
+	} else if err != nil {
+		log.Printf("Failed to load credential of user. Reason: %s", err)
+		return dao.UserCredential{}, false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load credential of user", err)
+	}
+
+	credential.PasswordHash = ledger.PasswordHash(passwordHash)
+	return credential, true, nil
+}
+
+func (d *DefaultUserDao) GetCredentialTx(ctx context.Context, id ledger.UserId, tx *sql.Tx) (dao.UserCredential, bool, error) {
+	var (
+		credential   = dao.UserCredential{UserId: id}
+		passwordHash string
+	)
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT password_hash, updated_at FROM budget.user_credential WHERE user_id = $1",
+		id,
+	).Scan(&passwordHash, &credential.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return dao.UserCredential{}, false, nil
+	} else if err != nil {
+		log.Printf("Failed to load credential of user %d. Reason: %s", id, err)
+		return dao.UserCredential{}, false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load credential of user", err)
+	}
+
+	credential.PasswordHash = ledger.PasswordHash(passwordHash)
+	return credential, true, nil
+}
+
+func (d *DefaultUserDao) GetUserIdByEmailTx(ctx context.Context, email string, tx *sql.Tx) (ledger.UserId, bool, error) {
+	var userId ledger.UserId
+	err := tx.QueryRowContext(ctx, "SELECT id FROM budget.user WHERE email = $1", email).Scan(&userId)
+
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		log.Printf("Failed to load user by email. Reason: %s", err)
+		return 0, false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load user", err)
+	}
+	return userId, true, nil
+}
+
+func (d *DefaultUserDao) SavePasswordResetTx(ctx context.Context, reset dao.PasswordReset, tx *sql.Tx) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO budget.password_reset (
+			user_id,
+			token_hash,
+			token_expires_at,
+			requested_at
+		) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			token_hash = EXCLUDED.token_hash,
+			token_expires_at = EXCLUDED.token_expires_at,
+			requested_at = EXCLUDED.requested_at`,
+		reset.UserId,
+		reset.TokenHash,
+		reset.TokenExpiresAt,
+		reset.RequestedAt,
+	)
+	if err != nil {
+		log.Printf("Failed to save password reset of user %d. Reason: %s", reset.UserId, err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save password reset of user", err)
+	}
+	return nil
+}
+
+func (d *DefaultUserDao) GetPasswordResetByTokenHashTx(ctx context.Context, tokenHash string, tx *sql.Tx) (dao.PasswordReset, bool, error) {
+	reset := dao.PasswordReset{TokenHash: tokenHash}
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT user_id, token_expires_at, requested_at FROM budget.password_reset WHERE token_hash = $1",
+		tokenHash,
+	).Scan(&reset.UserId, &reset.TokenExpiresAt, &reset.RequestedAt)
+
+	if err == sql.ErrNoRows {
+		return dao.PasswordReset{}, false, nil
+	} else if err != nil {
+		log.Printf("Failed to load password reset. Reason: %s", err)
+		return dao.PasswordReset{}, false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to load password reset", err)
+	}
+	return reset, true, nil
+}
+
+func (d *DefaultUserDao) DeletePasswordResetTx(ctx context.Context, id ledger.UserId, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM budget.password_reset WHERE user_id = $1", id); err != nil {
+		log.Printf("Failed to delete password reset of user %d. Reason: %s", id, err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to delete password reset of user", err)
+	}
+	return nil
+}
+
+func (d *DefaultUserDao) SaveRefreshTokenTx(ctx context.Context, token dao.RefreshToken, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		"DELETE FROM budget.refresh_token WHERE user_id = $1 AND expires_at <= $2",
+		token.UserId,
+		token.IssuedAt,
+	); err != nil {
+		log.Printf("Failed to delete expired refresh tokens of user %d. Reason: %s", token.UserId, err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save refresh token", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO budget.refresh_token (id, user_id, issued_at, expires_at) VALUES ($1, $2, $3, $4)",
+		token.Id,
+		token.UserId,
+		token.IssuedAt,
+		token.ExpiresAt,
+	); err != nil {
+		log.Printf("Failed to save refresh token of user %d. Reason: %s", token.UserId, err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save refresh token", err)
+	}
+	return nil
+}
+
+// UseRefreshTokenTx marks the token as used in a single statement, so that a token exchanged twice at the same time is only accepted once.
+func (d *DefaultUserDao) UseRefreshTokenTx(ctx context.Context, id string, userId ledger.UserId, now time.Time, tx *sql.Tx) (bool, error) {
+	result, err := tx.ExecContext(
+		ctx,
+		"UPDATE budget.refresh_token SET used_at = $3 WHERE id = $1 AND user_id = $2 AND used_at IS NULL AND expires_at > $3",
+		id,
+		userId,
+		now,
+	)
+	if err != nil {
+		log.Printf("Failed to use refresh token of user %d. Reason: %s", userId, err)
+		return false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to use refresh token", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to use refresh token", err)
+	}
+	return affected == 1, nil
+}
+
+func (d *DefaultUserDao) RevokeRefreshTokensTx(ctx context.Context, userId ledger.UserId, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM budget.refresh_token WHERE user_id = $1", userId); err != nil {
+		log.Printf("Failed to revoke refresh tokens of user %d. Reason: %s", userId, err)
+		return pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to revoke refresh tokens", err)
+	}
+	return nil
+}
+
+// DeleteTx deletes a user. Everything else that belongs to the user is deleted by cascade,
+// except records: records are deleted first because the source account of a transfer can not be deleted while a record refers to it.
+func (d *DefaultUserDao) DeleteDueTx(ctx context.Context, id ledger.UserId, now time.Time, tx *sql.Tx) (bool, error) {
+	// The deletion is locked so that it can not be cancelled or postponed while the user is deleted
+	var userId ledger.UserId
+	err := tx.QueryRowContext(
+		ctx,
+		"SELECT user_id FROM budget.user_deletion WHERE user_id = $1 AND delete_after <= $2 FOR UPDATE",
+		id,
+		now,
+	).Scan(&userId)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		log.Printf("Failed to lock deletion of user %d. Reason: %s", id, err)
+		return false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to delete user", err)
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		"DELETE FROM budget.record WHERE account_id IN (SELECT id FROM budget.account WHERE user_id = $1)",
+		id,
+	); err != nil {
+		log.Printf("Failed to delete records of user %d. Reason: %s", id, err)
+		return false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to delete user", err)
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM budget.user WHERE id = $1", id)
+	if err != nil {
+		log.Printf("Failed to delete user %d. Reason: %s", id, err)
+		return false, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to delete user", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return false, pkg.ValidationErrorWithError(pkg.ErrUserNotFound, fmt.Sprintf("User with id %d not found", id), nil)
+	}
+	return true, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: len(s) > 0}
+}
+
+// nullId is null for the id 0, which is never assigned
+func nullId(id uint64) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id > 0}
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
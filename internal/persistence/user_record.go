@@ -10,13 +10,20 @@ import (
 )
 
 type userRecord struct {
-	id         ledger.UserId
-	email      string
-	createdBy  string
-	createdAt  time.Time
-	modifiedBy sql.NullString
-	modifiedAt sql.NullTime
-	version    ledger.Version
+	id                ledger.UserId
+	email             string
+	timezone          string
+	locale            string
+	weekStart         string
+	displayName       sql.NullString
+	currency          sql.NullString
+	defaultAccountId  sql.NullInt64
+	defaultCategoryId sql.NullInt64
+	createdBy         string
+	createdAt         time.Time
+	modifiedBy        sql.NullString
+	modifiedAt        sql.NullTime
+	version           ledger.Version
 }
 
 func (ur userRecord) Id() ledger.UserId {
@@ -32,6 +39,27 @@ func (ur userRecord) Email() *mail.Address {
 	return email
 }
 
+func (ur userRecord) Profile() ledger.UserProfile {
+	profile, err := ledger.NewUserProfile(ur.timezone, ur.locale, ur.weekStart)
+	if err != nil {
+		log.Fatalf("Invalid profile persisted for user %d. Reason: %s", ur.id, err)
+	}
+	return profile
+}
+
+func (ur userRecord) Preferences() ledger.UserPreferences {
+	preferences, err := ledger.NewUserPreferences(
+		ur.displayName.String,
+		ur.currency.String,
+		ledger.AccountId(ur.defaultAccountId.Int64),
+		ledger.CategoryId(ur.defaultCategoryId.Int64),
+	)
+	if err != nil {
+		log.Fatalf("Invalid preferences persisted for user %d. Reason: %s", ur.id, err)
+	}
+	return preferences
+}
+
 func (ur userRecord) CreatedBy() ledger.UpdatedBy {
 	updatedBy, err := ledger.ParseUpdatedBy(ur.createdBy)
 	if err != nil {
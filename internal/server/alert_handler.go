@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/http"
+
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+func (a *App) GetAlerts(w http.ResponseWriter, req *http.Request) {
+	var (
+		resp svc.BudgetAlertsResponse
+		err  error
+	)
+
+	if resp, err = a.AlertService.GetAlerts(req.Context()); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
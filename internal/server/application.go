@@ -8,11 +8,13 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/rakyll/statik/fs"
 	cfg "github.com/w-k-s/simple-budget-tracker/internal/config"
 	dao "github.com/w-k-s/simple-budget-tracker/internal/persistence"
+	"github.com/w-k-s/simple-budget-tracker/internal/service"
 	"github.com/w-k-s/simple-budget-tracker/pkg"
 	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
 	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
@@ -22,10 +24,24 @@ import (
 
 type App struct {
 	config            *cfg.Config
+	AuthService       svc.AuthService
 	UserService       svc.UserService
 	AccountService    svc.AccountService
 	CategoriesService svc.CategoriesService
 	RecordService     svc.RecordService
+	// RecurringRecordService is also used by the scheduler to create the records of recurring records
+	RecurringRecordService svc.RecurringRecordService
+	ImportService          svc.ImportService
+	CategoryRuleService    svc.CategoryRuleService
+	ExportService          svc.ExportService
+	UserImportService      svc.UserImportService
+	BudgetService          svc.BudgetService
+	AlertService           svc.AlertService
+	// AlertNotifier delivers the budget alerts raised when records are created
+	AlertNotifier      svc.AlertNotifier
+	MonthlyPlanService svc.MonthlyPlanService
+	// PasswordResetNotifier delivers the tokens with which users reset their password
+	PasswordResetNotifier svc.PasswordResetNotifier
 }
 
 func (app *App) Config() *cfg.Config {
@@ -52,10 +68,31 @@ func Init(config *cfg.Config) (*App, error) {
 	}
 	dao.MustRunMigrations(db, config.Database())
 
+	// Without a notifier, password resets are disabled
+	var passwordResetNotifier svc.PasswordResetNotifier
+	switch config.Auth().PasswordResetNotifier() {
+	case cfg.PasswordResetNotifierLog:
+		log.Printf("WARNING: Password reset tokens are written to the log. This must only be enabled in development")
+		passwordResetNotifier = svc.NewLogPasswordResetNotifier()
+	case cfg.PasswordResetNotifierMemory:
+		passwordResetNotifier = svc.NewInMemoryPasswordResetNotifier()
+	default:
+		log.Printf("WARNING: No password reset notifier is configured. Password resets are disabled")
+	}
+
 	userDao := dao.MustOpenUserDao(db)
-	userService, err := svc.NewUserService(userDao)
+	authService, err := svc.NewAuthService(
+		userDao,
+		config.Auth().SigningKey(),
+		config.Auth().AccessTokenTtl(),
+		config.Auth().RefreshTokenTtl(),
+		passwordResetNotifier,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initiaise user service. Reason: %w", err)
+		return nil, fmt.Errorf("failed to initiaise auth service. Reason: %w", err)
+	}
+	if config.Auth().DevUserIdHeader() {
+		log.Printf("WARNING: User ids are accepted in the Authorization header. This must only be enabled in development")
 	}
 
 	accountDao := dao.MustOpenAccountDao(db)
@@ -70,24 +107,140 @@ func Init(config *cfg.Config) (*App, error) {
 		return nil, fmt.Errorf("failed to initiaise categories service. Reason: %w", err)
 	}
 
+	userService, err := svc.NewUserService(userDao, accountDao, categoryDao, config.User().DeletionGracePeriod())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiaise user service. Reason: %w", err)
+	}
+
+	var alertNotifier svc.AlertNotifier = svc.NewLogAlertNotifier()
+	if config.Alert().Notifier() == cfg.AlertNotifierMemory {
+		alertNotifier = svc.NewInMemoryAlertNotifier()
+	}
+
+	budgetDao := dao.MustOpenBudgetDao(db)
+	alertService, err := svc.NewAlertService(
+		budgetDao,
+		dao.MustOpenBudgetAlertDao(db),
+		alertNotifier,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiaise alert service. Reason: %w", err)
+	}
+
 	recordDao := dao.MustOpenRecordDao(db)
+	categoryRuleDao := dao.MustOpenCategoryRuleDao(db)
 	recordService, err := svc.NewRecordService(
 		recordDao,
 		accountDao,
 		categoryDao,
+		categoryRuleDao,
+		userDao,
 		config.Gpt().ApiKey(),
+		config.Record().DuplicateWindow(),
+		alertService,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initiaise record service. Reason: %w", err)
 	}
 
+	recurringRecordService, err := svc.NewRecurringRecordService(
+		dao.MustOpenRecurringRecordDao(db),
+		recordDao,
+		accountDao,
+		categoryDao,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiaise recurring record service. Reason: %w", err)
+	}
+
+	importService, err := svc.NewImportService(
+		dao.MustOpenImportProfileDao(db),
+		recordDao,
+		accountDao,
+		categoryDao,
+		categoryRuleDao,
+		userDao,
+		config.Import().UncategorizedCategory(),
+		config.Record().DuplicateWindow(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiaise import service. Reason: %w", err)
+	}
+
+	categoryRuleService, err := svc.NewCategoryRuleService(
+		categoryRuleDao,
+		recordDao,
+		accountDao,
+		categoryDao,
+		config.Import().UncategorizedCategory(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiaise category rule service. Reason: %w", err)
+	}
+
+	exportService, err := svc.NewExportService(
+		recordDao,
+		accountDao,
+		categoryDao,
+		budgetDao,
+		userDao,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiaise export service. Reason: %w", err)
+	}
+
+	uniqueIdService := service.NewUniqueIdService(dao.MustOpenDefaultUniqueIdDao(db), config.Database().UniqueIdSalt())
+	userImportService, err := svc.NewUserImportService(
+		uniqueIdService,
+		accountDao,
+		categoryDao,
+		budgetDao,
+		recordDao,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiaise user import service. Reason: %w", err)
+	}
+
+	budgetService, err := svc.NewBudgetService(
+		uniqueIdService,
+		accountDao,
+		categoryDao,
+		budgetDao,
+		dao.MustOpenEnvelopeTransferDao(db),
+		userDao,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiaise budget service. Reason: %w", err)
+	}
+
+	monthlyPlanService, err := svc.NewMonthlyPlanService(
+		accountDao,
+		categoryDao,
+		dao.MustOpenMonthlyPlanDao(db),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiaise monthly plan service. Reason: %w", err)
+	}
+
 	log.Printf("--- Application Initialized ---")
 	return &App{
 		config:            config,
+		AuthService:       authService,
 		UserService:       userService,
 		AccountService:    accountService,
 		CategoriesService: categoriesService,
 		RecordService:     recordService,
+
+		RecurringRecordService: recurringRecordService,
+		ImportService:          importService,
+		CategoryRuleService:    categoryRuleService,
+		ExportService:          exportService,
+		UserImportService:      userImportService,
+		BudgetService:          budgetService,
+		AlertService:           alertService,
+		AlertNotifier:          alertNotifier,
+		MonthlyPlanService:     monthlyPlanService,
+		PasswordResetNotifier:  passwordResetNotifier,
 	}, nil
 }
 
@@ -98,9 +251,33 @@ func (app *App) Router() *mux.Router {
 
 	r.HandleFunc("/health", app.HealthHandler)
 
+	auth := r.PathPrefix("/api/v1/auth").Subrouter()
+	auth.HandleFunc("/login", app.Login).
+		Methods("POST")
+	auth.HandleFunc("/refresh", app.RefreshToken).
+		Methods("POST")
+	auth.HandleFunc("/password-reset", app.RequestPasswordReset).
+		Methods("POST")
+	auth.HandleFunc("/password-reset/confirm", app.ResetPassword).
+		Methods("POST")
+
 	users := r.PathPrefix("/api/v1/user").Subrouter()
 	users.HandleFunc("", app.RegisterUser).
 		Methods("POST")
+	users.HandleFunc("", app.GetUser).
+		Methods("GET")
+	users.HandleFunc("", app.PatchUser).
+		Methods("PATCH")
+	users.HandleFunc("", app.DeleteUser).
+		Methods("DELETE")
+	users.HandleFunc("/export", app.ExportUserData).
+		Methods("GET")
+	users.HandleFunc("/import", app.ImportUserData).
+		Methods("POST")
+	users.HandleFunc("/deletion", app.CancelUserDeletion).
+		Methods("DELETE")
+	users.HandleFunc("/password", app.SetPassword).
+		Methods("PUT")
 
 	accounts := r.PathPrefix("/api/v1/accounts").Subrouter()
 	accounts.HandleFunc("", app.RegisterAccounts).
@@ -121,6 +298,88 @@ func (app *App) Router() *mux.Router {
 		Methods("POST")
 	records.HandleFunc("", app.GetRecords).
 		Methods("GET")
+	records.HandleFunc("/export", app.ExportRecords).
+		Methods("GET")
+	records.HandleFunc("/{recordId}", app.UpdateRecord).
+		Methods("PUT")
+	records.HandleFunc("/{recordId}", app.PatchRecord).
+		Methods("PATCH")
+	records.HandleFunc("/{recordId}", app.DeleteRecord).
+		Methods("DELETE")
+
+	r.HandleFunc("/api/v1/records/export", app.ExportAllRecords).
+		Methods("GET")
+
+	recurringRecords := r.PathPrefix("/api/v1/accounts/{accountId}/recurring-records").Subrouter()
+	recurringRecords.HandleFunc("", app.CreateRecurringRecord).
+		Methods("POST")
+	recurringRecords.HandleFunc("", app.GetRecurringRecords).
+		Methods("GET")
+	recurringRecords.HandleFunc("/{recurringRecordId}", app.GetRecurringRecord).
+		Methods("GET")
+	recurringRecords.HandleFunc("/{recurringRecordId}", app.UpdateRecurringRecord).
+		Methods("PUT")
+	recurringRecords.HandleFunc("/{recurringRecordId}", app.DeleteRecurringRecord).
+		Methods("DELETE")
+	recurringRecords.HandleFunc("/{recurringRecordId}/pause", app.PauseRecurringRecord).
+		Methods("POST")
+	recurringRecords.HandleFunc("/{recurringRecordId}/resume", app.ResumeRecurringRecord).
+		Methods("POST")
+	recurringRecords.HandleFunc("/{recurringRecordId}/skip", app.SkipNextOccurrence).
+		Methods("POST")
+
+	importProfiles := r.PathPrefix("/api/v1/import-profiles").Subrouter()
+	importProfiles.HandleFunc("", app.CreateImportProfile).
+		Methods("POST")
+	importProfiles.HandleFunc("", app.GetImportProfiles).
+		Methods("GET")
+
+	imports := r.PathPrefix("/api/v1/accounts/{accountId}/imports").Subrouter()
+	imports.HandleFunc("", app.ImportRecords).
+		Methods("POST")
+
+	categoryRules := r.PathPrefix("/api/v1/category-rules").Subrouter()
+	categoryRules.HandleFunc("", app.CreateCategoryRule).
+		Methods("POST")
+	categoryRules.HandleFunc("", app.GetCategoryRules).
+		Methods("GET")
+	categoryRules.HandleFunc("/apply", app.ApplyCategoryRules).
+		Methods("POST")
+	categoryRules.HandleFunc("/{ruleId}", app.DeleteCategoryRule).
+		Methods("DELETE")
+
+	budgets := r.PathPrefix("/api/v1/budgets").Subrouter()
+	budgets.HandleFunc("", app.CreateBudget).
+		Methods("POST")
+	budgets.HandleFunc("", app.GetBudgets).
+		Methods("GET")
+	budgets.HandleFunc("/{budgetId}", app.GetBudget).
+		Methods("GET")
+	budgets.HandleFunc("/{budgetId}", app.UpdateBudget).
+		Methods("PUT")
+	budgets.HandleFunc("/{budgetId}", app.DeleteBudget).
+		Methods("DELETE")
+	budgets.HandleFunc("/{budgetId}/progress", app.GetBudgetProgress).
+		Methods("GET")
+	budgets.HandleFunc("/{budgetId}/envelopes", app.GetBudgetEnvelopes).
+		Methods("GET")
+	budgets.HandleFunc("/{budgetId}/transfers", app.TransferBetweenEnvelopes).
+		Methods("POST")
+	budgets.HandleFunc("/{budgetId}/transfers", app.GetEnvelopeTransfers).
+		Methods("GET")
+
+	r.HandleFunc("/api/v1/alerts", app.GetAlerts).
+		Methods("GET")
+
+	plans := r.PathPrefix("/api/v1/plans").Subrouter()
+	plans.HandleFunc("", app.CreateMonthlyPlan).
+		Methods("POST")
+	plans.HandleFunc("/{month}", app.GetMonthlyPlan).
+		Methods("GET")
+	plans.HandleFunc("/{month}", app.UpdateMonthlyPlan).
+		Methods("PUT")
+	plans.HandleFunc("/{month}/summary", app.GetMonthlyPlanSummary).
+		Methods("GET")
 
 	statikFS, err := fs.New()
 	if err != nil {
@@ -153,14 +412,48 @@ func (app *App) DecodeJsonOrSendBadRequest(w http.ResponseWriter, req *http.Requ
 	return true
 }
 
+// getIfMatchVersionOrBadRequest returns the version in the If-Match header (e.g. "3" or W/"3").
+// When the header is absent, the version provided in the request body is returned.
+// An invalid header is reported with the validation code of the entity, e.g. ErrBudgetValidation and "budget".
+func (app *App) getIfMatchVersionOrBadRequest(w http.ResponseWriter, req *http.Request, bodyVersion uint64, code pkg.ErrorCode, entity string) (uint64, bool) {
+	ifMatch := strings.TrimSpace(req.Header.Get("If-Match"))
+	if len(ifMatch) == 0 {
+		return bodyVersion, true
+	}
+
+	value := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+	version, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		app.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(
+			code,
+			"Invalid If-Match header",
+			err,
+			map[string]string{"version": fmt.Sprintf("If-Match '%s' must be the version of the %s", ifMatch, entity)},
+		))
+		return 0, false
+	}
+	return version, true
+}
+
+// problemMembers are the members of every problem. An invalid field with the same name e.g. the type query parameter is reported in invalidParams instead.
+var problemMembers = map[string]bool{"type": true, "title": true, "status": true, "detail": true, "instance": true}
+
 func (app *App) MustEncodeProblem(w http.ResponseWriter, req *http.Request, err error) {
 
 	log.Printf("Error: %s", err.Error())
 
 	opts := []problem.Option{}
+	invalidParams := map[string]string{}
 	for key, value := range errorFields(err) {
+		if problemMembers[key] {
+			invalidParams[key] = value
+			continue
+		}
 		opts = append(opts, problem.Custom(key, value))
 	}
+	if len(invalidParams) != 0 {
+		opts = append(opts, problem.Custom("invalidParams", invalidParams))
+	}
 
 	p := problem.New(
 		problem.Type(fmt.Sprintf("/api/v1/problems/%d", errorCode(err))),
@@ -220,17 +513,37 @@ func errorFields(err error) map[string]string {
 	return map[string]string{}
 }
 
+// AuthenticationMiddleware sets the user of the Bearer access token in the Authorization header in the context of the request.
+// Requests without an Authorization header are passed on without a user, and are rejected by the endpoints that require one.
+// A malformed header, or a token that is invalid or has expired, is rejected with 401.
 func (a *App) AuthenticationMiddleware(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		if authorization := r.Header.Get("Authorization"); len(authorization) != 0 {
-			userId, err := strconv.ParseUint(authorization, 10, 64)
+			userId, err := a.authenticate(authorization)
 			if err != nil {
-				log.Printf("Failed to parse userId %q in authorization header", authorization)
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				a.MustEncodeProblem(w, r, err)
+				return
 			}
-			r = r.WithContext(context.WithValue(r.Context(), svc.CtxUserId, ledger.UserId(userId)))
+			r = r.WithContext(context.WithValue(r.Context(), svc.CtxUserId, userId))
 		}
 
 		h.ServeHTTP(w, r)
 	})
 }
+
+func (a *App) authenticate(authorization string) (ledger.UserId, error) {
+	if parts := strings.SplitN(authorization, " ", 2); len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+		return a.AuthService.Authenticate(strings.TrimSpace(parts[1]))
+	}
+
+	// Before access tokens, the Authorization header was the id of the user
+	if a.config.Auth().DevUserIdHeader() {
+		if userId, err := strconv.ParseUint(authorization, 10, 64); err == nil && userId != 0 {
+			return ledger.UserId(userId), nil
+		}
+	}
+
+	return 0, pkg.ValidationErrorWithError(pkg.ErrAccessTokenInvalid, "Authorization header must be a Bearer access token", nil)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+func (a *App) Login(w http.ResponseWriter, req *http.Request) {
+	var (
+		loginRequest svc.LoginRequest
+		resp         svc.TokenResponse
+		err          error
+	)
+
+	if ok := a.DecodeJsonOrSendBadRequest(w, req, &loginRequest); !ok {
+		return
+	}
+
+	if resp, err = a.AuthService.Login(req.Context(), loginRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) RefreshToken(w http.ResponseWriter, req *http.Request) {
+	var (
+		refreshRequest svc.RefreshTokenRequest
+		resp           svc.TokenResponse
+		err            error
+	)
+
+	if ok := a.DecodeJsonOrSendBadRequest(w, req, &refreshRequest); !ok {
+		return
+	}
+
+	if resp, err = a.AuthService.Refresh(req.Context(), refreshRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+// RequestPasswordReset sends a reset token to the email of a user. 202 is returned whether or not the email has an account.
+func (a *App) RequestPasswordReset(w http.ResponseWriter, req *http.Request) {
+	var resetRequest svc.PasswordResetRequest
+
+	if ok := a.DecodeJsonOrSendBadRequest(w, req, &resetRequest); !ok {
+		return
+	}
+
+	if err := a.AuthService.RequestPasswordReset(req.Context(), resetRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (a *App) ResetPassword(w http.ResponseWriter, req *http.Request) {
+	var resetRequest svc.ResetPasswordRequest
+
+	if ok := a.DecodeJsonOrSendBadRequest(w, req, &resetRequest); !ok {
+		return
+	}
+
+	if err := a.AuthService.ResetPassword(req.Context(), resetRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+func (a *App) CreateBudget(w http.ResponseWriter, req *http.Request) {
+	var (
+		createRequest svc.CreateBudgetRequest
+		resp          svc.BudgetResponse
+		err           error
+		ok            bool
+	)
+
+	if ok = a.DecodeJsonOrSendBadRequest(w, req, &createRequest); !ok {
+		return
+	}
+
+	if resp, err = a.BudgetService.CreateBudget(req.Context(), createRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+func (a *App) GetBudgets(w http.ResponseWriter, req *http.Request) {
+	var (
+		resp svc.BudgetsResponse
+		err  error
+	)
+
+	if resp, err = a.BudgetService.GetBudgets(req.Context()); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) GetBudget(w http.ResponseWriter, req *http.Request) {
+	var (
+		budgetId ledger.BudgetId
+		resp     svc.BudgetResponse
+		err      error
+		ok       bool
+	)
+
+	if budgetId, ok = a.getBudgetIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if resp, err = a.BudgetService.GetBudget(req.Context(), budgetId); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) UpdateBudget(w http.ResponseWriter, req *http.Request) {
+	var (
+		budgetId      ledger.BudgetId
+		updateRequest svc.UpdateBudgetRequest
+		resp          svc.BudgetResponse
+		err           error
+		ok            bool
+	)
+
+	if budgetId, ok = a.getBudgetIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if ok = a.DecodeJsonOrSendBadRequest(w, req, &updateRequest); !ok {
+		return
+	}
+
+	if updateRequest.Version, ok = a.getIfMatchVersionOrBadRequest(w, req, updateRequest.Version, pkg.ErrBudgetValidation, "budget"); !ok {
+		return
+	}
+
+	if resp, err = a.BudgetService.UpdateBudget(req.Context(), budgetId, updateRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) DeleteBudget(w http.ResponseWriter, req *http.Request) {
+	var (
+		budgetId       ledger.BudgetId
+		versionRequest svc.BudgetVersionRequest
+		err            error
+		ok             bool
+	)
+
+	if budgetId, ok = a.getBudgetIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	// The version can be provided in the optional request body or in the If-Match header.
+	if req.ContentLength > 0 {
+		if ok = a.DecodeJsonOrSendBadRequest(w, req, &versionRequest); !ok {
+			return
+		}
+	}
+
+	if versionRequest.Version, ok = a.getIfMatchVersionOrBadRequest(w, req, versionRequest.Version, pkg.ErrBudgetValidation, "budget"); !ok {
+		return
+	}
+
+	if err = a.BudgetService.DeleteBudget(req.Context(), budgetId, versionRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) GetBudgetProgress(w http.ResponseWriter, req *http.Request) {
+	var (
+		budgetId ledger.BudgetId
+		resp     svc.BudgetProgressResponse
+		err      error
+		ok       bool
+	)
+
+	if budgetId, ok = a.getBudgetIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	progressRequest := svc.BudgetProgressRequest{
+		Period: req.URL.Query().Get("period"),
+	}
+
+	if resp, err = a.BudgetService.GetBudgetProgress(req.Context(), budgetId, progressRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) GetBudgetEnvelopes(w http.ResponseWriter, req *http.Request) {
+	var (
+		budgetId ledger.BudgetId
+		resp     svc.BudgetEnvelopesResponse
+		err      error
+		ok       bool
+	)
+
+	if budgetId, ok = a.getBudgetIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	envelopesRequest := svc.BudgetProgressRequest{
+		Period: req.URL.Query().Get("period"),
+	}
+
+	if resp, err = a.BudgetService.GetBudgetEnvelopes(req.Context(), budgetId, envelopesRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) TransferBetweenEnvelopes(w http.ResponseWriter, req *http.Request) {
+	var (
+		budgetId        ledger.BudgetId
+		transferRequest svc.EnvelopeTransferRequest
+		resp            svc.EnvelopeTransferResponse
+		err             error
+		ok              bool
+	)
+
+	if budgetId, ok = a.getBudgetIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if ok = a.DecodeJsonOrSendBadRequest(w, req, &transferRequest); !ok {
+		return
+	}
+
+	if resp, err = a.BudgetService.TransferBetweenEnvelopes(req.Context(), budgetId, transferRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+func (a *App) GetEnvelopeTransfers(w http.ResponseWriter, req *http.Request) {
+	var (
+		budgetId ledger.BudgetId
+		resp     svc.EnvelopeTransfersResponse
+		err      error
+		ok       bool
+	)
+
+	if budgetId, ok = a.getBudgetIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if resp, err = a.BudgetService.GetEnvelopeTransfers(req.Context(), budgetId); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) getBudgetIdOrBadRequest(w http.ResponseWriter, req *http.Request) (ledger.BudgetId, bool) {
+	var (
+		budgetId uint64
+		err      error
+	)
+	params := mux.Vars(req)
+	if budgetId, err = strconv.ParseUint(params["budgetId"], 10, 64); err != nil {
+		a.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(
+			pkg.ErrBudgetValidation,
+			"Invalid or no budget Id provided",
+			err,
+			map[string]string{"budgetId": params["budgetId"]},
+		))
+		return 0, false
+	}
+	return ledger.BudgetId(budgetId), true
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+func (a *App) CreateCategoryRule(w http.ResponseWriter, req *http.Request) {
+	var (
+		createRequest svc.CreateCategoryRuleRequest
+		resp          svc.CategoryRuleResponse
+		err           error
+	)
+
+	if ok := a.DecodeJsonOrSendBadRequest(w, req, &createRequest); !ok {
+		return
+	}
+
+	if resp, err = a.CategoryRuleService.CreateCategoryRule(req.Context(), createRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+func (a *App) GetCategoryRules(w http.ResponseWriter, req *http.Request) {
+	var (
+		resp svc.CategoryRulesResponse
+		err  error
+	)
+
+	if resp, err = a.CategoryRuleService.GetCategoryRules(req.Context()); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) DeleteCategoryRule(w http.ResponseWriter, req *http.Request) {
+	var (
+		ruleId ledger.CategoryRuleId
+		err    error
+		ok     bool
+	)
+
+	if ruleId, ok = a.getCategoryRuleIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if err = a.CategoryRuleService.DeleteCategoryRule(req.Context(), ruleId); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ApplyCategoryRules runs the rules over the uncategorised records of the user.
+// When the dryRun query parameter is true, the changes are previewed but not saved.
+func (a *App) ApplyCategoryRules(w http.ResponseWriter, req *http.Request) {
+	var (
+		applyRequest svc.ApplyCategoryRulesRequest
+		resp         svc.ApplyCategoryRulesResponse
+		err          error
+	)
+
+	if value := req.URL.Query().Get("dryRun"); len(value) > 0 {
+		if applyRequest.DryRun, err = strconv.ParseBool(value); err != nil {
+			a.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(
+				pkg.ErrCategoryRuleValidation,
+				"Invalid dryRun provided",
+				err,
+				map[string]string{"dryRun": "dryRun must be true or false"},
+			))
+			return
+		}
+	}
+
+	if resp, err = a.CategoryRuleService.ApplyCategoryRules(req.Context(), applyRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) getCategoryRuleIdOrBadRequest(w http.ResponseWriter, req *http.Request) (ledger.CategoryRuleId, bool) {
+	var (
+		ruleId uint64
+		err    error
+	)
+
+	params := mux.Vars(req)
+	if ruleId, err = strconv.ParseUint(params["ruleId"], 10, 64); err != nil {
+		a.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(
+			pkg.ErrCategoryRuleValidation,
+			"Invalid or no rule Id provided",
+			err,
+			map[string]string{"ruleId": params["ruleId"]},
+		))
+		return 0, false
+	}
+	return ledger.CategoryRuleId(ruleId), true
+}
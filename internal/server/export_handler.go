@@ -0,0 +1,69 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+// ExportRecords streams the records of an account as a CSV, JSON or OFX file.
+// The format, from and to query parameters are optional; all records are exported as a CSV by default.
+func (a *App) ExportRecords(w http.ResponseWriter, req *http.Request) {
+	var (
+		accountId ledger.AccountId
+		export    svc.ExportedFile
+		err       error
+		ok        bool
+	)
+
+	if accountId, ok = a.getAccountIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
+	if export, err = a.ExportService.ExportRecords(req.Context(), makeExportRecordsRequest(req)); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	streamExport(w, export)
+}
+
+// ExportAllRecords streams the records of all the accounts of the user, zipped when the user has several accounts.
+func (a *App) ExportAllRecords(w http.ResponseWriter, req *http.Request) {
+	var (
+		export svc.ExportedFile
+		err    error
+	)
+
+	if export, err = a.ExportService.ExportAllRecords(req.Context(), makeExportRecordsRequest(req)); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	streamExport(w, export)
+}
+
+func makeExportRecordsRequest(req *http.Request) svc.ExportRecordsRequest {
+	query := req.URL.Query()
+	return svc.ExportRecordsRequest{
+		Format: query.Get("format"),
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+	}
+}
+
+// streamExport writes an export that has been validated.
+// Once the file has started, errors can no longer be reported with a problem, so the file is left incomplete.
+func streamExport(w http.ResponseWriter, export svc.ExportedFile) {
+	w.Header().Set("Content-Type", export.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.FileName))
+	w.WriteHeader(http.StatusOK)
+
+	if err := export.Stream(w); err != nil {
+		log.Printf("Failed to export %q. Reason: %s", export.FileName, err)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+	"github.com/w-k-s/simple-budget-tracker/pkg/statement/qif"
+)
+
+// maxImportFileSize is the maximum size of a file uploaded to be imported
+const maxImportFileSize = 10 << 20
+
+func (a *App) CreateImportProfile(w http.ResponseWriter, req *http.Request) {
+	var (
+		createRequest svc.CreateImportProfileRequest
+		resp          svc.ImportProfileResponse
+		err           error
+	)
+
+	if ok := a.DecodeJsonOrSendBadRequest(w, req, &createRequest); !ok {
+		return
+	}
+
+	if resp, err = a.ImportService.CreateImportProfile(req.Context(), createRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+func (a *App) GetImportProfiles(w http.ResponseWriter, req *http.Request) {
+	var (
+		resp svc.ImportProfilesResponse
+		err  error
+	)
+
+	if resp, err = a.ImportService.GetImportProfiles(req.Context()); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+// ImportRecords imports the file uploaded in the "file" field of a multipart form.
+// The "format" field is one of CSV, OFX, QFX or QIF; when it is blank, the format is determined from the extension of the file.
+// A CSV is imported using the import profile in the "profileId" field, which is optional for other formats.
+// The "dateOrder" field of a QIF is MDY (default) or DMY.
+// When the dryRun query parameter is true, the records are previewed but not saved.
+func (a *App) ImportRecords(w http.ResponseWriter, req *http.Request) {
+	var (
+		accountId ledger.AccountId
+		profileId uint64
+		dateOrder qif.DateOrder
+		dryRun    bool
+		file      multipart.File
+		header    *multipart.FileHeader
+		resp      svc.ImportRecordsResponse
+		err       error
+		ok        bool
+	)
+
+	if accountId, ok = a.getAccountIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if value := req.URL.Query().Get("dryRun"); len(value) > 0 {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			a.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(
+				pkg.ErrImportValidation,
+				"Invalid dryRun provided",
+				err,
+				map[string]string{"dryRun": "dryRun must be true or false"},
+			))
+			return
+		}
+	}
+
+	req.Body = http.MaxBytesReader(w, req.Body, maxImportFileSize)
+	if file, header, err = req.FormFile("file"); err != nil {
+		a.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(
+			pkg.ErrImportValidation,
+			fmt.Sprintf("A file of at most %d MB must be uploaded in the file field of a multipart form", maxImportFileSize>>20),
+			err,
+			map[string]string{"file": "file is required"},
+		))
+		return
+	}
+	defer file.Close()
+
+	if value := req.FormValue("profileId"); len(value) > 0 {
+		if profileId, err = strconv.ParseUint(value, 10, 64); err != nil {
+			a.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(
+				pkg.ErrImportValidation,
+				"Invalid import profile Id provided",
+				err,
+				map[string]string{"profileId": value},
+			))
+			return
+		}
+	}
+
+	switch value := req.FormValue("dateOrder"); value {
+	case "", "MDY":
+		dateOrder = qif.MonthFirst
+	case "DMY":
+		dateOrder = qif.DayFirst
+	default:
+		a.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(
+			pkg.ErrImportValidation,
+			fmt.Sprintf("Invalid dateOrder %q provided", value),
+			nil,
+			map[string]string{"dateOrder": "dateOrder must be MDY or DMY"},
+		))
+		return
+	}
+
+	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
+	if resp, err = a.ImportService.ImportRecords(req.Context(), svc.ImportRecordsRequest{
+		ProfileId:    ledger.ImportProfileId(profileId),
+		Format:       svc.ImportFormat(req.FormValue("format")),
+		QifDateOrder: dateOrder,
+		FileName:     header.Filename,
+		File:         file,
+		DryRun:       dryRun,
+	}); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	a.MustEncodeJson(w, resp, status)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+func (a *App) CreateMonthlyPlan(w http.ResponseWriter, req *http.Request) {
+	var (
+		createRequest svc.CreateMonthlyPlanRequest
+		resp          svc.MonthlyPlanResponse
+		err           error
+		ok            bool
+	)
+
+	if ok = a.DecodeJsonOrSendBadRequest(w, req, &createRequest); !ok {
+		return
+	}
+
+	if resp, err = a.MonthlyPlanService.CreateMonthlyPlan(req.Context(), createRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+func (a *App) GetMonthlyPlan(w http.ResponseWriter, req *http.Request) {
+	var (
+		resp svc.MonthlyPlanResponse
+		err  error
+	)
+
+	if resp, err = a.MonthlyPlanService.GetMonthlyPlan(req.Context(), mux.Vars(req)["month"]); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) UpdateMonthlyPlan(w http.ResponseWriter, req *http.Request) {
+	var (
+		updateRequest svc.UpdateMonthlyPlanRequest
+		resp          svc.MonthlyPlanResponse
+		err           error
+		ok            bool
+	)
+
+	if ok = a.DecodeJsonOrSendBadRequest(w, req, &updateRequest); !ok {
+		return
+	}
+
+	if updateRequest.Version, ok = a.getIfMatchVersionOrBadRequest(w, req, updateRequest.Version, pkg.ErrMonthlyPlanValidation, "plan"); !ok {
+		return
+	}
+
+	if resp, err = a.MonthlyPlanService.UpdateMonthlyPlan(req.Context(), mux.Vars(req)["month"], updateRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) GetMonthlyPlanSummary(w http.ResponseWriter, req *http.Request) {
+	var (
+		resp svc.MonthlyPlanSummaryResponse
+		err  error
+	)
+
+	if resp, err = a.MonthlyPlanService.GetMonthlyPlanSummary(req.Context(), mux.Vars(req)["month"]); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
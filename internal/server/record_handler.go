@@ -1,7 +1,7 @@
 package server
 
 import (
-	"log"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -29,6 +29,18 @@ func (a *App) CreateRecord(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if value := req.URL.Query().Get("force"); len(value) > 0 {
+		if createRecordRequest.Force, err = strconv.ParseBool(value); err != nil {
+			a.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(
+				pkg.ErrRecordValidation,
+				"Invalid force provided",
+				err,
+				map[string]string{"force": "force must be true or false"},
+			))
+			return
+		}
+	}
+
 	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
 	if resp, err = a.RecordService.CreateRecord(req.Context(), createRecordRequest); err != nil {
 		a.MustEncodeProblem(w, req, err)
@@ -77,19 +89,168 @@ func (a *App) GetRecords(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if req.URL.Query().Has("latest") {
-		if resp, err = a.RecordService.GetRecords(req.Context(), ledger.AccountId(accountId)); err != nil {
+	query := req.URL.Query()
+	pageRequest := svc.PageRequest{
+		Cursor: query.Get("cursor"),
+		Limit:  query.Get("limit"),
+	}
+	if query.Has("latest") {
+		if resp, err = a.RecordService.GetRecords(req.Context(), ledger.AccountId(accountId), pageRequest); err != nil {
 			a.MustEncodeProblem(w, req, err)
 			return
 		}
 	} else {
-		log.Printf("Not implemented")
-		// TODO search
+		searchRequest := svc.SearchRecordsRequest{
+			PageRequest:      pageRequest,
+			SearchTerm:       query.Get("search"),
+			From:             query.Get("from"),
+			To:               query.Get("to"),
+			Period:           query.Get("period"),
+			CategoryNames:    query["category"],
+			RecordTypes:      query["type"],
+			BeneficiaryNames: query["beneficiary"],
+			CreatedByKinds:   query["createdBy"],
+		}
+		if resp, err = a.RecordService.SearchRecords(req.Context(), accountId, searchRequest); err != nil {
+			a.MustEncodeProblem(w, req, err)
+			return
+		}
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) UpdateRecord(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		accountId     ledger.AccountId
+		recordId      ledger.RecordId
+		updateRequest svc.UpdateRecordRequest
+		resp          svc.RecordResponse
+		err           error
+		ok            bool
+	)
+
+	if accountId, ok = a.getAccountIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if recordId, ok = a.getRecordIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if ok = a.DecodeJsonOrSendBadRequest(w, req, &updateRequest); !ok {
+		return
+	}
+
+	if updateRequest.Version, ok = a.getIfMatchVersionOrBadRequest(w, req, updateRequest.Version, pkg.ErrRecordValidation, "record"); !ok {
+		return
+	}
+
+	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
+	if resp, err = a.RecordService.UpdateRecord(req.Context(), recordId, updateRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
 	}
 
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
 	a.MustEncodeJson(w, resp, http.StatusOK)
 }
 
+func (a *App) PatchRecord(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		accountId    ledger.AccountId
+		recordId     ledger.RecordId
+		patchRequest svc.PatchRecordRequest
+		resp         svc.RecordResponse
+		err          error
+		ok           bool
+	)
+
+	if accountId, ok = a.getAccountIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if recordId, ok = a.getRecordIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if ok = a.DecodeJsonOrSendBadRequest(w, req, &patchRequest); !ok {
+		return
+	}
+
+	if patchRequest.Version, ok = a.getIfMatchVersionOrBadRequest(w, req, patchRequest.Version, pkg.ErrRecordValidation, "record"); !ok {
+		return
+	}
+
+	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
+	if resp, err = a.RecordService.PatchRecord(req.Context(), recordId, patchRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) DeleteRecord(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		accountId     ledger.AccountId
+		recordId      ledger.RecordId
+		deleteRequest svc.DeleteRecordRequest
+		err           error
+		ok            bool
+	)
+
+	if accountId, ok = a.getAccountIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if recordId, ok = a.getRecordIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	// The body is optional since the version can be sent in the If-Match header
+	if req.ContentLength > 0 {
+		if ok = a.DecodeJsonOrSendBadRequest(w, req, &deleteRequest); !ok {
+			return
+		}
+	}
+
+	if deleteRequest.Version, ok = a.getIfMatchVersionOrBadRequest(w, req, deleteRequest.Version, pkg.ErrRecordValidation, "record"); !ok {
+		return
+	}
+
+	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
+	if err = a.RecordService.DeleteRecord(req.Context(), recordId, deleteRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) getRecordIdOrBadRequest(w http.ResponseWriter, req *http.Request) (ledger.RecordId, bool) {
+	var (
+		recordId uint64
+		err      error
+	)
+
+	params := mux.Vars(req)
+	if recordId, err = strconv.ParseUint(params["recordId"], 10, 64); err != nil {
+		a.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(
+			pkg.ErrRecordValidation,
+			"Invalid or no record Id provided",
+			err,
+			map[string]string{"recordId": params["recordId"]},
+		))
+		return 0, false
+	}
+	return ledger.RecordId(recordId), true
+}
+
 func (a *App) getAccountIdOrBadRequest(w http.ResponseWriter, req *http.Request) (ledger.AccountId, bool) {
 	var (
 		accountId uint64
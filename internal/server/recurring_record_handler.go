@@ -0,0 +1,238 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+func (a *App) CreateRecurringRecord(w http.ResponseWriter, req *http.Request) {
+	var (
+		accountId     ledger.AccountId
+		createRequest svc.CreateRecurringRecordRequest
+		resp          svc.RecurringRecordResponse
+		err           error
+		ok            bool
+	)
+
+	if accountId, ok = a.getAccountIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if ok = a.DecodeJsonOrSendBadRequest(w, req, &createRequest); !ok {
+		return
+	}
+
+	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
+	if resp, err = a.RecurringRecordService.CreateRecurringRecord(req.Context(), createRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+func (a *App) GetRecurringRecords(w http.ResponseWriter, req *http.Request) {
+	var (
+		accountId ledger.AccountId
+		resp      svc.RecurringRecordsResponse
+		err       error
+		ok        bool
+	)
+
+	if accountId, ok = a.getAccountIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
+	if resp, err = a.RecurringRecordService.GetRecurringRecords(req.Context()); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) GetRecurringRecord(w http.ResponseWriter, req *http.Request) {
+	var (
+		accountId         ledger.AccountId
+		recurringRecordId ledger.RecurringRecordId
+		resp              svc.RecurringRecordResponse
+		err               error
+		ok                bool
+	)
+
+	if accountId, ok = a.getAccountIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if recurringRecordId, ok = a.getRecurringRecordIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
+	if resp, err = a.RecurringRecordService.GetRecurringRecord(req.Context(), recurringRecordId); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) UpdateRecurringRecord(w http.ResponseWriter, req *http.Request) {
+	var (
+		accountId         ledger.AccountId
+		recurringRecordId ledger.RecurringRecordId
+		updateRequest     svc.UpdateRecurringRecordRequest
+		resp              svc.RecurringRecordResponse
+		err               error
+		ok                bool
+	)
+
+	if accountId, ok = a.getAccountIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if recurringRecordId, ok = a.getRecurringRecordIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if ok = a.DecodeJsonOrSendBadRequest(w, req, &updateRequest); !ok {
+		return
+	}
+
+	if updateRequest.Version, ok = a.getIfMatchVersionOrBadRequest(w, req, updateRequest.Version, pkg.ErrRecurringRecordValidation, "recurring record"); !ok {
+		return
+	}
+
+	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
+	if resp, err = a.RecurringRecordService.UpdateRecurringRecord(req.Context(), recurringRecordId, updateRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (a *App) DeleteRecurringRecord(w http.ResponseWriter, req *http.Request) {
+	var (
+		accountId         ledger.AccountId
+		recurringRecordId ledger.RecurringRecordId
+		versionRequest    svc.RecurringRecordVersionRequest
+		err               error
+		ok                bool
+	)
+
+	if accountId, ok = a.getAccountIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if recurringRecordId, ok = a.getRecurringRecordIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if versionRequest, ok = a.getRecurringRecordVersionOrBadRequest(w, req); !ok {
+		return
+	}
+
+	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
+	if err = a.RecurringRecordService.DeleteRecurringRecord(req.Context(), recurringRecordId, versionRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) PauseRecurringRecord(w http.ResponseWriter, req *http.Request) {
+	a.changeRecurringRecord(w, req, a.RecurringRecordService.PauseRecurringRecord)
+}
+
+func (a *App) ResumeRecurringRecord(w http.ResponseWriter, req *http.Request) {
+	a.changeRecurringRecord(w, req, a.RecurringRecordService.ResumeRecurringRecord)
+}
+
+func (a *App) SkipNextOccurrence(w http.ResponseWriter, req *http.Request) {
+	a.changeRecurringRecord(w, req, a.RecurringRecordService.SkipNextOccurrence)
+}
+
+func (a *App) changeRecurringRecord(
+	w http.ResponseWriter,
+	req *http.Request,
+	change func(ctx context.Context, id ledger.RecurringRecordId, request svc.RecurringRecordVersionRequest) (svc.RecurringRecordResponse, error),
+) {
+	var (
+		accountId         ledger.AccountId
+		recurringRecordId ledger.RecurringRecordId
+		versionRequest    svc.RecurringRecordVersionRequest
+		resp              svc.RecurringRecordResponse
+		err               error
+		ok                bool
+	)
+
+	if accountId, ok = a.getAccountIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if recurringRecordId, ok = a.getRecurringRecordIdOrBadRequest(w, req); !ok {
+		return
+	}
+
+	if versionRequest, ok = a.getRecurringRecordVersionOrBadRequest(w, req); !ok {
+		return
+	}
+
+	req = req.WithContext(svc.SetAccountId(req.Context(), accountId))
+	if resp, err = change(req.Context(), recurringRecordId, versionRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+// getRecurringRecordVersionOrBadRequest reads the version from the optional request body or from the If-Match header.
+func (a *App) getRecurringRecordVersionOrBadRequest(w http.ResponseWriter, req *http.Request) (svc.RecurringRecordVersionRequest, bool) {
+	var (
+		versionRequest svc.RecurringRecordVersionRequest
+		ok             bool
+	)
+
+	if req.ContentLength > 0 {
+		if ok = a.DecodeJsonOrSendBadRequest(w, req, &versionRequest); !ok {
+			return svc.RecurringRecordVersionRequest{}, false
+		}
+	}
+
+	if versionRequest.Version, ok = a.getIfMatchVersionOrBadRequest(w, req, versionRequest.Version, pkg.ErrRecurringRecordValidation, "recurring record"); !ok {
+		return svc.RecurringRecordVersionRequest{}, false
+	}
+	return versionRequest, true
+}
+
+func (a *App) getRecurringRecordIdOrBadRequest(w http.ResponseWriter, req *http.Request) (ledger.RecurringRecordId, bool) {
+	var (
+		recurringRecordId uint64
+		err               error
+	)
+	params := mux.Vars(req)
+	if recurringRecordId, err = strconv.ParseUint(params["recurringRecordId"], 10, 64); err != nil {
+		a.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(
+			pkg.ErrRecurringRecordValidation,
+			"Invalid or no recurring record Id provided",
+			err,
+			map[string]string{"recurringRecordId": params["recurringRecordId"]},
+		))
+		return 0, false
+	}
+	return ledger.RecurringRecordId(recurringRecordId), true
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// StartScheduler creates the records of recurring records that are due and deletes the users whose deletion is due,
+// once immediately and then at the interval set in the configuration.
+// The scheduler runs in the background until the context is cancelled.
+func (app *App) StartScheduler(ctx context.Context) {
+	interval := app.config.Server().SchedulerInterval()
+	log.Printf("Scheduler will check for due recurring records every %s", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			app.createDueRecords(ctx)
+			app.deleteDueUsers(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (app *App) createDueRecords(ctx context.Context) {
+	created, err := app.RecurringRecordService.CreateDueRecords(ctx, time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to create records of recurring records. Reason: %s", err)
+		return
+	}
+	if created > 0 {
+		log.Printf("Created %d records of recurring records", created)
+	}
+}
+
+func (app *App) deleteDueUsers(ctx context.Context) {
+	deleted, err := app.UserService.DeleteDueUsers(ctx, time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to delete users due for deletion. Reason: %s", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Deleted %d users", deleted)
+	}
+}
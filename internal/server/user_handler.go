@@ -1,8 +1,11 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/w-k-s/simple-budget-tracker/pkg"
 	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
 )
 
@@ -25,3 +28,134 @@ func (a *App) RegisterUser(w http.ResponseWriter, req *http.Request) {
 
 	a.MustEncodeJson(w, resp, http.StatusCreated)
 }
+
+func (a *App) GetUser(w http.ResponseWriter, req *http.Request) {
+	resp, err := a.UserService.GetUser(req.Context())
+	if err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+// PatchUser changes the profile and preferences of the user.
+// The version of the user can be provided in the request body or in the If-Match header.
+func (a *App) PatchUser(w http.ResponseWriter, req *http.Request) {
+	var (
+		patchRequest svc.PatchUserRequest
+		resp         svc.UserResponse
+		err          error
+		ok           bool
+	)
+
+	if ok = a.DecodeJsonOrSendBadRequest(w, req, &patchRequest); !ok {
+		return
+	}
+
+	if patchRequest.Version, ok = a.getIfMatchVersionOrBadRequest(w, req, patchRequest.Version, pkg.ErrUserProfileValidation, "user"); !ok {
+		return
+	}
+
+	if resp, err = a.UserService.PatchUser(req.Context(), patchRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", strconv.FormatUint(resp.Version, 10)))
+	a.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+// ExportUserData streams all the data of the user as a versioned JSON archive.
+func (a *App) ExportUserData(w http.ResponseWriter, req *http.Request) {
+	var (
+		export svc.ExportedFile
+		err    error
+	)
+
+	if export, err = a.ExportService.ExportUserData(req.Context()); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	streamExport(w, export)
+}
+
+// maxUserImportSize is the maximum size of an archive of the data of a user that can be imported
+const maxUserImportSize = 100 << 20
+
+// ImportUserData imports the archive in the body of the request, as exported by ExportUserData, into the data of the user.
+// The onConflict query parameter is how accounts and categories with the same name as those of the user are imported: skip, rename (default) or merge.
+func (a *App) ImportUserData(w http.ResponseWriter, req *http.Request) {
+	var (
+		resp svc.ImportUserDataResponse
+		err  error
+	)
+
+	if req.Body == nil {
+		a.MustEncodeProblem(w, req, pkg.ValidationErrorWithFields(pkg.ErrUserImportValidation, "An archive is required", nil, map[string]string{
+			"archive": "the body must be an archive exported from /api/v1/user/export",
+		}))
+		return
+	}
+
+	if resp, err = a.UserImportService.ImportUserData(req.Context(), svc.ImportUserDataRequest{
+		OnConflict: req.URL.Query().Get("onConflict"),
+		Archive:    http.MaxBytesReader(w, req.Body, maxUserImportSize),
+	}); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	a.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+// DeleteUser deletes the account of the user in two steps.
+// Without a confirmationToken query parameter, a token is returned with which the deletion must be confirmed.
+// With the token, the user and all their data are deleted once the grace period is over.
+func (a *App) DeleteUser(w http.ResponseWriter, req *http.Request) {
+	confirmationToken := req.URL.Query().Get("confirmationToken")
+	if len(confirmationToken) == 0 {
+		resp, err := a.UserService.RequestDeletion(req.Context())
+		if err != nil {
+			a.MustEncodeProblem(w, req, err)
+			return
+		}
+		a.MustEncodeJson(w, resp, http.StatusAccepted)
+		return
+	}
+
+	resp, err := a.UserService.ConfirmDeletion(req.Context(), confirmationToken)
+	if err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+	a.MustEncodeJson(w, resp, http.StatusAccepted)
+}
+
+// CancelUserDeletion cancels the deletion of the account of the user, if the grace period is not yet over.
+func (a *App) CancelUserDeletion(w http.ResponseWriter, req *http.Request) {
+	if err := a.UserService.CancelDeletion(req.Context()); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetPassword changes the password of the user. Sessions opened with the previous password can no longer be refreshed.
+func (a *App) SetPassword(w http.ResponseWriter, req *http.Request) {
+	var setPasswordRequest svc.SetPasswordRequest
+
+	if ok := a.DecodeJsonOrSendBadRequest(w, req, &setPasswordRequest); !ok {
+		return
+	}
+
+	if err := a.AuthService.SetPassword(req.Context(), setPasswordRequest); err != nil {
+		a.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -53,7 +53,7 @@ func (d defaultUniqueIdService) GetId(entity svc.Entity) (uint64, error) {
 }
 
 func (d defaultUniqueIdService) MustGetId(entity svc.Entity) uint64 {
-	uid, err := d.GetId(svc.Entity(d.tableNameForEntity(entity)))
+	uid, err := d.GetId(entity)
 	if err != nil {
 		log.Fatal(err)
 	}
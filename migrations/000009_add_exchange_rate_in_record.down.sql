@@ -0,0 +1,2 @@
+ALTER TABLE budget.record
+DROP COLUMN exchange_rate;
@@ -0,0 +1,2 @@
+ALTER TABLE budget.record
+ADD COLUMN exchange_rate NUMERIC CHECK (exchange_rate > 0);
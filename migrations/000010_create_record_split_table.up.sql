@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS budget.record_split(
+    record_id BIGINT NOT NULL,
+    line SMALLINT NOT NULL,
+    category_id BIGINT NOT NULL,
+    note VARCHAR(50) NOT NULL,
+    amount_minor_units DECIMAL(19,0) NOT NULL,
+    CONSTRAINT pk_record_split PRIMARY KEY(record_id, line),
+    CONSTRAINT fk_record_split_record_id FOREIGN KEY(record_id) REFERENCES budget.record(id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+    CONSTRAINT fk_record_split_category_id FOREIGN KEY(category_id) REFERENCES budget.category(id)
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS idx_record_split_category_id ON budget.record_split(category_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS budget.recurring_record_occurrence;
+DROP TABLE IF EXISTS budget.recurring_record;
+DROP SEQUENCE IF EXISTS budget.recurring_record_id;
@@ -0,0 +1,52 @@
+CREATE SEQUENCE IF NOT EXISTS budget.recurring_record_id;
+CREATE TABLE IF NOT EXISTS budget.recurring_record(
+    id BIGINT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    account_id BIGINT NOT NULL,
+    category_id BIGINT NOT NULL,
+    note VARCHAR(50) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    amount_minor_units DECIMAL(19,0) NOT NULL,
+    type budget.record_type NOT NULL,
+    beneficiary_id BIGINT,
+    exchange_rate NUMERIC CHECK (exchange_rate > 0),
+    frequency VARCHAR(20) NOT NULL,
+    day_of_month SMALLINT,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    next_date DATE,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by VARCHAR (255) NOT NULL,
+    last_modified_at TIMESTAMP WITH TIME ZONE,
+    last_modified_by VARCHAR (255),
+    version BIGINT NOT NULL,
+    CONSTRAINT fk_recurring_record_user FOREIGN KEY(user_id) REFERENCES budget.user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_recurring_record_account FOREIGN KEY(account_id) REFERENCES budget.account(id) ON DELETE CASCADE,
+    CONSTRAINT fk_recurring_record_category FOREIGN KEY(category_id) REFERENCES budget.category(id) ON DELETE NO ACTION,
+    CONSTRAINT fk_recurring_record_beneficiary_id FOREIGN KEY(beneficiary_id) REFERENCES budget.account(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_record_next_date ON budget.recurring_record(next_date) WHERE NOT paused;
+
+-- An occurrence is recorded before a record is created for it (or when it is skipped),
+-- so that a record is never created twice for the same occurrence.
+-- The record id is checked when the transaction is committed since the occurrence is saved before the record.
+-- The occurrence is kept when the record created for it is deleted, so that it is not created again.
+CREATE TABLE IF NOT EXISTS budget.recurring_record_occurrence(
+    recurring_record_id BIGINT NOT NULL,
+    occurrence_date DATE NOT NULL,
+    record_id BIGINT,
+    CONSTRAINT pk_recurring_record_occurrence PRIMARY KEY(recurring_record_id, occurrence_date),
+    CONSTRAINT fk_recurring_record_occurrence_recurring_record_id FOREIGN KEY(recurring_record_id) REFERENCES budget.recurring_record(id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+    CONSTRAINT fk_recurring_record_occurrence_record_id FOREIGN KEY(record_id) REFERENCES budget.record(id)
+        ON DELETE SET NULL
+        DEFERRABLE INITIALLY DEFERRED
+);
+
+DROP TRIGGER IF EXISTS audit_recurring_record ON budget.recurring_record;
+create trigger audit_recurring_record
+BEFORE update on budget.recurring_record
+for each row execute procedure audit_record();
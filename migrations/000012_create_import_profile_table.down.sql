@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS budget.import_profile;
+DROP SEQUENCE IF EXISTS budget.import_profile_id;
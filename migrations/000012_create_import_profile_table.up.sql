@@ -0,0 +1,29 @@
+CREATE SEQUENCE IF NOT EXISTS budget.import_profile_id;
+CREATE TABLE IF NOT EXISTS budget.import_profile(
+    id BIGINT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    date_column SMALLINT NOT NULL,
+    amount_column SMALLINT NOT NULL,
+    debit_column SMALLINT,
+    note_column SMALLINT,
+    category_column SMALLINT,
+    date_format VARCHAR(25) NOT NULL,
+    decimal_separator VARCHAR(1) NOT NULL,
+    debit_format VARCHAR(20) NOT NULL,
+    has_header BOOLEAN NOT NULL DEFAULT TRUE,
+    default_category_id BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by VARCHAR (255) NOT NULL,
+    last_modified_at TIMESTAMP WITH TIME ZONE,
+    last_modified_by VARCHAR (255),
+    version BIGINT NOT NULL,
+    CONSTRAINT fk_import_profile_user FOREIGN KEY(user_id) REFERENCES budget.user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_import_profile_default_category FOREIGN KEY(default_category_id) REFERENCES budget.category(id) ON DELETE SET NULL,
+    CONSTRAINT uq_import_profile_name UNIQUE(user_id, name)
+);
+
+DROP TRIGGER IF EXISTS audit_import_profile ON budget.import_profile;
+create trigger audit_import_profile
+BEFORE update on budget.import_profile
+for each row execute procedure audit_record();
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS budget.record_external_id;
@@ -0,0 +1,14 @@
+-- The id given to a transaction by a bank e.g. the FITID of an OFX transaction, so that a statement can be imported more than once.
+-- The external id is saved before the record is created for it, so the record id is checked when the transaction is committed.
+-- The external id is kept when its record is deleted, so that the transaction is not imported again.
+CREATE TABLE IF NOT EXISTS budget.record_external_id(
+    account_id BIGINT NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    record_id BIGINT,
+    CONSTRAINT pk_record_external_id PRIMARY KEY(account_id, external_id),
+    CONSTRAINT fk_record_external_id_account_id FOREIGN KEY(account_id) REFERENCES budget.account(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_record_external_id_record_id FOREIGN KEY(record_id) REFERENCES budget.record(id)
+        ON DELETE SET NULL
+        DEFERRABLE INITIALLY DEFERRED
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS budget.category_rule;
+DROP SEQUENCE IF EXISTS budget.category_rule_id;
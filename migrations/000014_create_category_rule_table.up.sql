@@ -0,0 +1,26 @@
+CREATE SEQUENCE IF NOT EXISTS budget.category_rule_id;
+CREATE TABLE IF NOT EXISTS budget.category_rule(
+    id BIGINT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    priority INTEGER NOT NULL CHECK (priority >= 0),
+    note_contains VARCHAR(50),
+    account_id BIGINT,
+    min_amount_minor_units DECIMAL(19,0) CHECK (min_amount_minor_units >= 0),
+    max_amount_minor_units DECIMAL(19,0) CHECK (max_amount_minor_units >= 0),
+    category_id BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by VARCHAR (255) NOT NULL,
+    last_modified_at TIMESTAMP WITH TIME ZONE,
+    last_modified_by VARCHAR (255),
+    version BIGINT NOT NULL,
+    CONSTRAINT fk_category_rule_user FOREIGN KEY(user_id) REFERENCES budget.user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_category_rule_account FOREIGN KEY(account_id) REFERENCES budget.account(id) ON DELETE CASCADE,
+    CONSTRAINT fk_category_rule_category FOREIGN KEY(category_id) REFERENCES budget.category(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_rule_user_priority ON budget.category_rule(user_id, priority);
+
+DROP TRIGGER IF EXISTS audit_category_rule ON budget.category_rule;
+create trigger audit_category_rule
+BEFORE update on budget.category_rule
+for each row execute procedure audit_record();
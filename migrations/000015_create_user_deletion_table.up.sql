@@ -0,0 +1,15 @@
+-- A request by a user to delete their account and all their data.
+-- Only a hash of the confirmation token is kept. Once the deletion is confirmed, the user is deleted after delete_after unless the deletion is cancelled.
+CREATE TABLE IF NOT EXISTS budget.user_deletion(
+    user_id BIGINT NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    token_expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    delete_after TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT pk_user_deletion PRIMARY KEY(user_id),
+    CONSTRAINT fk_user_deletion_user_id FOREIGN KEY(user_id) REFERENCES budget.user(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_deletion_delete_after ON budget.user_deletion(delete_after);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS budget.budget_alert;
+DROP SEQUENCE IF EXISTS budget.budget_alert_id;
+ALTER TABLE budget.budget_per_category DROP COLUMN IF EXISTS alert_thresholds;
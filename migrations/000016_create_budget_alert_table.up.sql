@@ -0,0 +1,24 @@
+ALTER TABLE budget.budget_per_category ADD COLUMN IF NOT EXISTS alert_thresholds SMALLINT[] NOT NULL DEFAULT '{50,80,100}';
+
+CREATE SEQUENCE IF NOT EXISTS budget.budget_alert_id;
+CREATE TABLE IF NOT EXISTS budget.budget_alert(
+    id BIGINT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    budget_id BIGINT NOT NULL,
+    category_id BIGINT NOT NULL,
+    period VARCHAR(20) NOT NULL,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    threshold SMALLINT NOT NULL CHECK (threshold > 0),
+    currency VARCHAR(3) NOT NULL,
+    spent_minor_units BIGINT NOT NULL,
+    max_limit_minor_units BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CONSTRAINT fk_budget_alert_user FOREIGN KEY(user_id) REFERENCES budget.user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_budget_alert_budget FOREIGN KEY(budget_id) REFERENCES budget.budget(id) ON DELETE CASCADE,
+    CONSTRAINT fk_budget_alert_category FOREIGN KEY(category_id) REFERENCES budget.category(id) ON DELETE CASCADE,
+    -- An alert is raised once per threshold in each period
+    CONSTRAINT uq_budget_alert_threshold_per_period UNIQUE (budget_id, category_id, period, period_start, threshold)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_alert_user_created_at ON budget.budget_alert(user_id, created_at);
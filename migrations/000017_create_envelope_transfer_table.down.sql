@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS budget.envelope_transfer;
+DROP SEQUENCE IF EXISTS budget.envelope_transfer_id;
+ALTER TABLE budget.budget_per_category DROP COLUMN IF EXISTS rollover;
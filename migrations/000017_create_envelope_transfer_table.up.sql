@@ -0,0 +1,32 @@
+ALTER TABLE budget.budget_per_category ADD COLUMN IF NOT EXISTS rollover VARCHAR(20) NOT NULL DEFAULT 'None';
+
+CREATE SEQUENCE IF NOT EXISTS budget.envelope_transfer_id;
+CREATE TABLE IF NOT EXISTS budget.envelope_transfer(
+    id BIGINT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    budget_id BIGINT NOT NULL,
+    from_category_id BIGINT NOT NULL,
+    to_category_id BIGINT NOT NULL,
+    period VARCHAR(20) NOT NULL,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    amount_minor_units BIGINT NOT NULL CHECK (amount_minor_units > 0),
+    note VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by VARCHAR (255) NOT NULL,
+    last_modified_at TIMESTAMP WITH TIME ZONE,
+    last_modified_by VARCHAR (255),
+    version BIGINT NOT NULL,
+    CONSTRAINT fk_envelope_transfer_user FOREIGN KEY(user_id) REFERENCES budget.user(id) ON DELETE CASCADE,
+    CONSTRAINT fk_envelope_transfer_budget FOREIGN KEY(budget_id) REFERENCES budget.budget(id) ON DELETE CASCADE,
+    CONSTRAINT fk_envelope_transfer_from_category FOREIGN KEY(from_category_id) REFERENCES budget.category(id) ON DELETE CASCADE,
+    CONSTRAINT fk_envelope_transfer_to_category FOREIGN KEY(to_category_id) REFERENCES budget.category(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_envelope_transfer_budget_period ON budget.envelope_transfer(budget_id, period_start);
+
+DROP TRIGGER IF EXISTS audit_envelope_transfer ON budget.envelope_transfer;
+create trigger audit_envelope_transfer
+BEFORE update on budget.envelope_transfer
+for each row execute procedure audit_record();
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS budget.monthly_plan_assignment;
+DROP TABLE IF EXISTS budget.monthly_plan_account;
+DROP TABLE IF EXISTS budget.monthly_plan;
+DROP SEQUENCE IF EXISTS budget.monthly_plan_id;
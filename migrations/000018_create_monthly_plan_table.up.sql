@@ -0,0 +1,46 @@
+CREATE SEQUENCE IF NOT EXISTS budget.monthly_plan_id;
+CREATE TABLE IF NOT EXISTS budget.monthly_plan(
+    id BIGINT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    year INTEGER NOT NULL CHECK (year > 0),
+    month SMALLINT NOT NULL CHECK (month BETWEEN 1 AND 12),
+    currency VARCHAR(3) NOT NULL,
+    expected_income_minor_units BIGINT NOT NULL CHECK (expected_income_minor_units >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by VARCHAR (255) NOT NULL,
+    last_modified_at TIMESTAMP WITH TIME ZONE,
+    last_modified_by VARCHAR (255),
+    version BIGINT NOT NULL,
+    CONSTRAINT fk_monthly_plan_user FOREIGN KEY(user_id) REFERENCES budget.user(id) ON DELETE CASCADE,
+    CONSTRAINT uq_monthly_plan_user_month UNIQUE(user_id, year, month)
+);
+
+CREATE TABLE IF NOT EXISTS budget.monthly_plan_account(
+    plan_id BIGINT NOT NULL,
+    account_id BIGINT NOT NULL,
+    CONSTRAINT fk_monthly_plan_account_plan_id FOREIGN KEY(plan_id) REFERENCES budget.monthly_plan(id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+    CONSTRAINT fk_monthly_plan_account_account_id FOREIGN KEY(account_id) REFERENCES budget.account(id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS budget.monthly_plan_assignment(
+    plan_id BIGINT NOT NULL,
+    category_id BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    amount_minor_units BIGINT NOT NULL CHECK (amount_minor_units >= 0),
+    CONSTRAINT fk_monthly_plan_assignment_plan_id FOREIGN KEY(plan_id) REFERENCES budget.monthly_plan(id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+    CONSTRAINT fk_monthly_plan_assignment_category_id FOREIGN KEY(category_id) REFERENCES budget.category(id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+    CONSTRAINT uq_monthly_plan_assignment_category UNIQUE(plan_id, category_id)
+);
+
+DROP TRIGGER IF EXISTS audit_monthly_plan ON budget.monthly_plan;
+create trigger audit_monthly_plan
+BEFORE update on budget.monthly_plan
+for each row execute procedure audit_record();
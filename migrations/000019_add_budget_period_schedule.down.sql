@@ -0,0 +1,2 @@
+ALTER TABLE budget.budget DROP COLUMN IF EXISTS period_start_day;
+ALTER TABLE budget.budget DROP COLUMN IF EXISTS period_anchor;
@@ -0,0 +1,2 @@
+ALTER TABLE budget.budget ADD COLUMN IF NOT EXISTS period_anchor DATE;
+ALTER TABLE budget.budget ADD COLUMN IF NOT EXISTS period_start_day SMALLINT CHECK (period_start_day BETWEEN 1 AND 28);
@@ -0,0 +1,5 @@
+ALTER TABLE budget.record DROP COLUMN IF EXISTS occurred_at;
+
+ALTER TABLE budget.user DROP COLUMN IF EXISTS week_start;
+ALTER TABLE budget.user DROP COLUMN IF EXISTS locale;
+ALTER TABLE budget.user DROP COLUMN IF EXISTS timezone;
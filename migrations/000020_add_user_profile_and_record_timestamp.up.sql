@@ -0,0 +1,9 @@
+ALTER TABLE budget.user ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE budget.user ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT 'en-US';
+ALTER TABLE budget.user ADD COLUMN IF NOT EXISTS week_start VARCHAR(9) NOT NULL DEFAULT 'Monday';
+
+-- The date of a record is the day of the transaction in the timezone of the user; occurred_at is the moment of the transaction.
+-- Records created before the moment was kept are taken to have occurred at the start of their date.
+ALTER TABLE budget.record ADD COLUMN IF NOT EXISTS occurred_at TIMESTAMP WITH TIME ZONE;
+UPDATE budget.record SET occurred_at = date::TIMESTAMP AT TIME ZONE 'UTC' WHERE occurred_at IS NULL;
+ALTER TABLE budget.record ALTER COLUMN occurred_at SET NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE budget.user DROP COLUMN IF EXISTS default_category_id;
+ALTER TABLE budget.user DROP COLUMN IF EXISTS default_account_id;
+ALTER TABLE budget.user DROP COLUMN IF EXISTS currency;
+ALTER TABLE budget.user DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,7 @@
+ALTER TABLE budget.user ADD COLUMN IF NOT EXISTS display_name VARCHAR(50);
+ALTER TABLE budget.user ADD COLUMN IF NOT EXISTS currency VARCHAR(3);
+
+-- The default account and category are not foreign keys: they are deleted along with the user,
+-- and a default that no longer exists is ignored.
+ALTER TABLE budget.user ADD COLUMN IF NOT EXISTS default_account_id BIGINT;
+ALTER TABLE budget.user ADD COLUMN IF NOT EXISTS default_category_id BIGINT;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS budget.user_credential;
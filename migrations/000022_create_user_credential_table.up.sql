@@ -0,0 +1,9 @@
+-- The password with which a user logs in. Only a salted, slow hash of the password is kept.
+CREATE TABLE IF NOT EXISTS budget.user_credential(
+    user_id BIGINT NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CONSTRAINT pk_user_credential PRIMARY KEY(user_id),
+    CONSTRAINT fk_user_credential_user_id FOREIGN KEY(user_id) REFERENCES budget.user(id)
+        ON DELETE CASCADE
+);
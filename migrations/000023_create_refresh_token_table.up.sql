@@ -0,0 +1,14 @@
+-- The refresh tokens issued to a user. A refresh token can be exchanged for new tokens once; it is marked as used when it is.
+-- Used tokens are kept until they expire, so that the reuse of a token that may have leaked can be detected.
+CREATE TABLE IF NOT EXISTS budget.refresh_token(
+    id VARCHAR(32) NOT NULL,
+    user_id BIGINT NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT pk_refresh_token PRIMARY KEY(id),
+    CONSTRAINT fk_refresh_token_user_id FOREIGN KEY(user_id) REFERENCES budget.user(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_token_user_id ON budget.refresh_token(user_id);
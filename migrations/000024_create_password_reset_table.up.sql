@@ -0,0 +1,12 @@
+-- A request by a user to reset their password, or to set it if they registered before passwords were required.
+-- Only a hash of the reset token is kept. A later request replaces the token of an earlier one.
+CREATE TABLE IF NOT EXISTS budget.password_reset(
+    user_id BIGINT NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    token_expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CONSTRAINT pk_password_reset PRIMARY KEY(user_id),
+    CONSTRAINT uq_password_reset_token_hash UNIQUE(token_hash),
+    CONSTRAINT fk_password_reset_user_id FOREIGN KEY(user_id) REFERENCES budget.user(id)
+        ON DELETE CASCADE
+);
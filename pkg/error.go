@@ -218,35 +218,93 @@ const (
 	ErrServiceAccountIdRequired
 	ErrBudgetValidation
 	ErrBudgetNotFound
+	ErrRecordSearchValidation
+	ErrRecordNotFound
+	ErrRecordVersionConflict
+	ErrRecurringRecordValidation
+	ErrRecurringRecordNotFound
+	ErrImportProfileValidation
+	ErrImportProfileNotFound
+	ErrImportValidation
+	ErrRecordDuplicated
+	ErrCategoryRuleValidation
+	ErrCategoryRuleNotFound
+	ErrExportValidation
+	ErrUserDeletionTokenInvalid
+	ErrUserDeletionNotFound
+	ErrUserImportValidation
+	ErrMonthlyPlanValidation
+	ErrMonthlyPlanNotFound
+	ErrMonthlyPlanDuplicated
+	ErrPeriodInvalid
+	ErrUserProfileValidation
+	ErrUserPasswordValidation
+	ErrAuthenticationFailed
+	ErrAccessTokenInvalid
+	ErrRecurringRecordVersionConflict
+	ErrBudgetVersionConflict
+	ErrMonthlyPlanVersionConflict
+	ErrUserVersionConflict
+	ErrPasswordResetTokenInvalid
+	ErrPasswordResetUnavailable
 )
 
 var errorCodeNames = map[ErrorCode]string{
-	ErrUnknown:                     "UNKOWN",
-	ErrDatabaseConnectivity:        "DATABASE_CONNECTIVITY",
-	ErrDatabaseState:               "DATABASE_STATE",
-	ErrUserIdDuplicated:            "DUPLICATE_USER_ID",
-	ErrUserEmailInvalid:            "USER_EMAIL_INVALID",
-	ErrUserEmailDuplicated:         "DUPLICATE_USER_EMAIL",
-	ErrUserNotFound:                "USER_NOT_FOUND",
-	ErrAccountValidation:           "ACCOUNT_VALIDATION_FAILED",
-	ErrAccountNotFound:             "ACCOUNT_NOT_FOUND",
-	ErrAccountNameDuplicated:       "ACCOUNT_NAME_DUPLICATED",
-	ErrCurrencyInvalidCode:         "INVALID_CURRENCY_CODE",
-	ErrCategoryValidation:          "CATEGORY_VALIDATION_FAILED",
-	ErrCategoryNameDuplicated:      "CATEGORY_NAME_DUPLICATED",
-	ErrCategoriesNotFound:          "CATEGORIES_NOT_FOUND",
-	ErrRecordValidation:            "RECORD_VALIDATION_FAILED",
-	ErrRecordsPeriodOfEmptySet:     "RECORDS_PERIOD_OF_EMPTY_SET",
-	ErrAmountOverflow:              "AMOUNT_OVERFLOW",
-	ErrAmountMismatchingCurrencies: "AMOUNT_MISMATCHING_CURRENCIES",
-	ErrAmountTotalOfEmptySet:       "AMOUNT_TOTAL_OF_EMPTY_SET",
-	ErrAuditValidation:             "AUDIT_VALIDATION_FAILED",
-	ErrAuditUpdatedByBadFormat:     "AUDIT_UPDATED_BY_BAD_FORMAT",
-	ErrRequestUnmarshallingFailed:  "REQUEST_UNMARSHALLING_FAILED",
-	ErrServiceUserIdRequired:       "SERVICE_REQUIRED_USER_ID",
-	ErrServiceAccountIdRequired:    "SERVICE_REQUIRED_ACCOUNT_ID",
-	ErrBudgetValidation:            "BUDGET_VALIDATION_FAILED",
-	ErrBudgetNotFound:              "BUDGET_NOT_FOUND",
+	ErrUnknown:                        "UNKOWN",
+	ErrDatabaseConnectivity:           "DATABASE_CONNECTIVITY",
+	ErrDatabaseState:                  "DATABASE_STATE",
+	ErrUserIdDuplicated:               "DUPLICATE_USER_ID",
+	ErrUserEmailInvalid:               "USER_EMAIL_INVALID",
+	ErrUserEmailDuplicated:            "DUPLICATE_USER_EMAIL",
+	ErrUserNotFound:                   "USER_NOT_FOUND",
+	ErrAccountValidation:              "ACCOUNT_VALIDATION_FAILED",
+	ErrAccountNotFound:                "ACCOUNT_NOT_FOUND",
+	ErrAccountNameDuplicated:          "ACCOUNT_NAME_DUPLICATED",
+	ErrCurrencyInvalidCode:            "INVALID_CURRENCY_CODE",
+	ErrCategoryValidation:             "CATEGORY_VALIDATION_FAILED",
+	ErrCategoryNameDuplicated:         "CATEGORY_NAME_DUPLICATED",
+	ErrCategoriesNotFound:             "CATEGORIES_NOT_FOUND",
+	ErrRecordValidation:               "RECORD_VALIDATION_FAILED",
+	ErrRecordsPeriodOfEmptySet:        "RECORDS_PERIOD_OF_EMPTY_SET",
+	ErrAmountOverflow:                 "AMOUNT_OVERFLOW",
+	ErrAmountMismatchingCurrencies:    "AMOUNT_MISMATCHING_CURRENCIES",
+	ErrAmountTotalOfEmptySet:          "AMOUNT_TOTAL_OF_EMPTY_SET",
+	ErrAuditValidation:                "AUDIT_VALIDATION_FAILED",
+	ErrAuditUpdatedByBadFormat:        "AUDIT_UPDATED_BY_BAD_FORMAT",
+	ErrRequestUnmarshallingFailed:     "REQUEST_UNMARSHALLING_FAILED",
+	ErrServiceUserIdRequired:          "SERVICE_REQUIRED_USER_ID",
+	ErrServiceAccountIdRequired:       "SERVICE_REQUIRED_ACCOUNT_ID",
+	ErrBudgetValidation:               "BUDGET_VALIDATION_FAILED",
+	ErrBudgetNotFound:                 "BUDGET_NOT_FOUND",
+	ErrRecordSearchValidation:         "RECORD_SEARCH_VALIDATION_FAILED",
+	ErrRecordNotFound:                 "RECORD_NOT_FOUND",
+	ErrRecordVersionConflict:          "RECORD_VERSION_CONFLICT",
+	ErrRecurringRecordValidation:      "RECURRING_RECORD_VALIDATION_FAILED",
+	ErrRecurringRecordNotFound:        "RECURRING_RECORD_NOT_FOUND",
+	ErrImportProfileValidation:        "IMPORT_PROFILE_VALIDATION_FAILED",
+	ErrImportProfileNotFound:          "IMPORT_PROFILE_NOT_FOUND",
+	ErrImportValidation:               "IMPORT_VALIDATION_FAILED",
+	ErrRecordDuplicated:               "RECORD_DUPLICATED",
+	ErrCategoryRuleValidation:         "CATEGORY_RULE_VALIDATION_FAILED",
+	ErrCategoryRuleNotFound:           "CATEGORY_RULE_NOT_FOUND",
+	ErrExportValidation:               "EXPORT_VALIDATION_FAILED",
+	ErrUserDeletionTokenInvalid:       "USER_DELETION_TOKEN_INVALID",
+	ErrUserDeletionNotFound:           "USER_DELETION_NOT_FOUND",
+	ErrUserImportValidation:           "USER_IMPORT_VALIDATION_FAILED",
+	ErrMonthlyPlanValidation:          "MONTHLY_PLAN_VALIDATION_FAILED",
+	ErrMonthlyPlanNotFound:            "MONTHLY_PLAN_NOT_FOUND",
+	ErrMonthlyPlanDuplicated:          "MONTHLY_PLAN_DUPLICATED",
+	ErrPeriodInvalid:                  "PERIOD_INVALID",
+	ErrUserProfileValidation:          "USER_PROFILE_VALIDATION_FAILED",
+	ErrUserPasswordValidation:         "USER_PASSWORD_VALIDATION_FAILED",
+	ErrAuthenticationFailed:           "AUTHENTICATION_FAILED",
+	ErrAccessTokenInvalid:             "ACCESS_TOKEN_INVALID",
+	ErrRecurringRecordVersionConflict: "RECURRING_RECORD_VERSION_CONFLICT",
+	ErrBudgetVersionConflict:          "BUDGET_VERSION_CONFLICT",
+	ErrMonthlyPlanVersionConflict:     "MONTHLY_PLAN_VERSION_CONFLICT",
+	ErrUserVersionConflict:            "USER_VERSION_CONFLICT",
+	ErrPasswordResetTokenInvalid:      "PASSWORD_RESET_TOKEN_INVALID",
+	ErrPasswordResetUnavailable:       "PASSWORD_RESET_UNAVAILABLE",
 }
 
 func (c ErrorCode) name() string {
@@ -287,9 +345,41 @@ func (c ErrorCode) status() int {
 	case ErrRequestUnmarshallingFailed:
 		fallthrough
 	case ErrServiceAccountIdRequired:
+		fallthrough
+	case ErrBudgetValidation:
+		fallthrough
+	case ErrRecordSearchValidation:
+		fallthrough
+	case ErrRecurringRecordValidation:
+		fallthrough
+	case ErrImportProfileValidation:
+		fallthrough
+	case ErrImportValidation:
+		fallthrough
+	case ErrCategoryRuleValidation:
+		fallthrough
+	case ErrExportValidation:
+		fallthrough
+	case ErrUserDeletionTokenInvalid:
+		fallthrough
+	case ErrUserImportValidation:
+		fallthrough
+	case ErrMonthlyPlanValidation:
+		fallthrough
+	case ErrPeriodInvalid:
+		fallthrough
+	case ErrUserProfileValidation:
+		fallthrough
+	case ErrUserPasswordValidation:
+		fallthrough
+	case ErrPasswordResetTokenInvalid:
 		return http.StatusBadRequest
 
 	case ErrServiceUserIdRequired:
+		fallthrough
+	case ErrAuthenticationFailed:
+		fallthrough
+	case ErrAccessTokenInvalid:
 		return http.StatusUnauthorized
 
 	case ErrUserNotFound:
@@ -299,8 +389,38 @@ func (c ErrorCode) status() int {
 	case ErrCategoriesNotFound:
 		fallthrough
 	case ErrBudgetNotFound:
+		fallthrough
+	case ErrRecordNotFound:
+		fallthrough
+	case ErrRecurringRecordNotFound:
+		fallthrough
+	case ErrImportProfileNotFound:
+		fallthrough
+	case ErrCategoryRuleNotFound:
+		fallthrough
+	case ErrUserDeletionNotFound:
+		fallthrough
+	case ErrMonthlyPlanNotFound:
 		return http.StatusNotFound
 
+	case ErrRecordVersionConflict:
+		fallthrough
+	case ErrRecurringRecordVersionConflict:
+		fallthrough
+	case ErrBudgetVersionConflict:
+		fallthrough
+	case ErrMonthlyPlanVersionConflict:
+		fallthrough
+	case ErrUserVersionConflict:
+		fallthrough
+	case ErrRecordDuplicated:
+		fallthrough
+	case ErrMonthlyPlanDuplicated:
+		return http.StatusConflict
+
+	case ErrPasswordResetUnavailable:
+		return http.StatusServiceUnavailable
+
 	case ErrDatabaseConnectivity:
 		fallthrough
 	case ErrDatabaseState:
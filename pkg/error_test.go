@@ -70,4 +70,13 @@ func (suite *ErrorTestSuite) Test_GIVEN_errorCode_WHEN_mappedToHttpStatus_THEN_m
 	assert.Equal(suite.T(), http.StatusBadRequest, ErrRequestUnmarshallingFailed.status())
 	assert.Equal(suite.T(), http.StatusUnauthorized, ErrServiceUserIdRequired.status())
 	assert.Equal(suite.T(), http.StatusBadRequest, ErrServiceAccountIdRequired.status())
+	assert.Equal(suite.T(), http.StatusBadRequest, ErrBudgetValidation.status())
+	assert.Equal(suite.T(), http.StatusNotFound, ErrBudgetNotFound.status())
+	assert.Equal(suite.T(), http.StatusConflict, ErrRecordVersionConflict.status())
+	assert.Equal(suite.T(), http.StatusConflict, ErrRecurringRecordVersionConflict.status())
+	assert.Equal(suite.T(), http.StatusConflict, ErrBudgetVersionConflict.status())
+	assert.Equal(suite.T(), http.StatusConflict, ErrMonthlyPlanVersionConflict.status())
+	assert.Equal(suite.T(), http.StatusConflict, ErrUserVersionConflict.status())
+	assert.Equal(suite.T(), http.StatusBadRequest, ErrPasswordResetTokenInvalid.status())
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, ErrPasswordResetUnavailable.status())
 }
@@ -0,0 +1,213 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+// ArchiveVersion is the version of the format of the archive of the data of a user.
+// It is incremented whenever the format changes in a way that older archives can not be restored as they are.
+const ArchiveVersion = 1
+
+// Archive is all the data of a user:
+//
+//	{"version": 1, "exportedAt": "...", "user": {...}, "categories": [...], "budgets": [...], "accounts": [{..., "records": [...]}]}
+//
+// Ids are those of the exported user, so that the records, categories and accounts that refer to one another can be matched when an archive is restored.
+type Archive struct {
+	Version    int                `json:"version"`
+	ExportedAt string             `json:"exportedAt"`
+	User       ArchivedUser       `json:"user"`
+	Categories []ArchivedCategory `json:"categories"`
+	Budgets    []ArchivedBudget   `json:"budgets"`
+	// Accounts are omitted while the archive is written, so that the records of each account can be written as they are loaded.
+	Accounts []ArchivedAccount `json:"accounts,omitempty"`
+}
+
+type ArchivedUser struct {
+	Id    uint64 `json:"id"`
+	Email string `json:"email"`
+	Audit
+}
+
+type ArchivedCategory struct {
+	Category
+	Audit
+}
+
+type ArchivedCategoryBudget struct {
+	CategoryId      uint64 `json:"categoryId"`
+	MaxLimit        Amount `json:"maxLimit"`
+	AlertThresholds []uint `json:"alertThresholds"`
+	Rollover        string `json:"rollover"`
+}
+
+type ArchivedBudget struct {
+	Id     uint64 `json:"id"`
+	Period string `json:"period"`
+	// PeriodAnchor is the first day of a bi-weekly period, formatted as yyyy-MM-dd
+	PeriodAnchor string `json:"periodAnchor,omitempty"`
+	// PeriodStartDay is the day of the month on which months starting on a day start
+	PeriodStartDay  uint                     `json:"periodStartDay,omitempty"`
+	AccountIds      []uint64                 `json:"accountIds"`
+	CategoryBudgets []ArchivedCategoryBudget `json:"categoryBudgets"`
+	Audit
+}
+
+// ArchivedAccount is an account and its records, oldest first.
+type ArchivedAccount struct {
+	Account
+	Audit
+	Records []Record `json:"records"`
+}
+
+// ForEachRecord calls fn with each record of an account, oldest first.
+type ForEachRecord func(account ledger.Account, fn func(ledger.Record) error) error
+
+// WriteArchive writes the archive of all the data of a user as JSON.
+// The records are written as they are loaded by forEachRecord, so that all the records of the user are not held in memory at once.
+func WriteArchive(
+	w io.Writer,
+	user ledger.User,
+	categories ledger.Categories,
+	budgets []ledger.Budget,
+	accounts ledger.Accounts,
+	forEachRecord ForEachRecord,
+	exportedAt time.Time,
+) error {
+	accountNames := make(map[ledger.AccountId]string, len(accounts))
+	for _, account := range accounts {
+		accountNames[account.Id()] = account.Name()
+	}
+
+	archive := Archive{
+		Version:    ArchiveVersion,
+		ExportedAt: exportedAt.UTC().Format(time.RFC3339),
+		User: ArchivedUser{
+			Id:    uint64(user.Id()),
+			Email: user.Email().Address,
+			Audit: makeAudit(user),
+		},
+		Categories: make([]ArchivedCategory, 0, len(categories)),
+		Budgets:    make([]ArchivedBudget, 0, len(budgets)),
+	}
+	for _, category := range categories {
+		archive.Categories = append(archive.Categories, ArchivedCategory{
+			Category: makeCategory(category),
+			Audit:    makeAudit(category),
+		})
+	}
+	for _, budget := range budgets {
+		archive.Budgets = append(archive.Budgets, makeArchivedBudget(budget))
+	}
+
+	// The accounts are written last, and the records of each account are written between the details of the account and its closing brace.
+	if err := writeOpenObject(w, archive, "accounts"); err != nil {
+		return err
+	}
+
+	for i, account := range accounts {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+
+		if err := writeOpenObject(w, struct {
+			Account
+			Audit
+		}{makeAccount(account), makeAudit(account)}, "records"); err != nil {
+			return err
+		}
+
+		count := 0
+		if err := forEachRecord(account, func(record ledger.Record) error {
+			data, err := json.Marshal(makeRecord(record, accountNames))
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				if _, err = io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			count += 1
+			_, err = w.Write(data)
+			return err
+		}); err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(w, "]}"); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "]}\n")
+	return err
+}
+
+// ReadArchive reads an archive written by WriteArchive.
+// An error is returned if the archive is not valid JSON or was written in a version of the format that can not be restored.
+func ReadArchive(r io.Reader) (Archive, error) {
+	var archive Archive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return Archive{}, pkg.ValidationErrorWithFields(pkg.ErrUserImportValidation, "Archive is not valid JSON", err, nil)
+	}
+
+	if archive.Version < 1 || archive.Version > ArchiveVersion {
+		return Archive{}, pkg.ValidationErrorWithFields(pkg.ErrUserImportValidation, fmt.Sprintf("Unsupported archive version %d", archive.Version), nil, map[string]string{
+			"version": fmt.Sprintf("version must be between 1 and %d", ArchiveVersion),
+		})
+	}
+	return archive, nil
+}
+
+func makeArchivedBudget(budget ledger.Budget) ArchivedBudget {
+	b := ArchivedBudget{
+		Id:              uint64(budget.Id()),
+		Period:          string(budget.PeriodType()),
+		PeriodStartDay:  budget.PeriodCalculator().StartDay(),
+		AccountIds:      make([]uint64, 0, len(budget.AccountIds())),
+		CategoryBudgets: make([]ArchivedCategoryBudget, 0, len(budget.CategoryBudgets())),
+		Audit:           makeAudit(budget),
+	}
+	if anchorDate := budget.PeriodCalculator().AnchorDate(); !anchorDate.IsZero() {
+		b.PeriodAnchor = anchorDate.Format("2006-01-02")
+	}
+	for _, accountId := range budget.AccountIds() {
+		b.AccountIds = append(b.AccountIds, uint64(accountId))
+	}
+	for _, categoryBudget := range budget.CategoryBudgets() {
+		b.CategoryBudgets = append(b.CategoryBudgets, ArchivedCategoryBudget{
+			CategoryId:      uint64(categoryBudget.CategoryId()),
+			MaxLimit:        makeAmount(categoryBudget.MaxLimit()),
+			AlertThresholds: append([]uint{}, categoryBudget.AlertThresholds()...),
+			Rollover:        string(categoryBudget.Rollover()),
+		})
+	}
+	return b
+}
+
+// writeOpenObject writes v as a JSON object that is left open at the start of an array with the given name,
+// e.g. {"id":1,"records":[
+// v must not already have a field with the given name.
+func writeOpenObject(w io.Writer, v interface{}, arrayName string) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	data = bytes.TrimSuffix(data, []byte("}"))
+	if !bytes.HasSuffix(data, []byte("{")) {
+		data = append(data, ',')
+	}
+	_, err = fmt.Fprintf(w, `%s%q:[`, data, arrayName)
+	return err
+}
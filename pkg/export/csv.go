@@ -0,0 +1,94 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+var csvHeader = []string{
+	"id",
+	"date",
+	"type",
+	"note",
+	"category",
+	"amount",
+	"currency",
+	"beneficiary_account",
+	"transfer_reference",
+	"exchange_rate",
+	"created_by",
+	"created_at",
+	"modified_by",
+	"modified_at",
+	"version",
+}
+
+// csvWriter writes a row for each record. A split record is written as a row for each of its lines,
+// with the note, category and amount of the line and the other details of the record.
+type csvWriter struct {
+	w            *csv.Writer
+	accountNames map[ledger.AccountId]string
+	started      bool
+}
+
+func newCsvWriter(w io.Writer, accountNames map[ledger.AccountId]string) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), accountNames: accountNames}
+}
+
+func (c *csvWriter) begin() error {
+	if c.started {
+		return nil
+	}
+	c.started = true
+	return c.w.Write(csvHeader)
+}
+
+func (c *csvWriter) Write(record ledger.Record) error {
+	if err := c.begin(); err != nil {
+		return err
+	}
+
+	var beneficiary, modifiedBy, modifiedAt string
+	if record.Type() == ledger.Transfer {
+		beneficiary = c.accountNames[record.BeneficiaryId()]
+	}
+	if isModified(record) {
+		modifiedBy = record.ModifiedBy().String()
+		modifiedAt = record.ModifiedAtUTC().Format(time.RFC3339)
+	}
+
+	for _, line := range record.Lines() {
+		if err := c.w.Write([]string{
+			fmt.Sprint(record.Id()),
+			record.OccurredAtUTC().Format(time.RFC3339),
+			string(record.Type()),
+			line.Note(),
+			line.Category().Name(),
+			decimal(line.Amount()),
+			line.Amount().Currency().CurrencyCode(),
+			beneficiary,
+			string(record.TransferReference()),
+			record.ExchangeRate().String(),
+			record.CreatedBy().String(),
+			record.CreatedAtUTC().Format(time.RFC3339),
+			modifiedBy,
+			modifiedAt,
+			fmt.Sprint(record.Version()),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.begin(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
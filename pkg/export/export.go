@@ -0,0 +1,94 @@
+// Package export writes the records of an account as CSV, JSON or OFX files.
+// Records are written one at a time, so that an export can be streamed without holding all the records in memory.
+package export
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bojanz/currency"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+// Format is the format of an exported file
+type Format string
+
+const (
+	FormatCsv  Format = "CSV"
+	FormatJson Format = "JSON"
+	FormatOfx  Format = "OFX"
+)
+
+// ParseFormat parses the format of an export, ignoring case. CSV is used when the format is blank.
+func ParseFormat(format string) (Format, error) {
+	switch f := Format(strings.ToUpper(strings.TrimSpace(format))); f {
+	case "":
+		return FormatCsv, nil
+	case FormatCsv, FormatJson, FormatOfx:
+		return f, nil
+	}
+	return "", pkg.ValidationErrorWithFields(pkg.ErrExportValidation, fmt.Sprintf("Unknown export format %q", format), nil, map[string]string{
+		"format": "format must be csv, json or ofx",
+	})
+}
+
+func (f Format) Extension() string {
+	return strings.ToLower(string(f))
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJson:
+		return "application/json;charset=utf-8"
+	case FormatOfx:
+		return "application/x-ofx"
+	default:
+		return "text/csv;charset=utf-8"
+	}
+}
+
+// Writer writes the records of an account to a file.
+type Writer interface {
+	Write(record ledger.Record) error
+	// Close writes the end of the file. The underlying writer is not closed.
+	Close() error
+}
+
+// NewWriter returns a writer of the records of the account in the given format.
+// accountNames are the names of the accounts of the user by id, so that the beneficiaries of transfers are written by name.
+func NewWriter(format Format, w io.Writer, account ledger.Account, accountNames map[ledger.AccountId]string) Writer {
+	switch format {
+	case FormatJson:
+		return newJsonWriter(w, account, accountNames)
+	case FormatOfx:
+		return newOfxWriter(w, account, time.Now().UTC())
+	default:
+		return newCsvWriter(w, accountNames)
+	}
+}
+
+var unsafeFileNameCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+// FileName is the name of the file of the records of an account e.g. "1-current.csv"
+func FileName(account ledger.Account, format Format) string {
+	name := strings.Trim(unsafeFileNameCharacters.ReplaceAllString(strings.ToLower(account.Name()), "-"), "-")
+	return fmt.Sprintf("%d-%s.%s", account.Id(), name, format.Extension())
+}
+
+// decimal formats an amount as a decimal number with a '.' decimal separator e.g. -12.50
+func decimal(amount ledger.Money) string {
+	minorUnits, _ := amount.MinorUnits()
+	value, err := currency.NewAmountFromInt64(minorUnits, amount.Currency().CurrencyCode())
+	if err != nil {
+		return fmt.Sprint(minorUnits)
+	}
+	return value.Number()
+}
+
+func isModified(auditable interface{ ModifiedBy() ledger.UpdatedBy }) bool {
+	return auditable.ModifiedBy() != (ledger.UpdatedBy{})
+}
@@ -0,0 +1,270 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	"github.com/w-k-s/simple-budget-tracker/pkg/statement/ofx"
+)
+
+type ExportTestSuite struct {
+	suite.Suite
+	current      ledger.Account
+	savings      ledger.Account
+	accountNames map[ledger.AccountId]string
+	records      ledger.Records
+}
+
+func TestExportTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportTestSuite))
+}
+
+// -- SETUP
+
+func (suite *ExportTestSuite) SetupTest() {
+	updatedBy := ledger.MustMakeUpdatedByUserId(ledger.UserId(1))
+	date := time.Date(2021, time.July, 3, 10, 0, 0, 0, time.UTC)
+
+	suite.current, _ = ledger.NewAccount(ledger.AccountId(1), "Current Account", ledger.AccountTypeCurrent, "AED", updatedBy)
+	suite.savings, _ = ledger.NewAccount(ledger.AccountId(2), "Savings", ledger.AccountTypeSaving, "AED", updatedBy)
+	suite.accountNames = map[ledger.AccountId]string{
+		suite.current.Id(): suite.current.Name(),
+		suite.savings.Id(): suite.savings.Name(),
+	}
+
+	groceries, _ := ledger.NewCategory(ledger.CategoryId(1), "Groceries", updatedBy)
+	household, _ := ledger.NewCategory(ledger.CategoryId(2), "Household", updatedBy)
+	savings, _ := ledger.NewCategory(ledger.CategoryId(3), "Savings", updatedBy)
+
+	sourceAccountId, beneficiaryId, transferReference := ledger.NoTransfer()
+	expense, _ := ledger.NewRecord(
+		ledger.RecordId(1),
+		"Carrefour <Mall of the Emirates> & Co",
+		groceries,
+		ledger.MustMoney(ledger.NewMoney("AED", -1234_50)),
+		date,
+		ledger.Expense,
+		sourceAccountId,
+		beneficiaryId,
+		ledger.NoBeneficiaryType,
+		transferReference,
+		updatedBy,
+	)
+
+	groceriesLine, _ := ledger.NewRecordSplit("Food", groceries, ledger.MustMoney(ledger.NewMoney("AED", -60_00)))
+	householdLine, _ := ledger.NewRecordSplit("Soap", household, ledger.MustMoney(ledger.NewMoney("AED", -40_00)))
+	split, _ := ledger.NewSplitRecord(
+		ledger.RecordId(2),
+		"Spinneys",
+		groceries,
+		ledger.MustMoney(ledger.NewMoney("AED", -100_00)),
+		date.AddDate(0, 0, 1),
+		ledger.Expense,
+		ledger.RecordSplits{groceriesLine, householdLine},
+		updatedBy,
+	)
+
+	transfer, _ := ledger.NewTransfer(
+		ledger.RecordId(3),
+		ledger.RecordId(4),
+		"Monthly savings",
+		savings,
+		ledger.MustMoney(ledger.NewMoney("AED", 500_00)),
+		nil,
+		ledger.NoExchangeRate,
+		date.AddDate(0, 0, 2),
+		suite.current,
+		suite.savings,
+		updatedBy,
+	)
+
+	suite.records = ledger.Records{expense, split, transfer.Debit()}
+}
+
+func (suite *ExportTestSuite) export(format Format) string {
+	var buffer bytes.Buffer
+	writer := NewWriter(format, &buffer, suite.current, suite.accountNames)
+	for _, record := range suite.records {
+		assert.Nil(suite.T(), writer.Write(record))
+	}
+	assert.Nil(suite.T(), writer.Close())
+	return buffer.String()
+}
+
+// -- SUITE
+
+func (suite *ExportTestSuite) Test_GIVEN_formats_WHEN_parsed_THEN_caseIsIgnoredAndCsvIsTheDefault() {
+	for input, expected := range map[string]Format{"": FormatCsv, "csv": FormatCsv, "Json": FormatJson, "OFX": FormatOfx} {
+		format, err := ParseFormat(input)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), expected, format)
+	}
+
+	_, err := ParseFormat("xlsx")
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *ExportTestSuite) Test_GIVEN_anAccount_WHEN_fileNameIsMade_THEN_nameIsSafe() {
+	assert.Equal(suite.T(), "1-current-account.ofx", FileName(suite.current, FormatOfx))
+}
+
+func (suite *ExportTestSuite) Test_GIVEN_records_WHEN_exportedAsCsv_THEN_splitRecordsHaveARowPerLine() {
+	// WHEN
+	rows, err := csv.NewReader(bytes.NewBufferString(suite.export(FormatCsv))).ReadAll()
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), rows, 5)
+	assert.Equal(suite.T(), csvHeader, rows[0])
+
+	assert.Equal(suite.T(), []string{"1", "2021-07-03T10:00:00Z", "EXPENSE", "Carrefour <Mall of the Emirates> & Co", "Groceries", "-1234.50", "AED", "", "", ""}, rows[1][:10])
+	assert.Equal(suite.T(), "UserId: 1", rows[1][10])
+	assert.Equal(suite.T(), "", rows[1][12])
+	assert.Equal(suite.T(), "1", rows[1][14])
+
+	assert.Equal(suite.T(), []string{"2", "Food", "Groceries", "-60.00"}, []string{rows[2][0], rows[2][3], rows[2][4], rows[2][5]})
+	assert.Equal(suite.T(), []string{"2", "Soap", "Household", "-40.00"}, []string{rows[3][0], rows[3][3], rows[3][4], rows[3][5]})
+
+	assert.Equal(suite.T(), "TRANSFER", rows[4][2])
+	assert.Equal(suite.T(), "-500.00", rows[4][5])
+	assert.Equal(suite.T(), "Savings", rows[4][7])
+	assert.Equal(suite.T(), string(suite.records[2].TransferReference()), rows[4][8])
+}
+
+func (suite *ExportTestSuite) Test_GIVEN_noRecords_WHEN_exported_THEN_fileIsStillComplete() {
+	// GIVEN
+	suite.records = ledger.Records{}
+
+	// THEN
+	assert.Equal(suite.T(), "id,date,type,note,category,amount,currency,beneficiary_account,transfer_reference,exchange_rate,created_by,created_at,modified_by,modified_at,version\n", suite.export(FormatCsv))
+	assert.JSONEq(suite.T(), `{"account":{"id":1,"name":"Current Account","type":"Current","currency":"AED"},"records":[]}`, suite.export(FormatJson))
+
+	stmt, err := ofx.Parse(bytes.NewBufferString(suite.export(FormatOfx)))
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), stmt.Transactions)
+}
+
+func (suite *ExportTestSuite) Test_GIVEN_records_WHEN_exportedAsJson_THEN_transfersAndSplitsAreIncluded() {
+	// WHEN
+	var exported struct {
+		Account Account  `json:"account"`
+		Records []Record `json:"records"`
+	}
+	err := json.Unmarshal([]byte(suite.export(FormatJson)), &exported)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), Account{Id: 1, Name: "Current Account", Type: "Current", Currency: "AED"}, exported.Account)
+	assert.Len(suite.T(), exported.Records, 3)
+
+	assert.Equal(suite.T(), Category{Id: 1, Name: "Groceries"}, exported.Records[0].Category)
+	assert.Equal(suite.T(), Amount{Currency: "AED", Value: -1234_50}, exported.Records[0].Amount)
+	assert.Equal(suite.T(), UpdatedBy{Kind: "USER", UserId: 1}, exported.Records[0].CreatedBy)
+	assert.Nil(suite.T(), exported.Records[0].ModifiedBy)
+
+	assert.Len(suite.T(), exported.Records[1].Splits, 2)
+	assert.Equal(suite.T(), "Household", exported.Records[1].Splits[1].Category.Name)
+
+	assert.NotNil(suite.T(), exported.Records[2].Transfer)
+	assert.Equal(suite.T(), string(suite.records[2].TransferReference()), exported.Records[2].Transfer.Reference)
+	assert.Equal(suite.T(), uint64(2), exported.Records[2].Transfer.Beneficiary.Id)
+	assert.Equal(suite.T(), "Savings", exported.Records[2].Transfer.Beneficiary.Name)
+}
+
+func (suite *ExportTestSuite) Test_GIVEN_records_WHEN_exportedAsOfx_THEN_statementCanBeImported() {
+	// WHEN
+	stmt, err := ofx.Parse(bytes.NewBufferString(suite.export(FormatOfx)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "AED", stmt.Currency)
+	assert.Len(suite.T(), stmt.Transactions, 3)
+
+	assert.Equal(suite.T(), "1", stmt.Transactions[0].ExternalId)
+	assert.Equal(suite.T(), time.Date(2021, time.July, 3, 0, 0, 0, 0, time.UTC), stmt.Transactions[0].Date)
+	assert.Equal(suite.T(), "-1234.50", stmt.Transactions[0].Amount)
+	assert.Equal(suite.T(), "Carrefour <Mall of the Emirates>", stmt.Transactions[0].Payee)
+	assert.Equal(suite.T(), "Groceries", stmt.Transactions[0].Memo)
+
+	assert.Equal(suite.T(), "Groceries, Household", stmt.Transactions[1].Memo)
+	assert.Equal(suite.T(), "-500.00", stmt.Transactions[2].Amount)
+}
+
+func (suite *ExportTestSuite) Test_GIVEN_theDataOfAUser_WHEN_archived_THEN_archiveHasTheRecordsOfEachAccount() {
+	// GIVEN
+	user, _ := ledger.NewUserWithEmailString(ledger.UserId(1), "jack.torrence@theoverlook.com")
+	updatedBy := ledger.MustMakeUpdatedByUserId(user.Id())
+	groceries, _ := ledger.NewCategory(ledger.CategoryId(1), "Groceries", updatedBy)
+	budget, _ := ledger.NewBudget(
+		ledger.BudgetId(1),
+		ledger.AccountIds{suite.current.Id()},
+		ledger.BudgetPeriodTypeMonth,
+		ledger.CategoryBudgets{ledger.MustCategoryBudget(ledger.NewCategoryBudget(groceries.Id(), ledger.MustMoney(ledger.NewMoney("AED", 500_00))))},
+		updatedBy,
+	)
+	forEachRecord := func(account ledger.Account, fn func(ledger.Record) error) error {
+		if account.Id() != suite.current.Id() {
+			return nil
+		}
+		for _, record := range suite.records {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// WHEN
+	var buffer bytes.Buffer
+	err := WriteArchive(
+		&buffer,
+		user,
+		ledger.Categories{groceries},
+		[]ledger.Budget{budget},
+		ledger.Accounts{suite.current, suite.savings},
+		forEachRecord,
+		time.Date(2021, time.August, 1, 0, 0, 0, 0, time.UTC),
+	)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+
+	var archive Archive
+	assert.Nil(suite.T(), json.Unmarshal(buffer.Bytes(), &archive))
+	assert.Equal(suite.T(), ArchiveVersion, archive.Version)
+	assert.Equal(suite.T(), "2021-08-01T00:00:00Z", archive.ExportedAt)
+	assert.Equal(suite.T(), "jack.torrence@theoverlook.com", archive.User.Email)
+	assert.Equal(suite.T(), UpdatedBy{Kind: "USER", UserId: 1}, archive.User.CreatedBy)
+
+	assert.Len(suite.T(), archive.Categories, 1)
+	assert.Equal(suite.T(), "Groceries", archive.Categories[0].Name)
+	assert.Equal(suite.T(), uint64(1), archive.Categories[0].Version)
+
+	assert.Len(suite.T(), archive.Budgets, 1)
+	assert.Equal(suite.T(), []uint64{1}, archive.Budgets[0].AccountIds)
+	assert.Equal(suite.T(), []ArchivedCategoryBudget{{CategoryId: 1, MaxLimit: Amount{Currency: "AED", Value: 500_00}, AlertThresholds: []uint{50, 80, 100}, Rollover: "None"}}, archive.Budgets[0].CategoryBudgets)
+
+	assert.Len(suite.T(), archive.Accounts, 2)
+	assert.Equal(suite.T(), "Current Account", archive.Accounts[0].Name)
+	assert.Len(suite.T(), archive.Accounts[0].Records, 3)
+	assert.Equal(suite.T(), uint64(1), archive.Accounts[0].Records[2].Transfer.Source.Id)
+	assert.Equal(suite.T(), "Savings", archive.Accounts[1].Name)
+	assert.Empty(suite.T(), archive.Accounts[1].Records)
+}
+
+func (suite *ExportTestSuite) Test_GIVEN_anArchiveOfAnUnknownVersion_WHEN_read_THEN_errorIsReturned() {
+	// WHEN
+	_, err := ReadArchive(bytes.NewBufferString(`{"version": 2, "accounts": []}`))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), uint64(pkg.ErrUserImportValidation), err.(pkg.ValidationError).Code())
+	assert.Equal(suite.T(), "version must be between 1 and 1", err.(pkg.ValidationError).InvalidFields()["version"])
+}
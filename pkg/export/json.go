@@ -0,0 +1,219 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+// The JSON export is an object with the account and an array of its records:
+//
+//	{"account": {...}, "records": [{...}, {...}]}
+//
+// Amounts are in minor units, as in the API. The same types are used in the archive of all the data of a user.
+type Account struct {
+	Id       uint64 `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Currency string `json:"currency"`
+}
+
+type Amount struct {
+	Currency string `json:"currency"`
+	Value    int64  `json:"value"`
+}
+
+type Category struct {
+	Id   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+type UpdatedBy struct {
+	Kind     string `json:"kind"`
+	UserId   uint64 `json:"userId,omitempty"`
+	TaskName string `json:"taskName,omitempty"`
+	FileName string `json:"fileName,omitempty"`
+	BatchId  string `json:"batchId,omitempty"`
+}
+
+// Audit is who created and last modified an entity, and when.
+// ModifiedBy and ModifiedAt are omitted if the entity has not been modified.
+type Audit struct {
+	CreatedBy  UpdatedBy  `json:"createdBy"`
+	CreatedAt  string     `json:"createdAt"`
+	ModifiedBy *UpdatedBy `json:"modifiedBy,omitempty"`
+	ModifiedAt string     `json:"modifiedAt,omitempty"`
+	Version    uint64     `json:"version"`
+}
+
+// AccountRef is the id and name of an account that a transfer was made from or to
+type AccountRef struct {
+	Id   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+type Transfer struct {
+	Reference    string     `json:"reference"`
+	Source       AccountRef `json:"source"`
+	Beneficiary  AccountRef `json:"beneficiary"`
+	ExchangeRate string     `json:"exchangeRate,omitempty"`
+}
+
+type Split struct {
+	Note     string   `json:"note"`
+	Category Category `json:"category"`
+	Amount   Amount   `json:"amount"`
+}
+
+// Record is an exported record. Date is the moment at which the record occurred,
+// and LocalDate is the day on which it occurred in the timezone of the user, formatted as yyyy-MM-dd.
+type Record struct {
+	Id        uint64    `json:"id"`
+	Date      string    `json:"date"`
+	LocalDate string    `json:"localDate,omitempty"`
+	Type      string    `json:"type"`
+	Note      string    `json:"note"`
+	Category  Category  `json:"category"`
+	Amount    Amount    `json:"amount"`
+	Transfer  *Transfer `json:"transfer,omitempty"`
+	Splits    []Split   `json:"splits,omitempty"`
+	Audit
+}
+
+type jsonWriter struct {
+	w            io.Writer
+	account      ledger.Account
+	accountNames map[ledger.AccountId]string
+	started      bool
+	count        int
+}
+
+func newJsonWriter(w io.Writer, account ledger.Account, accountNames map[ledger.AccountId]string) *jsonWriter {
+	return &jsonWriter{w: w, account: account, accountNames: accountNames}
+}
+
+func (j *jsonWriter) begin() error {
+	if j.started {
+		return nil
+	}
+	j.started = true
+
+	account, err := json.Marshal(makeAccount(j.account))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(j.w, `{"account":%s,"records":[`, account)
+	return err
+}
+
+func (j *jsonWriter) Write(record ledger.Record) error {
+	if err := j.begin(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(makeRecord(record, j.accountNames))
+	if err != nil {
+		return err
+	}
+
+	if j.count > 0 {
+		if _, err = io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	j.count += 1
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if err := j.begin(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(j.w, "]}\n")
+	return err
+}
+
+func makeAccount(account ledger.Account) Account {
+	return Account{
+		Id:       uint64(account.Id()),
+		Name:     account.Name(),
+		Type:     string(account.Type()),
+		Currency: account.Currency(),
+	}
+}
+
+// makeRecord converts a record for export.
+// accountNames are the names of the accounts of the user by id, so that the accounts of transfers are written by name.
+func makeRecord(record ledger.Record, accountNames map[ledger.AccountId]string) Record {
+	r := Record{
+		Id:        uint64(record.Id()),
+		Date:      record.OccurredAtUTC().Format(time.RFC3339),
+		LocalDate: record.DateUTC().Format("2006-01-02"),
+		Type:      string(record.Type()),
+		Note:      record.Note(),
+		Category:  makeCategory(record.Category()),
+		Amount:    makeAmount(record.Amount()),
+		Audit:     makeAudit(record),
+	}
+
+	if record.Type() == ledger.Transfer {
+		r.Transfer = &Transfer{
+			Reference: string(record.TransferReference()),
+			Source: AccountRef{
+				Id:   uint64(record.SourceAccountId()),
+				Name: accountNames[record.SourceAccountId()],
+			},
+			Beneficiary: AccountRef{
+				Id:   uint64(record.BeneficiaryId()),
+				Name: accountNames[record.BeneficiaryId()],
+			},
+			ExchangeRate: record.ExchangeRate().String(),
+		}
+	}
+
+	for _, split := range record.Splits() {
+		r.Splits = append(r.Splits, Split{
+			Note:     split.Note(),
+			Category: makeCategory(split.Category()),
+			Amount:   makeAmount(split.Amount()),
+		})
+	}
+	return r
+}
+
+func makeCategory(category ledger.Category) Category {
+	return Category{Id: uint64(category.Id()), Name: category.Name()}
+}
+
+func makeAmount(amount ledger.Money) Amount {
+	value, _ := amount.MinorUnits()
+	return Amount{Currency: amount.Currency().CurrencyCode(), Value: value}
+}
+
+func makeAudit(auditable ledger.Auditable) Audit {
+	audit := Audit{
+		CreatedBy: makeUpdatedBy(auditable.CreatedBy()),
+		CreatedAt: auditable.CreatedAtUTC().Format(time.RFC3339),
+		Version:   uint64(auditable.Version()),
+	}
+	if isModified(auditable) {
+		modifiedBy := makeUpdatedBy(auditable.ModifiedBy())
+		audit.ModifiedBy = &modifiedBy
+		audit.ModifiedAt = auditable.ModifiedAtUTC().Format(time.RFC3339)
+	}
+	return audit
+}
+
+func makeUpdatedBy(updatedBy ledger.UpdatedBy) UpdatedBy {
+	return UpdatedBy{
+		Kind:     updatedBy.Kind().String(),
+		UserId:   uint64(updatedBy.UserId()),
+		TaskName: updatedBy.TaskName(),
+		FileName: updatedBy.ImportFileName(),
+		BatchId:  updatedBy.ImportBatchId(),
+	}
+}
@@ -0,0 +1,113 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+// maxOfxNameLength is the maximum length of the NAME of an OFX transaction
+const maxOfxNameLength = 32
+
+const ofxHeader = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+`
+
+var ofxEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// ofxWriter writes an OFX 1.x (SGML) bank statement with a transaction for each record.
+// The id of the record is the FITID of its transaction, so that the statement can be imported again without creating its transactions twice.
+// The note of the record is the NAME of the transaction and its category is the MEMO.
+type ofxWriter struct {
+	w          io.Writer
+	account    ledger.Account
+	exportedAt time.Time
+	started    bool
+}
+
+func newOfxWriter(w io.Writer, account ledger.Account, exportedAt time.Time) *ofxWriter {
+	return &ofxWriter{w: w, account: account, exportedAt: exportedAt}
+}
+
+func (o *ofxWriter) begin() error {
+	if o.started {
+		return nil
+	}
+	o.started = true
+
+	accountType := "CHECKING"
+	if o.account.Type() == ledger.AccountTypeSaving {
+		accountType = "SAVINGS"
+	}
+
+	_, err := fmt.Fprintf(o.w, "%s<OFX>\n"+
+		"<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>%s<LANGUAGE>ENG</SONRS></SIGNONMSGSRSV1>\n"+
+		"<BANKMSGSRSV1><STMTTRNRS><TRNUID>0<STATUS><CODE>0<SEVERITY>INFO</STATUS><STMTRS>\n"+
+		"<CURDEF>%s\n"+
+		"<BANKACCTFROM><BANKID>0<ACCTID>%d<ACCTTYPE>%s</BANKACCTFROM>\n"+
+		"<BANKTRANLIST>\n",
+		ofxHeader,
+		o.exportedAt.Format("20060102150405"),
+		o.account.Currency(),
+		o.account.Id(),
+		accountType,
+	)
+	return err
+}
+
+func (o *ofxWriter) Write(record ledger.Record) error {
+	if err := o.begin(); err != nil {
+		return err
+	}
+
+	transactionType := "CREDIT"
+	switch {
+	case record.Type() == ledger.Transfer:
+		transactionType = "XFER"
+	case record.Amount().IsNegative():
+		transactionType = "DEBIT"
+	}
+
+	name := []rune(strings.TrimSpace(record.Note()))
+	if len(name) > maxOfxNameLength {
+		name = name[:maxOfxNameLength]
+	}
+
+	memo := record.Category().Name()
+	if record.IsSplit() {
+		categories := make([]string, 0, len(record.Splits()))
+		for _, split := range record.Splits() {
+			categories = append(categories, split.Category().Name())
+		}
+		memo = strings.Join(categories, ", ")
+	}
+
+	_, err := fmt.Fprintf(o.w, "<STMTTRN><TRNTYPE>%s<DTPOSTED>%s<TRNAMT>%s<FITID>%d<NAME>%s<MEMO>%s</STMTTRN>\n",
+		transactionType,
+		record.DateUTC().Format("20060102"),
+		decimal(record.Amount()),
+		record.Id(),
+		ofxEscaper.Replace(string(name)),
+		ofxEscaper.Replace(memo),
+	)
+	return err
+}
+
+func (o *ofxWriter) Close() error {
+	if err := o.begin(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(o.w, "</BANKTRANLIST>\n</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n</OFX>\n")
+	return err
+}
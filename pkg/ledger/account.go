@@ -126,7 +126,7 @@ func newAccount(
 	return Account{
 		auditInfo:      auditInfo,
 		id:             id,
-		name:           strings.Title(strings.ToLower(name)),
+		name:           NormalizeName(name),
 		accountType:    AccountType(accountType),
 		currency:       currency,
 		currentBalance: currentBalance,
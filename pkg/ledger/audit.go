@@ -12,42 +12,143 @@ import (
 	"github.com/w-k-s/simple-budget-tracker/pkg"
 )
 
+// UpdatedByKind is the kind of actor that created or modified an entity
+type UpdatedByKind string
+
+const (
+	UpdatedByKindUser   UpdatedByKind = "USER"
+	UpdatedByKindTask   UpdatedByKind = "TASK"
+	UpdatedByKindImport UpdatedByKind = "IMPORT"
+	UpdatedByKindSystem UpdatedByKind = "SYSTEM"
+)
+
+func (k UpdatedByKind) String() string {
+	return string(k)
+}
+
+// ParseUpdatedByKind parses the kind of actor e.g. for filtering records by who created them. The kind is case insensitive.
+func ParseUpdatedByKind(kind string) (UpdatedByKind, error) {
+	switch updatedByKind := UpdatedByKind(strings.ToUpper(strings.TrimSpace(kind))); updatedByKind {
+	case UpdatedByKindUser, UpdatedByKindTask, UpdatedByKindImport, UpdatedByKindSystem:
+		return updatedByKind, nil
+	}
+	return "", pkg.ValidationErrorWithFields(pkg.ErrAuditValidation, fmt.Sprintf("Unknown createdBy kind %q", kind), nil, map[string]string{
+		"createdBy": "createdBy must be USER, TASK, IMPORT or SYSTEM",
+	})
+}
+
+// UpdatedBy is the actor that created or modified an entity. It is stored in the created_by and last_modified_by columns as:
+//
+//	UserId: 1
+//	CronTask: RecurringRecords
+//	Import: statement.csv; Batch: 42
+//	System
+//
+// The zero value is used when an entity has not been modified.
 type UpdatedBy struct {
-	value string
+	kind UpdatedByKind
+	// userId is only set when the kind is UpdatedByKindUser
+	userId UserId
+	// name is the name of the task or of the imported file
+	name string
+	// batchId is only set when the kind is UpdatedByKindImport, and optional
+	batchId string
 }
 
+const (
+	updatedByUserIdKey = "UserId"
+	updatedByTaskKey   = "CronTask"
+	updatedByImportKey = "Import"
+	updatedByBatchKey  = "Batch"
+	updatedBySystem    = "System"
+)
+
 func ParseUpdatedBy(updatedBy string) (UpdatedBy, error) {
+	if strings.TrimSpace(updatedBy) == updatedBySystem {
+		return MakeUpdatedBySystem(), nil
+	}
+
+	values := map[string]string{}
 	parts := strings.Split(updatedBy, ";")
 	for _, pairs := range parts {
 		pair := strings.Split(pairs, ":")
 		if len(pair) != 2 {
 			return UpdatedBy{}, pkg.ValidationErrorWithFields(pkg.ErrAuditUpdatedByBadFormat, fmt.Sprintf("Invalid createdBy/modifiedBy provided: %q", updatedBy), nil, nil)
 		}
-		key := strings.TrimSpace(pair[0])
-		value := strings.TrimSpace(pair[1])
-		if key == "UserId" {
-			var (
-				userId int
-				err    error
-			)
-			if userId, err = strconv.Atoi(value); err != nil {
-				return UpdatedBy{}, pkg.ValidationErrorWithFields(pkg.ErrAuditUpdatedByBadFormat, fmt.Sprintf("Invalid createdBy/modifiedBy provided: %q", updatedBy), err, nil)
-			}
-			return MakeUpdatedByUserId(UserId(userId))
+		values[strings.TrimSpace(pair[0])] = strings.TrimSpace(pair[1])
+	}
+
+	if value, ok := values[updatedByUserIdKey]; ok {
+		var (
+			userId int
+			err    error
+		)
+		if userId, err = strconv.Atoi(value); err != nil {
+			return UpdatedBy{}, pkg.ValidationErrorWithFields(pkg.ErrAuditUpdatedByBadFormat, fmt.Sprintf("Invalid createdBy/modifiedBy provided: %q", updatedBy), err, nil)
 		}
+		return MakeUpdatedByUserId(UserId(userId))
+	}
+	if value, ok := values[updatedByTaskKey]; ok {
+		return MakeUpdatedByTask(value)
+	}
+	if value, ok := values[updatedByImportKey]; ok {
+		return MakeUpdatedByImport(value, values[updatedByBatchKey])
 	}
 	return UpdatedBy{}, pkg.ValidationErrorWithFields(pkg.ErrAuditUpdatedByBadFormat, fmt.Sprintf("Unknown createdBy/modifiedBy provided: %q", updatedBy), nil, nil)
 }
 
 func (u UpdatedBy) String() string {
-	return u.value
+	switch u.kind {
+	case UpdatedByKindUser:
+		return fmt.Sprintf("%s: %d", updatedByUserIdKey, u.userId)
+	case UpdatedByKindTask:
+		return fmt.Sprintf("%s: %s", updatedByTaskKey, u.name)
+	case UpdatedByKindImport:
+		if len(u.batchId) == 0 {
+			return fmt.Sprintf("%s: %s", updatedByImportKey, u.name)
+		}
+		return fmt.Sprintf("%s: %s; %s: %s", updatedByImportKey, u.name, updatedByBatchKey, u.batchId)
+	case UpdatedByKindSystem:
+		return updatedBySystem
+	}
+	return ""
+}
+
+func (u UpdatedBy) Kind() UpdatedByKind {
+	return u.kind
+}
+
+// UserId is the user that made the change. Zero is returned if the change was not made by a user.
+func (u UpdatedBy) UserId() UserId {
+	return u.userId
+}
+
+// TaskName is the name of the scheduled task that made the change. An empty string is returned if the change was not made by a task.
+func (u UpdatedBy) TaskName() string {
+	if u.kind != UpdatedByKindTask {
+		return ""
+	}
+	return u.name
+}
+
+// ImportFileName is the name of the file the entity was imported from. An empty string is returned if the entity was not imported.
+func (u UpdatedBy) ImportFileName() string {
+	if u.kind != UpdatedByKindImport {
+		return ""
+	}
+	return u.name
+}
+
+// ImportBatchId identifies the import the entity was created by, if one was provided.
+func (u UpdatedBy) ImportBatchId() string {
+	return u.batchId
 }
 
 func MakeUpdatedByUserId(userId UserId) (UpdatedBy, error) {
 	if userId <= 0 {
 		return UpdatedBy{}, pkg.ValidationErrorWithFields(pkg.ErrAuditValidation, "userId must be greater than 0", nil, nil)
 	}
-	return UpdatedBy{fmt.Sprintf("UserId: %d", userId)}, nil
+	return UpdatedBy{kind: UpdatedByKindUser, userId: userId}, nil
 }
 
 func MustMakeUpdatedByUserId(userId UserId) UpdatedBy {
@@ -61,8 +162,55 @@ func MustMakeUpdatedByUserId(userId UserId) UpdatedBy {
 	return updatedBy
 }
 
-// TODO: MakeEditedByTask -> "CronTask: taskName"
-// TODO: MakeEditedByImport -> "Import: fileName"
+// MakeUpdatedByTask is used for changes made by a scheduled task rather than by a user e.g. records created from a recurring record.
+func MakeUpdatedByTask(taskName string) (UpdatedBy, error) {
+	taskName = strings.TrimSpace(taskName)
+	if len(taskName) == 0 || strings.ContainsAny(taskName, ":;") {
+		return UpdatedBy{}, pkg.ValidationErrorWithFields(pkg.ErrAuditValidation, fmt.Sprintf("Invalid task name %q", taskName), nil, nil)
+	}
+	return UpdatedBy{kind: UpdatedByKindTask, name: taskName}, nil
+}
+
+func MustMakeUpdatedByTask(taskName string) UpdatedBy {
+	var (
+		updatedBy UpdatedBy
+		err       error
+	)
+	if updatedBy, err = MakeUpdatedByTask(taskName); err != nil {
+		log.Fatalf("Invalid task name provided for createdBy/modifiedBy. Reason: %s", err)
+	}
+	return updatedBy
+}
+
+// MakeUpdatedByImport is used for entities created from an imported file e.g. a bank statement.
+// The batch id is optional and identifies a single import of the file.
+func MakeUpdatedByImport(fileName string, batchId string) (UpdatedBy, error) {
+	fileName = strings.TrimSpace(fileName)
+	batchId = strings.TrimSpace(batchId)
+	if len(fileName) == 0 || strings.ContainsAny(fileName, ":;") {
+		return UpdatedBy{}, pkg.ValidationErrorWithFields(pkg.ErrAuditValidation, fmt.Sprintf("Invalid import file name %q", fileName), nil, nil)
+	}
+	if strings.ContainsAny(batchId, ":;") {
+		return UpdatedBy{}, pkg.ValidationErrorWithFields(pkg.ErrAuditValidation, fmt.Sprintf("Invalid import batch id %q", batchId), nil, nil)
+	}
+	return UpdatedBy{kind: UpdatedByKindImport, name: fileName, batchId: batchId}, nil
+}
+
+func MustMakeUpdatedByImport(fileName string, batchId string) UpdatedBy {
+	var (
+		updatedBy UpdatedBy
+		err       error
+	)
+	if updatedBy, err = MakeUpdatedByImport(fileName, batchId); err != nil {
+		log.Fatalf("Invalid import provided for createdBy/modifiedBy. Reason: %s", err)
+	}
+	return updatedBy
+}
+
+// MakeUpdatedBySystem is used for changes made by the application itself or by admin tooling, rather than on behalf of a user.
+func MakeUpdatedBySystem() UpdatedBy {
+	return UpdatedBy{kind: UpdatedByKindSystem}
+}
 
 type Version uint64
 
@@ -112,6 +260,18 @@ func makeAuditForCreation(updatedBy UpdatedBy) (auditInfo, error) {
 	)
 }
 
+// makeAuditForUpdate records that an existing entity was modified by updatedBy.
+// The version is left as is; it is incremented by the database when the entity is saved.
+func makeAuditForUpdate(ai auditInfo, updatedBy UpdatedBy) (auditInfo, error) {
+	return makeAuditForModification(
+		ai.createdBy,
+		ai.createdAtUTC,
+		updatedBy,
+		time.Now().UTC(),
+		ai.version,
+	)
+}
+
 func makeAuditForModification(
 	createdBy UpdatedBy,
 	createdAt time.Time,
@@ -152,3 +152,73 @@ func (suite *AuditTestSuite) Test_GIVEN_userIdKeyWithValidUserIdInUpdatedByStrin
 	assert.Nil(suite.T(), err)
 	assert.Equal(suite.T(), "UserId: 1", updatedBy.String())
 }
+
+func (suite *AuditTestSuite) Test_GIVEN_cronTaskKeyInUpdatedByString_WHEN_parsed_THEN_updatedByIsReturned() {
+	// GIVEN
+	updatedBy, err := ParseUpdatedBy("CronTask: RecurringRecords")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), MustMakeUpdatedByTask("RecurringRecords"), updatedBy)
+	assert.Equal(suite.T(), "CronTask: RecurringRecords", updatedBy.String())
+}
+
+func (suite *AuditTestSuite) Test_GIVEN_updatedByOfEachKind_WHEN_formattedAndParsed_THEN_updatedByIsUnchanged() {
+	testCases := []struct {
+		name      string
+		updatedBy UpdatedBy
+		formatted string
+		kind      UpdatedByKind
+	}{
+		{name: "user", updatedBy: MustMakeUpdatedByUserId(7), formatted: "UserId: 7", kind: UpdatedByKindUser},
+		{name: "task", updatedBy: MustMakeUpdatedByTask("RecurringRecords"), formatted: "CronTask: RecurringRecords", kind: UpdatedByKindTask},
+		{name: "import", updatedBy: MustMakeUpdatedByImport("statement.csv", ""), formatted: "Import: statement.csv", kind: UpdatedByKindImport},
+		{name: "import batch", updatedBy: MustMakeUpdatedByImport("statement.csv", "42"), formatted: "Import: statement.csv; Batch: 42", kind: UpdatedByKindImport},
+		{name: "system", updatedBy: MakeUpdatedBySystem(), formatted: "System", kind: UpdatedByKindSystem},
+	}
+
+	for _, testCase := range testCases {
+		suite.Run(testCase.name, func() {
+			// WHEN
+			formatted := testCase.updatedBy.String()
+			parsed, err := ParseUpdatedBy(formatted)
+
+			// THEN
+			assert.Nil(suite.T(), err)
+			assert.Equal(suite.T(), testCase.formatted, formatted)
+			assert.Equal(suite.T(), testCase.updatedBy, parsed)
+			assert.Equal(suite.T(), testCase.kind, parsed.Kind())
+		})
+	}
+}
+
+func (suite *AuditTestSuite) Test_GIVEN_importWithBatch_WHEN_parsed_THEN_fileNameAndBatchIdAreReturned() {
+	// WHEN
+	updatedBy, err := ParseUpdatedBy("Batch: 42; Import: statement.csv")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "statement.csv", updatedBy.ImportFileName())
+	assert.Equal(suite.T(), "42", updatedBy.ImportBatchId())
+	assert.Equal(suite.T(), "", updatedBy.TaskName())
+	assert.Equal(suite.T(), UserId(0), updatedBy.UserId())
+}
+
+func (suite *AuditTestSuite) Test_GIVEN_importFileNameWithSeparator_WHEN_updatedByIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := MakeUpdatedByImport("c:statement.csv", "")
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrAuditValidation, errorCode(err, 0))
+}
+
+func (suite *AuditTestSuite) Test_GIVEN_unknownKind_WHEN_kindIsParsed_THEN_errorIsReturned() {
+	// WHEN
+	kind, err := ParseUpdatedByKind("robot")
+
+	// THEN
+	assert.Equal(suite.T(), UpdatedByKind(""), kind)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "createdBy must be USER, TASK, IMPORT or SYSTEM", errorFields(err)["createdBy"])
+}
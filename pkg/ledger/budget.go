@@ -3,6 +3,7 @@ package ledger
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,30 +18,74 @@ type BudgetId uint64
 type BudgetPeriodType string
 
 const (
-	BudgetPeriodTypeWeek  BudgetPeriodType = "Week"
-	BudgetPeriodTypeMonth BudgetPeriodType = "Month"
+	BudgetPeriodTypeWeek BudgetPeriodType = "Week"
+	// BudgetPeriodTypeBiWeek is a period of 14 days, counted from an anchor date such as a payday
+	BudgetPeriodTypeBiWeek BudgetPeriodType = "BiWeek"
+	BudgetPeriodTypeMonth  BudgetPeriodType = "Month"
+	// BudgetPeriodTypeMonthStartingOnDay is a month that starts on a given day, such as the 25th, and ends the day before that day in the next month
+	BudgetPeriodTypeMonthStartingOnDay BudgetPeriodType = "MonthStartingOnDay"
+	BudgetPeriodTypeQuarter            BudgetPeriodType = "Quarter"
+	BudgetPeriodTypeYear               BudgetPeriodType = "Year"
 )
 
-type budgetPeriodTypeValidator struct {
+// AlertThresholds are percentages of the maximum amount of a category budget.
+// An alert is raised the first time the spending of the category in a period reaches each of them.
+type AlertThresholds []uint
+
+// DefaultAlertThresholds are the thresholds of a category budget created without thresholds.
+func DefaultAlertThresholds() AlertThresholds {
+	return AlertThresholds{50, 80, 100}
+}
+
+type alertThresholdsValidator struct {
 	Name  string
-	Field string
+	Field AlertThresholds
 }
 
-func (v *budgetPeriodTypeValidator) IsValid(errors *validate.Errors) {
-	if len(v.Field) == 0 {
-		errors.Add(strings.ToLower(v.Name), "periodType is required")
-		return
+func (v *alertThresholdsValidator) IsValid(errors *validate.Errors) {
+	thresholds := map[uint]bool{}
+	for _, threshold := range v.Field {
+		if threshold == 0 || threshold > 1000 {
+			errors.Add(strings.ToLower(v.Name), "alertThresholds must be between 1 and 1000 percent")
+			return
+		}
+		if thresholds[threshold] {
+			errors.Add(strings.ToLower(v.Name), fmt.Sprintf("alert threshold %d can only be set once", threshold))
+			return
+		}
+		thresholds[threshold] = true
 	}
-	validPeriodTypes := []string{
-		string(BudgetPeriodTypeWeek),
-		string(BudgetPeriodTypeMonth),
+}
+
+// RolloverMode decides what happens to the balance of a category budget at the end of a period.
+type RolloverMode string
+
+const (
+	// RolloverNone starts each period with the maximum amount, regardless of the previous period.
+	RolloverNone RolloverMode = "None"
+	// RolloverSurplusOnly carries the amount left over in a period into the next period; overspending is forgiven.
+	RolloverSurplusOnly RolloverMode = "SurplusOnly"
+	// RolloverSurplusAndDeficit carries the amount left over into the next period, and deducts overspending from it.
+	RolloverSurplusAndDeficit RolloverMode = "SurplusAndDeficit"
+)
+
+type rolloverModeValidator struct {
+	Name  string
+	Field string
+}
+
+func (v *rolloverModeValidator) IsValid(errors *validate.Errors) {
+	validRolloverModes := []string{
+		string(RolloverNone),
+		string(RolloverSurplusOnly),
+		string(RolloverSurplusAndDeficit),
 	}
 
 	validator := &validators.StringInclusion{
 		Name:    v.Name,
 		Field:   v.Field,
-		List:    validPeriodTypes,
-		Message: fmt.Sprintf("periodType must be one of %q", validPeriodTypes),
+		List:    validRolloverModes,
+		Message: fmt.Sprintf("rollover must be one of %q", validRolloverModes),
 	}
 	validator.IsValid(errors)
 }
@@ -49,12 +94,37 @@ type CategoryBudget struct {
 	categoryId CategoryId
 	// The maximum amount allowed to be spent for the associated category in a time period.
 	maxLimit Money
+	// Percentages of maxLimit at which alerts are raised, in ascending order
+	alertThresholds AlertThresholds
+	// Whether the balance at the end of a period is carried into the next period
+	rollover RolloverMode
 }
 
 func NewCategoryBudget(
 	categoryId CategoryId,
 	maxLimit Money,
 ) (CategoryBudget, error) {
+	return NewCategoryBudgetWithAlertThresholds(categoryId, maxLimit, DefaultAlertThresholds())
+}
+
+// NewCategoryBudgetWithAlertThresholds creates a category budget that raises alerts at the given percentages of maxLimit.
+// No alerts are raised if alertThresholds is empty.
+func NewCategoryBudgetWithAlertThresholds(
+	categoryId CategoryId,
+	maxLimit Money,
+	alertThresholds AlertThresholds,
+) (CategoryBudget, error) {
+	return NewCategoryBudgetWithRollover(categoryId, maxLimit, alertThresholds, RolloverNone)
+}
+
+// NewCategoryBudgetWithRollover creates a category budget that raises alerts at the given percentages of maxLimit,
+// and carries its balance into the next period according to the rollover mode.
+func NewCategoryBudgetWithRollover(
+	categoryId CategoryId,
+	maxLimit Money,
+	alertThresholds AlertThresholds,
+	rollover RolloverMode,
+) (CategoryBudget, error) {
 
 	errors := validate.Validate(
 		&validators.IntIsGreaterThan{
@@ -68,6 +138,14 @@ func NewCategoryBudget(
 			Field:   maxLimit,
 			Message: "MaxLimit must be greater than or equal to 0",
 		},
+		&alertThresholdsValidator{
+			Name:  "alertThresholds",
+			Field: alertThresholds,
+		},
+		&rolloverModeValidator{
+			Name:  "rollover",
+			Field: string(rollover),
+		},
 	)
 
 	err := pkg.ValidationErrorWithErrors(pkg.ErrBudgetValidation, "", errors)
@@ -75,9 +153,15 @@ func NewCategoryBudget(
 		return CategoryBudget{}, err
 	}
 
+	sortedThresholds := make(AlertThresholds, len(alertThresholds))
+	copy(sortedThresholds, alertThresholds)
+	sort.Slice(sortedThresholds, func(i, j int) bool { return sortedThresholds[i] < sortedThresholds[j] })
+
 	return CategoryBudget{
-		categoryId: categoryId,
-		maxLimit:   maxLimit,
+		categoryId:      categoryId,
+		maxLimit:        maxLimit,
+		alertThresholds: sortedThresholds,
+		rollover:        rollover,
 	}, nil
 }
 
@@ -96,10 +180,54 @@ func (cb CategoryBudget) MaxLimit() Money {
 	return cb.maxLimit
 }
 
+func (cb CategoryBudget) AlertThresholds() AlertThresholds {
+	return cb.alertThresholds
+}
+
+func (cb CategoryBudget) Rollover() RolloverMode {
+	return cb.rollover
+}
+
+// CarryOver returns the part of the balance at the end of a period that is carried into the next period.
+func (cb CategoryBudget) CarryOver(balance Money) Money {
+	switch cb.rollover {
+	case RolloverSurplusAndDeficit:
+		return balance
+	case RolloverSurplusOnly:
+		if balance.IsPositive() {
+			return balance
+		}
+	}
+	return MustMoney(NewMoney(balance.Currency().CurrencyCode(), 0))
+}
+
+// ReachedAlertThresholds returns the alert thresholds that the spent amount has reached, in ascending order.
+// When the maximum amount is zero, all the thresholds are reached as soon as anything is spent.
+func (cb CategoryBudget) ReachedAlertThresholds(spent Money) AlertThresholds {
+	maxLimit := cb.maxLimit.MustMinorUnits()
+	spentMinorUnits := spent.MustMinorUnits()
+
+	reached := AlertThresholds{}
+	for _, threshold := range cb.alertThresholds {
+		if maxLimit == 0 {
+			if spentMinorUnits > 0 {
+				reached = append(reached, threshold)
+			}
+			continue
+		}
+		if spentMinorUnits*100 >= maxLimit*int64(threshold) {
+			reached = append(reached, threshold)
+		}
+	}
+	return reached
+}
+
 func (cb CategoryBudget) String() string {
-	return fmt.Sprintf("CategoryBudget{Category: %d, Max: %s}",
+	return fmt.Sprintf("CategoryBudget{Category: %d, Max: %s, AlertThresholds: %v, Rollover: %s}",
 		cb.categoryId,
 		cb.maxLimit,
+		cb.alertThresholds,
+		cb.rollover,
 	)
 }
 
@@ -127,7 +255,7 @@ func (cb CategoryBudgets) String() string {
 type BudgetRecord interface {
 	Id() BudgetId
 	AccountIds() AccountIds
-	PeriodType() BudgetPeriodType
+	PeriodCalculator() PeriodCalculator
 	CategoryBudgets() CategoryBudgets
 	CreatedBy() UpdatedBy
 	CreatedAtUTC() time.Time
@@ -144,9 +272,9 @@ type Budget struct {
 	// For example: Let's say a budget of no more than a $100 per month on shopping sets accountIds to Account A and B.
 	// The budget will be exceeded when the total spent on shopping by the two accounts combined is greater than $100.
 	accountIds AccountIds
-	// Whether the budget limits the amount spent in a week or the amount spent in a month.
-	periodType      BudgetPeriodType
-	categoryBudgets CategoryBudgets
+	// The periods, such as weeks or months, in which the amount spent is limited.
+	periodCalculator PeriodCalculator
+	categoryBudgets  CategoryBudgets
 }
 
 func (b Budget) Id() BudgetId {
@@ -158,7 +286,12 @@ func (b Budget) AccountIds() AccountIds {
 }
 
 func (b Budget) PeriodType() BudgetPeriodType {
-	return b.periodType
+	return b.periodCalculator.PeriodType()
+}
+
+// PeriodCalculator finds the period of the budget that contains a date.
+func (b Budget) PeriodCalculator() PeriodCalculator {
+	return b.periodCalculator
 }
 
 func (b Budget) CategoryBudgets() CategoryBudgets {
@@ -186,14 +319,15 @@ func (b Budget) Version() Version {
 }
 
 func (b Budget) String() string {
-	return fmt.Sprintf("Budget{id: %d, accountIds: %v, periodType: %s, categoryBudgets: %s}",
+	return fmt.Sprintf("Budget{id: %d, accountIds: %v, period: %s, categoryBudgets: %s}",
 		b.id,
 		b.accountIds,
-		b.periodType,
+		b.periodCalculator,
 		b.categoryBudgets,
 	)
 }
 
+// NewBudget creates a budget whose periods are found by the default calculator of the period type.
 func NewBudget(
 	id BudgetId,
 	accountIds AccountIds,
@@ -201,6 +335,17 @@ func NewBudget(
 	categoryBudgets CategoryBudgets,
 	createdBy UpdatedBy,
 ) (Budget, error) {
+	return NewBudgetWithPeriodCalculator(id, accountIds, DefaultPeriodCalculator(periodType), categoryBudgets, createdBy)
+}
+
+// NewBudgetWithPeriodCalculator creates a budget whose periods, such as bi-weekly periods from a payday, are found by the given calculator.
+func NewBudgetWithPeriodCalculator(
+	id BudgetId,
+	accountIds AccountIds,
+	periodCalculator PeriodCalculator,
+	categoryBudgets CategoryBudgets,
+	createdBy UpdatedBy,
+) (Budget, error) {
 
 	auditInfo, err := makeAuditForCreation(createdBy)
 	if err != nil {
@@ -210,7 +355,7 @@ func NewBudget(
 	return newBudget(
 		id,
 		accountIds,
-		periodType,
+		periodCalculator,
 		categoryBudgets,
 		auditInfo,
 	)
@@ -232,16 +377,49 @@ func NewBudgetFromRecord(record BudgetRecord) (Budget, error) {
 	return newBudget(
 		record.Id(),
 		record.AccountIds(),
-		record.PeriodType(),
+		record.PeriodCalculator(),
 		record.CategoryBudgets(),
 		auditInfo,
 	)
 }
 
+// Edit returns a copy of the budget with the given details, validated in the same way as a new budget.
+// The category budgets replace the category budgets of the budget.
+// The periods of the budget are found by the default calculator of the period type.
+func (b Budget) Edit(
+	accountIds AccountIds,
+	periodType BudgetPeriodType,
+	categoryBudgets CategoryBudgets,
+	updatedBy UpdatedBy,
+) (Budget, error) {
+	return b.EditWithPeriodCalculator(accountIds, DefaultPeriodCalculator(periodType), categoryBudgets, updatedBy)
+}
+
+// EditWithPeriodCalculator returns a copy of the budget with the given details, validated in the same way as a new budget.
+func (b Budget) EditWithPeriodCalculator(
+	accountIds AccountIds,
+	periodCalculator PeriodCalculator,
+	categoryBudgets CategoryBudgets,
+	updatedBy UpdatedBy,
+) (Budget, error) {
+	auditInfo, err := makeAuditForUpdate(b.auditInfo, updatedBy)
+	if err != nil {
+		return Budget{}, err
+	}
+
+	return newBudget(
+		b.id,
+		accountIds,
+		periodCalculator,
+		categoryBudgets,
+		auditInfo,
+	)
+}
+
 func newBudget(
 	id BudgetId,
 	accountIds AccountIds,
-	periodType BudgetPeriodType,
+	periodCalculator PeriodCalculator,
 	categoryBudgets CategoryBudgets,
 	auditInfo auditInfo,
 ) (Budget, error) {
@@ -261,12 +439,16 @@ func newBudget(
 		},
 		&budgetPeriodTypeValidator{
 			Name:  "periodType",
-			Field: string(periodType),
+			Field: string(periodCalculator.PeriodType()),
 		},
 		&categoryBudgetsHaveSameCurrency{
 			Name:  "categoryBudgets",
 			Field: categoryBudgets,
 		},
+		&categoryBudgetsHaveDistinctCategories{
+			Name:  "categoryBudgets",
+			Field: categoryBudgets,
+		},
 		&validators.IntIsGreaterThan{
 			Name:     "categoryBudgets",
 			Field:    len(categoryBudgets),
@@ -281,11 +463,11 @@ func newBudget(
 	}
 
 	return Budget{
-		auditInfo:       auditInfo,
-		id:              id,
-		accountIds:      accountIds,
-		periodType:      periodType,
-		categoryBudgets: categoryBudgets,
+		auditInfo:        auditInfo,
+		id:               id,
+		accountIds:       accountIds,
+		periodCalculator: periodCalculator,
+		categoryBudgets:  categoryBudgets,
 	}, nil
 }
 
@@ -308,3 +490,19 @@ func (v *categoryBudgetsHaveSameCurrency) IsValid(errors *validate.Errors) {
 		}
 	}
 }
+
+type categoryBudgetsHaveDistinctCategories struct {
+	Name  string
+	Field CategoryBudgets
+}
+
+func (v *categoryBudgetsHaveDistinctCategories) IsValid(errors *validate.Errors) {
+	categoryIds := map[CategoryId]bool{}
+	for _, cb := range v.Field {
+		if categoryIds[cb.categoryId] {
+			errors.Add(strings.ToLower(v.Name), fmt.Sprintf("category %d can only be budgeted once", cb.categoryId))
+			return
+		}
+		categoryIds[cb.categoryId] = true
+	}
+}
@@ -0,0 +1,155 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type BudgetAlertId uint64
+
+// BudgetAlert is raised the first time the spending of a category of a budget in a period reaches one of the alert thresholds of the category.
+type BudgetAlert struct {
+	id         BudgetAlertId
+	userId     UserId
+	budgetId   BudgetId
+	categoryId CategoryId
+	period     BudgetPeriod
+	threshold  uint
+	spent      Money
+	maxLimit   Money
+	createdAt  time.Time
+}
+
+type BudgetAlertRecord interface {
+	Id() BudgetAlertId
+	UserId() UserId
+	BudgetId() BudgetId
+	CategoryId() CategoryId
+	Period() BudgetPeriod
+	Threshold() uint
+	Spent() Money
+	MaxLimit() Money
+	CreatedAtUTC() time.Time
+}
+
+// NewBudgetAlert creates an alert for a threshold of a category budget that the spent amount has reached in the period.
+func NewBudgetAlert(
+	id BudgetAlertId,
+	userId UserId,
+	budgetId BudgetId,
+	categoryBudget CategoryBudget,
+	period BudgetPeriod,
+	threshold uint,
+	spent Money,
+) (BudgetAlert, error) {
+	return newBudgetAlert(id, userId, budgetId, categoryBudget.CategoryId(), period, threshold, spent, categoryBudget.MaxLimit(), time.Now().UTC())
+}
+
+func NewBudgetAlertFromRecord(record BudgetAlertRecord) (BudgetAlert, error) {
+	return newBudgetAlert(
+		record.Id(),
+		record.UserId(),
+		record.BudgetId(),
+		record.CategoryId(),
+		record.Period(),
+		record.Threshold(),
+		record.Spent(),
+		record.MaxLimit(),
+		record.CreatedAtUTC(),
+	)
+}
+
+func newBudgetAlert(
+	id BudgetAlertId,
+	userId UserId,
+	budgetId BudgetId,
+	categoryId CategoryId,
+	period BudgetPeriod,
+	threshold uint,
+	spent Money,
+	maxLimit Money,
+	createdAt time.Time,
+) (BudgetAlert, error) {
+	errors := validate.Validate(
+		&validators.IntIsGreaterThan{Name: "Id", Field: int(id), Compared: 0, Message: "Id must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "UserId", Field: int(userId), Compared: 0, Message: "UserId must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "BudgetId", Field: int(budgetId), Compared: 0, Message: "BudgetId must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "CategoryId", Field: int(categoryId), Compared: 0, Message: "CategoryId must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "Threshold", Field: int(threshold), Compared: 0, Message: "Threshold must be greater than 0"},
+		&validators.TimeIsPresent{Name: "CreatedAt", Field: createdAt, Message: "CreatedAt is required"},
+	)
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrBudgetValidation, "", errors); err != nil {
+		return BudgetAlert{}, err
+	}
+
+	if spent.Currency().CurrencyCode() != maxLimit.Currency().CurrencyCode() {
+		return BudgetAlert{}, pkg.ValidationErrorWithFields(pkg.ErrAmountMismatchingCurrencies, fmt.Sprintf("Spent amount %s is not in the currency of the budget %s", spent, maxLimit), nil, nil)
+	}
+
+	return BudgetAlert{
+		id:         id,
+		userId:     userId,
+		budgetId:   budgetId,
+		categoryId: categoryId,
+		period:     period,
+		threshold:  threshold,
+		spent:      spent,
+		maxLimit:   maxLimit,
+		createdAt:  createdAt,
+	}, nil
+}
+
+func (a BudgetAlert) Id() BudgetAlertId {
+	return a.id
+}
+
+func (a BudgetAlert) UserId() UserId {
+	return a.userId
+}
+
+func (a BudgetAlert) BudgetId() BudgetId {
+	return a.budgetId
+}
+
+func (a BudgetAlert) CategoryId() CategoryId {
+	return a.categoryId
+}
+
+func (a BudgetAlert) Period() BudgetPeriod {
+	return a.period
+}
+
+// Threshold is the percentage of the maximum amount of the category budget that was reached.
+func (a BudgetAlert) Threshold() uint {
+	return a.threshold
+}
+
+// Spent is the amount spent on the category in the period when the alert was raised.
+func (a BudgetAlert) Spent() Money {
+	return a.spent
+}
+
+func (a BudgetAlert) MaxLimit() Money {
+	return a.maxLimit
+}
+
+func (a BudgetAlert) CreatedAtUTC() time.Time {
+	return a.createdAt
+}
+
+func (a BudgetAlert) String() string {
+	return fmt.Sprintf("BudgetAlert{id: %d, budget: %d, category: %d, period: %s, threshold: %d%%, spent: %s, max: %s}",
+		a.id,
+		a.budgetId,
+		a.categoryId,
+		a.period,
+		a.threshold,
+		a.spent,
+		a.maxLimit,
+	)
+}
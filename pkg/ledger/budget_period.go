@@ -0,0 +1,243 @@
+package ledger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+// DefaultBiWeekAnchor is the first day of a bi-weekly period when a budget does not choose one. It is a Monday.
+var DefaultBiWeekAnchor = time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// PeriodCalculator finds the period of a budget that contains any date.
+// Weekly periods start on the week start, bi-weekly periods are counted in steps of 14 days from the anchor date,
+// and months of the MonthStartingOnDay type start on the start day.
+type PeriodCalculator struct {
+	periodType BudgetPeriodType
+	anchorDate time.Time
+	startDay   uint
+	weekStart  time.Weekday
+}
+
+// NewPeriodCalculator creates a calculator for the periods of the given type.
+// The anchor date is required for bi-weekly periods, and the start day, between 1 and 28, for months starting on a day; both are ignored for the other types.
+func NewPeriodCalculator(periodType BudgetPeriodType, anchorDate time.Time, startDay uint) (PeriodCalculator, error) {
+	errors := validate.Validate(
+		&budgetPeriodTypeValidator{Name: "periodType", Field: string(periodType)},
+	)
+
+	switch periodType {
+	case BudgetPeriodTypeBiWeek:
+		startDay = 0
+		if anchorDate.IsZero() {
+			errors.Add("periodAnchor", "periodAnchor is required for bi-weekly periods")
+		}
+		anchorDate = startOfDay(anchorDate)
+	case BudgetPeriodTypeMonthStartingOnDay:
+		anchorDate = time.Time{}
+		if startDay < 1 || startDay > 28 {
+			errors.Add("periodStartDay", "periodStartDay must be between 1 and 28")
+		}
+	default:
+		anchorDate = time.Time{}
+		startDay = 0
+	}
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrBudgetValidation, "", errors); err != nil {
+		return PeriodCalculator{}, err
+	}
+
+	return PeriodCalculator{periodType: periodType, anchorDate: anchorDate, startDay: startDay, weekStart: DefaultWeekStart}, nil
+}
+
+// DefaultPeriodCalculator is the calculator of a period type that is not given an anchor date or a start day.
+// Weeks start on DefaultWeekStart, bi-weekly periods are counted from DefaultBiWeekAnchor, and months starting on a day start on the first.
+func DefaultPeriodCalculator(periodType BudgetPeriodType) PeriodCalculator {
+	calculator := PeriodCalculator{periodType: periodType, weekStart: DefaultWeekStart}
+	switch periodType {
+	case BudgetPeriodTypeBiWeek:
+		calculator.anchorDate = DefaultBiWeekAnchor
+	case BudgetPeriodTypeMonthStartingOnDay:
+		calculator.startDay = 1
+	}
+	return calculator
+}
+
+func MustPeriodCalculator(c PeriodCalculator, err error) PeriodCalculator {
+	if err != nil {
+		log.Fatalf("Failed to create period calculator. Reason: %s", err)
+	}
+	return c
+}
+
+func (c PeriodCalculator) PeriodType() BudgetPeriodType {
+	return c.periodType
+}
+
+// AnchorDate is the first day of one of the bi-weekly periods. It is zero for the other types.
+func (c PeriodCalculator) AnchorDate() time.Time {
+	return c.anchorDate
+}
+
+// StartDay is the day of the month on which months starting on a day start. It is zero for the other types.
+func (c PeriodCalculator) StartDay() uint {
+	return c.startDay
+}
+
+// WeekStart is the first day of weekly periods. It is DefaultWeekStart unless it is changed with WithWeekStart.
+func (c PeriodCalculator) WeekStart() time.Weekday {
+	return c.weekStart
+}
+
+// WithWeekStart returns a copy of the calculator whose weekly periods start on the given day, which is the week start of the user of the budget.
+// Only weekly periods depend on it.
+func (c PeriodCalculator) WithWeekStart(weekStart time.Weekday) PeriodCalculator {
+	c.weekStart = weekStart
+	return c
+}
+
+// PeriodOf returns the period that contains the date.
+// Weeks start on the week start; months, quarters and years are the calendar periods that contain the date.
+func (c PeriodCalculator) PeriodOf(date time.Time) BudgetPeriod {
+	day := startOfDay(date)
+
+	var firstDay time.Time
+	switch c.periodType {
+	case BudgetPeriodTypeWeek:
+		firstDay = MakeWeek(day, c.weekStart).FirstDay()
+	case BudgetPeriodTypeBiWeek:
+		days := int(day.Sub(c.anchorDate).Hours() / 24)
+		periods := days / 14
+		if days < 0 && days%14 != 0 {
+			periods--
+		}
+		firstDay = c.anchorDate.AddDate(0, 0, periods*14)
+	case BudgetPeriodTypeMonthStartingOnDay:
+		firstDay = time.Date(day.Year(), day.Month(), int(c.startDay), 0, 0, 0, 0, time.UTC)
+		if day.Before(firstDay) {
+			firstDay = firstDay.AddDate(0, -1, 0)
+		}
+	case BudgetPeriodTypeQuarter:
+		firstDay = MakeQuarterFromDate(day).FirstDay()
+	case BudgetPeriodTypeYear:
+		firstDay = MakeYearFromDate(day).FirstDay()
+	default:
+		firstDay = MakeCalendarMonthFromDate(day).FirstDay()
+	}
+	return BudgetPeriodStartingOn(c.periodType, firstDay)
+}
+
+func (c PeriodCalculator) String() string {
+	switch c.periodType {
+	case BudgetPeriodTypeBiWeek:
+		return fmt.Sprintf("%s{anchor: %s}", c.periodType, c.anchorDate.Format("2006-01-02"))
+	case BudgetPeriodTypeMonthStartingOnDay:
+		return fmt.Sprintf("%s{day: %d}", c.periodType, c.startDay)
+	default:
+		return string(c.periodType)
+	}
+}
+
+// PeriodOf returns the period of the given type that contains the date, as calculated by the default calculator of the type.
+func (pt BudgetPeriodType) PeriodOf(date time.Time) BudgetPeriod {
+	return DefaultPeriodCalculator(pt).PeriodOf(date)
+}
+
+// length returns the number of years, months and days between the first day of a period of this type and the first day of the next period.
+func (pt BudgetPeriodType) length() (years int, months int, days int) {
+	switch pt {
+	case BudgetPeriodTypeWeek:
+		return 0, 0, 7
+	case BudgetPeriodTypeBiWeek:
+		return 0, 0, 14
+	case BudgetPeriodTypeQuarter:
+		return 0, 3, 0
+	case BudgetPeriodTypeYear:
+		return 1, 0, 0
+	default:
+		return 0, 1, 0
+	}
+}
+
+// BudgetPeriod is the range of days, inclusive, over which the spending of a budget is compared to its limits.
+type BudgetPeriod struct {
+	periodType BudgetPeriodType
+	firstDay   time.Time
+	lastDay    time.Time
+}
+
+// BudgetPeriodStartingOn returns the period of the given type that starts on the given day.
+// The first day of a month starting on a day must be at most the 28th, so that each month starts on the same day.
+func BudgetPeriodStartingOn(periodType BudgetPeriodType, firstDay time.Time) BudgetPeriod {
+	firstDay = startOfDay(firstDay)
+	years, months, days := periodType.length()
+	return BudgetPeriod{
+		periodType: periodType,
+		firstDay:   firstDay,
+		lastDay:    firstDay.AddDate(years, months, days-1),
+	}
+}
+
+func (p BudgetPeriod) PeriodType() BudgetPeriodType {
+	return p.periodType
+}
+
+func (p BudgetPeriod) FirstDay() time.Time {
+	return p.firstDay
+}
+
+func (p BudgetPeriod) LastDay() time.Time {
+	return p.lastDay
+}
+
+// Next returns the period that starts the day after the last day of this period.
+func (p BudgetPeriod) Next() BudgetPeriod {
+	return BudgetPeriodStartingOn(p.periodType, p.lastDay.AddDate(0, 0, 1))
+}
+
+// Previous returns the period that ends the day before the first day of this period.
+func (p BudgetPeriod) Previous() BudgetPeriod {
+	years, months, days := p.periodType.length()
+	return BudgetPeriodStartingOn(p.periodType, p.firstDay.AddDate(-years, -months, -days))
+}
+
+func (p BudgetPeriod) String() string {
+	return fmt.Sprintf("%s{%s - %s}", p.periodType, p.firstDay.Format("2006-01-02"), p.lastDay.Format("2006-01-02"))
+}
+
+type budgetPeriodTypeValidator struct {
+	Name  string
+	Field string
+}
+
+func (v *budgetPeriodTypeValidator) IsValid(errors *validate.Errors) {
+	if len(v.Field) == 0 {
+		errors.Add(strings.ToLower(v.Name), "periodType is required")
+		return
+	}
+	validPeriodTypes := []string{
+		string(BudgetPeriodTypeWeek),
+		string(BudgetPeriodTypeBiWeek),
+		string(BudgetPeriodTypeMonth),
+		string(BudgetPeriodTypeMonthStartingOnDay),
+		string(BudgetPeriodTypeQuarter),
+		string(BudgetPeriodTypeYear),
+	}
+
+	validator := &validators.StringInclusion{
+		Name:    v.Name,
+		Field:   v.Field,
+		List:    validPeriodTypes,
+		Message: fmt.Sprintf("periodType must be one of %q", validPeriodTypes),
+	}
+	validator.IsValid(errors)
+}
+
+func startOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,145 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type BudgetPeriodTestSuite struct {
+	suite.Suite
+}
+
+func TestBudgetPeriodTestSuite(t *testing.T) {
+	suite.Run(t, new(BudgetPeriodTestSuite))
+}
+
+// -- SUITE
+
+func (suite *BudgetPeriodTestSuite) Test_GIVEN_anAnchorDate_WHEN_biWeeklyPeriodIsCalculated_THEN_periodsAreCountedInStepsOf14DaysFromTheAnchor() {
+	// GIVEN
+	calculator, err := NewPeriodCalculator(BudgetPeriodTypeBiWeek, time.Date(2021, time.July, 2, 9, 0, 0, 0, time.UTC), 0)
+	assert.Nil(suite.T(), err)
+
+	for _, test := range []struct {
+		date     time.Time
+		firstDay time.Time
+		lastDay  time.Time
+	}{
+		{date: utcDate(2021, time.July, 2), firstDay: utcDate(2021, time.July, 2), lastDay: utcDate(2021, time.July, 15)},
+		{date: utcDate(2021, time.July, 15), firstDay: utcDate(2021, time.July, 2), lastDay: utcDate(2021, time.July, 15)},
+		{date: utcDate(2021, time.August, 1), firstDay: utcDate(2021, time.July, 30), lastDay: utcDate(2021, time.August, 12)},
+		{date: utcDate(2021, time.July, 1), firstDay: utcDate(2021, time.June, 18), lastDay: utcDate(2021, time.July, 1)},
+		{date: utcDate(2021, time.June, 18), firstDay: utcDate(2021, time.June, 18), lastDay: utcDate(2021, time.July, 1)},
+	} {
+		// WHEN
+		period := calculator.PeriodOf(test.date)
+
+		// THEN
+		assert.Equal(suite.T(), test.firstDay, period.FirstDay(), "first day of period of %s", test.date)
+		assert.Equal(suite.T(), test.lastDay, period.LastDay(), "last day of period of %s", test.date)
+	}
+}
+
+func (suite *BudgetPeriodTestSuite) Test_GIVEN_aWeekStart_WHEN_weeklyPeriodIsCalculated_THEN_weekStartsOnTheWeekStart() {
+	// GIVEN
+	calculator := DefaultPeriodCalculator(BudgetPeriodTypeWeek)
+
+	for _, test := range []struct {
+		weekStart time.Weekday
+		date      time.Time
+		firstDay  time.Time
+		lastDay   time.Time
+	}{
+		{weekStart: time.Monday, date: utcDate(2021, time.July, 4), firstDay: utcDate(2021, time.June, 28), lastDay: utcDate(2021, time.July, 4)},
+		{weekStart: time.Sunday, date: utcDate(2021, time.July, 4), firstDay: utcDate(2021, time.July, 4), lastDay: utcDate(2021, time.July, 10)},
+		{weekStart: time.Saturday, date: utcDate(2021, time.July, 2), firstDay: utcDate(2021, time.June, 26), lastDay: utcDate(2021, time.July, 2)},
+	} {
+		// WHEN
+		period := calculator.WithWeekStart(test.weekStart).PeriodOf(test.date)
+
+		// THEN
+		assert.Equal(suite.T(), test.firstDay, period.FirstDay(), "first day of period of %s starting on %s", test.date, test.weekStart)
+		assert.Equal(suite.T(), test.lastDay, period.LastDay(), "last day of period of %s starting on %s", test.date, test.weekStart)
+	}
+}
+
+func (suite *BudgetPeriodTestSuite) Test_GIVEN_aStartDay_WHEN_monthStartingOnDayIsCalculated_THEN_monthStartsOnThatDay() {
+	// GIVEN
+	calculator, err := NewPeriodCalculator(BudgetPeriodTypeMonthStartingOnDay, time.Time{}, 25)
+	assert.Nil(suite.T(), err)
+
+	for _, test := range []struct {
+		date     time.Time
+		firstDay time.Time
+		lastDay  time.Time
+	}{
+		{date: utcDate(2021, time.July, 25), firstDay: utcDate(2021, time.July, 25), lastDay: utcDate(2021, time.August, 24)},
+		{date: utcDate(2021, time.July, 24), firstDay: utcDate(2021, time.June, 25), lastDay: utcDate(2021, time.July, 24)},
+		{date: utcDate(2022, time.January, 3), firstDay: utcDate(2021, time.December, 25), lastDay: utcDate(2022, time.January, 24)},
+		{date: utcDate(2020, time.February, 29), firstDay: utcDate(2020, time.February, 25), lastDay: utcDate(2020, time.March, 24)},
+	} {
+		// WHEN
+		period := calculator.PeriodOf(test.date)
+
+		// THEN
+		assert.Equal(suite.T(), test.firstDay, period.FirstDay(), "first day of period of %s", test.date)
+		assert.Equal(suite.T(), test.lastDay, period.LastDay(), "last day of period of %s", test.date)
+	}
+}
+
+func (suite *BudgetPeriodTestSuite) Test_GIVEN_aDate_WHEN_quarterlyAndYearlyPeriodsAreCalculated_THEN_periodsAreCalendarQuartersAndYears() {
+	// GIVEN
+	day := utcDate(2021, time.August, 17)
+
+	// WHEN
+	quarter := DefaultPeriodCalculator(BudgetPeriodTypeQuarter).PeriodOf(day)
+	year := DefaultPeriodCalculator(BudgetPeriodTypeYear).PeriodOf(day)
+
+	// THEN
+	assert.Equal(suite.T(), utcDate(2021, time.July, 1), quarter.FirstDay())
+	assert.Equal(suite.T(), utcDate(2021, time.September, 30), quarter.LastDay())
+	assert.Equal(suite.T(), utcDate(2021, time.January, 1), year.FirstDay())
+	assert.Equal(suite.T(), utcDate(2021, time.December, 31), year.LastDay())
+}
+
+func (suite *BudgetPeriodTestSuite) Test_GIVEN_aPeriod_WHEN_previousAndNextPeriodsAreCalculated_THEN_periodsAreAdjacent() {
+	for _, calculator := range []PeriodCalculator{
+		DefaultPeriodCalculator(BudgetPeriodTypeWeek),
+		MustPeriodCalculator(NewPeriodCalculator(BudgetPeriodTypeBiWeek, utcDate(2021, time.July, 2), 0)),
+		DefaultPeriodCalculator(BudgetPeriodTypeMonth),
+		MustPeriodCalculator(NewPeriodCalculator(BudgetPeriodTypeMonthStartingOnDay, time.Time{}, 28)),
+		DefaultPeriodCalculator(BudgetPeriodTypeQuarter),
+		DefaultPeriodCalculator(BudgetPeriodTypeYear),
+	} {
+		// GIVEN
+		period := calculator.PeriodOf(utcDate(2021, time.March, 1))
+
+		// WHEN
+		previous := period.Previous()
+		next := period.Next()
+
+		// THEN
+		assert.Equal(suite.T(), period.FirstDay().AddDate(0, 0, -1), previous.LastDay(), "previous period of %s", period)
+		assert.Equal(suite.T(), period.LastDay().AddDate(0, 0, 1), next.FirstDay(), "next period of %s", period)
+		assert.Equal(suite.T(), calculator.PeriodOf(previous.FirstDay()), previous, "previous period of %s", period)
+		assert.Equal(suite.T(), calculator.PeriodOf(next.LastDay()), next, "next period of %s", period)
+	}
+}
+
+func (suite *BudgetPeriodTestSuite) Test_GIVEN_missingOrInvalidParameters_WHEN_periodCalculatorIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, biWeekErr := NewPeriodCalculator(BudgetPeriodTypeBiWeek, time.Time{}, 0)
+	_, startDayErr := NewPeriodCalculator(BudgetPeriodTypeMonthStartingOnDay, time.Time{}, 29)
+	_, periodTypeErr := NewPeriodCalculator(BudgetPeriodType("Fortnight"), time.Time{}, 0)
+
+	// THEN
+	assert.Equal(suite.T(), pkg.ErrBudgetValidation, errorCode(biWeekErr, 0))
+	assert.Equal(suite.T(), "periodAnchor is required for bi-weekly periods", errorFields(biWeekErr)["periodAnchor"])
+	assert.Equal(suite.T(), pkg.ErrBudgetValidation, errorCode(startDayErr, 0))
+	assert.Equal(suite.T(), "periodStartDay must be between 1 and 28", errorFields(startDayErr)["periodStartDay"])
+	assert.Equal(suite.T(), pkg.ErrBudgetValidation, errorCode(periodTypeErr, 0))
+}
@@ -0,0 +1,138 @@
+package ledger
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+// Progress compares the amount spent to the maximum amount that can be spent.
+type Progress struct {
+	maxLimit Money
+	spent    Money
+}
+
+func newProgress(maxLimit Money, spent Money) (Progress, error) {
+	if maxLimit.Currency().CurrencyCode() != spent.Currency().CurrencyCode() {
+		return Progress{}, pkg.ValidationErrorWithFields(pkg.ErrAmountMismatchingCurrencies, fmt.Sprintf("Spent amount %s is not in the currency of the budget %s", spent, maxLimit), nil, nil)
+	}
+	return Progress{maxLimit: maxLimit, spent: spent}, nil
+}
+
+func (p Progress) MaxLimit() Money {
+	return p.maxLimit
+}
+
+func (p Progress) Spent() Money {
+	return p.spent
+}
+
+// Remaining is the amount that can still be spent. It is negative when more than the maximum amount was spent.
+func (p Progress) Remaining() Money {
+	return MustMoney(NewMoney(p.maxLimit.Currency().CurrencyCode(), p.maxLimit.MustMinorUnits()-p.spent.MustMinorUnits()))
+}
+
+// PercentUsed is the percentage of the maximum amount that was spent, rounded to two decimal places.
+// It can be over 100 when more than the maximum amount was spent. Any spending of a zero limit is reported as 100 percent.
+func (p Progress) PercentUsed() float64 {
+	maxLimit := p.maxLimit.MustMinorUnits()
+	spent := p.spent.MustMinorUnits()
+	if maxLimit == 0 {
+		if spent == 0 {
+			return 0
+		}
+		return 100
+	}
+	return math.Round(float64(spent)*10000/float64(maxLimit)) / 100
+}
+
+type CategoryBudgetProgress struct {
+	Progress
+	categoryId CategoryId
+}
+
+func (cp CategoryBudgetProgress) CategoryId() CategoryId {
+	return cp.categoryId
+}
+
+// BudgetProgress is the spending of each category of a budget in a period.
+type BudgetProgress struct {
+	budget     Budget
+	period     BudgetPeriod
+	categories []CategoryBudgetProgress
+	total      Progress
+}
+
+// NewBudgetProgress compares the amount spent on each category of a budget in a period to its maximum amount.
+// Categories that are not in spentPerCategory were not spent on.
+func NewBudgetProgress(budget Budget, period BudgetPeriod, spentPerCategory map[CategoryId]Money) (BudgetProgress, error) {
+	var currencyCode string
+	if len(budget.CategoryBudgets()) != 0 {
+		currencyCode = budget.CategoryBudgets()[0].MaxLimit().Currency().CurrencyCode()
+	}
+
+	categories, total, err := newCategoriesProgress(budget.CategoryBudgets(), spentPerCategory, currencyCode)
+	if err != nil {
+		return BudgetProgress{}, err
+	}
+
+	return BudgetProgress{
+		budget:     budget,
+		period:     period,
+		categories: categories,
+		total:      total,
+	}, nil
+}
+
+// newCategoriesProgress compares the amount spent on each category to its maximum amount, and the amount spent on all of them to the sum of their maximum amounts.
+// The total is in the given currency.
+func newCategoriesProgress(categoryBudgets CategoryBudgets, spentPerCategory map[CategoryId]Money, currencyCode string) ([]CategoryBudgetProgress, Progress, error) {
+	var (
+		categories    = make([]CategoryBudgetProgress, 0, len(categoryBudgets))
+		totalMaxLimit int64
+		totalSpent    int64
+	)
+
+	for _, categoryBudget := range categoryBudgets {
+		spent, ok := spentPerCategory[categoryBudget.CategoryId()]
+		if !ok {
+			spent = MustMoney(NewMoney(categoryBudget.MaxLimit().Currency().CurrencyCode(), 0))
+		}
+
+		progress, err := newProgress(categoryBudget.MaxLimit(), spent)
+		if err != nil {
+			return nil, Progress{}, err
+		}
+
+		categories = append(categories, CategoryBudgetProgress{Progress: progress, categoryId: categoryBudget.CategoryId()})
+		totalMaxLimit += categoryBudget.MaxLimit().MustMinorUnits()
+		totalSpent += spent.MustMinorUnits()
+	}
+
+	var total Progress
+	if len(currencyCode) != 0 {
+		total = Progress{
+			maxLimit: MustMoney(NewMoney(currencyCode, totalMaxLimit)),
+			spent:    MustMoney(NewMoney(currencyCode, totalSpent)),
+		}
+	}
+	return categories, total, nil
+}
+
+func (bp BudgetProgress) Budget() Budget {
+	return bp.budget
+}
+
+func (bp BudgetProgress) Period() BudgetPeriod {
+	return bp.period
+}
+
+func (bp BudgetProgress) Categories() []CategoryBudgetProgress {
+	return bp.categories
+}
+
+// Total is the progress of all the categories of the budget combined.
+func (bp BudgetProgress) Total() Progress {
+	return bp.total
+}
@@ -0,0 +1,117 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type BudgetProgressTestSuite struct {
+	suite.Suite
+}
+
+func TestBudgetProgressTestSuite(t *testing.T) {
+	suite.Run(t, new(BudgetProgressTestSuite))
+}
+
+// -- SUITE
+
+func (suite *BudgetProgressTestSuite) Test_GIVEN_aDate_WHEN_weeklyPeriodIsCalculated_THEN_periodStartsOnMondayAndEndsOnSunday() {
+	// GIVEN
+	date := time.Date(2021, time.July, 15, 18, 30, 0, 0, time.UTC) // Thursday
+
+	// WHEN
+	period := BudgetPeriodTypeWeek.PeriodOf(date)
+
+	// THEN
+	assert.Equal(suite.T(), time.Date(2021, time.July, 12, 0, 0, 0, 0, time.UTC), period.FirstDay())
+	assert.Equal(suite.T(), time.Date(2021, time.July, 18, 0, 0, 0, 0, time.UTC), period.LastDay())
+}
+
+func (suite *BudgetProgressTestSuite) Test_GIVEN_aSunday_WHEN_weeklyPeriodIsCalculated_THEN_periodEndsOnThatSunday() {
+	// GIVEN
+	date := time.Date(2021, time.August, 1, 0, 0, 0, 0, time.UTC)
+
+	// WHEN
+	period := BudgetPeriodTypeWeek.PeriodOf(date)
+
+	// THEN
+	assert.Equal(suite.T(), time.Date(2021, time.July, 26, 0, 0, 0, 0, time.UTC), period.FirstDay())
+	assert.Equal(suite.T(), time.Date(2021, time.August, 1, 0, 0, 0, 0, time.UTC), period.LastDay())
+}
+
+func (suite *BudgetProgressTestSuite) Test_GIVEN_aDate_WHEN_monthlyPeriodIsCalculated_THEN_periodIsTheCalendarMonth() {
+	// GIVEN
+	date := time.Date(2020, time.February, 10, 0, 0, 0, 0, time.UTC)
+
+	// WHEN
+	period := BudgetPeriodTypeMonth.PeriodOf(date)
+
+	// THEN
+	assert.Equal(suite.T(), time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC), period.FirstDay())
+	assert.Equal(suite.T(), time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC), period.LastDay())
+}
+
+func (suite *BudgetProgressTestSuite) Test_GIVEN_spendingPerCategory_WHEN_budgetProgressIsCalculated_THEN_remainingAndPercentUsedAreCalculatedPerCategoryAndInTotal() {
+	// GIVEN
+	budget, err := NewBudget(
+		BudgetId(1),
+		AccountIds{AccountId(1)},
+		BudgetPeriodTypeMonth,
+		CategoryBudgets{
+			MustCategoryBudget(NewCategoryBudget(CategoryId(1), MustMoney(NewMoney("AED", 1000_00)))),
+			MustCategoryBudget(NewCategoryBudget(CategoryId(2), MustMoney(NewMoney("AED", 500_00)))),
+			MustCategoryBudget(NewCategoryBudget(CategoryId(3), MustMoney(NewMoney("AED", 300_00)))),
+		},
+		MustMakeUpdatedByUserId(UserId(1)),
+	)
+	assert.Nil(suite.T(), err)
+
+	// WHEN
+	progress, err := NewBudgetProgress(budget, BudgetPeriodTypeMonth.PeriodOf(time.Now()), map[CategoryId]Money{
+		CategoryId(1): MustMoney(NewMoney("AED", 250_00)),
+		CategoryId(2): MustMoney(NewMoney("AED", 600_00)),
+	})
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), progress.Categories(), 3)
+
+	assert.Equal(suite.T(), CategoryId(1), progress.Categories()[0].CategoryId())
+	assert.Equal(suite.T(), int64(750_00), progress.Categories()[0].Remaining().MustMinorUnits())
+	assert.Equal(suite.T(), 25.0, progress.Categories()[0].PercentUsed())
+
+	assert.Equal(suite.T(), int64(-100_00), progress.Categories()[1].Remaining().MustMinorUnits())
+	assert.Equal(suite.T(), 120.0, progress.Categories()[1].PercentUsed())
+
+	assert.Equal(suite.T(), int64(0), progress.Categories()[2].Spent().MustMinorUnits())
+	assert.Equal(suite.T(), 0.0, progress.Categories()[2].PercentUsed())
+
+	assert.Equal(suite.T(), int64(1800_00), progress.Total().MaxLimit().MustMinorUnits())
+	assert.Equal(suite.T(), int64(850_00), progress.Total().Spent().MustMinorUnits())
+	assert.Equal(suite.T(), int64(950_00), progress.Total().Remaining().MustMinorUnits())
+	assert.Equal(suite.T(), 47.22, progress.Total().PercentUsed())
+}
+
+func (suite *BudgetProgressTestSuite) Test_GIVEN_spendingInAnotherCurrency_WHEN_budgetProgressIsCalculated_THEN_errorIsReturned() {
+	// GIVEN
+	budget, _ := NewBudget(
+		BudgetId(1),
+		AccountIds{AccountId(1)},
+		BudgetPeriodTypeWeek,
+		CategoryBudgets{MustCategoryBudget(NewCategoryBudget(CategoryId(1), MustMoney(NewMoney("AED", 1000_00))))},
+		MustMakeUpdatedByUserId(UserId(1)),
+	)
+
+	// WHEN
+	_, err := NewBudgetProgress(budget, BudgetPeriodTypeWeek.PeriodOf(time.Now()), map[CategoryId]Money{
+		CategoryId(1): MustMoney(NewMoney("USD", 250_00)),
+	})
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrAmountMismatchingCurrencies, errorCode(err, 0))
+}
@@ -150,3 +150,107 @@ func (suite *BudgetTestSuite) Test_GIVEN_anAccount_WHEN_stringIsCalled_THEN_stri
 	// THEN
 	assert.Equal(suite.T(), "Budget{id: 1, accountIds: [1], periodType: Month, categoryBudgets: CategoryBudgets{1: AED 0.00 / AED 2000.00, 2: AED 0.00 / AED 1000.00}}", budget.String())
 }
+
+func (suite *BudgetTestSuite) Test_GIVEN_aCategoryBudgetedTwice_WHEN_CreatingBudget_THEN_errorIsReturned() {
+	// WHEN
+	budget, err := NewBudget(
+		1,
+		AccountIds{1},
+		BudgetPeriodTypeMonth,
+		CategoryBudgets{suite.testCategoryBudgets[0], suite.testCategoryBudgets[0]},
+		MustMakeUpdatedByUserId(1),
+	)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), Budget{}, budget)
+	assert.Equal(suite.T(), pkg.ErrBudgetValidation, errorCode(err, 0))
+	assert.Equal(suite.T(), "category 1 can only be budgeted once", err.Error())
+}
+
+func (suite *BudgetTestSuite) Test_GIVEN_aBudget_WHEN_edited_THEN_detailsAreReplacedAndModifiedByIsSet() {
+	// GIVEN
+	budget, _ := NewBudget(
+		1,
+		AccountIds{1},
+		BudgetPeriodTypeMonth,
+		suite.testCategoryBudgets,
+		MustMakeUpdatedByUserId(1),
+	)
+
+	// WHEN
+	edited, err := budget.Edit(
+		AccountIds{1, 2},
+		BudgetPeriodTypeWeek,
+		suite.testCategoryBudgets[1:],
+		MustMakeUpdatedByUserId(2),
+	)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), budget.Id(), edited.Id())
+	assert.Equal(suite.T(), AccountIds{1, 2}, edited.AccountIds())
+	assert.Equal(suite.T(), BudgetPeriodTypeWeek, edited.PeriodType())
+	assert.Len(suite.T(), edited.CategoryBudgets(), 1)
+	assert.Equal(suite.T(), budget.CreatedBy(), edited.CreatedBy())
+	assert.Equal(suite.T(), "UserId: 2", edited.ModifiedBy().String())
+	assert.Equal(suite.T(), budget.Version(), edited.Version())
+}
+
+func (suite *BudgetTestSuite) Test_GIVEN_aBudget_WHEN_editedWithoutCategoryBudgets_THEN_errorIsReturned() {
+	// GIVEN
+	budget, _ := NewBudget(
+		1,
+		AccountIds{1},
+		BudgetPeriodTypeMonth,
+		suite.testCategoryBudgets,
+		MustMakeUpdatedByUserId(1),
+	)
+
+	// WHEN
+	_, err := budget.Edit(AccountIds{1}, BudgetPeriodTypeMonth, CategoryBudgets{}, MustMakeUpdatedByUserId(1))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrBudgetValidation, errorCode(err, 0))
+}
+
+func (suite *BudgetTestSuite) Test_GIVEN_noAlertThresholds_WHEN_categoryBudgetIsCreated_THEN_defaultThresholdsAreUsed() {
+	// WHEN
+	categoryBudget, err := NewCategoryBudget(1, MustMoney(NewMoney("AED", 1000_00)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), AlertThresholds{50, 80, 100}, categoryBudget.AlertThresholds())
+}
+
+func (suite *BudgetTestSuite) Test_GIVEN_unorderedAlertThresholds_WHEN_categoryBudgetIsCreated_THEN_thresholdsAreSorted() {
+	// WHEN
+	categoryBudget, err := NewCategoryBudgetWithAlertThresholds(1, MustMoney(NewMoney("AED", 1000_00)), AlertThresholds{120, 75})
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), AlertThresholds{75, 120}, categoryBudget.AlertThresholds())
+}
+
+func (suite *BudgetTestSuite) Test_GIVEN_invalidAlertThresholds_WHEN_categoryBudgetIsCreated_THEN_errorIsReturned() {
+	for _, thresholds := range []AlertThresholds{{0}, {1001}, {50, 50}} {
+		// WHEN
+		_, err := NewCategoryBudgetWithAlertThresholds(1, MustMoney(NewMoney("AED", 1000_00)), thresholds)
+
+		// THEN
+		assert.NotNil(suite.T(), err, "thresholds %v", thresholds)
+		assert.Equal(suite.T(), pkg.ErrBudgetValidation, errorCode(err, 0))
+		assert.Contains(suite.T(), errorFields(err), "alertthresholds")
+	}
+}
+
+func (suite *BudgetTestSuite) Test_GIVEN_aSpentAmount_WHEN_reachedAlertThresholdsAreCalculated_THEN_thresholdsUpToTheSpentPercentageAreReturned() {
+	// GIVEN
+	categoryBudget := MustCategoryBudget(NewCategoryBudget(1, MustMoney(NewMoney("AED", 1000_00))))
+
+	// THEN
+	assert.Equal(suite.T(), AlertThresholds{}, categoryBudget.ReachedAlertThresholds(MustMoney(NewMoney("AED", 499_99))))
+	assert.Equal(suite.T(), AlertThresholds{50}, categoryBudget.ReachedAlertThresholds(MustMoney(NewMoney("AED", 500_00))))
+	assert.Equal(suite.T(), AlertThresholds{50, 80, 100}, categoryBudget.ReachedAlertThresholds(MustMoney(NewMoney("AED", 1200_00))))
+}
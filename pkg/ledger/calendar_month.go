@@ -22,11 +22,7 @@ func MakeCalendarMonthFromDate(date time.Time) CalendarMonth {
 }
 
 func CurrentCalendarMonth() CalendarMonth {
-	current := time.Now().UTC().AddDate(0, -1, time.Now().UTC().Day()+1)
-	return CalendarMonth{
-		year:  uint(current.Year()),
-		month: current.Month(),
-	}
+	return MakeCalendarMonthFromDate(time.Now().UTC())
 }
 
 func (cm CalendarMonth) Month() time.Month {
@@ -55,6 +51,18 @@ func (cm CalendarMonth) PreviousMonth() CalendarMonth {
 	return MakeCalendarMonth(uint(date.Year()), date.Month())
 }
 
+func (cm CalendarMonth) Next() Period {
+	return cm.NextMonth()
+}
+
+func (cm CalendarMonth) Previous() Period {
+	return cm.PreviousMonth()
+}
+
+func (cm CalendarMonth) Contains(date time.Time) bool {
+	return periodContains(cm, date)
+}
+
 func (cm CalendarMonth) String() string {
 	return fmt.Sprintf("%04d-%02d", cm.year, cm.month)
 }
@@ -71,7 +71,7 @@ func newCategory(id CategoryId, name string, auditInfo auditInfo) (Category, err
 	return Category{
 		auditInfo: auditInfo,
 		id:        id,
-		name:      strings.Title(strings.ToLower(name)),
+		name:      NormalizeName(name),
 	}, nil
 }
 
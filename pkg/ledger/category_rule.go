@@ -0,0 +1,228 @@
+package ledger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+// AnyAccount is used for the rules that apply to the records of all accounts.
+const AnyAccount = AccountId(0)
+
+// NoAmountLimit is used for the bounds of the amount of a rule that are not set.
+const NoAmountLimit = int64(-1)
+
+type CategoryRuleId uint64
+
+// CategoryRuleConditions are the conditions that a record must meet for a rule to apply to it.
+// Conditions that are not set (an empty note, AnyAccount or NoAmountLimit) are met by all records.
+// The amounts are in minor units and are compared with the absolute amount of a record, so they apply to income and expenses alike.
+type CategoryRuleConditions struct {
+	NoteContains string
+	AccountId    AccountId
+	MinAmount    int64
+	MaxAmount    int64
+}
+
+// CategoryRule categorises the records that meet its conditions e.g. "note contains 'UBER' → Transport".
+// Rules are tried in ascending order of priority; the first rule that applies categorises the record.
+type CategoryRule struct {
+	auditInfo
+	id         CategoryRuleId
+	priority   int
+	conditions CategoryRuleConditions
+	categoryId CategoryId
+}
+
+type CategoryRuleRecord interface {
+	Id() CategoryRuleId
+	Priority() int
+	Conditions() CategoryRuleConditions
+	CategoryId() CategoryId
+	CreatedBy() UpdatedBy
+	CreatedAtUTC() time.Time
+	ModifiedBy() UpdatedBy
+	ModifiedAtUTC() time.Time
+	Version() Version
+}
+
+func NewCategoryRule(
+	id CategoryRuleId,
+	priority int,
+	conditions CategoryRuleConditions,
+	categoryId CategoryId,
+	createdBy UpdatedBy,
+) (CategoryRule, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	if auditInfo, err = makeAuditForCreation(createdBy); err != nil {
+		return CategoryRule{}, err
+	}
+
+	return newCategoryRule(id, priority, conditions, categoryId, auditInfo)
+}
+
+func NewCategoryRuleFromRecord(cr CategoryRuleRecord) (CategoryRule, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	if auditInfo, err = makeAuditForModification(
+		cr.CreatedBy(),
+		cr.CreatedAtUTC(),
+		cr.ModifiedBy(),
+		cr.ModifiedAtUTC(),
+		cr.Version(),
+	); err != nil {
+		return CategoryRule{}, err
+	}
+
+	return newCategoryRule(cr.Id(), cr.Priority(), cr.Conditions(), cr.CategoryId(), auditInfo)
+}
+
+func newCategoryRule(
+	id CategoryRuleId,
+	priority int,
+	conditions CategoryRuleConditions,
+	categoryId CategoryId,
+	auditInfo auditInfo,
+) (CategoryRule, error) {
+	conditions.NoteContains = strings.TrimSpace(conditions.NoteContains)
+
+	errors := validate.Validate(
+		&validators.IntIsGreaterThan{Name: "Id", Field: int(id), Compared: 0, Message: "Id must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "Priority", Field: priority, Compared: -1, Message: "Priority must be 0 or greater"},
+		&validators.IntIsGreaterThan{Name: "CategoryId", Field: int(categoryId), Compared: 0, Message: "CategoryId must be greater than 0"},
+		&validators.StringLengthInRange{Name: "NoteContains", Field: conditions.NoteContains, Min: 0, Max: 50, Message: "noteContains must be at most 50 characters long"},
+		&categoryRuleConditionsValidator{Value: conditions},
+	)
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrCategoryRuleValidation, "", errors); err != nil {
+		return CategoryRule{}, err
+	}
+
+	return CategoryRule{
+		auditInfo:  auditInfo,
+		id:         id,
+		priority:   priority,
+		conditions: conditions,
+		categoryId: categoryId,
+	}, nil
+}
+
+func (r CategoryRule) Id() CategoryRuleId {
+	return r.id
+}
+
+func (r CategoryRule) Priority() int {
+	return r.priority
+}
+
+func (r CategoryRule) Conditions() CategoryRuleConditions {
+	return r.conditions
+}
+
+// CategoryId is the category of the records that the rule applies to
+func (r CategoryRule) CategoryId() CategoryId {
+	return r.categoryId
+}
+
+// AppliesTo reports whether a record of the account with the given note and amount meets the conditions of the rule.
+// Notes are compared ignoring case.
+func (r CategoryRule) AppliesTo(accountId AccountId, note string, amount Money) bool {
+	if r.conditions.AccountId != AnyAccount && r.conditions.AccountId != accountId {
+		return false
+	}
+	if len(r.conditions.NoteContains) > 0 && !strings.Contains(strings.ToLower(note), strings.ToLower(r.conditions.NoteContains)) {
+		return false
+	}
+	if r.conditions.MinAmount == NoAmountLimit && r.conditions.MaxAmount == NoAmountLimit {
+		return true
+	}
+
+	if amount == nil {
+		return false
+	}
+	abs, err := amount.Abs()
+	if err != nil {
+		return false
+	}
+	minorUnits, err := abs.MinorUnits()
+	if err != nil {
+		return false
+	}
+	if r.conditions.MinAmount != NoAmountLimit && minorUnits < r.conditions.MinAmount {
+		return false
+	}
+	if r.conditions.MaxAmount != NoAmountLimit && minorUnits > r.conditions.MaxAmount {
+		return false
+	}
+	return true
+}
+
+func (r CategoryRule) String() string {
+	return fmt.Sprintf("CategoryRule{id: %d, priority: %d, categoryId: %d}", r.id, r.priority, r.categoryId)
+}
+
+type CategoryRules []CategoryRule
+
+func (rs CategoryRules) Len() int {
+	return len(rs)
+}
+
+func (rs CategoryRules) Less(i, j int) bool {
+	if rs[i].priority != rs[j].priority {
+		return rs[i].priority < rs[j].priority
+	}
+	return rs[i].id < rs[j].id
+}
+
+func (rs CategoryRules) Swap(i, j int) {
+	rs[i], rs[j] = rs[j], rs[i]
+}
+
+// Match returns the rule with the lowest priority that applies to a record of the account with the given note and amount.
+// false is returned if no rule applies.
+func (rs CategoryRules) Match(accountId AccountId, note string, amount Money) (CategoryRule, bool) {
+	sorted := make(CategoryRules, len(rs))
+	copy(sorted, rs)
+	sort.Stable(sorted)
+
+	for _, rule := range sorted {
+		if rule.AppliesTo(accountId, note, amount) {
+			return rule, true
+		}
+	}
+	return CategoryRule{}, false
+}
+
+type categoryRuleConditionsValidator struct {
+	Value CategoryRuleConditions
+}
+
+func (v *categoryRuleConditionsValidator) IsValid(errors *validate.Errors) {
+	if v.Value.MinAmount < NoAmountLimit {
+		errors.Add("minAmount", "minAmount must be 0 or greater")
+	}
+	if v.Value.MaxAmount < NoAmountLimit {
+		errors.Add("maxAmount", "maxAmount must be 0 or greater")
+	}
+	if v.Value.MinAmount != NoAmountLimit && v.Value.MaxAmount != NoAmountLimit && v.Value.MinAmount > v.Value.MaxAmount {
+		errors.Add("maxAmount", "maxAmount must be greater than or equal to minAmount")
+	}
+	if len(v.Value.NoteContains) == 0 &&
+		v.Value.AccountId == AnyAccount &&
+		v.Value.MinAmount == NoAmountLimit &&
+		v.Value.MaxAmount == NoAmountLimit {
+		errors.Add("conditions", "a rule must have at least one condition")
+	}
+}
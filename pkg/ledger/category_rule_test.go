@@ -0,0 +1,80 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type CategoryRuleTestSuite struct {
+	suite.Suite
+}
+
+func TestCategoryRuleTestSuite(t *testing.T) {
+	suite.Run(t, new(CategoryRuleTestSuite))
+}
+
+func categoryRule(id CategoryRuleId, priority int, conditions CategoryRuleConditions, categoryId CategoryId) (CategoryRule, error) {
+	return NewCategoryRule(id, priority, conditions, categoryId, MustMakeUpdatedByUserId(UserId(1)))
+}
+
+// -- SUITE
+
+func (suite *CategoryRuleTestSuite) Test_GIVEN_invalidConditions_WHEN_ruleIsCreated_THEN_errorIsReturned() {
+	testCases := []struct {
+		name       string
+		conditions CategoryRuleConditions
+		field      string
+	}{
+		{name: "no conditions", conditions: CategoryRuleConditions{MinAmount: NoAmountLimit, MaxAmount: NoAmountLimit}, field: "conditions"},
+		{name: "blank note", conditions: CategoryRuleConditions{NoteContains: "  ", MinAmount: NoAmountLimit, MaxAmount: NoAmountLimit}, field: "conditions"},
+		{name: "negative amount", conditions: CategoryRuleConditions{MinAmount: -5, MaxAmount: NoAmountLimit}, field: "minAmount"},
+		{name: "inverted range", conditions: CategoryRuleConditions{MinAmount: 200, MaxAmount: 100}, field: "maxAmount"},
+	}
+
+	for _, tc := range testCases {
+		// WHEN
+		_, err := categoryRule(1, 0, tc.conditions, CategoryId(1))
+
+		// THEN
+		assert.NotNil(suite.T(), err, tc.name)
+		assert.Equal(suite.T(), pkg.ErrCategoryRuleValidation, errorCode(err, 0), tc.name)
+		assert.Contains(suite.T(), errorFields(err), tc.field, tc.name)
+	}
+}
+
+func (suite *CategoryRuleTestSuite) Test_GIVEN_rules_WHEN_recordIsMatched_THEN_firstApplicableRuleByPriorityIsReturned() {
+	// GIVEN
+	transport, _ := categoryRule(1, 10, CategoryRuleConditions{NoteContains: "uber", MinAmount: NoAmountLimit, MaxAmount: NoAmountLimit}, CategoryId(1))
+	rent, _ := categoryRule(2, 5, CategoryRuleConditions{AccountId: AccountId(7), MinAmount: 500000, MaxAmount: 600000}, CategoryId(2))
+	eats, _ := categoryRule(3, 1, CategoryRuleConditions{NoteContains: "UBER EATS", MinAmount: NoAmountLimit, MaxAmount: NoAmountLimit}, CategoryId(3))
+	rules := CategoryRules{transport, rent, eats}
+
+	testCases := []struct {
+		name      string
+		accountId AccountId
+		note      string
+		amount    int64
+		ruleId    CategoryRuleId
+		matched   bool
+	}{
+		{name: "note", accountId: 1, note: "Uber trip", amount: -2500, ruleId: 1, matched: true},
+		{name: "priority", accountId: 1, note: "Uber Eats order", amount: -2500, ruleId: 3, matched: true},
+		{name: "account and amount", accountId: 7, note: "Landlord", amount: -550000, ruleId: 2, matched: true},
+		{name: "amount out of range", accountId: 7, note: "Landlord", amount: -650000, matched: false},
+		{name: "other account", accountId: 1, note: "Landlord", amount: -550000, matched: false},
+	}
+
+	for _, tc := range testCases {
+		amount, _ := NewMoney("AED", tc.amount)
+
+		// WHEN
+		rule, ok := rules.Match(tc.accountId, tc.note, amount)
+
+		// THEN
+		assert.Equal(suite.T(), tc.matched, ok, tc.name)
+		assert.Equal(suite.T(), tc.ruleId, rule.Id(), tc.name)
+	}
+}
@@ -133,3 +133,22 @@ func (suite *CategoryTestSuite) Test_GIVEN_categoryNames_WHEN_categoriesAreSaved
 	// THEN
 	assert.Equal(suite.T(), "Categories{Category{id: 2, name: Entertainment}, Category{id: 1, name: Health}}", categories.String())
 }
+
+func (suite *CategoryTestSuite) Test_GIVEN_names_WHEN_CategoriesAreCreated_THEN_namesAreNormalizedAsTheyWereSaved() {
+	for name, expected := range map[string]string{
+		"FOOD & drink": "Food & Drink",
+		"McDONALD'S":   "Mcdonald'S",
+		"O'Neil":       "O'Neil",
+		"3G data":      "3g Data",
+		"fast-food":    "Fast-Food",
+		"café crème":   "Café Crème",
+	} {
+		// WHEN
+		category, err := NewCategory(CategoryId(1), name, MustMakeUpdatedByUserId(UserId(1)))
+
+		// THEN
+		assert.Nil(suite.T(), err, name)
+		assert.Equal(suite.T(), expected, category.Name(), name)
+		assert.Equal(suite.T(), expected, NormalizeName(name), name)
+	}
+}
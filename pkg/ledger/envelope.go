@@ -0,0 +1,357 @@
+package ledger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type EnvelopeTransferId uint64
+
+// EnvelopeTransfer moves part of the allocation of a category budget (an envelope) to another category budget of the same budget, in a period.
+type EnvelopeTransfer struct {
+	auditInfo
+	id             EnvelopeTransferId
+	budgetId       BudgetId
+	fromCategoryId CategoryId
+	toCategoryId   CategoryId
+	period         BudgetPeriod
+	amount         Money
+	note           string
+}
+
+type EnvelopeTransferRecord interface {
+	Id() EnvelopeTransferId
+	BudgetId() BudgetId
+	FromCategoryId() CategoryId
+	ToCategoryId() CategoryId
+	Period() BudgetPeriod
+	Amount() Money
+	Note() string
+	CreatedBy() UpdatedBy
+	CreatedAtUTC() time.Time
+	ModifiedBy() UpdatedBy
+	ModifiedAtUTC() time.Time
+	Version() Version
+}
+
+// NewEnvelopeTransfer creates a transfer between two category budgets of a budget, in a period of the budget.
+// The amount must be in the currency of the budget.
+func NewEnvelopeTransfer(
+	id EnvelopeTransferId,
+	budget Budget,
+	fromCategoryId CategoryId,
+	toCategoryId CategoryId,
+	period BudgetPeriod,
+	amount Money,
+	note string,
+	createdBy UpdatedBy,
+) (EnvelopeTransfer, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	categoryBudgets := map[CategoryId]CategoryBudget{}
+	for _, categoryBudget := range budget.CategoryBudgets() {
+		categoryBudgets[categoryBudget.CategoryId()] = categoryBudget
+	}
+
+	for i, categoryId := range []CategoryId{fromCategoryId, toCategoryId} {
+		if _, ok := categoryBudgets[categoryId]; !ok {
+			field := "fromCategoryId"
+			if i == 1 {
+				field = "toCategoryId"
+			}
+			return EnvelopeTransfer{}, pkg.ValidationErrorWithFields(pkg.ErrBudgetValidation, fmt.Sprintf("Category %d is not budgeted by budget %d", categoryId, budget.Id()), nil, map[string]string{
+				field: fmt.Sprintf("category %d is not budgeted by budget %d", categoryId, budget.Id()),
+			})
+		}
+	}
+
+	if period.PeriodType() != budget.PeriodType() || !budget.PeriodCalculator().PeriodOf(period.FirstDay()).FirstDay().Equal(period.FirstDay()) {
+		return EnvelopeTransfer{}, pkg.ValidationErrorWithFields(pkg.ErrBudgetValidation, fmt.Sprintf("Period %s is not a period of budget %d", period, budget.Id()), nil, map[string]string{
+			"period": fmt.Sprintf("period must be a %s", budget.PeriodType()),
+		})
+	}
+
+	if currency := categoryBudgets[fromCategoryId].MaxLimit().Currency().CurrencyCode(); amount.Currency().CurrencyCode() != currency {
+		return EnvelopeTransfer{}, pkg.ValidationErrorWithFields(pkg.ErrAmountMismatchingCurrencies, fmt.Sprintf("Amount %s is not in the currency of the budget %s", amount, currency), nil, map[string]string{
+			"amount": fmt.Sprintf("amount must be in %s", currency),
+		})
+	}
+
+	if auditInfo, err = makeAuditForCreation(createdBy); err != nil {
+		return EnvelopeTransfer{}, err
+	}
+
+	return newEnvelopeTransfer(id, budget.Id(), fromCategoryId, toCategoryId, period, amount, note, auditInfo)
+}
+
+func NewEnvelopeTransferFromRecord(record EnvelopeTransferRecord) (EnvelopeTransfer, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	if auditInfo, err = makeAuditForModification(
+		record.CreatedBy(),
+		record.CreatedAtUTC(),
+		record.ModifiedBy(),
+		record.ModifiedAtUTC(),
+		record.Version(),
+	); err != nil {
+		return EnvelopeTransfer{}, err
+	}
+
+	return newEnvelopeTransfer(
+		record.Id(),
+		record.BudgetId(),
+		record.FromCategoryId(),
+		record.ToCategoryId(),
+		record.Period(),
+		record.Amount(),
+		record.Note(),
+		auditInfo,
+	)
+}
+
+func newEnvelopeTransfer(
+	id EnvelopeTransferId,
+	budgetId BudgetId,
+	fromCategoryId CategoryId,
+	toCategoryId CategoryId,
+	period BudgetPeriod,
+	amount Money,
+	note string,
+	auditInfo auditInfo,
+) (EnvelopeTransfer, error) {
+	note = strings.TrimSpace(note)
+
+	errors := validate.Validate(
+		&validators.IntIsGreaterThan{Name: "Id", Field: int(id), Compared: 0, Message: "Id must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "BudgetId", Field: int(budgetId), Compared: 0, Message: "BudgetId must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "FromCategoryId", Field: int(fromCategoryId), Compared: 0, Message: "FromCategoryId must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "ToCategoryId", Field: int(toCategoryId), Compared: 0, Message: "ToCategoryId must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "Amount", Field: int(amount.MustMinorUnits()), Compared: 0, Message: "Amount must be greater than 0"},
+		&validators.StringLengthInRange{Name: "Note", Field: note, Min: 0, Max: 255, Message: "Note must be at most 255 characters long"},
+	)
+	if fromCategoryId == toCategoryId {
+		errors.Add("tocategoryid", "Envelopes can not be transferred to themselves")
+	}
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrBudgetValidation, "", errors); err != nil {
+		return EnvelopeTransfer{}, err
+	}
+
+	return EnvelopeTransfer{
+		auditInfo:      auditInfo,
+		id:             id,
+		budgetId:       budgetId,
+		fromCategoryId: fromCategoryId,
+		toCategoryId:   toCategoryId,
+		period:         period,
+		amount:         amount,
+		note:           note,
+	}, nil
+}
+
+func (t EnvelopeTransfer) Id() EnvelopeTransferId {
+	return t.id
+}
+
+func (t EnvelopeTransfer) BudgetId() BudgetId {
+	return t.budgetId
+}
+
+func (t EnvelopeTransfer) FromCategoryId() CategoryId {
+	return t.fromCategoryId
+}
+
+func (t EnvelopeTransfer) ToCategoryId() CategoryId {
+	return t.toCategoryId
+}
+
+func (t EnvelopeTransfer) Period() BudgetPeriod {
+	return t.period
+}
+
+func (t EnvelopeTransfer) Amount() Money {
+	return t.amount
+}
+
+func (t EnvelopeTransfer) Note() string {
+	return t.note
+}
+
+func (t EnvelopeTransfer) String() string {
+	return fmt.Sprintf("EnvelopeTransfer{id: %d, budget: %d, from: %d, to: %d, period: %s, amount: %s}",
+		t.id,
+		t.budgetId,
+		t.fromCategoryId,
+		t.toCategoryId,
+		t.period,
+		t.amount,
+	)
+}
+
+// Envelope is the balance of a category budget in a period.
+type Envelope struct {
+	categoryId CategoryId
+	rollover   RolloverMode
+	// The maximum amount of the category budget
+	allocated Money
+	// The balance carried over from the previous period
+	carriedOver Money
+	// The amount transferred from other envelopes, less the amount transferred to other envelopes
+	transferred Money
+	spent       Money
+}
+
+func (e Envelope) CategoryId() CategoryId {
+	return e.categoryId
+}
+
+func (e Envelope) Rollover() RolloverMode {
+	return e.rollover
+}
+
+func (e Envelope) Allocated() Money {
+	return e.allocated
+}
+
+func (e Envelope) CarriedOver() Money {
+	return e.carriedOver
+}
+
+func (e Envelope) Transferred() Money {
+	return e.transferred
+}
+
+func (e Envelope) Spent() Money {
+	return e.spent
+}
+
+// Available is the amount that can be spent in the period.
+func (e Envelope) Available() Money {
+	return e.sum(e.allocated, e.carriedOver, e.transferred)
+}
+
+// Balance is the amount left in the envelope at the end of the period. It is negative when more than the available amount was spent.
+func (e Envelope) Balance() Money {
+	return e.sum(e.Available(), MustMoney(e.spent.Negate()))
+}
+
+func (e Envelope) sum(amounts ...Money) Money {
+	total := int64(0)
+	for _, amount := range amounts {
+		total += amount.MustMinorUnits()
+	}
+	return MustMoney(NewMoney(e.allocated.Currency().CurrencyCode(), total))
+}
+
+// PeriodEnvelopes are the envelopes of the category budgets of a budget in a period.
+type PeriodEnvelopes struct {
+	period    BudgetPeriod
+	envelopes []Envelope
+}
+
+func (p PeriodEnvelopes) Period() BudgetPeriod {
+	return p.period
+}
+
+// Envelopes are in the order of the category budgets of the budget.
+func (p PeriodEnvelopes) Envelopes() []Envelope {
+	return p.envelopes
+}
+
+func (p PeriodEnvelopes) Envelope(categoryId CategoryId) (Envelope, bool) {
+	for _, envelope := range p.envelopes {
+		if envelope.categoryId == categoryId {
+			return envelope, true
+		}
+	}
+	return Envelope{}, false
+}
+
+// EnvelopePeriods returns the periods of the budget from the period in which it was created until the period that contains the date, oldest first.
+// Only the period of the date is returned if it is before the budget was created.
+func (b Budget) EnvelopePeriods(date time.Time) []BudgetPeriod {
+	last := b.periodCalculator.PeriodOf(date)
+	period := b.periodCalculator.PeriodOf(b.createdAtUTC)
+	if period.FirstDay().After(last.FirstDay()) {
+		return []BudgetPeriod{last}
+	}
+
+	periods := []BudgetPeriod{}
+	for ; !period.FirstDay().After(last.FirstDay()); period = period.Next() {
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+// NewEnvelopeHistory calculates the envelopes of the category budgets of a budget in consecutive periods, oldest first.
+// spentPerPeriod holds the expenses of each category in the period at the same index. Nothing is carried over into the first period.
+// The current maximum amounts and rollover modes of the category budgets are used for every period.
+// Transfers of other periods, or of categories that are no longer budgeted, are ignored.
+func NewEnvelopeHistory(
+	budget Budget,
+	periods []BudgetPeriod,
+	spentPerPeriod []map[CategoryId]Money,
+	transfers []EnvelopeTransfer,
+) ([]PeriodEnvelopes, error) {
+	if len(periods) != len(spentPerPeriod) {
+		return nil, fmt.Errorf("expected expenses for %d periods, got %d", len(periods), len(spentPerPeriod))
+	}
+
+	minorUnits := func(amount Money, currency string) (int64, error) {
+		if amount.Currency().CurrencyCode() != currency {
+			return 0, pkg.ValidationErrorWithFields(pkg.ErrAmountMismatchingCurrencies, fmt.Sprintf("Amount %s is not in the currency of the budget %s", amount, currency), nil, nil)
+		}
+		return amount.MustMinorUnits(), nil
+	}
+
+	carriedOver := map[CategoryId]int64{}
+	history := make([]PeriodEnvelopes, 0, len(periods))
+	for i, period := range periods {
+		transferred := map[CategoryId]int64{}
+		for _, transfer := range transfers {
+			if transfer.Period().PeriodType() != period.PeriodType() || !transfer.Period().FirstDay().Equal(period.FirstDay()) {
+				continue
+			}
+			transferred[transfer.FromCategoryId()] -= transfer.Amount().MustMinorUnits()
+			transferred[transfer.ToCategoryId()] += transfer.Amount().MustMinorUnits()
+		}
+
+		envelopes := make([]Envelope, 0, len(budget.CategoryBudgets()))
+		for _, categoryBudget := range budget.CategoryBudgets() {
+			var (
+				currency = categoryBudget.MaxLimit().Currency().CurrencyCode()
+				spent    int64
+				err      error
+			)
+			if amount, ok := spentPerPeriod[i][categoryBudget.CategoryId()]; ok {
+				if spent, err = minorUnits(amount, currency); err != nil {
+					return nil, err
+				}
+			}
+
+			envelope := Envelope{
+				categoryId:  categoryBudget.CategoryId(),
+				rollover:    categoryBudget.Rollover(),
+				allocated:   categoryBudget.MaxLimit(),
+				carriedOver: MustMoney(NewMoney(currency, carriedOver[categoryBudget.CategoryId()])),
+				transferred: MustMoney(NewMoney(currency, transferred[categoryBudget.CategoryId()])),
+				spent:       MustMoney(NewMoney(currency, spent)),
+			}
+			envelopes = append(envelopes, envelope)
+			carriedOver[categoryBudget.CategoryId()] = categoryBudget.CarryOver(envelope.Balance()).MustMinorUnits()
+		}
+		history = append(history, PeriodEnvelopes{period: period, envelopes: envelopes})
+	}
+	return history, nil
+}
@@ -0,0 +1,177 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type EnvelopeTestSuite struct {
+	suite.Suite
+	budget Budget
+	july   BudgetPeriod
+	august BudgetPeriod
+}
+
+func TestEnvelopeTestSuite(t *testing.T) {
+	suite.Run(t, new(EnvelopeTestSuite))
+}
+
+// -- SETUP
+
+func (suite *EnvelopeTestSuite) SetupTest() {
+	budget, err := NewBudget(
+		1,
+		AccountIds{1},
+		BudgetPeriodTypeMonth,
+		CategoryBudgets{
+			MustCategoryBudget(NewCategoryBudgetWithRollover(1, MustMoney(NewMoney("AED", 100_00)), DefaultAlertThresholds(), RolloverNone)),
+			MustCategoryBudget(NewCategoryBudgetWithRollover(2, MustMoney(NewMoney("AED", 100_00)), DefaultAlertThresholds(), RolloverSurplusOnly)),
+			MustCategoryBudget(NewCategoryBudgetWithRollover(3, MustMoney(NewMoney("AED", 100_00)), DefaultAlertThresholds(), RolloverSurplusAndDeficit)),
+		},
+		MustMakeUpdatedByUserId(1),
+	)
+	suite.Require().Nil(err)
+
+	suite.budget = budget
+	suite.july = BudgetPeriodTypeMonth.PeriodOf(time.Date(2021, time.July, 1, 0, 0, 0, 0, time.UTC))
+	suite.august = suite.july.Next()
+}
+
+func (suite *EnvelopeTestSuite) spent(amounts map[CategoryId]int64) map[CategoryId]Money {
+	spent := map[CategoryId]Money{}
+	for categoryId, amount := range amounts {
+		spent[categoryId] = MustMoney(NewMoney("AED", amount))
+	}
+	return spent
+}
+
+// -- SUITE
+
+func (suite *EnvelopeTestSuite) Test_GIVEN_aMonthlyPeriod_WHEN_nextPeriodIsCalculated_THEN_nextPeriodIsTheNextCalendarMonth() {
+	assert.Equal(suite.T(), time.Date(2021, time.August, 1, 0, 0, 0, 0, time.UTC), suite.august.FirstDay())
+	assert.Equal(suite.T(), time.Date(2021, time.August, 31, 0, 0, 0, 0, time.UTC), suite.august.LastDay())
+}
+
+func (suite *EnvelopeTestSuite) Test_GIVEN_anInvalidRolloverMode_WHEN_categoryBudgetIsCreated_THEN_validationErrorIsReturned() {
+	// WHEN
+	_, err := NewCategoryBudgetWithRollover(1, MustMoney(NewMoney("AED", 100_00)), DefaultAlertThresholds(), RolloverMode("Sometimes"))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrBudgetValidation, errorCode(err, 0))
+	assert.Contains(suite.T(), errorFields(err), "rollover")
+}
+
+func (suite *EnvelopeTestSuite) Test_GIVEN_surplusesInAPeriod_WHEN_envelopesAreCalculated_THEN_surplusIsCarriedOverAccordingToRolloverMode() {
+	// GIVEN
+	periods := []BudgetPeriod{suite.july, suite.august}
+	spentPerPeriod := []map[CategoryId]Money{
+		suite.spent(map[CategoryId]int64{1: 40_00, 2: 40_00, 3: 40_00}),
+		suite.spent(map[CategoryId]int64{}),
+	}
+
+	// WHEN
+	history, err := NewEnvelopeHistory(suite.budget, periods, spentPerPeriod, nil)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), history, 2)
+
+	none, _ := history[1].Envelope(1)
+	surplusOnly, _ := history[1].Envelope(2)
+	surplusAndDeficit, _ := history[1].Envelope(3)
+	assert.Equal(suite.T(), int64(0), none.CarriedOver().MustMinorUnits())
+	assert.Equal(suite.T(), int64(100_00), none.Balance().MustMinorUnits())
+	assert.Equal(suite.T(), int64(60_00), surplusOnly.CarriedOver().MustMinorUnits())
+	assert.Equal(suite.T(), int64(160_00), surplusOnly.Balance().MustMinorUnits())
+	assert.Equal(suite.T(), int64(60_00), surplusAndDeficit.CarriedOver().MustMinorUnits())
+	assert.Equal(suite.T(), int64(160_00), surplusAndDeficit.Balance().MustMinorUnits())
+}
+
+func (suite *EnvelopeTestSuite) Test_GIVEN_overspendingInAPeriod_WHEN_envelopesAreCalculated_THEN_deficitIsOnlyCarriedOverWhenRolloverIncludesDeficits() {
+	// GIVEN
+	periods := []BudgetPeriod{suite.july, suite.august}
+	spentPerPeriod := []map[CategoryId]Money{
+		suite.spent(map[CategoryId]int64{1: 130_00, 2: 130_00, 3: 130_00}),
+		suite.spent(map[CategoryId]int64{3: 50_00}),
+	}
+
+	// WHEN
+	history, err := NewEnvelopeHistory(suite.budget, periods, spentPerPeriod, nil)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+
+	july, _ := history[0].Envelope(3)
+	assert.Equal(suite.T(), int64(-30_00), july.Balance().MustMinorUnits())
+
+	none, _ := history[1].Envelope(1)
+	surplusOnly, _ := history[1].Envelope(2)
+	surplusAndDeficit, _ := history[1].Envelope(3)
+	assert.Equal(suite.T(), int64(0), none.CarriedOver().MustMinorUnits())
+	assert.Equal(suite.T(), int64(0), surplusOnly.CarriedOver().MustMinorUnits())
+	assert.Equal(suite.T(), int64(-30_00), surplusAndDeficit.CarriedOver().MustMinorUnits())
+	assert.Equal(suite.T(), int64(70_00), surplusAndDeficit.Available().MustMinorUnits())
+	assert.Equal(suite.T(), int64(20_00), surplusAndDeficit.Balance().MustMinorUnits())
+}
+
+func (suite *EnvelopeTestSuite) Test_GIVEN_aTransferBetweenEnvelopes_WHEN_envelopesAreCalculated_THEN_amountIsMovedInThePeriodOfTheTransfer() {
+	// GIVEN
+	transfer, err := NewEnvelopeTransfer(1, suite.budget, 1, 3, suite.august, MustMoney(NewMoney("AED", 25_00)), "Dinner party", MustMakeUpdatedByUserId(1))
+	assert.Nil(suite.T(), err)
+
+	periods := []BudgetPeriod{suite.july, suite.august}
+	spentPerPeriod := []map[CategoryId]Money{suite.spent(nil), suite.spent(nil)}
+
+	// WHEN
+	history, err := NewEnvelopeHistory(suite.budget, periods, spentPerPeriod, []EnvelopeTransfer{transfer})
+
+	// THEN
+	assert.Nil(suite.T(), err)
+
+	julyTarget, _ := history[0].Envelope(3)
+	assert.Equal(suite.T(), int64(0), julyTarget.Transferred().MustMinorUnits())
+
+	source, _ := history[1].Envelope(1)
+	target, _ := history[1].Envelope(3)
+	assert.Equal(suite.T(), int64(-25_00), source.Transferred().MustMinorUnits())
+	assert.Equal(suite.T(), int64(75_00), source.Balance().MustMinorUnits())
+	assert.Equal(suite.T(), int64(25_00), target.Transferred().MustMinorUnits())
+	assert.Equal(suite.T(), int64(225_00), target.Balance().MustMinorUnits())
+}
+
+func (suite *EnvelopeTestSuite) Test_GIVEN_aCategoryThatIsNotBudgeted_WHEN_envelopeTransferIsCreated_THEN_validationErrorIsReturned() {
+	// WHEN
+	_, err := NewEnvelopeTransfer(1, suite.budget, 1, 4, suite.july, MustMoney(NewMoney("AED", 25_00)), "", MustMakeUpdatedByUserId(1))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrBudgetValidation, errorCode(err, 0))
+	assert.Contains(suite.T(), errorFields(err), "toCategoryId")
+}
+
+func (suite *EnvelopeTestSuite) Test_GIVEN_theSameCategory_WHEN_envelopeTransferIsCreated_THEN_validationErrorIsReturned() {
+	// WHEN
+	_, err := NewEnvelopeTransfer(1, suite.budget, 1, 1, suite.july, MustMoney(NewMoney("AED", 25_00)), "", MustMakeUpdatedByUserId(1))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrBudgetValidation, errorCode(err, 0))
+}
+
+func (suite *EnvelopeTestSuite) Test_GIVEN_aBudgetCreatedInThePast_WHEN_envelopePeriodsAreRequested_THEN_periodsFromCreationUntilTheDateAreReturned() {
+	// GIVEN
+	suite.budget.createdAtUTC = time.Date(2021, time.June, 15, 10, 0, 0, 0, time.UTC)
+
+	// WHEN
+	periods := suite.budget.EnvelopePeriods(time.Date(2021, time.August, 3, 0, 0, 0, 0, time.UTC))
+
+	// THEN
+	assert.Len(suite.T(), periods, 3)
+	assert.Equal(suite.T(), time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC), periods[0].FirstDay())
+	assert.Equal(suite.T(), time.Date(2021, time.August, 1, 0, 0, 0, 0, time.UTC), periods[2].FirstDay())
+}
@@ -0,0 +1,101 @@
+package ledger
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bojanz/currency"
+	"github.com/gobuffalo/validate"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+// Number of decimal places kept when an exchange rate is calculated from two amounts
+const exchangeRateDigits = 10
+
+// ExchangeRate is the amount of the beneficiary's currency received for one unit of the source currency
+// when money is transferred between accounts with different currencies.
+type ExchangeRate string
+
+const NoExchangeRate = ExchangeRate("")
+
+func ParseExchangeRate(rate string) (ExchangeRate, error) {
+	rate = strings.TrimSpace(rate)
+	if !isPositiveDecimal(rate) {
+		return NoExchangeRate, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, fmt.Sprintf("Invalid exchange rate %q", rate), nil, map[string]string{
+			"exchangeRate": "exchangeRate must be a positive decimal number",
+		})
+	}
+	return ExchangeRate(rate), nil
+}
+
+// ExchangeRateBetween returns the rate at which sent was exchanged for received.
+func ExchangeRateBetween(sent Money, received Money) (ExchangeRate, error) {
+	var (
+		sentAmount     currency.Amount
+		receivedAmount currency.Amount
+		rate           currency.Amount
+		err            error
+	)
+
+	if sentAmount, err = moneyToAmount(sent); err != nil {
+		return NoExchangeRate, err
+	}
+	if receivedAmount, err = moneyToAmount(received); err != nil {
+		return NoExchangeRate, err
+	}
+	if sentAmount.IsZero() || receivedAmount.IsZero() {
+		return NoExchangeRate, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, "Can not calculate the exchange rate of a zero amount", nil, map[string]string{
+			"receivedAmount": "amounts sent and received must not be zero",
+		})
+	}
+	if rate, err = receivedAmount.Div(sentAmount.Number()); err != nil {
+		return NoExchangeRate, pkg.ValidationErrorWithError(pkg.ErrUnknown, "Failed to calculate exchange rate", err)
+	}
+
+	number := rate.RoundTo(exchangeRateDigits, currency.RoundHalfUp).Number()
+	if strings.Contains(number, ".") {
+		number = strings.TrimRight(strings.TrimRight(number, "0"), ".")
+	}
+	return ParseExchangeRate(strings.TrimPrefix(number, "-"))
+}
+
+func (r ExchangeRate) String() string {
+	return string(r)
+}
+
+func moneyToAmount(m Money) (currency.Amount, error) {
+	minorUnits, err := m.MinorUnits()
+	if err != nil {
+		return currency.Amount{}, pkg.ValidationErrorWithError(pkg.ErrAmountOverflow, "The number is too large to be represented", err)
+	}
+	return currency.NewAmountFromInt64(minorUnits, m.Currency().CurrencyCode())
+}
+
+func isPositiveDecimal(number string) bool {
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) || value <= 0 {
+		return false
+	}
+	_, err = currency.NewAmount(number, "USD")
+	return err == nil
+}
+
+type exchangeRateValidator struct {
+	Value      ExchangeRate
+	RecordType RecordType
+}
+
+func (v *exchangeRateValidator) IsValid(errors *validate.Errors) {
+	if len(v.Value) == 0 {
+		return
+	}
+	if v.RecordType != Transfer {
+		errors.Add("exchangeRate", fmt.Sprintf("exchangeRate must be empty when record type is %s", v.RecordType))
+		return
+	}
+	if !isPositiveDecimal(string(v.Value)) {
+		errors.Add("exchangeRate", "exchangeRate must be a positive decimal number")
+	}
+}
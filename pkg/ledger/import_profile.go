@@ -0,0 +1,364 @@
+package ledger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+// NoColumn is used for the optional columns of an import profile that are not in the file.
+const NoColumn = -1
+
+type DecimalSeparator string
+
+const (
+	DecimalSeparatorDot   DecimalSeparator = "."
+	DecimalSeparatorComma DecimalSeparator = ","
+)
+
+// DebitFormat describes how debits are distinguished from credits in an imported file.
+type DebitFormat string
+
+const (
+	// Debits are negative amounts in the amount column.
+	DebitsNegative DebitFormat = "NEGATIVE"
+	// Credits are in the amount column and debits are in the debit column.
+	DebitsInSeparateColumn DebitFormat = "SEPARATE_COLUMN"
+)
+
+// ImportColumns are the zero-based indices of the columns of an imported file.
+// The note, category and debit columns are optional; NoColumn is used when they are not in the file.
+type ImportColumns struct {
+	Date     int
+	Amount   int
+	Debit    int
+	Note     int
+	Category int
+}
+
+type ImportProfileId uint64
+
+// ImportProfile describes how the rows of a bank statement exported as a CSV are mapped to records.
+// The date format is written with the tokens yyyy, yy, MM, M, dd and d e.g. dd/MM/yyyy.
+// Rows without a category (or without a category column) are recorded in the default category, if one is set.
+type ImportProfile struct {
+	auditInfo
+	id                ImportProfileId
+	name              string
+	columns           ImportColumns
+	dateFormat        string
+	decimalSeparator  DecimalSeparator
+	debitFormat       DebitFormat
+	hasHeader         bool
+	defaultCategoryId CategoryId
+}
+
+type ImportProfileRecord interface {
+	Id() ImportProfileId
+	Name() string
+	Columns() ImportColumns
+	DateFormat() string
+	DecimalSeparator() DecimalSeparator
+	DebitFormat() DebitFormat
+	HasHeader() bool
+	DefaultCategoryId() CategoryId
+	CreatedBy() UpdatedBy
+	CreatedAtUTC() time.Time
+	ModifiedBy() UpdatedBy
+	ModifiedAtUTC() time.Time
+	Version() Version
+}
+
+const NoDefaultCategory = CategoryId(0)
+
+func NewImportProfile(
+	id ImportProfileId,
+	name string,
+	columns ImportColumns,
+	dateFormat string,
+	decimalSeparator DecimalSeparator,
+	debitFormat DebitFormat,
+	hasHeader bool,
+	defaultCategoryId CategoryId,
+	createdBy UpdatedBy,
+) (ImportProfile, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	if auditInfo, err = makeAuditForCreation(createdBy); err != nil {
+		return ImportProfile{}, err
+	}
+
+	return newImportProfile(id, name, columns, dateFormat, decimalSeparator, debitFormat, hasHeader, defaultCategoryId, auditInfo)
+}
+
+func NewImportProfileFromRecord(ir ImportProfileRecord) (ImportProfile, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	if auditInfo, err = makeAuditForModification(
+		ir.CreatedBy(),
+		ir.CreatedAtUTC(),
+		ir.ModifiedBy(),
+		ir.ModifiedAtUTC(),
+		ir.Version(),
+	); err != nil {
+		return ImportProfile{}, err
+	}
+
+	return newImportProfile(
+		ir.Id(),
+		ir.Name(),
+		ir.Columns(),
+		ir.DateFormat(),
+		ir.DecimalSeparator(),
+		ir.DebitFormat(),
+		ir.HasHeader(),
+		ir.DefaultCategoryId(),
+		auditInfo,
+	)
+}
+
+func newImportProfile(
+	id ImportProfileId,
+	name string,
+	columns ImportColumns,
+	dateFormat string,
+	decimalSeparator DecimalSeparator,
+	debitFormat DebitFormat,
+	hasHeader bool,
+	defaultCategoryId CategoryId,
+	auditInfo auditInfo,
+) (ImportProfile, error) {
+	errors := validate.Validate(
+		&validators.IntIsGreaterThan{Name: "Id", Field: int(id), Compared: 0, Message: "Id must be greater than 0"},
+		&validators.StringLengthInRange{Name: "Name", Field: name, Min: 1, Max: 50, Message: "Name must be 1 and 50 characters long"},
+		&importColumnsValidator{Value: columns, DebitFormat: debitFormat},
+		&dateFormatValidator{Value: dateFormat},
+		&validators.StringInclusion{Name: "DecimalSeparator", Field: string(decimalSeparator), List: []string{string(DecimalSeparatorDot), string(DecimalSeparatorComma)}, Message: "decimalSeparator must be '.' or ','"},
+		&validators.StringInclusion{Name: "DebitFormat", Field: string(debitFormat), List: []string{string(DebitsNegative), string(DebitsInSeparateColumn)}, Message: "debitFormat must be NEGATIVE or SEPARATE_COLUMN"},
+	)
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrImportProfileValidation, "", errors); err != nil {
+		return ImportProfile{}, err
+	}
+
+	return ImportProfile{
+		auditInfo:         auditInfo,
+		id:                id,
+		name:              name,
+		columns:           columns,
+		dateFormat:        dateFormat,
+		decimalSeparator:  decimalSeparator,
+		debitFormat:       debitFormat,
+		hasHeader:         hasHeader,
+		defaultCategoryId: defaultCategoryId,
+	}, nil
+}
+
+func (p ImportProfile) Id() ImportProfileId {
+	return p.id
+}
+
+func (p ImportProfile) Name() string {
+	return p.name
+}
+
+func (p ImportProfile) Columns() ImportColumns {
+	return p.columns
+}
+
+func (p ImportProfile) DateFormat() string {
+	return p.dateFormat
+}
+
+func (p ImportProfile) DecimalSeparator() DecimalSeparator {
+	return p.decimalSeparator
+}
+
+func (p ImportProfile) DebitFormat() DebitFormat {
+	return p.debitFormat
+}
+
+// HasHeader is true when the first row of the file is a header that is not imported.
+func (p ImportProfile) HasHeader() bool {
+	return p.hasHeader
+}
+
+func (p ImportProfile) DefaultCategoryId() CategoryId {
+	return p.defaultCategoryId
+}
+
+func (p ImportProfile) String() string {
+	return fmt.Sprintf("ImportProfile{id: %d, name: %s}", p.id, p.name)
+}
+
+// ImportedRow holds the values of a row of an imported file.
+// The amount is negative when the row is a debit.
+type ImportedRow struct {
+	date         time.Time
+	amount       Money
+	note         string
+	categoryName string
+}
+
+func (r ImportedRow) Date() time.Time {
+	return r.date
+}
+
+func (r ImportedRow) Amount() Money {
+	return r.amount
+}
+
+func (r ImportedRow) Note() string {
+	return r.note
+}
+
+// CategoryName is empty when the row has no category.
+func (r ImportedRow) CategoryName() string {
+	return r.categoryName
+}
+
+// RecordType is Income for credits and Expense for debits.
+func (r ImportedRow) RecordType() RecordType {
+	if r.amount.IsNegative() {
+		return Expense
+	}
+	return Income
+}
+
+// ParseRow reads the values of a row of an imported file, in the currency of the account the file is imported into.
+func (p ImportProfile) ParseRow(row []string, currencyCode string) (ImportedRow, error) {
+	var (
+		errors = validate.NewErrors()
+		parsed ImportedRow
+		err    error
+	)
+
+	value := func(column int) string {
+		if column == NoColumn || column >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[column])
+	}
+
+	if date := value(p.columns.Date); len(date) == 0 {
+		errors.Add("date", "date is required")
+	} else if parsed.date, err = time.Parse(p.goDateLayout(), date); err != nil {
+		errors.Add("date", fmt.Sprintf("date '%s' does not match format '%s'", date, p.dateFormat))
+	}
+
+	credit, debit := value(p.columns.Amount), value(p.columns.Debit)
+	switch {
+	case p.debitFormat == DebitsInSeparateColumn && len(credit) > 0 && len(debit) > 0:
+		errors.Add("amount", "a row can not have both a credit and a debit")
+	case len(credit) == 0 && len(debit) == 0:
+		errors.Add("amount", "amount is required")
+	case len(credit) > 0:
+		if parsed.amount, err = p.parseAmount(credit, currencyCode); err != nil {
+			errors.Add("amount", fmt.Sprintf("amount '%s' is not a number", credit))
+		}
+	default:
+		if parsed.amount, err = p.parseAmount(debit, currencyCode); err != nil {
+			errors.Add("amount", fmt.Sprintf("amount '%s' is not a number", debit))
+		} else if parsed.amount, err = negative(parsed.amount); err != nil {
+			errors.Add("amount", fmt.Sprintf("amount '%s' is not a number", debit))
+		}
+	}
+
+	parsed.note = value(p.columns.Note)
+	parsed.categoryName = value(p.columns.Category)
+
+	if err = pkg.ValidationErrorWithErrors(pkg.ErrImportValidation, "", errors); err != nil {
+		return ImportedRow{}, err
+	}
+	return parsed, nil
+}
+
+// parseAmount reads a decimal amount that may contain thousands separators e.g. 1,234.50 or 1.234,50
+func (p ImportProfile) parseAmount(value string, currencyCode string) (Money, error) {
+	thousandsSeparator := string(DecimalSeparatorComma)
+	if p.decimalSeparator == DecimalSeparatorComma {
+		thousandsSeparator = string(DecimalSeparatorDot)
+	}
+	value = strings.NewReplacer(thousandsSeparator, "", " ", "", "'", "").Replace(value)
+	value = strings.Replace(value, string(p.decimalSeparator), ".", 1)
+	return ParseMoney(currencyCode, value)
+}
+
+func (p ImportProfile) goDateLayout() string {
+	return dateFormatTokens.Replace(p.dateFormat)
+}
+
+func negative(amount Money) (Money, error) {
+	if amount.IsNegative() {
+		return amount, nil
+	}
+	return amount.Negate()
+}
+
+var dateFormatTokens = strings.NewReplacer(
+	"yyyy", "2006",
+	"yy", "06",
+	"MM", "01",
+	"M", "1",
+	"dd", "02",
+	"d", "2",
+)
+
+type ImportProfiles []ImportProfile
+
+type importColumnsValidator struct {
+	Value       ImportColumns
+	DebitFormat DebitFormat
+}
+
+func (v *importColumnsValidator) IsValid(errors *validate.Errors) {
+	if v.Value.Date < 0 {
+		errors.Add("dateColumn", "dateColumn must be 0 or greater")
+	}
+	if v.Value.Amount < 0 {
+		errors.Add("amountColumn", "amountColumn must be 0 or greater")
+	}
+	if v.Value.Note < NoColumn {
+		errors.Add("noteColumn", "noteColumn must be 0 or greater")
+	}
+	if v.Value.Category < NoColumn {
+		errors.Add("categoryColumn", "categoryColumn must be 0 or greater")
+	}
+	if v.DebitFormat == DebitsInSeparateColumn && v.Value.Debit < 0 {
+		errors.Add("debitColumn", fmt.Sprintf("debitColumn is required when debitFormat is %s", DebitsInSeparateColumn))
+	}
+	if v.DebitFormat != DebitsInSeparateColumn && v.Value.Debit != NoColumn {
+		errors.Add("debitColumn", fmt.Sprintf("debitColumn must be empty when debitFormat is %s", v.DebitFormat))
+	}
+
+	seen := map[int]bool{}
+	for _, column := range []int{v.Value.Date, v.Value.Amount, v.Value.Debit, v.Value.Note, v.Value.Category} {
+		if column == NoColumn {
+			continue
+		}
+		if seen[column] {
+			errors.Add("columns", fmt.Sprintf("column %d is mapped more than once", column))
+		}
+		seen[column] = true
+	}
+}
+
+type dateFormatValidator struct {
+	Value string
+}
+
+func (v *dateFormatValidator) IsValid(errors *validate.Errors) {
+	if !strings.Contains(v.Value, "yy") || !strings.Contains(v.Value, "M") || !strings.Contains(v.Value, "d") {
+		errors.Add("dateFormat", "dateFormat must contain a year (yyyy or yy), a month (MM or M) and a day (dd or d)")
+	}
+}
@@ -0,0 +1,146 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type ImportProfileTestSuite struct {
+	suite.Suite
+}
+
+func TestImportProfileTestSuite(t *testing.T) {
+	suite.Run(t, new(ImportProfileTestSuite))
+}
+
+func importProfile(columns ImportColumns, dateFormat string, decimalSeparator DecimalSeparator, debitFormat DebitFormat) (ImportProfile, error) {
+	return NewImportProfile(
+		ImportProfileId(1),
+		"Bank",
+		columns,
+		dateFormat,
+		decimalSeparator,
+		debitFormat,
+		true,
+		NoDefaultCategory,
+		MustMakeUpdatedByUserId(UserId(1)),
+	)
+}
+
+// -- SUITE
+
+func (suite *ImportProfileTestSuite) Test_GIVEN_aProfileWithNegativeDebits_WHEN_rowsAreParsed_THEN_debitsAreExpensesAndCreditsAreIncome() {
+	// GIVEN
+	profile, err := importProfile(
+		ImportColumns{Date: 0, Note: 1, Amount: 2, Category: 3, Debit: NoColumn},
+		"dd/MM/yyyy",
+		DecimalSeparatorDot,
+		DebitsNegative,
+	)
+	assert.Nil(suite.T(), err)
+
+	// WHEN
+	debit, debitErr := profile.ParseRow([]string{"03/07/2021", "Groceries", "-1,234.50", "Food"}, "AED")
+	credit, creditErr := profile.ParseRow([]string{"25/07/2021", " Salary ", "20000", ""}, "AED")
+
+	// THEN
+	assert.Nil(suite.T(), debitErr)
+	assert.Equal(suite.T(), time.Date(2021, time.July, 3, 0, 0, 0, 0, time.UTC), debit.Date())
+	assert.Equal(suite.T(), int64(-123450), debit.Amount().MustMinorUnits())
+	assert.Equal(suite.T(), "Groceries", debit.Note())
+	assert.Equal(suite.T(), "Food", debit.CategoryName())
+	assert.Equal(suite.T(), Expense, debit.RecordType())
+
+	assert.Nil(suite.T(), creditErr)
+	assert.Equal(suite.T(), int64(2000000), credit.Amount().MustMinorUnits())
+	assert.Equal(suite.T(), "Salary", credit.Note())
+	assert.Equal(suite.T(), "", credit.CategoryName())
+	assert.Equal(suite.T(), Income, credit.RecordType())
+}
+
+func (suite *ImportProfileTestSuite) Test_GIVEN_aProfileWithDebitsInSeparateColumn_WHEN_rowsAreParsed_THEN_amountsInTheDebitColumnAreNegative() {
+	// GIVEN
+	profile, err := importProfile(
+		ImportColumns{Date: 0, Note: NoColumn, Amount: 1, Debit: 2, Category: NoColumn},
+		"yyyy-M-d",
+		DecimalSeparatorComma,
+		DebitsInSeparateColumn,
+	)
+	assert.Nil(suite.T(), err)
+
+	// WHEN
+	debit, debitErr := profile.ParseRow([]string{"2021-7-3", "", "1.234,50"}, "EUR")
+	credit, creditErr := profile.ParseRow([]string{"2021-7-4", "10,5", ""}, "EUR")
+
+	// THEN
+	assert.Nil(suite.T(), debitErr)
+	assert.Equal(suite.T(), int64(-123450), debit.Amount().MustMinorUnits())
+	assert.Equal(suite.T(), time.Date(2021, time.July, 3, 0, 0, 0, 0, time.UTC), debit.Date())
+
+	assert.Nil(suite.T(), creditErr)
+	assert.Equal(suite.T(), int64(1050), credit.Amount().MustMinorUnits())
+}
+
+func (suite *ImportProfileTestSuite) Test_GIVEN_invalidRows_WHEN_rowsAreParsed_THEN_invalidFieldsAreReported() {
+	// GIVEN
+	profile, _ := importProfile(
+		ImportColumns{Date: 0, Note: NoColumn, Amount: 1, Debit: 2, Category: NoColumn},
+		"dd/MM/yyyy",
+		DecimalSeparatorDot,
+		DebitsInSeparateColumn,
+	)
+
+	testCases := []struct {
+		name     string
+		row      []string
+		expected map[string]string
+	}{
+		{
+			name:     "bad date and amount",
+			row:      []string{"2021-07-03", "abc", ""},
+			expected: map[string]string{"date": "date '2021-07-03' does not match format 'dd/MM/yyyy'", "amount": "amount 'abc' is not a number"},
+		},
+		{
+			name:     "credit and debit",
+			row:      []string{"03/07/2021", "10", "20"},
+			expected: map[string]string{"amount": "a row can not have both a credit and a debit"},
+		},
+		{
+			name:     "missing columns",
+			row:      []string{""},
+			expected: map[string]string{"date": "date is required", "amount": "amount is required"},
+		},
+	}
+
+	for _, tc := range testCases {
+		// WHEN
+		_, err := profile.ParseRow(tc.row, "AED")
+
+		// THEN
+		assert.Equal(suite.T(), pkg.ErrImportValidation, errorCode(err, 0), tc.name)
+		assert.Equal(suite.T(), tc.expected, errorFields(err), tc.name)
+	}
+}
+
+func (suite *ImportProfileTestSuite) Test_GIVEN_invalidMapping_WHEN_profileIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := importProfile(
+		ImportColumns{Date: 0, Note: 0, Amount: 1, Debit: NoColumn, Category: NoColumn},
+		"dd/yyyy",
+		";",
+		DebitsInSeparateColumn,
+	)
+
+	// THEN
+	assert.Equal(suite.T(), pkg.ErrImportProfileValidation, errorCode(err, 0))
+	assert.Equal(suite.T(), map[string]string{
+		"columns":           "column 0 is mapped more than once",
+		"dateFormat":        "dateFormat must contain a year (yyyy or yy), a month (MM or M) and a day (dd or d)",
+		"debitColumn":       "debitColumn is required when debitFormat is SEPARATE_COLUMN",
+		"decimal_separator": "decimalSeparator must be '.' or ','",
+	}, errorFields(err))
+}
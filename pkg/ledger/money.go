@@ -30,6 +30,7 @@ type Money interface {
 	Add(m Money) (Money, error)
 	Abs() (Money, error)
 	Negate() (Money, error)
+	Convert(currencyCode string, rate ExchangeRate) (Money, error)
 
 	MinorUnits() (int64, error)
 	MustMinorUnits() int64
@@ -122,6 +123,18 @@ func (i internalMoney) Negate() (Money, error) {
 	return i, nil
 }
 
+// Convert returns the amount in the given currency at the given exchange rate, rounded to the minor unit of that currency.
+func (i internalMoney) Convert(currencyCode string, rate ExchangeRate) (Money, error) {
+	converted, err := i.amount.Convert(currencyCode, string(rate))
+	if err != nil {
+		if _, ok := err.(currency.InvalidCurrencyCodeError); ok {
+			return nil, pkg.ValidationErrorWithFields(pkg.ErrCurrencyInvalidCode, err.Error(), err, map[string]string{"code": currencyCode})
+		}
+		return nil, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, err.Error(), err, map[string]string{"exchangeRate": string(rate)})
+	}
+	return &internalMoney{converted.Round()}, nil
+}
+
 func (i internalMoney) MinorUnits() (int64, error) {
 	return i.amount.Int64()
 }
@@ -149,6 +162,22 @@ func NewMoney(currencyCode string, amountMinorUnits int64) (Money, error) {
 	return &internalMoney{amount}, nil
 }
 
+// ParseMoney parses a decimal amount e.g. "-12.50" in the given currency.
+// The amount is rounded to the minor unit of the currency.
+func ParseMoney(currencyCode string, number string) (Money, error) {
+	amount, err := currency.NewAmount(number, currencyCode)
+	if err != nil {
+		if _, ok := err.(currency.InvalidCurrencyCodeError); ok {
+			return nil, pkg.ValidationErrorWithFields(pkg.ErrCurrencyInvalidCode, err.Error(), err, map[string]string{"code": currencyCode})
+		}
+		return nil, pkg.ValidationErrorWithFields(pkg.ErrUnknown, "Invalid Monetary amount", err, map[string]string{"code": currencyCode, "amount": number})
+	}
+	if _, err = amount.Int64(); err != nil {
+		return nil, pkg.ValidationErrorWithError(pkg.ErrAmountOverflow, "The number is too large to be represented", err)
+	}
+	return &internalMoney{amount.Round()}, nil
+}
+
 func MustMoney(m Money, err error) Money {
 	if err != nil {
 		log.Fatal(err)
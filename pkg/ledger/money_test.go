@@ -177,3 +177,65 @@ func (suite *MoneyTestSuite) Test_GIVEN_amountsOfDifferentCurrency_WHEN_adding_T
 	assert.Equal(suite.T(), pkg.ErrAmountMismatchingCurrencies, errorCode(err, 0))
 	assert.Equal(suite.T(), "Can not sum mismatching currencies", err.Error())
 }
+
+func (suite *MoneyTestSuite) Test_GIVEN_anExchangeRate_WHEN_moneyIsConverted_THEN_amountIsRoundedToMinorUnitsOfNewCurrency() {
+	// GIVEN
+	money, _ := NewMoney("USD", 12345)
+
+	// WHEN
+	converted, err := money.Convert("KWD", ExchangeRate("0.30712"))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "KWD", converted.Currency().CurrencyCode())
+	assert.Equal(suite.T(), int64(37914), converted.MustMinorUnits())
+}
+
+func (suite *MoneyTestSuite) Test_GIVEN_amountsSentAndReceived_WHEN_exchangeRateIsCalculated_THEN_rateIsReceivedOverSent() {
+	// GIVEN
+	sent, _ := NewMoney("USD", 300)
+	received, _ := NewMoney("EUR", 1000)
+
+	// WHEN
+	rate, err := ExchangeRateBetween(sent, received)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), ExchangeRate("3.3333333333"), rate)
+}
+
+func (suite *MoneyTestSuite) Test_GIVEN_invalidExchangeRate_WHEN_parsed_THEN_errorIsReturned() {
+	for _, rate := range []string{"", "abc", "0", "-1.5", "NaN"} {
+		// WHEN
+		_, err := ParseExchangeRate(rate)
+
+		// THEN
+		assert.NotNil(suite.T(), err, rate)
+		assert.Equal(suite.T(), "exchangeRate must be a positive decimal number", errorFields(err)["exchangeRate"])
+	}
+}
+
+func (suite *MoneyTestSuite) Test_GIVEN_decimalAmounts_WHEN_parsed_THEN_amountsAreRoundedToMinorUnits() {
+	testCases := []struct {
+		currency string
+		number   string
+		expected int64
+	}{
+		{"AED", "-1234.50", -123450},
+		{"USD", "0.005", 1},
+		{"KWD", "1.2345", 1235},
+		{"JPY", "100", 100},
+	}
+
+	for _, tc := range testCases {
+		// WHEN
+		amount, err := ParseMoney(tc.currency, tc.number)
+
+		// THEN
+		assert.Nil(suite.T(), err, tc.number)
+		assert.Equal(suite.T(), tc.expected, amount.MustMinorUnits(), tc.number)
+	}
+
+	_, err := ParseMoney("AED", "12,50")
+	assert.NotNil(suite.T(), err)
+}
@@ -0,0 +1,266 @@
+package ledger
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type MonthlyPlanId uint64
+
+// MonthlyPlan assigns the income expected in a calendar month to categories (zero-based budgeting).
+// The plan is complete when every unit of the expected income is assigned to a category, so that nothing is left to be assigned.
+type MonthlyPlan struct {
+	auditInfo
+	id    MonthlyPlanId
+	month CalendarMonth
+	// The accounts whose income and expenses are compared to the plan
+	accountIds     AccountIds
+	expectedIncome Money
+	// The amount assigned to each category is the maximum amount of its category budget
+	assignments CategoryBudgets
+}
+
+type MonthlyPlanRecord interface {
+	Id() MonthlyPlanId
+	Month() CalendarMonth
+	AccountIds() AccountIds
+	ExpectedIncome() Money
+	Assignments() CategoryBudgets
+	CreatedBy() UpdatedBy
+	CreatedAtUTC() time.Time
+	ModifiedBy() UpdatedBy
+	ModifiedAtUTC() time.Time
+	Version() Version
+}
+
+func NewMonthlyPlan(
+	id MonthlyPlanId,
+	month CalendarMonth,
+	accountIds AccountIds,
+	expectedIncome Money,
+	assignments CategoryBudgets,
+	createdBy UpdatedBy,
+) (MonthlyPlan, error) {
+	auditInfo, err := makeAuditForCreation(createdBy)
+	if err != nil {
+		return MonthlyPlan{}, err
+	}
+
+	return newMonthlyPlan(id, month, accountIds, expectedIncome, assignments, auditInfo)
+}
+
+func NewMonthlyPlanFromRecord(record MonthlyPlanRecord) (MonthlyPlan, error) {
+	auditInfo, err := makeAuditForModification(
+		record.CreatedBy(),
+		record.CreatedAtUTC(),
+		record.ModifiedBy(),
+		record.ModifiedAtUTC(),
+		record.Version(),
+	)
+	if err != nil {
+		return MonthlyPlan{}, err
+	}
+
+	return newMonthlyPlan(
+		record.Id(),
+		record.Month(),
+		record.AccountIds(),
+		record.ExpectedIncome(),
+		record.Assignments(),
+		auditInfo,
+	)
+}
+
+// Edit returns a copy of the plan with the given details, validated in the same way as a new plan.
+// The month of a plan can not be changed.
+func (p MonthlyPlan) Edit(
+	accountIds AccountIds,
+	expectedIncome Money,
+	assignments CategoryBudgets,
+	updatedBy UpdatedBy,
+) (MonthlyPlan, error) {
+	auditInfo, err := makeAuditForUpdate(p.auditInfo, updatedBy)
+	if err != nil {
+		return MonthlyPlan{}, err
+	}
+
+	return newMonthlyPlan(p.id, p.month, accountIds, expectedIncome, assignments, auditInfo)
+}
+
+func newMonthlyPlan(
+	id MonthlyPlanId,
+	month CalendarMonth,
+	accountIds AccountIds,
+	expectedIncome Money,
+	assignments CategoryBudgets,
+	auditInfo auditInfo,
+) (MonthlyPlan, error) {
+
+	errors := validate.Validate(
+		&validators.IntIsGreaterThan{Name: "Id", Field: int(id), Compared: 0, Message: "Id must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "Year", Field: int(month.Year()), Compared: 0, Message: "Year must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "accountIds", Field: len(accountIds), Compared: 0, Message: "AccountIds must not be empty"},
+		&amountPositiveOrZeroValidator{Name: "expectedIncome", Field: expectedIncome, Message: "ExpectedIncome must be greater than or equal to 0"},
+		&categoryBudgetsHaveSameCurrency{Name: "assignments", Field: assignments},
+		&categoryBudgetsHaveDistinctCategories{Name: "assignments", Field: assignments},
+	)
+	if month.Month() < time.January || month.Month() > time.December {
+		errors.Add("month", "Month must be between 1 and 12")
+	}
+	if len(assignments) != 0 && assignments[0].MaxLimit().Currency().CurrencyCode() != expectedIncome.Currency().CurrencyCode() {
+		errors.Add("assignments", "assignments must be in the currency of the expected income")
+	}
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrMonthlyPlanValidation, "", errors); err != nil {
+		return MonthlyPlan{}, err
+	}
+
+	return MonthlyPlan{
+		auditInfo:      auditInfo,
+		id:             id,
+		month:          month,
+		accountIds:     accountIds,
+		expectedIncome: expectedIncome,
+		assignments:    assignments,
+	}, nil
+}
+
+func (p MonthlyPlan) Id() MonthlyPlanId {
+	return p.id
+}
+
+func (p MonthlyPlan) Month() CalendarMonth {
+	return p.month
+}
+
+func (p MonthlyPlan) AccountIds() AccountIds {
+	return p.accountIds
+}
+
+func (p MonthlyPlan) ExpectedIncome() Money {
+	return p.expectedIncome
+}
+
+func (p MonthlyPlan) Assignments() CategoryBudgets {
+	return p.assignments
+}
+
+// Assigned is the total amount assigned to categories.
+func (p MonthlyPlan) Assigned() Money {
+	total := int64(0)
+	for _, assignment := range p.assignments {
+		total += assignment.MaxLimit().MustMinorUnits()
+	}
+	return MustMoney(NewMoney(p.expectedIncome.Currency().CurrencyCode(), total))
+}
+
+// ToBeAssigned is the part of the expected income that is not assigned to a category yet.
+// It is negative when more than the expected income is assigned.
+func (p MonthlyPlan) ToBeAssigned() Money {
+	return MustMoney(NewMoney(p.expectedIncome.Currency().CurrencyCode(), p.expectedIncome.MustMinorUnits()-p.Assigned().MustMinorUnits()))
+}
+
+// OverAssigned is the amount assigned to categories in excess of the expected income, or zero.
+func (p MonthlyPlan) OverAssigned() Money {
+	toBeAssigned := p.ToBeAssigned().MustMinorUnits()
+	if toBeAssigned > 0 {
+		toBeAssigned = 0
+	}
+	return MustMoney(NewMoney(p.expectedIncome.Currency().CurrencyCode(), -toBeAssigned))
+}
+
+func (p MonthlyPlan) CreatedBy() UpdatedBy {
+	return p.createdBy
+}
+
+func (p MonthlyPlan) CreatedAtUTC() time.Time {
+	return p.createdAtUTC
+}
+
+func (p MonthlyPlan) ModifiedBy() UpdatedBy {
+	return p.modifiedBy
+}
+
+func (p MonthlyPlan) ModifiedAtUTC() time.Time {
+	return p.modifiedAtUTC
+}
+
+func (p MonthlyPlan) Version() Version {
+	return p.version
+}
+
+func (p MonthlyPlan) String() string {
+	return fmt.Sprintf("MonthlyPlan{id: %d, month: %s, accountIds: %v, expectedIncome: %s, assignments: %s}",
+		p.id,
+		p.month,
+		p.accountIds,
+		p.expectedIncome,
+		p.assignments,
+	)
+}
+
+// MonthlyPlanSummary compares the income and expenses of the month of a plan to the plan.
+type MonthlyPlanSummary struct {
+	plan         MonthlyPlan
+	actualIncome Money
+	categories   []CategoryBudgetProgress
+	total        Progress
+}
+
+// NewMonthlyPlanSummary compares the amount spent on each category in the month of a plan to the amount assigned to it.
+// Categories that were spent on without being assigned anything follow the assigned categories, with nothing planned.
+func NewMonthlyPlanSummary(plan MonthlyPlan, actualIncome Money, spentPerCategory map[CategoryId]Money) (MonthlyPlanSummary, error) {
+	currencyCode := plan.ExpectedIncome().Currency().CurrencyCode()
+	if actualIncome.Currency().CurrencyCode() != currencyCode {
+		return MonthlyPlanSummary{}, pkg.ValidationErrorWithFields(pkg.ErrAmountMismatchingCurrencies, fmt.Sprintf("Income %s is not in the currency of the plan %s", actualIncome, currencyCode), nil, nil)
+	}
+
+	assigned := map[CategoryId]bool{}
+	for _, assignment := range plan.Assignments() {
+		assigned[assignment.CategoryId()] = true
+	}
+
+	unassigned := CategoryBudgets{}
+	for categoryId := range spentPerCategory {
+		if !assigned[categoryId] {
+			unassigned = append(unassigned, CategoryBudget{categoryId: categoryId, maxLimit: MustMoney(NewMoney(currencyCode, 0))})
+		}
+	}
+	sort.Sort(unassigned)
+
+	categoryBudgets := append(append(CategoryBudgets{}, plan.Assignments()...), unassigned...)
+	categories, total, err := newCategoriesProgress(categoryBudgets, spentPerCategory, currencyCode)
+	if err != nil {
+		return MonthlyPlanSummary{}, err
+	}
+
+	return MonthlyPlanSummary{
+		plan:         plan,
+		actualIncome: actualIncome,
+		categories:   categories,
+		total:        total,
+	}, nil
+}
+
+func (s MonthlyPlanSummary) Plan() MonthlyPlan {
+	return s.plan
+}
+
+func (s MonthlyPlanSummary) ActualIncome() Money {
+	return s.actualIncome
+}
+
+// Categories compares the amount spent on each category to the amount assigned to it.
+func (s MonthlyPlanSummary) Categories() []CategoryBudgetProgress {
+	return s.categories
+}
+
+// Total compares the amount spent on all categories to the total amount assigned.
+func (s MonthlyPlanSummary) Total() Progress {
+	return s.total
+}
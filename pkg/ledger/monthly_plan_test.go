@@ -0,0 +1,130 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type MonthlyPlanTestSuite struct {
+	suite.Suite
+}
+
+func TestMonthlyPlanTestSuite(t *testing.T) {
+	suite.Run(t, new(MonthlyPlanTestSuite))
+}
+
+func (suite *MonthlyPlanTestSuite) assignment(categoryId CategoryId, amount int64) CategoryBudget {
+	return MustCategoryBudget(NewCategoryBudget(categoryId, MustMoney(NewMoney("AED", amount))))
+}
+
+// -- SUITE
+
+func (suite *MonthlyPlanTestSuite) Test_GIVEN_partOfTheIncomeIsAssigned_WHEN_planIsCreated_THEN_theRestIsToBeAssigned() {
+	// WHEN
+	plan, err := NewMonthlyPlan(
+		1,
+		MakeCalendarMonth(2021, time.July),
+		AccountIds{1},
+		MustMoney(NewMoney("AED", 10000_00)),
+		CategoryBudgets{suite.assignment(1, 6000_00), suite.assignment(2, 2500_00)},
+		MustMakeUpdatedByUserId(1),
+	)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(8500_00), plan.Assigned().MustMinorUnits())
+	assert.Equal(suite.T(), int64(1500_00), plan.ToBeAssigned().MustMinorUnits())
+	assert.True(suite.T(), plan.OverAssigned().IsZero())
+}
+
+func (suite *MonthlyPlanTestSuite) Test_GIVEN_moreThanTheIncomeIsAssigned_WHEN_planIsCreated_THEN_planIsOverAssigned() {
+	// WHEN
+	plan, err := NewMonthlyPlan(
+		1,
+		MakeCalendarMonth(2021, time.July),
+		AccountIds{1},
+		MustMoney(NewMoney("AED", 10000_00)),
+		CategoryBudgets{suite.assignment(1, 8000_00), suite.assignment(2, 2500_00)},
+		MustMakeUpdatedByUserId(1),
+	)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(-500_00), plan.ToBeAssigned().MustMinorUnits())
+	assert.Equal(suite.T(), int64(500_00), plan.OverAssigned().MustMinorUnits())
+}
+
+func (suite *MonthlyPlanTestSuite) Test_GIVEN_assignmentsInAnotherCurrency_WHEN_planIsCreated_THEN_validationErrorIsReturned() {
+	// WHEN
+	_, err := NewMonthlyPlan(
+		1,
+		MakeCalendarMonth(2021, time.July),
+		AccountIds{1},
+		MustMoney(NewMoney("USD", 10000_00)),
+		CategoryBudgets{suite.assignment(1, 8000_00)},
+		MustMakeUpdatedByUserId(1),
+	)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrMonthlyPlanValidation, errorCode(err, 0))
+	assert.Contains(suite.T(), errorFields(err), "assignments")
+}
+
+func (suite *MonthlyPlanTestSuite) Test_GIVEN_noAccounts_WHEN_planIsCreated_THEN_validationErrorIsReturned() {
+	// WHEN
+	_, err := NewMonthlyPlan(
+		1,
+		MakeCalendarMonth(2021, time.July),
+		AccountIds{},
+		MustMoney(NewMoney("AED", 10000_00)),
+		CategoryBudgets{},
+		MustMakeUpdatedByUserId(1),
+	)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrMonthlyPlanValidation, errorCode(err, 0))
+	assert.Contains(suite.T(), errorFields(err), "account_ids")
+}
+
+func (suite *MonthlyPlanTestSuite) Test_GIVEN_expensesOfPlannedAndUnplannedCategories_WHEN_summaryIsCalculated_THEN_actualsAreComparedToThePlan() {
+	// GIVEN
+	plan, _ := NewMonthlyPlan(
+		1,
+		MakeCalendarMonth(2021, time.July),
+		AccountIds{1},
+		MustMoney(NewMoney("AED", 10000_00)),
+		CategoryBudgets{suite.assignment(2, 6000_00), suite.assignment(1, 2000_00)},
+		MustMakeUpdatedByUserId(1),
+	)
+	spent := map[CategoryId]Money{
+		1: MustMoney(NewMoney("AED", 2500_00)),
+		3: MustMoney(NewMoney("AED", 100_00)),
+	}
+
+	// WHEN
+	summary, err := NewMonthlyPlanSummary(plan, MustMoney(NewMoney("AED", 9000_00)), spent)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(9000_00), summary.ActualIncome().MustMinorUnits())
+	assert.Len(suite.T(), summary.Categories(), 3)
+
+	assert.Equal(suite.T(), CategoryId(2), summary.Categories()[0].CategoryId())
+	assert.Equal(suite.T(), int64(6000_00), summary.Categories()[0].Remaining().MustMinorUnits())
+
+	assert.Equal(suite.T(), CategoryId(1), summary.Categories()[1].CategoryId())
+	assert.Equal(suite.T(), int64(-500_00), summary.Categories()[1].Remaining().MustMinorUnits())
+	assert.Equal(suite.T(), 125.0, summary.Categories()[1].PercentUsed())
+
+	assert.Equal(suite.T(), CategoryId(3), summary.Categories()[2].CategoryId())
+	assert.True(suite.T(), summary.Categories()[2].MaxLimit().IsZero())
+
+	assert.Equal(suite.T(), int64(8000_00), summary.Total().MaxLimit().MustMinorUnits())
+	assert.Equal(suite.T(), int64(2600_00), summary.Total().Spent().MustMinorUnits())
+}
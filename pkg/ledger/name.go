@@ -0,0 +1,46 @@
+package ledger
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NormalizeName is the title case in which the names of accounts and categories are saved e.g. "FOOD & drink" is saved as "Food & Drink".
+// Names that are searched for must be normalized the same way.
+// The first letter after each separator is upper-cased, exactly as strings.Title did before it was deprecated, so that saved names keep matching
+// e.g. "McDONALD'S" is saved as "Mcdonald'S" and "3G data" as "3g Data".
+func NormalizeName(name string) string {
+	previous := ' '
+	return strings.Map(func(r rune) rune {
+		if isNameSeparator(previous) {
+			previous = r
+			return unicode.ToTitle(r)
+		}
+		previous = r
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// isNameSeparator is true when the rune separates the words of a name, as in strings.Title.
+func isNameSeparator(r rune) bool {
+	// ASCII alphanumerics and underscore are not separators
+	if r <= 0x7F {
+		switch {
+		case '0' <= r && r <= '9':
+			return false
+		case 'a' <= r && r <= 'z':
+			return false
+		case 'A' <= r && r <= 'Z':
+			return false
+		case r == '_':
+			return false
+		}
+		return true
+	}
+	// Letters and digits are not separators
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return false
+	}
+	// Otherwise, all we can do for now is treat spaces as separators.
+	return unicode.IsSpace(r)
+}
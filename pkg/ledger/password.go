@@ -0,0 +1,80 @@
+package ledger
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 128
+
+	// passwordHashIterations is the number of PBKDF2-HMAC-SHA256 iterations recommended by OWASP.
+	// The iterations are stored in the hash, so that it can be raised without invalidating existing passwords.
+	passwordHashIterations = 600_000
+	passwordSaltLength     = 16
+	passwordKeyLength      = 32
+	passwordHashAlgorithm  = "pbkdf2-sha256"
+)
+
+// PasswordHash is a salted, slow hash of a password formatted as pbkdf2-sha256$<iterations>$<salt>$<key>.
+// The password itself is never kept.
+type PasswordHash string
+
+// HashPassword validates the length of a password and hashes it with a random salt.
+func HashPassword(password string) (PasswordHash, error) {
+	if length := utf8.RuneCountInString(password); length < minPasswordLength || length > maxPasswordLength {
+		return "", pkg.ValidationErrorWithFields(pkg.ErrUserPasswordValidation, "Invalid password", nil, map[string]string{
+			"password": fmt.Sprintf("password must be between %d and %d characters long", minPasswordLength, maxPasswordLength),
+		})
+	}
+
+	salt := make([]byte, passwordSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", pkg.NewSystemError(pkg.ErrUnknown, "Failed to generate password salt", err)
+	}
+
+	key := pbkdf2.Key([]byte(password), salt, passwordHashIterations, passwordKeyLength, sha256.New)
+	return PasswordHash(fmt.Sprintf(
+		"%s$%d$%s$%s",
+		passwordHashAlgorithm,
+		passwordHashIterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+// Matches is true when the password hashes to the same key with the salt and iterations of this hash.
+// A malformed hash matches no password.
+func (h PasswordHash) Matches(password string) bool {
+	parts := strings.Split(string(h), "$")
+	if len(parts) != 4 || parts[0] != passwordHashAlgorithm {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, pbkdf2.Key([]byte(password), salt, iterations, len(key), sha256.New)) == 1
+}
+
+func (h PasswordHash) String() string {
+	return "PasswordHash{<redacted>}"
+}
@@ -0,0 +1,75 @@
+package ledger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type PasswordTestSuite struct {
+	suite.Suite
+}
+
+func TestPasswordTestSuite(t *testing.T) {
+	suite.Run(t, new(PasswordTestSuite))
+}
+
+// -- SUITE
+
+func (suite *PasswordTestSuite) Test_GIVEN_aPassword_WHEN_passwordIsHashed_THEN_onlyThatPasswordMatches() {
+	// WHEN
+	hash, err := HashPassword("all work and no play")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), strings.HasPrefix(string(hash), "pbkdf2-sha256$600000$"))
+	assert.NotContains(suite.T(), string(hash), "all work and no play")
+	assert.True(suite.T(), hash.Matches("all work and no play"))
+	assert.False(suite.T(), hash.Matches("all work and no play "))
+	assert.False(suite.T(), hash.Matches(""))
+}
+
+func (suite *PasswordTestSuite) Test_GIVEN_theSamePassword_WHEN_passwordIsHashedTwice_THEN_hashesAreSaltedDifferently() {
+	// WHEN
+	first, _ := HashPassword("heeeere's johnny")
+	second, _ := HashPassword("heeeere's johnny")
+
+	// THEN
+	assert.NotEqual(suite.T(), first, second)
+	assert.True(suite.T(), second.Matches("heeeere's johnny"))
+}
+
+func (suite *PasswordTestSuite) Test_GIVEN_aShortPassword_WHEN_passwordIsHashed_THEN_errorIsReturned() {
+	// WHEN
+	hash, err := HashPassword("redrum")
+
+	// THEN
+	assert.Equal(suite.T(), PasswordHash(""), hash)
+	assert.Equal(suite.T(), pkg.ErrUserPasswordValidation, errorCode(err, 0))
+	assert.Contains(suite.T(), errorFields(err), "password")
+}
+
+func (suite *PasswordTestSuite) Test_GIVEN_aMalformedHash_WHEN_passwordIsMatched_THEN_itDoesNotMatch() {
+	for _, hash := range []PasswordHash{
+		"",
+		"password",
+		"md5$1$c2FsdA$a2V5",
+		"pbkdf2-sha256$0$c2FsdA$a2V5",
+		"pbkdf2-sha256$1$not base64$a2V5",
+	} {
+		// THEN
+		assert.False(suite.T(), hash.Matches("password"), string(hash))
+	}
+}
+
+func (suite *PasswordTestSuite) Test_GIVEN_aHashSavedBefore_WHEN_itsPasswordIsMatched_THEN_itMatches() {
+	// GIVEN
+	hash := PasswordHash("pbkdf2-sha256$1000$c2FsdHNhbHRzYWx0c2FsdA$31ltvuXrbs8POP2F4tw9851NSNQCQpm0sCwu2eEvId8")
+
+	// THEN
+	assert.True(suite.T(), hash.Matches("correct horse battery staple"))
+	assert.False(suite.T(), hash.Matches("correct horse battery"))
+}
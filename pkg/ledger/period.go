@@ -0,0 +1,346 @@
+package ledger
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+// Period is a span of whole days, from its first day to its last day inclusive.
+// The days of a period are at midnight UTC.
+type Period interface {
+	FirstDay() time.Time
+	LastDay() time.Time
+	// Next is the period of the same kind and length that starts the day after the last day
+	Next() Period
+	// Previous is the period of the same kind and length that ends the day before the first day
+	Previous() Period
+	// Contains is true when the calendar day of the date is between the first and last day of the period
+	Contains(date time.Time) bool
+	String() string
+}
+
+var (
+	dayPeriodPattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	weekPeriodPattern    = regexp.MustCompile(`^(\d{4})-W(\d{2})$`)
+	monthPeriodPattern   = regexp.MustCompile(`^\d{4}-\d{2}$`)
+	quarterPeriodPattern = regexp.MustCompile(`^(\d{4})-Q([1-4])$`)
+	yearPeriodPattern    = regexp.MustCompile(`^\d{4}$`)
+)
+
+// ParsePeriod parses a period formatted as one of:
+//   - yyyy-MM-dd: a day
+//   - yyyy-Www: an ISO week e.g. 2021-W28. The week starts on weekStart and is numbered after the ISO week of its fourth day.
+//   - yyyy-MM: a calendar month
+//   - yyyy-Qq: a quarter e.g. 2021-Q3
+//   - yyyy: a year
+//   - yyyy-MM-dd/yyyy-MM-dd: a range of days, both inclusive
+func ParsePeriod(value string, weekStart time.Weekday) (Period, error) {
+	value = strings.TrimSpace(value)
+
+	if parts := strings.Split(value, "/"); len(parts) == 2 {
+		firstDay, err := time.Parse("2006-01-02", parts[0])
+		if err != nil {
+			return nil, periodFormatError(value, err)
+		}
+		lastDay, err := time.Parse("2006-01-02", parts[1])
+		if err != nil {
+			return nil, periodFormatError(value, err)
+		}
+		dateRange, err := NewDateRange(firstDay, lastDay)
+		if err != nil {
+			return nil, err
+		}
+		return dateRange, nil
+	}
+
+	switch {
+	case dayPeriodPattern.MatchString(value):
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, periodFormatError(value, err)
+		}
+		return MakeDay(date), nil
+	case weekPeriodPattern.MatchString(value):
+		matches := weekPeriodPattern.FindStringSubmatch(value)
+		year, _ := strconv.Atoi(matches[1])
+		week, _ := strconv.Atoi(matches[2])
+		isoWeek, err := NewISOWeek(year, week, weekStart)
+		if err != nil {
+			return nil, err
+		}
+		return isoWeek, nil
+	case monthPeriodPattern.MatchString(value):
+		date, err := time.Parse("2006-01", value)
+		if err != nil {
+			return nil, periodFormatError(value, err)
+		}
+		return MakeCalendarMonthFromDate(date), nil
+	case quarterPeriodPattern.MatchString(value):
+		matches := quarterPeriodPattern.FindStringSubmatch(value)
+		year, _ := strconv.ParseUint(matches[1], 10, 32)
+		quarter, _ := strconv.ParseUint(matches[2], 10, 32)
+		return MakeQuarter(uint(year), uint(quarter)), nil
+	case yearPeriodPattern.MatchString(value):
+		year, _ := strconv.ParseUint(value, 10, 32)
+		return MakeYear(uint(year)), nil
+	}
+
+	return nil, periodFormatError(value, nil)
+}
+
+func periodFormatError(value string, err error) error {
+	return pkg.ValidationErrorWithFields(pkg.ErrPeriodInvalid, fmt.Sprintf("Invalid period %q", value), err, map[string]string{
+		"period": "period must be formatted as yyyy-MM-dd, yyyy-Www, yyyy-MM, yyyy-Qq, yyyy or yyyy-MM-dd/yyyy-MM-dd",
+	})
+}
+
+func periodContains(period Period, date time.Time) bool {
+	day := startOfDay(date)
+	return !day.Before(period.FirstDay()) && !day.After(period.LastDay())
+}
+
+// -- Day
+
+type Day struct {
+	date time.Time
+}
+
+func MakeDay(date time.Time) Day {
+	return Day{date: startOfDay(date)}
+}
+
+func (d Day) FirstDay() time.Time {
+	return d.date
+}
+
+func (d Day) LastDay() time.Time {
+	return d.date
+}
+
+func (d Day) Next() Period {
+	return MakeDay(d.date.AddDate(0, 0, 1))
+}
+
+func (d Day) Previous() Period {
+	return MakeDay(d.date.AddDate(0, 0, -1))
+}
+
+func (d Day) Contains(date time.Time) bool {
+	return periodContains(d, date)
+}
+
+func (d Day) String() string {
+	return d.date.Format("2006-01-02")
+}
+
+// -- Week
+
+// Week is the seven days starting on its week start.
+type Week struct {
+	firstDay  time.Time
+	weekStart time.Weekday
+}
+
+// MakeWeek is the week starting on weekStart that contains the date.
+func MakeWeek(date time.Time, weekStart time.Weekday) Week {
+	day := startOfDay(date)
+	offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+	return Week{
+		firstDay:  day.AddDate(0, 0, -offset),
+		weekStart: weekStart,
+	}
+}
+
+// NewISOWeek is the week starting on weekStart whose fourth day is in the given ISO week.
+// When the week starts on a Monday, this is exactly the ISO week.
+func NewISOWeek(year int, week int, weekStart time.Weekday) (Week, error) {
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	firstMonday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	monday := firstMonday.AddDate(0, 0, 7*(week-1))
+	fourthDay := monday.AddDate(0, 0, (int(weekStart)+2)%7)
+
+	if isoYear, isoWeek := fourthDay.ISOWeek(); week < 1 || isoYear != year || isoWeek != week {
+		return Week{}, pkg.ValidationErrorWithFields(pkg.ErrPeriodInvalid, fmt.Sprintf("Invalid week %04d-W%02d", year, week), nil, map[string]string{
+			"period": fmt.Sprintf("%04d does not have a week %d", year, week),
+		})
+	}
+
+	return MakeWeek(fourthDay.AddDate(0, 0, -3), weekStart), nil
+}
+
+func (w Week) WeekStart() time.Weekday {
+	return w.weekStart
+}
+
+func (w Week) FirstDay() time.Time {
+	return w.firstDay
+}
+
+func (w Week) LastDay() time.Time {
+	return w.firstDay.AddDate(0, 0, 6)
+}
+
+func (w Week) Next() Period {
+	return MakeWeek(w.firstDay.AddDate(0, 0, 7), w.weekStart)
+}
+
+func (w Week) Previous() Period {
+	return MakeWeek(w.firstDay.AddDate(0, 0, -7), w.weekStart)
+}
+
+func (w Week) Contains(date time.Time) bool {
+	return periodContains(w, date)
+}
+
+func (w Week) String() string {
+	year, week := w.firstDay.AddDate(0, 0, 3).ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// -- Quarter
+
+type Quarter struct {
+	year    uint
+	quarter uint
+}
+
+// MakeQuarter is the quarter, from 1 to 4, of the year.
+func MakeQuarter(year uint, quarter uint) Quarter {
+	return Quarter{year: year, quarter: quarter}
+}
+
+func MakeQuarterFromDate(date time.Time) Quarter {
+	return MakeQuarter(uint(date.Year()), uint(date.Month()-1)/3+1)
+}
+
+func (q Quarter) Year() uint {
+	return q.year
+}
+
+func (q Quarter) Quarter() uint {
+	return q.quarter
+}
+
+func (q Quarter) FirstDay() time.Time {
+	return time.Date(int(q.year), time.Month(3*(q.quarter-1)+1), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (q Quarter) LastDay() time.Time {
+	return q.FirstDay().AddDate(0, 3, -1)
+}
+
+func (q Quarter) Next() Period {
+	return MakeQuarterFromDate(q.FirstDay().AddDate(0, 3, 0))
+}
+
+func (q Quarter) Previous() Period {
+	return MakeQuarterFromDate(q.FirstDay().AddDate(0, -3, 0))
+}
+
+func (q Quarter) Contains(date time.Time) bool {
+	return periodContains(q, date)
+}
+
+func (q Quarter) String() string {
+	return fmt.Sprintf("%04d-Q%d", q.year, q.quarter)
+}
+
+// -- Year
+
+type Year struct {
+	year uint
+}
+
+func MakeYear(year uint) Year {
+	return Year{year: year}
+}
+
+func MakeYearFromDate(date time.Time) Year {
+	return MakeYear(uint(date.Year()))
+}
+
+func (y Year) Year() uint {
+	return y.year
+}
+
+func (y Year) FirstDay() time.Time {
+	return time.Date(int(y.year), time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (y Year) LastDay() time.Time {
+	return time.Date(int(y.year), time.December, 31, 0, 0, 0, 0, time.UTC)
+}
+
+func (y Year) Next() Period {
+	return MakeYear(y.year + 1)
+}
+
+func (y Year) Previous() Period {
+	return MakeYear(y.year - 1)
+}
+
+func (y Year) Contains(date time.Time) bool {
+	return periodContains(y, date)
+}
+
+func (y Year) String() string {
+	return fmt.Sprintf("%04d", y.year)
+}
+
+// -- DateRange
+
+// DateRange is an arbitrary range of days. Its next and previous ranges have the same number of days.
+type DateRange struct {
+	firstDay time.Time
+	lastDay  time.Time
+}
+
+func NewDateRange(firstDay time.Time, lastDay time.Time) (DateRange, error) {
+	firstDay, lastDay = startOfDay(firstDay), startOfDay(lastDay)
+	if lastDay.Before(firstDay) {
+		return DateRange{}, pkg.ValidationErrorWithFields(pkg.ErrPeriodInvalid, "Invalid date range", nil, map[string]string{
+			"period": "the last day of the range must not be before the first day",
+		})
+	}
+	return DateRange{firstDay: firstDay, lastDay: lastDay}, nil
+}
+
+// days is the number of days in the range
+func (r DateRange) days() int {
+	return int(r.lastDay.Sub(r.firstDay).Hours()/24) + 1
+}
+
+func (r DateRange) FirstDay() time.Time {
+	return r.firstDay
+}
+
+func (r DateRange) LastDay() time.Time {
+	return r.lastDay
+}
+
+func (r DateRange) Next() Period {
+	return DateRange{
+		firstDay: r.lastDay.AddDate(0, 0, 1),
+		lastDay:  r.lastDay.AddDate(0, 0, r.days()),
+	}
+}
+
+func (r DateRange) Previous() Period {
+	return DateRange{
+		firstDay: r.firstDay.AddDate(0, 0, -r.days()),
+		lastDay:  r.firstDay.AddDate(0, 0, -1),
+	}
+}
+
+func (r DateRange) Contains(date time.Time) bool {
+	return periodContains(r, date)
+}
+
+func (r DateRange) String() string {
+	return fmt.Sprintf("%s/%s", r.firstDay.Format("2006-01-02"), r.lastDay.Format("2006-01-02"))
+}
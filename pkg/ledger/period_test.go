@@ -0,0 +1,139 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type PeriodTestSuite struct {
+	suite.Suite
+}
+
+func TestPeriodTestSuite(t *testing.T) {
+	suite.Run(t, new(PeriodTestSuite))
+}
+
+// -- SUITE
+
+func (suite *PeriodTestSuite) Test_GIVEN_aPeriodString_WHEN_periodIsParsed_THEN_firstAndLastDayAreCorrect() {
+	for _, test := range []struct {
+		value     string
+		weekStart time.Weekday
+		firstDay  time.Time
+		lastDay   time.Time
+	}{
+		{value: "2021-07-15", weekStart: time.Monday, firstDay: utcDate(2021, time.July, 15), lastDay: utcDate(2021, time.July, 15)},
+		{value: "2021-W28", weekStart: time.Monday, firstDay: utcDate(2021, time.July, 12), lastDay: utcDate(2021, time.July, 18)},
+		{value: "2021-W28", weekStart: time.Sunday, firstDay: utcDate(2021, time.July, 11), lastDay: utcDate(2021, time.July, 17)},
+		{value: "2021-W28", weekStart: time.Saturday, firstDay: utcDate(2021, time.July, 10), lastDay: utcDate(2021, time.July, 16)},
+		{value: "2020-W53", weekStart: time.Monday, firstDay: utcDate(2020, time.December, 28), lastDay: utcDate(2021, time.January, 3)},
+		{value: "2021-W01", weekStart: time.Monday, firstDay: utcDate(2021, time.January, 4), lastDay: utcDate(2021, time.January, 10)},
+		{value: "2020-02", weekStart: time.Monday, firstDay: utcDate(2020, time.February, 1), lastDay: utcDate(2020, time.February, 29)},
+		{value: "2021-Q1", weekStart: time.Monday, firstDay: utcDate(2021, time.January, 1), lastDay: utcDate(2021, time.March, 31)},
+		{value: "2021-Q4", weekStart: time.Monday, firstDay: utcDate(2021, time.October, 1), lastDay: utcDate(2021, time.December, 31)},
+		{value: "2021", weekStart: time.Monday, firstDay: utcDate(2021, time.January, 1), lastDay: utcDate(2021, time.December, 31)},
+		{value: "2021-07-01/2021-07-20", weekStart: time.Monday, firstDay: utcDate(2021, time.July, 1), lastDay: utcDate(2021, time.July, 20)},
+	} {
+		// WHEN
+		period, err := ParsePeriod(test.value, test.weekStart)
+
+		// THEN
+		assert.Nil(suite.T(), err, test.value)
+		assert.Equal(suite.T(), test.firstDay, period.FirstDay(), test.value)
+		assert.Equal(suite.T(), test.lastDay, period.LastDay(), test.value)
+		assert.Equal(suite.T(), test.value, period.String(), test.value)
+	}
+}
+
+func (suite *PeriodTestSuite) Test_GIVEN_anInvalidPeriodString_WHEN_periodIsParsed_THEN_errorIsReturned() {
+	for _, value := range []string{
+		"",
+		"July",
+		"2021-13",
+		"2021-02-30",
+		"2021-W00",
+		"2021-W53",
+		"2021-Q5",
+		"2021-07-20/2021-07-01",
+		"2021-07-01/July",
+	} {
+		// WHEN
+		period, err := ParsePeriod(value, time.Monday)
+
+		// THEN
+		assert.Nil(suite.T(), period, value)
+		assert.NotNil(suite.T(), err, value)
+		assert.Equal(suite.T(), pkg.ErrPeriodInvalid, errorCode(err, 0), value)
+		assert.Contains(suite.T(), errorFields(err), "period", value)
+	}
+}
+
+func (suite *PeriodTestSuite) Test_GIVEN_aPeriod_WHEN_nextAndPreviousPeriodsAreCalculated_THEN_periodsAreAdjacent() {
+	for _, test := range []struct {
+		period   Period
+		next     string
+		previous string
+	}{
+		{period: MakeDay(utcDate(2021, time.December, 31)), next: "2022-01-01", previous: "2021-12-30"},
+		{period: MakeWeek(utcDate(2020, time.December, 30), time.Monday), next: "2021-W01", previous: "2020-W52"},
+		{period: MakeCalendarMonth(2021, time.January), next: "2021-02", previous: "2020-12"},
+		{period: MakeQuarter(2021, 1), next: "2021-Q2", previous: "2020-Q4"},
+		{period: MakeQuarter(2021, 4), next: "2022-Q1", previous: "2021-Q3"},
+		{period: MakeYear(2021), next: "2022", previous: "2020"},
+		{period: mustDateRange(utcDate(2021, time.July, 1), utcDate(2021, time.July, 10)), next: "2021-07-11/2021-07-20", previous: "2021-06-21/2021-06-30"},
+	} {
+		// WHEN
+		next := test.period.Next()
+		previous := test.period.Previous()
+
+		// THEN
+		assert.Equal(suite.T(), test.next, next.String(), test.period.String())
+		assert.Equal(suite.T(), test.previous, previous.String(), test.period.String())
+		assert.Equal(suite.T(), test.period.LastDay().AddDate(0, 0, 1), next.FirstDay(), test.period.String())
+		assert.Equal(suite.T(), test.period.FirstDay().AddDate(0, 0, -1), previous.LastDay(), test.period.String())
+	}
+}
+
+func (suite *PeriodTestSuite) Test_GIVEN_aPeriod_WHEN_checkingIfItContainsADate_THEN_wholeFirstAndLastDaysAreIncluded() {
+	for _, test := range []struct {
+		period   Period
+		date     time.Time
+		contains bool
+	}{
+		{period: MakeDay(utcDate(2021, time.July, 15)), date: time.Date(2021, time.July, 15, 23, 59, 59, 0, time.UTC), contains: true},
+		{period: MakeDay(utcDate(2021, time.July, 15)), date: utcDate(2021, time.July, 16), contains: false},
+		{period: MakeWeek(utcDate(2021, time.July, 15), time.Sunday), date: utcDate(2021, time.July, 11), contains: true},
+		{period: MakeWeek(utcDate(2021, time.July, 15), time.Sunday), date: time.Date(2021, time.July, 17, 18, 0, 0, 0, time.UTC), contains: true},
+		{period: MakeWeek(utcDate(2021, time.July, 15), time.Sunday), date: utcDate(2021, time.July, 18), contains: false},
+		{period: MakeCalendarMonth(2021, time.July), date: time.Date(2021, time.July, 31, 12, 0, 0, 0, time.UTC), contains: true},
+		{period: MakeCalendarMonth(2021, time.July), date: utcDate(2021, time.June, 30), contains: false},
+		{period: MakeQuarterFromDate(utcDate(2021, time.August, 15)), date: utcDate(2021, time.September, 30), contains: true},
+		{period: MakeQuarterFromDate(utcDate(2021, time.August, 15)), date: utcDate(2021, time.October, 1), contains: false},
+		{period: MakeYearFromDate(utcDate(2021, time.August, 15)), date: time.Date(2021, time.December, 31, 23, 0, 0, 0, time.UTC), contains: true},
+		{period: mustDateRange(utcDate(2021, time.July, 1), utcDate(2021, time.July, 10)), date: utcDate(2021, time.July, 11), contains: false},
+	} {
+		// THEN
+		assert.Equal(suite.T(), test.contains, test.period.Contains(test.date), "%s contains %s", test.period, test.date)
+	}
+}
+
+func (suite *PeriodTestSuite) Test_WHEN_currentCalendarMonthIsCalculated_THEN_itContainsToday() {
+	// WHEN
+	now := time.Now().UTC()
+	month := CurrentCalendarMonth()
+
+	// THEN
+	assert.True(suite.T(), month.Contains(now))
+}
+
+func mustDateRange(firstDay time.Time, lastDay time.Time) DateRange {
+	dateRange, err := NewDateRange(firstDay, lastDay)
+	if err != nil {
+		panic(err)
+	}
+	return dateRange
+}
@@ -50,11 +50,14 @@ type Record struct {
 	category          Category
 	amount            Money
 	date              time.Time
+	occurredAt        time.Time
 	recordType        RecordType
 	sourceAccountId   AccountId
 	beneficiaryId     AccountId
 	beneficiaryType   AccountType
 	transferReference TransferReference
+	exchangeRate      ExchangeRate
+	splits            RecordSplits
 }
 
 // I did not think the naming through :(
@@ -64,11 +67,14 @@ type RecordRecord interface {
 	Category() Category
 	Amount() Money
 	DateUTC() time.Time
+	OccurredAtUTC() time.Time
 	RecordType() RecordType
 	SourceAccountId() AccountId
 	BeneficiaryId() AccountId
 	BeneficiaryType() AccountType
 	TransferReference() TransferReference
+	ExchangeRate() ExchangeRate
+	Splits() RecordSplits
 	CreatedBy() UpdatedBy
 	CreatedAtUTC() time.Time
 	ModifiedBy() UpdatedBy
@@ -76,12 +82,14 @@ type RecordRecord interface {
 	Version() Version
 }
 
+// NewRecord creates a record that occurred at the given moment.
+// The date of the record is the day of the moment in its location, which should be the timezone of the user.
 func NewRecord(
 	id RecordId,
 	note string,
 	category Category,
 	amount Money,
-	dateUTC time.Time,
+	date time.Time,
 	recordType RecordType,
 	sourceAccountId AccountId,
 	beneficiaryId AccountId,
@@ -103,12 +111,55 @@ func NewRecord(
 		note,
 		category,
 		amount,
-		dateUTC,
+		startOfDay(date),
+		date.UTC(),
 		recordType,
 		sourceAccountId,
 		beneficiaryId,
 		beneficiaryType,
 		transferReference,
+		NoExchangeRate,
+		NoSplits,
+		auditInfo,
+	)
+}
+
+// NewSplitRecord creates an income or expense whose amount is split across the categories of the given lines.
+// The category of the record is the category of the record as a whole e.g. the supermarket the receipt is from.
+func NewSplitRecord(
+	id RecordId,
+	note string,
+	category Category,
+	amount Money,
+	date time.Time,
+	recordType RecordType,
+	splits RecordSplits,
+	updatedBy UpdatedBy,
+) (Record, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	if auditInfo, err = makeAuditForCreation(updatedBy); err != nil {
+		return Record{}, err
+	}
+
+	sourceAccountId, beneficiaryId, transferReference := NoTransfer()
+	return newRecord(
+		id,
+		note,
+		category,
+		amount,
+		startOfDay(date),
+		date.UTC(),
+		recordType,
+		sourceAccountId,
+		beneficiaryId,
+		NoBeneficiaryType,
+		transferReference,
+		NoExchangeRate,
+		splits,
 		auditInfo,
 	)
 }
@@ -129,17 +180,26 @@ func NewRecordFromRecord(rr RecordRecord) (Record, error) {
 		return Record{}, err
 	}
 
+	// Records that were saved before the moment they occurred was kept are taken to have occurred at the start of their date
+	occurredAt := rr.OccurredAtUTC()
+	if occurredAt.IsZero() {
+		occurredAt = rr.DateUTC()
+	}
+
 	return newRecord(
 		rr.Id(),
 		rr.Note(),
 		rr.Category(),
 		rr.Amount(),
 		rr.DateUTC(),
+		occurredAt,
 		rr.RecordType(),
 		rr.SourceAccountId(),
 		rr.BeneficiaryId(),
 		rr.BeneficiaryType(),
 		rr.TransferReference(),
+		rr.ExchangeRate(),
+		rr.Splits(),
 		auditInfo,
 	)
 }
@@ -149,12 +209,15 @@ func newRecord(
 	note string,
 	category Category,
 	amount Money,
-	dateUTC time.Time,
+	date time.Time,
+	occurredAt time.Time,
 	recordType RecordType,
 	sourceAccountId,
 	beneficiaryId AccountId,
 	beneficiaryType AccountType,
 	transferReference TransferReference,
+	exchangeRate ExchangeRate,
+	splits RecordSplits,
 	auditInfo auditInfo,
 ) (Record, error) {
 	errors := validate.Validate(
@@ -162,11 +225,13 @@ func newRecord(
 		&validators.StringLengthInRange{Name: "Note", Field: note, Min: 0, Max: 50, Message: "Note can not be longer than 50 characters"},
 		&categoryValidator{Field: "Category", Value: category},
 		&amountValidator{Field: "Amount", Value: amount},
-		&validators.TimeIsPresent{Name: "Date", Field: dateUTC, Message: "Invalid date"},
+		&validators.TimeIsPresent{Name: "Date", Field: date, Message: "Invalid date"},
 		&validators.StringInclusion{Name: "RecordType", Field: string(recordType), List: []string{"INCOME", "EXPENSE", "TRANSFER"}, Message: "recordType must be INCOME,EXPENSE or TRANSFER."},
 		&beneficiaryIdValidator{BeneficiaryId: beneficiaryId, SourceAccountId: sourceAccountId, RecordType: recordType},
 		&beneficiaryTypeValidator{Field: string(beneficiaryType)},
 		&transferReferenceValidator{Value: transferReference, RecordType: recordType},
+		&exchangeRateValidator{Value: exchangeRate, RecordType: recordType},
+		&recordSplitsValidator{Splits: splits, Amount: amount, RecordType: recordType},
 	)
 
 	err := pkg.ValidationErrorWithErrors(pkg.ErrRecordValidation, "", errors)
@@ -188,23 +253,89 @@ func newRecord(
 		}
 	}
 
+	if splits, err = splits.withSignOf(recordType); err != nil {
+		return Record{}, err
+	}
+
 	record := Record{
 		auditInfo:         auditInfo,
 		id:                id,
 		note:              note,
 		category:          category,
 		amount:            actualAmount,
-		date:              dateUTC,
+		date:              date,
+		occurredAt:        occurredAt,
 		recordType:        recordType,
 		sourceAccountId:   sourceAccountId,
 		beneficiaryId:     beneficiaryId,
 		beneficiaryType:   beneficiaryType,
 		transferReference: transferReference,
+		exchangeRate:      exchangeRate,
+		splits:            splits,
 	}
 
 	return record, nil
 }
 
+// Edit returns a copy of the record with the given details, validated in the same way as a new record.
+// The splits replace the splits of the record. The transfer details of the record are not changed.
+// The date of the record is kept if the record still occurred at the same moment, otherwise it is the day of the new moment in its location.
+func (r Record) Edit(
+	note string,
+	category Category,
+	amount Money,
+	date time.Time,
+	recordType RecordType,
+	splits RecordSplits,
+	updatedBy UpdatedBy,
+) (Record, error) {
+	return r.edit(note, category, amount, date, recordType, r.beneficiaryId, r.beneficiaryType, r.exchangeRate, splits, updatedBy)
+}
+
+func (r Record) edit(
+	note string,
+	category Category,
+	amount Money,
+	occurredAt time.Time,
+	recordType RecordType,
+	beneficiaryId AccountId,
+	beneficiaryType AccountType,
+	exchangeRate ExchangeRate,
+	splits RecordSplits,
+	updatedBy UpdatedBy,
+) (Record, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	if auditInfo, err = makeAuditForUpdate(r.auditInfo, updatedBy); err != nil {
+		return Record{}, err
+	}
+
+	date := r.date
+	if !occurredAt.Equal(r.occurredAt) {
+		date = startOfDay(occurredAt)
+	}
+
+	return newRecord(
+		r.id,
+		note,
+		category,
+		amount,
+		date,
+		occurredAt.UTC(),
+		recordType,
+		r.sourceAccountId,
+		beneficiaryId,
+		beneficiaryType,
+		r.transferReference,
+		exchangeRate,
+		splits,
+		auditInfo,
+	)
+}
+
 func (r Record) Id() RecordId {
 	return r.id
 }
@@ -225,6 +356,11 @@ func (r Record) DateUTC() time.Time {
 	return r.date
 }
 
+// OccurredAtUTC is the moment at which the record occurred
+func (r Record) OccurredAtUTC() time.Time {
+	return r.occurredAt
+}
+
 func (r Record) DateUTCString() string {
 	return r.date.Format("2006-01-02T15:04:05-0700")
 }
@@ -249,6 +385,29 @@ func (r Record) TransferReference() TransferReference {
 	return r.transferReference
 }
 
+// ExchangeRate is only set on the records of a transfer between accounts with different currencies.
+func (r Record) ExchangeRate() ExchangeRate {
+	return r.exchangeRate
+}
+
+// Splits are only set when the amount of the record is split across multiple categories.
+func (r Record) Splits() RecordSplits {
+	return r.splits
+}
+
+func (r Record) IsSplit() bool {
+	return len(r.splits) > 0
+}
+
+// Lines returns the amounts of the record per category:
+// the lines of a split record, or a single line with the category and amount of the record.
+func (r Record) Lines() RecordSplits {
+	if r.IsSplit() {
+		return r.splits
+	}
+	return RecordSplits{{note: r.note, category: r.category, amount: r.amount}}
+}
+
 func (r Record) IsTransferToSavingAccount() bool {
 	return r.recordType == Transfer && r.beneficiaryType == AccountTypeSaving
 }
@@ -259,7 +418,7 @@ func (r Record) String() string {
 		r.recordType,
 		r.amount,
 		r.category,
-		r.DateUTCString(),
+		r.occurredAt.Format("2006-01-02T15:04:05-0700"),
 		r.sourceAccountId,
 		r.beneficiaryId,
 		r.beneficiaryType,
@@ -373,6 +532,7 @@ func (rs Records) NetBalance() (Money, error) {
 }
 
 // Total Expenses of given records = Total Expenses + Transfers of given records
+// The lines of a split record are counted individually.
 func (rs Records) TotalExpenses() (Money, error) {
 	if rs.Len() == 0 {
 		return nil, pkg.ValidationErrorWithFields(pkg.ErrAmountTotalOfEmptySet, "No amounts to total", nil, nil)
@@ -385,8 +545,11 @@ func (rs Records) TotalExpenses() (Money, error) {
 	)
 	for i := 0; i < rs.Len(); i++ {
 		record := rs[i]
-		if record.recordType == Expense {
-			amountAbs, err = record.Amount().Abs()
+		if record.recordType != Expense {
+			continue
+		}
+		for _, line := range record.Lines() {
+			amountAbs, err = line.Amount().Abs()
 			if err != nil {
 				return nil, err
 			}
@@ -399,6 +562,36 @@ func (rs Records) TotalExpenses() (Money, error) {
 	return total, nil
 }
 
+// Expenses of given records per category.
+// The lines of a split record are counted against their own categories.
+func (rs Records) ExpensesByCategory() (map[CategoryId]Money, error) {
+	totals := map[CategoryId]Money{}
+	for _, record := range rs {
+		if record.recordType != Expense {
+			continue
+		}
+		for _, line := range record.Lines() {
+			var (
+				amountAbs Money
+				total     Money
+				ok        bool
+				err       error
+			)
+			if amountAbs, err = line.Amount().Abs(); err != nil {
+				return nil, err
+			}
+			if total, ok = totals[line.Category().Id()]; !ok {
+				totals[line.Category().Id()] = amountAbs
+				continue
+			}
+			if totals[line.Category().Id()], err = total.Add(amountAbs); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return totals, nil
+}
+
 // Total income of given records
 func (rs Records) TotalIncome() (Money, error) {
 	if rs.Len() == 0 {
@@ -477,6 +670,7 @@ func (rs Records) NetExpenses() (Money, error) {
 	return total, nil
 }
 
+// Period is the first and last day on which the records occurred
 func (rs Records) Period() (time.Time, time.Time, error) {
 	if len(rs) == 0 {
 		return time.Time{}, time.Time{}, pkg.ValidationErrorWithFields(pkg.ErrRecordsPeriodOfEmptySet, "Can not determine records period for empty set", nil, nil)
@@ -0,0 +1,90 @@
+package ledger
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MinNoteSimilarity is the similarity from which the notes of two records are considered to describe the same transaction
+const MinNoteSimilarity = 0.8
+
+// IsLikelyDuplicateOf reports whether r and other are likely to be the same transaction recorded twice:
+// they have the same amount, similar notes and are recorded within window of each other.
+// The account of the records is not compared.
+func (r Record) IsLikelyDuplicateOf(other Record, window time.Duration) bool {
+	if r.Id() == other.Id() || !r.hasSameAmountAs(other) {
+		return false
+	}
+	gap := r.DateUTC().Sub(other.DateUTC())
+	if gap < 0 {
+		gap = -gap
+	}
+	return gap <= window && NoteSimilarity(r.Note(), other.Note()) >= MinNoteSimilarity
+}
+
+// IsExactDuplicateOf reports whether r and other have the same amount, date and note, ignoring case, spaces and punctuation.
+func (r Record) IsExactDuplicateOf(other Record) bool {
+	return r.Id() != other.Id() &&
+		r.hasSameAmountAs(other) &&
+		r.DateUTCString() == other.DateUTCString() &&
+		normalizeNote(r.Note()) == normalizeNote(other.Note())
+}
+
+func (r Record) hasSameAmountAs(other Record) bool {
+	if r.Amount() == nil || other.Amount() == nil {
+		return false
+	}
+	return r.Amount().Currency().CurrencyCode() == other.Amount().Currency().CurrencyCode() &&
+		r.Amount().MustMinorUnits() == other.Amount().MustMinorUnits()
+}
+
+// NoteSimilarity is a number between 0 (nothing in common) and 1 (the same) measuring how similar two notes are,
+// ignoring case, spaces and punctuation. It is based on the number of characters that must be changed to turn one note into the other.
+func NoteSimilarity(a, b string) float64 {
+	x, y := []rune(normalizeNote(a)), []rune(normalizeNote(b))
+	longest := len(x)
+	if len(y) > longest {
+		longest = len(y)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(x, y))/float64(longest)
+}
+
+func normalizeNote(note string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, note)
+}
+
+// editDistance is the Levenshtein distance between x and y
+func editDistance(x, y []rune) int {
+	previous := make([]int, len(y)+1)
+	current := make([]int, len(y)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		current[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(y)]
+}
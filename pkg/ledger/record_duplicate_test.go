@@ -0,0 +1,89 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RecordDuplicateTestSuite struct {
+	suite.Suite
+	groceriesCategory Category
+}
+
+func TestRecordDuplicateTestSuite(t *testing.T) {
+	suite.Run(t, new(RecordDuplicateTestSuite))
+}
+
+func (suite *RecordDuplicateTestSuite) SetupTest() {
+	suite.groceriesCategory, _ = NewCategory(CategoryId(1), "Groceries", MustMakeUpdatedByUserId(UserId(1)))
+}
+
+func (suite *RecordDuplicateTestSuite) makeRecord(id RecordId, note string, amount int64, date time.Time) Record {
+	money, _ := NewMoney("AED", amount)
+	record, err := NewRecord(
+		id,
+		note,
+		suite.groceriesCategory,
+		money,
+		date,
+		Expense,
+		NoSourceAccount,
+		NoBeneficiaryAccount,
+		NoBeneficiaryType,
+		NoTransferReference,
+		MustMakeUpdatedByUserId(UserId(1)),
+	)
+	assert.Nil(suite.T(), err)
+	return record
+}
+
+// -- SUITE
+
+func (suite *RecordDuplicateTestSuite) Test_GIVEN_notes_WHEN_similarityIsMeasured_THEN_caseSpacesAndPunctuationAreIgnored() {
+	testCases := []struct {
+		a, b     string
+		expected float64
+	}{
+		{a: "Carrefour", b: "CARREFOUR", expected: 1},
+		{a: "Carrefour - MOE", b: "carrefour moe", expected: 1},
+		{a: "", b: "", expected: 1},
+		{a: "Carrefour", b: "", expected: 0},
+		{a: "Carrefour", b: "Carrefur", expected: 8.0 / 9.0},
+		{a: "abc", b: "xyz", expected: 0},
+	}
+
+	for _, tc := range testCases {
+		assert.InDelta(suite.T(), tc.expected, NoteSimilarity(tc.a, tc.b), 0.0001, "%q and %q", tc.a, tc.b)
+	}
+}
+
+func (suite *RecordDuplicateTestSuite) Test_GIVEN_records_WHEN_comparedForDuplicates_THEN_amountNoteAndDateAreCompared() {
+	// GIVEN
+	date := time.Date(2021, time.July, 3, 0, 0, 0, 0, time.UTC)
+	window := 3 * 24 * time.Hour
+	record := suite.makeRecord(1, "Carrefour", -12345, date)
+
+	testCases := []struct {
+		name   string
+		other  Record
+		likely bool
+		exact  bool
+	}{
+		{name: "same", other: suite.makeRecord(2, "carrefour", -12345, date), likely: true, exact: true},
+		{name: "similar note", other: suite.makeRecord(2, "Carrefur", -12345, date), likely: true, exact: false},
+		{name: "within window", other: suite.makeRecord(2, "Carrefour", -12345, date.AddDate(0, 0, 3)), likely: true, exact: false},
+		{name: "outside window", other: suite.makeRecord(2, "Carrefour", -12345, date.AddDate(0, 0, -4)), likely: false, exact: false},
+		{name: "different amount", other: suite.makeRecord(2, "Carrefour", -12346, date), likely: false, exact: false},
+		{name: "different note", other: suite.makeRecord(2, "Lulu", -12345, date), likely: false, exact: false},
+		{name: "itself", other: record, likely: false, exact: false},
+	}
+
+	for _, tc := range testCases {
+		// THEN
+		assert.Equal(suite.T(), tc.likely, record.IsLikelyDuplicateOf(tc.other, window), tc.name)
+		assert.Equal(suite.T(), tc.exact, record.IsExactDuplicateOf(tc.other), tc.name)
+	}
+}
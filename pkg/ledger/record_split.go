@@ -0,0 +1,148 @@
+package ledger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+// RecordSplit is one line of a record whose amount is split across multiple categories
+// e.g. the groceries, household items and alcohol on a single supermarket receipt.
+// The amounts of the lines of a record add up to the amount of the record.
+type RecordSplit struct {
+	note     string
+	category Category
+	amount   Money
+}
+
+type RecordSplits []RecordSplit
+
+var NoSplits = RecordSplits(nil)
+
+func NewRecordSplit(note string, category Category, amount Money) (RecordSplit, error) {
+	errors := validate.Validate(
+		&validators.StringLengthInRange{Name: "Note", Field: note, Min: 0, Max: 50, Message: "Note can not be longer than 50 characters"},
+		&categoryValidator{Field: "Category", Value: category},
+		&amountValidator{Field: "Amount", Value: amount},
+	)
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrRecordValidation, "", errors); err != nil {
+		return RecordSplit{}, err
+	}
+
+	return RecordSplit{
+		note:     note,
+		category: category,
+		amount:   amount,
+	}, nil
+}
+
+func (s RecordSplit) Note() string {
+	return s.note
+}
+
+func (s RecordSplit) Category() Category {
+	return s.category
+}
+
+func (s RecordSplit) Amount() Money {
+	return s.amount
+}
+
+func (s RecordSplit) String() string {
+	return fmt.Sprintf("RecordSplit{category: %s, amount: %s, note: %s}", s.category, s.amount, s.note)
+}
+
+// withSignOf returns the splits with amounts of the same sign as the amounts of records of the given type.
+func (ss RecordSplits) withSignOf(recordType RecordType) (RecordSplits, error) {
+	if len(ss) == 0 {
+		return NoSplits, nil
+	}
+
+	signed := make(RecordSplits, 0, len(ss))
+	for _, split := range ss {
+		var (
+			amount = split.amount
+			err    error
+		)
+		if recordType == Expense {
+			if amount, err = split.amount.Negate(); err != nil {
+				return NoSplits, err
+			}
+		}
+		if recordType == Income {
+			if amount, err = split.amount.Abs(); err != nil {
+				return NoSplits, err
+			}
+		}
+		signed = append(signed, RecordSplit{note: split.note, category: split.category, amount: amount})
+	}
+	return signed, nil
+}
+
+func (ss RecordSplits) String() string {
+	strs := make([]string, 0, len(ss))
+	for _, split := range ss {
+		strs = append(strs, split.String())
+	}
+	return fmt.Sprintf("RecordSplits{%s}", strings.Join(strs, ", "))
+}
+
+type recordSplitsValidator struct {
+	Splits     RecordSplits
+	Amount     Money
+	RecordType RecordType
+}
+
+func (v *recordSplitsValidator) IsValid(errors *validate.Errors) {
+	if len(v.Splits) == 0 {
+		return
+	}
+	if v.RecordType == Transfer {
+		errors.Add("splits", "a transfer can not be split")
+		return
+	}
+	if len(v.Splits) < 2 {
+		errors.Add("splits", "a record must be split into at least 2 lines")
+		return
+	}
+	if v.Amount == nil || v.Amount.IsZero() {
+		// Reported by the amount validator
+		return
+	}
+
+	var total int64
+	for _, split := range v.Splits {
+		if split.amount == nil {
+			errors.Add("splits", "the amount of each line is required")
+			return
+		}
+		if split.amount.Currency().CurrencyCode() != v.Amount.Currency().CurrencyCode() {
+			errors.Add("splits", "the lines of a record must be in the currency of the record")
+			return
+		}
+		minorUnits, err := split.amount.MinorUnits()
+		if err != nil {
+			errors.Add("splits", "the amount of a line is too large")
+			return
+		}
+		if minorUnits < 0 {
+			minorUnits = -minorUnits
+		}
+		total += minorUnits
+	}
+
+	amount, err := v.Amount.MinorUnits()
+	if err != nil {
+		return
+	}
+	if amount < 0 {
+		amount = -amount
+	}
+	if total != amount {
+		errors.Add("splits", "the amounts of the lines must add up to the amount of the record")
+	}
+}
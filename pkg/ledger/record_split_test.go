@@ -0,0 +1,128 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type RecordSplitTestSuite struct {
+	suite.Suite
+	supermarketCategory Category
+	groceriesCategory   Category
+	householdCategory   Category
+	date                time.Time
+}
+
+func TestRecordSplitTestSuite(t *testing.T) {
+	suite.Run(t, new(RecordSplitTestSuite))
+}
+
+func (suite *RecordSplitTestSuite) SetupTest() {
+	suite.supermarketCategory, _ = NewCategory(CategoryId(1), "Supermarket", MustMakeUpdatedByUserId(UserId(1)))
+	suite.groceriesCategory, _ = NewCategory(CategoryId(2), "Groceries", MustMakeUpdatedByUserId(UserId(1)))
+	suite.householdCategory, _ = NewCategory(CategoryId(3), "Household", MustMakeUpdatedByUserId(UserId(1)))
+	suite.date = time.Date(2021, time.July, 2, 0, 0, 0, 0, time.UTC)
+}
+
+func (suite *RecordSplitTestSuite) splits(groceries int64, household int64) RecordSplits {
+	groceriesSplit, _ := NewRecordSplit("Vegetables", suite.groceriesCategory, MustMoney(NewMoney("AED", groceries)))
+	householdSplit, _ := NewRecordSplit("Detergent", suite.householdCategory, MustMoney(NewMoney("AED", household)))
+	return RecordSplits{groceriesSplit, householdSplit}
+}
+
+// -- SUITE
+
+func (suite *RecordSplitTestSuite) Test_GIVEN_splitsThatAddUpToAmount_WHEN_expenseIsCreated_THEN_splitAmountsAreNegative() {
+	// WHEN
+	record, err := NewSplitRecord(RecordId(1), "Receipt", suite.supermarketCategory, MustMoney(NewMoney("AED", 15000)), suite.date, Expense, suite.splits(10000, 5000), MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), record.IsSplit())
+	assert.Len(suite.T(), record.Splits(), 2)
+	assert.Equal(suite.T(), "AED -150.00", record.Amount().String())
+	assert.Equal(suite.T(), "AED -100.00", record.Splits()[0].Amount().String())
+	assert.Equal(suite.T(), "Groceries", record.Splits()[0].Category().Name())
+	assert.Equal(suite.T(), "AED -50.00", record.Splits()[1].Amount().String())
+	assert.Equal(suite.T(), "Household", record.Splits()[1].Category().Name())
+}
+
+func (suite *RecordSplitTestSuite) Test_GIVEN_splitsThatDoNotAddUpToAmount_WHEN_recordIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewSplitRecord(RecordId(1), "Receipt", suite.supermarketCategory, MustMoney(NewMoney("AED", 15000)), suite.date, Expense, suite.splits(10000, 4000), MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrRecordValidation, errorCode(err, 0))
+	assert.Equal(suite.T(), "the amounts of the lines must add up to the amount of the record", errorFields(err)["splits"])
+}
+
+func (suite *RecordSplitTestSuite) Test_GIVEN_aSingleSplit_WHEN_recordIsCreated_THEN_errorIsReturned() {
+	// GIVEN
+	split, _ := NewRecordSplit("Vegetables", suite.groceriesCategory, MustMoney(NewMoney("AED", 15000)))
+
+	// WHEN
+	_, err := NewSplitRecord(RecordId(1), "Receipt", suite.supermarketCategory, MustMoney(NewMoney("AED", 15000)), suite.date, Expense, RecordSplits{split}, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "a record must be split into at least 2 lines", errorFields(err)["splits"])
+}
+
+func (suite *RecordSplitTestSuite) Test_GIVEN_splitsInDifferentCurrency_WHEN_recordIsCreated_THEN_errorIsReturned() {
+	// GIVEN
+	groceriesSplit, _ := NewRecordSplit("Vegetables", suite.groceriesCategory, MustMoney(NewMoney("USD", 10000)))
+	householdSplit, _ := NewRecordSplit("Detergent", suite.householdCategory, MustMoney(NewMoney("AED", 5000)))
+
+	// WHEN
+	_, err := NewSplitRecord(RecordId(1), "Receipt", suite.supermarketCategory, MustMoney(NewMoney("AED", 15000)), suite.date, Expense, RecordSplits{groceriesSplit, householdSplit}, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "the lines of a record must be in the currency of the record", errorFields(err)["splits"])
+}
+
+func (suite *RecordSplitTestSuite) Test_GIVEN_aSplitWithoutCategory_WHEN_splitIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewRecordSplit("Vegetables", Category{}, MustMoney(NewMoney("AED", 10000)))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Category is required", errorFields(err)["category"])
+}
+
+func (suite *RecordSplitTestSuite) Test_GIVEN_splitAndUnsplitExpenses_WHEN_expensesByCategoryAreCalculated_THEN_splitLinesAreCountedInTheirCategories() {
+	// GIVEN
+	splitRecord, _ := NewSplitRecord(RecordId(1), "Receipt", suite.supermarketCategory, MustMoney(NewMoney("AED", 15000)), suite.date, Expense, suite.splits(10000, 5000), MustMakeUpdatedByUserId(UserId(1)))
+	groceries, _ := NewRecord(RecordId(2), "Bakery", suite.groceriesCategory, MustMoney(NewMoney("AED", 2000)), suite.date, Expense, NoSourceAccount, NoBeneficiaryAccount, NoBeneficiaryType, NoTransferReference, MustMakeUpdatedByUserId(UserId(1)))
+	records := Records{splitRecord, groceries}
+
+	// WHEN
+	byCategory, err := records.ExpensesByCategory()
+	total, _ := records.TotalExpenses()
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), byCategory, 2)
+	assert.Equal(suite.T(), "AED 120.00", byCategory[suite.groceriesCategory.Id()].String())
+	assert.Equal(suite.T(), "AED 50.00", byCategory[suite.householdCategory.Id()].String())
+	assert.Equal(suite.T(), "AED 170.00", total.String())
+}
+
+func (suite *RecordSplitTestSuite) Test_GIVEN_aSplitRecord_WHEN_editedWithoutSplits_THEN_splitsAreRemoved() {
+	// GIVEN
+	record, _ := NewSplitRecord(RecordId(1), "Receipt", suite.supermarketCategory, MustMoney(NewMoney("AED", 15000)), suite.date, Expense, suite.splits(10000, 5000), MustMakeUpdatedByUserId(UserId(1)))
+
+	// WHEN
+	edited, err := record.Edit("Receipt", suite.groceriesCategory, record.Amount(), suite.date, Expense, NoSplits, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), edited.IsSplit())
+	assert.Len(suite.T(), edited.Lines(), 1)
+	assert.Equal(suite.T(), "Groceries", edited.Lines()[0].Category().Name())
+}
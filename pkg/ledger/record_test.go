@@ -461,6 +461,40 @@ func (suite *RecordTestSuite) Test_GIVEN_incomeRecordTypeWithPositiveAmount_WHEN
 	assert.Equal(suite.T(), AccountId(0), record.BeneficiaryId())
 }
 
+func (suite *RecordTestSuite) Test_GIVEN_anExpenseRecord_WHEN_recordIsEditedToIncome_THEN_amountIsPositiveAndModifiedByIsSet() {
+
+	// GIVEN
+	record, _ := NewRecord(RecordId(1), "Telephone Bill", suite.billsCategory, suite.billAmount, time.Now().UTC(), Expense, NoSourceAccount, NoBeneficiaryAccount, NoBeneficiaryType, NoTransferReference, MustMakeUpdatedByUserId(1))
+
+	// WHEN
+	edited, err := record.Edit("Refund", suite.billsCategory, suite.billAmount, record.DateUTC(), Income, NoSplits, MustMakeUpdatedByUserId(2))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), RecordId(1), edited.Id())
+	assert.Equal(suite.T(), "Refund", edited.Note())
+	assert.Equal(suite.T(), "AED 200.00", edited.Amount().String())
+	assert.Equal(suite.T(), Income, edited.Type())
+	assert.Equal(suite.T(), record.CreatedBy(), edited.CreatedBy())
+	assert.Equal(suite.T(), MustMakeUpdatedByUserId(2), edited.ModifiedBy())
+	assert.False(suite.T(), edited.ModifiedAtUTC().IsZero())
+	assert.Equal(suite.T(), record.Version(), edited.Version())
+}
+
+func (suite *RecordTestSuite) Test_GIVEN_aRecord_WHEN_recordIsEditedWithInvalidDetails_THEN_errorIsReturned() {
+
+	// GIVEN
+	record, _ := NewRecord(RecordId(1), "Telephone Bill", suite.billsCategory, suite.billAmount, time.Now().UTC(), Expense, NoSourceAccount, NoBeneficiaryAccount, NoBeneficiaryType, NoTransferReference, MustMakeUpdatedByUserId(1))
+
+	// WHEN
+	edited, err := record.Edit("Telephone Bill", suite.billsCategory, suite.billAmount, record.DateUTC(), Transfer, NoSplits, MustMakeUpdatedByUserId(1))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), Record{}, edited)
+	assert.Equal(suite.T(), pkg.ErrRecordValidation, errorCode(err, 0))
+}
+
 func (suite *RecordTestSuite) Test_GIVEN_aRecord_WHEN_stringIsCalled_THEN_stringIsReadable() {
 
 	// GIVEN
@@ -680,8 +714,8 @@ func (suite *RecordTestSuite) Test_GIVEN_recordsAcrossTwoMonths_WHEN_determining
 
 	// THEN
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), "2021-01-01 12:00:00 +0000 UTC", from.String())
-	assert.Equal(suite.T(), "2021-02-28 12:00:00 +0000 UTC", to.String())
+	assert.Equal(suite.T(), "2021-01-01 00:00:00 +0000 UTC", from.String())
+	assert.Equal(suite.T(), "2021-02-28 00:00:00 +0000 UTC", to.String())
 }
 
 func (suite *RecordTestSuite) Test_GIVEN_emptyRecords_WHEN_determiningRecordPeriod_THEN_errorIsReturned() {
@@ -696,3 +730,36 @@ func (suite *RecordTestSuite) Test_GIVEN_emptyRecords_WHEN_determiningRecordPeri
 	assert.Equal(suite.T(), pkg.ErrRecordsPeriodOfEmptySet, errorCode(err, 0))
 	assert.Equal(suite.T(), "Can not determine records period for empty set", err.Error())
 }
+
+func (suite *RecordTestSuite) Test_GIVEN_aDateInTheTimezoneOfTheUser_WHEN_RecordIsCreated_THEN_dateIsTheLocalDayAndMomentIsKept() {
+	// GIVEN
+	dubai, _ := time.LoadLocation("Asia/Dubai")
+	occurredAt := time.Date(2021, time.August, 1, 0, 30, 0, 0, dubai)
+
+	// WHEN
+	record, err := NewRecord(
+		RecordId(1),
+		"Telephone Bill",
+		suite.billsCategory,
+		suite.billAmount,
+		occurredAt,
+		Expense,
+		NoSourceAccount,
+		NoBeneficiaryAccount,
+		NoBeneficiaryType,
+		NoTransferReference,
+		MustMakeUpdatedByUserId(UserId(1)),
+	)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), utcDate(2021, time.August, 1), record.DateUTC())
+	assert.Equal(suite.T(), time.Date(2021, time.July, 31, 20, 30, 0, 0, time.UTC), record.OccurredAtUTC())
+
+	// WHEN
+	edited, err := record.Edit(record.Note(), record.Category(), record.Amount(), occurredAt.In(time.UTC), record.Type(), NoSplits, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), utcDate(2021, time.August, 1), edited.DateUTC(), "date is kept when the moment does not change")
+}
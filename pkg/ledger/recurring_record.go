@@ -0,0 +1,563 @@
+package ledger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type Frequency string
+
+const (
+	Daily  Frequency = "DAILY"
+	Weekly Frequency = "WEEKLY"
+	// Monthly on a given day of the month.
+	// In months that are shorter, the record is created on the last day of the month.
+	Monthly Frequency = "MONTHLY"
+	// Monthly on the last weekday of the month.
+	LastBusinessDay Frequency = "LAST_BUSINESS_DAY"
+	Yearly          Frequency = "YEARLY"
+)
+
+// Recurrence describes when a recurring record occurs.
+// Weekly and yearly records occur on the weekday and on the day of the year of their start date.
+type Recurrence struct {
+	frequency  Frequency
+	dayOfMonth int
+}
+
+func NewRecurrence(frequency Frequency, dayOfMonth int) (Recurrence, error) {
+	errors := validate.Validate(
+		&validators.StringInclusion{
+			Name:    "Frequency",
+			Field:   string(frequency),
+			List:    []string{string(Daily), string(Weekly), string(Monthly), string(LastBusinessDay), string(Yearly)},
+			Message: "frequency must be DAILY, WEEKLY, MONTHLY, LAST_BUSINESS_DAY or YEARLY.",
+		},
+		&dayOfMonthValidator{Frequency: frequency, Value: dayOfMonth},
+	)
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrRecurringRecordValidation, "", errors); err != nil {
+		return Recurrence{}, err
+	}
+
+	return Recurrence{
+		frequency:  frequency,
+		dayOfMonth: dayOfMonth,
+	}, nil
+}
+
+func (r Recurrence) Frequency() Frequency {
+	return r.frequency
+}
+
+// DayOfMonth is only set when the frequency is monthly.
+func (r Recurrence) DayOfMonth() int {
+	return r.dayOfMonth
+}
+
+// firstOnOrAfter returns the first occurrence on or after the given date.
+func (r Recurrence) firstOnOrAfter(date time.Time) time.Time {
+	date = truncateToDay(date)
+	month := MakeCalendarMonthFromDate(date)
+	switch r.frequency {
+	case Monthly:
+		if occurrence := dayOfCalendarMonth(month, r.dayOfMonth); !occurrence.Before(date) {
+			return occurrence
+		}
+		return dayOfCalendarMonth(month.NextMonth(), r.dayOfMonth)
+	case LastBusinessDay:
+		if occurrence := lastBusinessDay(month); !occurrence.Before(date) {
+			return occurrence
+		}
+		return lastBusinessDay(month.NextMonth())
+	default:
+		return date
+	}
+}
+
+// next returns the occurrence that follows the given occurrence of a recurring record that started on startDate.
+func (r Recurrence) next(occurrence time.Time, startDate time.Time) time.Time {
+	occurrence = truncateToDay(occurrence)
+	nextMonth := MakeCalendarMonthFromDate(occurrence).NextMonth()
+	switch r.frequency {
+	case Daily:
+		return occurrence.AddDate(0, 0, 1)
+	case Weekly:
+		return occurrence.AddDate(0, 0, 7)
+	case Monthly:
+		return dayOfCalendarMonth(nextMonth, r.dayOfMonth)
+	case LastBusinessDay:
+		return lastBusinessDay(nextMonth)
+	case Yearly:
+		return dayOfCalendarMonth(MakeCalendarMonth(uint(occurrence.Year()+1), startDate.Month()), startDate.Day())
+	default:
+		return occurrence
+	}
+}
+
+func (r Recurrence) String() string {
+	if r.frequency == Monthly {
+		return fmt.Sprintf("Recurrence{frequency: %s, dayOfMonth: %d}", r.frequency, r.dayOfMonth)
+	}
+	return fmt.Sprintf("Recurrence{frequency: %s}", r.frequency)
+}
+
+// dayOfCalendarMonth returns the given day of the month, or the last day of the month if the month is shorter.
+func dayOfCalendarMonth(month CalendarMonth, day int) time.Time {
+	lastDay := month.LastDay()
+	if day > lastDay.Day() {
+		return lastDay
+	}
+	return time.Date(int(month.Year()), month.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+func lastBusinessDay(month CalendarMonth) time.Time {
+	day := month.LastDay()
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+func truncateToDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+type dayOfMonthValidator struct {
+	Frequency Frequency
+	Value     int
+}
+
+func (v *dayOfMonthValidator) IsValid(errors *validate.Errors) {
+	if v.Frequency == Monthly && (v.Value < 1 || v.Value > 31) {
+		errors.Add("dayOfMonth", "dayOfMonth must be between 1 and 31 when frequency is MONTHLY")
+	}
+	if v.Frequency != Monthly && v.Value != 0 {
+		errors.Add("dayOfMonth", fmt.Sprintf("dayOfMonth must be empty when frequency is %s", v.Frequency))
+	}
+}
+
+type RecurringRecordId uint64
+
+// RecurringRecord is a template of a record that is created on every occurrence of its recurrence
+// e.g. rent, salary or a subscription.
+// A recurring record ends once its next occurrence is after its end date.
+type RecurringRecord struct {
+	auditInfo
+	id            RecurringRecordId
+	accountId     AccountId
+	note          string
+	category      Category
+	amount        Money
+	recordType    RecordType
+	beneficiaryId AccountId
+	exchangeRate  ExchangeRate
+	recurrence    Recurrence
+	startDate     time.Time
+	endDate       time.Time
+	nextDate      time.Time
+	paused        bool
+}
+
+type RecurringRecordRecord interface {
+	Id() RecurringRecordId
+	AccountId() AccountId
+	Note() string
+	Category() Category
+	Amount() Money
+	RecordType() RecordType
+	BeneficiaryId() AccountId
+	ExchangeRate() ExchangeRate
+	Recurrence() Recurrence
+	StartDate() time.Time
+	EndDate() time.Time
+	NextDate() time.Time
+	IsPaused() bool
+	CreatedBy() UpdatedBy
+	CreatedAtUTC() time.Time
+	ModifiedBy() UpdatedBy
+	ModifiedAtUTC() time.Time
+	Version() Version
+}
+
+var NoEndDate = time.Time{}
+
+// NewRecurringRecord creates a recurring record whose first occurrence is on or after its start date.
+// The beneficiary and exchange rate are only provided when the record is a transfer.
+func NewRecurringRecord(
+	id RecurringRecordId,
+	accountId AccountId,
+	note string,
+	category Category,
+	amount Money,
+	recordType RecordType,
+	beneficiaryId AccountId,
+	exchangeRate ExchangeRate,
+	recurrence Recurrence,
+	startDate time.Time,
+	endDate time.Time,
+	createdBy UpdatedBy,
+) (RecurringRecord, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	if auditInfo, err = makeAuditForCreation(createdBy); err != nil {
+		return RecurringRecord{}, err
+	}
+
+	return newRecurringRecord(
+		id,
+		accountId,
+		note,
+		category,
+		amount,
+		recordType,
+		beneficiaryId,
+		exchangeRate,
+		recurrence,
+		startDate,
+		endDate,
+		recurrence.firstOnOrAfter(startDate),
+		false,
+		auditInfo,
+	)
+}
+
+func NewRecurringRecordFromRecord(rr RecurringRecordRecord) (RecurringRecord, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	if auditInfo, err = makeAuditForModification(
+		rr.CreatedBy(),
+		rr.CreatedAtUTC(),
+		rr.ModifiedBy(),
+		rr.ModifiedAtUTC(),
+		rr.Version(),
+	); err != nil {
+		return RecurringRecord{}, err
+	}
+
+	return newRecurringRecord(
+		rr.Id(),
+		rr.AccountId(),
+		rr.Note(),
+		rr.Category(),
+		rr.Amount(),
+		rr.RecordType(),
+		rr.BeneficiaryId(),
+		rr.ExchangeRate(),
+		rr.Recurrence(),
+		rr.StartDate(),
+		rr.EndDate(),
+		rr.NextDate(),
+		rr.IsPaused(),
+		auditInfo,
+	)
+}
+
+func newRecurringRecord(
+	id RecurringRecordId,
+	accountId AccountId,
+	note string,
+	category Category,
+	amount Money,
+	recordType RecordType,
+	beneficiaryId AccountId,
+	exchangeRate ExchangeRate,
+	recurrence Recurrence,
+	startDate time.Time,
+	endDate time.Time,
+	nextDate time.Time,
+	paused bool,
+	auditInfo auditInfo,
+) (RecurringRecord, error) {
+	if !startDate.IsZero() {
+		startDate = truncateToDay(startDate)
+	}
+	if !endDate.IsZero() {
+		endDate = truncateToDay(endDate)
+	}
+	if !nextDate.IsZero() {
+		nextDate = truncateToDay(nextDate)
+	}
+
+	errors := validate.Validate(
+		&validators.IntIsGreaterThan{Name: "Id", Field: int(id), Compared: 0, Message: "Id must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "AccountId", Field: int(accountId), Compared: 0, Message: "AccountId must be greater than 0"},
+		&validators.StringLengthInRange{Name: "Note", Field: note, Min: 0, Max: 50, Message: "Note can not be longer than 50 characters"},
+		&categoryValidator{Field: "Category", Value: category},
+		&amountValidator{Field: "Amount", Value: amount},
+		&validators.StringInclusion{Name: "RecordType", Field: string(recordType), List: []string{"INCOME", "EXPENSE", "TRANSFER"}, Message: "recordType must be INCOME,EXPENSE or TRANSFER."},
+		&recurringBeneficiaryValidator{BeneficiaryId: beneficiaryId, AccountId: accountId, RecordType: recordType},
+		&exchangeRateValidator{Value: exchangeRate, RecordType: recordType},
+		&validators.StringIsPresent{Name: "Frequency", Field: string(recurrence.frequency), Message: "frequency is required"},
+		&validators.TimeIsPresent{Name: "StartDate", Field: startDate, Message: "startDate is required"},
+		&endDateValidator{StartDate: startDate, EndDate: endDate},
+	)
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrRecurringRecordValidation, "", errors); err != nil {
+		return RecurringRecord{}, err
+	}
+
+	var err error
+	if amount, err = amount.Abs(); err != nil {
+		return RecurringRecord{}, err
+	}
+
+	// The record has ended once its next occurrence is after its end date
+	if !endDate.IsZero() && nextDate.After(endDate) {
+		nextDate = time.Time{}
+	}
+
+	return RecurringRecord{
+		auditInfo:     auditInfo,
+		id:            id,
+		accountId:     accountId,
+		note:          note,
+		category:      category,
+		amount:        amount,
+		recordType:    recordType,
+		beneficiaryId: beneficiaryId,
+		exchangeRate:  exchangeRate,
+		recurrence:    recurrence,
+		startDate:     startDate,
+		endDate:       endDate,
+		nextDate:      nextDate,
+		paused:        paused,
+	}, nil
+}
+
+// Edit returns a copy of the recurring record with the given details.
+// When the recurrence or the start date changes, the next occurrence is the first occurrence on or after today or the start date, whichever is later.
+func (rr RecurringRecord) Edit(
+	note string,
+	category Category,
+	amount Money,
+	recordType RecordType,
+	beneficiaryId AccountId,
+	exchangeRate ExchangeRate,
+	recurrence Recurrence,
+	startDate time.Time,
+	endDate time.Time,
+	today time.Time,
+	updatedBy UpdatedBy,
+) (RecurringRecord, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	if auditInfo, err = makeAuditForUpdate(rr.auditInfo, updatedBy); err != nil {
+		return RecurringRecord{}, err
+	}
+
+	nextDate := rr.nextDate
+	if recurrence != rr.recurrence || !truncateToDay(startDate).Equal(rr.startDate) || nextDate.IsZero() {
+		from := truncateToDay(startDate)
+		if today = truncateToDay(today); today.After(from) {
+			from = today
+		}
+		nextDate = recurrence.firstOnOrAfter(from)
+	}
+
+	return newRecurringRecord(
+		rr.id,
+		rr.accountId,
+		note,
+		category,
+		amount,
+		recordType,
+		beneficiaryId,
+		exchangeRate,
+		recurrence,
+		startDate,
+		endDate,
+		nextDate,
+		rr.paused,
+		auditInfo,
+	)
+}
+
+// Pause stops records from being created until the recurring record is resumed.
+func (rr RecurringRecord) Pause(updatedBy UpdatedBy) (RecurringRecord, error) {
+	return rr.withNextDate(rr.nextDate, true, updatedBy)
+}
+
+// Resume continues creating records from the first occurrence on or after today.
+// Occurrences missed while the recurring record was paused are not created.
+func (rr RecurringRecord) Resume(today time.Time, updatedBy UpdatedBy) (RecurringRecord, error) {
+	nextDate := rr.nextDate
+	for today = truncateToDay(today); !nextDate.IsZero() && nextDate.Before(today); {
+		nextDate = rr.recurrence.next(nextDate, rr.startDate)
+	}
+	return rr.withNextDate(nextDate, false, updatedBy)
+}
+
+// Skip moves the next occurrence forward without creating a record for it.
+func (rr RecurringRecord) Skip(updatedBy UpdatedBy) (RecurringRecord, error) {
+	if rr.HasEnded() {
+		return RecurringRecord{}, pkg.ValidationErrorWithFields(pkg.ErrRecurringRecordValidation, fmt.Sprintf("Recurring record %d has ended", rr.id), nil, map[string]string{
+			"nextDate": "a recurring record that has ended has no occurrence to skip",
+		})
+	}
+	return rr.withNextDate(rr.recurrence.next(rr.nextDate, rr.startDate), rr.paused, updatedBy)
+}
+
+// Advance moves the next occurrence forward once a record has been created for it.
+func (rr RecurringRecord) Advance(updatedBy UpdatedBy) (RecurringRecord, error) {
+	return rr.Skip(updatedBy)
+}
+
+func (rr RecurringRecord) withNextDate(nextDate time.Time, paused bool, updatedBy UpdatedBy) (RecurringRecord, error) {
+	var (
+		auditInfo auditInfo
+		err       error
+	)
+
+	if auditInfo, err = makeAuditForUpdate(rr.auditInfo, updatedBy); err != nil {
+		return RecurringRecord{}, err
+	}
+
+	return newRecurringRecord(
+		rr.id,
+		rr.accountId,
+		rr.note,
+		rr.category,
+		rr.amount,
+		rr.recordType,
+		rr.beneficiaryId,
+		rr.exchangeRate,
+		rr.recurrence,
+		rr.startDate,
+		rr.endDate,
+		nextDate,
+		paused,
+		auditInfo,
+	)
+}
+
+// IsDue is true when a record should be created for the next occurrence.
+func (rr RecurringRecord) IsDue(today time.Time) bool {
+	return !rr.paused && !rr.HasEnded() && !rr.nextDate.After(truncateToDay(today))
+}
+
+func (rr RecurringRecord) HasEnded() bool {
+	return rr.nextDate.IsZero()
+}
+
+func (rr RecurringRecord) Id() RecurringRecordId {
+	return rr.id
+}
+
+func (rr RecurringRecord) AccountId() AccountId {
+	return rr.accountId
+}
+
+func (rr RecurringRecord) Note() string {
+	return rr.note
+}
+
+func (rr RecurringRecord) Category() Category {
+	return rr.category
+}
+
+// Amount is always positive. The sign of the records created depends on the record type.
+func (rr RecurringRecord) Amount() Money {
+	return rr.amount
+}
+
+func (rr RecurringRecord) RecordType() RecordType {
+	return rr.recordType
+}
+
+func (rr RecurringRecord) BeneficiaryId() AccountId {
+	return rr.beneficiaryId
+}
+
+func (rr RecurringRecord) ExchangeRate() ExchangeRate {
+	return rr.exchangeRate
+}
+
+func (rr RecurringRecord) Recurrence() Recurrence {
+	return rr.recurrence
+}
+
+func (rr RecurringRecord) StartDate() time.Time {
+	return rr.startDate
+}
+
+// EndDate is zero when the recurring record does not end.
+func (rr RecurringRecord) EndDate() time.Time {
+	return rr.endDate
+}
+
+// NextDate is zero when the recurring record has ended.
+func (rr RecurringRecord) NextDate() time.Time {
+	return rr.nextDate
+}
+
+func (rr RecurringRecord) IsPaused() bool {
+	return rr.paused
+}
+
+func (rr RecurringRecord) String() string {
+	return fmt.Sprintf("RecurringRecord{id: %d, accountId: %d, note: %s, category: %s, amount: %s, type: %s, recurrence: %s, nextDate: %s, paused: %t}",
+		rr.id,
+		rr.accountId,
+		rr.note,
+		rr.category,
+		rr.amount,
+		rr.recordType,
+		rr.recurrence,
+		rr.nextDate.Format("2006-01-02"),
+		rr.paused,
+	)
+}
+
+type RecurringRecords []RecurringRecord
+
+func (rrs RecurringRecords) String() string {
+	strs := make([]string, 0, len(rrs))
+	for _, rr := range rrs {
+		strs = append(strs, rr.String())
+	}
+	return fmt.Sprintf("RecurringRecords{%s}", strings.Join(strs, ", "))
+}
+
+type recurringBeneficiaryValidator struct {
+	BeneficiaryId AccountId
+	AccountId     AccountId
+	RecordType    RecordType
+}
+
+func (v *recurringBeneficiaryValidator) IsValid(errors *validate.Errors) {
+	if v.RecordType == Transfer && v.BeneficiaryId <= 0 {
+		errors.Add("beneficiaryId", fmt.Sprintf("beneficiaryId can not be <= 0 when record type is %s", Transfer))
+	}
+	if v.RecordType == Transfer && v.BeneficiaryId == v.AccountId {
+		errors.Add("beneficiaryId", "beneficiaryId must not be the account of the record")
+	}
+	if v.RecordType != Transfer && v.BeneficiaryId > 0 {
+		errors.Add("beneficiaryId", fmt.Sprintf("beneficiaryId must be 0 when record type is %q", v.RecordType))
+	}
+}
+
+type endDateValidator struct {
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+func (v *endDateValidator) IsValid(errors *validate.Errors) {
+	if !v.EndDate.IsZero() && v.EndDate.Before(v.StartDate) {
+		errors.Add("endDate", "endDate must not be before startDate")
+	}
+}
@@ -0,0 +1,187 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type RecurringRecordTestSuite struct {
+	suite.Suite
+	rentCategory Category
+}
+
+func TestRecurringRecordTestSuite(t *testing.T) {
+	suite.Run(t, new(RecurringRecordTestSuite))
+}
+
+func (suite *RecurringRecordTestSuite) SetupTest() {
+	suite.rentCategory, _ = NewCategory(CategoryId(1), "Rent", MustMakeUpdatedByUserId(UserId(1)))
+}
+
+func (suite *RecurringRecordTestSuite) recurringRecord(recurrence Recurrence, startDate time.Time, endDate time.Time) RecurringRecord {
+	rr, err := NewRecurringRecord(
+		RecurringRecordId(1),
+		AccountId(1),
+		"Rent",
+		suite.rentCategory,
+		MustMoney(NewMoney("AED", -500000)),
+		Expense,
+		NoBeneficiaryAccount,
+		NoExchangeRate,
+		recurrence,
+		startDate,
+		endDate,
+		MustMakeUpdatedByUserId(UserId(1)),
+	)
+	assert.Nil(suite.T(), err)
+	return rr
+}
+
+func utcDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// -- SUITE
+
+func (suite *RecurringRecordTestSuite) Test_GIVEN_recurrences_WHEN_occurrencesAreCalculated_THEN_occurrencesAreCorrect() {
+	testCases := []struct {
+		name       string
+		frequency  Frequency
+		dayOfMonth int
+		startDate  time.Time
+		expected   []time.Time
+	}{
+		{
+			name:      "daily",
+			frequency: Daily,
+			startDate: utcDate(2021, time.December, 30),
+			expected:  []time.Time{utcDate(2021, time.December, 30), utcDate(2021, time.December, 31), utcDate(2022, time.January, 1)},
+		},
+		{
+			name:      "weekly",
+			frequency: Weekly,
+			startDate: utcDate(2021, time.July, 5),
+			expected:  []time.Time{utcDate(2021, time.July, 5), utcDate(2021, time.July, 12), utcDate(2021, time.July, 19)},
+		},
+		{
+			name:       "monthly on a day that is not in every month",
+			frequency:  Monthly,
+			dayOfMonth: 31,
+			startDate:  utcDate(2021, time.January, 5),
+			expected:   []time.Time{utcDate(2021, time.January, 31), utcDate(2021, time.February, 28), utcDate(2021, time.March, 31), utcDate(2021, time.April, 30)},
+		},
+		{
+			name:       "monthly on a day that has passed in the month of the start date",
+			frequency:  Monthly,
+			dayOfMonth: 1,
+			startDate:  utcDate(2021, time.December, 5),
+			expected:   []time.Time{utcDate(2022, time.January, 1), utcDate(2022, time.February, 1)},
+		},
+		{
+			name:      "last business day",
+			frequency: LastBusinessDay,
+			startDate: utcDate(2021, time.July, 5),
+			expected:  []time.Time{utcDate(2021, time.July, 30), utcDate(2021, time.August, 31), utcDate(2021, time.September, 30), utcDate(2021, time.October, 29)},
+		},
+		{
+			name:      "yearly on a leap day",
+			frequency: Yearly,
+			startDate: utcDate(2020, time.February, 29),
+			expected:  []time.Time{utcDate(2020, time.February, 29), utcDate(2021, time.February, 28), utcDate(2022, time.February, 28), utcDate(2023, time.February, 28), utcDate(2024, time.February, 29)},
+		},
+	}
+
+	for _, testCase := range testCases {
+		suite.Run(testCase.name, func() {
+			// GIVEN
+			recurrence, err := NewRecurrence(testCase.frequency, testCase.dayOfMonth)
+			assert.Nil(suite.T(), err)
+			rr := suite.recurringRecord(recurrence, testCase.startDate, NoEndDate)
+
+			// WHEN
+			occurrences := []time.Time{}
+			for len(occurrences) < len(testCase.expected) {
+				occurrences = append(occurrences, rr.NextDate())
+				rr, _ = rr.Advance(MustMakeUpdatedByUserId(UserId(1)))
+			}
+
+			// THEN
+			assert.Equal(suite.T(), testCase.expected, occurrences)
+		})
+	}
+}
+
+func (suite *RecurringRecordTestSuite) Test_GIVEN_aMonthlyRecurrenceWithoutDayOfMonth_WHEN_recurrenceIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewRecurrence(Monthly, 0)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrRecurringRecordValidation, errorCode(err, 0))
+	assert.Equal(suite.T(), "dayOfMonth must be between 1 and 31 when frequency is MONTHLY", errorFields(err)["dayOfMonth"])
+}
+
+func (suite *RecurringRecordTestSuite) Test_GIVEN_anExpense_WHEN_recurringRecordIsCreated_THEN_amountIsPositive() {
+	// WHEN
+	rr := suite.recurringRecord(Recurrence{frequency: Weekly}, utcDate(2021, time.July, 5), NoEndDate)
+
+	// THEN
+	assert.Equal(suite.T(), "AED 5000.00", rr.Amount().String())
+}
+
+func (suite *RecurringRecordTestSuite) Test_GIVEN_anEndDate_WHEN_lastOccurrenceIsAdvanced_THEN_recurringRecordHasEndedAndIsNotDue() {
+	// GIVEN
+	rr := suite.recurringRecord(Recurrence{frequency: Weekly}, utcDate(2021, time.July, 5), utcDate(2021, time.July, 15))
+
+	// WHEN
+	rr, _ = rr.Advance(MustMakeUpdatedByUserId(UserId(1)))
+	assert.False(suite.T(), rr.HasEnded())
+	rr, _ = rr.Advance(MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.True(suite.T(), rr.HasEnded())
+	assert.False(suite.T(), rr.IsDue(utcDate(2021, time.December, 1)))
+}
+
+func (suite *RecurringRecordTestSuite) Test_GIVEN_aPausedRecurringRecord_WHEN_resumed_THEN_missedOccurrencesAreNotDue() {
+	// GIVEN
+	rr := suite.recurringRecord(Recurrence{frequency: Weekly}, utcDate(2021, time.July, 5), NoEndDate)
+	rr, _ = rr.Pause(MustMakeUpdatedByUserId(UserId(1)))
+	assert.False(suite.T(), rr.IsDue(utcDate(2021, time.July, 5)))
+
+	// WHEN
+	rr, err := rr.Resume(utcDate(2021, time.July, 20), MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), rr.IsPaused())
+	assert.Equal(suite.T(), utcDate(2021, time.July, 26), rr.NextDate())
+	assert.False(suite.T(), rr.IsDue(utcDate(2021, time.July, 20)))
+	assert.True(suite.T(), rr.IsDue(utcDate(2021, time.July, 26)))
+}
+
+func (suite *RecurringRecordTestSuite) Test_GIVEN_aTransferToTheSameAccount_WHEN_recurringRecordIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewRecurringRecord(
+		RecurringRecordId(1),
+		AccountId(1),
+		"Savings",
+		suite.rentCategory,
+		MustMoney(NewMoney("AED", 500000)),
+		Transfer,
+		AccountId(1),
+		NoExchangeRate,
+		Recurrence{frequency: Monthly, dayOfMonth: 1},
+		utcDate(2021, time.July, 5),
+		NoEndDate,
+		MustMakeUpdatedByUserId(UserId(1)),
+	)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "beneficiaryId must not be the account of the record", errorFields(err)["beneficiaryId"])
+}
@@ -0,0 +1,294 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+// TransferPair is the pair of records written when money is transferred from one account to another.
+// The debit is recorded in the source account and the credit in the beneficiary account.
+// Both records share the same TransferReference and are always changed together.
+type TransferPair struct {
+	debit  Record
+	credit Record
+}
+
+// NewTransferPair pairs the two records of a transfer, in any order.
+func NewTransferPair(first Record, second Record) (TransferPair, error) {
+	debit, credit := first, second
+	if debit.Amount() != nil && debit.Amount().IsPositive() {
+		debit, credit = second, first
+	}
+
+	errors := validate.Validate(
+		&transferLegsValidator{Debit: debit, Credit: credit},
+	)
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrRecordValidation, "", errors); err != nil {
+		return TransferPair{}, err
+	}
+
+	return TransferPair{
+		debit:  debit,
+		credit: credit,
+	}, nil
+}
+
+func (t TransferPair) Debit() Record {
+	return t.debit
+}
+
+func (t TransferPair) Credit() Record {
+	return t.credit
+}
+
+func (t TransferPair) Reference() TransferReference {
+	return t.debit.TransferReference()
+}
+
+func (t TransferPair) SourceAccountId() AccountId {
+	return t.debit.SourceAccountId()
+}
+
+func (t TransferPair) BeneficiaryId() AccountId {
+	return t.debit.BeneficiaryId()
+}
+
+// Leg returns the record of the transfer that is recorded in the given account.
+func (t TransferPair) Leg(accountId AccountId) (Record, bool) {
+	switch accountId {
+	case t.SourceAccountId():
+		return t.debit, true
+	case t.BeneficiaryId():
+		return t.credit, true
+	default:
+		return Record{}, false
+	}
+}
+
+// NewTransfer creates both records of a transfer of amount from the source account to the beneficiary account.
+// The amount is in the currency of the source account and is debited regardless of its sign.
+// When the accounts have different currencies, the amount credited is either the received amount
+// or the amount converted at the given exchange rate; exactly one of the two must be provided.
+func NewTransfer(
+	debitId RecordId,
+	creditId RecordId,
+	note string,
+	category Category,
+	amount Money,
+	received Money,
+	rate ExchangeRate,
+	date time.Time,
+	source Account,
+	beneficiary Account,
+	updatedBy UpdatedBy,
+) (TransferPair, error) {
+	var (
+		debitAmount  Money
+		creditAmount Money
+		auditInfo    auditInfo
+		debit        Record
+		credit       Record
+		err          error
+	)
+
+	if debitAmount, creditAmount, rate, err = transferAmounts(amount, received, rate, source.Currency(), beneficiary.Currency()); err != nil {
+		return TransferPair{}, err
+	}
+
+	if auditInfo, err = makeAuditForCreation(updatedBy); err != nil {
+		return TransferPair{}, err
+	}
+
+	reference := MakeTransferReference()
+	if debit, err = newRecord(debitId, note, category, debitAmount, startOfDay(date), date.UTC(), Transfer, source.Id(), beneficiary.Id(), beneficiary.Type(), reference, rate, NoSplits, auditInfo); err != nil {
+		return TransferPair{}, err
+	}
+	if credit, err = newRecord(creditId, note, category, creditAmount, startOfDay(date), date.UTC(), Transfer, source.Id(), beneficiary.Id(), beneficiary.Type(), reference, rate, NoSplits, auditInfo); err != nil {
+		return TransferPair{}, err
+	}
+
+	return NewTransferPair(debit, credit)
+}
+
+// ExchangeRate is only set when the source and beneficiary accounts have different currencies.
+func (t TransferPair) ExchangeRate() ExchangeRate {
+	return t.debit.ExchangeRate()
+}
+
+// Edit changes both records of the transfer.
+// The amount is debited from the source account and credited to the beneficiary account, regardless of its sign.
+// When neither a received amount nor an exchange rate is provided, the current exchange rate is kept
+// unless the beneficiary account has a different currency than before. If the amount sent is not changed either,
+// the amount received is kept as it is, rather than converted again at the rate.
+func (t TransferPair) Edit(
+	note string,
+	category Category,
+	amount Money,
+	received Money,
+	rate ExchangeRate,
+	date time.Time,
+	beneficiary Account,
+	updatedBy UpdatedBy,
+) (TransferPair, error) {
+	var (
+		debitAmount  Money
+		creditAmount Money
+		debit        Record
+		credit       Record
+		err          error
+	)
+
+	keepExchange := received == nil && len(rate) == 0 && beneficiary.Currency() == t.credit.Amount().Currency().CurrencyCode()
+	if keepExchange && sameAbsoluteAmount(amount, t.debit.Amount()) {
+		debitAmount, creditAmount, rate = t.debit.Amount(), t.credit.Amount(), t.ExchangeRate()
+	} else {
+		if keepExchange {
+			rate = t.ExchangeRate()
+		}
+		sourceCurrency := t.debit.Amount().Currency().CurrencyCode()
+		if debitAmount, creditAmount, rate, err = transferAmounts(amount, received, rate, sourceCurrency, beneficiary.Currency()); err != nil {
+			return TransferPair{}, err
+		}
+	}
+
+	if debit, err = t.debit.edit(note, category, debitAmount, date, Transfer, beneficiary.Id(), beneficiary.Type(), rate, NoSplits, updatedBy); err != nil {
+		return TransferPair{}, err
+	}
+	if credit, err = t.credit.edit(note, category, creditAmount, date, Transfer, beneficiary.Id(), beneficiary.Type(), rate, NoSplits, updatedBy); err != nil {
+		return TransferPair{}, err
+	}
+
+	return NewTransferPair(debit, credit)
+}
+
+// transferAmounts returns the amount debited from the source account and the amount credited to the beneficiary account,
+// along with the exchange rate between the two.
+func transferAmounts(
+	amount Money,
+	received Money,
+	rate ExchangeRate,
+	sourceCurrency string,
+	beneficiaryCurrency string,
+) (Money, Money, ExchangeRate, error) {
+	var (
+		debitAmount  Money
+		creditAmount Money
+		err          error
+	)
+
+	if amount == nil {
+		return nil, nil, NoExchangeRate, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, "Amount is required", nil, map[string]string{"amount": "Amount is required"})
+	}
+
+	if amount.Currency().CurrencyCode() != sourceCurrency {
+		return nil, nil, NoExchangeRate, pkg.ValidationErrorWithFields(pkg.ErrAmountMismatchingCurrencies, "Amount must be in the currency of the source account", nil, map[string]string{
+			"amount": fmt.Sprintf("amount must be in %s, the currency of the source account", sourceCurrency),
+		})
+	}
+
+	if debitAmount, err = amount.Negate(); err != nil {
+		return nil, nil, NoExchangeRate, err
+	}
+	if creditAmount, err = amount.Abs(); err != nil {
+		return nil, nil, NoExchangeRate, err
+	}
+
+	if sourceCurrency == beneficiaryCurrency {
+		if received != nil || len(rate) > 0 {
+			return nil, nil, NoExchangeRate, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, "Accounts have the same currency", nil, map[string]string{
+				"exchangeRate": "exchangeRate and receivedAmount can only be provided when the accounts have different currencies",
+			})
+		}
+		return debitAmount, creditAmount, NoExchangeRate, nil
+	}
+
+	switch {
+	case received != nil && len(rate) > 0:
+		return nil, nil, NoExchangeRate, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, "Either the received amount or the exchange rate must be provided, not both", nil, map[string]string{
+			"exchangeRate": "exchangeRate can not be provided along with receivedAmount",
+		})
+	case received != nil:
+		if received.Currency().CurrencyCode() != beneficiaryCurrency {
+			return nil, nil, NoExchangeRate, pkg.ValidationErrorWithFields(pkg.ErrAmountMismatchingCurrencies, "Received amount must be in the currency of the beneficiary account", nil, map[string]string{
+				"receivedAmount": fmt.Sprintf("receivedAmount must be in %s, the currency of the beneficiary account", beneficiaryCurrency),
+			})
+		}
+		if received, err = received.Abs(); err != nil {
+			return nil, nil, NoExchangeRate, err
+		}
+		if rate, err = ExchangeRateBetween(creditAmount, received); err != nil {
+			return nil, nil, NoExchangeRate, err
+		}
+		return debitAmount, received, rate, nil
+	case len(rate) > 0:
+		if rate, err = ParseExchangeRate(string(rate)); err != nil {
+			return nil, nil, NoExchangeRate, err
+		}
+		if creditAmount, err = creditAmount.Convert(beneficiaryCurrency, rate); err != nil {
+			return nil, nil, NoExchangeRate, err
+		}
+		return debitAmount, creditAmount, rate, nil
+	default:
+		return nil, nil, NoExchangeRate, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, fmt.Sprintf("An exchange rate is required to transfer from %s to %s", sourceCurrency, beneficiaryCurrency), nil, map[string]string{
+			"exchangeRate": "exchangeRate or receivedAmount is required when the accounts have different currencies",
+		})
+	}
+}
+
+// sameAbsoluteAmount is true when both amounts are of the same currency and size, regardless of their signs
+func sameAbsoluteAmount(a Money, b Money) bool {
+	if a == nil || b == nil || a.Currency().CurrencyCode() != b.Currency().CurrencyCode() {
+		return false
+	}
+	aMinorUnits, aErr := a.MinorUnits()
+	bMinorUnits, bErr := b.MinorUnits()
+	if aErr != nil || bErr != nil {
+		return false
+	}
+	if aMinorUnits < 0 {
+		aMinorUnits = -aMinorUnits
+	}
+	if bMinorUnits < 0 {
+		bMinorUnits = -bMinorUnits
+	}
+	return aMinorUnits == bMinorUnits
+}
+
+type transferLegsValidator struct {
+	Debit  Record
+	Credit Record
+}
+
+func (v *transferLegsValidator) IsValid(errors *validate.Errors) {
+	if v.Debit.Type() != Transfer || v.Credit.Type() != Transfer {
+		errors.Add("type", fmt.Sprintf("both records of a transfer must be of type %s", Transfer))
+		return
+	}
+	if len(v.Debit.TransferReference()) == 0 || v.Debit.TransferReference() != v.Credit.TransferReference() {
+		errors.Add("transferReference", "both records of a transfer must have the same transfer reference")
+	}
+	if v.Debit.SourceAccountId() != v.Credit.SourceAccountId() || v.Debit.BeneficiaryId() != v.Credit.BeneficiaryId() {
+		errors.Add("beneficiaryId", "both records of a transfer must have the same source and beneficiary accounts")
+	}
+	if v.Debit.SourceAccountId() == v.Debit.BeneficiaryId() {
+		errors.Add("beneficiaryId", "money can not be transferred to the source account")
+	}
+	if v.Debit.Amount() == nil || v.Credit.Amount() == nil || !v.Debit.Amount().IsNegative() || !v.Credit.Amount().IsPositive() {
+		errors.Add("amount", "a transfer must debit the source account and credit the beneficiary account")
+		return
+	}
+	if v.Debit.ExchangeRate() != v.Credit.ExchangeRate() {
+		errors.Add("exchangeRate", "both records of a transfer must have the same exchange rate")
+	}
+	sameCurrency := v.Debit.Amount().Currency().CurrencyCode() == v.Credit.Amount().Currency().CurrencyCode()
+	if !sameCurrency && len(v.Debit.ExchangeRate()) == 0 {
+		errors.Add("exchangeRate", "an exchange rate is required when the accounts have different currencies")
+	}
+	if sameCurrency && len(v.Debit.ExchangeRate()) > 0 {
+		errors.Add("exchangeRate", "an exchange rate can only be set when the accounts have different currencies")
+	}
+}
@@ -0,0 +1,207 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type TransferPairTestSuite struct {
+	suite.Suite
+	savingsCategory Category
+	debit           Record
+	credit          Record
+}
+
+func TestTransferPairTestSuite(t *testing.T) {
+	suite.Run(t, new(TransferPairTestSuite))
+}
+
+func (suite *TransferPairTestSuite) SetupTest() {
+	category, _ := NewCategory(CategoryId(1), "Savings", MustMakeUpdatedByUserId(UserId(1)))
+	debitAmount, _ := NewMoney("AED", -20000)
+	creditAmount, _ := NewMoney("AED", 20000)
+	reference := MakeTransferReference()
+	date := time.Date(2021, time.July, 2, 0, 0, 0, 0, time.UTC)
+
+	suite.savingsCategory = category
+	suite.debit, _ = NewRecord(RecordId(1), "Savings", category, debitAmount, date, Transfer, AccountId(1), AccountId(2), AccountTypeSaving, reference, MustMakeUpdatedByUserId(UserId(1)))
+	suite.credit, _ = NewRecord(RecordId(2), "Savings", category, creditAmount, date, Transfer, AccountId(1), AccountId(2), AccountTypeSaving, reference, MustMakeUpdatedByUserId(UserId(1)))
+}
+
+// -- SUITE
+
+func (suite *TransferPairTestSuite) Test_GIVEN_creditAndDebitInAnyOrder_WHEN_transferPairIsCreated_THEN_legsAreIdentified() {
+	// WHEN
+	transfer, err := NewTransferPair(suite.credit, suite.debit)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), RecordId(1), transfer.Debit().Id())
+	assert.Equal(suite.T(), RecordId(2), transfer.Credit().Id())
+	assert.Equal(suite.T(), suite.debit.TransferReference(), transfer.Reference())
+
+	sourceLeg, ok := transfer.Leg(AccountId(1))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), RecordId(1), sourceLeg.Id())
+
+	_, ok = transfer.Leg(AccountId(3))
+	assert.False(suite.T(), ok)
+}
+
+func (suite *TransferPairTestSuite) Test_GIVEN_recordsWithDifferentReferences_WHEN_transferPairIsCreated_THEN_errorIsReturned() {
+	// GIVEN
+	otherCredit, _ := NewRecord(RecordId(3), "Savings", suite.savingsCategory, suite.credit.Amount(), suite.credit.DateUTC(), Transfer, AccountId(1), AccountId(2), AccountTypeSaving, MakeTransferReference(), MustMakeUpdatedByUserId(UserId(1)))
+
+	// WHEN
+	transfer, err := NewTransferPair(suite.debit, otherCredit)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), TransferPair{}, transfer)
+	assert.Equal(suite.T(), pkg.ErrRecordValidation, errorCode(err, 0))
+	assert.Equal(suite.T(), "both records of a transfer must have the same transfer reference", errorFields(err)["transferReference"])
+}
+
+func (suite *TransferPairTestSuite) Test_GIVEN_aTransferPair_WHEN_edited_THEN_bothLegsAreChanged() {
+	// GIVEN
+	transfer, _ := NewTransferPair(suite.debit, suite.credit)
+	amount, _ := NewMoney("AED", -50000)
+	date := time.Date(2021, time.July, 10, 0, 0, 0, 0, time.UTC)
+	beneficiary, _ := NewAccount(AccountId(3), "Current", AccountTypeCurrent, "AED", MustMakeUpdatedByUserId(UserId(1)))
+
+	// WHEN
+	edited, err := transfer.Edit("Emergency Fund", suite.savingsCategory, amount, nil, NoExchangeRate, date, beneficiary, MustMakeUpdatedByUserId(UserId(2)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	for _, leg := range []Record{edited.Debit(), edited.Credit()} {
+		assert.Equal(suite.T(), "Emergency Fund", leg.Note())
+		assert.Equal(suite.T(), date, leg.DateUTC())
+		assert.Equal(suite.T(), AccountId(1), leg.SourceAccountId())
+		assert.Equal(suite.T(), AccountId(3), leg.BeneficiaryId())
+		assert.Equal(suite.T(), AccountTypeCurrent, leg.BeneficiaryType())
+		assert.Equal(suite.T(), MustMakeUpdatedByUserId(UserId(2)), leg.ModifiedBy())
+		assert.Equal(suite.T(), transfer.Reference(), leg.TransferReference())
+	}
+	assert.Equal(suite.T(), "AED -500.00", edited.Debit().Amount().String())
+	assert.Equal(suite.T(), "AED 500.00", edited.Credit().Amount().String())
+}
+
+func (suite *TransferPairTestSuite) Test_GIVEN_aTransferPair_WHEN_editedToTransferToSourceAccount_THEN_errorIsReturned() {
+	// GIVEN
+	transfer, _ := NewTransferPair(suite.debit, suite.credit)
+	source, _ := NewAccount(AccountId(1), "Current", AccountTypeCurrent, "AED", MustMakeUpdatedByUserId(UserId(1)))
+
+	// WHEN
+	_, err := transfer.Edit("Savings", suite.savingsCategory, suite.credit.Amount(), nil, NoExchangeRate, suite.credit.DateUTC(), source, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "money can not be transferred to the source account", errorFields(err)["beneficiaryId"])
+}
+
+func (suite *TransferPairTestSuite) Test_GIVEN_accountsWithDifferentCurrencies_WHEN_transferIsCreatedWithExchangeRate_THEN_creditIsConverted() {
+	// GIVEN
+	source, _ := NewAccount(AccountId(1), "Current", AccountTypeCurrent, "USD", MustMakeUpdatedByUserId(UserId(1)))
+	beneficiary, _ := NewAccount(AccountId(2), "Savings", AccountTypeSaving, "AED", MustMakeUpdatedByUserId(UserId(1)))
+	amount, _ := NewMoney("USD", 10000)
+
+	// WHEN
+	transfer, err := NewTransfer(RecordId(1), RecordId(2), "Savings", suite.savingsCategory, amount, nil, ExchangeRate("3.6725"), suite.debit.DateUTC(), source, beneficiary, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "USD -100.00", transfer.Debit().Amount().String())
+	assert.Equal(suite.T(), "AED 367.25", transfer.Credit().Amount().String())
+	assert.Equal(suite.T(), ExchangeRate("3.6725"), transfer.Debit().ExchangeRate())
+	assert.Equal(suite.T(), ExchangeRate("3.6725"), transfer.Credit().ExchangeRate())
+	assert.Equal(suite.T(), transfer.Debit().TransferReference(), transfer.Credit().TransferReference())
+}
+
+func (suite *TransferPairTestSuite) Test_GIVEN_accountsWithDifferentCurrencies_WHEN_transferIsCreatedWithReceivedAmount_THEN_exchangeRateIsCalculated() {
+	// GIVEN
+	source, _ := NewAccount(AccountId(1), "Current", AccountTypeCurrent, "USD", MustMakeUpdatedByUserId(UserId(1)))
+	beneficiary, _ := NewAccount(AccountId(2), "Savings", AccountTypeSaving, "JPY", MustMakeUpdatedByUserId(UserId(1)))
+	amount, _ := NewMoney("USD", -20000)
+	received, _ := NewMoney("JPY", 29000)
+
+	// WHEN
+	transfer, err := NewTransfer(RecordId(1), RecordId(2), "Savings", suite.savingsCategory, amount, received, NoExchangeRate, suite.debit.DateUTC(), source, beneficiary, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "USD -200.00", transfer.Debit().Amount().String())
+	assert.Equal(suite.T(), "JPY 29000", transfer.Credit().Amount().String())
+	assert.Equal(suite.T(), ExchangeRate("145"), transfer.ExchangeRate())
+}
+
+func (suite *TransferPairTestSuite) Test_GIVEN_accountsWithDifferentCurrencies_WHEN_transferIsCreatedWithoutExchangeRate_THEN_errorIsReturned() {
+	// GIVEN
+	source, _ := NewAccount(AccountId(1), "Current", AccountTypeCurrent, "USD", MustMakeUpdatedByUserId(UserId(1)))
+	beneficiary, _ := NewAccount(AccountId(2), "Savings", AccountTypeSaving, "AED", MustMakeUpdatedByUserId(UserId(1)))
+	amount, _ := NewMoney("USD", 10000)
+
+	// WHEN
+	_, err := NewTransfer(RecordId(1), RecordId(2), "Savings", suite.savingsCategory, amount, nil, NoExchangeRate, suite.debit.DateUTC(), source, beneficiary, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrRecordValidation, errorCode(err, 0))
+	assert.Equal(suite.T(), "exchangeRate or receivedAmount is required when the accounts have different currencies", errorFields(err)["exchangeRate"])
+}
+
+func (suite *TransferPairTestSuite) Test_GIVEN_amountInDifferentCurrencyThanSourceAccount_WHEN_transferIsCreated_THEN_errorIsReturned() {
+	// GIVEN
+	source, _ := NewAccount(AccountId(1), "Current", AccountTypeCurrent, "AED", MustMakeUpdatedByUserId(UserId(1)))
+	beneficiary, _ := NewAccount(AccountId(2), "Savings", AccountTypeSaving, "AED", MustMakeUpdatedByUserId(UserId(1)))
+	amount, _ := NewMoney("USD", 10000)
+
+	// WHEN
+	_, err := NewTransfer(RecordId(1), RecordId(2), "Savings", suite.savingsCategory, amount, nil, NoExchangeRate, suite.debit.DateUTC(), source, beneficiary, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), pkg.ErrAmountMismatchingCurrencies, errorCode(err, 0))
+	assert.Equal(suite.T(), "amount must be in AED, the currency of the source account", errorFields(err)["amount"])
+}
+
+func (suite *TransferPairTestSuite) Test_GIVEN_aCrossCurrencyTransfer_WHEN_amountIsEdited_THEN_exchangeRateIsKept() {
+	// GIVEN
+	source, _ := NewAccount(AccountId(1), "Current", AccountTypeCurrent, "USD", MustMakeUpdatedByUserId(UserId(1)))
+	beneficiary, _ := NewAccount(AccountId(2), "Savings", AccountTypeSaving, "AED", MustMakeUpdatedByUserId(UserId(1)))
+	amount, _ := NewMoney("USD", 10000)
+	transfer, _ := NewTransfer(RecordId(1), RecordId(2), "Savings", suite.savingsCategory, amount, nil, ExchangeRate("3.6725"), suite.debit.DateUTC(), source, beneficiary, MustMakeUpdatedByUserId(UserId(1)))
+	newAmount, _ := NewMoney("USD", 20000)
+
+	// WHEN
+	edited, err := transfer.Edit("Savings", suite.savingsCategory, newAmount, nil, NoExchangeRate, suite.debit.DateUTC(), beneficiary, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "USD -200.00", edited.Debit().Amount().String())
+	assert.Equal(suite.T(), "AED 734.50", edited.Credit().Amount().String())
+	assert.Equal(suite.T(), ExchangeRate("3.6725"), edited.ExchangeRate())
+}
+
+func (suite *TransferPairTestSuite) Test_GIVEN_aCrossCurrencyTransfer_WHEN_editedWithoutChangingTheAmount_THEN_amountReceivedIsKept() {
+	// GIVEN
+	source, _ := NewAccount(AccountId(1), "Current", AccountTypeCurrent, "USD", MustMakeUpdatedByUserId(UserId(1)))
+	beneficiary, _ := NewAccount(AccountId(2), "Savings", AccountTypeSaving, "AED", MustMakeUpdatedByUserId(UserId(1)))
+	amount, _ := NewMoney("USD", 30000000000)
+	received, _ := NewMoney("AED", 100000000000)
+	transfer, _ := NewTransfer(RecordId(1), RecordId(2), "Savings", suite.savingsCategory, amount, received, NoExchangeRate, suite.debit.DateUTC(), source, beneficiary, MustMakeUpdatedByUserId(UserId(1)))
+
+	// WHEN
+	edited, err := transfer.Edit("Emergency Fund", suite.savingsCategory, amount, nil, NoExchangeRate, suite.debit.DateUTC(), beneficiary, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Emergency Fund", edited.Credit().Note())
+	assert.Equal(suite.T(), "USD -300000000.00", edited.Debit().Amount().String())
+	assert.Equal(suite.T(), "AED 1000000000.00", edited.Credit().Amount().String())
+	assert.Equal(suite.T(), transfer.ExchangeRate(), edited.ExchangeRate())
+}
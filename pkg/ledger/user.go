@@ -11,13 +11,17 @@ import (
 type UserId uint64
 type User struct {
 	auditInfo
-	id    UserId
-	email *mail.Address
+	id          UserId
+	email       *mail.Address
+	profile     UserProfile
+	preferences UserPreferences
 }
 
 type UserRecord interface {
 	Id() UserId
 	Email() *mail.Address
+	Profile() UserProfile
+	Preferences() UserPreferences
 	CreatedBy() UpdatedBy
 	CreatedAtUTC() time.Time
 	ModifiedBy() UpdatedBy
@@ -26,6 +30,23 @@ type UserRecord interface {
 }
 
 func NewUser(id UserId, email *mail.Address) (User, error) {
+	return newUserWithProfile(id, email, DefaultUserProfile())
+}
+
+func NewUserWithEmailString(id UserId, emailString string) (User, error) {
+	return NewUserWithProfile(id, emailString, DefaultUserProfile())
+}
+
+// NewUserWithProfile creates a user whose dates are read and shown according to the given profile.
+func NewUserWithProfile(id UserId, emailString string, profile UserProfile) (User, error) {
+	email, err := mail.ParseAddress(emailString)
+	if err != nil {
+		return User{}, pkg.ValidationErrorWithFields(pkg.ErrUserEmailInvalid, "", err, nil)
+	}
+	return newUserWithProfile(id, email, profile)
+}
+
+func newUserWithProfile(id UserId, email *mail.Address, profile UserProfile) (User, error) {
 	var (
 		updatedBy UpdatedBy
 		audit     auditInfo
@@ -38,15 +59,7 @@ func NewUser(id UserId, email *mail.Address) (User, error) {
 		return User{}, nil
 	}
 
-	return newUser(id, email, audit), nil
-}
-
-func NewUserWithEmailString(id UserId, emailString string) (User, error) {
-	email, err := mail.ParseAddress(emailString)
-	if err != nil {
-		return User{}, pkg.ValidationErrorWithFields(pkg.ErrUserEmailInvalid, "", err, nil)
-	}
-	return NewUser(id, email)
+	return newUser(id, email, profile, UserPreferences{}, audit), nil
 }
 
 func NewUserFromRecord(record UserRecord) (User, error) {
@@ -65,15 +78,28 @@ func NewUserFromRecord(record UserRecord) (User, error) {
 		return User{}, err
 	}
 
-	return newUser(record.Id(), record.Email(), auditInfo), nil
+	return newUser(record.Id(), record.Email(), record.Profile(), record.Preferences(), auditInfo), nil
 }
 
-func newUser(id UserId, email *mail.Address, auditInfo auditInfo) User {
+func newUser(id UserId, email *mail.Address, profile UserProfile, preferences UserPreferences, auditInfo auditInfo) User {
 	return User{
-		auditInfo: auditInfo,
-		id:        id,
-		email:     email,
+		auditInfo:   auditInfo,
+		id:          id,
+		email:       email,
+		profile:     profile,
+		preferences: preferences,
+	}
+}
+
+// Edit changes the profile and preferences of the user.
+// The version of the user is incremented when the user is saved.
+func (u User) Edit(profile UserProfile, preferences UserPreferences, updatedBy UpdatedBy) (User, error) {
+	auditInfo, err := makeAuditForUpdate(u.auditInfo, updatedBy)
+	if err != nil {
+		return User{}, err
 	}
+
+	return newUser(u.id, u.email, profile, preferences, auditInfo), nil
 }
 
 func (u User) Id() UserId {
@@ -84,6 +110,14 @@ func (u User) Email() *mail.Address {
 	return u.email
 }
 
+func (u User) Profile() UserProfile {
+	return u.profile
+}
+
+func (u User) Preferences() UserPreferences {
+	return u.preferences
+}
+
 func (u User) String() string {
 	return fmt.Sprintf("User{id: %d, email: %s}", u.id, u.email.Address)
 }
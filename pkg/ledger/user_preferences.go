@@ -0,0 +1,68 @@
+package ledger
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/gobuffalo/validate"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+const (
+	NoDefaultAccount = AccountId(0)
+
+	maxDisplayNameLength = 50
+)
+
+// UserPreferences are the defaults of a user, used in place of values that are not provided or can not be inferred
+// e.g. the currency of an amount described to ChatGPT, or the category of a record that is created without one.
+type UserPreferences struct {
+	displayName       string
+	currency          string
+	defaultAccountId  AccountId
+	defaultCategoryId CategoryId
+}
+
+// NewUserPreferences creates the preferences of a user. Every preference is optional:
+// the currency can be empty, and the default account and category can be NoDefaultAccount and NoDefaultCategory.
+func NewUserPreferences(displayName string, currency string, defaultAccountId AccountId, defaultCategoryId CategoryId) (UserPreferences, error) {
+	errors := validate.NewErrors()
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		errors.Add("displayName", fmt.Sprintf("displayName must be at most %d characters long", maxDisplayNameLength))
+	}
+	if len(currency) > 0 && !IsValidCurrency(currency) {
+		errors.Add("currency", fmt.Sprintf("No such currency '%s'", currency))
+	}
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrUserProfileValidation, "", errors); err != nil {
+		return UserPreferences{}, err
+	}
+
+	return UserPreferences{
+		displayName:       displayName,
+		currency:          currency,
+		defaultAccountId:  defaultAccountId,
+		defaultCategoryId: defaultCategoryId,
+	}, nil
+}
+
+func (p UserPreferences) DisplayName() string {
+	return p.displayName
+}
+
+// Currency is the home currency of the user, in which amounts are reported. It is empty if the user has not chosen one.
+func (p UserPreferences) Currency() string {
+	return p.currency
+}
+
+func (p UserPreferences) DefaultAccountId() AccountId {
+	return p.defaultAccountId
+}
+
+func (p UserPreferences) DefaultCategoryId() CategoryId {
+	return p.defaultCategoryId
+}
+
+func (p UserPreferences) String() string {
+	return fmt.Sprintf("UserPreferences{displayName: %s, currency: %s, defaultAccountId: %d, defaultCategoryId: %d}", p.displayName, p.currency, p.defaultAccountId, p.defaultCategoryId)
+}
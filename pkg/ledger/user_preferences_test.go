@@ -0,0 +1,50 @@
+package ledger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type UserPreferencesTestSuite struct {
+	suite.Suite
+}
+
+func TestUserPreferencesTestSuite(t *testing.T) {
+	suite.Run(t, new(UserPreferencesTestSuite))
+}
+
+// -- SUITE
+
+func (suite *UserPreferencesTestSuite) Test_GIVEN_noPreferences_WHEN_preferencesAreCreated_THEN_preferencesAreEmpty() {
+	// WHEN
+	preferences, err := NewUserPreferences("", "", NoDefaultAccount, NoDefaultCategory)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), UserPreferences{}, preferences)
+}
+
+func (suite *UserPreferencesTestSuite) Test_GIVEN_invalidPreferences_WHEN_preferencesAreCreated_THEN_errorIsReturned() {
+	for _, test := range []struct {
+		displayName string
+		currency    string
+		field       string
+	}{
+		{displayName: strings.Repeat("J", 51), currency: "AED", field: "displayName"},
+		{displayName: "Jack", currency: "XYZ", field: "currency"},
+		{displayName: "Jack", currency: "aed", field: "currency"},
+	} {
+		// WHEN
+		preferences, err := NewUserPreferences(test.displayName, test.currency, NoDefaultAccount, NoDefaultCategory)
+
+		// THEN
+		assert.NotNil(suite.T(), err, test.field)
+		assert.Equal(suite.T(), UserPreferences{}, preferences, test.field)
+		assert.Equal(suite.T(), pkg.ErrUserProfileValidation, errorCode(err, 0), test.field)
+		assert.Contains(suite.T(), errorFields(err), test.field, test.field)
+	}
+}
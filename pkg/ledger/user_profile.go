@@ -0,0 +1,181 @@
+package ledger
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	// The timezones of users are loaded from the embedded database, so that they do not depend on the timezones installed on the host
+	_ "time/tzdata"
+
+	"github.com/gobuffalo/validate"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+const (
+	DefaultTimezone  = "UTC"
+	DefaultLocale    = "en-US"
+	DefaultWeekStart = time.Monday
+)
+
+// localePattern matches BCP 47 language tags made of a language, and optionally a script and region e.g. en, en-GB, zh-Hant-TW
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z][a-z]{3})?(-([A-Z]{2}|[0-9]{3}))?$`)
+
+// UserProfile holds the preferences of a user that decide how dates are read and shown.
+// The date of a record is the day on which it occurred in the timezone of the user, and weeks start on the week start of the user.
+// The locale is only kept for clients: responses are formatted with the timezone, not the locale.
+type UserProfile struct {
+	location  *time.Location
+	locale    string
+	weekStart time.Weekday
+}
+
+// NewUserProfile creates a profile with an IANA timezone e.g. Asia/Dubai, a BCP 47 locale e.g. en-GB and the name of the first day of the week e.g. Sunday.
+func NewUserProfile(timezone string, locale string, weekStart string) (UserProfile, error) {
+	var (
+		location *time.Location
+		weekday  time.Weekday
+	)
+
+	errors := validate.Validate(
+		&timezoneValidator{Name: "timezone", Field: timezone, Location: &location},
+		&localeValidator{Name: "locale", Field: locale},
+		&weekdayValidator{Name: "weekStart", Field: weekStart, Weekday: &weekday},
+	)
+
+	if err := pkg.ValidationErrorWithErrors(pkg.ErrUserProfileValidation, "", errors); err != nil {
+		return UserProfile{}, err
+	}
+
+	return UserProfile{
+		location:  location,
+		locale:    locale,
+		weekStart: weekday,
+	}, nil
+}
+
+// DefaultUserProfile is the profile of a user that has not chosen their preferences
+func DefaultUserProfile() UserProfile {
+	return UserProfile{
+		location:  time.UTC,
+		locale:    DefaultLocale,
+		weekStart: DefaultWeekStart,
+	}
+}
+
+func MustUserProfile(timezone string, locale string, weekStart string) UserProfile {
+	profile, err := NewUserProfile(timezone, locale, weekStart)
+	if err != nil {
+		log.Fatalf("Invalid user profile. Reason: %s", err)
+	}
+	return profile
+}
+
+func (p UserProfile) Timezone() string {
+	return p.Location().String()
+}
+
+func (p UserProfile) Location() *time.Location {
+	if p.location == nil {
+		return time.UTC
+	}
+	return p.location
+}
+
+// Locale is the language tag that clients format dates and amounts with. It is not used to format responses.
+func (p UserProfile) Locale() string {
+	if len(p.locale) == 0 {
+		return DefaultLocale
+	}
+	return p.locale
+}
+
+func (p UserProfile) WeekStart() time.Weekday {
+	return p.weekStart
+}
+
+// LocalDate is the day of the moment in the timezone of the user, at midnight UTC like the dates of records.
+func (p UserProfile) LocalDate(moment time.Time) time.Time {
+	return startOfDay(moment.In(p.Location()))
+}
+
+// CurrentMonth is the calendar month of the moment in the timezone of the user.
+func (p UserProfile) CurrentMonth(now time.Time) CalendarMonth {
+	return MakeCalendarMonthFromDate(p.LocalDate(now))
+}
+
+// ParsePeriod parses a period whose weeks start on the week start of the user.
+func (p UserProfile) ParsePeriod(value string) (Period, error) {
+	return ParsePeriod(value, p.weekStart)
+}
+
+// FormatDate formats a date as the start of that day in the timezone of the user e.g. 2021-07-05T00:00:00+0400
+func (p UserProfile) FormatDate(date time.Time) string {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, p.Location()).Format("2006-01-02T15:04:05-0700")
+}
+
+// FormatTime formats a moment in the timezone of the user as RFC 3339
+func (p UserProfile) FormatTime(moment time.Time) string {
+	return moment.In(p.Location()).Format(time.RFC3339)
+}
+
+func (p UserProfile) String() string {
+	return fmt.Sprintf("UserProfile{timezone: %s, locale: %s, weekStart: %s}", p.Timezone(), p.Locale(), p.weekStart)
+}
+
+type timezoneValidator struct {
+	Name     string
+	Field    string
+	Location **time.Location
+}
+
+func (v *timezoneValidator) IsValid(errors *validate.Errors) {
+	// LoadLocation treats an empty name as UTC and Local as the timezone of the host, neither of which is a timezone a user can choose
+	if len(v.Field) == 0 || v.Field == "Local" {
+		errors.Add(v.Name, "timezone must be an IANA timezone e.g. Asia/Dubai")
+		return
+	}
+	location, err := time.LoadLocation(v.Field)
+	if err != nil {
+		errors.Add(v.Name, fmt.Sprintf("No such timezone '%s'", v.Field))
+		return
+	}
+	*v.Location = location
+}
+
+type localeValidator struct {
+	Name  string
+	Field string
+}
+
+func (v *localeValidator) IsValid(errors *validate.Errors) {
+	if !localePattern.MatchString(v.Field) {
+		errors.Add(v.Name, "locale must be a language tag e.g. en-GB")
+	}
+}
+
+type weekdayValidator struct {
+	Name    string
+	Field   string
+	Weekday *time.Weekday
+}
+
+func (v *weekdayValidator) IsValid(errors *validate.Errors) {
+	weekday, ok := ParseWeekday(v.Field)
+	if !ok {
+		errors.Add(v.Name, "weekStart must be a day of the week e.g. Monday")
+		return
+	}
+	*v.Weekday = weekday
+}
+
+// ParseWeekday parses the English name of a day of the week e.g. Monday. false is returned if it is not the name of a day.
+func ParseWeekday(name string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if weekday.String() == name {
+			return weekday, true
+		}
+	}
+	return time.Sunday, false
+}
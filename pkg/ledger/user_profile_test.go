@@ -0,0 +1,103 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+)
+
+type UserProfileTestSuite struct {
+	suite.Suite
+}
+
+func TestUserProfileTestSuite(t *testing.T) {
+	suite.Run(t, new(UserProfileTestSuite))
+}
+
+// -- SUITE
+
+func (suite *UserProfileTestSuite) Test_GIVEN_validPreferences_WHEN_profileIsCreated_THEN_profileIsCreated() {
+	// WHEN
+	profile, err := NewUserProfile("Asia/Dubai", "en-GB", "Sunday")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Asia/Dubai", profile.Timezone())
+	assert.Equal(suite.T(), "en-GB", profile.Locale())
+	assert.Equal(suite.T(), time.Sunday, profile.WeekStart())
+}
+
+func (suite *UserProfileTestSuite) Test_GIVEN_invalidPreferences_WHEN_profileIsCreated_THEN_errorIsReturned() {
+	for _, test := range []struct {
+		timezone  string
+		locale    string
+		weekStart string
+		field     string
+	}{
+		{timezone: "", locale: "en-US", weekStart: "Monday", field: "timezone"},
+		{timezone: "Local", locale: "en-US", weekStart: "Monday", field: "timezone"},
+		{timezone: "Mars/Olympus_Mons", locale: "en-US", weekStart: "Monday", field: "timezone"},
+		{timezone: "UTC", locale: "", weekStart: "Monday", field: "locale"},
+		{timezone: "UTC", locale: "english", weekStart: "Monday", field: "locale"},
+		{timezone: "UTC", locale: "en_US", weekStart: "Monday", field: "locale"},
+		{timezone: "UTC", locale: "en-US", weekStart: "monday", field: "weekStart"},
+		{timezone: "UTC", locale: "en-US", weekStart: "1", field: "weekStart"},
+	} {
+		// WHEN
+		profile, err := NewUserProfile(test.timezone, test.locale, test.weekStart)
+
+		// THEN
+		assert.NotNil(suite.T(), err, test.field)
+		assert.Equal(suite.T(), UserProfile{}, profile, test.field)
+		assert.Equal(suite.T(), pkg.ErrUserProfileValidation, errorCode(err, 0), test.field)
+		assert.Contains(suite.T(), errorFields(err), test.field, test.field)
+	}
+}
+
+func (suite *UserProfileTestSuite) Test_GIVEN_aMomentNearMidnight_WHEN_localDateIsCalculated_THEN_dayIsTheDayInTheTimezoneOfTheUser() {
+	for _, test := range []struct {
+		timezone string
+		moment   time.Time
+		date     time.Time
+	}{
+		{timezone: "UTC", moment: time.Date(2021, time.July, 31, 20, 30, 0, 0, time.UTC), date: utcDate(2021, time.July, 31)},
+		{timezone: "Asia/Dubai", moment: time.Date(2021, time.July, 31, 20, 30, 0, 0, time.UTC), date: utcDate(2021, time.August, 1)},
+		{timezone: "America/New_York", moment: time.Date(2021, time.August, 1, 2, 0, 0, 0, time.UTC), date: utcDate(2021, time.July, 31)},
+	} {
+		// GIVEN
+		profile := MustUserProfile(test.timezone, DefaultLocale, "Monday")
+
+		// WHEN
+		date := profile.LocalDate(test.moment)
+		month := profile.CurrentMonth(test.moment)
+
+		// THEN
+		assert.Equal(suite.T(), test.date, date, test.timezone)
+		assert.Equal(suite.T(), test.date.Month(), month.Month(), test.timezone)
+	}
+}
+
+func (suite *UserProfileTestSuite) Test_GIVEN_aProfile_WHEN_datesAreFormatted_THEN_theyAreInTheTimezoneOfTheUser() {
+	// GIVEN
+	profile := MustUserProfile("Asia/Dubai", DefaultLocale, "Monday")
+
+	// THEN
+	assert.Equal(suite.T(), "2021-08-01T00:00:00+0400", profile.FormatDate(utcDate(2021, time.August, 1)))
+	assert.Equal(suite.T(), "2021-08-01T00:30:00+04:00", profile.FormatTime(time.Date(2021, time.July, 31, 20, 30, 0, 0, time.UTC)))
+	assert.Equal(suite.T(), "2021-07-31T00:00:00+0000", DefaultUserProfile().FormatDate(utcDate(2021, time.July, 31)))
+}
+
+func (suite *UserProfileTestSuite) Test_GIVEN_aWeekStart_WHEN_weekIsParsed_THEN_weekStartsOnTheWeekStartOfTheUser() {
+	// GIVEN
+	profile := MustUserProfile("UTC", DefaultLocale, "Sunday")
+
+	// WHEN
+	period, err := profile.ParsePeriod("2021-W28")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), utcDate(2021, time.July, 11), period.FirstDay())
+}
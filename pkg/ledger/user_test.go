@@ -55,3 +55,23 @@ func (suite *UserTestSuite) Test_GIVEN_validUserEmail_WHEN_userIsCreated_THEN_us
 	assert.Equal(suite.T(), "UserId: 1", user.CreatedBy().String())
 	assert.True(suite.T(), time.Now().In(time.UTC).Sub(user.createdAtUTC) < time.Duration(1)*time.Second)
 }
+
+func (suite *UserTestSuite) Test_GIVEN_aUser_WHEN_userIsEdited_THEN_profileAndPreferencesAreChangedAndModificationIsAudited() {
+	// GIVEN
+	user, _ := NewUserWithEmailString(UserId(1), "john@example.com")
+	profile := MustUserProfile("Asia/Dubai", "en-AE", "Sunday")
+	preferences, _ := NewUserPreferences("John", "AED", AccountId(2), CategoryId(3))
+
+	// WHEN
+	edited, err := user.Edit(profile, preferences, MustMakeUpdatedByUserId(UserId(1)))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), user.Email(), edited.Email())
+	assert.Equal(suite.T(), profile, edited.Profile())
+	assert.Equal(suite.T(), "AED", edited.Preferences().Currency())
+	assert.Equal(suite.T(), AccountId(2), edited.Preferences().DefaultAccountId())
+	assert.Equal(suite.T(), CategoryId(3), edited.Preferences().DefaultCategoryId())
+	assert.Equal(suite.T(), "UserId: 1", edited.ModifiedBy().String())
+	assert.Equal(suite.T(), user.Version(), edited.Version())
+}
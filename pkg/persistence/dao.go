@@ -27,10 +27,82 @@ type UserDao interface {
 	SaveTx(u ledger.User, tx *sql.Tx) error
 
 	GetUserById(id ledger.UserId) (ledger.User, error)
+	GetUserByIdTx(ctx context.Context, id ledger.UserId, tx *sql.Tx) (ledger.User, error)
+	// UpdateTx saves the profile and preferences of a user, if the version of the user has not changed since it was read.
+	UpdateTx(ctx context.Context, u ledger.User, tx *sql.Tx) error
+
+	// SaveDeletionTx saves the request of a user to delete their account, replacing any previous request.
+	SaveDeletionTx(ctx context.Context, deletion UserDeletion, tx *sql.Tx) error
+	// GetDeletionTx returns the request of a user to delete their account. false is returned if the user has not requested the deletion.
+	GetDeletionTx(ctx context.Context, id ledger.UserId, tx *sql.Tx) (UserDeletion, bool, error)
+	DeleteDeletionTx(ctx context.Context, id ledger.UserId, tx *sql.Tx) error
+	// GetUsersDueForDeletionTx returns the users whose confirmed deletion is due at the given time.
+	GetUsersDueForDeletionTx(ctx context.Context, now time.Time, tx *sql.Tx) ([]ledger.UserId, error)
+	// DeleteDueTx deletes a user and all their data if their confirmed deletion is due at the given time.
+	// false is returned, and nothing is deleted, if the deletion is not due, e.g. because it was cancelled after the due users were loaded.
+	DeleteDueTx(ctx context.Context, id ledger.UserId, now time.Time, tx *sql.Tx) (bool, error)
+
+	// SaveCredentialTx saves the password hash of a user, replacing any previous password.
+	SaveCredentialTx(ctx context.Context, credential UserCredential, tx *sql.Tx) error
+	// GetCredentialByEmailTx returns the password hash of the user with the given email. false is returned if there is no such user, or if the user has no password.
+	GetCredentialByEmailTx(ctx context.Context, email string, tx *sql.Tx) (UserCredential, bool, error)
+	// GetCredentialTx returns the password hash of a user. false is returned if the user has no password.
+	GetCredentialTx(ctx context.Context, id ledger.UserId, tx *sql.Tx) (UserCredential, bool, error)
+	// GetUserIdByEmailTx returns the id of the user with the given email, whether or not they have a password. false is returned if there is no such user.
+	GetUserIdByEmailTx(ctx context.Context, email string, tx *sql.Tx) (ledger.UserId, bool, error)
+
+	// SavePasswordResetTx saves the request of a user to reset their password, replacing any previous request.
+	SavePasswordResetTx(ctx context.Context, reset PasswordReset, tx *sql.Tx) error
+	// GetPasswordResetByTokenHashTx returns the password reset with the given token hash. false is returned if there is none.
+	GetPasswordResetByTokenHashTx(ctx context.Context, tokenHash string, tx *sql.Tx) (PasswordReset, bool, error)
+	// DeletePasswordResetTx deletes the password reset of a user, if there is one.
+	DeletePasswordResetTx(ctx context.Context, id ledger.UserId, tx *sql.Tx) error
+
+	// SaveRefreshTokenTx saves a refresh token issued to a user, and deletes the tokens of the user that have expired.
+	SaveRefreshTokenTx(ctx context.Context, token RefreshToken, tx *sql.Tx) error
+	// UseRefreshTokenTx marks a refresh token of a user as used. false is returned if the token has already been used, revoked or has expired.
+	UseRefreshTokenTx(ctx context.Context, id string, userId ledger.UserId, now time.Time, tx *sql.Tx) (bool, error)
+	// RevokeRefreshTokensTx deletes all refresh tokens of a user.
+	RevokeRefreshTokensTx(ctx context.Context, userId ledger.UserId, tx *sql.Tx) error
 
 	IsDuplicateKeyError(error) (string, bool)
 }
 
+// UserCredential is the password with which a user logs in. Only a hash of the password is kept.
+type UserCredential struct {
+	UserId       ledger.UserId
+	PasswordHash ledger.PasswordHash
+	UpdatedAt    time.Time
+}
+
+// PasswordReset is the request of a user to reset their password. Only a hash of the reset token is kept.
+type PasswordReset struct {
+	UserId         ledger.UserId
+	TokenHash      string
+	TokenExpiresAt time.Time
+	RequestedAt    time.Time
+}
+
+// RefreshToken is a refresh token issued to a user. Only its id is kept; the token itself is signed.
+type RefreshToken struct {
+	Id        string
+	UserId    ledger.UserId
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// UserDeletion is the request of a user to delete their account and all their data.
+// Only a hash of the confirmation token is kept.
+type UserDeletion struct {
+	UserId         ledger.UserId
+	TokenHash      string
+	TokenExpiresAt time.Time
+	RequestedAt    time.Time
+	// ConfirmedAt and DeleteAfter are only set once the deletion has been confirmed with the token.
+	ConfirmedAt *time.Time
+	DeleteAfter *time.Time
+}
+
 type AccountDao interface {
 	BeginTx() (*sql.Tx, error)
 	MustBeginTx() *sql.Tx
@@ -75,10 +147,33 @@ type RecordDao interface {
 	NewRecordId(*sql.Tx) (ledger.RecordId, error)
 
 	SaveTx(ctx context.Context, id ledger.AccountId, r ledger.Record, tx *sql.Tx) error
+	UpdateTx(ctx context.Context, id ledger.AccountId, r ledger.Record, tx *sql.Tx) error
+	DeleteTx(ctx context.Context, id ledger.AccountId, recordId ledger.RecordId, version ledger.Version, tx *sql.Tx) error
 
+	GetTransferPairTx(ctx context.Context, reference ledger.TransferReference, userId ledger.UserId, tx *sql.Tx) (ledger.TransferPair, error)
+	UpdateTransferPairTx(ctx context.Context, transfer ledger.TransferPair, tx *sql.Tx) error
+	DeleteTransferPairTx(ctx context.Context, transfer ledger.TransferPair, tx *sql.Tx) error
+
+	GetRecordByIdTx(ctx context.Context, id ledger.RecordId, accountId ledger.AccountId, tx *sql.Tx) (ledger.Record, error)
 	Search(id ledger.AccountId, search RecordSearch) (ledger.Records, error)
-	GetRecordsForMonth(id ledger.AccountId, month ledger.CalendarMonth) (ledger.Records, error)
-	GetRecordsForLastPeriod(ctx context.Context, id ledger.AccountId, tx *sql.Tx) (ledger.Records, error)
+	// ForEachRecord calls fn with each record of an account that matches the search, oldest first, and stops at the first error.
+	// Records are loaded in pages of search.Limit, so that all the records are not held in memory at once. The cursors of the search are ignored.
+	ForEachRecord(ctx context.Context, id ledger.AccountId, search RecordSearch, fn func(ledger.Record) error) error
+	Summarize(id ledger.AccountId, search RecordSearch) (RecordsSummary, error)
+	// GetLastPeriod returns the calendar month of the newest record of an account,
+	// or the current month in the timezone of the user if the account has no records.
+	GetLastPeriod(ctx context.Context, id ledger.AccountId, profile ledger.UserProfile, tx *sql.Tx) (ledger.Period, error)
+	// GetRecordsForPeriod returns the records of an account dated between the first and last day of the period inclusive.
+	GetRecordsForPeriod(id ledger.AccountId, period ledger.Period) (ledger.Records, error)
+	GetRecordsForLastPeriod(ctx context.Context, id ledger.AccountId, profile ledger.UserProfile, tx *sql.Tx) (ledger.Records, error)
+
+	// SaveExternalIdTx records that the transaction of a bank statement with the given external id has been imported into an account.
+	// false is returned if the transaction had already been imported.
+	SaveExternalIdTx(ctx context.Context, id ledger.AccountId, externalId string, recordId ledger.RecordId, tx *sql.Tx) (bool, error)
+	// GetDuplicateCandidatesTx returns the records of an account with the given amount that are dated between from and to inclusive.
+	GetDuplicateCandidatesTx(ctx context.Context, id ledger.AccountId, amount ledger.Money, from time.Time, to time.Time, tx *sql.Tx) (ledger.Records, error)
+	// GetRecordsInCategoryTx returns the records of an account in the given category, newest first. The categories of the lines of split records are not matched.
+	GetRecordsInCategoryTx(ctx context.Context, id ledger.AccountId, categoryId ledger.CategoryId, tx *sql.Tx) (ledger.Records, error)
 }
 
 type RecordSearch struct {
@@ -88,6 +183,83 @@ type RecordSearch struct {
 	CategoryNames           []string
 	RecordTypes             []ledger.RecordType
 	BeneficiaryAccountNames []string
+	// CreatedByKinds only matches records created by one of the given kinds of actor e.g. records created by a scheduled task
+	CreatedByKinds []ledger.UpdatedByKind
+	// Profile is the profile of the user whose records are searched. When FromDate or ToDate is not set,
+	// the search starts on the first day of the current month and ends today, in the timezone of the user.
+	Profile ledger.UserProfile
+
+	// Records are listed newest first, ordered by date and then by id.
+	// When After is set, only records that come after the cursor (i.e. older records) are returned.
+	// When Before is set, only records that come before the cursor (i.e. newer records) are returned.
+	After  *RecordCursor
+	Before *RecordCursor
+	// Limit is the maximum number of records returned. Zero means no limit.
+	Limit uint
+}
+
+// RecordCursor is the position of a record in a listing of records.
+type RecordCursor struct {
+	Date time.Time
+	Id   ledger.RecordId
+}
+
+// RecordsSummary holds the totals of all the records that match a search, regardless of the page requested.
+type RecordsSummary struct {
+	TotalIncome   ledger.Money
+	TotalExpenses ledger.Money
+	TotalSavings  ledger.Money
+}
+
+type RecurringRecordDao interface {
+	BeginTx() (*sql.Tx, error)
+	MustBeginTx() *sql.Tx
+
+	NewRecurringRecordId(tx *sql.Tx) (ledger.RecurringRecordId, error)
+
+	SaveTx(ctx context.Context, id ledger.UserId, rr ledger.RecurringRecord, tx *sql.Tx) error
+	UpdateTx(ctx context.Context, id ledger.UserId, rr ledger.RecurringRecord, tx *sql.Tx) error
+	DeleteTx(ctx context.Context, id ledger.RecurringRecordId, userId ledger.UserId, version ledger.Version, tx *sql.Tx) error
+
+	GetRecurringRecordByIdTx(ctx context.Context, id ledger.RecurringRecordId, userId ledger.UserId, tx *sql.Tx) (ledger.RecurringRecord, error)
+	GetRecurringRecordsForAccount(ctx context.Context, id ledger.AccountId, userId ledger.UserId, tx *sql.Tx) (ledger.RecurringRecords, error)
+
+	// GetDueRecurringRecordsTx returns up to limit recurring records whose next date is on or before the date of now in the timezone of their user,
+	// earliest occurrence first. Zero means no limit.
+	GetDueRecurringRecordsTx(ctx context.Context, now time.Time, limit uint, tx *sql.Tx) ([]DueRecurringRecord, error)
+	// SaveOccurrenceTx records that the occurrence of a recurring record has been handled.
+	// false is returned if the occurrence had already been handled.
+	SaveOccurrenceTx(ctx context.Context, id ledger.RecurringRecordId, occurrence time.Time, recordId ledger.RecordId, tx *sql.Tx) (bool, error)
+}
+
+// DueRecurringRecord is a recurring record for which a record should be created, along with the user it belongs to.
+type DueRecurringRecord struct {
+	UserId          ledger.UserId
+	RecurringRecord ledger.RecurringRecord
+}
+
+type ImportProfileDao interface {
+	BeginTx() (*sql.Tx, error)
+	MustBeginTx() *sql.Tx
+
+	NewImportProfileId(tx *sql.Tx) (ledger.ImportProfileId, error)
+
+	SaveTx(ctx context.Context, id ledger.UserId, p ledger.ImportProfile, tx *sql.Tx) error
+
+	GetImportProfileByIdTx(ctx context.Context, id ledger.ImportProfileId, userId ledger.UserId, tx *sql.Tx) (ledger.ImportProfile, error)
+	GetImportProfilesForUser(ctx context.Context, id ledger.UserId, tx *sql.Tx) (ledger.ImportProfiles, error)
+}
+
+type CategoryRuleDao interface {
+	BeginTx() (*sql.Tx, error)
+	MustBeginTx() *sql.Tx
+
+	NewCategoryRuleId(tx *sql.Tx) (ledger.CategoryRuleId, error)
+
+	SaveTx(ctx context.Context, id ledger.UserId, r ledger.CategoryRule, tx *sql.Tx) error
+	DeleteTx(ctx context.Context, id ledger.CategoryRuleId, userId ledger.UserId, tx *sql.Tx) error
+
+	GetCategoryRulesForUser(ctx context.Context, id ledger.UserId, tx *sql.Tx) (ledger.CategoryRules, error)
 }
 
 type BudgetDao interface {
@@ -95,12 +267,62 @@ type BudgetDao interface {
 	MustBeginTx() *sql.Tx
 
 	Save(ctx context.Context, id ledger.UserId, budget ledger.Budget, tx *sql.Tx) error
+	// UpdateTx replaces the period, accounts and category budgets of a budget, if it has not been changed since it was loaded.
+	UpdateTx(ctx context.Context, id ledger.UserId, budget ledger.Budget, tx *sql.Tx) error
+	DeleteTx(ctx context.Context, id ledger.BudgetId, userId ledger.UserId, version ledger.Version, tx *sql.Tx) error
 	GetBudgetById(
 		ctx context.Context,
 		id ledger.BudgetId,
 		userId ledger.UserId,
 		tx *sql.Tx,
 	) (ledger.Budget, error)
+	GetBudgetsForUser(ctx context.Context, id ledger.UserId, tx *sql.Tx) ([]ledger.Budget, error)
+	// LockBudgetTx locks the budget until the transaction ends, so that changes that depend on its balances are made one at a time.
+	LockBudgetTx(ctx context.Context, id ledger.BudgetId, userId ledger.UserId, tx *sql.Tx) error
+	// GetSpentPerCategoryTx returns the total expenses of each category of a budget across the accounts of the budget, dated in the period.
+	// The lines of split records are counted in their own categories. Categories with no expenses are not returned.
+	GetSpentPerCategoryTx(ctx context.Context, id ledger.UserId, budget ledger.Budget, period ledger.BudgetPeriod, tx *sql.Tx) (map[ledger.CategoryId]ledger.Money, error)
+	// GetSpentPerCategoryPerPeriodTx is GetSpentPerCategoryTx for each of the periods, calculated in a single query.
+	// The expenses of each period are returned at the index of the period.
+	GetSpentPerCategoryPerPeriodTx(ctx context.Context, id ledger.UserId, budget ledger.Budget, periods []ledger.BudgetPeriod, tx *sql.Tx) ([]map[ledger.CategoryId]ledger.Money, error)
+}
+
+type BudgetAlertDao interface {
+	BeginTx() (*sql.Tx, error)
+	MustBeginTx() *sql.Tx
+
+	NewBudgetAlertId(tx *sql.Tx) (ledger.BudgetAlertId, error)
+
+	// SaveTx saves an alert unless an alert was already raised for the same threshold of the category budget in the same period.
+	// false is returned if the alert had already been raised.
+	SaveTx(ctx context.Context, alert ledger.BudgetAlert, tx *sql.Tx) (bool, error)
+	GetBudgetAlertsForUser(ctx context.Context, id ledger.UserId, tx *sql.Tx) ([]ledger.BudgetAlert, error)
+}
+
+type EnvelopeTransferDao interface {
+	BeginTx() (*sql.Tx, error)
+	MustBeginTx() *sql.Tx
+
+	NewEnvelopeTransferId(tx *sql.Tx) (ledger.EnvelopeTransferId, error)
+	SaveTx(ctx context.Context, id ledger.UserId, transfer ledger.EnvelopeTransfer, tx *sql.Tx) error
+	// GetEnvelopeTransfersForBudget returns the transfers between the envelopes of a budget of a user, oldest first.
+	GetEnvelopeTransfersForBudget(ctx context.Context, id ledger.UserId, budgetId ledger.BudgetId, tx *sql.Tx) ([]ledger.EnvelopeTransfer, error)
+}
+
+type MonthlyPlanDao interface {
+	BeginTx() (*sql.Tx, error)
+	MustBeginTx() *sql.Tx
+
+	NewMonthlyPlanId(tx *sql.Tx) (ledger.MonthlyPlanId, error)
+	// SaveTx saves a plan. ErrMonthlyPlanDuplicated is returned if the user already has a plan for the month.
+	SaveTx(ctx context.Context, id ledger.UserId, plan ledger.MonthlyPlan, tx *sql.Tx) error
+	// UpdateTx replaces the accounts, expected income and assignments of a plan, if it has not been changed since it was loaded.
+	UpdateTx(ctx context.Context, id ledger.UserId, plan ledger.MonthlyPlan, tx *sql.Tx) error
+	// GetMonthlyPlanTx returns the plan of a user for a month. ErrMonthlyPlanNotFound is returned if the user has no plan for the month.
+	GetMonthlyPlanTx(ctx context.Context, id ledger.UserId, month ledger.CalendarMonth, tx *sql.Tx) (ledger.MonthlyPlan, error)
+	// GetActualsTx returns the total income and the total expenses of each category across the accounts of a plan, dated in the month of the plan.
+	// The lines of split records are counted in their own categories. Categories with no expenses are not returned.
+	GetActualsTx(ctx context.Context, id ledger.UserId, plan ledger.MonthlyPlan, tx *sql.Tx) (ledger.Money, map[ledger.CategoryId]ledger.Money, error)
 }
 
 func DeferRollback(tx *sql.Tx, reference string) {
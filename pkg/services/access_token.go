@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
+// tokenClaims are the claims of access and refresh tokens, which are JSON Web Tokens signed with HMAC-SHA256.
+// The type keeps a refresh token from being used as an access token, and the other way around.
+type tokenClaims struct {
+	Type string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// tokenSigner signs and verifies the tokens of users with a secret key
+type tokenSigner struct {
+	key []byte
+}
+
+// sign returns a token of the given type for a user. The id is left out of the token if it is empty.
+func (s tokenSigner) sign(userId ledger.UserId, tokenType string, id string, issuedAt time.Time, ttl time.Duration) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims{
+		Type: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   strconv.FormatUint(uint64(userId), 10),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(ttl)),
+		},
+	}).SignedString(s.key)
+}
+
+// verify returns the user and the id of a token of the given type, if the token was signed with the key of this signer and has not expired at the given time
+func (s tokenSigner) verify(token string, tokenType string, now time.Time) (ledger.UserId, string, error) {
+	var claims tokenClaims
+
+	// The expiry is checked against the given time rather than the clock of the parser
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithoutClaimsValidation())
+	if _, err := parser.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) { return s.key, nil }); err != nil {
+		return 0, "", err
+	}
+
+	if claims.Type != tokenType {
+		return 0, "", fmt.Errorf("token is a %q token, not a %q token", claims.Type, tokenType)
+	}
+	if !claims.VerifyExpiresAt(now, true) {
+		return 0, "", fmt.Errorf("token has expired")
+	}
+	userId, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || userId == 0 {
+		return 0, "", fmt.Errorf("token subject %q is not a user id", claims.Subject)
+	}
+	return ledger.UserId(userId), claims.ID, nil
+}
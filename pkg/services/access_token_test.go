@@ -0,0 +1,82 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+type AccessTokenTestSuite struct {
+	suite.Suite
+	signer   tokenSigner
+	issuedAt time.Time
+}
+
+func TestAccessTokenTestSuite(t *testing.T) {
+	suite.Run(t, new(AccessTokenTestSuite))
+}
+
+// -- SETUP
+
+func (suite *AccessTokenTestSuite) SetupTest() {
+	suite.signer = tokenSigner{key: []byte("the-overlook-hotel-room-237-signing-key")}
+	suite.issuedAt = time.Date(2021, time.July, 5, 10, 0, 0, 0, time.UTC)
+}
+
+// -- SUITE
+
+func (suite *AccessTokenTestSuite) Test_GIVEN_aToken_WHEN_verifiedBeforeItExpires_THEN_userIsReturned() {
+	// GIVEN
+	token, err := suite.signer.sign(ledger.UserId(1), accessTokenType, "", suite.issuedAt, 15*time.Minute)
+	assert.Nil(suite.T(), err)
+
+	// WHEN
+	userId, _, err := suite.signer.verify(token, accessTokenType, suite.issuedAt.Add(14*time.Minute))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), ledger.UserId(1), userId)
+}
+
+func (suite *AccessTokenTestSuite) Test_GIVEN_aToken_WHEN_verifiedOnceItExpired_THEN_errorIsReturned() {
+	// GIVEN
+	token, _ := suite.signer.sign(ledger.UserId(1), accessTokenType, "", suite.issuedAt, 15*time.Minute)
+
+	// WHEN
+	_, _, err := suite.signer.verify(token, accessTokenType, suite.issuedAt.Add(15*time.Minute))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *AccessTokenTestSuite) Test_GIVEN_aRefreshToken_WHEN_verifiedAsAnAccessToken_THEN_errorIsReturned() {
+	// GIVEN
+	token, _ := suite.signer.sign(ledger.UserId(1), refreshTokenType, "", suite.issuedAt, time.Hour)
+
+	// WHEN
+	_, _, err := suite.signer.verify(token, accessTokenType, suite.issuedAt)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *AccessTokenTestSuite) Test_GIVEN_aTokenSignedWithAnotherKeyOrAlgorithm_WHEN_verified_THEN_errorIsReturned() {
+	// GIVEN
+	otherKey, _ := tokenSigner{key: []byte("another-key-that-is-at-least-32-bytes")}.sign(ledger.UserId(1), accessTokenType, "", suite.issuedAt, time.Hour)
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, tokenClaims{
+		Type:             accessTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "1", ExpiresAt: jwt.NewNumericDate(suite.issuedAt.Add(time.Hour))},
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	for _, token := range []string{otherKey, unsigned} {
+		// WHEN
+		_, _, err := suite.signer.verify(token, accessTokenType, suite.issuedAt)
+
+		// THEN
+		assert.NotNil(suite.T(), err, token)
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+// AlertNotifier delivers the budget alerts raised for a user.
+type AlertNotifier interface {
+	Notify(ctx context.Context, alert ledger.BudgetAlert) error
+}
+
+// LogAlertNotifier writes budget alerts to the application log.
+type LogAlertNotifier struct{}
+
+func NewLogAlertNotifier() *LogAlertNotifier {
+	return &LogAlertNotifier{}
+}
+
+func (n *LogAlertNotifier) Notify(ctx context.Context, alert ledger.BudgetAlert) error {
+	log.Printf(
+		"Budget alert for user %d: %d%% of the budget of category %d in %s reached. Spent %s of %s",
+		alert.UserId(),
+		alert.Threshold(),
+		alert.CategoryId(),
+		alert.Period(),
+		alert.Spent(),
+		alert.MaxLimit(),
+	)
+	return nil
+}
+
+// InMemoryAlertNotifier keeps the budget alerts it is notified of, so that they can be inspected in tests.
+type InMemoryAlertNotifier struct {
+	mutex  sync.Mutex
+	alerts []ledger.BudgetAlert
+}
+
+func NewInMemoryAlertNotifier() *InMemoryAlertNotifier {
+	return &InMemoryAlertNotifier{}
+}
+
+func (n *InMemoryAlertNotifier) Notify(ctx context.Context, alert ledger.BudgetAlert) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+// Alerts returns the alerts notified so far, oldest first.
+func (n *InMemoryAlertNotifier) Alerts() []ledger.BudgetAlert {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	alerts := make([]ledger.BudgetAlert, len(n.alerts))
+	copy(alerts, n.alerts)
+	return alerts
+}
+
+// Reset forgets the alerts notified so far.
+func (n *InMemoryAlertNotifier) Reset() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.alerts = nil
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+type BudgetAlertResponse struct {
+	Id         uint64               `json:"id"`
+	BudgetId   uint64               `json:"budgetId"`
+	CategoryId uint64               `json:"categoryId"`
+	Period     BudgetPeriodResponse `json:"period"`
+	Threshold  uint                 `json:"threshold"`
+	Spent      AmountResponse       `json:"spent"`
+	MaxAmount  AmountResponse       `json:"maxAmount"`
+	CreatedAt  string               `json:"createdAt"`
+}
+
+type BudgetAlertsResponse struct {
+	Alerts []BudgetAlertResponse `json:"alerts"`
+}
+
+func makeBudgetAlertResponse(alert ledger.BudgetAlert) BudgetAlertResponse {
+	return BudgetAlertResponse{
+		Id:         uint64(alert.Id()),
+		BudgetId:   uint64(alert.BudgetId()),
+		CategoryId: uint64(alert.CategoryId()),
+		Period:     makeBudgetPeriodResponse(alert.Period()),
+		Threshold:  alert.Threshold(),
+		Spent: AmountResponse{
+			Currency: alert.Spent().Currency().CurrencyCode(),
+			Value:    alert.Spent().MustMinorUnits(),
+		},
+		MaxAmount: AmountResponse{
+			Currency: alert.MaxLimit().Currency().CurrencyCode(),
+			Value:    alert.MaxLimit().MustMinorUnits(),
+		},
+		CreatedAt: alert.CreatedAtUTC().Format(time.RFC3339),
+	}
+}
+
+type AlertService interface {
+	// CheckAlertThresholds raises an alert for each alert threshold that the expenses of the period of a record have reached,
+	// in the budgets of the account of the record that include its categories.
+	// An alert is raised once per threshold in each period; the alerts that are raised are delivered by the notifier.
+	CheckAlertThresholds(ctx context.Context, accountId ledger.AccountId, record ledger.Record) ([]ledger.BudgetAlert, error)
+	GetAlerts(ctx context.Context) (BudgetAlertsResponse, error)
+}
+
+type alertService struct {
+	budgetDao      dao.BudgetDao
+	budgetAlertDao dao.BudgetAlertDao
+	notifier       AlertNotifier
+}
+
+func NewAlertService(budgetDao dao.BudgetDao, budgetAlertDao dao.BudgetAlertDao, notifier AlertNotifier) (AlertService, error) {
+	if budgetDao == nil {
+		return nil, fmt.Errorf("can not create alert service. budgetDao is nil")
+	}
+	if budgetAlertDao == nil {
+		return nil, fmt.Errorf("can not create alert service. budgetAlertDao is nil")
+	}
+	if notifier == nil {
+		return nil, fmt.Errorf("can not create alert service. notifier is nil")
+	}
+
+	return &alertService{
+		budgetDao:      budgetDao,
+		budgetAlertDao: budgetAlertDao,
+		notifier:       notifier,
+	}, nil
+}
+
+func (svc alertService) CheckAlertThresholds(ctx context.Context, accountId ledger.AccountId, record ledger.Record) ([]ledger.BudgetAlert, error) {
+	var (
+		userId  ledger.UserId
+		tx      *sql.Tx
+		budgets []ledger.Budget
+		raised  []ledger.BudgetAlert
+		err     error
+	)
+
+	if record.Type() != ledger.Expense {
+		return nil, nil
+	}
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return nil, err
+	}
+
+	if tx, err = svc.budgetAlertDao.BeginTx(); err != nil {
+		return nil, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("CheckAlertThresholds: %d", userId))
+
+	if budgets, err = svc.budgetDao.GetBudgetsForUser(ctx, userId, tx); err != nil {
+		return nil, err
+	}
+
+	recordCategories := map[ledger.CategoryId]bool{}
+	for _, line := range record.Lines() {
+		recordCategories[line.Category().Id()] = true
+	}
+
+	for _, budget := range budgets {
+		if !budgetIncludesAccount(budget, accountId) {
+			continue
+		}
+
+		categoryBudgets := ledger.CategoryBudgets{}
+		for _, categoryBudget := range budget.CategoryBudgets() {
+			if recordCategories[categoryBudget.CategoryId()] {
+				categoryBudgets = append(categoryBudgets, categoryBudget)
+			}
+		}
+		if len(categoryBudgets) == 0 {
+			continue
+		}
+
+		var alerts []ledger.BudgetAlert
+		if alerts, err = svc.raiseAlertsTx(ctx, userId, budget, categoryBudgets, budget.PeriodCalculator().PeriodOf(record.DateUTC()), tx); err != nil {
+			return nil, err
+		}
+		raised = append(raised, alerts...)
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return nil, err
+	}
+
+	for _, alert := range raised {
+		if err := svc.notifier.Notify(ctx, alert); err != nil {
+			log.Printf("Failed to notify budget alert %s. Reason: %s", alert, err)
+		}
+	}
+	return raised, nil
+}
+
+// raiseAlertsTx saves an alert for each threshold of the category budgets reached in the period that was not already raised, and returns the alerts that were saved.
+func (svc alertService) raiseAlertsTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	budget ledger.Budget,
+	categoryBudgets ledger.CategoryBudgets,
+	period ledger.BudgetPeriod,
+	tx *sql.Tx,
+) ([]ledger.BudgetAlert, error) {
+	var (
+		spentPerCategory map[ledger.CategoryId]ledger.Money
+		raised           []ledger.BudgetAlert
+		err              error
+	)
+
+	if spentPerCategory, err = svc.budgetDao.GetSpentPerCategoryTx(ctx, userId, budget, period, tx); err != nil {
+		return nil, err
+	}
+
+	for _, categoryBudget := range categoryBudgets {
+		spent, ok := spentPerCategory[categoryBudget.CategoryId()]
+		if !ok {
+			continue
+		}
+
+		for _, threshold := range categoryBudget.ReachedAlertThresholds(spent) {
+			var (
+				id    ledger.BudgetAlertId
+				alert ledger.BudgetAlert
+				saved bool
+			)
+			if id, err = svc.budgetAlertDao.NewBudgetAlertId(tx); err != nil {
+				return nil, err
+			}
+			if alert, err = ledger.NewBudgetAlert(id, userId, budget.Id(), categoryBudget, period, threshold, spent); err != nil {
+				return nil, err
+			}
+			if saved, err = svc.budgetAlertDao.SaveTx(ctx, alert, tx); err != nil {
+				return nil, err
+			}
+			if saved {
+				raised = append(raised, alert)
+			}
+		}
+	}
+	return raised, nil
+}
+
+func (svc alertService) GetAlerts(ctx context.Context) (BudgetAlertsResponse, error) {
+	var (
+		userId ledger.UserId
+		tx     *sql.Tx
+		alerts []ledger.BudgetAlert
+		err    error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return BudgetAlertsResponse{}, err
+	}
+
+	if tx, err = svc.budgetAlertDao.BeginTx(); err != nil {
+		return BudgetAlertsResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("GetAlerts: %d", userId))
+
+	if alerts, err = svc.budgetAlertDao.GetBudgetAlertsForUser(ctx, userId, tx); err != nil {
+		return BudgetAlertsResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return BudgetAlertsResponse{}, err
+	}
+
+	resp := BudgetAlertsResponse{Alerts: make([]BudgetAlertResponse, 0, len(alerts))}
+	for _, alert := range alerts {
+		resp.Alerts = append(resp.Alerts, makeBudgetAlertResponse(alert))
+	}
+	return resp, nil
+}
+
+func budgetIncludesAccount(budget ledger.Budget, accountId ledger.AccountId) bool {
+	for _, id := range budget.AccountIds() {
+		if id == accountId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+// timingPasswordHash is matched against the password of a login for an unknown email,
+// so that the response takes as long as it does for a known email with the wrong password.
+const timingPasswordHash = ledger.PasswordHash("pbkdf2-sha256$600000$KbtNtvJVVTgM0zWi0RQ5HQ$tTBD52zLTmlFmremuA6gSlAmzhE6Hi/PPWUZbBaV53k")
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// SetPasswordRequest changes the password of the user. The current password is required if the user already has one.
+type SetPasswordRequest struct {
+	CurrentPassword string `json:"currentPassword,omitempty"`
+	Password        string `json:"password"`
+}
+
+// PasswordResetRequest asks for a reset token to be sent to the email of a user.
+// Users who registered before passwords were required set their first password this way.
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest sets the password of the user to whom the reset token was sent.
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// TokenResponse holds a short-lived access token, sent as a Bearer token in the Authorization header,
+// and a refresh token with which a new access token can be requested once it expires.
+type TokenResponse struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	// ExpiresIn is the number of seconds for which the access token is valid
+	ExpiresIn    int64  `json:"expiresIn"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type AuthService interface {
+	// Login returns tokens for the user with the given email, if the password is theirs.
+	Login(ctx context.Context, request LoginRequest) (TokenResponse, error)
+	// Refresh returns new tokens in exchange for a refresh token that has not expired. Each refresh token can only be exchanged once;
+	// exchanging it again revokes every refresh token of the user.
+	Refresh(ctx context.Context, request RefreshTokenRequest) (TokenResponse, error)
+	// Authenticate returns the user of an access token that has not expired.
+	Authenticate(accessToken string) (ledger.UserId, error)
+
+	// SetPassword changes the password of the user, and revokes their refresh tokens.
+	SetPassword(ctx context.Context, request SetPasswordRequest) error
+	// RequestPasswordReset sends a reset token to the user with the given email. Nothing is sent, and no error is returned, if there is no such user.
+	// Password resets are unavailable when no notifier is configured.
+	RequestPasswordReset(ctx context.Context, request PasswordResetRequest) error
+	// ResetPassword sets the password of the user to whom the reset token was sent, and revokes their refresh tokens.
+	ResetPassword(ctx context.Context, request ResetPasswordRequest) error
+}
+
+// errPasswordResetUnavailable is returned when no notifier is configured to deliver reset tokens
+var errPasswordResetUnavailable = pkg.NewSystemError(pkg.ErrPasswordResetUnavailable, "Password reset is not available", nil)
+
+// passwordResetTokenValidity is how long a user has to reset their password with the token sent to them
+const passwordResetTokenValidity = time.Hour
+
+type authService struct {
+	userDao               dao.UserDao
+	signer                tokenSigner
+	accessTokenTtl        time.Duration
+	refreshTokenTtl       time.Duration
+	passwordResetNotifier PasswordResetNotifier
+}
+
+// NewAuthService creates the auth service. Password resets are disabled when passwordResetNotifier is nil.
+func NewAuthService(userDao dao.UserDao, signingKey []byte, accessTokenTtl time.Duration, refreshTokenTtl time.Duration, passwordResetNotifier PasswordResetNotifier) (AuthService, error) {
+	if userDao == nil {
+		return nil, fmt.Errorf("can not create auth service. userDao is nil")
+	}
+	if len(signingKey) == 0 {
+		return nil, fmt.Errorf("can not create auth service. signingKey is empty")
+	}
+
+	return &authService{
+		userDao:               userDao,
+		signer:                tokenSigner{key: signingKey},
+		accessTokenTtl:        accessTokenTtl,
+		refreshTokenTtl:       refreshTokenTtl,
+		passwordResetNotifier: passwordResetNotifier,
+	}, nil
+}
+
+func (a authService) Login(ctx context.Context, request LoginRequest) (TokenResponse, error) {
+	var (
+		tx         *sql.Tx
+		email      *mail.Address
+		credential dao.UserCredential
+		found      bool
+		response   TokenResponse
+		err        error
+	)
+
+	// The reason a login failed is not given, so that it can not be used to find out which emails have accounts
+	loginFailed := pkg.ValidationErrorWithError(pkg.ErrAuthenticationFailed, "Email or password is incorrect", nil)
+
+	if email, err = mail.ParseAddress(request.Email); err != nil {
+		return TokenResponse{}, loginFailed
+	}
+
+	if tx, err = a.userDao.BeginTx(); err != nil {
+		return TokenResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, "Login")
+
+	if credential, found, err = a.userDao.GetCredentialByEmailTx(ctx, email.Address, tx); err != nil {
+		return TokenResponse{}, err
+	}
+
+	if !found {
+		_ = timingPasswordHash.Matches(request.Password)
+		return TokenResponse{}, loginFailed
+	}
+
+	if !credential.PasswordHash.Matches(request.Password) {
+		return TokenResponse{}, loginFailed
+	}
+
+	if response, err = a.makeTokenResponseTx(ctx, credential.UserId, time.Now(), tx); err != nil {
+		return TokenResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return TokenResponse{}, err
+	}
+
+	return response, nil
+}
+
+func (a authService) Refresh(ctx context.Context, request RefreshTokenRequest) (TokenResponse, error) {
+	var (
+		tx       *sql.Tx
+		userId   ledger.UserId
+		tokenId  string
+		unused   bool
+		response TokenResponse
+		err      error
+		now      = time.Now()
+	)
+
+	invalidToken := pkg.ValidationErrorWithFields(pkg.ErrAccessTokenInvalid, "Refresh token is invalid or has expired", nil, map[string]string{
+		"refreshToken": "refreshToken must be the latest refresh token returned when logging in or refreshing",
+	})
+
+	if userId, tokenId, err = a.signer.verify(request.RefreshToken, refreshTokenType, now); err != nil || len(tokenId) == 0 {
+		return TokenResponse{}, invalidToken
+	}
+
+	if tx, err = a.userDao.BeginTx(); err != nil {
+		return TokenResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("Refresh: %d", userId))
+
+	// The tokens of a user are deleted with the user, so a token of a deleted user is not found either
+	if unused, err = a.userDao.UseRefreshTokenTx(ctx, tokenId, userId, now, tx); err != nil {
+		return TokenResponse{}, err
+	}
+
+	if !unused {
+		// The token was signed by us but has already been exchanged or revoked, so it may have leaked.
+		// Every refresh token of the user is revoked, and the user has to log in again.
+		if err = a.userDao.RevokeRefreshTokensTx(ctx, userId, tx); err != nil {
+			return TokenResponse{}, err
+		}
+		if err = dao.Commit(tx); err != nil {
+			return TokenResponse{}, err
+		}
+		return TokenResponse{}, invalidToken
+	}
+
+	if response, err = a.makeTokenResponseTx(ctx, userId, now, tx); err != nil {
+		return TokenResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return TokenResponse{}, err
+	}
+
+	return response, nil
+}
+
+func (a authService) Authenticate(accessToken string) (ledger.UserId, error) {
+	userId, _, err := a.signer.verify(accessToken, accessTokenType, time.Now())
+	if err != nil {
+		return 0, pkg.ValidationErrorWithError(pkg.ErrAccessTokenInvalid, "Access token is invalid or has expired", err)
+	}
+	return userId, nil
+}
+
+func (a authService) SetPassword(ctx context.Context, request SetPasswordRequest) error {
+	var (
+		userId       ledger.UserId
+		tx           *sql.Tx
+		credential   dao.UserCredential
+		hasPassword  bool
+		passwordHash ledger.PasswordHash
+		err          error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return err
+	}
+
+	if passwordHash, err = ledger.HashPassword(request.Password); err != nil {
+		return err
+	}
+
+	if tx, err = a.userDao.BeginTx(); err != nil {
+		return err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("SetPassword: %d", userId))
+
+	if credential, hasPassword, err = a.userDao.GetCredentialTx(ctx, userId, tx); err != nil {
+		return err
+	}
+
+	if hasPassword && !credential.PasswordHash.Matches(request.CurrentPassword) {
+		return pkg.ValidationErrorWithFields(pkg.ErrUserPasswordValidation, "Current password is incorrect", nil, map[string]string{
+			"currentPassword": "currentPassword must be the password the user logs in with",
+		})
+	}
+
+	if err = a.savePasswordTx(ctx, userId, passwordHash, tx); err != nil {
+		return err
+	}
+
+	return dao.Commit(tx)
+}
+
+func (a authService) RequestPasswordReset(ctx context.Context, request PasswordResetRequest) error {
+	var (
+		email  *mail.Address
+		tx     *sql.Tx
+		userId ledger.UserId
+		found  bool
+		token  string
+		err    error
+		now    = time.Now().UTC()
+	)
+
+	if a.passwordResetNotifier == nil {
+		return errPasswordResetUnavailable
+	}
+
+	if email, err = mail.ParseAddress(request.Email); err != nil {
+		return pkg.ValidationErrorWithFields(pkg.ErrUserEmailInvalid, "Invalid email", err, map[string]string{
+			"email": "email must be the email address of the user",
+		})
+	}
+
+	if token, err = makeOneTimeToken(); err != nil {
+		return pkg.NewSystemError(pkg.ErrUnknown, "Failed to create reset token", err)
+	}
+
+	if tx, err = a.userDao.BeginTx(); err != nil {
+		return err
+	}
+
+	defer dao.DeferRollback(tx, "RequestPasswordReset")
+
+	// Whether the email has an account is not revealed, so that it can not be used to find out which emails have accounts
+	if userId, found, err = a.userDao.GetUserIdByEmailTx(ctx, email.Address, tx); err != nil || !found {
+		return err
+	}
+
+	reset := dao.PasswordReset{
+		UserId:         userId,
+		TokenHash:      hashOneTimeToken(token),
+		TokenExpiresAt: now.Add(passwordResetTokenValidity),
+		RequestedAt:    now,
+	}
+	if err = a.userDao.SavePasswordResetTx(ctx, reset, tx); err != nil {
+		return err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return err
+	}
+
+	if err = a.passwordResetNotifier.Notify(ctx, PasswordResetNotice{
+		UserId:    userId,
+		Email:     email.Address,
+		Token:     token,
+		ExpiresAt: reset.TokenExpiresAt,
+	}); err != nil {
+		return pkg.NewSystemError(pkg.ErrUnknown, "Failed to send reset token", err)
+	}
+	return nil
+}
+
+func (a authService) ResetPassword(ctx context.Context, request ResetPasswordRequest) error {
+	var (
+		tx           *sql.Tx
+		reset        dao.PasswordReset
+		found        bool
+		passwordHash ledger.PasswordHash
+		err          error
+		now          = time.Now().UTC()
+	)
+
+	if a.passwordResetNotifier == nil {
+		return errPasswordResetUnavailable
+	}
+
+	if tx, err = a.userDao.BeginTx(); err != nil {
+		return err
+	}
+
+	defer dao.DeferRollback(tx, "ResetPassword")
+
+	if reset, found, err = a.userDao.GetPasswordResetByTokenHashTx(ctx, hashOneTimeToken(request.Token), tx); err != nil {
+		return err
+	}
+
+	if !found || !now.Before(reset.TokenExpiresAt) {
+		return pkg.ValidationErrorWithFields(pkg.ErrPasswordResetTokenInvalid, "Reset token is invalid or has expired", nil, map[string]string{
+			"token": "token must be the latest token sent to the email of the user",
+		})
+	}
+
+	if passwordHash, err = ledger.HashPassword(request.Password); err != nil {
+		return err
+	}
+
+	if err = a.savePasswordTx(ctx, reset.UserId, passwordHash, tx); err != nil {
+		return err
+	}
+
+	return dao.Commit(tx)
+}
+
+// savePasswordTx replaces the password of a user. The reset token of the user can no longer be used,
+// and sessions opened before the change can not be refreshed.
+func (a authService) savePasswordTx(ctx context.Context, userId ledger.UserId, passwordHash ledger.PasswordHash, tx *sql.Tx) error {
+	if err := a.userDao.SaveCredentialTx(ctx, dao.UserCredential{
+		UserId:       userId,
+		PasswordHash: passwordHash,
+		UpdatedAt:    time.Now().UTC(),
+	}, tx); err != nil {
+		return err
+	}
+	if err := a.userDao.DeletePasswordResetTx(ctx, userId, tx); err != nil {
+		return err
+	}
+	return a.userDao.RevokeRefreshTokensTx(ctx, userId, tx)
+}
+
+// makeTokenResponseTx signs new tokens for a user and saves the id of the refresh token, so that it can only be exchanged once.
+func (a authService) makeTokenResponseTx(ctx context.Context, userId ledger.UserId, now time.Time, tx *sql.Tx) (TokenResponse, error) {
+	var (
+		accessToken  string
+		refreshToken string
+		tokenId      string
+		err          error
+	)
+
+	if tokenId, err = makeRefreshTokenId(); err != nil {
+		return TokenResponse{}, pkg.NewSystemError(pkg.ErrUnknown, "Failed to create refresh token", err)
+	}
+	if accessToken, err = a.signer.sign(userId, accessTokenType, "", now, a.accessTokenTtl); err != nil {
+		return TokenResponse{}, pkg.NewSystemError(pkg.ErrUnknown, "Failed to sign access token", err)
+	}
+	if refreshToken, err = a.signer.sign(userId, refreshTokenType, tokenId, now, a.refreshTokenTtl); err != nil {
+		return TokenResponse{}, pkg.NewSystemError(pkg.ErrUnknown, "Failed to sign refresh token", err)
+	}
+
+	if err = a.userDao.SaveRefreshTokenTx(ctx, dao.RefreshToken{
+		Id:        tokenId,
+		UserId:    userId,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(a.refreshTokenTtl),
+	}, tx); err != nil {
+		return TokenResponse{}, err
+	}
+
+	return TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(a.accessTokenTtl / time.Second),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func makeRefreshTokenId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
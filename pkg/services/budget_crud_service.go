@@ -2,35 +2,106 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/w-k-s/simple-budget-tracker/pkg"
 	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
 	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
 )
 
+// CategoryBudgetRequest is the maximum amount of a category in a budget.
+// AlertThresholds are the percentages of the maximum amount at which alerts are raised; 50%, 80% and 100% are used when they are not provided.
+// Rollover is one of None (the default), SurplusOnly or SurplusAndDeficit.
 type CategoryBudgetRequest struct {
-	CategoryId uint64         `json:"categoryId"`
-	MaxAmount  AmountResponse `json:"maxAmount"`
+	CategoryId      uint64         `json:"categoryId"`
+	MaxAmount       AmountResponse `json:"maxAmount"`
+	AlertThresholds []uint         `json:"alertThresholds"`
+	Rollover        string         `json:"rollover"`
 }
 
+// CreateBudgetRequest limits the amount spent on categories across accounts in each period.
+// PeriodAnchor, formatted as yyyy-MM-dd, is the first day of one of the periods of a BiWeek budget, such as a payday.
+// PeriodStartDay, between 1 and 28, is the day on which the months of a MonthStartingOnDay budget start.
 type CreateBudgetRequest struct {
 	AccountIds      []uint64                `json:"accountIds"`
 	PeriodType      string                  `json:"period"`
+	PeriodAnchor    string                  `json:"periodAnchor,omitempty"`
+	PeriodStartDay  uint                    `json:"periodStartDay,omitempty"`
 	CategoryBudgets []CategoryBudgetRequest `json:"categoryBudgets"`
 }
 
+// UpdateBudgetRequest replaces the accounts, period and category budgets of a budget.
+// Version is the version of the budget the client last saw; it can also be provided with the If-Match header.
+type UpdateBudgetRequest struct {
+	CreateBudgetRequest
+	Version uint64 `json:"version"`
+}
+
+// BudgetVersionRequest is used to delete a budget.
+// Version is the version of the budget the client last saw; it can also be provided with the If-Match header.
+type BudgetVersionRequest struct {
+	Version uint64 `json:"version"`
+}
+
 type BudgetResponse struct {
 	Id              uint64                  `json:"id"`
 	AccountIds      []uint64                `json:"accountIds"`
 	PeriodType      string                  `json:"period"`
+	PeriodAnchor    string                  `json:"periodAnchor,omitempty"`
+	PeriodStartDay  uint                    `json:"periodStartDay,omitempty"`
 	CategoryBudgets []CategoryBudgetRequest `json:"categoryBudgets"`
+	Version         uint64                  `json:"version"`
+}
+
+type BudgetsResponse struct {
+	Budgets []BudgetResponse `json:"budgets"`
+}
+
+func makeBudgetResponse(budget ledger.Budget) BudgetResponse {
+	categoryBudgets := []CategoryBudgetRequest{}
+	for _, categoryBudget := range budget.CategoryBudgets() {
+		categoryBudgets = append(categoryBudgets, CategoryBudgetRequest{
+			CategoryId: uint64(categoryBudget.CategoryId()),
+			MaxAmount: AmountResponse{
+				Currency: categoryBudget.MaxLimit().Currency().CurrencyCode(),
+				Value:    categoryBudget.MaxLimit().MustMinorUnits(),
+			},
+			AlertThresholds: append([]uint{}, categoryBudget.AlertThresholds()...),
+			Rollover:        string(categoryBudget.Rollover()),
+		})
+	}
+
+	var periodAnchor string
+	if anchorDate := budget.PeriodCalculator().AnchorDate(); !anchorDate.IsZero() {
+		periodAnchor = anchorDate.Format("2006-01-02")
+	}
+
+	return BudgetResponse{
+		Id:              uint64(budget.Id()),
+		AccountIds:      accountIdsToUint64(budget.AccountIds()),
+		PeriodType:      string(budget.PeriodType()),
+		PeriodAnchor:    periodAnchor,
+		PeriodStartDay:  budget.PeriodCalculator().StartDay(),
+		CategoryBudgets: categoryBudgets,
+		Version:         uint64(budget.Version()),
+	}
 }
 
 type BudgetService interface {
 	CreateBudget(ctx context.Context, request CreateBudgetRequest) (BudgetResponse, error)
 	GetBudget(ctx context.Context, budgetId ledger.BudgetId) (BudgetResponse, error)
+	GetBudgets(ctx context.Context) (BudgetsResponse, error)
+	UpdateBudget(ctx context.Context, budgetId ledger.BudgetId, request UpdateBudgetRequest) (BudgetResponse, error)
+	DeleteBudget(ctx context.Context, budgetId ledger.BudgetId, request BudgetVersionRequest) error
+	// GetBudgetProgress compares the expenses of each category of a budget in a period to the maximum amount of the category.
+	GetBudgetProgress(ctx context.Context, budgetId ledger.BudgetId, request BudgetProgressRequest) (BudgetProgressResponse, error)
+	// GetBudgetEnvelopes returns the balance of each category of a budget in each period, from the period in which the budget was created.
+	GetBudgetEnvelopes(ctx context.Context, budgetId ledger.BudgetId, request BudgetProgressRequest) (BudgetEnvelopesResponse, error)
+	// TransferBetweenEnvelopes moves part of the balance of a category of a budget to another category of the budget, in a period.
+	TransferBetweenEnvelopes(ctx context.Context, budgetId ledger.BudgetId, request EnvelopeTransferRequest) (EnvelopeTransferResponse, error)
+	GetEnvelopeTransfers(ctx context.Context, budgetId ledger.BudgetId) (EnvelopeTransfersResponse, error)
 }
 
 type budgetService struct {
@@ -38,6 +109,8 @@ type budgetService struct {
 	accountDao      dao.AccountDao
 	categoryDao     dao.CategoryDao
 	budgetDao       dao.BudgetDao
+	transferDao     dao.EnvelopeTransferDao
+	userDao         dao.UserDao
 }
 
 func NewBudgetService(
@@ -45,15 +118,26 @@ func NewBudgetService(
 	accountDao dao.AccountDao,
 	categoryDao dao.CategoryDao,
 	budgetDao dao.BudgetDao,
+	transferDao dao.EnvelopeTransferDao,
+	userDao dao.UserDao,
 ) (BudgetService, error) {
 	if uniqueIdService == nil {
-		log.Fatalf("can not create budget service. uniqueIdService is nil")
+		return nil, fmt.Errorf("can not create budget service. uniqueIdService is nil")
+	}
+	if accountDao == nil {
+		return nil, fmt.Errorf("can not create budget service. accountDao is nil")
 	}
 	if categoryDao == nil {
-		log.Fatalf("can not create budget service. categoryDao is nil")
+		return nil, fmt.Errorf("can not create budget service. categoryDao is nil")
 	}
 	if budgetDao == nil {
-		log.Fatalf("can not create budget service. budgetDao is nil")
+		return nil, fmt.Errorf("can not create budget service. budgetDao is nil")
+	}
+	if transferDao == nil {
+		return nil, fmt.Errorf("can not create budget service. transferDao is nil")
+	}
+	if userDao == nil {
+		return nil, fmt.Errorf("can not create budget service. userDao is nil")
 	}
 
 	return &budgetService{
@@ -61,119 +145,375 @@ func NewBudgetService(
 		accountDao:      accountDao,
 		categoryDao:     categoryDao,
 		budgetDao:       budgetDao,
+		transferDao:     transferDao,
+		userDao:         userDao,
 	}, nil
 }
 
 func (svc budgetService) CreateBudget(ctx context.Context, request CreateBudgetRequest) (BudgetResponse, error) {
+	var (
+		userId           ledger.UserId
+		tx               *sql.Tx
+		id               uint64
+		periodCalculator ledger.PeriodCalculator
+		categoryBudgets  ledger.CategoryBudgets
+		budget           ledger.Budget
+		err              error
+	)
 
-	userId, err := RequireUserId(ctx)
-	if err != nil {
+	if userId, err = RequireUserId(ctx); err != nil {
 		return BudgetResponse{}, err
 	}
 
-	tx := svc.budgetDao.MustBeginTx()
-	defer dao.DeferRollback(tx, fmt.Sprintf("CreateBudget: %d", userId))
+	if tx, err = svc.budgetDao.BeginTx(); err != nil {
+		return BudgetResponse{}, err
+	}
 
-	accountIds := uint64ToAccountIds(request.AccountIds)
+	defer dao.DeferRollback(tx, fmt.Sprintf("CreateBudget: %d", userId))
 
-	// Ensure that the currency of the accounts = currency of the budget.
-	if len(request.CategoryBudgets) > 0 {
-		// Currencies of the budget should be the same (validated later), so we'll just take the first one
-		budgetCurrency := request.CategoryBudgets[0].MaxAmount.Currency
-		accountCurrenciesMap, err := svc.accountDao.GetCurrenciesOfAccounts(ctx, accountIds, userId, tx)
-		if err != nil {
-			return BudgetResponse{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to get account currencies", err)
-		}
+	if periodCalculator, err = makePeriodCalculator(request); err != nil {
+		return BudgetResponse{}, err
+	}
 
-		for _, currency := range accountCurrenciesMap {
-			if currency.CurrencyCode() != budgetCurrency {
-				return BudgetResponse{}, pkg.ValidationErrorWithError(pkg.ErrBudgetValidation, "Budget currency must match account currencies", nil)
-			}
+	accountIds := uint64ToAccountIds(request.AccountIds)
+	if len(accountIds) == 0 {
+		if accountIds, err = svc.defaultAccountIdsTx(ctx, userId, tx); err != nil {
+			return BudgetResponse{}, err
 		}
 	}
 
-	// Ensure the budget categories belong to the user.
-	categories, err := svc.categoryDao.GetCategoriesForUser(ctx, userId, tx)
-	if err != nil {
-		return BudgetResponse{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to get categories for user", err)
+	if categoryBudgets, err = svc.makeCategoryBudgets(ctx, userId, 0, accountIds, request.CategoryBudgets, tx); err != nil {
+		return BudgetResponse{}, err
 	}
 
-	categoryIdMap := categories.MapById()
-	categoryBudgets := ledger.CategoryBudgets{}
-	for _, budget := range request.CategoryBudgets {
-		if category, ok := categoryIdMap[ledger.CategoryId(budget.CategoryId)]; ok {
-			limit, err := ledger.NewMoney(budget.MaxAmount.Currency, budget.MaxAmount.Value)
-			if err != nil {
-				return BudgetResponse{}, err
-			}
-			categoryBudget, err := ledger.NewCategoryBudget(
-				category.Id(),
-				limit,
-			)
-			categoryBudgets = append(categoryBudgets, categoryBudget)
-		}
+	if id, err = svc.uniqueIdService.GetId(EntityBudget); err != nil {
+		return BudgetResponse{}, err
 	}
 
-	budget, err := ledger.NewBudget(
-		ledger.BudgetId(svc.uniqueIdService.MustGetId(EntityBudget)),
+	if budget, err = ledger.NewBudgetWithPeriodCalculator(
+		ledger.BudgetId(id),
 		accountIds,
-		ledger.BudgetPeriodType(request.PeriodType),
-		nil,
+		periodCalculator,
+		categoryBudgets,
 		ledger.MustMakeUpdatedByUserId(userId),
-	)
-	if err != nil {
+	); err != nil {
 		return BudgetResponse{}, err
 	}
 
-	err = svc.budgetDao.Save(ctx, userId, budget, tx)
-	if err != nil {
+	if err = svc.budgetDao.Save(ctx, userId, budget, tx); err != nil {
 		return BudgetResponse{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save budget", err)
 	}
 
 	if err = dao.Commit(tx); err != nil {
-		return BudgetResponse{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to save budget", err)
+		return BudgetResponse{}, err
 	}
 
-	return BudgetResponse{
-		Id:              uint64(budget.Id()),
-		AccountIds:      request.AccountIds,
-		PeriodType:      request.PeriodType,
-		CategoryBudgets: request.CategoryBudgets,
-	}, nil
+	return makeBudgetResponse(budget), nil
+}
+
+// defaultAccountIdsTx returns the default account of the user, which a budget created without accounts applies to.
+// No account is returned if the user has no default account, in which case the budget is not created.
+func (svc budgetService) defaultAccountIdsTx(ctx context.Context, userId ledger.UserId, tx *sql.Tx) (ledger.AccountIds, error) {
+	user, err := svc.userDao.GetUserByIdTx(ctx, userId, tx)
+	if err != nil {
+		return nil, err
+	}
+	if user.Preferences().DefaultAccountId() == ledger.NoDefaultAccount {
+		return ledger.AccountIds{}, nil
+	}
+	return ledger.AccountIds{user.Preferences().DefaultAccountId()}, nil
 }
 
 func (svc budgetService) GetBudget(ctx context.Context, budgetId ledger.BudgetId) (BudgetResponse, error) {
+	var (
+		userId ledger.UserId
+		tx     *sql.Tx
+		budget ledger.Budget
+		err    error
+	)
 
-	userId, err := RequireUserId(ctx)
-	if err != nil {
+	if userId, err = RequireUserId(ctx); err != nil {
+		return BudgetResponse{}, err
+	}
+
+	if tx, err = svc.budgetDao.BeginTx(); err != nil {
 		return BudgetResponse{}, err
 	}
 
-	tx := svc.budgetDao.MustBeginTx()
 	defer dao.DeferRollback(tx, fmt.Sprintf("GetBudget: %d", userId))
 
-	budget, err := svc.budgetDao.GetBudgetById(ctx, budgetId, userId, tx)
-	if err != nil {
+	if budget, err = svc.budgetDao.GetBudgetById(ctx, budgetId, userId, tx); err != nil {
 		return BudgetResponse{}, err
 	}
 
-	categoryBudgets := []CategoryBudgetRequest{}
-	for _, categoryBudget := range budget.CategoryBudgets() {
-		categoryBudgets = append(categoryBudgets, CategoryBudgetRequest{
-			CategoryId: uint64(categoryBudget.CategoryId()),
-			MaxAmount: AmountResponse{
-				Currency: categoryBudget.MaxLimit().Currency().CurrencyCode(),
-				Value:    categoryBudget.MaxLimit().MustMinorUnits(),
-			},
+	if err = dao.Commit(tx); err != nil {
+		return BudgetResponse{}, err
+	}
+
+	return makeBudgetResponse(budget), nil
+}
+
+func (svc budgetService) GetBudgets(ctx context.Context) (BudgetsResponse, error) {
+	var (
+		userId  ledger.UserId
+		tx      *sql.Tx
+		budgets []ledger.Budget
+		err     error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return BudgetsResponse{}, err
+	}
+
+	if tx, err = svc.budgetDao.BeginTx(); err != nil {
+		return BudgetsResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("GetBudgets: %d", userId))
+
+	if budgets, err = svc.budgetDao.GetBudgetsForUser(ctx, userId, tx); err != nil {
+		return BudgetsResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return BudgetsResponse{}, err
+	}
+
+	resp := BudgetsResponse{Budgets: make([]BudgetResponse, 0, len(budgets))}
+	for _, budget := range budgets {
+		resp.Budgets = append(resp.Budgets, makeBudgetResponse(budget))
+	}
+	return resp, nil
+}
+
+func (svc budgetService) UpdateBudget(ctx context.Context, budgetId ledger.BudgetId, request UpdateBudgetRequest) (BudgetResponse, error) {
+	var (
+		userId           ledger.UserId
+		tx               *sql.Tx
+		budget           ledger.Budget
+		periodCalculator ledger.PeriodCalculator
+		categoryBudgets  ledger.CategoryBudgets
+		err              error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return BudgetResponse{}, err
+	}
+
+	if tx, err = svc.budgetDao.BeginTx(); err != nil {
+		return BudgetResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("UpdateBudget: %d", userId))
+
+	if budget, err = svc.getBudgetOfVersion(ctx, budgetId, userId, request.Version, tx); err != nil {
+		return BudgetResponse{}, err
+	}
+
+	if periodCalculator, err = makePeriodCalculator(request.CreateBudgetRequest); err != nil {
+		return BudgetResponse{}, err
+	}
+
+	accountIds := uint64ToAccountIds(request.AccountIds)
+	if categoryBudgets, err = svc.makeCategoryBudgets(ctx, userId, budgetId, accountIds, request.CategoryBudgets, tx); err != nil {
+		return BudgetResponse{}, err
+	}
+
+	if budget, err = budget.EditWithPeriodCalculator(
+		accountIds,
+		periodCalculator,
+		categoryBudgets,
+		ledger.MustMakeUpdatedByUserId(userId),
+	); err != nil {
+		return BudgetResponse{}, err
+	}
+
+	if err = svc.budgetDao.UpdateTx(ctx, userId, budget, tx); err != nil {
+		return BudgetResponse{}, err
+	}
+
+	// The version is assigned by the database
+	if budget, err = svc.budgetDao.GetBudgetById(ctx, budgetId, userId, tx); err != nil {
+		return BudgetResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return BudgetResponse{}, err
+	}
+
+	return makeBudgetResponse(budget), nil
+}
+
+func (svc budgetService) DeleteBudget(ctx context.Context, budgetId ledger.BudgetId, request BudgetVersionRequest) error {
+	var (
+		userId ledger.UserId
+		tx     *sql.Tx
+		budget ledger.Budget
+		err    error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return err
+	}
+
+	if tx, err = svc.budgetDao.BeginTx(); err != nil {
+		return err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("DeleteBudget: %d", userId))
+
+	if budget, err = svc.getBudgetOfVersion(ctx, budgetId, userId, request.Version, tx); err != nil {
+		return err
+	}
+
+	if err = svc.budgetDao.DeleteTx(ctx, budgetId, userId, budget.Version(), tx); err != nil {
+		return err
+	}
+
+	return dao.Commit(tx)
+}
+
+func (svc budgetService) getBudgetOfVersion(
+	ctx context.Context,
+	id ledger.BudgetId,
+	userId ledger.UserId,
+	version uint64,
+	tx *sql.Tx,
+) (ledger.Budget, error) {
+	if version == 0 {
+		return ledger.Budget{}, pkg.ValidationErrorWithFields(pkg.ErrBudgetValidation, "The version of the budget is required", nil, map[string]string{
+			"version": "version must be provided in the request body or in the If-Match header",
 		})
 	}
 
-	return BudgetResponse{
-		Id:              uint64(budget.Id()),
-		AccountIds:      accountIdsToUint64(budget.AccountIds()),
-		PeriodType:      string(budget.PeriodType()),
-		CategoryBudgets: categoryBudgets,
-	}, err
+	budget, err := svc.budgetDao.GetBudgetById(ctx, id, userId, tx)
+	if err != nil {
+		return ledger.Budget{}, err
+	}
+
+	if budget.Version() != ledger.Version(version) {
+		return ledger.Budget{}, pkg.ValidationErrorWithFields(
+			pkg.ErrBudgetVersionConflict,
+			fmt.Sprintf("Budget %d has been changed. Expected version %d but found version %d", id, version, budget.Version()),
+			nil,
+			nil,
+		)
+	}
+	return budget, nil
+}
+
+// makeCategoryBudgets checks that the accounts and categories of a budget belong to the user, and that the accounts are in the currency of the budget.
+// A category can only be budgeted by one budget; budgetId is the budget being updated, or zero for a new budget.
+func (svc budgetService) makeCategoryBudgets(
+	ctx context.Context,
+	userId ledger.UserId,
+	budgetId ledger.BudgetId,
+	accountIds ledger.AccountIds,
+	requests []CategoryBudgetRequest,
+	tx *sql.Tx,
+) (ledger.CategoryBudgets, error) {
+	var (
+		currencies map[ledger.AccountId]ledger.Currency
+		categories ledger.Categories
+		budgets    []ledger.Budget
+		err        error
+	)
+
+	if currencies, err = svc.accountDao.GetCurrenciesOfAccounts(ctx, accountIds, userId, tx); err != nil {
+		return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to get account currencies", err)
+	}
+
+	for _, accountId := range accountIds {
+		if _, ok := currencies[accountId]; !ok {
+			return nil, pkg.ValidationErrorWithFields(pkg.ErrAccountNotFound, fmt.Sprintf("Account #%d not found", accountId), nil, map[string]string{
+				"accountIds": fmt.Sprintf("account %d does not exist", accountId),
+			})
+		}
+	}
+
+	// Currencies of the budget should be the same (validated later), so we'll just take the first one
+	if len(requests) > 0 {
+		budgetCurrency := requests[0].MaxAmount.Currency
+		for _, currency := range currencies {
+			if currency.CurrencyCode() != budgetCurrency {
+				return nil, pkg.ValidationErrorWithFields(pkg.ErrBudgetValidation, "Budget currency must match account currencies", nil, map[string]string{
+					"categoryBudgets": fmt.Sprintf("maxAmount must be in %s, the currency of the accounts", currency.CurrencyCode()),
+				})
+			}
+		}
+	}
+
+	if categories, err = svc.categoryDao.GetCategoriesForUser(ctx, userId, tx); err != nil {
+		return nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to get categories for user", err)
+	}
+
+	if budgets, err = svc.budgetDao.GetBudgetsForUser(ctx, userId, tx); err != nil {
+		return nil, err
+	}
+
+	budgeted := map[ledger.CategoryId]ledger.BudgetId{}
+	for _, budget := range budgets {
+		for _, categoryBudget := range budget.CategoryBudgets() {
+			budgeted[categoryBudget.CategoryId()] = budget.Id()
+		}
+	}
+
+	categoryIdMap := categories.MapById()
+	categoryBudgets := ledger.CategoryBudgets{}
+	for _, request := range requests {
+		var (
+			category       ledger.Category
+			limit          ledger.Money
+			categoryBudget ledger.CategoryBudget
+			ok             bool
+		)
+
+		if category, ok = categoryIdMap[ledger.CategoryId(request.CategoryId)]; !ok {
+			return nil, pkg.ValidationErrorWithFields(pkg.ErrCategoriesNotFound, fmt.Sprintf("Category #%d not found", request.CategoryId), nil, map[string]string{
+				"categoryBudgets": fmt.Sprintf("category %d does not exist", request.CategoryId),
+			})
+		}
+
+		if otherBudgetId, ok := budgeted[category.Id()]; ok && otherBudgetId != budgetId {
+			return nil, pkg.ValidationErrorWithFields(pkg.ErrBudgetValidation, fmt.Sprintf("Category %q is already budgeted", category.Name()), nil, map[string]string{
+				"categoryBudgets": fmt.Sprintf("category %d is already budgeted by budget %d", category.Id(), otherBudgetId),
+			})
+		}
+
+		if limit, err = ledger.NewMoney(request.MaxAmount.Currency, request.MaxAmount.Value); err != nil {
+			return nil, err
+		}
+
+		alertThresholds := ledger.DefaultAlertThresholds()
+		if request.AlertThresholds != nil {
+			alertThresholds = ledger.AlertThresholds(request.AlertThresholds)
+		}
+
+		rollover := ledger.RolloverNone
+		if len(request.Rollover) != 0 {
+			rollover = ledger.RolloverMode(request.Rollover)
+		}
+
+		if categoryBudget, err = ledger.NewCategoryBudgetWithRollover(category.Id(), limit, alertThresholds, rollover); err != nil {
+			return nil, err
+		}
+		categoryBudgets = append(categoryBudgets, categoryBudget)
+	}
+	return categoryBudgets, nil
+}
+
+// makePeriodCalculator reads the period of a budget request. The anchor date and the start day are only read for the period types that use them.
+func makePeriodCalculator(request CreateBudgetRequest) (ledger.PeriodCalculator, error) {
+	var anchorDate time.Time
+	if len(request.PeriodAnchor) != 0 {
+		var err error
+		if anchorDate, err = time.Parse("2006-01-02", request.PeriodAnchor); err != nil {
+			return ledger.PeriodCalculator{}, pkg.ValidationErrorWithFields(pkg.ErrBudgetValidation, fmt.Sprintf("Invalid period anchor %q", request.PeriodAnchor), err, map[string]string{
+				"periodAnchor": "periodAnchor must be a date formatted as yyyy-MM-dd",
+			})
+		}
+	}
+	return ledger.NewPeriodCalculator(ledger.BudgetPeriodType(request.PeriodType), anchorDate, request.PeriodStartDay)
 }
 
 func uint64ToAccountIds(ids []uint64) ledger.AccountIds {
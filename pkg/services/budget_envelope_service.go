@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+type EnvelopeResponse struct {
+	CategoryId  uint64         `json:"categoryId"`
+	Rollover    string         `json:"rollover"`
+	Allocated   AmountResponse `json:"allocated"`
+	CarriedOver AmountResponse `json:"carriedOver"`
+	Transferred AmountResponse `json:"transferred"`
+	Available   AmountResponse `json:"available"`
+	Spent       AmountResponse `json:"spent"`
+	Balance     AmountResponse `json:"balance"`
+}
+
+type PeriodEnvelopesResponse struct {
+	Period    BudgetPeriodResponse `json:"period"`
+	Envelopes []EnvelopeResponse   `json:"envelopes"`
+}
+
+// BudgetEnvelopesResponse lists the envelopes of a budget in each period, oldest first.
+type BudgetEnvelopesResponse struct {
+	BudgetId uint64                    `json:"budgetId"`
+	Periods  []PeriodEnvelopesResponse `json:"periods"`
+}
+
+// EnvelopeTransferRequest moves an amount from the envelope of a category of a budget to the envelope of another category.
+// Period is any date in the period of the transfer, formatted as yyyy-MM-dd. The current period is used when it is not provided. It can not be after the period that follows the current period.
+type EnvelopeTransferRequest struct {
+	FromCategoryId uint64         `json:"fromCategoryId"`
+	ToCategoryId   uint64         `json:"toCategoryId"`
+	Amount         AmountResponse `json:"amount"`
+	Period         string         `json:"period"`
+	Note           string         `json:"note"`
+}
+
+type EnvelopeTransferResponse struct {
+	Id             uint64               `json:"id"`
+	BudgetId       uint64               `json:"budgetId"`
+	FromCategoryId uint64               `json:"fromCategoryId"`
+	ToCategoryId   uint64               `json:"toCategoryId"`
+	Period         BudgetPeriodResponse `json:"period"`
+	Amount         AmountResponse       `json:"amount"`
+	Note           string               `json:"note"`
+	CreatedBy      UpdatedByResponse    `json:"createdBy"`
+	CreatedAt      string               `json:"createdAt"`
+}
+
+type EnvelopeTransfersResponse struct {
+	Transfers []EnvelopeTransferResponse `json:"transfers"`
+}
+
+func makeAmountResponse(money ledger.Money) AmountResponse {
+	return AmountResponse{
+		Currency: money.Currency().CurrencyCode(),
+		Value:    money.MustMinorUnits(),
+	}
+}
+
+func makeBudgetPeriodResponse(period ledger.BudgetPeriod) BudgetPeriodResponse {
+	return BudgetPeriodResponse{
+		Type: string(period.PeriodType()),
+		From: period.FirstDay().Format("2006-01-02"),
+		To:   period.LastDay().Format("2006-01-02"),
+	}
+}
+
+func makeBudgetEnvelopesResponse(budget ledger.Budget, history []ledger.PeriodEnvelopes) BudgetEnvelopesResponse {
+	resp := BudgetEnvelopesResponse{
+		BudgetId: uint64(budget.Id()),
+		Periods:  make([]PeriodEnvelopesResponse, 0, len(history)),
+	}
+	for _, periodEnvelopes := range history {
+		envelopes := make([]EnvelopeResponse, 0, len(periodEnvelopes.Envelopes()))
+		for _, envelope := range periodEnvelopes.Envelopes() {
+			envelopes = append(envelopes, EnvelopeResponse{
+				CategoryId:  uint64(envelope.CategoryId()),
+				Rollover:    string(envelope.Rollover()),
+				Allocated:   makeAmountResponse(envelope.Allocated()),
+				CarriedOver: makeAmountResponse(envelope.CarriedOver()),
+				Transferred: makeAmountResponse(envelope.Transferred()),
+				Available:   makeAmountResponse(envelope.Available()),
+				Spent:       makeAmountResponse(envelope.Spent()),
+				Balance:     makeAmountResponse(envelope.Balance()),
+			})
+		}
+		resp.Periods = append(resp.Periods, PeriodEnvelopesResponse{
+			Period:    makeBudgetPeriodResponse(periodEnvelopes.Period()),
+			Envelopes: envelopes,
+		})
+	}
+	return resp
+}
+
+func makeEnvelopeTransferResponse(transfer ledger.EnvelopeTransfer) EnvelopeTransferResponse {
+	return EnvelopeTransferResponse{
+		Id:             uint64(transfer.Id()),
+		BudgetId:       uint64(transfer.BudgetId()),
+		FromCategoryId: uint64(transfer.FromCategoryId()),
+		ToCategoryId:   uint64(transfer.ToCategoryId()),
+		Period:         makeBudgetPeriodResponse(transfer.Period()),
+		Amount:         makeAmountResponse(transfer.Amount()),
+		Note:           transfer.Note(),
+		CreatedBy:      makeUpdatedByResponse(transfer.CreatedBy()),
+		CreatedAt:      transfer.CreatedAtUTC().Format(time.RFC3339),
+	}
+}
+
+func (svc budgetService) GetBudgetEnvelopes(ctx context.Context, budgetId ledger.BudgetId, request BudgetProgressRequest) (BudgetEnvelopesResponse, error) {
+	var (
+		userId  ledger.UserId
+		date    time.Time
+		tx      *sql.Tx
+		profile ledger.UserProfile
+		budget  ledger.Budget
+		history []ledger.PeriodEnvelopes
+		err     error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return BudgetEnvelopesResponse{}, err
+	}
+
+	if tx, err = svc.budgetDao.BeginTx(); err != nil {
+		return BudgetEnvelopesResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("GetBudgetEnvelopes: %d", userId))
+
+	if profile, err = getUserProfileTx(ctx, svc.userDao, userId, tx); err != nil {
+		return BudgetEnvelopesResponse{}, err
+	}
+
+	if date, err = parsePeriodDate(request.Period, profile); err != nil {
+		return BudgetEnvelopesResponse{}, err
+	}
+
+	if budget, err = svc.budgetDao.GetBudgetById(ctx, budgetId, userId, tx); err != nil {
+		return BudgetEnvelopesResponse{}, err
+	}
+
+	if err = requireEnvelopePeriod(budget, date, profile); err != nil {
+		return BudgetEnvelopesResponse{}, err
+	}
+
+	if history, err = svc.envelopeHistoryTx(ctx, userId, budget, date, tx); err != nil {
+		return BudgetEnvelopesResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return BudgetEnvelopesResponse{}, err
+	}
+
+	return makeBudgetEnvelopesResponse(budget, history), nil
+}
+
+func (svc budgetService) TransferBetweenEnvelopes(ctx context.Context, budgetId ledger.BudgetId, request EnvelopeTransferRequest) (EnvelopeTransferResponse, error) {
+	var (
+		userId   ledger.UserId
+		date     time.Time
+		tx       *sql.Tx
+		profile  ledger.UserProfile
+		budget   ledger.Budget
+		id       ledger.EnvelopeTransferId
+		amount   ledger.Money
+		transfer ledger.EnvelopeTransfer
+		history  []ledger.PeriodEnvelopes
+		err      error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	if amount, err = ledger.NewMoney(request.Amount.Currency, request.Amount.Value); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	if tx, err = svc.transferDao.BeginTx(); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("TransferBetweenEnvelopes: %d", userId))
+
+	if profile, err = getUserProfileTx(ctx, svc.userDao, userId, tx); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	if date, err = parsePeriodDate(request.Period, profile); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	// Transfers of a budget are made one at a time, so that two transfers can not both spend the same balance
+	if err = svc.budgetDao.LockBudgetTx(ctx, budgetId, userId, tx); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	if budget, err = svc.budgetDao.GetBudgetById(ctx, budgetId, userId, tx); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	if err = requireEnvelopePeriod(budget, date, profile); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	if id, err = svc.transferDao.NewEnvelopeTransferId(tx); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	if transfer, err = ledger.NewEnvelopeTransfer(
+		id,
+		budget,
+		ledger.CategoryId(request.FromCategoryId),
+		ledger.CategoryId(request.ToCategoryId),
+		budget.PeriodCalculator().PeriodOf(date),
+		amount,
+		request.Note,
+		ledger.MustMakeUpdatedByUserId(userId),
+	); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	// Only the balance of the source envelope in the period of the transfer can be moved
+	if history, err = svc.envelopeHistoryTx(ctx, userId, budget, date, tx); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+	source, _ := history[len(history)-1].Envelope(transfer.FromCategoryId())
+	if source.Balance().MustMinorUnits() < amount.MustMinorUnits() {
+		return EnvelopeTransferResponse{}, pkg.ValidationErrorWithFields(pkg.ErrBudgetValidation, fmt.Sprintf("Envelope of category %d has a balance of %s", transfer.FromCategoryId(), source.Balance()), nil, map[string]string{
+			"amount": fmt.Sprintf("amount must not be greater than the balance of the envelope %s", source.Balance()),
+		})
+	}
+
+	if err = svc.transferDao.SaveTx(ctx, userId, transfer, tx); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return EnvelopeTransferResponse{}, err
+	}
+
+	return makeEnvelopeTransferResponse(transfer), nil
+}
+
+func (svc budgetService) GetEnvelopeTransfers(ctx context.Context, budgetId ledger.BudgetId) (EnvelopeTransfersResponse, error) {
+	var (
+		userId    ledger.UserId
+		tx        *sql.Tx
+		transfers []ledger.EnvelopeTransfer
+		err       error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return EnvelopeTransfersResponse{}, err
+	}
+
+	if tx, err = svc.transferDao.BeginTx(); err != nil {
+		return EnvelopeTransfersResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("GetEnvelopeTransfers: %d", userId))
+
+	if _, err = svc.budgetDao.GetBudgetById(ctx, budgetId, userId, tx); err != nil {
+		return EnvelopeTransfersResponse{}, err
+	}
+
+	if transfers, err = svc.transferDao.GetEnvelopeTransfersForBudget(ctx, userId, budgetId, tx); err != nil {
+		return EnvelopeTransfersResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return EnvelopeTransfersResponse{}, err
+	}
+
+	resp := EnvelopeTransfersResponse{Transfers: make([]EnvelopeTransferResponse, 0, len(transfers))}
+	for _, transfer := range transfers {
+		resp.Transfers = append(resp.Transfers, makeEnvelopeTransferResponse(transfer))
+	}
+	return resp, nil
+}
+
+// envelopeHistoryTx calculates the envelopes of a budget in each period from the period in which it was created until the period that contains the date.
+// The date must have been checked with requireEnvelopePeriod, so that the number of periods is bounded.
+func (svc budgetService) envelopeHistoryTx(ctx context.Context, userId ledger.UserId, budget ledger.Budget, date time.Time, tx *sql.Tx) ([]ledger.PeriodEnvelopes, error) {
+	var (
+		spentPerPeriod []map[ledger.CategoryId]ledger.Money
+		transfers      []ledger.EnvelopeTransfer
+		err            error
+	)
+
+	periods := budget.EnvelopePeriods(date)
+	if spentPerPeriod, err = svc.budgetDao.GetSpentPerCategoryPerPeriodTx(ctx, userId, budget, periods, tx); err != nil {
+		return nil, err
+	}
+
+	if transfers, err = svc.transferDao.GetEnvelopeTransfersForBudget(ctx, userId, budget.Id(), tx); err != nil {
+		return nil, err
+	}
+
+	return ledger.NewEnvelopeHistory(budget, periods, spentPerPeriod, transfers)
+}
+
+// requireEnvelopePeriod rejects dates after the period that follows the current period of the budget, in the timezone of the user.
+// The envelopes of the next period can be requested to see what will be carried over into it.
+func requireEnvelopePeriod(budget ledger.Budget, date time.Time, profile ledger.UserProfile) error {
+	calculator := budget.PeriodCalculator()
+	latest := calculator.PeriodOf(profile.LocalDate(time.Now())).Next()
+	if calculator.PeriodOf(date).FirstDay().After(latest.FirstDay()) {
+		return pkg.ValidationErrorWithFields(pkg.ErrBudgetValidation, fmt.Sprintf("Period %s is too far in the future", date.Format("2006-01-02")), nil, map[string]string{
+			"period": fmt.Sprintf("period must not be after %s", latest.LastDay().Format("2006-01-02")),
+		})
+	}
+	return nil
+}
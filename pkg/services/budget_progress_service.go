@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+// BudgetProgressRequest selects the period of a budget.
+// Period is any date in the period, formatted as yyyy-MM-dd. The current period is used when it is not provided.
+type BudgetProgressRequest struct {
+	Period string
+}
+
+type ProgressResponse struct {
+	MaxAmount   AmountResponse `json:"maxAmount"`
+	Spent       AmountResponse `json:"spent"`
+	Remaining   AmountResponse `json:"remaining"`
+	PercentUsed float64        `json:"percentUsed"`
+}
+
+type CategoryBudgetProgressResponse struct {
+	CategoryId uint64 `json:"categoryId"`
+	ProgressResponse
+}
+
+type BudgetPeriodResponse struct {
+	Type string `json:"type"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type BudgetProgressResponse struct {
+	BudgetId        uint64                           `json:"budgetId"`
+	Period          BudgetPeriodResponse             `json:"period"`
+	CategoryBudgets []CategoryBudgetProgressResponse `json:"categoryBudgets"`
+	Total           ProgressResponse                 `json:"total"`
+}
+
+func makeProgressResponse(progress ledger.Progress) ProgressResponse {
+	makeAmount := func(money ledger.Money) AmountResponse {
+		return AmountResponse{
+			Currency: money.Currency().CurrencyCode(),
+			Value:    money.MustMinorUnits(),
+		}
+	}
+	return ProgressResponse{
+		MaxAmount:   makeAmount(progress.MaxLimit()),
+		Spent:       makeAmount(progress.Spent()),
+		Remaining:   makeAmount(progress.Remaining()),
+		PercentUsed: progress.PercentUsed(),
+	}
+}
+
+func makeBudgetProgressResponse(progress ledger.BudgetProgress) BudgetProgressResponse {
+	categoryBudgets := make([]CategoryBudgetProgressResponse, 0, len(progress.Categories()))
+	for _, category := range progress.Categories() {
+		categoryBudgets = append(categoryBudgets, CategoryBudgetProgressResponse{
+			CategoryId:       uint64(category.CategoryId()),
+			ProgressResponse: makeProgressResponse(category.Progress),
+		})
+	}
+
+	return BudgetProgressResponse{
+		BudgetId:        uint64(progress.Budget().Id()),
+		Period:          makeBudgetPeriodResponse(progress.Period()),
+		CategoryBudgets: categoryBudgets,
+		Total:           makeProgressResponse(progress.Total()),
+	}
+}
+
+func (svc budgetService) GetBudgetProgress(ctx context.Context, budgetId ledger.BudgetId, request BudgetProgressRequest) (BudgetProgressResponse, error) {
+	var (
+		userId   ledger.UserId
+		date     time.Time
+		tx       *sql.Tx
+		profile  ledger.UserProfile
+		budget   ledger.Budget
+		spent    map[ledger.CategoryId]ledger.Money
+		progress ledger.BudgetProgress
+		err      error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return BudgetProgressResponse{}, err
+	}
+
+	if tx, err = svc.budgetDao.BeginTx(); err != nil {
+		return BudgetProgressResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("GetBudgetProgress: %d", userId))
+
+	if profile, err = getUserProfileTx(ctx, svc.userDao, userId, tx); err != nil {
+		return BudgetProgressResponse{}, err
+	}
+
+	if date, err = parsePeriodDate(request.Period, profile); err != nil {
+		return BudgetProgressResponse{}, err
+	}
+
+	if budget, err = svc.budgetDao.GetBudgetById(ctx, budgetId, userId, tx); err != nil {
+		return BudgetProgressResponse{}, err
+	}
+
+	period := budget.PeriodCalculator().PeriodOf(date)
+	if spent, err = svc.budgetDao.GetSpentPerCategoryTx(ctx, userId, budget, period, tx); err != nil {
+		return BudgetProgressResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return BudgetProgressResponse{}, err
+	}
+
+	if progress, err = ledger.NewBudgetProgress(budget, period, spent); err != nil {
+		return BudgetProgressResponse{}, err
+	}
+
+	return makeBudgetProgressResponse(progress), nil
+}
+
+// parsePeriodDate parses a date in a budget period, formatted as yyyy-MM-dd. Today, in the timezone of the user, is returned if the date is empty.
+func parsePeriodDate(period string, profile ledger.UserProfile) (time.Time, error) {
+	if len(period) == 0 {
+		return profile.LocalDate(time.Now()), nil
+	}
+
+	date, err := time.Parse("2006-01-02", period)
+	if err != nil {
+		return time.Time{}, pkg.ValidationErrorWithFields(pkg.ErrBudgetValidation, fmt.Sprintf("Invalid period %q", period), err, map[string]string{
+			"period": "period must be a date formatted as yyyy-MM-dd",
+		})
+	}
+	return date, nil
+}
@@ -0,0 +1,415 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+// CategoryRuleConditions are the conditions of a rule. Conditions that are not provided are met by all records.
+// Amounts are in minor units and are compared with the absolute amount of a record.
+type CategoryRuleConditions struct {
+	NoteContains string `json:"noteContains,omitempty"`
+	Account      *struct {
+		Id uint64 `json:"id"`
+	} `json:"account,omitempty"`
+	MinAmount *int64 `json:"minAmount,omitempty"`
+	MaxAmount *int64 `json:"maxAmount,omitempty"`
+}
+
+// CreateCategoryRuleRequest is a rule that categorises the records that meet its conditions.
+// Rules are tried in ascending order of priority.
+type CreateCategoryRuleRequest struct {
+	Priority   int                    `json:"priority"`
+	Conditions CategoryRuleConditions `json:"conditions"`
+	Category   struct {
+		Id uint64 `json:"id"`
+	} `json:"category"`
+}
+
+type CategoryRuleResponse struct {
+	Id         uint64                 `json:"id"`
+	Priority   int                    `json:"priority"`
+	Conditions CategoryRuleConditions `json:"conditions"`
+	Category   struct {
+		Id uint64 `json:"id"`
+	} `json:"category"`
+	Version uint64 `json:"version"`
+}
+
+type CategoryRulesResponse struct {
+	Rules []CategoryRuleResponse `json:"rules"`
+}
+
+// ApplyCategoryRulesRequest runs the rules over the uncategorised records of the user.
+// When DryRun is set, the changes that would be made are returned but not saved.
+type ApplyCategoryRulesRequest struct {
+	DryRun bool
+}
+
+// CategoryChangeResponse is a record that is moved from the uncategorised category by a rule.
+type CategoryChangeResponse struct {
+	Account struct {
+		Id uint64 `json:"id"`
+	} `json:"account"`
+	Record struct {
+		Id uint64 `json:"id"`
+	} `json:"record"`
+	Note string `json:"note"`
+	Rule struct {
+		Id uint64 `json:"id"`
+	} `json:"rule"`
+	From CategoryResponse `json:"from"`
+	To   CategoryResponse `json:"to"`
+}
+
+type ApplyCategoryRulesResponse struct {
+	DryRun  bool                     `json:"dryRun"`
+	Changes []CategoryChangeResponse `json:"changes"`
+}
+
+func makeCategoryRuleResponse(r ledger.CategoryRule) CategoryRuleResponse {
+	resp := CategoryRuleResponse{
+		Id:       uint64(r.Id()),
+		Priority: r.Priority(),
+		Version:  uint64(r.Version()),
+	}
+	resp.Category.Id = uint64(r.CategoryId())
+
+	conditions := r.Conditions()
+	resp.Conditions.NoteContains = conditions.NoteContains
+	if conditions.AccountId != ledger.AnyAccount {
+		resp.Conditions.Account = &struct {
+			Id uint64 `json:"id"`
+		}{Id: uint64(conditions.AccountId)}
+	}
+	if conditions.MinAmount != ledger.NoAmountLimit {
+		minAmount := conditions.MinAmount
+		resp.Conditions.MinAmount = &minAmount
+	}
+	if conditions.MaxAmount != ledger.NoAmountLimit {
+		maxAmount := conditions.MaxAmount
+		resp.Conditions.MaxAmount = &maxAmount
+	}
+	return resp
+}
+
+type CategoryRuleService interface {
+	CreateCategoryRule(ctx context.Context, request CreateCategoryRuleRequest) (CategoryRuleResponse, error)
+	GetCategoryRules(ctx context.Context) (CategoryRulesResponse, error)
+	DeleteCategoryRule(ctx context.Context, id ledger.CategoryRuleId) error
+
+	// ApplyCategoryRules moves the records in the uncategorised category to the category of the first rule that applies to them.
+	// Split records and transfers are not changed.
+	ApplyCategoryRules(ctx context.Context, request ApplyCategoryRulesRequest) (ApplyCategoryRulesResponse, error)
+}
+
+type categoryRuleService struct {
+	categoryRuleDao dao.CategoryRuleDao
+	recordDao       dao.RecordDao
+	accountDao      dao.AccountDao
+	categoryDao     dao.CategoryDao
+	// uncategorizedCategoryName is the name of the category of the records that the rules are applied to.
+	uncategorizedCategoryName string
+}
+
+func NewCategoryRuleService(
+	categoryRuleDao dao.CategoryRuleDao,
+	recordDao dao.RecordDao,
+	accountDao dao.AccountDao,
+	categoryDao dao.CategoryDao,
+	uncategorizedCategoryName string,
+) (CategoryRuleService, error) {
+	if categoryRuleDao == nil {
+		return nil, fmt.Errorf("can not create category rule service. categoryRuleDao is nil")
+	}
+	if recordDao == nil {
+		return nil, fmt.Errorf("can not create category rule service. recordDao is nil")
+	}
+	if accountDao == nil {
+		return nil, fmt.Errorf("can not create category rule service. accountDao is nil")
+	}
+	if categoryDao == nil {
+		return nil, fmt.Errorf("can not create category rule service. categoryDao is nil")
+	}
+	if len(strings.TrimSpace(uncategorizedCategoryName)) == 0 {
+		return nil, fmt.Errorf("can not create category rule service. uncategorizedCategoryName is blank")
+	}
+
+	return &categoryRuleService{
+		categoryRuleDao:           categoryRuleDao,
+		recordDao:                 recordDao,
+		accountDao:                accountDao,
+		categoryDao:               categoryDao,
+		uncategorizedCategoryName: strings.TrimSpace(uncategorizedCategoryName),
+	}, nil
+}
+
+func (svc categoryRuleService) CreateCategoryRule(ctx context.Context, request CreateCategoryRuleRequest) (CategoryRuleResponse, error) {
+	var (
+		userId ledger.UserId
+		tx     *sql.Tx
+		err    error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return CategoryRuleResponse{}, err
+	}
+
+	if tx, err = svc.categoryRuleDao.BeginTx(); err != nil {
+		return CategoryRuleResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("CreateCategoryRule: %d", userId))
+
+	var (
+		ruleId     ledger.CategoryRuleId
+		category   ledger.Category
+		rule       ledger.CategoryRule
+		conditions = ledger.CategoryRuleConditions{
+			NoteContains: request.Conditions.NoteContains,
+			AccountId:    ledger.AnyAccount,
+			MinAmount:    ledger.NoAmountLimit,
+			MaxAmount:    ledger.NoAmountLimit,
+		}
+	)
+
+	if category, err = svc.categoryDao.GetCategoryById(ctx, ledger.CategoryId(request.Category.Id), userId, tx); err != nil {
+		return CategoryRuleResponse{}, err
+	}
+
+	if request.Conditions.Account != nil {
+		var account ledger.Account
+		if account, err = svc.accountDao.GetAccountById(ctx, ledger.AccountId(request.Conditions.Account.Id), userId, tx); err != nil {
+			return CategoryRuleResponse{}, err
+		}
+		conditions.AccountId = account.Id()
+	}
+	if request.Conditions.MinAmount != nil {
+		conditions.MinAmount = *request.Conditions.MinAmount
+	}
+	if request.Conditions.MaxAmount != nil {
+		conditions.MaxAmount = *request.Conditions.MaxAmount
+	}
+
+	if ruleId, err = svc.categoryRuleDao.NewCategoryRuleId(tx); err != nil {
+		return CategoryRuleResponse{}, err
+	}
+
+	if rule, err = ledger.NewCategoryRule(
+		ruleId,
+		request.Priority,
+		conditions,
+		category.Id(),
+		ledger.MustMakeUpdatedByUserId(userId),
+	); err != nil {
+		return CategoryRuleResponse{}, err
+	}
+
+	if err = svc.categoryRuleDao.SaveTx(ctx, userId, rule, tx); err != nil {
+		return CategoryRuleResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return CategoryRuleResponse{}, err
+	}
+
+	return makeCategoryRuleResponse(rule), nil
+}
+
+func (svc categoryRuleService) GetCategoryRules(ctx context.Context) (CategoryRulesResponse, error) {
+	var (
+		userId ledger.UserId
+		tx     *sql.Tx
+		rules  ledger.CategoryRules
+		err    error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return CategoryRulesResponse{}, err
+	}
+
+	if tx, err = svc.categoryRuleDao.BeginTx(); err != nil {
+		return CategoryRulesResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("GetCategoryRules: %d", userId))
+
+	if rules, err = svc.categoryRuleDao.GetCategoryRulesForUser(ctx, userId, tx); err != nil {
+		return CategoryRulesResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return CategoryRulesResponse{}, err
+	}
+
+	resp := CategoryRulesResponse{Rules: make([]CategoryRuleResponse, 0, len(rules))}
+	for _, rule := range rules {
+		resp.Rules = append(resp.Rules, makeCategoryRuleResponse(rule))
+	}
+	return resp, nil
+}
+
+func (svc categoryRuleService) DeleteCategoryRule(ctx context.Context, id ledger.CategoryRuleId) error {
+	var (
+		userId ledger.UserId
+		tx     *sql.Tx
+		err    error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return err
+	}
+
+	if tx, err = svc.categoryRuleDao.BeginTx(); err != nil {
+		return err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("DeleteCategoryRule: %d", id))
+
+	if err = svc.categoryRuleDao.DeleteTx(ctx, id, userId, tx); err != nil {
+		return err
+	}
+
+	return dao.Commit(tx)
+}
+
+func (svc categoryRuleService) ApplyCategoryRules(ctx context.Context, request ApplyCategoryRulesRequest) (ApplyCategoryRulesResponse, error) {
+	var (
+		userId ledger.UserId
+		tx     *sql.Tx
+		err    error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return ApplyCategoryRulesResponse{}, err
+	}
+
+	if tx, err = svc.categoryRuleDao.BeginTx(); err != nil {
+		return ApplyCategoryRulesResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("ApplyCategoryRules: %d", userId))
+
+	var (
+		rules         ledger.CategoryRules
+		categories    ledger.Categories
+		accounts      ledger.Accounts
+		uncategorized ledger.Category
+		found         bool
+		resp          = ApplyCategoryRulesResponse{DryRun: request.DryRun, Changes: []CategoryChangeResponse{}}
+	)
+
+	if categories, err = svc.categoryDao.GetCategoriesForUser(ctx, userId, tx); err != nil {
+		return ApplyCategoryRulesResponse{}, err
+	}
+
+	for _, category := range categories {
+		if strings.EqualFold(category.Name(), svc.uncategorizedCategoryName) {
+			uncategorized, found = category, true
+			break
+		}
+	}
+	if !found {
+		return resp, nil
+	}
+
+	if rules, err = svc.categoryRuleDao.GetCategoryRulesForUser(ctx, userId, tx); err != nil {
+		return ApplyCategoryRulesResponse{}, err
+	}
+	if len(rules) == 0 {
+		return resp, nil
+	}
+
+	if accounts, err = svc.accountDao.GetAccountsByUserId(ctx, userId, tx); err != nil {
+		return ApplyCategoryRulesResponse{}, err
+	}
+
+	categorizer := newRuleCategorizer(rules, categories)
+	updatedBy := ledger.MustMakeUpdatedByUserId(userId)
+	for _, account := range accounts {
+		var records ledger.Records
+		if records, err = svc.recordDao.GetRecordsInCategoryTx(ctx, account.Id(), uncategorized.Id(), tx); err != nil {
+			return ApplyCategoryRulesResponse{}, err
+		}
+
+		for _, record := range records {
+			if record.IsSplit() || record.Type() == ledger.Transfer {
+				continue
+			}
+
+			rule, category, ok := categorizer.match(account.Id(), record.Note(), record.Amount())
+			if !ok || category.Id() == uncategorized.Id() {
+				continue
+			}
+
+			var change CategoryChangeResponse
+			change.Account.Id = uint64(account.Id())
+			change.Record.Id = uint64(record.Id())
+			change.Note = record.Note()
+			change.Rule.Id = uint64(rule.Id())
+			change.From = CategoryResponse{Id: uint64(uncategorized.Id()), Name: uncategorized.Name()}
+			change.To = CategoryResponse{Id: uint64(category.Id()), Name: category.Name()}
+			resp.Changes = append(resp.Changes, change)
+
+			if request.DryRun {
+				continue
+			}
+
+			if record, err = record.Edit(
+				record.Note(),
+				category,
+				record.Amount(),
+				record.DateUTC(),
+				record.Type(),
+				record.Splits(),
+				updatedBy,
+			); err != nil {
+				return ApplyCategoryRulesResponse{}, err
+			}
+
+			if err = svc.recordDao.UpdateTx(ctx, account.Id(), record, tx); err != nil {
+				return ApplyCategoryRulesResponse{}, err
+			}
+
+			if err = svc.categoryDao.UpdateCategoryLastUsed(ctx, category.Id(), record.DateUTC(), tx); err != nil {
+				return ApplyCategoryRulesResponse{}, err
+			}
+		}
+	}
+
+	if request.DryRun {
+		return resp, nil
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return ApplyCategoryRulesResponse{}, err
+	}
+
+	return resp, nil
+}
+
+// ruleCategorizer finds the category of a record using the rules of a user.
+type ruleCategorizer struct {
+	rules          ledger.CategoryRules
+	categoriesById map[ledger.CategoryId]ledger.Category
+}
+
+func newRuleCategorizer(rules ledger.CategoryRules, categories ledger.Categories) ruleCategorizer {
+	return ruleCategorizer{rules: rules, categoriesById: categories.MapById()}
+}
+
+// match returns the first rule that applies to a record of the account with the given note and amount, along with its category.
+// false is returned if no rule applies.
+func (c ruleCategorizer) match(accountId ledger.AccountId, note string, amount ledger.Money) (ledger.CategoryRule, ledger.Category, bool) {
+	rule, ok := c.rules.Match(accountId, note, amount)
+	if !ok {
+		return ledger.CategoryRule{}, ledger.Category{}, false
+	}
+	category, ok := c.categoriesById[rule.CategoryId()]
+	return rule, category, ok
+}
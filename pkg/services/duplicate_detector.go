@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+// duplicateDetector finds the records of an account that are likely to be the same transaction as a new record,
+// e.g. an expense recorded twice by a double-tap or imported from overlapping statements.
+type duplicateDetector struct {
+	recordDao dao.RecordDao
+	// window is how far apart the dates of two records can be for one to be considered a duplicate of the other
+	window time.Duration
+}
+
+// findLikelyDuplicatesTx returns the ids of the records of the account with the same amount as the record and a similar note,
+// dated within the window of the record.
+func (d duplicateDetector) findLikelyDuplicatesTx(ctx context.Context, accountId ledger.AccountId, record ledger.Record, tx *sql.Tx) ([]ledger.RecordId, error) {
+	candidates, err := d.recordDao.GetDuplicateCandidatesTx(ctx, accountId, record.Amount(), record.DateUTC().Add(-d.window), record.DateUTC().Add(d.window), tx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []ledger.RecordId{}
+	for _, candidate := range candidates {
+		if record.IsLikelyDuplicateOf(candidate, d.window) {
+			ids = append(ids, candidate.Id())
+		}
+	}
+	return ids, nil
+}
+
+// findExactDuplicatesTx returns the ids of the records of the account with the same amount, date and note as the record,
+// that were not created by the given actor e.g. by the same import.
+func (d duplicateDetector) findExactDuplicatesTx(ctx context.Context, accountId ledger.AccountId, record ledger.Record, ignoredCreator ledger.UpdatedBy, tx *sql.Tx) ([]ledger.RecordId, error) {
+	candidates, err := d.recordDao.GetDuplicateCandidatesTx(ctx, accountId, record.Amount(), record.DateUTC(), record.DateUTC(), tx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []ledger.RecordId{}
+	for _, candidate := range candidates {
+		if candidate.CreatedBy() != ignoredCreator && record.IsExactDuplicateOf(candidate) {
+			ids = append(ids, candidate.Id())
+		}
+	}
+	return ids, nil
+}
+
+// duplicateRecordError reports the records that a record duplicates
+func duplicateRecordError(ids []ledger.RecordId) error {
+	formatted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		formatted = append(formatted, fmt.Sprint(id))
+	}
+	return pkg.ValidationErrorWithFields(
+		pkg.ErrRecordDuplicated,
+		fmt.Sprintf("Record is likely a duplicate of %d existing record(s)", len(ids)),
+		nil,
+		map[string]string{"duplicateRecordIds": strings.Join(formatted, ",")},
+	)
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/export"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+// ExportRecordsRequest holds the raw export parameters, as provided by the client.
+// Dates can be provided either as yyyy-MM-dd or in RFC3339 format. All records are exported when the dates are not provided.
+type ExportRecordsRequest struct {
+	Format string
+	From   string
+	To     string
+}
+
+// ExportedFile is a file of exported records or data.
+// The records are only loaded when the file is streamed, so that the export can be validated before the response is started.
+type ExportedFile struct {
+	FileName    string
+	ContentType string
+	stream      func(w io.Writer) error
+}
+
+// Stream writes the file, loading the records a page at a time.
+func (e ExportedFile) Stream(w io.Writer) error {
+	return e.stream(w)
+}
+
+type ExportService interface {
+	// ExportRecords exports the records of the account in the context.
+	ExportRecords(ctx context.Context, request ExportRecordsRequest) (ExportedFile, error)
+	// ExportAllRecords exports the records of all the accounts of the user.
+	// When the user has several accounts, the file of each account is zipped.
+	ExportAllRecords(ctx context.Context, request ExportRecordsRequest) (ExportedFile, error)
+	// ExportUserData exports all the data of the user as a versioned JSON archive that can be restored.
+	ExportUserData(ctx context.Context) (ExportedFile, error)
+}
+
+type exportService struct {
+	recordDao   dao.RecordDao
+	accountDao  dao.AccountDao
+	categoryDao dao.CategoryDao
+	budgetDao   dao.BudgetDao
+	userDao     dao.UserDao
+}
+
+func NewExportService(
+	recordDao dao.RecordDao,
+	accountDao dao.AccountDao,
+	categoryDao dao.CategoryDao,
+	budgetDao dao.BudgetDao,
+	userDao dao.UserDao,
+) (ExportService, error) {
+	if recordDao == nil {
+		return nil, fmt.Errorf("can not create export service. recordDao is nil")
+	}
+	if accountDao == nil {
+		return nil, fmt.Errorf("can not create export service. accountDao is nil")
+	}
+	if categoryDao == nil {
+		return nil, fmt.Errorf("can not create export service. categoryDao is nil")
+	}
+	if budgetDao == nil {
+		return nil, fmt.Errorf("can not create export service. budgetDao is nil")
+	}
+	if userDao == nil {
+		return nil, fmt.Errorf("can not create export service. userDao is nil")
+	}
+
+	return &exportService{
+		recordDao:   recordDao,
+		accountDao:  accountDao,
+		categoryDao: categoryDao,
+		budgetDao:   budgetDao,
+		userDao:     userDao,
+	}, nil
+}
+
+func (svc exportService) ExportRecords(ctx context.Context, request ExportRecordsRequest) (ExportedFile, error) {
+	var (
+		userId    ledger.UserId
+		accountId ledger.AccountId
+		search    dao.RecordSearch
+		format    export.Format
+		accounts  ledger.Accounts
+		err       error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return ExportedFile{}, err
+	}
+
+	if accountId, err = RequireAccountId(ctx); err != nil {
+		return ExportedFile{}, err
+	}
+
+	if format, search, err = makeExportSearch(request); err != nil {
+		return ExportedFile{}, err
+	}
+
+	if accounts, err = svc.getAccounts(ctx, userId); err != nil {
+		return ExportedFile{}, err
+	}
+
+	var account ledger.Account
+	for _, a := range accounts {
+		if a.Id() == accountId {
+			account = a
+		}
+	}
+	if account == (ledger.Account{}) {
+		return ExportedFile{}, pkg.ValidationErrorWithError(pkg.ErrAccountNotFound, fmt.Sprintf("Account #%d not found", accountId), nil)
+	}
+
+	accountNames := accountNamesById(accounts)
+	return ExportedFile{
+		FileName:    export.FileName(account, format),
+		ContentType: format.ContentType(),
+		stream: func(w io.Writer) error {
+			return svc.exportAccount(ctx, w, format, account, accountNames, search)
+		},
+	}, nil
+}
+
+func (svc exportService) ExportAllRecords(ctx context.Context, request ExportRecordsRequest) (ExportedFile, error) {
+	var (
+		userId   ledger.UserId
+		search   dao.RecordSearch
+		format   export.Format
+		accounts ledger.Accounts
+		err      error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return ExportedFile{}, err
+	}
+
+	if format, search, err = makeExportSearch(request); err != nil {
+		return ExportedFile{}, err
+	}
+
+	if accounts, err = svc.getAccounts(ctx, userId); err != nil {
+		return ExportedFile{}, err
+	}
+
+	accountNames := accountNamesById(accounts)
+	if len(accounts) == 1 {
+		return ExportedFile{
+			FileName:    export.FileName(accounts[0], format),
+			ContentType: format.ContentType(),
+			stream: func(w io.Writer) error {
+				return svc.exportAccount(ctx, w, format, accounts[0], accountNames, search)
+			},
+		}, nil
+	}
+
+	return ExportedFile{
+		FileName:    fmt.Sprintf("records-%s.zip", format.Extension()),
+		ContentType: "application/zip",
+		stream: func(w io.Writer) error {
+			archive := zip.NewWriter(w)
+			for _, account := range accounts {
+				file, err := archive.Create(export.FileName(account, format))
+				if err != nil {
+					return err
+				}
+				if err = svc.exportAccount(ctx, file, format, account, accountNames, search); err != nil {
+					return err
+				}
+			}
+			return archive.Close()
+		},
+	}, nil
+}
+
+func (svc exportService) ExportUserData(ctx context.Context) (ExportedFile, error) {
+	var (
+		userId     ledger.UserId
+		user       ledger.User
+		accounts   ledger.Accounts
+		categories ledger.Categories
+		budgets    []ledger.Budget
+		tx         *sql.Tx
+		err        error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return ExportedFile{}, err
+	}
+
+	if user, err = svc.userDao.GetUserById(userId); err != nil {
+		return ExportedFile{}, err
+	}
+
+	if tx, err = svc.accountDao.BeginTx(); err != nil {
+		return ExportedFile{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("ExportUserData: %d", userId))
+
+	if accounts, err = svc.accountDao.GetAccountsByUserId(ctx, userId, tx); err != nil {
+		return ExportedFile{}, err
+	}
+
+	if categories, err = svc.categoryDao.GetCategoriesForUser(ctx, userId, tx); err != nil {
+		return ExportedFile{}, err
+	}
+
+	if budgets, err = svc.budgetDao.GetBudgetsForUser(ctx, userId, tx); err != nil {
+		return ExportedFile{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return ExportedFile{}, err
+	}
+
+	exportedAt := time.Now().UTC()
+	search := allRecordsSearch()
+	return ExportedFile{
+		FileName:    fmt.Sprintf("user-%d-%s.json", userId, exportedAt.Format("20060102")),
+		ContentType: export.FormatJson.ContentType(),
+		stream: func(w io.Writer) error {
+			return export.WriteArchive(w, user, categories, budgets, accounts, func(account ledger.Account, fn func(ledger.Record) error) error {
+				return svc.recordDao.ForEachRecord(ctx, account.Id(), search, fn)
+			}, exportedAt)
+		},
+	}, nil
+}
+
+func (svc exportService) getAccounts(ctx context.Context, userId ledger.UserId) (ledger.Accounts, error) {
+	var (
+		tx       *sql.Tx
+		accounts ledger.Accounts
+		err      error
+	)
+
+	if tx, err = svc.accountDao.BeginTx(); err != nil {
+		return ledger.Accounts{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("ExportRecords: %d", userId))
+
+	if accounts, err = svc.accountDao.GetAccountsByUserId(ctx, userId, tx); err != nil {
+		return ledger.Accounts{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return ledger.Accounts{}, err
+	}
+	return accounts, nil
+}
+
+// exportAccount writes the records of an account that match the search to w, oldest first.
+func (svc exportService) exportAccount(
+	ctx context.Context,
+	w io.Writer,
+	format export.Format,
+	account ledger.Account,
+	accountNames map[ledger.AccountId]string,
+	search dao.RecordSearch,
+) error {
+	writer := export.NewWriter(format, w, account, accountNames)
+	if err := svc.recordDao.ForEachRecord(ctx, account.Id(), search, writer.Write); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func accountNamesById(accounts ledger.Accounts) map[ledger.AccountId]string {
+	names := make(map[ledger.AccountId]string, len(accounts))
+	for _, account := range accounts {
+		names[account.Id()] = account.Name()
+	}
+	return names
+}
+
+func makeExportSearch(request ExportRecordsRequest) (export.Format, dao.RecordSearch, error) {
+	var (
+		format export.Format
+		err    error
+	)
+
+	if format, err = export.ParseFormat(request.Format); err != nil {
+		return "", dao.RecordSearch{}, err
+	}
+
+	invalidFields := map[string]string{}
+	parseDate := func(field string, value string, defaultDate time.Time) *time.Time {
+		if len(value) == 0 {
+			return &defaultDate
+		}
+		if date, err := time.Parse("2006-01-02", value); err == nil {
+			return &date
+		}
+		if date, err := time.Parse(time.RFC3339, value); err == nil {
+			utcDate := date.In(time.UTC)
+			return &utcDate
+		}
+		invalidFields[field] = fmt.Sprintf("%s '%s' must be formatted as yyyy-MM-dd or %s", field, value, time.RFC3339)
+		return nil
+	}
+
+	all := allRecordsSearch()
+	search := dao.RecordSearch{
+		FromDate: parseDate("from", request.From, *all.FromDate),
+		ToDate:   parseDate("to", request.To, *all.ToDate),
+	}
+
+	if search.FromDate != nil && search.ToDate != nil && search.ToDate.Before(*search.FromDate) {
+		invalidFields["to"] = "to must not be before from"
+	}
+
+	if len(invalidFields) != 0 {
+		return "", dao.RecordSearch{}, pkg.ValidationErrorWithFields(pkg.ErrExportValidation, "Invalid export parameters", nil, invalidFields)
+	}
+	return format, search, nil
+}
+
+// allRecordsSearch matches all the records of an account
+func allRecordsSearch() dao.RecordSearch {
+	from := time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+	return dao.RecordSearch{FromDate: &from, ToDate: &to}
+}
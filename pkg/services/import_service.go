@@ -0,0 +1,890 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+	"github.com/w-k-s/simple-budget-tracker/pkg/statement"
+	"github.com/w-k-s/simple-budget-tracker/pkg/statement/ofx"
+	"github.com/w-k-s/simple-budget-tracker/pkg/statement/qif"
+)
+
+// MaxImportRows is the maximum number of rows that can be imported from a single file.
+const MaxImportRows = 10000
+
+// CreateImportProfileRequest describes how the columns of a CSV are mapped to records.
+// Columns are zero-based; the debit, note and category columns are optional.
+type CreateImportProfileRequest struct {
+	Name    string `json:"name"`
+	Columns struct {
+		Date     int  `json:"date"`
+		Amount   int  `json:"amount"`
+		Debit    *int `json:"debit,omitempty"`
+		Note     *int `json:"note,omitempty"`
+		Category *int `json:"category,omitempty"`
+	} `json:"columns"`
+	DateFormat       string `json:"dateFormat"`
+	DecimalSeparator string `json:"decimalSeparator"`
+	DebitFormat      string `json:"debitFormat"`
+	HasHeader        bool   `json:"hasHeader"`
+	DefaultCategory  *struct {
+		Id uint64 `json:"id"`
+	} `json:"defaultCategory,omitempty"`
+}
+
+type ImportProfileResponse struct {
+	Id      uint64 `json:"id"`
+	Name    string `json:"name"`
+	Columns struct {
+		Date     int  `json:"date"`
+		Amount   int  `json:"amount"`
+		Debit    *int `json:"debit,omitempty"`
+		Note     *int `json:"note,omitempty"`
+		Category *int `json:"category,omitempty"`
+	} `json:"columns"`
+	DateFormat       string `json:"dateFormat"`
+	DecimalSeparator string `json:"decimalSeparator"`
+	DebitFormat      string `json:"debitFormat"`
+	HasHeader        bool   `json:"hasHeader"`
+	DefaultCategory  *struct {
+		Id uint64 `json:"id"`
+	} `json:"defaultCategory,omitempty"`
+	Version uint64 `json:"version"`
+}
+
+type ImportProfilesResponse struct {
+	ImportProfiles []ImportProfileResponse `json:"importProfiles"`
+}
+
+func makeImportProfileResponse(p ledger.ImportProfile) ImportProfileResponse {
+	column := func(c int) *int {
+		if c == ledger.NoColumn {
+			return nil
+		}
+		return &c
+	}
+
+	resp := ImportProfileResponse{}
+	resp.Id = uint64(p.Id())
+	resp.Name = p.Name()
+	resp.Columns.Date = p.Columns().Date
+	resp.Columns.Amount = p.Columns().Amount
+	resp.Columns.Debit = column(p.Columns().Debit)
+	resp.Columns.Note = column(p.Columns().Note)
+	resp.Columns.Category = column(p.Columns().Category)
+	resp.DateFormat = p.DateFormat()
+	resp.DecimalSeparator = string(p.DecimalSeparator())
+	resp.DebitFormat = string(p.DebitFormat())
+	resp.HasHeader = p.HasHeader()
+	resp.Version = uint64(p.Version())
+
+	if p.DefaultCategoryId() != ledger.NoDefaultCategory {
+		resp.DefaultCategory = &struct {
+			Id uint64 `json:"id"`
+		}{Id: uint64(p.DefaultCategoryId())}
+	}
+	return resp
+}
+
+// ImportFormat is the format of an imported file
+type ImportFormat string
+
+const (
+	ImportFormatCsv ImportFormat = "CSV"
+	// ImportFormatOfx is used for both OFX and QFX files
+	ImportFormatOfx ImportFormat = "OFX"
+	ImportFormatQif ImportFormat = "QIF"
+)
+
+// ImportRecordsRequest is a file that is imported into an account.
+// A CSV is imported using an import profile. OFX and QIF statements may be imported with an import profile,
+// in which case transactions whose category is not found are recorded in its default category.
+// When Format is empty, it is determined from the extension of the file name.
+// When DryRun is set, the records that would be created are returned but not saved.
+type ImportRecordsRequest struct {
+	ProfileId ledger.ImportProfileId
+	Format    ImportFormat
+	// QifDateOrder is the order of the day and the month in the dates of a QIF file
+	QifDateOrder qif.DateOrder
+	FileName     string
+	File         io.Reader
+	DryRun       bool
+}
+
+// ImportedRecordResponse is a record created from a row of an imported file.
+// The records of a dry run do not have an id.
+type ImportedRecordResponse struct {
+	Row    int            `json:"row"`
+	Record RecordResponse `json:"record"`
+}
+
+// ImportRowError is the problem with a row of an imported file that could not be imported.
+type ImportRowError struct {
+	Row    int               `json:"row"`
+	Code   uint64            `json:"code"`
+	Title  string            `json:"title"`
+	Detail string            `json:"detail,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// ImportRecordsResponse lists the records created from the valid rows of the file and the problems with the rest.
+// Rows of a CSV are numbered from 1, including the header. Transactions of a statement are numbered from 1 in the order they appear.
+// Transactions of a statement that were imported before are skipped.
+type ImportRecordsResponse struct {
+	BatchId  string                   `json:"batchId"`
+	DryRun   bool                     `json:"dryRun"`
+	Imported int                      `json:"imported"`
+	Skipped  int                      `json:"skipped"`
+	Records  []ImportedRecordResponse `json:"records"`
+	Errors   []ImportRowError         `json:"errors"`
+}
+
+type ImportService interface {
+	CreateImportProfile(ctx context.Context, request CreateImportProfileRequest) (ImportProfileResponse, error)
+	GetImportProfiles(ctx context.Context) (ImportProfilesResponse, error)
+
+	// ImportRecords creates a record for each valid row of a CSV or transaction of a statement in the account in the context.
+	// Records are created with the same validation as records created by the user, and are created by the import.
+	ImportRecords(ctx context.Context, request ImportRecordsRequest) (ImportRecordsResponse, error)
+}
+
+type importService struct {
+	importProfileDao dao.ImportProfileDao
+	recordDao        dao.RecordDao
+	accountDao       dao.AccountDao
+	categoryDao      dao.CategoryDao
+	categoryRuleDao  dao.CategoryRuleDao
+	userDao          dao.UserDao
+	records          recordService
+	// uncategorizedCategoryName is the name of the category of statement transactions whose category is not found.
+	// It is created when it does not exist.
+	uncategorizedCategoryName string
+}
+
+func NewImportService(
+	importProfileDao dao.ImportProfileDao,
+	recordDao dao.RecordDao,
+	accountDao dao.AccountDao,
+	categoryDao dao.CategoryDao,
+	categoryRuleDao dao.CategoryRuleDao,
+	userDao dao.UserDao,
+	uncategorizedCategoryName string,
+	duplicateWindow time.Duration,
+) (ImportService, error) {
+	if importProfileDao == nil {
+		return nil, fmt.Errorf("can not create import service. importProfileDao is nil")
+	}
+	if recordDao == nil {
+		return nil, fmt.Errorf("can not create import service. recordDao is nil")
+	}
+	if accountDao == nil {
+		return nil, fmt.Errorf("can not create import service. accountDao is nil")
+	}
+	if categoryDao == nil {
+		return nil, fmt.Errorf("can not create import service. categoryDao is nil")
+	}
+	if categoryRuleDao == nil {
+		return nil, fmt.Errorf("can not create import service. categoryRuleDao is nil")
+	}
+	if userDao == nil {
+		return nil, fmt.Errorf("can not create import service. userDao is nil")
+	}
+	if len(strings.TrimSpace(uncategorizedCategoryName)) == 0 {
+		return nil, fmt.Errorf("can not create import service. uncategorizedCategoryName is blank")
+	}
+
+	return &importService{
+		importProfileDao: importProfileDao,
+		recordDao:        recordDao,
+		accountDao:       accountDao,
+		categoryDao:      categoryDao,
+		categoryRuleDao:  categoryRuleDao,
+		userDao:          userDao,
+		records: recordService{
+			recordDao:       recordDao,
+			accountDao:      accountDao,
+			categoryDao:     categoryDao,
+			categoryRuleDao: categoryRuleDao,
+			userDao:         userDao,
+			duplicates:      duplicateDetector{recordDao: recordDao, window: duplicateWindow},
+		},
+		uncategorizedCategoryName: strings.TrimSpace(uncategorizedCategoryName),
+	}, nil
+}
+
+func (svc importService) CreateImportProfile(ctx context.Context, request CreateImportProfileRequest) (ImportProfileResponse, error) {
+	var (
+		userId ledger.UserId
+		tx     *sql.Tx
+		err    error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return ImportProfileResponse{}, err
+	}
+
+	if tx, err = svc.importProfileDao.BeginTx(); err != nil {
+		return ImportProfileResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("CreateImportProfile: %d", userId))
+
+	var (
+		id                ledger.ImportProfileId
+		defaultCategoryId = ledger.NoDefaultCategory
+		profile           ledger.ImportProfile
+	)
+
+	if request.DefaultCategory != nil {
+		var category ledger.Category
+		if category, err = svc.categoryDao.GetCategoryById(ctx, ledger.CategoryId(request.DefaultCategory.Id), userId, tx); err != nil {
+			return ImportProfileResponse{}, err
+		}
+		defaultCategoryId = category.Id()
+	}
+
+	if id, err = svc.importProfileDao.NewImportProfileId(tx); err != nil {
+		return ImportProfileResponse{}, err
+	}
+
+	column := func(c *int) int {
+		if c == nil {
+			return ledger.NoColumn
+		}
+		return *c
+	}
+
+	if profile, err = ledger.NewImportProfile(
+		id,
+		request.Name,
+		ledger.ImportColumns{
+			Date:     request.Columns.Date,
+			Amount:   request.Columns.Amount,
+			Debit:    column(request.Columns.Debit),
+			Note:     column(request.Columns.Note),
+			Category: column(request.Columns.Category),
+		},
+		request.DateFormat,
+		ledger.DecimalSeparator(request.DecimalSeparator),
+		ledger.DebitFormat(request.DebitFormat),
+		request.HasHeader,
+		defaultCategoryId,
+		ledger.MustMakeUpdatedByUserId(userId),
+	); err != nil {
+		return ImportProfileResponse{}, err
+	}
+
+	if err = svc.importProfileDao.SaveTx(ctx, userId, profile, tx); err != nil {
+		return ImportProfileResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return ImportProfileResponse{}, err
+	}
+
+	return makeImportProfileResponse(profile), nil
+}
+
+func (svc importService) GetImportProfiles(ctx context.Context) (ImportProfilesResponse, error) {
+	var (
+		userId   ledger.UserId
+		tx       *sql.Tx
+		profiles ledger.ImportProfiles
+		err      error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return ImportProfilesResponse{}, err
+	}
+
+	if tx, err = svc.importProfileDao.BeginTx(); err != nil {
+		return ImportProfilesResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("GetImportProfiles: %d", userId))
+
+	if profiles, err = svc.importProfileDao.GetImportProfilesForUser(ctx, userId, tx); err != nil {
+		return ImportProfilesResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return ImportProfilesResponse{}, err
+	}
+
+	resp := ImportProfilesResponse{
+		ImportProfiles: make([]ImportProfileResponse, 0, len(profiles)),
+	}
+	for _, profile := range profiles {
+		resp.ImportProfiles = append(resp.ImportProfiles, makeImportProfileResponse(profile))
+	}
+	return resp, nil
+}
+
+func (svc importService) ImportRecords(ctx context.Context, request ImportRecordsRequest) (ImportRecordsResponse, error) {
+	var (
+		userId    ledger.UserId
+		accountId ledger.AccountId
+		format    ImportFormat
+		tx        *sql.Tx
+		err       error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return ImportRecordsResponse{}, err
+	}
+
+	if accountId, err = RequireAccountId(ctx); err != nil {
+		return ImportRecordsResponse{}, err
+	}
+
+	if format, err = importFormat(request); err != nil {
+		return ImportRecordsResponse{}, err
+	}
+
+	if format == ImportFormatCsv && request.ProfileId == 0 {
+		return ImportRecordsResponse{}, pkg.ValidationErrorWithFields(
+			pkg.ErrImportValidation,
+			"An import profile is required to import a CSV",
+			nil,
+			map[string]string{"profileId": "profileId is required"},
+		)
+	}
+
+	if tx, err = svc.importProfileDao.BeginTx(); err != nil {
+		return ImportRecordsResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("ImportRecords: %d", userId))
+
+	var (
+		account     ledger.Account
+		userProfile ledger.UserProfile
+		profile     ledger.ImportProfile
+		categories  ledger.Categories
+		rules       ledger.CategoryRules
+		batchId     = uuid.NewString()
+		updatedBy   ledger.UpdatedBy
+	)
+
+	if account, err = svc.accountDao.GetAccountById(ctx, accountId, userId, tx); err != nil {
+		return ImportRecordsResponse{}, err
+	}
+
+	if userProfile, err = getUserProfileTx(ctx, svc.userDao, userId, tx); err != nil {
+		return ImportRecordsResponse{}, err
+	}
+
+	if request.ProfileId != 0 {
+		if profile, err = svc.importProfileDao.GetImportProfileByIdTx(ctx, request.ProfileId, userId, tx); err != nil {
+			return ImportRecordsResponse{}, err
+		}
+	}
+
+	if categories, err = svc.categoryDao.GetCategoriesForUser(ctx, userId, tx); err != nil {
+		return ImportRecordsResponse{}, err
+	}
+
+	if rules, err = svc.categoryRuleDao.GetCategoryRulesForUser(ctx, userId, tx); err != nil {
+		return ImportRecordsResponse{}, err
+	}
+
+	if updatedBy, err = ledger.MakeUpdatedByImport(importFileName(request.FileName), batchId); err != nil {
+		return ImportRecordsResponse{}, err
+	}
+
+	resp := ImportRecordsResponse{
+		BatchId: batchId,
+		DryRun:  request.DryRun,
+		Records: []ImportedRecordResponse{},
+		Errors:  []ImportRowError{},
+	}
+
+	switch format {
+	case ImportFormatCsv:
+		err = svc.importCsvTx(ctx, account, userProfile, profile, categories, rules, request, updatedBy, &resp, tx)
+	default:
+		err = svc.importStatementTx(ctx, userId, account, userProfile, profile, categories, rules, format, request, updatedBy, &resp, tx)
+	}
+	if err != nil {
+		return ImportRecordsResponse{}, err
+	}
+	resp.Imported = len(resp.Records)
+
+	if request.DryRun {
+		return resp, nil
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return ImportRecordsResponse{}, err
+	}
+
+	return resp, nil
+}
+
+// importCsvTx creates the records of the rows of a CSV using an import profile.
+// Rows without a category are categorised by the rules of the user, or else recorded in the default category of the profile.
+func (svc importService) importCsvTx(
+	ctx context.Context,
+	account ledger.Account,
+	userProfile ledger.UserProfile,
+	profile ledger.ImportProfile,
+	categories ledger.Categories,
+	rules ledger.CategoryRules,
+	request ImportRecordsRequest,
+	updatedBy ledger.UpdatedBy,
+	resp *ImportRecordsResponse,
+	tx *sql.Tx,
+) error {
+	var (
+		rows [][]string
+		err  error
+	)
+
+	if rows, err = readImportFile(request.File); err != nil {
+		return err
+	}
+
+	categoriesByName := map[string]ledger.Category{}
+	for _, category := range categories {
+		categoriesByName[strings.ToLower(category.Name())] = category
+	}
+	defaultCategory := categories.MapById()[profile.DefaultCategoryId()]
+	categorizer := newRuleCategorizer(rules, categories)
+
+	for i, row := range rows {
+		rowNumber := i + 1
+		if i == 0 && profile.HasHeader() {
+			continue
+		}
+
+		var (
+			record     ledger.Record
+			recordResp RecordResponse
+		)
+		if record, err = svc.importRowTx(ctx, account.Id(), account.Currency(), profile, categoriesByName, categorizer, defaultCategory, row, rowNumber, request.DryRun, updatedBy, tx); err != nil {
+			if rowError, ok := makeImportRowError(rowNumber, err); ok {
+				resp.Errors = append(resp.Errors, rowError)
+				continue
+			}
+			return err
+		}
+
+		if recordResp, err = makeImportedRecordResponse(record, userProfile, request.DryRun); err != nil {
+			return err
+		}
+		resp.Records = append(resp.Records, ImportedRecordResponse{Row: rowNumber, Record: recordResp})
+	}
+	return nil
+}
+
+// importStatementTx creates the records of the transactions of an OFX or QIF statement.
+// Transactions are matched to categories by name. Transactions whose category is not found are categorised by the rules
+// of the user, or else recorded in the default category of the import profile if one is given, or in the uncategorized category.
+// A transaction is only imported once into an account.
+func (svc importService) importStatementTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	account ledger.Account,
+	userProfile ledger.UserProfile,
+	profile ledger.ImportProfile,
+	categories ledger.Categories,
+	rules ledger.CategoryRules,
+	format ImportFormat,
+	request ImportRecordsRequest,
+	updatedBy ledger.UpdatedBy,
+	resp *ImportRecordsResponse,
+	tx *sql.Tx,
+) error {
+	var (
+		stmt     statement.Statement
+		fallback ledger.Category
+		err      error
+	)
+
+	if request.File == nil {
+		return pkg.ValidationErrorWithFields(pkg.ErrImportValidation, "No file provided", nil, map[string]string{"file": "file is required"})
+	}
+
+	switch format {
+	case ImportFormatOfx:
+		stmt, err = ofx.Parse(request.File)
+	default:
+		stmt, err = qif.Parse(request.File, request.QifDateOrder)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(stmt.Transactions) > MaxImportRows {
+		return pkg.ValidationErrorWithFields(pkg.ErrImportValidation, fmt.Sprintf("A file can not have more than %d transactions", MaxImportRows), nil, map[string]string{"file": "file has too many transactions"})
+	}
+
+	if len(stmt.Currency) > 0 && stmt.Currency != account.Currency() {
+		return pkg.ValidationErrorWithFields(
+			pkg.ErrAmountMismatchingCurrencies,
+			fmt.Sprintf("Statement in %s can not be imported into an account in %s", stmt.Currency, account.Currency()),
+			nil,
+			map[string]string{"file": fmt.Sprintf("currency of statement must be %s", account.Currency())},
+		)
+	}
+
+	if fallback, err = svc.fallbackCategoryTx(ctx, userId, profile, categories, tx); err != nil {
+		return err
+	}
+	resolver := statement.NewCategoryResolver(categories, fallback)
+	categorizer := newRuleCategorizer(rules, categories)
+
+	for i, transaction := range stmt.Transactions {
+		var (
+			record     ledger.Record
+			recordResp RecordResponse
+			imported   bool
+		)
+
+		category := resolver.Resolve(transaction.Category)
+		if category.Id() == fallback.Id() {
+			// An invalid amount is reported when the record of the transaction is made
+			if amount, err := ledger.ParseMoney(account.Currency(), transaction.Amount); err == nil {
+				if _, ruleCategory, ok := categorizer.match(account.Id(), transaction.Note(), amount); ok {
+					category = ruleCategory
+				}
+			}
+		}
+
+		if record, imported, err = svc.importTransactionTx(ctx, account, transaction, category, i+1, request.DryRun, updatedBy, tx); err != nil {
+			if rowError, ok := makeImportRowError(i+1, err); ok {
+				resp.Errors = append(resp.Errors, rowError)
+				continue
+			}
+			return err
+		}
+		if !imported {
+			resp.Skipped += 1
+			continue
+		}
+
+		if recordResp, err = makeImportedRecordResponse(record, userProfile, request.DryRun); err != nil {
+			return err
+		}
+		resp.Records = append(resp.Records, ImportedRecordResponse{Row: i + 1, Record: recordResp})
+	}
+	return nil
+}
+
+// fallbackCategoryTx returns the category of statement transactions whose category is not found.
+func (svc importService) fallbackCategoryTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	profile ledger.ImportProfile,
+	categories ledger.Categories,
+	tx *sql.Tx,
+) (ledger.Category, error) {
+	var (
+		categoryId ledger.CategoryId
+		category   ledger.Category
+		err        error
+	)
+
+	if profile.DefaultCategoryId() != ledger.NoDefaultCategory {
+		if category, ok := categories.MapById()[profile.DefaultCategoryId()]; ok {
+			return category, nil
+		}
+	}
+
+	for _, category := range categories {
+		if strings.EqualFold(category.Name(), svc.uncategorizedCategoryName) {
+			return category, nil
+		}
+	}
+
+	if categoryId, err = svc.categoryDao.NewCategoryId(tx); err != nil {
+		return ledger.Category{}, err
+	}
+
+	if category, err = ledger.NewCategory(
+		categoryId,
+		svc.uncategorizedCategoryName,
+		ledger.MustMakeUpdatedByUserId(userId),
+	); err != nil {
+		return ledger.Category{}, err
+	}
+
+	if err = svc.categoryDao.SaveTx(ctx, userId, ledger.Categories{category}, tx); err != nil {
+		return ledger.Category{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to create uncategorized category", err)
+	}
+	return category, nil
+}
+
+// importTransactionTx creates the record of a transaction of a statement.
+// false is returned if the transaction was imported into the account before.
+func (svc importService) importTransactionTx(
+	ctx context.Context,
+	account ledger.Account,
+	transaction statement.Transaction,
+	category ledger.Category,
+	number int,
+	dryRun bool,
+	updatedBy ledger.UpdatedBy,
+	tx *sql.Tx,
+) (ledger.Record, bool, error) {
+	var (
+		recordId ledger.RecordId
+		record   ledger.Record
+		imported bool
+		err      error
+	)
+
+	// Records of a dry run are not saved, so they are not assigned an id
+	recordId = ledger.RecordId(number)
+	if !dryRun {
+		if recordId, err = svc.recordDao.NewRecordId(tx); err != nil {
+			return ledger.Record{}, false, err
+		}
+	}
+
+	if record, err = transaction.Record(recordId, account.Currency(), category, updatedBy); err != nil {
+		return ledger.Record{}, false, err
+	}
+
+	// The external id of a dry run is saved so that duplicates within the file are reported, and is rolled back with the rest of the dry run
+	savedRecordId := recordId
+	if dryRun {
+		savedRecordId = 0
+	}
+
+	// A transaction that was recorded before it was imported e.g. by hand or from a CSV is not imported.
+	// Its external id is saved without a record, so that it is not reported again.
+	var duplicateIds []ledger.RecordId
+	if duplicateIds, err = svc.records.duplicates.findExactDuplicatesTx(ctx, account.Id(), record, updatedBy, tx); err != nil {
+		return ledger.Record{}, false, err
+	}
+	if len(duplicateIds) > 0 {
+		if imported, err = svc.recordDao.SaveExternalIdTx(ctx, account.Id(), transaction.ExternalId, 0, tx); err != nil || !imported {
+			return ledger.Record{}, false, err
+		}
+		return ledger.Record{}, false, duplicateRecordError(duplicateIds)
+	}
+	if imported, err = svc.recordDao.SaveExternalIdTx(ctx, account.Id(), transaction.ExternalId, savedRecordId, tx); err != nil || !imported {
+		return ledger.Record{}, false, err
+	}
+
+	if dryRun {
+		return record, true, nil
+	}
+
+	if err = svc.recordDao.SaveTx(ctx, account.Id(), record, tx); err != nil {
+		return ledger.Record{}, false, err
+	}
+
+	if err = svc.records.updateCategoriesLastUsed(ctx, record, tx); err != nil {
+		return ledger.Record{}, false, err
+	}
+
+	return record, true, nil
+}
+
+func makeImportedRecordResponse(record ledger.Record, userProfile ledger.UserProfile, dryRun bool) (RecordResponse, error) {
+	recordResp, err := makeRecordResponse(record, ledger.Account{}, userProfile)
+	if err != nil {
+		return RecordResponse{}, err
+	}
+	if dryRun {
+		recordResp.Id = 0
+	}
+	return recordResp, nil
+}
+
+// importRowTx creates the record of a row of an imported file.
+// Rows that can not be imported are reported with a validation error.
+func (svc importService) importRowTx(
+	ctx context.Context,
+	accountId ledger.AccountId,
+	currencyCode string,
+	profile ledger.ImportProfile,
+	categoriesByName map[string]ledger.Category,
+	categorizer ruleCategorizer,
+	defaultCategory ledger.Category,
+	row []string,
+	rowNumber int,
+	dryRun bool,
+	updatedBy ledger.UpdatedBy,
+	tx *sql.Tx,
+) (ledger.Record, error) {
+	var (
+		parsed   ledger.ImportedRow
+		category ledger.Category
+		recordId ledger.RecordId
+		record   ledger.Record
+		ok       bool
+		err      error
+	)
+
+	if parsed, err = profile.ParseRow(row, currencyCode); err != nil {
+		return ledger.Record{}, err
+	}
+
+	if len(parsed.CategoryName()) > 0 {
+		if category, ok = categoriesByName[strings.ToLower(parsed.CategoryName())]; !ok {
+			return ledger.Record{}, pkg.ValidationErrorWithFields(
+				pkg.ErrCategoriesNotFound,
+				fmt.Sprintf("Category %q not found", parsed.CategoryName()),
+				nil,
+				map[string]string{"category": fmt.Sprintf("category %q not found", parsed.CategoryName())},
+			)
+		}
+	} else if _, category, ok = categorizer.match(accountId, parsed.Note(), parsed.Amount()); !ok {
+		if category, ok = defaultCategory, defaultCategory != (ledger.Category{}); !ok {
+			return ledger.Record{}, pkg.ValidationErrorWithFields(
+				pkg.ErrImportValidation,
+				"Row has no category, no rule applies to it and the import profile has no default category",
+				nil,
+				map[string]string{"category": "category is required"},
+			)
+		}
+	}
+
+	// Records of a dry run are not saved, so they are not assigned an id
+	recordId = ledger.RecordId(rowNumber)
+	if !dryRun {
+		if recordId, err = svc.recordDao.NewRecordId(tx); err != nil {
+			return ledger.Record{}, err
+		}
+	}
+
+	sourceAccountId, beneficiaryId, transferReference := ledger.NoTransfer()
+	if record, err = ledger.NewRecord(
+		recordId,
+		parsed.Note(),
+		category,
+		parsed.Amount(),
+		parsed.Date(),
+		parsed.RecordType(),
+		sourceAccountId,
+		beneficiaryId,
+		ledger.NoBeneficiaryType,
+		transferReference,
+		updatedBy,
+	); err != nil {
+		return ledger.Record{}, err
+	}
+
+	var duplicateIds []ledger.RecordId
+	if duplicateIds, err = svc.records.duplicates.findExactDuplicatesTx(ctx, accountId, record, updatedBy, tx); err != nil {
+		return ledger.Record{}, err
+	}
+	if len(duplicateIds) > 0 {
+		return ledger.Record{}, duplicateRecordError(duplicateIds)
+	}
+
+	if dryRun {
+		return record, nil
+	}
+
+	if err = svc.recordDao.SaveTx(ctx, accountId, record, tx); err != nil {
+		return ledger.Record{}, err
+	}
+
+	if err = svc.records.updateCategoriesLastUsed(ctx, record, tx); err != nil {
+		return ledger.Record{}, err
+	}
+
+	return record, nil
+}
+
+// makeImportRowError reports a validation error of a row. false is returned for other errors, which fail the import.
+func makeImportRowError(row int, err error) (ImportRowError, bool) {
+	validationError, ok := err.(pkg.ValidationError)
+	if !ok {
+		return ImportRowError{}, false
+	}
+	return ImportRowError{
+		Row:    row,
+		Code:   validationError.Code(),
+		Title:  validationError.Title(),
+		Detail: validationError.Detail(),
+		Fields: validationError.InvalidFields(),
+	}, true
+}
+
+// importFormat is the format of the request, or else the format of the extension of the file name. Files are CSVs by default.
+func importFormat(request ImportRecordsRequest) (ImportFormat, error) {
+	if len(request.Format) > 0 {
+		switch format := ImportFormat(strings.ToUpper(string(request.Format))); format {
+		case ImportFormatCsv, ImportFormatOfx, ImportFormatQif:
+			return format, nil
+		case "QFX":
+			return ImportFormatOfx, nil
+		default:
+			return "", pkg.ValidationErrorWithFields(
+				pkg.ErrImportValidation,
+				fmt.Sprintf("Unsupported format %q", request.Format),
+				nil,
+				map[string]string{"format": "format must be one of CSV, OFX, QFX or QIF"},
+			)
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(request.FileName)) {
+	case ".ofx", ".qfx":
+		return ImportFormatOfx, nil
+	case ".qif":
+		return ImportFormatQif, nil
+	default:
+		return ImportFormatCsv, nil
+	}
+}
+
+func readImportFile(file io.Reader) ([][]string, error) {
+	if file == nil {
+		return nil, pkg.ValidationErrorWithFields(pkg.ErrImportValidation, "No file provided", nil, map[string]string{"file": "file is required"})
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows := [][]string{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, pkg.ValidationErrorWithFields(pkg.ErrImportValidation, fmt.Sprintf("File is not a valid CSV. Reason: %s", err), err, map[string]string{"file": "file must be a CSV"})
+		}
+		if len(rows) == 0 && len(row) > 0 {
+			row[0] = strings.TrimPrefix(row[0], "\ufeff")
+		}
+		if len(rows) == MaxImportRows {
+			return nil, pkg.ValidationErrorWithFields(pkg.ErrImportValidation, fmt.Sprintf("A file can not have more than %d rows", MaxImportRows), nil, map[string]string{"file": "file has too many rows"})
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, pkg.ValidationErrorWithFields(pkg.ErrImportValidation, "File is empty", nil, map[string]string{"file": "file is empty"})
+	}
+	return rows, nil
+}
+
+// importFileName is the name of the imported file, as recorded in the audit of the records created from it.
+func importFileName(fileName string) string {
+	fileName = strings.NewReplacer(":", "_", ";", "_").Replace(filepath.Base(strings.TrimSpace(fileName)))
+	if fileName == "." || fileName == string(filepath.Separator) || len(fileName) == 0 {
+		return "upload.csv"
+	}
+	if len(fileName) > 100 {
+		fileName = fileName[:100]
+	}
+	return fileName
+}
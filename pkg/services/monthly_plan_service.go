@@ -0,0 +1,447 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+// CategoryAssignmentRequest is the part of the expected income of a month assigned to a category.
+type CategoryAssignmentRequest struct {
+	CategoryId uint64         `json:"categoryId"`
+	Amount     AmountResponse `json:"amount"`
+}
+
+// CreateMonthlyPlanRequest assigns the income expected in a month, formatted as yyyy-MM, to categories.
+type CreateMonthlyPlanRequest struct {
+	Month          string                      `json:"month"`
+	AccountIds     []uint64                    `json:"accountIds"`
+	ExpectedIncome AmountResponse              `json:"expectedIncome"`
+	Assignments    []CategoryAssignmentRequest `json:"assignments"`
+}
+
+// UpdateMonthlyPlanRequest replaces the accounts, expected income and assignments of a plan.
+// Version is the version of the plan the client last saw; it can also be provided with the If-Match header.
+type UpdateMonthlyPlanRequest struct {
+	AccountIds     []uint64                    `json:"accountIds"`
+	ExpectedIncome AmountResponse              `json:"expectedIncome"`
+	Assignments    []CategoryAssignmentRequest `json:"assignments"`
+	Version        uint64                      `json:"version"`
+}
+
+// MonthlyPlanResponse is a plan with the amount of its expected income that is left to be assigned.
+// ToBeAssigned is negative when more than the expected income is assigned, in which case OverAssigned is the excess.
+type MonthlyPlanResponse struct {
+	Id             uint64                      `json:"id"`
+	Month          string                      `json:"month"`
+	AccountIds     []uint64                    `json:"accountIds"`
+	ExpectedIncome AmountResponse              `json:"expectedIncome"`
+	Assignments    []CategoryAssignmentRequest `json:"assignments"`
+	Assigned       AmountResponse              `json:"assigned"`
+	ToBeAssigned   AmountResponse              `json:"toBeAssigned"`
+	OverAssigned   AmountResponse              `json:"overAssigned"`
+	Version        uint64                      `json:"version"`
+}
+
+// PlanProgressResponse compares the amount spent to the amount planned.
+type PlanProgressResponse struct {
+	Planned     AmountResponse `json:"planned"`
+	Actual      AmountResponse `json:"actual"`
+	Remaining   AmountResponse `json:"remaining"`
+	PercentUsed float64        `json:"percentUsed"`
+}
+
+type CategoryPlanProgressResponse struct {
+	CategoryId uint64 `json:"categoryId"`
+	PlanProgressResponse
+}
+
+type MonthlyPlanSummaryResponse struct {
+	Plan         MonthlyPlanResponse            `json:"plan"`
+	ActualIncome AmountResponse                 `json:"actualIncome"`
+	Categories   []CategoryPlanProgressResponse `json:"categories"`
+	Total        PlanProgressResponse           `json:"total"`
+}
+
+func makeMonthlyPlanResponse(plan ledger.MonthlyPlan) MonthlyPlanResponse {
+	assignments := make([]CategoryAssignmentRequest, 0, len(plan.Assignments()))
+	for _, assignment := range plan.Assignments() {
+		assignments = append(assignments, CategoryAssignmentRequest{
+			CategoryId: uint64(assignment.CategoryId()),
+			Amount:     makeAmountResponse(assignment.MaxLimit()),
+		})
+	}
+
+	return MonthlyPlanResponse{
+		Id:             uint64(plan.Id()),
+		Month:          formatMonth(plan.Month()),
+		AccountIds:     accountIdsToUint64(plan.AccountIds()),
+		ExpectedIncome: makeAmountResponse(plan.ExpectedIncome()),
+		Assignments:    assignments,
+		Assigned:       makeAmountResponse(plan.Assigned()),
+		ToBeAssigned:   makeAmountResponse(plan.ToBeAssigned()),
+		OverAssigned:   makeAmountResponse(plan.OverAssigned()),
+		Version:        uint64(plan.Version()),
+	}
+}
+
+func makePlanProgressResponse(progress ledger.Progress) PlanProgressResponse {
+	return PlanProgressResponse{
+		Planned:     makeAmountResponse(progress.MaxLimit()),
+		Actual:      makeAmountResponse(progress.Spent()),
+		Remaining:   makeAmountResponse(progress.Remaining()),
+		PercentUsed: progress.PercentUsed(),
+	}
+}
+
+func makeMonthlyPlanSummaryResponse(summary ledger.MonthlyPlanSummary) MonthlyPlanSummaryResponse {
+	categories := make([]CategoryPlanProgressResponse, 0, len(summary.Categories()))
+	for _, category := range summary.Categories() {
+		categories = append(categories, CategoryPlanProgressResponse{
+			CategoryId:           uint64(category.CategoryId()),
+			PlanProgressResponse: makePlanProgressResponse(category.Progress),
+		})
+	}
+
+	return MonthlyPlanSummaryResponse{
+		Plan:         makeMonthlyPlanResponse(summary.Plan()),
+		ActualIncome: makeAmountResponse(summary.ActualIncome()),
+		Categories:   categories,
+		Total:        makePlanProgressResponse(summary.Total()),
+	}
+}
+
+type MonthlyPlanService interface {
+	CreateMonthlyPlan(ctx context.Context, request CreateMonthlyPlanRequest) (MonthlyPlanResponse, error)
+	GetMonthlyPlan(ctx context.Context, month string) (MonthlyPlanResponse, error)
+	UpdateMonthlyPlan(ctx context.Context, month string, request UpdateMonthlyPlanRequest) (MonthlyPlanResponse, error)
+	// GetMonthlyPlanSummary compares the income and the expenses of each category of the month of a plan to the plan.
+	// Categories that were spent on without being assigned anything are included with nothing planned.
+	GetMonthlyPlanSummary(ctx context.Context, month string) (MonthlyPlanSummaryResponse, error)
+}
+
+type monthlyPlanService struct {
+	accountDao     dao.AccountDao
+	categoryDao    dao.CategoryDao
+	monthlyPlanDao dao.MonthlyPlanDao
+}
+
+func NewMonthlyPlanService(
+	accountDao dao.AccountDao,
+	categoryDao dao.CategoryDao,
+	monthlyPlanDao dao.MonthlyPlanDao,
+) (MonthlyPlanService, error) {
+	if accountDao == nil {
+		return nil, fmt.Errorf("can not create monthly plan service. accountDao is nil")
+	}
+	if categoryDao == nil {
+		return nil, fmt.Errorf("can not create monthly plan service. categoryDao is nil")
+	}
+	if monthlyPlanDao == nil {
+		return nil, fmt.Errorf("can not create monthly plan service. monthlyPlanDao is nil")
+	}
+
+	return &monthlyPlanService{
+		accountDao:     accountDao,
+		categoryDao:    categoryDao,
+		monthlyPlanDao: monthlyPlanDao,
+	}, nil
+}
+
+func (svc monthlyPlanService) CreateMonthlyPlan(ctx context.Context, request CreateMonthlyPlanRequest) (MonthlyPlanResponse, error) {
+	var (
+		userId         ledger.UserId
+		tx             *sql.Tx
+		month          ledger.CalendarMonth
+		id             ledger.MonthlyPlanId
+		expectedIncome ledger.Money
+		assignments    ledger.CategoryBudgets
+		plan           ledger.MonthlyPlan
+		err            error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if month, err = parseMonth(request.Month); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if tx, err = svc.monthlyPlanDao.BeginTx(); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("CreateMonthlyPlan: %d", userId))
+
+	accountIds := uint64ToAccountIds(request.AccountIds)
+	if expectedIncome, assignments, err = svc.makeAssignments(ctx, userId, accountIds, request.ExpectedIncome, request.Assignments, tx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if id, err = svc.monthlyPlanDao.NewMonthlyPlanId(tx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if plan, err = ledger.NewMonthlyPlan(
+		id,
+		month,
+		accountIds,
+		expectedIncome,
+		assignments,
+		ledger.MustMakeUpdatedByUserId(userId),
+	); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if err = svc.monthlyPlanDao.SaveTx(ctx, userId, plan, tx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	return makeMonthlyPlanResponse(plan), nil
+}
+
+func (svc monthlyPlanService) GetMonthlyPlan(ctx context.Context, monthString string) (MonthlyPlanResponse, error) {
+	var (
+		userId ledger.UserId
+		tx     *sql.Tx
+		month  ledger.CalendarMonth
+		plan   ledger.MonthlyPlan
+		err    error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if month, err = parseMonth(monthString); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if tx, err = svc.monthlyPlanDao.BeginTx(); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("GetMonthlyPlan: %d", userId))
+
+	if plan, err = svc.monthlyPlanDao.GetMonthlyPlanTx(ctx, userId, month, tx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	return makeMonthlyPlanResponse(plan), nil
+}
+
+func (svc monthlyPlanService) UpdateMonthlyPlan(ctx context.Context, monthString string, request UpdateMonthlyPlanRequest) (MonthlyPlanResponse, error) {
+	var (
+		userId         ledger.UserId
+		tx             *sql.Tx
+		month          ledger.CalendarMonth
+		expectedIncome ledger.Money
+		assignments    ledger.CategoryBudgets
+		plan           ledger.MonthlyPlan
+		err            error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if month, err = parseMonth(monthString); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if request.Version == 0 {
+		return MonthlyPlanResponse{}, pkg.ValidationErrorWithFields(pkg.ErrMonthlyPlanValidation, "The version of the plan is required", nil, map[string]string{
+			"version": "version must be provided in the request body or in the If-Match header",
+		})
+	}
+
+	if tx, err = svc.monthlyPlanDao.BeginTx(); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("UpdateMonthlyPlan: %d", userId))
+
+	if plan, err = svc.monthlyPlanDao.GetMonthlyPlanTx(ctx, userId, month, tx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if plan.Version() != ledger.Version(request.Version) {
+		return MonthlyPlanResponse{}, pkg.ValidationErrorWithFields(
+			pkg.ErrMonthlyPlanVersionConflict,
+			fmt.Sprintf("Plan for %s has been changed. Expected version %d but found version %d", month, request.Version, plan.Version()),
+			nil,
+			nil,
+		)
+	}
+
+	accountIds := uint64ToAccountIds(request.AccountIds)
+	if expectedIncome, assignments, err = svc.makeAssignments(ctx, userId, accountIds, request.ExpectedIncome, request.Assignments, tx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if plan, err = plan.Edit(
+		accountIds,
+		expectedIncome,
+		assignments,
+		ledger.MustMakeUpdatedByUserId(userId),
+	); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if err = svc.monthlyPlanDao.UpdateTx(ctx, userId, plan, tx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	// The version is assigned by the database
+	if plan, err = svc.monthlyPlanDao.GetMonthlyPlanTx(ctx, userId, month, tx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return MonthlyPlanResponse{}, err
+	}
+
+	return makeMonthlyPlanResponse(plan), nil
+}
+
+func (svc monthlyPlanService) GetMonthlyPlanSummary(ctx context.Context, monthString string) (MonthlyPlanSummaryResponse, error) {
+	var (
+		userId           ledger.UserId
+		tx               *sql.Tx
+		month            ledger.CalendarMonth
+		plan             ledger.MonthlyPlan
+		actualIncome     ledger.Money
+		spentPerCategory map[ledger.CategoryId]ledger.Money
+		summary          ledger.MonthlyPlanSummary
+		err              error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return MonthlyPlanSummaryResponse{}, err
+	}
+
+	if month, err = parseMonth(monthString); err != nil {
+		return MonthlyPlanSummaryResponse{}, err
+	}
+
+	if tx, err = svc.monthlyPlanDao.BeginTx(); err != nil {
+		return MonthlyPlanSummaryResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("GetMonthlyPlanSummary: %d", userId))
+
+	if plan, err = svc.monthlyPlanDao.GetMonthlyPlanTx(ctx, userId, month, tx); err != nil {
+		return MonthlyPlanSummaryResponse{}, err
+	}
+
+	if actualIncome, spentPerCategory, err = svc.monthlyPlanDao.GetActualsTx(ctx, userId, plan, tx); err != nil {
+		return MonthlyPlanSummaryResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return MonthlyPlanSummaryResponse{}, err
+	}
+
+	if summary, err = ledger.NewMonthlyPlanSummary(plan, actualIncome, spentPerCategory); err != nil {
+		return MonthlyPlanSummaryResponse{}, err
+	}
+
+	return makeMonthlyPlanSummaryResponse(summary), nil
+}
+
+// makeAssignments checks that the accounts and categories of a plan belong to the user, and that the accounts are in the currency of the expected income.
+func (svc monthlyPlanService) makeAssignments(
+	ctx context.Context,
+	userId ledger.UserId,
+	accountIds ledger.AccountIds,
+	expectedIncomeRequest AmountResponse,
+	requests []CategoryAssignmentRequest,
+	tx *sql.Tx,
+) (ledger.Money, ledger.CategoryBudgets, error) {
+	var (
+		currencies     map[ledger.AccountId]ledger.Currency
+		categories     ledger.Categories
+		expectedIncome ledger.Money
+		err            error
+	)
+
+	if expectedIncome, err = ledger.NewMoney(expectedIncomeRequest.Currency, expectedIncomeRequest.Value); err != nil {
+		return nil, nil, err
+	}
+
+	if currencies, err = svc.accountDao.GetCurrenciesOfAccounts(ctx, accountIds, userId, tx); err != nil {
+		return nil, nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to get account currencies", err)
+	}
+
+	for _, accountId := range accountIds {
+		currency, ok := currencies[accountId]
+		if !ok {
+			return nil, nil, pkg.ValidationErrorWithFields(pkg.ErrAccountNotFound, fmt.Sprintf("Account #%d not found", accountId), nil, map[string]string{
+				"accountIds": fmt.Sprintf("account %d does not exist", accountId),
+			})
+		}
+		if currency.CurrencyCode() != expectedIncome.Currency().CurrencyCode() {
+			return nil, nil, pkg.ValidationErrorWithFields(pkg.ErrMonthlyPlanValidation, "Plan currency must match account currencies", nil, map[string]string{
+				"expectedIncome": fmt.Sprintf("expectedIncome must be in %s, the currency of the accounts", currency.CurrencyCode()),
+			})
+		}
+	}
+
+	if categories, err = svc.categoryDao.GetCategoriesForUser(ctx, userId, tx); err != nil {
+		return nil, nil, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to get categories for user", err)
+	}
+
+	categoryIdMap := categories.MapById()
+	assignments := ledger.CategoryBudgets{}
+	for _, request := range requests {
+		var (
+			amount     ledger.Money
+			assignment ledger.CategoryBudget
+		)
+
+		if _, ok := categoryIdMap[ledger.CategoryId(request.CategoryId)]; !ok {
+			return nil, nil, pkg.ValidationErrorWithFields(pkg.ErrCategoriesNotFound, fmt.Sprintf("Category #%d not found", request.CategoryId), nil, map[string]string{
+				"assignments": fmt.Sprintf("category %d does not exist", request.CategoryId),
+			})
+		}
+
+		if amount, err = ledger.NewMoney(request.Amount.Currency, request.Amount.Value); err != nil {
+			return nil, nil, err
+		}
+
+		if assignment, err = ledger.NewCategoryBudgetWithAlertThresholds(ledger.CategoryId(request.CategoryId), amount, ledger.AlertThresholds{}); err != nil {
+			return nil, nil, err
+		}
+		assignments = append(assignments, assignment)
+	}
+	return expectedIncome, assignments, nil
+}
+
+// parseMonth parses a calendar month formatted as yyyy-MM.
+func parseMonth(month string) (ledger.CalendarMonth, error) {
+	date, err := time.Parse("2006-01", month)
+	if err != nil {
+		return ledger.CalendarMonth{}, pkg.ValidationErrorWithFields(pkg.ErrMonthlyPlanValidation, fmt.Sprintf("Invalid month %q", month), err, map[string]string{
+			"month": "month must be formatted as yyyy-MM",
+		})
+	}
+	return ledger.MakeCalendarMonthFromDate(date), nil
+}
+
+func formatMonth(month ledger.CalendarMonth) string {
+	return month.FirstDay().Format("2006-01")
+}
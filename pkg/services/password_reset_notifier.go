@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+// PasswordResetNotice is sent to the email of a user who asked to reset their password.
+type PasswordResetNotice struct {
+	UserId    ledger.UserId
+	Email     string
+	Token     string
+	ExpiresAt time.Time
+}
+
+// PasswordResetNotifier delivers the token with which a user resets their password.
+// The token is only delivered to the email of the user, so that whoever asks for it must own that email.
+type PasswordResetNotifier interface {
+	Notify(ctx context.Context, notice PasswordResetNotice) error
+}
+
+// LogPasswordResetNotifier writes password reset tokens to the application log.
+// Anyone who can read the log can reset any password, so it must only be used in development.
+type LogPasswordResetNotifier struct{}
+
+func NewLogPasswordResetNotifier() *LogPasswordResetNotifier {
+	return &LogPasswordResetNotifier{}
+}
+
+func (n *LogPasswordResetNotifier) Notify(ctx context.Context, notice PasswordResetNotice) error {
+	log.Printf(
+		"Password reset for user %d <%s>: token %s expires at %s",
+		notice.UserId,
+		notice.Email,
+		notice.Token,
+		notice.ExpiresAt.Format(time.RFC3339),
+	)
+	return nil
+}
+
+// InMemoryPasswordResetNotifier keeps the notices it is notified of, so that they can be inspected in tests.
+type InMemoryPasswordResetNotifier struct {
+	mutex   sync.Mutex
+	notices []PasswordResetNotice
+}
+
+func NewInMemoryPasswordResetNotifier() *InMemoryPasswordResetNotifier {
+	return &InMemoryPasswordResetNotifier{}
+}
+
+func (n *InMemoryPasswordResetNotifier) Notify(ctx context.Context, notice PasswordResetNotice) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.notices = append(n.notices, notice)
+	return nil
+}
+
+// Notices returns the notices notified so far, oldest first.
+func (n *InMemoryPasswordResetNotifier) Notices() []PasswordResetNotice {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	notices := make([]PasswordResetNotice, len(n.notices))
+	copy(notices, n.notices)
+	return notices
+}
+
+// Reset forgets the notices notified so far.
+func (n *InMemoryPasswordResetNotifier) Reset() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.notices = nil
+}
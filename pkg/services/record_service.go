@@ -2,9 +2,16 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ayush6624/go-chatgpt"
@@ -29,7 +36,110 @@ type CreateRecordRequest struct {
 		Beneficiary struct {
 			Id uint64 `json:"id"`
 		} `json:"beneficiary"`
+		ExchangeRate   string         `json:"exchangeRate,omitempty"`
+		ReceivedAmount *AmountRequest `json:"receivedAmount,omitempty"`
 	} `json:"transfer,omitempty"`
+	// Splits are only provided when the amount is split across multiple categories
+	Splits []SplitRequest `json:"splits,omitempty"`
+	// Force creates the record even if it is likely a duplicate of an existing record
+	Force bool `json:"-"`
+}
+
+// SplitRequest is one line of a record whose amount is split across multiple categories.
+type SplitRequest struct {
+	Note     string `json:"note"`
+	Category struct {
+		Id uint64 `json:"id"`
+	} `json:"category"`
+	Amount AmountRequest `json:"amount"`
+}
+
+// AmountRequest is a monetary amount in minor units, as provided by the client.
+type AmountRequest struct {
+	Currency string `json:"currency"`
+	Value    int64  `json:"value"`
+}
+
+const (
+	defaultRecordsPageSize = 50
+	maxRecordsPageSize     = 500
+)
+
+// PageRequest holds the raw pagination parameters, as provided by the client.
+// Cursor is the opaque nextCursor or prevCursor of a previous response.
+type PageRequest struct {
+	Cursor string
+	Limit  string
+}
+
+// SearchRecordsRequest holds the raw record search filters, as provided by the client.
+// Dates can be provided either as yyyy-MM-dd or in RFC3339 format.
+// Period is a day, week, month, quarter, year or range of days e.g. 2021-W28, and can not be combined with From or To.
+type SearchRecordsRequest struct {
+	PageRequest
+	SearchTerm       string
+	From             string
+	To               string
+	Period           string
+	CategoryNames    []string
+	RecordTypes      []string
+	BeneficiaryNames []string
+	// CreatedByKinds are the kinds of actor that created the records e.g. USER or TASK
+	CreatedByKinds []string
+}
+
+// UpdateRecordRequest replaces the details of a record.
+// When the record is a transfer, both records of the transfer are updated.
+// Version is the version of the record the client last saw; it can also be provided with the If-Match header.
+type UpdateRecordRequest struct {
+	Note     string `json:"note"`
+	Category struct {
+		Id uint64 `json:"id"`
+	} `json:"category"`
+	Amount struct {
+		Currency string `json:"currency"`
+		Value    int64  `json:"value"`
+	} `json:"amount"`
+	DateUTC  string `json:"date"`
+	Type     string `json:"type"`
+	Transfer struct {
+		Beneficiary struct {
+			Id uint64 `json:"id"`
+		} `json:"beneficiary"`
+		ExchangeRate   string         `json:"exchangeRate,omitempty"`
+		ReceivedAmount *AmountRequest `json:"receivedAmount,omitempty"`
+	} `json:"transfer,omitempty"`
+	Splits  []SplitRequest `json:"splits,omitempty"`
+	Version uint64         `json:"version"`
+}
+
+// PatchRecordRequest changes only the provided details of a record.
+// When the record is a transfer, both records of the transfer are updated.
+type PatchRecordRequest struct {
+	Note     *string `json:"note,omitempty"`
+	Category *struct {
+		Id uint64 `json:"id"`
+	} `json:"category,omitempty"`
+	Amount *struct {
+		Currency string `json:"currency"`
+		Value    int64  `json:"value"`
+	} `json:"amount,omitempty"`
+	DateUTC  *string `json:"date,omitempty"`
+	Type     *string `json:"type,omitempty"`
+	Transfer *struct {
+		Beneficiary struct {
+			Id uint64 `json:"id"`
+		} `json:"beneficiary"`
+		ExchangeRate   string         `json:"exchangeRate,omitempty"`
+		ReceivedAmount *AmountRequest `json:"receivedAmount,omitempty"`
+	} `json:"transfer,omitempty"`
+	// An empty list of splits removes the splits of the record
+	Splits  *[]SplitRequest `json:"splits,omitempty"`
+	Version uint64          `json:"version"`
+}
+
+type DeleteRecordRequest struct {
+	Version uint64 `json:"version"`
 }
 
 type CreateRecordPrompt struct {
@@ -43,18 +153,27 @@ type RecordResponse struct {
 		Id   uint64 `json:"id"`
 		Name string `json:"name"`
 	} `json:"category"`
-	Amount  AmountResponse `json:"amount"`
-	DateUTC string         `json:"date"`
-	Type    string         `json:"type"`
+	Amount     AmountResponse `json:"amount"`
+	DateUTC    string         `json:"date"`
+	OccurredAt string         `json:"occurredAt"`
+	Type       string         `json:"type"`
+	Version    uint64         `json:"version"`
+
+	CreatedBy UpdatedByResponse `json:"createdBy"`
+	// ModifiedBy is only set once the record has been changed
+	ModifiedBy *UpdatedByResponse `json:"modifiedBy,omitempty"`
 
 	// Transfer is only set when record type is transfer
 	Transfer *TransferResponse `json:"transfer,omitempty"`
 
+	// Splits are only set when the amount of the record is split across multiple categories
+	Splits []SplitResponse `json:"splits,omitempty"`
+
 	// Account is only set when a single record is updated.
 	Account *AccountBalanceResponse `json:"account,omitempty"`
 }
 
-func makeRecordResponse(record ledger.Record, account ledger.Account) (RecordResponse, error) {
+func makeRecordResponse(record ledger.Record, account ledger.Account, profile ledger.UserProfile) (RecordResponse, error) {
 	amountValue, _ := record.Amount().MinorUnits()
 
 	resp := RecordResponse{}
@@ -64,8 +183,15 @@ func makeRecordResponse(record ledger.Record, account ledger.Account) (RecordRes
 	resp.Category.Name = record.Category().Name()
 	resp.Amount.Currency = record.Amount().Currency().CurrencyCode()
 	resp.Amount.Value = amountValue
-	resp.DateUTC = record.DateUTCString()
+	resp.DateUTC = profile.FormatDate(record.DateUTC())
+	resp.OccurredAt = profile.FormatTime(record.OccurredAtUTC())
 	resp.Type = string(record.Type())
+	resp.Version = uint64(record.Version())
+	resp.CreatedBy = makeUpdatedByResponse(record.CreatedBy())
+	if record.ModifiedBy() != (ledger.UpdatedBy{}) {
+		modifiedBy := makeUpdatedByResponse(record.ModifiedBy())
+		resp.ModifiedBy = &modifiedBy
+	}
 
 	emptyAccount := ledger.Account{}
 	if account != emptyAccount {
@@ -80,15 +206,58 @@ func makeRecordResponse(record ledger.Record, account ledger.Account) (RecordRes
 	if record.Type() == ledger.Transfer {
 		resp.Transfer = new(TransferResponse)
 		resp.Transfer.Beneficiary.Id = uint64(record.BeneficiaryId())
+		resp.Transfer.ExchangeRate = record.ExchangeRate().String()
+	}
+
+	for _, split := range record.Splits() {
+		splitValue, _ := split.Amount().MinorUnits()
+
+		splitResponse := SplitResponse{}
+		splitResponse.Note = split.Note()
+		splitResponse.Category.Id = uint64(split.Category().Id())
+		splitResponse.Category.Name = split.Category().Name()
+		splitResponse.Amount.Currency = split.Amount().Currency().CurrencyCode()
+		splitResponse.Amount.Value = splitValue
+		resp.Splits = append(resp.Splits, splitResponse)
 	}
 
 	return resp, nil
 }
 
+// UpdatedByResponse is who created or changed an entity. Only the fields of its kind are set.
+type UpdatedByResponse struct {
+	Kind     string `json:"kind"`
+	UserId   uint64 `json:"userId,omitempty"`
+	TaskName string `json:"taskName,omitempty"`
+	FileName string `json:"fileName,omitempty"`
+	BatchId  string `json:"batchId,omitempty"`
+}
+
+func makeUpdatedByResponse(updatedBy ledger.UpdatedBy) UpdatedByResponse {
+	return UpdatedByResponse{
+		Kind:     updatedBy.Kind().String(),
+		UserId:   uint64(updatedBy.UserId()),
+		TaskName: updatedBy.TaskName(),
+		FileName: updatedBy.ImportFileName(),
+		BatchId:  updatedBy.ImportBatchId(),
+	}
+}
+
+type SplitResponse struct {
+	Note     string `json:"note"`
+	Category struct {
+		Id   uint64 `json:"id"`
+		Name string `json:"name"`
+	} `json:"category"`
+	Amount AmountResponse `json:"amount"`
+}
+
 type TransferResponse struct {
 	Beneficiary struct {
 		Id uint64 `json:"id"`
 	} `json:"beneficiary"`
+	// ExchangeRate is only set when the accounts have different currencies
+	ExchangeRate string `json:"exchangeRate,omitempty"`
 }
 
 type AccountBalanceResponse struct {
@@ -112,18 +281,19 @@ type RecordsResponse struct {
 		From time.Time `json:"from"`
 		To   time.Time `json:"to"`
 	} `json:"search"`
-}
 
-func makeRecordsResponse(records ledger.Records) (RecordsResponse, error) {
-	if len(records) == 0 {
-		return RecordsResponse{}, nil
-	}
+	// NextCursor is only set when there are older records
+	NextCursor string `json:"nextCursor,omitempty"`
+	// PrevCursor is only set when there are newer records
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
 
-	moneyToAmountResponse := func(money ledger.Money, err error) (AmountResponse, error) {
-		if err != nil {
-			return AmountResponse{}, err
-		}
-		var minorUnits int64
+func makeRecordsResponse(records ledger.Records, summary dao.RecordsSummary, profile ledger.UserProfile) (RecordsResponse, error) {
+	moneyToAmountResponse := func(money ledger.Money) (AmountResponse, error) {
+		var (
+			minorUnits int64
+			err        error
+		)
 		if minorUnits, err = money.MinorUnits(); err != nil {
 			return AmountResponse{}, err
 		}
@@ -134,41 +304,33 @@ func makeRecordsResponse(records ledger.Records) (RecordsResponse, error) {
 	}
 
 	var (
-		totalExpenses AmountResponse
-		totalIncome   AmountResponse
-		totalSavings  AmountResponse
-
-		from time.Time
-		to   time.Time
-
-		err error
+		recordsResponse RecordsResponse
+		err             error
 	)
 
-	if totalIncome, err = moneyToAmountResponse(records.TotalIncome()); err != nil {
+	if recordsResponse.Summary.TotalIncome, err = moneyToAmountResponse(summary.TotalIncome); err != nil {
 		return RecordsResponse{}, err
 	}
-	if totalExpenses, err = moneyToAmountResponse(records.TotalExpenses()); err != nil {
+	if recordsResponse.Summary.TotalExpenses, err = moneyToAmountResponse(summary.TotalExpenses); err != nil {
 		return RecordsResponse{}, err
 	}
-	if totalSavings, err = moneyToAmountResponse(records.TotalSavings()); err != nil {
+	if recordsResponse.Summary.TotalSavings, err = moneyToAmountResponse(summary.TotalSavings); err != nil {
 		return RecordsResponse{}, err
 	}
 
-	if from, to, err = records.Period(); err != nil {
-		return RecordsResponse{}, err
+	recordsResponse.Records = make([]RecordResponse, 0, len(records))
+	if len(records) == 0 {
+		return recordsResponse, nil
 	}
 
-	var recordsResponse RecordsResponse
-	recordsResponse.Summary.TotalExpenses = totalExpenses
-	recordsResponse.Summary.TotalIncome = totalIncome
-	recordsResponse.Summary.TotalSavings = totalSavings
-	recordsResponse.SearchParameters.From = from
-	recordsResponse.SearchParameters.To = to
+	if recordsResponse.SearchParameters.From, recordsResponse.SearchParameters.To, err = records.Period(); err != nil {
+		return RecordsResponse{}, err
+	}
 
 	for _, record := range records {
 		var recordResponse RecordResponse
 
-		if recordResponse, err = makeRecordResponse(record, ledger.Account{}); err != nil {
+		if recordResponse, err = makeRecordResponse(record, ledger.Account{}, profile); err != nil {
 			return RecordsResponse{}, err
 		}
 
@@ -179,7 +341,11 @@ func makeRecordsResponse(records ledger.Records) (RecordsResponse, error) {
 
 type RecordService interface {
 	CreateRecord(ctx context.Context, request CreateRecordRequest) (RecordResponse, error)
-	GetRecords(ctx context.Context, accountId ledger.AccountId) (RecordsResponse, error)
+	GetRecords(ctx context.Context, accountId ledger.AccountId, page PageRequest) (RecordsResponse, error)
+	SearchRecords(ctx context.Context, accountId ledger.AccountId, request SearchRecordsRequest) (RecordsResponse, error)
+	UpdateRecord(ctx context.Context, recordId ledger.RecordId, request UpdateRecordRequest) (RecordResponse, error)
+	PatchRecord(ctx context.Context, recordId ledger.RecordId, request PatchRecordRequest) (RecordResponse, error)
+	DeleteRecord(ctx context.Context, recordId ledger.RecordId, request DeleteRecordRequest) error
 	CreateRecordRequestWithChatGPT(ctx context.Context, prompt CreateRecordPrompt) (CreateRecordRequest, error)
 }
 
@@ -187,14 +353,25 @@ type recordService struct {
 	recordDao   dao.RecordDao
 	accountDao  dao.AccountDao
 	categoryDao dao.CategoryDao
-	gptApiKey   string
+	// categoryRuleDao is used to categorise records that are created without a category
+	categoryRuleDao dao.CategoryRuleDao
+	// userDao is used to load the profile of the user, according to which dates are read and shown
+	userDao    dao.UserDao
+	gptApiKey  string
+	duplicates duplicateDetector
+	// alertService raises budget alerts when a record that is created reaches an alert threshold
+	alertService AlertService
 }
 
 func NewRecordService(
 	recordDao dao.RecordDao,
 	accountDao dao.AccountDao,
 	categoryDao dao.CategoryDao,
+	categoryRuleDao dao.CategoryRuleDao,
+	userDao dao.UserDao,
 	gptApiKey string,
+	duplicateWindow time.Duration,
+	alertService AlertService,
 ) (RecordService, error) {
 	if recordDao == nil {
 		return nil, fmt.Errorf("can not create record service. recordDao is nil")
@@ -205,12 +382,25 @@ func NewRecordService(
 	if categoryDao == nil {
 		return nil, fmt.Errorf("can not create record service. categoryDao is nil")
 	}
+	if categoryRuleDao == nil {
+		return nil, fmt.Errorf("can not create record service. categoryRuleDao is nil")
+	}
+	if userDao == nil {
+		return nil, fmt.Errorf("can not create record service. userDao is nil")
+	}
+	if alertService == nil {
+		return nil, fmt.Errorf("can not create record service. alertService is nil")
+	}
 
 	return &recordService{
-		recordDao:   recordDao,
-		accountDao:  accountDao,
-		categoryDao: categoryDao,
-		gptApiKey:   gptApiKey,
+		recordDao:       recordDao,
+		accountDao:      accountDao,
+		categoryDao:     categoryDao,
+		categoryRuleDao: categoryRuleDao,
+		userDao:         userDao,
+		gptApiKey:       gptApiKey,
+		duplicates:      duplicateDetector{recordDao: recordDao, window: duplicateWindow},
+		alertService:    alertService,
 	}, nil
 }
 
@@ -238,113 +428,364 @@ func (svc recordService) CreateRecord(ctx context.Context, request CreateRecordR
 
 	var (
 		recordId ledger.RecordId
+		account  ledger.Account
+		record   ledger.Record
+		user     ledger.User
+	)
+
+	if user, err = svc.userDao.GetUserByIdTx(ctx, userId, tx); err != nil {
+		return RecordResponse{}, err
+	}
+	profile := user.Profile()
+
+	if recordId, err = svc.recordDao.NewRecordId(tx); err != nil {
+		return RecordResponse{}, err
+	}
+
+	if request.Category.Id == 0 {
+		if request.Category.Id, err = svc.categorizeByRulesTx(ctx, userId, accountId, request, tx); err != nil {
+			return RecordResponse{}, err
+		}
+	}
+
+	// The default category of the user is used when no rule applies
+	if request.Category.Id == 0 {
+		request.Category.Id = uint64(user.Preferences().DefaultCategoryId())
+	}
+
+	if record, err = svc.createRecordTx(ctx, userId, accountId, recordId, request, profile, ledger.MustMakeUpdatedByUserId(userId), tx); err != nil {
+		return RecordResponse{}, err
+	}
+
+	if !request.Force {
+		var duplicateIds []ledger.RecordId
+		if duplicateIds, err = svc.duplicates.findLikelyDuplicatesTx(ctx, accountId, record, tx); err != nil {
+			return RecordResponse{}, err
+		}
+		if len(duplicateIds) > 0 {
+			return RecordResponse{}, duplicateRecordError(duplicateIds)
+		}
+	}
+
+	// Get account balance
+	if account, err = svc.accountDao.GetAccountById(ctx, accountId, userId, tx); err != nil {
+		return RecordResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return RecordResponse{}, err
+	}
+
+	// The record is created even if the budget alerts can not be raised
+	if _, err = svc.alertService.CheckAlertThresholds(ctx, accountId, record); err != nil {
+		log.Printf("Failed to check budget alert thresholds for record %d. Reason: %s", record.Id(), err)
+	}
+
+	return makeRecordResponse(record, account, profile)
+}
+
+// categorizeByRulesTx returns the category of the first rule of the user that applies to a record created without a category.
+// 0 is returned if no rule applies, in which case the default category of the user is used, if the user has one.
+func (svc recordService) categorizeByRulesTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	accountId ledger.AccountId,
+	request CreateRecordRequest,
+	tx *sql.Tx,
+) (uint64, error) {
+	var (
+		rules      ledger.CategoryRules
+		categories ledger.Categories
+		amount     ledger.Money
+		err        error
+	)
+
+	// An invalid amount is reported when the record is created
+	if amount, err = ledger.NewMoney(request.Amount.Currency, request.Amount.Value); err != nil {
+		return 0, nil
+	}
+
+	if rules, err = svc.categoryRuleDao.GetCategoryRulesForUser(ctx, userId, tx); err != nil || len(rules) == 0 {
+		return 0, err
+	}
+
+	if categories, err = svc.categoryDao.GetCategoriesForUser(ctx, userId, tx); err != nil {
+		return 0, err
+	}
+
+	if _, category, ok := newRuleCategorizer(rules, categories).match(accountId, request.Note, amount); ok {
+		return uint64(category.Id()), nil
+	}
+	return 0, nil
+}
+
+// createRecordTx creates a record with the given id in the account of the user.
+// When the record is a transfer, the beneficiary account is credited as well and the debit record is returned.
+// The date of the record is the day of the requested moment in the timezone of the profile.
+func (svc recordService) createRecordTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	accountId ledger.AccountId,
+	recordId ledger.RecordId,
+	request CreateRecordRequest,
+	profile ledger.UserProfile,
+	updatedBy ledger.UpdatedBy,
+	tx *sql.Tx,
+) (ledger.Record, error) {
+	var (
 		category ledger.Category
 		account  ledger.Account
 		amount   ledger.Money
 		date     time.Time
 		record   ledger.Record
+		err      error
 	)
 
-	// TODO: Check account belongs to user id
-	if recordId, err = svc.recordDao.NewRecordId(tx); err != nil {
-		return RecordResponse{}, err
+	if account, err = svc.accountDao.GetAccountById(ctx, accountId, userId, tx); err != nil {
+		return ledger.Record{}, err
 	}
 
 	if category, err = svc.categoryDao.GetCategoryById(ctx, ledger.CategoryId(request.Category.Id), userId, tx); err != nil {
-		return RecordResponse{}, err
+		return ledger.Record{}, err
 	}
 
 	if amount, err = ledger.NewMoney(request.Amount.Currency, int64(request.Amount.Value)); err != nil {
-		return RecordResponse{}, err
+		return ledger.Record{}, err
+	}
+
+	if date, err = time.Parse(time.RFC3339, request.DateUTC); err != nil {
+		return ledger.Record{}, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, fmt.Sprintf("Date '%s' does not match format '%s'", request.DateUTC, time.RFC3339), nil, nil)
+	}
+	date = date.In(profile.Location())
+
+	if ledger.RecordType(request.Type) == ledger.Transfer && len(request.Splits) > 0 {
+		return ledger.Record{}, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, "A transfer can not be split", nil, map[string]string{
+			"splits": "a transfer can not be split",
+		})
 	}
 
-	transferReference := ledger.NoTransferReference
-	sourceAccountId := ledger.NoSourceAccount
-	var beneficiaryAccount ledger.Account
 	if ledger.RecordType(request.Type) == ledger.Transfer {
+		var (
+			beneficiaryAccount ledger.Account
+			creditId           ledger.RecordId
+			received           ledger.Money
+			rate               ledger.ExchangeRate
+			transfer           ledger.TransferPair
+		)
 
-		if amount, err = amount.Negate(); err != nil {
-			return RecordResponse{}, err
+		if beneficiaryAccount, err = svc.accountDao.GetAccountById(ctx, ledger.AccountId(request.Transfer.Beneficiary.Id), userId, tx); err != nil {
+			return ledger.Record{}, err
 		}
 
-		if beneficiaryAccount, err = svc.accountDao.GetAccountById(ctx, ledger.AccountId(request.Transfer.Beneficiary.Id), userId, tx); err != nil {
-			return RecordResponse{}, err
+		if received, rate, err = parseExchange(request.Transfer.ReceivedAmount, request.Transfer.ExchangeRate); err != nil {
+			return ledger.Record{}, err
 		}
 
-		// TODO: Check that beneficiary account has the same currency as source account.
+		if creditId, err = svc.recordDao.NewRecordId(tx); err != nil {
+			return ledger.Record{}, err
+		}
 
-		transferReference = ledger.MakeTransferReference()
-		sourceAccountId = accountId
+		if transfer, err = ledger.NewTransfer(
+			recordId,
+			creditId,
+			request.Note,
+			category,
+			amount,
+			received,
+			rate,
+			date,
+			account,
+			beneficiaryAccount,
+			updatedBy,
+		); err != nil {
+			return ledger.Record{}, err
+		}
+
+		// Debit the source account and credit the beneficiary account
+		if err = svc.recordDao.SaveTx(ctx, accountId, transfer.Debit(), tx); err != nil {
+			return ledger.Record{}, err
+		}
+		if err = svc.recordDao.SaveTx(ctx, beneficiaryAccount.Id(), transfer.Credit(), tx); err != nil {
+			return ledger.Record{}, err
+		}
+		record = transfer.Debit()
+	} else {
+		var splits ledger.RecordSplits
+		if splits, err = svc.makeSplits(ctx, userId, request.Splits, tx); err != nil {
+			return ledger.Record{}, err
+		}
+
+		if record, err = ledger.NewSplitRecord(
+			recordId,
+			request.Note,
+			category,
+			amount,
+			date,
+			ledger.RecordType(request.Type),
+			splits,
+			updatedBy,
+		); err != nil {
+			return ledger.Record{}, err
+		}
+
+		if err = svc.recordDao.SaveTx(ctx, accountId, record, tx); err != nil {
+			return ledger.Record{}, err
+		}
 	}
 
-	if date, err = time.Parse(time.RFC3339, request.DateUTC); err != nil {
-		return RecordResponse{}, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, fmt.Sprintf("Date '%s' does not match format '%s'", request.DateUTC, time.RFC3339), nil, nil)
+	// Update last used category
+	if err = svc.updateCategoriesLastUsed(ctx, record, tx); err != nil {
+		return ledger.Record{}, err
 	}
 
-	if record, err = ledger.NewRecord(
-		recordId,
-		request.Note,
-		category,
-		amount,
-		date.In(time.UTC),
-		ledger.RecordType(request.Type),
-		sourceAccountId,
-		beneficiaryAccount.Id(),
-		beneficiaryAccount.Type(),
-		transferReference,
-		ledger.MustMakeUpdatedByUserId(userId),
-	); err != nil {
+	return record, nil
+}
+
+func (svc recordService) UpdateRecord(ctx context.Context, recordId ledger.RecordId, request UpdateRecordRequest) (RecordResponse, error) {
+	patchRequest := PatchRecordRequest{
+		Note:     &request.Note,
+		Category: &request.Category,
+		Amount:   &request.Amount,
+		DateUTC:  &request.DateUTC,
+		Type:     &request.Type,
+		Splits:   &request.Splits,
+		Version:  request.Version,
+	}
+	if ledger.RecordType(request.Type) == ledger.Transfer {
+		patchRequest.Transfer = &request.Transfer
+	}
+	return svc.PatchRecord(ctx, recordId, patchRequest)
+}
+
+func (svc recordService) PatchRecord(ctx context.Context, recordId ledger.RecordId, request PatchRecordRequest) (RecordResponse, error) {
+	var (
+		userId    ledger.UserId
+		accountId ledger.AccountId
+		tx        *sql.Tx
+		err       error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return RecordResponse{}, err
+	}
+
+	if accountId, err = RequireAccountId(ctx); err != nil {
 		return RecordResponse{}, err
 	}
 
-	// Save Record(s)
-	if err = svc.recordDao.SaveTx(ctx, accountId, record, tx); err != nil {
+	if tx, err = svc.recordDao.BeginTx(); err != nil {
 		return RecordResponse{}, err
 	}
 
-	// In case of transfer, credit receiving account
-	if record.Type() == ledger.Transfer {
-		var (
-			transferId ledger.RecordId
-			transfer   ledger.Record
-			credit     ledger.Money
-		)
+	defer dao.DeferRollback(tx, fmt.Sprintf("PatchRecord: %d", userId))
+
+	var (
+		account  ledger.Account
+		record   ledger.Record
+		category ledger.Category
+		amount   ledger.Money
+		date     time.Time
+		profile  ledger.UserProfile
+	)
+
+	if _, err = svc.accountDao.GetAccountById(ctx, accountId, userId, tx); err != nil {
+		return RecordResponse{}, err
+	}
+
+	if profile, err = getUserProfileTx(ctx, svc.userDao, userId, tx); err != nil {
+		return RecordResponse{}, err
+	}
+
+	if record, err = svc.getRecordOfVersion(ctx, recordId, accountId, request.Version, tx); err != nil {
+		return RecordResponse{}, err
+	}
+
+	note := record.Note()
+	if request.Note != nil {
+		note = *request.Note
+	}
+
+	recordType := record.Type()
+	if request.Type != nil {
+		recordType = ledger.RecordType(*request.Type)
+	}
+
+	if record.Type() == ledger.Transfer && recordType != ledger.Transfer {
+		return RecordResponse{}, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, "The type of a transfer can not be changed", nil, nil)
+	}
 
-		if credit, err = amount.Abs(); err != nil {
+	if record.Type() != ledger.Transfer && recordType == ledger.Transfer {
+		return RecordResponse{}, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, "Incomes and expenses can not be changed to transfers", nil, nil)
+	}
+
+	category = record.Category()
+	if request.Category != nil && ledger.CategoryId(request.Category.Id) != category.Id() {
+		if category, err = svc.categoryDao.GetCategoryById(ctx, ledger.CategoryId(request.Category.Id), userId, tx); err != nil {
 			return RecordResponse{}, err
 		}
+	}
 
-		if transferId, err = svc.recordDao.NewRecordId(tx); err != nil {
+	amount = record.Amount()
+	if request.Amount != nil {
+		if amount, err = ledger.NewMoney(request.Amount.Currency, request.Amount.Value); err != nil {
 			return RecordResponse{}, err
 		}
+	}
+
+	// The date of the record is kept unless the moment at which it occurred is changed
+	date = record.OccurredAtUTC()
+	if request.DateUTC != nil {
+		if date, err = time.Parse(time.RFC3339, *request.DateUTC); err != nil {
+			return RecordResponse{}, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, fmt.Sprintf("Date '%s' does not match format '%s'", *request.DateUTC, time.RFC3339), nil, nil)
+		}
+		date = date.In(profile.Location())
+	}
 
-		if transfer, err = ledger.NewRecord(
-			transferId,
-			record.Note(),
+	if record.Type() == ledger.Transfer {
+		if request.Splits != nil && len(*request.Splits) > 0 {
+			return RecordResponse{}, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, "A transfer can not be split", nil, map[string]string{
+				"splits": "a transfer can not be split",
+			})
+		}
+		if record, err = svc.updateTransferTx(ctx, userId, record, note, category, amount, date, request, tx); err != nil {
+			return RecordResponse{}, err
+		}
+	} else {
+		splits := record.Splits()
+		if request.Splits != nil {
+			if splits, err = svc.makeSplits(ctx, userId, *request.Splits, tx); err != nil {
+				return RecordResponse{}, err
+			}
+		}
+
+		if record, err = record.Edit(
+			note,
 			category,
-			credit,
-			date.In(time.UTC),
-			ledger.RecordType(request.Type),
-			sourceAccountId,
-			beneficiaryAccount.Id(),
-			beneficiaryAccount.Type(),
-			transferReference,
+			amount,
+			date,
+			recordType,
+			splits,
 			ledger.MustMakeUpdatedByUserId(userId),
 		); err != nil {
 			return RecordResponse{}, err
 		}
 
-		if err = svc.recordDao.SaveTx(ctx, ledger.AccountId(request.Transfer.Beneficiary.Id), transfer, tx); err != nil {
+		if err = svc.recordDao.UpdateTx(ctx, accountId, record, tx); err != nil {
+			return RecordResponse{}, err
+		}
+
+		// Reload the record to get the version assigned by the database
+		if record, err = svc.recordDao.GetRecordByIdTx(ctx, recordId, accountId, tx); err != nil {
 			return RecordResponse{}, err
 		}
 	}
 
-	// Update last used category
-	if err = svc.categoryDao.UpdateCategoryLastUsed(ctx, category.Id(), date.In(time.UTC), tx); err != nil {
-		return RecordResponse{}, err
+	if request.Category != nil || request.Splits != nil {
+		if err = svc.updateCategoriesLastUsed(ctx, record, tx); err != nil {
+			return RecordResponse{}, err
+		}
 	}
 
-	// Get account balance
 	if account, err = svc.accountDao.GetAccountById(ctx, accountId, userId, tx); err != nil {
 		return RecordResponse{}, err
 	}
@@ -353,7 +794,241 @@ func (svc recordService) CreateRecord(ctx context.Context, request CreateRecordR
 		return RecordResponse{}, err
 	}
 
-	return makeRecordResponse(record, account)
+	return makeRecordResponse(record, account, profile)
+}
+
+func (svc recordService) DeleteRecord(ctx context.Context, recordId ledger.RecordId, request DeleteRecordRequest) error {
+	var (
+		userId    ledger.UserId
+		accountId ledger.AccountId
+		tx        *sql.Tx
+		record    ledger.Record
+		err       error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return err
+	}
+
+	if accountId, err = RequireAccountId(ctx); err != nil {
+		return err
+	}
+
+	if tx, err = svc.recordDao.BeginTx(); err != nil {
+		return err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("DeleteRecord: %d", userId))
+
+	if _, err = svc.accountDao.GetAccountById(ctx, accountId, userId, tx); err != nil {
+		return err
+	}
+
+	if record, err = svc.getRecordOfVersion(ctx, recordId, accountId, request.Version, tx); err != nil {
+		return err
+	}
+
+	if record.Type() == ledger.Transfer {
+		var transfer ledger.TransferPair
+		if transfer, err = svc.recordDao.GetTransferPairTx(ctx, record.TransferReference(), userId, tx); err != nil {
+			return err
+		}
+		if err = svc.recordDao.DeleteTransferPairTx(ctx, transfer, tx); err != nil {
+			return err
+		}
+	} else if err = svc.recordDao.DeleteTx(ctx, accountId, recordId, record.Version(), tx); err != nil {
+		return err
+	}
+
+	return dao.Commit(tx)
+}
+
+// updateTransferTx applies the changes made to one record of a transfer to both records of the transfer.
+// The updated record is returned.
+func (svc recordService) updateTransferTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	record ledger.Record,
+	note string,
+	category ledger.Category,
+	amount ledger.Money,
+	date time.Time,
+	request PatchRecordRequest,
+	tx *sql.Tx,
+) (ledger.Record, error) {
+	var (
+		transfer    ledger.TransferPair
+		beneficiary ledger.Account
+		received    ledger.Money
+		rate        ledger.ExchangeRate
+		err         error
+	)
+
+	if transfer, err = svc.recordDao.GetTransferPairTx(ctx, record.TransferReference(), userId, tx); err != nil {
+		return ledger.Record{}, err
+	}
+
+	// The amount of a transfer is the amount sent, which is in the currency of the source account.
+	// When the credit is patched, its amount is the amount received, which is in the currency of the beneficiary account.
+	patchingCredit := transfer.Credit().Id() == record.Id()
+	requestedAmount := amount
+	if request.Amount == nil || patchingCredit {
+		amount = transfer.Debit().Amount()
+	}
+
+	beneficiaryId := transfer.BeneficiaryId()
+	if request.Transfer != nil {
+		beneficiaryId = ledger.AccountId(request.Transfer.Beneficiary.Id)
+		if received, rate, err = parseExchange(request.Transfer.ReceivedAmount, request.Transfer.ExchangeRate); err != nil {
+			return ledger.Record{}, err
+		}
+	}
+
+	if patchingCredit && request.Amount != nil && received == nil && len(rate) == 0 {
+		if transfer.ExchangeRate() == ledger.NoExchangeRate {
+			amount = requestedAmount
+		} else {
+			// The amount sent is kept, and the exchange rate is calculated from the new amount received
+			received = requestedAmount
+		}
+	}
+	// Otherwise, neither a received amount nor an exchange rate is given, and the transfer keeps its exchange rate.
+	// The amount received is also kept, unless the amount sent is changed.
+
+	if beneficiary, err = svc.accountDao.GetAccountById(ctx, beneficiaryId, userId, tx); err != nil {
+		return ledger.Record{}, err
+	}
+
+	if transfer, err = transfer.Edit(
+		note,
+		category,
+		amount,
+		received,
+		rate,
+		date,
+		beneficiary,
+		ledger.MustMakeUpdatedByUserId(userId),
+	); err != nil {
+		return ledger.Record{}, err
+	}
+
+	if err = svc.recordDao.UpdateTransferPairTx(ctx, transfer, tx); err != nil {
+		return ledger.Record{}, err
+	}
+
+	// Reload the transfer to get the versions assigned by the database
+	if transfer, err = svc.recordDao.GetTransferPairTx(ctx, record.TransferReference(), userId, tx); err != nil {
+		return ledger.Record{}, err
+	}
+
+	if transfer.Debit().Id() == record.Id() {
+		return transfer.Debit(), nil
+	}
+	return transfer.Credit(), nil
+}
+
+// makeSplits loads the categories of the lines of a split record. No splits are returned when no lines are requested.
+func (svc recordService) makeSplits(ctx context.Context, userId ledger.UserId, requests []SplitRequest, tx *sql.Tx) (ledger.RecordSplits, error) {
+	if len(requests) == 0 {
+		return ledger.NoSplits, nil
+	}
+
+	categories := map[ledger.CategoryId]ledger.Category{}
+	splits := make(ledger.RecordSplits, 0, len(requests))
+	for _, request := range requests {
+		var (
+			category ledger.Category
+			amount   ledger.Money
+			split    ledger.RecordSplit
+			ok       bool
+			err      error
+		)
+
+		categoryId := ledger.CategoryId(request.Category.Id)
+		if category, ok = categories[categoryId]; !ok {
+			if category, err = svc.categoryDao.GetCategoryById(ctx, categoryId, userId, tx); err != nil {
+				return ledger.NoSplits, err
+			}
+			categories[categoryId] = category
+		}
+
+		if amount, err = ledger.NewMoney(request.Amount.Currency, request.Amount.Value); err != nil {
+			return ledger.NoSplits, err
+		}
+
+		if split, err = ledger.NewRecordSplit(request.Note, category, amount); err != nil {
+			return ledger.NoSplits, err
+		}
+		splits = append(splits, split)
+	}
+	return splits, nil
+}
+
+// updateCategoriesLastUsed records that the categories of the record, including the categories of its lines, were last used on the date of the record.
+func (svc recordService) updateCategoriesLastUsed(ctx context.Context, record ledger.Record, tx *sql.Tx) error {
+	categoryIds := []ledger.CategoryId{record.Category().Id()}
+	for _, split := range record.Splits() {
+		categoryIds = append(categoryIds, split.Category().Id())
+	}
+
+	updated := map[ledger.CategoryId]bool{}
+	for _, categoryId := range categoryIds {
+		if updated[categoryId] {
+			continue
+		}
+		if err := svc.categoryDao.UpdateCategoryLastUsed(ctx, categoryId, record.DateUTC(), tx); err != nil {
+			return err
+		}
+		updated[categoryId] = true
+	}
+	return nil
+}
+
+// parseExchange parses the amount received and the exchange rate of a transfer, either of which is optional.
+func parseExchange(receivedAmount *AmountRequest, exchangeRate string) (ledger.Money, ledger.ExchangeRate, error) {
+	var (
+		received ledger.Money
+		rate     ledger.ExchangeRate
+		err      error
+	)
+
+	if receivedAmount != nil {
+		if received, err = ledger.NewMoney(receivedAmount.Currency, receivedAmount.Value); err != nil {
+			return nil, ledger.NoExchangeRate, err
+		}
+	}
+
+	if len(exchangeRate) > 0 {
+		if rate, err = ledger.ParseExchangeRate(exchangeRate); err != nil {
+			return nil, ledger.NoExchangeRate, err
+		}
+	}
+
+	return received, rate, nil
+}
+
+// getRecordOfVersion loads a record and checks that it has not changed since the client loaded the given version.
+func (svc recordService) getRecordOfVersion(ctx context.Context, recordId ledger.RecordId, accountId ledger.AccountId, version uint64, tx *sql.Tx) (ledger.Record, error) {
+	if version == 0 {
+		return ledger.Record{}, pkg.ValidationErrorWithFields(pkg.ErrRecordValidation, "The version of the record is required", nil, map[string]string{
+			"version": "version must be provided in the request body or in the If-Match header",
+		})
+	}
+
+	record, err := svc.recordDao.GetRecordByIdTx(ctx, recordId, accountId, tx)
+	if err != nil {
+		return ledger.Record{}, err
+	}
+
+	if record.Version() != ledger.Version(version) {
+		return ledger.Record{}, pkg.ValidationErrorWithFields(
+			pkg.ErrRecordVersionConflict,
+			fmt.Sprintf("Record %d has been changed. Expected version %d but found version %d", recordId, version, record.Version()),
+			nil,
+			nil,
+		)
+	}
+	return record, nil
 }
 
 func (svc recordService) CreateRecordRequestWithChatGPT(ctx context.Context, prompt CreateRecordPrompt) (CreateRecordRequest, error) {
@@ -389,6 +1064,17 @@ func (svc recordService) CreateRecordRequestWithChatGPT(ctx context.Context, pro
 		return CreateRecordRequest{}, err
 	}
 
+	user, err := svc.userDao.GetUserByIdTx(ctx, userId, tx)
+	if err != nil {
+		return CreateRecordRequest{}, err
+	}
+
+	// The default category of the user is suggested when the prompt does not describe a category
+	defaultCategory := ""
+	if category, ok := categories.MapById()[user.Preferences().DefaultCategoryId()]; ok {
+		defaultCategory = fmt.Sprintf(" Default: '%s'.", category.Name())
+	}
+
 	jsonStructure, err := json.Marshal(CreateRecordRequest{})
 	if err != nil {
 		return CreateRecordRequest{}, fmt.Errorf("Failed to marshal empty create record request")
@@ -404,9 +1090,9 @@ func (svc recordService) CreateRecordRequestWithChatGPT(ctx context.Context, pro
 		
 		Details:
 		- Note: Required. this is what the money was spent on e.g. McDonalds
-		- Category: Required. the category of the transaction. One of: '%s'.
+		- Category: Required. the category of the transaction. One of: '%s'.%s
 		- Amount: Required. the value of the transaction. For expenses, this must be negative. By default the currency is: '%s'.
-		- Date: Required. The date of the transaction formatted as yyyy-MM-dd'T'HH:mm:ssX in UTC timezone. Default: '%s'.
+		- Date: Required. The date of the transaction formatted as yyyy-MM-dd'T'HH:mm:ssXXX in the '%s' timezone. Default: '%s'.
 		- Type: Required. One of INCOME, EXPENSE or TRANSFER. Default: EXPENSE.
 		- Transfer.Beneficiary.Id: Remove transfer field if type is not TRANSFER. The id of the account the money is being transferred to. Available accounts are: '%s'
 		
@@ -417,8 +1103,10 @@ func (svc recordService) CreateRecordRequestWithChatGPT(ctx context.Context, pro
 		string(jsonStructure),
 		prompt.Prompt,
 		categories.String(),
-		accounts[0].Currency(),
-		time.Now().UTC().Format("2006-01-02T15:04:05.999Z"),
+		defaultCategory,
+		defaultCurrency(user.Preferences(), accounts),
+		user.Profile().Timezone(),
+		user.Profile().FormatTime(time.Now()),
 		accounts.String(),
 	)
 
@@ -447,7 +1135,24 @@ func (svc recordService) CreateRecordRequestWithChatGPT(ctx context.Context, pro
 	return populatedRequest, nil
 }
 
-func (svc recordService) GetRecords(ctx context.Context, accountId ledger.AccountId) (RecordsResponse, error) {
+// defaultCurrency is the currency of amounts whose currency is not known: the home currency of the user,
+// otherwise the currency of the default account of the user or, failing that, of the first account of the user.
+func defaultCurrency(preferences ledger.UserPreferences, accounts ledger.Accounts) string {
+	if len(preferences.Currency()) > 0 {
+		return preferences.Currency()
+	}
+	for _, account := range accounts {
+		if account.Id() == preferences.DefaultAccountId() {
+			return account.Currency()
+		}
+	}
+	if len(accounts) > 0 {
+		return accounts[0].Currency()
+	}
+	return ""
+}
+
+func (svc recordService) GetRecords(ctx context.Context, accountId ledger.AccountId, page PageRequest) (RecordsResponse, error) {
 
 	userId, err := RequireUserId(ctx)
 	if err != nil {
@@ -465,10 +1170,337 @@ func (svc recordService) GetRecords(ctx context.Context, accountId ledger.Accoun
 		return RecordsResponse{}, err
 	}
 
-	records, err := svc.recordDao.GetRecordsForLastPeriod(ctx, accountId, tx)
+	profile, err := getUserProfileTx(ctx, svc.userDao, userId, tx)
+	if err != nil {
+		return RecordsResponse{}, err
+	}
+
+	period, err := svc.recordDao.GetLastPeriod(ctx, accountId, profile, tx)
+	if err != nil {
+		return RecordsResponse{}, err
+	}
+
+	fromDate, toDate := period.FirstDay(), period.LastDay()
+	search := dao.RecordSearch{FromDate: &fromDate, ToDate: &toDate, Profile: profile}
+
+	// The cursor is checked against the period, so the page request is applied once the period is known
+	invalidFields := map[string]string{}
+	limit := applyPageRequest(&search, page, invalidFields)
+	if len(invalidFields) != 0 {
+		return RecordsResponse{}, pkg.ValidationErrorWithFields(pkg.ErrRecordSearchValidation, "Invalid search parameters", nil, invalidFields)
+	}
+
+	return svc.searchPage(accountId, search, limit, profile)
+}
+
+func (svc recordService) SearchRecords(ctx context.Context, accountId ledger.AccountId, request SearchRecordsRequest) (RecordsResponse, error) {
+
+	userId, err := RequireUserId(ctx)
+	if err != nil {
+		return RecordsResponse{}, err
+	}
+
+	tx, err := svc.recordDao.BeginTx()
+	if err != nil {
+		return RecordsResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("SearchRecords: %d", userId))
+
+	if _, err = svc.accountDao.GetAccountById(ctx, accountId, userId, tx); err != nil {
+		return RecordsResponse{}, err
+	}
+
+	profile, err := getUserProfileTx(ctx, svc.userDao, userId, tx)
+	if err != nil {
+		return RecordsResponse{}, err
+	}
+
+	search, limit, err := makeRecordSearch(request, profile, time.Now())
+	if err != nil {
+		return RecordsResponse{}, err
+	}
+
+	response, err := svc.searchPage(accountId, search, limit, profile)
 	if err != nil {
 		return RecordsResponse{}, err
 	}
 
-	return makeRecordsResponse(records)
+	response.SearchParameters.From = *search.FromDate
+	response.SearchParameters.To = *search.ToDate
+	return response, nil
+}
+
+// searchPage loads a page of at most limit records, along with the totals of all the records that match the search.
+func (svc recordService) searchPage(accountId ledger.AccountId, search dao.RecordSearch, limit uint, profile ledger.UserProfile) (RecordsResponse, error) {
+	summary, err := svc.recordDao.Summarize(accountId, search)
+	if err != nil {
+		return RecordsResponse{}, err
+	}
+
+	// One more record than requested is loaded to know whether there is another page.
+	search.Limit = limit + 1
+	records, err := svc.recordDao.Search(accountId, search)
+	if err != nil {
+		return RecordsResponse{}, err
+	}
+
+	pagingBackwards := search.Before != nil
+	hasMore := uint(len(records)) > limit
+	if hasMore {
+		if pagingBackwards {
+			records = removeRecordAt(records, newestRecordIndex(records))
+		} else {
+			records = removeRecordAt(records, oldestRecordIndex(records))
+		}
+	}
+
+	response, err := makeRecordsResponse(records, summary, profile)
+	if err != nil {
+		return RecordsResponse{}, err
+	}
+
+	if len(records) == 0 {
+		return response, nil
+	}
+
+	oldest := records[oldestRecordIndex(records)]
+	newest := records[newestRecordIndex(records)]
+
+	if hasMore || pagingBackwards {
+		response.NextCursor = encodeRecordCursor(afterCursor, oldest, search)
+	}
+	if (hasMore && pagingBackwards) || search.After != nil {
+		response.PrevCursor = encodeRecordCursor(beforeCursor, newest, search)
+	}
+	return response, nil
+}
+
+// makeRecordSearch makes the search of a request. Dates are read as days in the timezone of the profile,
+// and the search defaults to the records from the start of the current month until today.
+func makeRecordSearch(request SearchRecordsRequest, profile ledger.UserProfile, now time.Time) (dao.RecordSearch, uint, error) {
+	invalidFields := map[string]string{}
+
+	parseDate := func(field string, value string) *time.Time {
+		if len(value) == 0 {
+			return nil
+		}
+		if date, err := time.Parse("2006-01-02", value); err == nil {
+			return &date
+		}
+		if date, err := time.Parse(time.RFC3339, value); err == nil {
+			localDate := profile.LocalDate(date)
+			return &localDate
+		}
+		invalidFields[field] = fmt.Sprintf("%s '%s' must be formatted as yyyy-MM-dd or %s", field, value, time.RFC3339)
+		return nil
+	}
+
+	search := dao.RecordSearch{
+		SearchTerm: strings.TrimSpace(request.SearchTerm),
+		FromDate:   parseDate("from", request.From),
+		ToDate:     parseDate("to", request.To),
+		Profile:    profile,
+	}
+
+	if len(request.Period) != 0 {
+		if search.FromDate != nil || search.ToDate != nil {
+			invalidFields["period"] = "period can not be combined with from or to"
+		} else if period, err := profile.ParsePeriod(request.Period); err != nil {
+			invalidFields["period"] = fmt.Sprintf("period '%s' must be a day, week, month, quarter, year or range of days e.g. 2021-07-05, 2021-W27, 2021-07, 2021-Q3, 2021 or 2021-07-01/2021-07-15", request.Period)
+		} else {
+			fromDate, toDate := period.FirstDay(), period.LastDay()
+			search.FromDate, search.ToDate = &fromDate, &toDate
+		}
+	}
+
+	if search.FromDate == nil {
+		defaultFromDate := profile.CurrentMonth(now).FirstDay()
+		search.FromDate = &defaultFromDate
+	}
+
+	if search.ToDate == nil {
+		defaultToDate := profile.LocalDate(now)
+		search.ToDate = &defaultToDate
+	}
+
+	if search.ToDate.Before(*search.FromDate) {
+		invalidFields["to"] = "to must not be before from"
+	}
+
+	for _, recordType := range request.RecordTypes {
+		switch ledger.RecordType(strings.ToUpper(recordType)) {
+		case ledger.Income, ledger.Expense, ledger.Transfer:
+			search.RecordTypes = append(search.RecordTypes, ledger.RecordType(strings.ToUpper(recordType)))
+		default:
+			invalidFields["type"] = fmt.Sprintf("type '%s' must be one of INCOME, EXPENSE or TRANSFER", recordType)
+		}
+	}
+
+	// Category and account names are saved in title case
+	for _, name := range request.CategoryNames {
+		if name = strings.TrimSpace(name); len(name) != 0 {
+			search.CategoryNames = append(search.CategoryNames, ledger.NormalizeName(name))
+		}
+	}
+
+	for _, name := range request.BeneficiaryNames {
+		if name = strings.TrimSpace(name); len(name) != 0 {
+			search.BeneficiaryAccountNames = append(search.BeneficiaryAccountNames, ledger.NormalizeName(name))
+		}
+	}
+
+	for _, createdBy := range request.CreatedByKinds {
+		kind, err := ledger.ParseUpdatedByKind(createdBy)
+		if err != nil {
+			invalidFields["createdBy"] = fmt.Sprintf("createdBy '%s' must be one of USER, TASK, IMPORT or SYSTEM", createdBy)
+			continue
+		}
+		search.CreatedByKinds = append(search.CreatedByKinds, kind)
+	}
+
+	limit := applyPageRequest(&search, request.PageRequest, invalidFields)
+
+	if len(invalidFields) != 0 {
+		return dao.RecordSearch{}, 0, pkg.ValidationErrorWithFields(pkg.ErrRecordSearchValidation, "Invalid search parameters", nil, invalidFields)
+	}
+
+	return search, limit, nil
+}
+
+// applyPageRequest sets the cursor of the search and returns the page size.
+// The filters of the search must already be set, since a cursor is only valid for the search that it was issued for.
+// Invalid parameters are reported in invalidFields.
+func applyPageRequest(search *dao.RecordSearch, page PageRequest, invalidFields map[string]string) uint {
+	limit := uint(defaultRecordsPageSize)
+	if len(page.Limit) != 0 {
+		if value, err := strconv.ParseUint(page.Limit, 10, 32); err != nil || value == 0 || value > maxRecordsPageSize {
+			invalidFields["limit"] = fmt.Sprintf("limit '%s' must be a number between 1 and %d", page.Limit, maxRecordsPageSize)
+		} else {
+			limit = uint(value)
+		}
+	}
+
+	if len(page.Cursor) != 0 {
+		direction, cursor, fingerprint, err := decodeRecordCursor(page.Cursor)
+		if err != nil {
+			invalidFields["cursor"] = fmt.Sprintf("cursor '%s' is invalid", page.Cursor)
+		} else if fingerprint != recordSearchFingerprint(*search) {
+			invalidFields["cursor"] = fmt.Sprintf("cursor '%s' was issued for different search parameters", page.Cursor)
+		} else if direction == afterCursor {
+			search.After = &cursor
+		} else {
+			search.Before = &cursor
+		}
+	}
+	return limit
+}
+
+const (
+	afterCursor  = "after"
+	beforeCursor = "before"
+)
+
+// encodeRecordCursor returns an opaque cursor for the position of the given record in the results of the search.
+// The direction determines whether the records after (older) or before (newer) the record are requested.
+func encodeRecordCursor(direction string, record ledger.Record, search dao.RecordSearch) string {
+	value := fmt.Sprintf("%s|%s|%d|%s", direction, record.DateUTC().Format("2006-01-02"), record.Id(), recordSearchFingerprint(search))
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func decodeRecordCursor(encoded string) (string, dao.RecordCursor, string, error) {
+	var (
+		value []byte
+		date  time.Time
+		id    uint64
+		err   error
+	)
+
+	if value, err = base64.RawURLEncoding.DecodeString(encoded); err != nil {
+		return "", dao.RecordCursor{}, "", err
+	}
+
+	parts := strings.Split(string(value), "|")
+	if len(parts) != 4 || (parts[0] != afterCursor && parts[0] != beforeCursor) {
+		return "", dao.RecordCursor{}, "", fmt.Errorf("malformed cursor %q", value)
+	}
+
+	if date, err = time.Parse("2006-01-02", parts[1]); err != nil {
+		return "", dao.RecordCursor{}, "", err
+	}
+
+	if id, err = strconv.ParseUint(parts[2], 10, 64); err != nil {
+		return "", dao.RecordCursor{}, "", err
+	}
+
+	return parts[0], dao.RecordCursor{Date: date, Id: ledger.RecordId(id)}, parts[3], nil
+}
+
+// recordSearchFingerprint is a hash of the filters of a search. The cursor, limit and the order of the values of a filter are ignored.
+func recordSearchFingerprint(search dao.RecordSearch) string {
+	formatDate := func(date *time.Time) string {
+		if date == nil {
+			return ""
+		}
+		return date.Format("2006-01-02")
+	}
+	sortedValues := func(values []string) string {
+		sorted := append([]string{}, values...)
+		sort.Strings(sorted)
+		return strings.Join(sorted, ",")
+	}
+
+	recordTypes := make([]string, 0, len(search.RecordTypes))
+	for _, recordType := range search.RecordTypes {
+		recordTypes = append(recordTypes, string(recordType))
+	}
+
+	createdByKinds := make([]string, 0, len(search.CreatedByKinds))
+	for _, kind := range search.CreatedByKinds {
+		createdByKinds = append(createdByKinds, string(kind))
+	}
+
+	filters, _ := json.Marshal([]string{
+		search.SearchTerm,
+		formatDate(search.FromDate),
+		formatDate(search.ToDate),
+		sortedValues(search.CategoryNames),
+		sortedValues(recordTypes),
+		sortedValues(search.BeneficiaryAccountNames),
+		sortedValues(createdByKinds),
+	})
+	hash := sha256.Sum256(filters)
+	return hex.EncodeToString(hash[:8])
+}
+
+// isOlderRecord reports whether a comes before b when records are ordered by date and then by id.
+func isOlderRecord(a ledger.Record, b ledger.Record) bool {
+	if a.DateUTC().Equal(b.DateUTC()) {
+		return a.Id() < b.Id()
+	}
+	return a.DateUTC().Before(b.DateUTC())
+}
+
+func oldestRecordIndex(records ledger.Records) int {
+	oldest := 0
+	for i := range records {
+		if isOlderRecord(records[i], records[oldest]) {
+			oldest = i
+		}
+	}
+	return oldest
+}
+
+func newestRecordIndex(records ledger.Records) int {
+	newest := 0
+	for i := range records {
+		if isOlderRecord(records[newest], records[i]) {
+			newest = i
+		}
+	}
+	return newest
+}
+
+func removeRecordAt(records ledger.Records, index int) ledger.Records {
+	return append(records[:index:index], records[index+1:]...)
 }
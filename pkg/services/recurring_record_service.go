@@ -0,0 +1,771 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+// RecurringRecordsTaskName is the name of the scheduled task that creates the records of recurring records.
+// Records created by the task are created by "CronTask: RecurringRecords".
+const RecurringRecordsTaskName = "RecurringRecords"
+
+const recurringRecordDateFormat = "2006-01-02"
+
+// CreateRecurringRecordRequest is the template of the records that are created for each occurrence of a recurring record.
+// Dates are provided as yyyy-MM-dd. The end date is optional.
+type CreateRecurringRecordRequest struct {
+	Note     string `json:"note"`
+	Category struct {
+		Id uint64 `json:"id"`
+	} `json:"category"`
+	Amount   AmountRequest `json:"amount"`
+	Type     string        `json:"type"`
+	Transfer struct {
+		Beneficiary struct {
+			Id uint64 `json:"id"`
+		} `json:"beneficiary"`
+		ExchangeRate string `json:"exchangeRate,omitempty"`
+	} `json:"transfer,omitempty"`
+	Recurrence struct {
+		Frequency string `json:"frequency"`
+		// DayOfMonth is only provided when the frequency is MONTHLY
+		DayOfMonth int `json:"dayOfMonth,omitempty"`
+	} `json:"recurrence"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate,omitempty"`
+}
+
+// UpdateRecurringRecordRequest replaces the details of a recurring record.
+// Version is the version of the recurring record the client last saw; it can also be provided with the If-Match header.
+type UpdateRecurringRecordRequest struct {
+	CreateRecurringRecordRequest
+	Version uint64 `json:"version"`
+}
+
+// RecurringRecordVersionRequest is used to delete, pause, resume or skip a recurring record.
+// Version is the version of the recurring record the client last saw; it can also be provided with the If-Match header.
+type RecurringRecordVersionRequest struct {
+	Version uint64 `json:"version"`
+}
+
+type RecurringRecordResponse struct {
+	Id        uint64 `json:"id"`
+	AccountId uint64 `json:"accountId"`
+	Note      string `json:"note"`
+	Category  struct {
+		Id   uint64 `json:"id"`
+		Name string `json:"name"`
+	} `json:"category"`
+	Amount AmountResponse `json:"amount"`
+	Type   string         `json:"type"`
+
+	// Transfer is only set when record type is transfer
+	Transfer *TransferResponse `json:"transfer,omitempty"`
+
+	Recurrence struct {
+		Frequency  string `json:"frequency"`
+		DayOfMonth int    `json:"dayOfMonth,omitempty"`
+	} `json:"recurrence"`
+	StartDate string `json:"startDate"`
+	// EndDate is only set when the recurring record has an end date
+	EndDate string `json:"endDate,omitempty"`
+	// NextDate is not set once the recurring record has ended
+	NextDate string `json:"nextDate,omitempty"`
+	Paused   bool   `json:"paused"`
+	Version  uint64 `json:"version"`
+}
+
+type RecurringRecordsResponse struct {
+	RecurringRecords []RecurringRecordResponse `json:"recurringRecords"`
+}
+
+func makeRecurringRecordResponse(rr ledger.RecurringRecord) RecurringRecordResponse {
+	amountValue, _ := rr.Amount().MinorUnits()
+
+	resp := RecurringRecordResponse{}
+	resp.Id = uint64(rr.Id())
+	resp.AccountId = uint64(rr.AccountId())
+	resp.Note = rr.Note()
+	resp.Category.Id = uint64(rr.Category().Id())
+	resp.Category.Name = rr.Category().Name()
+	resp.Amount.Currency = rr.Amount().Currency().CurrencyCode()
+	resp.Amount.Value = amountValue
+	resp.Type = string(rr.RecordType())
+	resp.Recurrence.Frequency = string(rr.Recurrence().Frequency())
+	resp.Recurrence.DayOfMonth = rr.Recurrence().DayOfMonth()
+	resp.StartDate = rr.StartDate().Format(recurringRecordDateFormat)
+	resp.Paused = rr.IsPaused()
+	resp.Version = uint64(rr.Version())
+
+	if !rr.EndDate().IsZero() {
+		resp.EndDate = rr.EndDate().Format(recurringRecordDateFormat)
+	}
+	if !rr.HasEnded() {
+		resp.NextDate = rr.NextDate().Format(recurringRecordDateFormat)
+	}
+
+	if rr.RecordType() == ledger.Transfer {
+		resp.Transfer = new(TransferResponse)
+		resp.Transfer.Beneficiary.Id = uint64(rr.BeneficiaryId())
+		resp.Transfer.ExchangeRate = rr.ExchangeRate().String()
+	}
+	return resp
+}
+
+type RecurringRecordService interface {
+	CreateRecurringRecord(ctx context.Context, request CreateRecurringRecordRequest) (RecurringRecordResponse, error)
+	GetRecurringRecord(ctx context.Context, id ledger.RecurringRecordId) (RecurringRecordResponse, error)
+	GetRecurringRecords(ctx context.Context) (RecurringRecordsResponse, error)
+	UpdateRecurringRecord(ctx context.Context, id ledger.RecurringRecordId, request UpdateRecurringRecordRequest) (RecurringRecordResponse, error)
+	DeleteRecurringRecord(ctx context.Context, id ledger.RecurringRecordId, request RecurringRecordVersionRequest) error
+	PauseRecurringRecord(ctx context.Context, id ledger.RecurringRecordId, request RecurringRecordVersionRequest) (RecurringRecordResponse, error)
+	ResumeRecurringRecord(ctx context.Context, id ledger.RecurringRecordId, request RecurringRecordVersionRequest) (RecurringRecordResponse, error)
+	SkipNextOccurrence(ctx context.Context, id ledger.RecurringRecordId, request RecurringRecordVersionRequest) (RecurringRecordResponse, error)
+
+	// CreateDueRecords creates a record for every occurrence of a recurring record up to and including today, in the timezone of its user.
+	// A record is created at most once for each occurrence, so it is safe to call concurrently and repeatedly.
+	// The number of records created is returned.
+	CreateDueRecords(ctx context.Context, now time.Time) (int, error)
+}
+
+type recurringRecordService struct {
+	recurringRecordDao dao.RecurringRecordDao
+	recordDao          dao.RecordDao
+	categoryDao        dao.CategoryDao
+	records            recordService
+}
+
+func NewRecurringRecordService(
+	recurringRecordDao dao.RecurringRecordDao,
+	recordDao dao.RecordDao,
+	accountDao dao.AccountDao,
+	categoryDao dao.CategoryDao,
+) (RecurringRecordService, error) {
+	if recurringRecordDao == nil {
+		return nil, fmt.Errorf("can not create recurring record service. recurringRecordDao is nil")
+	}
+	if recordDao == nil {
+		return nil, fmt.Errorf("can not create recurring record service. recordDao is nil")
+	}
+	if accountDao == nil {
+		return nil, fmt.Errorf("can not create recurring record service. accountDao is nil")
+	}
+	if categoryDao == nil {
+		return nil, fmt.Errorf("can not create recurring record service. categoryDao is nil")
+	}
+
+	return &recurringRecordService{
+		recurringRecordDao: recurringRecordDao,
+		recordDao:          recordDao,
+		categoryDao:        categoryDao,
+		records: recordService{
+			recordDao:   recordDao,
+			accountDao:  accountDao,
+			categoryDao: categoryDao,
+		},
+	}, nil
+}
+
+func (svc recurringRecordService) CreateRecurringRecord(ctx context.Context, request CreateRecurringRecordRequest) (RecurringRecordResponse, error) {
+	var (
+		userId    ledger.UserId
+		accountId ledger.AccountId
+		tx        *sql.Tx
+		err       error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if accountId, err = RequireAccountId(ctx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if tx, err = svc.recurringRecordDao.BeginTx(); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("CreateRecurringRecord: %d", userId))
+
+	var (
+		id        ledger.RecurringRecordId
+		template  recurringRecordTemplate
+		recurring ledger.RecurringRecord
+	)
+
+	if template, err = svc.parseTemplate(ctx, userId, request, tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if id, err = svc.recurringRecordDao.NewRecurringRecordId(tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if recurring, err = ledger.NewRecurringRecord(
+		id,
+		accountId,
+		request.Note,
+		template.category,
+		template.amount,
+		ledger.RecordType(request.Type),
+		template.beneficiaryId,
+		template.exchangeRate,
+		template.recurrence,
+		template.startDate,
+		template.endDate,
+		ledger.MustMakeUpdatedByUserId(userId),
+	); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if err = svc.recurringRecordDao.SaveTx(ctx, userId, recurring, tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	return makeRecurringRecordResponse(recurring), nil
+}
+
+func (svc recurringRecordService) GetRecurringRecord(ctx context.Context, id ledger.RecurringRecordId) (RecurringRecordResponse, error) {
+	var (
+		userId    ledger.UserId
+		accountId ledger.AccountId
+		tx        *sql.Tx
+		recurring ledger.RecurringRecord
+		err       error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if accountId, err = RequireAccountId(ctx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if tx, err = svc.recurringRecordDao.BeginTx(); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("GetRecurringRecord: %d", userId))
+
+	if recurring, err = svc.getRecurringRecordOfAccount(ctx, id, accountId, userId, tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	return makeRecurringRecordResponse(recurring), nil
+}
+
+func (svc recurringRecordService) GetRecurringRecords(ctx context.Context) (RecurringRecordsResponse, error) {
+	var (
+		userId           ledger.UserId
+		accountId        ledger.AccountId
+		tx               *sql.Tx
+		recurringRecords ledger.RecurringRecords
+		err              error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return RecurringRecordsResponse{}, err
+	}
+
+	if accountId, err = RequireAccountId(ctx); err != nil {
+		return RecurringRecordsResponse{}, err
+	}
+
+	if tx, err = svc.recurringRecordDao.BeginTx(); err != nil {
+		return RecurringRecordsResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("GetRecurringRecords: %d", userId))
+
+	if recurringRecords, err = svc.recurringRecordDao.GetRecurringRecordsForAccount(ctx, accountId, userId, tx); err != nil {
+		return RecurringRecordsResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return RecurringRecordsResponse{}, err
+	}
+
+	resp := RecurringRecordsResponse{
+		RecurringRecords: make([]RecurringRecordResponse, 0, len(recurringRecords)),
+	}
+	for _, recurring := range recurringRecords {
+		resp.RecurringRecords = append(resp.RecurringRecords, makeRecurringRecordResponse(recurring))
+	}
+	return resp, nil
+}
+
+func (svc recurringRecordService) UpdateRecurringRecord(ctx context.Context, id ledger.RecurringRecordId, request UpdateRecurringRecordRequest) (RecurringRecordResponse, error) {
+	var (
+		userId    ledger.UserId
+		accountId ledger.AccountId
+		tx        *sql.Tx
+		err       error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if accountId, err = RequireAccountId(ctx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if tx, err = svc.recurringRecordDao.BeginTx(); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("UpdateRecurringRecord: %d", userId))
+
+	var (
+		template  recurringRecordTemplate
+		recurring ledger.RecurringRecord
+	)
+
+	if recurring, err = svc.getRecurringRecordOfVersion(ctx, id, accountId, userId, request.Version, tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if template, err = svc.parseTemplate(ctx, userId, request.CreateRecurringRecordRequest, tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if recurring, err = recurring.Edit(
+		request.Note,
+		template.category,
+		template.amount,
+		ledger.RecordType(request.Type),
+		template.beneficiaryId,
+		template.exchangeRate,
+		template.recurrence,
+		template.startDate,
+		template.endDate,
+		time.Now().UTC(),
+		ledger.MustMakeUpdatedByUserId(userId),
+	); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	return svc.updateAndCommit(ctx, userId, recurring, tx)
+}
+
+func (svc recurringRecordService) DeleteRecurringRecord(ctx context.Context, id ledger.RecurringRecordId, request RecurringRecordVersionRequest) error {
+	var (
+		userId    ledger.UserId
+		accountId ledger.AccountId
+		tx        *sql.Tx
+		recurring ledger.RecurringRecord
+		err       error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return err
+	}
+
+	if accountId, err = RequireAccountId(ctx); err != nil {
+		return err
+	}
+
+	if tx, err = svc.recurringRecordDao.BeginTx(); err != nil {
+		return err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("DeleteRecurringRecord: %d", userId))
+
+	if recurring, err = svc.getRecurringRecordOfVersion(ctx, id, accountId, userId, request.Version, tx); err != nil {
+		return err
+	}
+
+	if err = svc.recurringRecordDao.DeleteTx(ctx, id, userId, recurring.Version(), tx); err != nil {
+		return err
+	}
+
+	return dao.Commit(tx)
+}
+
+func (svc recurringRecordService) PauseRecurringRecord(ctx context.Context, id ledger.RecurringRecordId, request RecurringRecordVersionRequest) (RecurringRecordResponse, error) {
+	return svc.change(ctx, "PauseRecurringRecord", id, request, func(rr ledger.RecurringRecord, updatedBy ledger.UpdatedBy) (ledger.RecurringRecord, error) {
+		return rr.Pause(updatedBy)
+	})
+}
+
+// ResumeRecurringRecord continues creating records from today. Occurrences missed while the recurring record was paused are not created.
+func (svc recurringRecordService) ResumeRecurringRecord(ctx context.Context, id ledger.RecurringRecordId, request RecurringRecordVersionRequest) (RecurringRecordResponse, error) {
+	return svc.change(ctx, "ResumeRecurringRecord", id, request, func(rr ledger.RecurringRecord, updatedBy ledger.UpdatedBy) (ledger.RecurringRecord, error) {
+		return rr.Resume(time.Now().UTC(), updatedBy)
+	})
+}
+
+// SkipNextOccurrence moves the next occurrence of a recurring record forward without creating a record for it.
+// The scheduler will not create a record for the skipped occurrence, even if it is already due.
+func (svc recurringRecordService) SkipNextOccurrence(ctx context.Context, id ledger.RecurringRecordId, request RecurringRecordVersionRequest) (RecurringRecordResponse, error) {
+	var (
+		userId    ledger.UserId
+		accountId ledger.AccountId
+		tx        *sql.Tx
+		err       error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if accountId, err = RequireAccountId(ctx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if tx, err = svc.recurringRecordDao.BeginTx(); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("SkipNextOccurrence: %d", userId))
+
+	var (
+		recurring ledger.RecurringRecord
+		skipped   ledger.RecurringRecord
+	)
+
+	if recurring, err = svc.getRecurringRecordOfVersion(ctx, id, accountId, userId, request.Version, tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if skipped, err = recurring.Skip(ledger.MustMakeUpdatedByUserId(userId)); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	// The occurrence is recorded without a record so that it is never created
+	if _, err = svc.recurringRecordDao.SaveOccurrenceTx(ctx, id, recurring.NextDate(), 0, tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	return svc.updateAndCommit(ctx, userId, skipped, tx)
+}
+
+// change applies a change that does not depend on the body of the request to a recurring record of the account in the context.
+func (svc recurringRecordService) change(
+	ctx context.Context,
+	reference string,
+	id ledger.RecurringRecordId,
+	request RecurringRecordVersionRequest,
+	apply func(ledger.RecurringRecord, ledger.UpdatedBy) (ledger.RecurringRecord, error),
+) (RecurringRecordResponse, error) {
+	var (
+		userId    ledger.UserId
+		accountId ledger.AccountId
+		tx        *sql.Tx
+		recurring ledger.RecurringRecord
+		err       error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if accountId, err = RequireAccountId(ctx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if tx, err = svc.recurringRecordDao.BeginTx(); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("%s: %d", reference, userId))
+
+	if recurring, err = svc.getRecurringRecordOfVersion(ctx, id, accountId, userId, request.Version, tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if recurring, err = apply(recurring, ledger.MustMakeUpdatedByUserId(userId)); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	return svc.updateAndCommit(ctx, userId, recurring, tx)
+}
+
+// updateAndCommit saves the changes made to a recurring record and returns it with the version assigned by the database.
+func (svc recurringRecordService) updateAndCommit(ctx context.Context, userId ledger.UserId, recurring ledger.RecurringRecord, tx *sql.Tx) (RecurringRecordResponse, error) {
+	var err error
+
+	if err = svc.recurringRecordDao.UpdateTx(ctx, userId, recurring, tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if recurring, err = svc.recurringRecordDao.GetRecurringRecordByIdTx(ctx, recurring.Id(), userId, tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return RecurringRecordResponse{}, err
+	}
+
+	return makeRecurringRecordResponse(recurring), nil
+}
+
+func (svc recurringRecordService) CreateDueRecords(ctx context.Context, now time.Time) (int, error) {
+	created := 0
+	// A recurring record that failed is not retried until the next call, so that it is only logged once per call
+	failed := map[ledger.RecurringRecordId]bool{}
+	for {
+		var (
+			due []dao.DueRecurringRecord
+			err error
+		)
+
+		if due, err = svc.getDueRecurringRecords(ctx, now); err != nil {
+			return created, err
+		}
+
+		// A recurring record that is behind by more than one occurrence is due again once it has been advanced,
+		// so due records are loaded again until no recurring record could be advanced.
+		advanced := 0
+		for _, d := range due {
+			if failed[d.RecurringRecord.Id()] {
+				continue
+			}
+			var ok bool
+			if ok, err = svc.createNextRecord(ctx, d.UserId, d.RecurringRecord); err != nil {
+				log.Printf("Failed to create record for recurring record %d. Reason: %s", d.RecurringRecord.Id(), err)
+				failed[d.RecurringRecord.Id()] = true
+				continue
+			}
+			if ok {
+				created++
+			}
+			advanced++
+		}
+
+		if advanced == 0 {
+			return created, nil
+		}
+	}
+}
+
+func (svc recurringRecordService) getDueRecurringRecords(ctx context.Context, now time.Time) ([]dao.DueRecurringRecord, error) {
+	tx, err := svc.recurringRecordDao.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer dao.DeferRollback(tx, "GetDueRecurringRecords")
+
+	due, err := svc.recurringRecordDao.GetDueRecurringRecordsTx(ctx, now, 0, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// createNextRecord creates the record for the next occurrence of a recurring record and moves the recurring record to its following occurrence.
+// false is returned if the record of the occurrence had already been created.
+// A version conflict is returned if the recurring record was changed since it was loaded e.g. paused, or advanced by another instance of the application.
+func (svc recurringRecordService) createNextRecord(ctx context.Context, userId ledger.UserId, recurring ledger.RecurringRecord) (bool, error) {
+	var (
+		tx        *sql.Tx
+		recordId  ledger.RecordId
+		claimed   bool
+		advanced  ledger.RecurringRecord
+		err       error
+		updatedBy = ledger.MustMakeUpdatedByTask(RecurringRecordsTaskName)
+	)
+
+	if tx, err = svc.recurringRecordDao.BeginTx(); err != nil {
+		return false, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("CreateDueRecord: %d", recurring.Id()))
+
+	if recordId, err = svc.recordDao.NewRecordId(tx); err != nil {
+		return false, err
+	}
+
+	// Claiming the occurrence first guarantees that only one record is created for it,
+	// even when records are created by more than one instance of the application at the same time.
+	if claimed, err = svc.recurringRecordDao.SaveOccurrenceTx(ctx, recurring.Id(), recurring.NextDate(), recordId, tx); err != nil {
+		return false, err
+	}
+
+	if claimed {
+		if _, err = svc.records.createRecordTx(
+			ctx,
+			userId,
+			recurring.AccountId(),
+			recordId,
+			makeCreateRecordRequest(recurring),
+			// The next date of a recurring record is already a day in the timezone of the user
+			ledger.DefaultUserProfile(),
+			updatedBy,
+			tx,
+		); err != nil {
+			return false, err
+		}
+	}
+
+	if advanced, err = recurring.Advance(updatedBy); err != nil {
+		return false, err
+	}
+
+	if err = svc.recurringRecordDao.UpdateTx(ctx, userId, advanced, tx); err != nil {
+		return false, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return false, err
+	}
+
+	return claimed, nil
+}
+
+// makeCreateRecordRequest makes the request for the record of the next occurrence of a recurring record.
+func makeCreateRecordRequest(recurring ledger.RecurringRecord) CreateRecordRequest {
+	amountValue, _ := recurring.Amount().MinorUnits()
+
+	request := CreateRecordRequest{}
+	request.Note = recurring.Note()
+	request.Category.Id = uint64(recurring.Category().Id())
+	request.Amount.Currency = recurring.Amount().Currency().CurrencyCode()
+	request.Amount.Value = amountValue
+	request.DateUTC = recurring.NextDate().Format(time.RFC3339)
+	request.Type = string(recurring.RecordType())
+	if recurring.RecordType() == ledger.Transfer {
+		request.Transfer.Beneficiary.Id = uint64(recurring.BeneficiaryId())
+		request.Transfer.ExchangeRate = recurring.ExchangeRate().String()
+	}
+	return request
+}
+
+// recurringRecordTemplate holds the parsed details of a CreateRecurringRecordRequest.
+type recurringRecordTemplate struct {
+	category      ledger.Category
+	amount        ledger.Money
+	beneficiaryId ledger.AccountId
+	exchangeRate  ledger.ExchangeRate
+	recurrence    ledger.Recurrence
+	startDate     time.Time
+	endDate       time.Time
+}
+
+func (svc recurringRecordService) parseTemplate(ctx context.Context, userId ledger.UserId, request CreateRecurringRecordRequest, tx *sql.Tx) (recurringRecordTemplate, error) {
+	var (
+		template recurringRecordTemplate
+		err      error
+	)
+
+	if template.category, err = svc.categoryDao.GetCategoryById(ctx, ledger.CategoryId(request.Category.Id), userId, tx); err != nil {
+		return recurringRecordTemplate{}, err
+	}
+
+	if template.amount, err = ledger.NewMoney(request.Amount.Currency, request.Amount.Value); err != nil {
+		return recurringRecordTemplate{}, err
+	}
+
+	if template.recurrence, err = ledger.NewRecurrence(ledger.Frequency(request.Recurrence.Frequency), request.Recurrence.DayOfMonth); err != nil {
+		return recurringRecordTemplate{}, err
+	}
+
+	if template.startDate, err = parseRecurringRecordDate("startDate", request.StartDate); err != nil {
+		return recurringRecordTemplate{}, err
+	}
+
+	template.endDate = ledger.NoEndDate
+	if len(request.EndDate) > 0 {
+		if template.endDate, err = parseRecurringRecordDate("endDate", request.EndDate); err != nil {
+			return recurringRecordTemplate{}, err
+		}
+	}
+
+	template.beneficiaryId = ledger.NoBeneficiaryAccount
+	template.exchangeRate = ledger.NoExchangeRate
+	if ledger.RecordType(request.Type) == ledger.Transfer {
+		template.beneficiaryId = ledger.AccountId(request.Transfer.Beneficiary.Id)
+		if len(request.Transfer.ExchangeRate) > 0 {
+			if template.exchangeRate, err = ledger.ParseExchangeRate(request.Transfer.ExchangeRate); err != nil {
+				return recurringRecordTemplate{}, err
+			}
+		}
+	}
+
+	return template, nil
+}
+
+func parseRecurringRecordDate(field string, value string) (time.Time, error) {
+	date, err := time.Parse(recurringRecordDateFormat, value)
+	if err != nil {
+		return time.Time{}, pkg.ValidationErrorWithFields(
+			pkg.ErrRecurringRecordValidation,
+			fmt.Sprintf("%s '%s' does not match format '%s'", field, value, recurringRecordDateFormat),
+			err,
+			map[string]string{field: fmt.Sprintf("%s must be formatted as yyyy-MM-dd", field)},
+		)
+	}
+	return date, nil
+}
+
+// getRecurringRecordOfAccount loads a recurring record of the user, provided it belongs to the given account.
+func (svc recurringRecordService) getRecurringRecordOfAccount(
+	ctx context.Context,
+	id ledger.RecurringRecordId,
+	accountId ledger.AccountId,
+	userId ledger.UserId,
+	tx *sql.Tx,
+) (ledger.RecurringRecord, error) {
+	recurring, err := svc.recurringRecordDao.GetRecurringRecordByIdTx(ctx, id, userId, tx)
+	if err != nil {
+		return ledger.RecurringRecord{}, err
+	}
+
+	if recurring.AccountId() != accountId {
+		return ledger.RecurringRecord{}, pkg.ValidationErrorWithFields(pkg.ErrRecurringRecordNotFound, fmt.Sprintf("Recurring record %d not found", id), nil, nil)
+	}
+	return recurring, nil
+}
+
+// getRecurringRecordOfVersion loads a recurring record and checks that it has not changed since the client loaded the given version.
+func (svc recurringRecordService) getRecurringRecordOfVersion(
+	ctx context.Context,
+	id ledger.RecurringRecordId,
+	accountId ledger.AccountId,
+	userId ledger.UserId,
+	version uint64,
+	tx *sql.Tx,
+) (ledger.RecurringRecord, error) {
+	if version == 0 {
+		return ledger.RecurringRecord{}, pkg.ValidationErrorWithFields(pkg.ErrRecurringRecordValidation, "The version of the recurring record is required", nil, map[string]string{
+			"version": "version must be provided in the request body or in the If-Match header",
+		})
+	}
+
+	recurring, err := svc.getRecurringRecordOfAccount(ctx, id, accountId, userId, tx)
+	if err != nil {
+		return ledger.RecurringRecord{}, err
+	}
+
+	if recurring.Version() != ledger.Version(version) {
+		return ledger.RecurringRecord{}, pkg.ValidationErrorWithFields(
+			pkg.ErrRecurringRecordVersionConflict,
+			fmt.Sprintf("Recurring record %d has been changed. Expected version %d but found version %d", id, version, recurring.Version()),
+			nil,
+			nil,
+		)
+	}
+	return recurring, nil
+}
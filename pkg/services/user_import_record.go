@@ -0,0 +1,252 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/export"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+// importedAudit is the audit information of an entity of an archive, so that it is kept when the entity is imported.
+type importedAudit struct {
+	createdBy  ledger.UpdatedBy
+	createdAt  time.Time
+	modifiedBy ledger.UpdatedBy
+	modifiedAt time.Time
+	version    ledger.Version
+}
+
+// makeImportedAudit reads the audit information of an entity of an archive.
+// Changes made by the user that exported the archive are attributed to the user that imports it.
+func makeImportedAudit(audit export.Audit, userId ledger.UserId) (importedAudit, error) {
+	var (
+		imported importedAudit
+		err      error
+	)
+
+	if imported.createdBy, err = makeImportedUpdatedBy(audit.CreatedBy, userId); err != nil {
+		return importedAudit{}, err
+	}
+	if imported.createdAt, err = parseArchiveTime("createdAt", audit.CreatedAt); err != nil {
+		return importedAudit{}, err
+	}
+	if audit.ModifiedBy != nil {
+		if imported.modifiedBy, err = makeImportedUpdatedBy(*audit.ModifiedBy, userId); err != nil {
+			return importedAudit{}, err
+		}
+		if imported.modifiedAt, err = parseArchiveTime("modifiedAt", audit.ModifiedAt); err != nil {
+			return importedAudit{}, err
+		}
+	}
+	imported.version = ledger.Version(audit.Version)
+	return imported, nil
+}
+
+func makeImportedUpdatedBy(updatedBy export.UpdatedBy, userId ledger.UserId) (ledger.UpdatedBy, error) {
+	var (
+		kind ledger.UpdatedByKind
+		err  error
+	)
+
+	if kind, err = ledger.ParseUpdatedByKind(updatedBy.Kind); err != nil {
+		return ledger.UpdatedBy{}, err
+	}
+
+	switch kind {
+	case ledger.UpdatedByKindTask:
+		return ledger.MakeUpdatedByTask(updatedBy.TaskName)
+	case ledger.UpdatedByKindImport:
+		return ledger.MakeUpdatedByImport(updatedBy.FileName, updatedBy.BatchId)
+	case ledger.UpdatedByKindSystem:
+		return ledger.MakeUpdatedBySystem(), nil
+	}
+	return ledger.MakeUpdatedByUserId(userId)
+}
+
+func parseArchiveTime(field string, value string) (time.Time, error) {
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, pkg.ValidationErrorWithFields(pkg.ErrUserImportValidation, fmt.Sprintf("Invalid %s %q in archive", field, value), err, map[string]string{
+			field: fmt.Sprintf("%s must be formatted as %s", field, time.RFC3339),
+		})
+	}
+	return date.In(time.UTC), nil
+}
+
+func (a importedAudit) CreatedBy() ledger.UpdatedBy {
+	return a.createdBy
+}
+
+func (a importedAudit) CreatedAtUTC() time.Time {
+	return a.createdAt
+}
+
+func (a importedAudit) ModifiedBy() ledger.UpdatedBy {
+	return a.modifiedBy
+}
+
+func (a importedAudit) ModifiedAtUTC() time.Time {
+	return a.modifiedAt
+}
+
+func (a importedAudit) Version() ledger.Version {
+	return a.version
+}
+
+type importedAccount struct {
+	importedAudit
+	id          ledger.AccountId
+	name        string
+	accountType ledger.AccountType
+	currency    string
+}
+
+func (a importedAccount) Id() ledger.AccountId {
+	return a.id
+}
+
+func (a importedAccount) Name() string {
+	return a.name
+}
+
+func (a importedAccount) Type() ledger.AccountType {
+	return a.accountType
+}
+
+func (a importedAccount) Currency() string {
+	return a.currency
+}
+
+// CurrentBalanceMinorUnits is zero; the balance of an account is calculated from its records when it is loaded.
+func (a importedAccount) CurrentBalanceMinorUnits() int64 {
+	return 0
+}
+
+type importedCategory struct {
+	importedAudit
+	id   ledger.CategoryId
+	name string
+}
+
+func (c importedCategory) Id() ledger.CategoryId {
+	return c.id
+}
+
+func (c importedCategory) Name() string {
+	return c.name
+}
+
+type importedRecord struct {
+	importedAudit
+	id                ledger.RecordId
+	note              string
+	category          ledger.Category
+	amount            ledger.Money
+	dateUTC           time.Time
+	occurredAtUTC     time.Time
+	recordType        ledger.RecordType
+	sourceAccountId   ledger.AccountId
+	beneficiaryId     ledger.AccountId
+	beneficiaryType   ledger.AccountType
+	transferReference ledger.TransferReference
+	exchangeRate      ledger.ExchangeRate
+	splits            ledger.RecordSplits
+}
+
+func (r importedRecord) Id() ledger.RecordId {
+	return r.id
+}
+
+func (r importedRecord) Note() string {
+	return r.note
+}
+
+func (r importedRecord) Category() ledger.Category {
+	return r.category
+}
+
+func (r importedRecord) Amount() ledger.Money {
+	return r.amount
+}
+
+func (r importedRecord) DateUTC() time.Time {
+	return r.dateUTC
+}
+
+func (r importedRecord) OccurredAtUTC() time.Time {
+	return r.occurredAtUTC
+}
+
+func (r importedRecord) RecordType() ledger.RecordType {
+	return r.recordType
+}
+
+func (r importedRecord) SourceAccountId() ledger.AccountId {
+	return r.sourceAccountId
+}
+
+func (r importedRecord) BeneficiaryId() ledger.AccountId {
+	return r.beneficiaryId
+}
+
+func (r importedRecord) BeneficiaryType() ledger.AccountType {
+	return r.beneficiaryType
+}
+
+func (r importedRecord) TransferReference() ledger.TransferReference {
+	return r.transferReference
+}
+
+func (r importedRecord) ExchangeRate() ledger.ExchangeRate {
+	return r.exchangeRate
+}
+
+func (r importedRecord) Splits() ledger.RecordSplits {
+	return r.splits
+}
+
+// makeImportedPeriodCalculator reads the periods of a budget of an archive.
+// Archives made before budgets could choose an anchor date or a start day use the default calculator of the period type.
+func makeImportedPeriodCalculator(b export.ArchivedBudget) (ledger.PeriodCalculator, error) {
+	periodType := ledger.BudgetPeriodType(b.Period)
+	if len(b.PeriodAnchor) == 0 && b.PeriodStartDay == 0 {
+		return ledger.DefaultPeriodCalculator(periodType), nil
+	}
+
+	var anchorDate time.Time
+	if len(b.PeriodAnchor) != 0 {
+		var err error
+		if anchorDate, err = time.Parse("2006-01-02", b.PeriodAnchor); err != nil {
+			return ledger.PeriodCalculator{}, pkg.ValidationErrorWithFields(pkg.ErrUserImportValidation, fmt.Sprintf("Invalid period anchor %q of budget %d", b.PeriodAnchor, b.Id), err, map[string]string{
+				"periodAnchor": "periodAnchor must be a date formatted as yyyy-MM-dd",
+			})
+		}
+	}
+	return ledger.NewPeriodCalculator(periodType, anchorDate, b.PeriodStartDay)
+}
+
+type importedBudget struct {
+	importedAudit
+	id               ledger.BudgetId
+	accountIds       ledger.AccountIds
+	periodCalculator ledger.PeriodCalculator
+	categoryBudgets  ledger.CategoryBudgets
+}
+
+func (b importedBudget) Id() ledger.BudgetId {
+	return b.id
+}
+
+func (b importedBudget) AccountIds() ledger.AccountIds {
+	return b.accountIds
+}
+
+func (b importedBudget) PeriodCalculator() ledger.PeriodCalculator {
+	return b.periodCalculator
+}
+
+func (b importedBudget) CategoryBudgets() ledger.CategoryBudgets {
+	return b.categoryBudgets
+}
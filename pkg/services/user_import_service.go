@@ -0,0 +1,587 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/export"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+)
+
+// ImportConflictStrategy is how an account or category of an archive is imported when the user already has one with the same name.
+type ImportConflictStrategy string
+
+const (
+	// ImportConflictSkip does not import the account or category, nor the records and budgets that refer to it.
+	ImportConflictSkip ImportConflictStrategy = "skip"
+	// ImportConflictRename imports the account or category with a number appended to its name e.g. "Current (2)".
+	ImportConflictRename ImportConflictStrategy = "rename"
+	// ImportConflictMerge imports the records and budgets of the account or category into the existing one.
+	ImportConflictMerge ImportConflictStrategy = "merge"
+)
+
+// maxImportedNameLength is the maximum length of the name of an account or category
+const maxImportedNameLength = 25
+
+func ParseImportConflictStrategy(value string) (ImportConflictStrategy, error) {
+	switch strategy := ImportConflictStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case "":
+		return ImportConflictRename, nil
+	case ImportConflictSkip, ImportConflictRename, ImportConflictMerge:
+		return strategy, nil
+	}
+	return "", pkg.ValidationErrorWithFields(pkg.ErrUserImportValidation, fmt.Sprintf("Unknown onConflict strategy %q", value), nil, map[string]string{
+		"onConflict": "onConflict must be skip, rename or merge",
+	})
+}
+
+// ImportUserDataRequest holds an archive exported by ExportUserData.
+// OnConflict is skip, rename (default) or merge.
+type ImportUserDataRequest struct {
+	OnConflict string
+	Archive    io.Reader
+}
+
+// ImportedEntitiesResponse is the number of entities of a kind that were imported.
+// Renamed and merged entities are only counted once, and not as created.
+type ImportedEntitiesResponse struct {
+	Created int `json:"created"`
+	Renamed int `json:"renamed"`
+	Merged  int `json:"merged"`
+	Skipped int `json:"skipped"`
+}
+
+type ImportUserDataResponse struct {
+	OnConflict ImportConflictStrategy   `json:"onConflict"`
+	Accounts   ImportedEntitiesResponse `json:"accounts"`
+	Categories ImportedEntitiesResponse `json:"categories"`
+	Budgets    ImportedEntitiesResponse `json:"budgets"`
+	Records    ImportedEntitiesResponse `json:"records"`
+}
+
+type UserImportService interface {
+	// ImportUserData recreates the accounts, categories, budgets and records of an archive for the user in the context, in a single transaction.
+	// Entities are given new ids, and the two records of a transfer are linked by a new transfer reference.
+	ImportUserData(ctx context.Context, request ImportUserDataRequest) (ImportUserDataResponse, error)
+}
+
+type userImportService struct {
+	uniqueIdService UniqueIdService
+	accountDao      dao.AccountDao
+	categoryDao     dao.CategoryDao
+	budgetDao       dao.BudgetDao
+	recordDao       dao.RecordDao
+}
+
+func NewUserImportService(
+	uniqueIdService UniqueIdService,
+	accountDao dao.AccountDao,
+	categoryDao dao.CategoryDao,
+	budgetDao dao.BudgetDao,
+	recordDao dao.RecordDao,
+) (UserImportService, error) {
+	if uniqueIdService == nil {
+		return nil, fmt.Errorf("can not create user import service. uniqueIdService is nil")
+	}
+	if accountDao == nil {
+		return nil, fmt.Errorf("can not create user import service. accountDao is nil")
+	}
+	if categoryDao == nil {
+		return nil, fmt.Errorf("can not create user import service. categoryDao is nil")
+	}
+	if budgetDao == nil {
+		return nil, fmt.Errorf("can not create user import service. budgetDao is nil")
+	}
+	if recordDao == nil {
+		return nil, fmt.Errorf("can not create user import service. recordDao is nil")
+	}
+
+	return &userImportService{
+		uniqueIdService: uniqueIdService,
+		accountDao:      accountDao,
+		categoryDao:     categoryDao,
+		budgetDao:       budgetDao,
+		recordDao:       recordDao,
+	}, nil
+}
+
+// userDataImport is the state of the import of an archive.
+// The accounts and categories of the archive are mapped by their archived id to the account or category they were imported as.
+// Accounts and categories that were skipped are not mapped, and neither is anything that refers to them imported.
+type userDataImport struct {
+	userId     ledger.UserId
+	onConflict ImportConflictStrategy
+	accounts   map[uint64]ledger.Account
+	categories map[uint64]ledger.Category
+	// references are the new transfer references by archived transfer reference
+	references map[string]ledger.TransferReference
+	response   ImportUserDataResponse
+}
+
+func (svc userImportService) ImportUserData(ctx context.Context, request ImportUserDataRequest) (ImportUserDataResponse, error) {
+	var (
+		userId     ledger.UserId
+		onConflict ImportConflictStrategy
+		archive    export.Archive
+		tx         *sql.Tx
+		err        error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return ImportUserDataResponse{}, err
+	}
+
+	if onConflict, err = ParseImportConflictStrategy(request.OnConflict); err != nil {
+		return ImportUserDataResponse{}, err
+	}
+
+	if archive, err = export.ReadArchive(request.Archive); err != nil {
+		return ImportUserDataResponse{}, err
+	}
+
+	if tx, err = svc.accountDao.BeginTx(); err != nil {
+		return ImportUserDataResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("ImportUserData: %d", userId))
+
+	in := &userDataImport{
+		userId:     userId,
+		onConflict: onConflict,
+		accounts:   map[uint64]ledger.Account{},
+		categories: map[uint64]ledger.Category{},
+		references: map[string]ledger.TransferReference{},
+		response:   ImportUserDataResponse{OnConflict: onConflict},
+	}
+
+	if err = svc.importCategories(ctx, in, archive.Categories, tx); err != nil {
+		return ImportUserDataResponse{}, err
+	}
+
+	if err = svc.importAccounts(ctx, in, archive.Accounts, tx); err != nil {
+		return ImportUserDataResponse{}, err
+	}
+
+	for _, account := range archive.Accounts {
+		if err = svc.importRecords(ctx, in, account, tx); err != nil {
+			return ImportUserDataResponse{}, err
+		}
+	}
+
+	if err = svc.importBudgets(ctx, in, archive.Budgets, tx); err != nil {
+		return ImportUserDataResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return ImportUserDataResponse{}, err
+	}
+
+	return in.response, nil
+}
+
+func (svc userImportService) importCategories(ctx context.Context, in *userDataImport, archived []export.ArchivedCategory, tx *sql.Tx) error {
+	var (
+		existing ledger.Categories
+		err      error
+	)
+
+	if existing, err = svc.categoryDao.GetCategoriesForUser(ctx, in.userId, tx); err != nil {
+		return err
+	}
+
+	categoriesByName := map[string]ledger.Category{}
+	for _, category := range existing {
+		categoriesByName[strings.ToLower(category.Name())] = category
+	}
+
+	categories := make(ledger.Categories, 0, len(archived))
+	for _, c := range archived {
+		var (
+			audit    importedAudit
+			id       uint64
+			category ledger.Category
+		)
+
+		name := c.Name
+		if conflict, ok := categoriesByName[strings.ToLower(name)]; ok {
+			switch in.onConflict {
+			case ImportConflictSkip:
+				in.response.Categories.Skipped += 1
+				continue
+			case ImportConflictMerge:
+				in.categories[c.Id] = conflict
+				in.response.Categories.Merged += 1
+				continue
+			}
+			name = uniqueImportedName(name, func(name string) bool {
+				_, ok := categoriesByName[strings.ToLower(name)]
+				return ok
+			})
+			in.response.Categories.Renamed += 1
+		} else {
+			in.response.Categories.Created += 1
+		}
+
+		if audit, err = makeImportedAudit(c.Audit, in.userId); err != nil {
+			return err
+		}
+
+		if id, err = svc.uniqueIdService.GetId(EntityCategory); err != nil {
+			return err
+		}
+
+		if category, err = ledger.NewCategoryFromRecord(importedCategory{
+			importedAudit: audit,
+			id:            ledger.CategoryId(id),
+			name:          name,
+		}); err != nil {
+			return err
+		}
+
+		in.categories[c.Id] = category
+		categoriesByName[strings.ToLower(category.Name())] = category
+		categories = append(categories, category)
+	}
+
+	if len(categories) == 0 {
+		return nil
+	}
+	return svc.categoryDao.SaveTx(ctx, in.userId, categories, tx)
+}
+
+func (svc userImportService) importAccounts(ctx context.Context, in *userDataImport, archived []export.ArchivedAccount, tx *sql.Tx) error {
+	var (
+		existing ledger.Accounts
+		err      error
+	)
+
+	if existing, err = svc.accountDao.GetAccountsByUserId(ctx, in.userId, tx); err != nil {
+		return err
+	}
+
+	accountsByName := map[string]ledger.Account{}
+	for _, account := range existing {
+		accountsByName[strings.ToLower(account.Name())] = account
+	}
+
+	accounts := make(ledger.Accounts, 0, len(archived))
+	for _, a := range archived {
+		var (
+			audit   importedAudit
+			id      uint64
+			account ledger.Account
+		)
+
+		name := a.Name
+		if conflict, ok := accountsByName[strings.ToLower(name)]; ok {
+			switch in.onConflict {
+			case ImportConflictSkip:
+				in.response.Accounts.Skipped += 1
+				continue
+			case ImportConflictMerge:
+				if conflict.Currency() != a.Currency {
+					return pkg.ValidationErrorWithFields(pkg.ErrUserImportValidation, fmt.Sprintf("Account %q can not be merged", a.Name), nil, map[string]string{
+						"accounts": fmt.Sprintf("account %q in %s can not be merged into an account in %s", a.Name, a.Currency, conflict.Currency()),
+					})
+				}
+				in.accounts[a.Id] = conflict
+				in.response.Accounts.Merged += 1
+				continue
+			}
+			name = uniqueImportedName(name, func(name string) bool {
+				_, ok := accountsByName[strings.ToLower(name)]
+				return ok
+			})
+			in.response.Accounts.Renamed += 1
+		} else {
+			in.response.Accounts.Created += 1
+		}
+
+		if audit, err = makeImportedAudit(a.Audit, in.userId); err != nil {
+			return err
+		}
+
+		if id, err = svc.uniqueIdService.GetId(EntityAccount); err != nil {
+			return err
+		}
+
+		if account, err = ledger.NewAccountFromRecord(importedAccount{
+			importedAudit: audit,
+			id:            ledger.AccountId(id),
+			name:          name,
+			accountType:   ledger.AccountType(a.Type),
+			currency:      a.Currency,
+		}); err != nil {
+			return err
+		}
+
+		in.accounts[a.Id] = account
+		accountsByName[strings.ToLower(account.Name())] = account
+		accounts = append(accounts, account)
+	}
+
+	if len(accounts) == 0 {
+		return nil
+	}
+	return svc.accountDao.SaveTx(ctx, in.userId, accounts, tx)
+}
+
+// importRecords imports the records of an archived account.
+// A record is skipped if its account, its category, the category of one of its lines, or the other account of a transfer was skipped,
+// so that either both or neither of the records of a transfer are imported.
+func (svc userImportService) importRecords(ctx context.Context, in *userDataImport, archived export.ArchivedAccount, tx *sql.Tx) error {
+	account, ok := in.accounts[archived.Id]
+	if !ok {
+		in.response.Records.Skipped += len(archived.Records)
+		return nil
+	}
+
+	for _, r := range archived.Records {
+		var (
+			record ledger.Record
+			err    error
+		)
+
+		if record, ok, err = svc.makeImportedRecord(in, r); err != nil {
+			return err
+		}
+		if !ok {
+			in.response.Records.Skipped += 1
+			continue
+		}
+
+		if err = svc.recordDao.SaveTx(ctx, account.Id(), record, tx); err != nil {
+			return err
+		}
+		in.response.Records.Created += 1
+	}
+	return nil
+}
+
+// makeImportedRecord returns false if the record refers to an account or category that was skipped.
+func (svc userImportService) makeImportedRecord(in *userDataImport, r export.Record) (ledger.Record, bool, error) {
+	var (
+		audit    importedAudit
+		id       uint64
+		amount   ledger.Money
+		date     time.Time
+		splits   ledger.RecordSplits
+		record   ledger.Record
+		category ledger.Category
+		ok       bool
+		err      error
+	)
+
+	if category, ok = in.categories[r.Category.Id]; !ok {
+		return ledger.Record{}, false, nil
+	}
+
+	for _, s := range r.Splits {
+		var (
+			splitCategory ledger.Category
+			splitAmount   ledger.Money
+			split         ledger.RecordSplit
+		)
+		if splitCategory, ok = in.categories[s.Category.Id]; !ok {
+			return ledger.Record{}, false, nil
+		}
+		if splitAmount, err = ledger.NewMoney(s.Amount.Currency, s.Amount.Value); err != nil {
+			return ledger.Record{}, false, err
+		}
+		if split, err = ledger.NewRecordSplit(s.Note, splitCategory, splitAmount); err != nil {
+			return ledger.Record{}, false, err
+		}
+		splits = append(splits, split)
+	}
+
+	imported := importedRecord{
+		note:       r.Note,
+		category:   category,
+		recordType: ledger.RecordType(r.Type),
+		splits:     splits,
+	}
+
+	if r.Transfer != nil {
+		var (
+			source      ledger.Account
+			beneficiary ledger.Account
+			reference   ledger.TransferReference
+		)
+		if source, ok = in.accounts[r.Transfer.Source.Id]; !ok {
+			return ledger.Record{}, false, nil
+		}
+		if beneficiary, ok = in.accounts[r.Transfer.Beneficiary.Id]; !ok {
+			return ledger.Record{}, false, nil
+		}
+		if reference, ok = in.references[r.Transfer.Reference]; !ok {
+			reference = ledger.MakeTransferReference()
+			in.references[r.Transfer.Reference] = reference
+		}
+		if len(r.Transfer.ExchangeRate) > 0 {
+			if imported.exchangeRate, err = ledger.ParseExchangeRate(r.Transfer.ExchangeRate); err != nil {
+				return ledger.Record{}, false, err
+			}
+		}
+		imported.sourceAccountId = source.Id()
+		imported.beneficiaryId = beneficiary.Id()
+		imported.beneficiaryType = beneficiary.Type()
+		imported.transferReference = reference
+	}
+
+	if amount, err = ledger.NewMoney(r.Amount.Currency, r.Amount.Value); err != nil {
+		return ledger.Record{}, false, err
+	}
+	imported.amount = amount
+
+	if date, err = parseArchiveTime("date", r.Date); err != nil {
+		return ledger.Record{}, false, err
+	}
+	imported.occurredAtUTC = date
+	// Archives exported before the local date of records was kept only have the moment of the record
+	imported.dateUTC = ledger.MakeDay(date).FirstDay()
+	if len(r.LocalDate) > 0 {
+		if imported.dateUTC, err = time.Parse("2006-01-02", r.LocalDate); err != nil {
+			return ledger.Record{}, false, pkg.ValidationErrorWithFields(pkg.ErrUserImportValidation, fmt.Sprintf("Invalid localDate %q in archive", r.LocalDate), err, map[string]string{
+				"localDate": "localDate must be formatted as yyyy-MM-dd",
+			})
+		}
+	}
+
+	if audit, err = makeImportedAudit(r.Audit, in.userId); err != nil {
+		return ledger.Record{}, false, err
+	}
+	imported.importedAudit = audit
+
+	if id, err = svc.uniqueIdService.GetId(EntityRecord); err != nil {
+		return ledger.Record{}, false, err
+	}
+	imported.id = ledger.RecordId(id)
+
+	if record, err = ledger.NewRecordFromRecord(imported); err != nil {
+		return ledger.Record{}, false, err
+	}
+	return record, true, nil
+}
+
+// importBudgets imports the budgets of an archive for the accounts and categories that were imported.
+// A category can only be budgeted once, so the budget of a category that is already budgeted is skipped.
+// A budget is skipped if none of its accounts or none of its categories were imported.
+func (svc userImportService) importBudgets(ctx context.Context, in *userDataImport, archived []export.ArchivedBudget, tx *sql.Tx) error {
+	var (
+		existing []ledger.Budget
+		err      error
+	)
+
+	if existing, err = svc.budgetDao.GetBudgetsForUser(ctx, in.userId, tx); err != nil {
+		return err
+	}
+
+	budgeted := map[ledger.CategoryId]bool{}
+	for _, budget := range existing {
+		for _, categoryBudget := range budget.CategoryBudgets() {
+			budgeted[categoryBudget.CategoryId()] = true
+		}
+	}
+
+	for _, b := range archived {
+		var (
+			audit            importedAudit
+			periodCalculator ledger.PeriodCalculator
+			id               uint64
+			budget           ledger.Budget
+		)
+
+		accountIds := ledger.AccountIds{}
+		for _, accountId := range b.AccountIds {
+			if account, ok := in.accounts[accountId]; ok {
+				accountIds = append(accountIds, account.Id())
+			}
+		}
+
+		categoryBudgets := ledger.CategoryBudgets{}
+		for _, cb := range b.CategoryBudgets {
+			var (
+				maxLimit       ledger.Money
+				categoryBudget ledger.CategoryBudget
+			)
+			category, ok := in.categories[cb.CategoryId]
+			if !ok || budgeted[category.Id()] {
+				continue
+			}
+			if maxLimit, err = ledger.NewMoney(cb.MaxLimit.Currency, cb.MaxLimit.Value); err != nil {
+				return err
+			}
+			// Archives exported before alert thresholds and rollover were introduced use the defaults
+			alertThresholds := ledger.DefaultAlertThresholds()
+			if cb.AlertThresholds != nil {
+				alertThresholds = ledger.AlertThresholds(cb.AlertThresholds)
+			}
+			rollover := ledger.RolloverNone
+			if len(cb.Rollover) != 0 {
+				rollover = ledger.RolloverMode(cb.Rollover)
+			}
+			if categoryBudget, err = ledger.NewCategoryBudgetWithRollover(category.Id(), maxLimit, alertThresholds, rollover); err != nil {
+				return err
+			}
+			categoryBudgets = append(categoryBudgets, categoryBudget)
+		}
+
+		if len(accountIds) == 0 || len(categoryBudgets) == 0 {
+			in.response.Budgets.Skipped += 1
+			continue
+		}
+
+		if audit, err = makeImportedAudit(b.Audit, in.userId); err != nil {
+			return err
+		}
+
+		if periodCalculator, err = makeImportedPeriodCalculator(b); err != nil {
+			return err
+		}
+
+		if id, err = svc.uniqueIdService.GetId(EntityBudget); err != nil {
+			return err
+		}
+
+		if budget, err = ledger.NewBudgetFromRecord(importedBudget{
+			importedAudit:    audit,
+			id:               ledger.BudgetId(id),
+			accountIds:       accountIds,
+			periodCalculator: periodCalculator,
+			categoryBudgets:  categoryBudgets,
+		}); err != nil {
+			return err
+		}
+
+		if err = svc.budgetDao.Save(ctx, in.userId, budget, tx); err != nil {
+			return err
+		}
+
+		for _, categoryBudget := range categoryBudgets {
+			budgeted[categoryBudget.CategoryId()] = true
+		}
+		in.response.Budgets.Created += 1
+	}
+	return nil
+}
+
+// uniqueImportedName appends the first number from 2 to the name e.g. "Current (2)" that is not taken.
+// The name is shortened, if need be, so that it is not longer than the maximum length of a name.
+func uniqueImportedName(name string, isTaken func(string) bool) string {
+	for n := 2; ; n++ {
+		suffix := []rune(fmt.Sprintf(" (%d)", n))
+		base := []rune(name)
+		if len(base)+len(suffix) > maxImportedNameLength {
+			base = []rune(strings.TrimSpace(string(base[:maxImportedNameLength-len(suffix)])))
+		}
+		if candidate := string(base) + string(suffix); !isTaken(candidate) {
+			return candidate
+		}
+	}
+}
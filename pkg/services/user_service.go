@@ -1,42 +1,166 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/w-k-s/simple-budget-tracker/pkg"
 	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
 	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
 )
 
+// CreateUserRequest holds the email and password of the user and, optionally, their preferences.
+// The timezone defaults to UTC, the locale to en-US and the week start to Monday.
 type CreateUserRequest struct {
-	Email string `json:"email"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	Timezone  string `json:"timezone,omitempty"`
+	Locale    string `json:"locale,omitempty"`
+	WeekStart string `json:"weekStart,omitempty"`
 }
 
 type CreateUserResponse struct {
-	Id    ledger.UserId `json:"id"`
-	Email string        `json:"email"`
+	Id        ledger.UserId `json:"id"`
+	Email     string        `json:"email"`
+	Timezone  string        `json:"timezone"`
+	Locale    string        `json:"locale"`
+	WeekStart string        `json:"weekStart"`
+}
+
+// makeUserProfile creates the profile of a new user, using the default of each preference that is not provided
+func makeUserProfile(request CreateUserRequest) (ledger.UserProfile, error) {
+	var (
+		timezone  = request.Timezone
+		locale    = request.Locale
+		weekStart = request.WeekStart
+	)
+	if len(timezone) == 0 {
+		timezone = ledger.DefaultTimezone
+	}
+	if len(locale) == 0 {
+		locale = ledger.DefaultLocale
+	}
+	if len(weekStart) == 0 {
+		weekStart = ledger.DefaultWeekStart.String()
+	}
+	return ledger.NewUserProfile(timezone, locale, weekStart)
+}
+
+// UserResponse is the profile and preferences of the user.
+// The default account and category are omitted when the user has not chosen them.
+type UserResponse struct {
+	Id                ledger.UserId `json:"id"`
+	Email             string        `json:"email"`
+	DisplayName       string        `json:"displayName,omitempty"`
+	Currency          string        `json:"currency,omitempty"`
+	Timezone          string        `json:"timezone"`
+	Locale            string        `json:"locale"`
+	WeekStart         string        `json:"weekStart"`
+	DefaultAccountId  uint64        `json:"defaultAccountId,omitempty"`
+	DefaultCategoryId uint64        `json:"defaultCategoryId,omitempty"`
+	Version           uint64        `json:"version"`
+}
+
+func makeUserResponse(user ledger.User) UserResponse {
+	return UserResponse{
+		Id:                user.Id(),
+		Email:             user.Email().Address,
+		DisplayName:       user.Preferences().DisplayName(),
+		Currency:          user.Preferences().Currency(),
+		Timezone:          user.Profile().Timezone(),
+		Locale:            user.Profile().Locale(),
+		WeekStart:         user.Profile().WeekStart().String(),
+		DefaultAccountId:  uint64(user.Preferences().DefaultAccountId()),
+		DefaultCategoryId: uint64(user.Preferences().DefaultCategoryId()),
+		Version:           uint64(user.Version()),
+	}
+}
+
+// PatchUserRequest changes only the provided fields of the profile and preferences of the user.
+// An empty display name or currency, or a default account or category of 0, removes the preference.
+type PatchUserRequest struct {
+	DisplayName       *string `json:"displayName,omitempty"`
+	Currency          *string `json:"currency,omitempty"`
+	Timezone          *string `json:"timezone,omitempty"`
+	Locale            *string `json:"locale,omitempty"`
+	WeekStart         *string `json:"weekStart,omitempty"`
+	DefaultAccountId  *uint64 `json:"defaultAccountId,omitempty"`
+	DefaultCategoryId *uint64 `json:"defaultCategoryId,omitempty"`
+	Version           uint64  `json:"version"`
+}
+
+// UserDeletionTokenResponse is returned when a user asks to delete their account.
+// The deletion must be confirmed with the token before it expires.
+type UserDeletionTokenResponse struct {
+	ConfirmationToken string `json:"confirmationToken"`
+	ExpiresAt         string `json:"expiresAt"`
+}
+
+// UserDeletionResponse is returned once the deletion of an account has been confirmed.
+// The user and all their data are deleted after the grace period, unless the deletion is cancelled.
+type UserDeletionResponse struct {
+	DeleteAfter string `json:"deleteAfter"`
 }
 
 type UserService interface {
 	CreateUser(request CreateUserRequest) (CreateUserResponse, error)
+	GetUser(ctx context.Context) (UserResponse, error)
+	// PatchUser changes the profile and preferences of the user, if the user has not been changed since the given version.
+	PatchUser(ctx context.Context, request PatchUserRequest) (UserResponse, error)
+
+	// RequestDeletion returns a token with which the user can confirm the deletion of their account.
+	RequestDeletion(ctx context.Context) (UserDeletionTokenResponse, error)
+	// ConfirmDeletion schedules the deletion of the account of the user after the grace period.
+	ConfirmDeletion(ctx context.Context, confirmationToken string) (UserDeletionResponse, error)
+	CancelDeletion(ctx context.Context) error
+	// DeleteDueUsers deletes the users whose confirmed deletion is due, along with all their data.
+	DeleteDueUsers(ctx context.Context, now time.Time) (int, error)
 }
 
+// userDeletionTokenValidity is how long a user has to confirm the deletion of their account
+const userDeletionTokenValidity = time.Hour
+
 type userService struct {
 	userDao dao.UserDao
+	// accountDao and categoryDao are used to check that the default account and category of the user are theirs
+	accountDao          dao.AccountDao
+	categoryDao         dao.CategoryDao
+	deletionGracePeriod time.Duration
 }
 
-func NewUserService(userDao dao.UserDao) (UserService, error) {
+func NewUserService(userDao dao.UserDao, accountDao dao.AccountDao, categoryDao dao.CategoryDao, deletionGracePeriod time.Duration) (UserService, error) {
 	if userDao == nil {
 		return nil, fmt.Errorf("can not create user service. userDao is nil")
 	}
+	if accountDao == nil {
+		return nil, fmt.Errorf("can not create user service. accountDao is nil")
+	}
+	if categoryDao == nil {
+		return nil, fmt.Errorf("can not create user service. categoryDao is nil")
+	}
 
 	return &userService{
-		userDao: userDao,
+		userDao:             userDao,
+		accountDao:          accountDao,
+		categoryDao:         categoryDao,
+		deletionGracePeriod: deletionGracePeriod,
 	}, nil
 }
 
 func (u userService) CreateUser(request CreateUserRequest) (CreateUserResponse, error) {
 
+	profile, err := makeUserProfile(request)
+	if err != nil {
+		return CreateUserResponse{}, err
+	}
+
 	tx, err := u.userDao.BeginTx()
 	if err != nil {
 		return CreateUserResponse{}, err
@@ -48,24 +172,389 @@ func (u userService) CreateUser(request CreateUserRequest) (CreateUserResponse,
 		return CreateUserResponse{}, err
 	}
 
-	user, err := ledger.NewUserWithEmailString(userId, request.Email)
+	user, err := ledger.NewUserWithProfile(userId, request.Email, profile)
+	if err != nil {
+		return CreateUserResponse{}, err
+	}
+
+	passwordHash, err := ledger.HashPassword(request.Password)
 	if err != nil {
 		return CreateUserResponse{}, err
 	}
 
-	err = u.userDao.SaveTx(user, tx)
-	if message, duplicate := u.userDao.IsDuplicateKeyError(err); duplicate {
-		return CreateUserResponse{}, pkg.ValidationErrorWithError(pkg.ErrUserEmailDuplicated, message, err)
-	} else {
+	if err = u.userDao.SaveTx(user, tx); err != nil {
+		if message, duplicate := u.userDao.IsDuplicateKeyError(err); duplicate {
+			return CreateUserResponse{}, pkg.ValidationErrorWithError(pkg.ErrUserEmailDuplicated, message, err)
+		}
 		return CreateUserResponse{}, pkg.NewSystemError(pkg.ErrDatabaseState, "Failed to create user", err)
 	}
 
+	if err = u.userDao.SaveCredentialTx(context.Background(), dao.UserCredential{
+		UserId:       userId,
+		PasswordHash: passwordHash,
+		UpdatedAt:    time.Now().UTC(),
+	}, tx); err != nil {
+		return CreateUserResponse{}, err
+	}
+
 	if err = dao.Commit(tx); err != nil {
 		return CreateUserResponse{}, err
 	}
 
 	return CreateUserResponse{
-		Id:    userId,
-		Email: user.Email().Address,
+		Id:        userId,
+		Email:     user.Email().Address,
+		Timezone:  profile.Timezone(),
+		Locale:    profile.Locale(),
+		WeekStart: profile.WeekStart().String(),
+	}, nil
+}
+
+func (u userService) GetUser(ctx context.Context) (UserResponse, error) {
+	var (
+		userId ledger.UserId
+		user   ledger.User
+		err    error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return UserResponse{}, err
+	}
+
+	if user, err = u.userDao.GetUserById(userId); err != nil {
+		return UserResponse{}, err
+	}
+
+	return makeUserResponse(user), nil
+}
+
+func (u userService) PatchUser(ctx context.Context, request PatchUserRequest) (UserResponse, error) {
+	var (
+		userId      ledger.UserId
+		tx          *sql.Tx
+		user        ledger.User
+		profile     ledger.UserProfile
+		preferences ledger.UserPreferences
+		err         error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return UserResponse{}, err
+	}
+
+	if request.Version == 0 {
+		return UserResponse{}, pkg.ValidationErrorWithFields(pkg.ErrUserProfileValidation, "The version of the user is required", nil, map[string]string{
+			"version": "version must be provided in the request body or in the If-Match header",
+		})
+	}
+
+	if tx, err = u.userDao.BeginTx(); err != nil {
+		return UserResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("PatchUser: %d", userId))
+
+	if user, err = u.userDao.GetUserByIdTx(ctx, userId, tx); err != nil {
+		return UserResponse{}, err
+	}
+
+	if user.Version() != ledger.Version(request.Version) {
+		return UserResponse{}, pkg.ValidationErrorWithFields(
+			pkg.ErrUserVersionConflict,
+			fmt.Sprintf("User %d has been changed. Expected version %d but found version %d", userId, request.Version, user.Version()),
+			nil,
+			nil,
+		)
+	}
+
+	if profile, err = patchUserProfile(user.Profile(), request); err != nil {
+		return UserResponse{}, err
+	}
+
+	if preferences, err = u.patchUserPreferencesTx(ctx, userId, user.Preferences(), request, tx); err != nil {
+		return UserResponse{}, err
+	}
+
+	if user, err = user.Edit(profile, preferences, ledger.MustMakeUpdatedByUserId(userId)); err != nil {
+		return UserResponse{}, err
+	}
+
+	if err = u.userDao.UpdateTx(ctx, user, tx); err != nil {
+		return UserResponse{}, err
+	}
+
+	// The version is assigned by the database
+	if user, err = u.userDao.GetUserByIdTx(ctx, userId, tx); err != nil {
+		return UserResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return UserResponse{}, err
+	}
+
+	return makeUserResponse(user), nil
+}
+
+func patchUserProfile(profile ledger.UserProfile, request PatchUserRequest) (ledger.UserProfile, error) {
+	var (
+		timezone  = profile.Timezone()
+		locale    = profile.Locale()
+		weekStart = profile.WeekStart().String()
+	)
+	if request.Timezone != nil {
+		timezone = *request.Timezone
+	}
+	if request.Locale != nil {
+		locale = *request.Locale
+	}
+	if request.WeekStart != nil {
+		weekStart = *request.WeekStart
+	}
+	return ledger.NewUserProfile(timezone, locale, weekStart)
+}
+
+// patchUserPreferencesTx changes the provided preferences. The default account and category must belong to the user.
+func (u userService) patchUserPreferencesTx(
+	ctx context.Context,
+	userId ledger.UserId,
+	preferences ledger.UserPreferences,
+	request PatchUserRequest,
+	tx *sql.Tx,
+) (ledger.UserPreferences, error) {
+	var (
+		displayName       = preferences.DisplayName()
+		currency          = preferences.Currency()
+		defaultAccountId  = preferences.DefaultAccountId()
+		defaultCategoryId = preferences.DefaultCategoryId()
+		err               error
+	)
+
+	if request.DisplayName != nil {
+		displayName = *request.DisplayName
+	}
+	if request.Currency != nil {
+		currency = *request.Currency
+	}
+	if request.DefaultAccountId != nil {
+		defaultAccountId = ledger.AccountId(*request.DefaultAccountId)
+		if defaultAccountId != ledger.NoDefaultAccount {
+			if _, err = u.accountDao.GetAccountById(ctx, defaultAccountId, userId, tx); err != nil {
+				return ledger.UserPreferences{}, err
+			}
+		}
+	}
+	if request.DefaultCategoryId != nil {
+		defaultCategoryId = ledger.CategoryId(*request.DefaultCategoryId)
+		if defaultCategoryId != ledger.NoDefaultCategory {
+			if _, err = u.categoryDao.GetCategoryById(ctx, defaultCategoryId, userId, tx); err != nil {
+				return ledger.UserPreferences{}, err
+			}
+		}
+	}
+
+	return ledger.NewUserPreferences(displayName, currency, defaultAccountId, defaultCategoryId)
+}
+
+// getUserProfileTx returns the profile of a user, according to which the dates of the user are read and shown.
+func getUserProfileTx(ctx context.Context, userDao dao.UserDao, userId ledger.UserId, tx *sql.Tx) (ledger.UserProfile, error) {
+	user, err := userDao.GetUserByIdTx(ctx, userId, tx)
+	if err != nil {
+		return ledger.UserProfile{}, err
+	}
+	return user.Profile(), nil
+}
+
+func (u userService) RequestDeletion(ctx context.Context) (UserDeletionTokenResponse, error) {
+	var (
+		userId   ledger.UserId
+		tx       *sql.Tx
+		token    string
+		deletion dao.UserDeletion
+		err      error
+		now      = time.Now().UTC()
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return UserDeletionTokenResponse{}, err
+	}
+
+	if token, err = makeOneTimeToken(); err != nil {
+		return UserDeletionTokenResponse{}, pkg.NewSystemError(pkg.ErrUnknown, "Failed to create confirmation token", err)
+	}
+
+	if tx, err = u.userDao.BeginTx(); err != nil {
+		return UserDeletionTokenResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("RequestDeletion: %d", userId))
+
+	// A deletion that has already been confirmed stays scheduled until it is cancelled
+	if deletion, _, err = u.userDao.GetDeletionTx(ctx, userId, tx); err != nil {
+		return UserDeletionTokenResponse{}, err
+	}
+
+	deletion.UserId = userId
+	deletion.TokenHash = hashOneTimeToken(token)
+	deletion.TokenExpiresAt = now.Add(userDeletionTokenValidity)
+	deletion.RequestedAt = now
+
+	if err = u.userDao.SaveDeletionTx(ctx, deletion, tx); err != nil {
+		return UserDeletionTokenResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return UserDeletionTokenResponse{}, err
+	}
+
+	return UserDeletionTokenResponse{
+		ConfirmationToken: token,
+		ExpiresAt:         deletion.TokenExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+func (u userService) ConfirmDeletion(ctx context.Context, confirmationToken string) (UserDeletionResponse, error) {
+	var (
+		userId    ledger.UserId
+		tx        *sql.Tx
+		deletion  dao.UserDeletion
+		requested bool
+		err       error
+		now       = time.Now().UTC()
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return UserDeletionResponse{}, err
+	}
+
+	if tx, err = u.userDao.BeginTx(); err != nil {
+		return UserDeletionResponse{}, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("ConfirmDeletion: %d", userId))
+
+	invalidToken := pkg.ValidationErrorWithFields(pkg.ErrUserDeletionTokenInvalid, "Confirmation token is invalid or has expired", nil, map[string]string{
+		"confirmationToken": "confirmationToken must be the latest token returned when the deletion was requested",
+	})
+
+	if deletion, requested, err = u.userDao.GetDeletionTx(ctx, userId, tx); err != nil {
+		return UserDeletionResponse{}, err
+	}
+
+	if !requested || subtle.ConstantTimeCompare([]byte(deletion.TokenHash), []byte(hashOneTimeToken(confirmationToken))) != 1 || !now.Before(deletion.TokenExpiresAt) {
+		return UserDeletionResponse{}, invalidToken
+	}
+
+	if deletion.DeleteAfter == nil {
+		deleteAfter := now.Add(u.deletionGracePeriod)
+		deletion.ConfirmedAt = &now
+		deletion.DeleteAfter = &deleteAfter
+
+		if err = u.userDao.SaveDeletionTx(ctx, deletion, tx); err != nil {
+			return UserDeletionResponse{}, err
+		}
+	}
+
+	// The user can still log in to cancel the deletion, but sessions that were open before it was confirmed can not be refreshed
+	if err = u.userDao.RevokeRefreshTokensTx(ctx, userId, tx); err != nil {
+		return UserDeletionResponse{}, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return UserDeletionResponse{}, err
+	}
+
+	return UserDeletionResponse{
+		DeleteAfter: deletion.DeleteAfter.UTC().Format(time.RFC3339),
 	}, nil
 }
+
+func (u userService) CancelDeletion(ctx context.Context) error {
+	var (
+		userId ledger.UserId
+		tx     *sql.Tx
+		err    error
+	)
+
+	if userId, err = RequireUserId(ctx); err != nil {
+		return err
+	}
+
+	if tx, err = u.userDao.BeginTx(); err != nil {
+		return err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("CancelDeletion: %d", userId))
+
+	if err = u.userDao.DeleteDeletionTx(ctx, userId, tx); err != nil {
+		return err
+	}
+
+	return dao.Commit(tx)
+}
+
+func (u userService) DeleteDueUsers(ctx context.Context, now time.Time) (int, error) {
+	var (
+		tx      *sql.Tx
+		userIds []ledger.UserId
+		err     error
+	)
+
+	if tx, err = u.userDao.BeginTx(); err != nil {
+		return 0, err
+	}
+
+	defer dao.DeferRollback(tx, "GetUsersDueForDeletion")
+
+	if userIds, err = u.userDao.GetUsersDueForDeletionTx(ctx, now, tx); err != nil {
+		return 0, err
+	}
+
+	if err = dao.Commit(tx); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, userId := range userIds {
+		var ok bool
+		if ok, err = u.deleteUser(ctx, userId, now); err != nil {
+			log.Printf("Failed to delete user %d. Reason: %s", userId, err)
+			continue
+		}
+		if ok {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// deleteUser deletes a user if their deletion is still due; it may have been cancelled since the due users were loaded.
+func (u userService) deleteUser(ctx context.Context, userId ledger.UserId, now time.Time) (bool, error) {
+	tx, err := u.userDao.BeginTx()
+	if err != nil {
+		return false, err
+	}
+
+	defer dao.DeferRollback(tx, fmt.Sprintf("DeleteUser: %d", userId))
+
+	deleted, err := u.userDao.DeleteDueTx(ctx, userId, now, tx)
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, dao.Commit(tx)
+}
+
+// makeOneTimeToken returns a random token with which a user confirms the deletion of their account or resets their password.
+// Only its hash, see hashOneTimeToken, is kept.
+func makeOneTimeToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+func hashOneTimeToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
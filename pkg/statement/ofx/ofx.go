@@ -0,0 +1,176 @@
+// Package ofx parses bank and credit card statements exported as OFX or QFX files.
+// Both the SGML (OFX 1.x) and XML (OFX 2.x) flavours are supported.
+package ofx
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/statement"
+)
+
+// Parse reads the transactions of an OFX or QFX file.
+// Transactions without a FITID are assigned an external id derived from their details.
+func Parse(r io.Reader) (statement.Statement, error) {
+	var (
+		content []byte
+		err     error
+	)
+
+	if content, err = ioutil.ReadAll(r); err != nil {
+		return statement.Statement{}, invalidFile("File could not be read", err)
+	}
+
+	body := string(content)
+	start := strings.Index(strings.ToUpper(body), "<OFX>")
+	if start < 0 {
+		return statement.Statement{}, invalidFile("File is not an OFX file", nil)
+	}
+
+	var (
+		stmt        = statement.Statement{Transactions: []statement.Transaction{}}
+		transaction *transactionFields
+	)
+
+	for _, t := range tokenize(body[start:]) {
+		switch {
+		case t.name == "STMTTRN" && !t.closing:
+			transaction = &transactionFields{}
+		case t.name == "STMTTRN" && t.closing:
+			if transaction == nil {
+				continue
+			}
+			var parsed statement.Transaction
+			if parsed, err = transaction.parse(len(stmt.Transactions) + 1); err != nil {
+				return statement.Statement{}, err
+			}
+			stmt.Transactions = append(stmt.Transactions, parsed)
+			transaction = nil
+		case t.name == "CURDEF" && len(stmt.Currency) == 0:
+			stmt.Currency = strings.ToUpper(t.value)
+		case transaction != nil && !t.closing:
+			transaction.set(t.name, t.value)
+		}
+	}
+
+	statement.AssignExternalIds(stmt.Transactions)
+	return stmt, nil
+}
+
+type transactionFields struct {
+	fitId  string
+	posted string
+	amount string
+	name   string
+	memo   string
+}
+
+func (f *transactionFields) set(name string, value string) {
+	switch name {
+	case "FITID":
+		f.fitId = value
+	case "DTPOSTED":
+		f.posted = value
+	case "TRNAMT":
+		f.amount = value
+	case "NAME":
+		// NAME is also the name of the PAYEE aggregate, when a transaction has one
+		f.name = value
+	case "MEMO":
+		f.memo = value
+	}
+}
+
+func (f *transactionFields) parse(number int) (statement.Transaction, error) {
+	var (
+		date time.Time
+		err  error
+	)
+
+	if date, err = parseDate(f.posted); err != nil {
+		return statement.Transaction{}, invalidFile(fmt.Sprintf("Transaction %d has an invalid DTPOSTED %q", number, f.posted), err)
+	}
+
+	amount := strings.TrimSpace(f.amount)
+	if len(amount) == 0 {
+		return statement.Transaction{}, invalidFile(fmt.Sprintf("Transaction %d has no TRNAMT", number), nil)
+	}
+	// Some banks use a comma as the decimal separator
+	if !strings.Contains(amount, ".") {
+		amount = strings.Replace(amount, ",", ".", 1)
+	}
+
+	return statement.Transaction{
+		ExternalId: f.fitId,
+		Date:       date,
+		Amount:     strings.TrimPrefix(amount, "+"),
+		Payee:      f.name,
+		Memo:       f.memo,
+	}, nil
+}
+
+// parseDate reads the date of an OFX datetime e.g. 20210703, 20210703120000 or 20210703120000.000[-5:EST].
+// The time and the timezone are ignored, since a record is kept on the date the bank posted the transaction.
+func parseDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("date must start with YYYYMMDD")
+	}
+	return time.Parse("20060102", value[:8])
+}
+
+type token struct {
+	name    string
+	closing bool
+	value   string
+}
+
+// tokenize splits an OFX document into its tags and the values that follow them.
+// In SGML documents, elements holding a value are not closed, so the value of a tag is the text up to the next tag.
+func tokenize(body string) []token {
+	tokens := []token{}
+	for {
+		open := strings.Index(body, "<")
+		if open < 0 {
+			return tokens
+		}
+		end := strings.Index(body[open:], ">")
+		if end < 0 {
+			return tokens
+		}
+		tag := strings.TrimSpace(body[open+1 : open+end])
+		body = body[open+end+1:]
+
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		t := token{}
+		if strings.HasPrefix(tag, "/") {
+			t.closing = true
+			tag = tag[1:]
+		}
+		// Elements of XML documents may be self-closing
+		tag = strings.TrimSuffix(tag, "/")
+		if fields := strings.Fields(tag); len(fields) > 0 {
+			t.name = strings.ToUpper(fields[0])
+		}
+
+		if !t.closing {
+			next := strings.Index(body, "<")
+			if next < 0 {
+				next = len(body)
+			}
+			t.value = html.UnescapeString(strings.TrimSpace(body[:next]))
+		}
+		tokens = append(tokens, t)
+	}
+}
+
+func invalidFile(message string, err error) error {
+	return pkg.ValidationErrorWithFields(pkg.ErrImportValidation, message, err, map[string]string{"file": "file must be an OFX or QFX file"})
+}
@@ -0,0 +1,92 @@
+package ofx
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg/statement"
+)
+
+type OfxTestSuite struct {
+	suite.Suite
+}
+
+func TestOfxTestSuite(t *testing.T) {
+	suite.Run(t, new(OfxTestSuite))
+}
+
+func (suite *OfxTestSuite) parseFixture(name string) (statement.Statement, error) {
+	file, err := os.Open("testdata/" + name)
+	assert.Nil(suite.T(), err)
+	defer file.Close()
+	return Parse(file)
+}
+
+// -- SUITE
+
+func (suite *OfxTestSuite) Test_GIVEN_anSgmlOfxFile_WHEN_parsed_THEN_transactionsAreRead() {
+	// WHEN
+	stmt, err := suite.parseFixture("statement_v1.ofx")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "AED", stmt.Currency)
+	assert.Equal(suite.T(), []statement.Transaction{
+		{
+			ExternalId: "202107030001",
+			Date:       time.Date(2021, time.July, 3, 0, 0, 0, 0, time.UTC),
+			Amount:     "-1234.50",
+			Payee:      "CARREFOUR MOE",
+			Memo:       "Card purchase",
+		},
+		{
+			ExternalId: "202107250001",
+			Date:       time.Date(2021, time.July, 25, 0, 0, 0, 0, time.UTC),
+			Amount:     "20000.00",
+			Payee:      "SALARY & ALLOWANCES",
+		},
+	}, stmt.Transactions)
+}
+
+func (suite *OfxTestSuite) Test_GIVEN_anXmlQfxFile_WHEN_parsed_THEN_transactionsAreRead() {
+	// WHEN
+	stmt, err := suite.parseFixture("statement_v2.qfx")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "USD", stmt.Currency)
+	assert.Len(suite.T(), stmt.Transactions, 2)
+
+	assert.Equal(suite.T(), "ABC-1", stmt.Transactions[0].ExternalId)
+	assert.Equal(suite.T(), "-45.00", stmt.Transactions[0].Amount)
+	assert.Equal(suite.T(), "Cinema", stmt.Transactions[0].Payee)
+
+	// A transaction without a FITID is assigned an id derived from its details
+	assert.NotEmpty(suite.T(), stmt.Transactions[1].ExternalId)
+	assert.Equal(suite.T(), "Coffee", stmt.Transactions[1].Note())
+	again, _ := suite.parseFixture("statement_v2.qfx")
+	assert.Equal(suite.T(), stmt.Transactions[1].ExternalId, again.Transactions[1].ExternalId)
+}
+
+func (suite *OfxTestSuite) Test_GIVEN_invalidFiles_WHEN_parsed_THEN_errorIsReturned() {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "not ofx", content: "Date,Amount\n03/07/2021,10"},
+		{name: "invalid date", content: "<OFX><STMTTRN><DTPOSTED>2021<TRNAMT>1.00</STMTTRN></OFX>"},
+		{name: "no amount", content: "<OFX><STMTTRN><DTPOSTED>20210703</STMTTRN></OFX>"},
+	}
+
+	for _, tc := range testCases {
+		// WHEN
+		_, err := Parse(strings.NewReader(tc.content))
+
+		// THEN
+		assert.NotNil(suite.T(), err, tc.name)
+	}
+}
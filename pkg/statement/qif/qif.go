@@ -0,0 +1,158 @@
+// Package qif parses bank and credit card statements exported as Quicken Interchange Format (QIF) files.
+package qif
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/statement"
+)
+
+// DateOrder is the order of the day and the month in the dates of a QIF file, which is not part of the format.
+type DateOrder int
+
+const (
+	// MonthFirst is the order used by Quicken e.g. 07/03/2021 or 7/3'21 for the 3rd of July
+	MonthFirst DateOrder = iota
+	DayFirst
+)
+
+// Types of the accounts whose transactions are read. Other sections e.g. !Type:Cat or !Account are skipped.
+var transactionTypes = map[string]bool{
+	"!TYPE:BANK":  true,
+	"!TYPE:CASH":  true,
+	"!TYPE:CCARD": true,
+	"!TYPE:OTH A": true,
+	"!TYPE:OTH L": true,
+}
+
+var datePattern = regexp.MustCompile(`^(\d{1,2})[/.\-](\d{1,2})\s*[/.\-']\s*(\d{2}|\d{4})$`)
+
+// Parse reads the transactions of a QIF file.
+// QIF transactions have no id, so each is assigned an external id derived from its details.
+func Parse(r io.Reader, dateOrder DateOrder) (statement.Statement, error) {
+	var (
+		stmt          = statement.Statement{Transactions: []statement.Transaction{}}
+		transaction   statement.Transaction
+		hasFields     bool
+		inTransaction bool
+		foundType     bool
+		lineNumber    int
+		err           error
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNumber == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			header := strings.ToUpper(strings.Join(strings.Fields(line), " "))
+			inTransaction = transactionTypes[header]
+			foundType = foundType || inTransaction
+			transaction, hasFields = statement.Transaction{}, false
+			continue
+		}
+		if !inTransaction {
+			continue
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+		switch code {
+		case '^':
+			if hasFields {
+				if err = validate(transaction, lineNumber); err != nil {
+					return statement.Statement{}, err
+				}
+				stmt.Transactions = append(stmt.Transactions, transaction)
+			}
+			transaction, hasFields = statement.Transaction{}, false
+			continue
+		case 'D':
+			if transaction.Date, err = parseDate(value, dateOrder); err != nil {
+				return statement.Statement{}, invalidFile(fmt.Sprintf("Line %d has an invalid date %q", lineNumber, value), err)
+			}
+		case 'T', 'U':
+			transaction.Amount = strings.ReplaceAll(value, ",", "")
+		case 'P':
+			transaction.Payee = value
+		case 'M':
+			transaction.Memo = value
+		case 'L':
+			// Transfers are written as the name of the other account in brackets
+			if !strings.HasPrefix(value, "[") {
+				transaction.Category = value
+			}
+		}
+		hasFields = true
+	}
+
+	if err = scanner.Err(); err != nil {
+		return statement.Statement{}, invalidFile("File could not be read", err)
+	}
+	if !foundType {
+		return statement.Statement{}, invalidFile("File is not a QIF file of a bank, cash or credit card account", nil)
+	}
+
+	statement.AssignExternalIds(stmt.Transactions)
+	return stmt, nil
+}
+
+func validate(t statement.Transaction, lineNumber int) error {
+	if t.Date.IsZero() {
+		return invalidFile(fmt.Sprintf("Transaction ending on line %d has no date", lineNumber), nil)
+	}
+	if len(t.Amount) == 0 {
+		return invalidFile(fmt.Sprintf("Transaction ending on line %d has no amount", lineNumber), nil)
+	}
+	return nil
+}
+
+// parseDate reads dates such as 07/03/2021, 7/3/21, 7/3'21 or 07-03-2021.
+// Two-digit years separated by an apostrophe are in the 2000s, as written by Quicken.
+// Other two-digit years are in the 2000s up to 49 and in the 1900s from 50.
+func parseDate(value string, dateOrder DateOrder) (time.Time, error) {
+	match := datePattern.FindStringSubmatch(value)
+	if match == nil {
+		return time.Time{}, fmt.Errorf("date must be written as month/day/year or day/month/year")
+	}
+
+	first, _ := strconv.Atoi(match[1])
+	second, _ := strconv.Atoi(match[2])
+	year, _ := strconv.Atoi(match[3])
+
+	if len(match[3]) == 2 {
+		if strings.Contains(value, "'") || year < 50 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+
+	month, day := first, second
+	if dateOrder == DayFirst {
+		month, day = second, first
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if month < 1 || month > 12 || date.Day() != day {
+		return time.Time{}, fmt.Errorf("%q is not a valid date", value)
+	}
+	return date, nil
+}
+
+func invalidFile(message string, err error) error {
+	return pkg.ValidationErrorWithFields(pkg.ErrImportValidation, message, err, map[string]string{"file": "file must be a QIF file"})
+}
@@ -0,0 +1,88 @@
+package qif
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg/statement"
+)
+
+type QifTestSuite struct {
+	suite.Suite
+}
+
+func TestQifTestSuite(t *testing.T) {
+	suite.Run(t, new(QifTestSuite))
+}
+
+func (suite *QifTestSuite) parseFixture(name string, dateOrder DateOrder) (statement.Statement, error) {
+	file, err := os.Open("testdata/" + name)
+	assert.Nil(suite.T(), err)
+	defer file.Close()
+	return Parse(file, dateOrder)
+}
+
+// -- SUITE
+
+func (suite *QifTestSuite) Test_GIVEN_aBankQifFile_WHEN_parsed_THEN_transactionsAreRead() {
+	// WHEN
+	stmt, err := suite.parseFixture("statement.qif", MonthFirst)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "", stmt.Currency)
+	assert.Len(suite.T(), stmt.Transactions, 4)
+
+	assert.Equal(suite.T(), time.Date(2021, time.July, 3, 0, 0, 0, 0, time.UTC), stmt.Transactions[0].Date)
+	assert.Equal(suite.T(), "-1234.50", stmt.Transactions[0].Amount)
+	assert.Equal(suite.T(), "Carrefour", stmt.Transactions[0].Payee)
+	assert.Equal(suite.T(), "Card purchase", stmt.Transactions[0].Memo)
+	assert.Equal(suite.T(), "Food:Groceries", stmt.Transactions[0].Category)
+
+	assert.Equal(suite.T(), time.Date(2021, time.July, 25, 0, 0, 0, 0, time.UTC), stmt.Transactions[1].Date)
+	assert.Equal(suite.T(), "20000.00", stmt.Transactions[1].Amount)
+
+	// Transfers have no category
+	assert.Equal(suite.T(), "", stmt.Transactions[2].Category)
+
+	// Identical transactions are told apart
+	assert.NotEqual(suite.T(), stmt.Transactions[2].ExternalId, stmt.Transactions[3].ExternalId)
+
+	again, _ := suite.parseFixture("statement.qif", MonthFirst)
+	assert.Equal(suite.T(), stmt.Transactions, again.Transactions)
+}
+
+func (suite *QifTestSuite) Test_GIVEN_dayFirstDates_WHEN_parsed_THEN_dayIsReadFirst() {
+	// WHEN
+	stmt, err := Parse(strings.NewReader("!Type:Bank\nD07/03/2021\nT-10.00\n^\n"), DayFirst)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), time.Date(2021, time.March, 7, 0, 0, 0, 0, time.UTC), stmt.Transactions[0].Date)
+}
+
+func (suite *QifTestSuite) Test_GIVEN_invalidFiles_WHEN_parsed_THEN_errorIsReturned() {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "no transactions section", content: "!Type:Cat\nNFood\n^\n"},
+		{name: "invalid date", content: "!Type:Bank\nD13/45/2021\nT10.00\n^\n"},
+		{name: "no amount", content: "!Type:Bank\nD07/03/2021\nPShop\n^\n"},
+	}
+
+	for _, tc := range testCases {
+		// WHEN
+		_, err := Parse(strings.NewReader(tc.content), MonthFirst)
+
+		// THEN
+		assert.NotNil(suite.T(), err, tc.name)
+	}
+
+	_, err := suite.parseFixture("categories.qif", MonthFirst)
+	assert.NotNil(suite.T(), err)
+}
@@ -0,0 +1,144 @@
+// Package statement holds the transactions of bank statements exported as OFX/QFX or QIF files,
+// and turns them into records of the account they are imported into.
+package statement
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+// maxNoteLength is the length of the longest note of a record
+const maxNoteLength = 50
+
+// Statement is the list of transactions of an account, as exported by a bank.
+type Statement struct {
+	// Currency is the ISO code of the currency of the statement.
+	// It is empty when the format does not include it e.g. QIF.
+	Currency     string
+	Transactions []Transaction
+}
+
+// Transaction is a transaction of a bank statement.
+type Transaction struct {
+	// ExternalId identifies the transaction at the bank e.g. the FITID of an OFX transaction.
+	// A transaction is only imported once into an account.
+	ExternalId string
+	Date       time.Time
+	// Amount is a decimal number with a '.' decimal separator. Debits are negative.
+	Amount string
+	Payee  string
+	Memo   string
+	// Category is the category of the transaction in the file, if any.
+	Category string
+}
+
+// Note is the payee of the transaction or its memo if there is no payee, shortened to the length of the note of a record.
+func (t Transaction) Note() string {
+	note := strings.TrimSpace(t.Payee)
+	if len(note) == 0 {
+		note = strings.TrimSpace(t.Memo)
+	}
+	if utf8.RuneCountInString(note) > maxNoteLength {
+		note = strings.TrimSpace(string([]rune(note)[:maxNoteLength]))
+	}
+	return note
+}
+
+// Record makes the record of a transaction in an account in the given currency.
+// Credits are recorded as income and debits as expenses.
+func (t Transaction) Record(
+	id ledger.RecordId,
+	currencyCode string,
+	category ledger.Category,
+	updatedBy ledger.UpdatedBy,
+) (ledger.Record, error) {
+	var (
+		amount     ledger.Money
+		recordType = ledger.Income
+		err        error
+	)
+
+	if amount, err = ledger.ParseMoney(currencyCode, t.Amount); err != nil {
+		return ledger.Record{}, pkg.ValidationErrorWithFields(
+			pkg.ErrImportValidation,
+			"",
+			err,
+			map[string]string{"amount": fmt.Sprintf("amount '%s' is not a number", t.Amount)},
+		)
+	}
+	if amount.IsNegative() {
+		recordType = ledger.Expense
+	}
+
+	sourceAccountId, beneficiaryId, transferReference := ledger.NoTransfer()
+	return ledger.NewRecord(
+		id,
+		t.Note(),
+		category,
+		amount,
+		t.Date,
+		recordType,
+		sourceAccountId,
+		beneficiaryId,
+		ledger.NoBeneficiaryType,
+		transferReference,
+		updatedBy,
+	)
+}
+
+// AssignExternalIds gives the transactions that have no external id one that is derived from their details,
+// so that the same file can be imported again without creating its transactions twice.
+// Identical transactions of a file are told apart by the order in which they appear.
+func AssignExternalIds(transactions []Transaction) {
+	occurrences := map[string]int{}
+	for i, t := range transactions {
+		if len(t.ExternalId) > 0 {
+			continue
+		}
+		sum := sha256.Sum256([]byte(strings.Join([]string{
+			t.Date.Format("2006-01-02"),
+			t.Amount,
+			t.Payee,
+			t.Memo,
+			t.Category,
+		}, "|")))
+		hash := fmt.Sprintf("%x", sum[:16])
+		occurrences[hash] += 1
+		transactions[i].ExternalId = fmt.Sprintf("%s-%d", hash, occurrences[hash])
+	}
+}
+
+// CategoryResolver finds the category of a transaction by name, ignoring case.
+// Categories of the form "Parent:Child" are matched by their full name and then by the name of the child category.
+// Transactions whose category is not found are recorded in the fallback category.
+type CategoryResolver struct {
+	byName   map[string]ledger.Category
+	fallback ledger.Category
+}
+
+func NewCategoryResolver(categories ledger.Categories, fallback ledger.Category) CategoryResolver {
+	byName := map[string]ledger.Category{}
+	for _, category := range categories {
+		byName[strings.ToLower(category.Name())] = category
+	}
+	return CategoryResolver{byName: byName, fallback: fallback}
+}
+
+func (r CategoryResolver) Resolve(name string) ledger.Category {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if category, ok := r.byName[name]; ok {
+		return category
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		if category, ok := r.byName[strings.TrimSpace(name[i+1:])]; ok {
+			return category
+		}
+	}
+	return r.fallback
+}
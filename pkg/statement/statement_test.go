@@ -0,0 +1,60 @@
+package statement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+)
+
+type StatementTestSuite struct {
+	suite.Suite
+	groceries     ledger.Category
+	uncategorized ledger.Category
+}
+
+func TestStatementTestSuite(t *testing.T) {
+	suite.Run(t, new(StatementTestSuite))
+}
+
+func (suite *StatementTestSuite) SetupTest() {
+	suite.groceries, _ = ledger.NewCategory(ledger.CategoryId(1), "Groceries", ledger.MustMakeUpdatedByUserId(ledger.UserId(1)))
+	suite.uncategorized, _ = ledger.NewCategory(ledger.CategoryId(2), "Uncategorized", ledger.MustMakeUpdatedByUserId(ledger.UserId(1)))
+}
+
+// -- SUITE
+
+func (suite *StatementTestSuite) Test_GIVEN_transactions_WHEN_recorded_THEN_debitsAreExpensesAndCreditsAreIncome() {
+	// GIVEN
+	updatedBy := ledger.MustMakeUpdatedByImport("statement.ofx", "1")
+	debit := Transaction{ExternalId: "1", Date: time.Date(2021, time.July, 3, 0, 0, 0, 0, time.UTC), Amount: "-1234.50", Memo: "Carrefour"}
+	credit := Transaction{ExternalId: "2", Date: time.Date(2021, time.July, 25, 0, 0, 0, 0, time.UTC), Amount: "20000", Payee: "A payee whose name is longer than the note of a record can be"}
+
+	// WHEN
+	debitRecord, debitErr := debit.Record(ledger.RecordId(1), "AED", suite.groceries, updatedBy)
+	creditRecord, creditErr := credit.Record(ledger.RecordId(2), "AED", suite.uncategorized, updatedBy)
+
+	// THEN
+	assert.Nil(suite.T(), debitErr)
+	assert.Equal(suite.T(), ledger.Expense, debitRecord.Type())
+	assert.Equal(suite.T(), int64(-123450), debitRecord.Amount().MustMinorUnits())
+	assert.Equal(suite.T(), "Carrefour", debitRecord.Note())
+	assert.Equal(suite.T(), updatedBy, debitRecord.CreatedBy())
+
+	assert.Nil(suite.T(), creditErr)
+	assert.Equal(suite.T(), ledger.Income, creditRecord.Type())
+	assert.Equal(suite.T(), "A payee whose name is longer than the note of a re", creditRecord.Note())
+}
+
+func (suite *StatementTestSuite) Test_GIVEN_categoryNames_WHEN_resolved_THEN_unknownCategoriesFallBack() {
+	// GIVEN
+	resolver := NewCategoryResolver(ledger.Categories{suite.groceries, suite.uncategorized}, suite.uncategorized)
+
+	// THEN
+	assert.Equal(suite.T(), suite.groceries, resolver.Resolve("groceries"))
+	assert.Equal(suite.T(), suite.groceries, resolver.Resolve("Food:Groceries"))
+	assert.Equal(suite.T(), suite.uncategorized, resolver.Resolve("Entertainment"))
+	assert.Equal(suite.T(), suite.uncategorized, resolver.Resolve(""))
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+type AlertHandlerTestSuite struct {
+	suite.Suite
+	simulatedUser           ledger.User
+	simulatedCurrentAccount ledger.Account
+	simulatedBillsCategory  ledger.Category
+	notifier                *svc.InMemoryAlertNotifier
+}
+
+func TestAlertHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(AlertHandlerTestSuite))
+}
+
+// -- SETUP
+
+func (suite *AlertHandlerTestSuite) SetupTest() {
+
+	aUser, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+
+	currentAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787222),
+		"Current",
+		ledger.AccountTypeCurrent,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	billsCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305041),
+		"Bills",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+
+	if err := UserDao.Save(aUser); err != nil {
+		log.Fatalf("AlertHandlerTestSuite: Test setup failed: %s", err)
+	}
+
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), aUser.Id(), ledger.Accounts{currentAccount}, tx)
+	_ = CategoryDao.SaveTx(context.Background(), aUser.Id(), ledger.Categories{billsCategory}, tx)
+	_ = tx.Commit()
+
+	suite.simulatedUser = aUser
+	suite.simulatedCurrentAccount = currentAccount
+	suite.simulatedBillsCategory = billsCategory
+	suite.notifier = TestApp.AlertNotifier.(*svc.InMemoryAlertNotifier)
+	suite.notifier.Reset()
+}
+
+func (suite *AlertHandlerTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down AlertHandlerTestSuite: %s", err)
+	}
+}
+
+func (suite *AlertHandlerTestSuite) createBudget(alertThresholds string) {
+	w := SendAsUser(suite.simulatedUser.Id(), "POST", "/api/v1/budgets", fmt.Sprintf(`{
+		"accountIds": [%d],
+		"period": "Month",
+		"categoryBudgets": [{"categoryId": %d, "maxAmount": {"currency": "AED", "value": 100000}, "alertThresholds": %s}]
+	}`, suite.simulatedCurrentAccount.Id(), suite.simulatedBillsCategory.Id(), alertThresholds))
+	assert.Equal(suite.T(), 201, w.Code)
+}
+
+func (suite *AlertHandlerTestSuite) createExpense(value int64, date string) {
+	CreateRecord(suite.T(), suite.simulatedUser.Id(), suite.simulatedCurrentAccount.Id(), TestRecord{
+		Note:     "Electricity",
+		Category: suite.simulatedBillsCategory,
+		Amount:   value,
+		Date:     date,
+		Type:     ledger.Expense,
+	})
+}
+
+func (suite *AlertHandlerTestSuite) getAlerts() svc.BudgetAlertsResponse {
+	w := SendAsUser(suite.simulatedUser.Id(), "GET", "/api/v1/alerts", "")
+
+	var alertsResponse svc.BudgetAlertsResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &alertsResponse))
+	return alertsResponse
+}
+
+// -- SUITE
+
+func (suite *AlertHandlerTestSuite) Test_GIVEN_expensesThatCrossThresholds_WHEN_recordsAreCreated_THEN_anAlertIsRaisedOncePerThresholdInThePeriod() {
+	// GIVEN
+	suite.createBudget("[50, 80, 100]")
+
+	// WHEN
+	suite.createExpense(400_00, "2021-07-05T10:00:00+00:00")
+	suite.createExpense(450_00, "2021-07-10T10:00:00+00:00")
+	suite.createExpense(10_00, "2021-07-12T10:00:00+00:00")
+
+	// THEN
+	alertsResponse := suite.getAlerts()
+	assert.Len(suite.T(), alertsResponse.Alerts, 2)
+
+	assert.Equal(suite.T(), uint(80), alertsResponse.Alerts[0].Threshold)
+	assert.Equal(suite.T(), uint(50), alertsResponse.Alerts[1].Threshold)
+	for _, alert := range alertsResponse.Alerts {
+		assert.Equal(suite.T(), uint64(suite.simulatedBillsCategory.Id()), alert.CategoryId)
+		assert.Equal(suite.T(), svc.BudgetPeriodResponse{Type: "Month", From: "2021-07-01", To: "2021-07-31"}, alert.Period)
+		assert.Equal(suite.T(), svc.AmountResponse{Currency: "AED", Value: 850_00}, alert.Spent)
+		assert.Equal(suite.T(), svc.AmountResponse{Currency: "AED", Value: 1000_00}, alert.MaxAmount)
+	}
+
+	notified := suite.notifier.Alerts()
+	assert.Len(suite.T(), notified, 2)
+}
+
+func (suite *AlertHandlerTestSuite) Test_GIVEN_aThresholdReachedInAPreviousPeriod_WHEN_itIsReachedInTheNextPeriod_THEN_anotherAlertIsRaised() {
+	// GIVEN
+	suite.createBudget("[50]")
+	suite.createExpense(600_00, "2021-07-05T10:00:00+00:00")
+
+	// WHEN
+	suite.createExpense(600_00, "2021-08-05T10:00:00+00:00")
+
+	// THEN
+	alertsResponse := suite.getAlerts()
+	assert.Len(suite.T(), alertsResponse.Alerts, 2)
+	assert.Equal(suite.T(), "2021-08-01", alertsResponse.Alerts[0].Period.From)
+	assert.Equal(suite.T(), "2021-07-01", alertsResponse.Alerts[1].Period.From)
+	assert.Len(suite.T(), suite.notifier.Alerts(), 2)
+}
+
+func (suite *AlertHandlerTestSuite) Test_GIVEN_aBudgetWithoutAlertThresholds_WHEN_budgetIsExceeded_THEN_noAlertIsRaised() {
+	// GIVEN
+	suite.createBudget("[]")
+
+	// WHEN
+	suite.createExpense(1200_00, "2021-07-05T10:00:00+00:00")
+
+	// THEN
+	assert.Empty(suite.T(), suite.getAlerts().Alerts)
+	assert.Empty(suite.T(), suite.notifier.Alerts())
+}
@@ -0,0 +1,376 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+type AuthHandlerTestSuite struct {
+	suite.Suite
+	userId   ledger.UserId
+	notifier *svc.InMemoryPasswordResetNotifier
+}
+
+func TestAuthHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(AuthHandlerTestSuite))
+}
+
+// -- SETUP
+
+func (suite *AuthHandlerTestSuite) SetupTest() {
+	r, _ := http.NewRequest("POST", "/api/v1/user", bytes.NewBufferString(`{"email":"jack.torrence@theoverlook.com","password":"all work and no play"}`))
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	var response svc.CreateUserResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusCreated {
+		log.Fatalf("AuthHandlerTestSuite: Test setup failed: %d %s", w.Code, w.Body.String())
+	}
+	suite.userId = response.Id
+	suite.notifier = TestApp.PasswordResetNotifier.(*svc.InMemoryPasswordResetNotifier)
+	suite.notifier.Reset()
+}
+
+// -- TEARDOWN
+
+func (suite *AuthHandlerTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down AuthHandlerTestSuite: %s", err)
+	}
+}
+
+// -- SUITE
+
+func (suite *AuthHandlerTestSuite) post(path string, body string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+func (suite *AuthHandlerTestSuite) getUser(authorization string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("GET", "/api/v1/user", nil)
+	r.Header.Set("Authorization", authorization)
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+func (suite *AuthHandlerTestSuite) setPassword(authorization string, body string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("PUT", "/api/v1/user/password", bytes.NewBufferString(body))
+	r.Header.Set("Authorization", authorization)
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+// requestPasswordReset returns the reset token sent to the email
+func (suite *AuthHandlerTestSuite) requestPasswordReset(email string) string {
+	w := suite.post("/api/v1/auth/password-reset", fmt.Sprintf(`{"email":%q}`, email))
+	assert.Equal(suite.T(), 202, w.Code)
+
+	notices := suite.notifier.Notices()
+	if !assert.NotEmpty(suite.T(), notices) {
+		return ""
+	}
+	assert.Equal(suite.T(), email, notices[len(notices)-1].Email)
+	return notices[len(notices)-1].Token
+}
+
+func (suite *AuthHandlerTestSuite) login() svc.TokenResponse {
+	w := suite.post("/api/v1/auth/login", `{"email":"jack.torrence@theoverlook.com","password":"all work and no play"}`)
+
+	var response svc.TokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusOK {
+		log.Fatalf("AuthHandlerTestSuite: Login failed: %d %s", w.Code, w.Body.String())
+	}
+	return response
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_theCorrectPassword_WHEN_loginEndpointIsCalled_THEN_accessTokenAuthenticatesTheUser() {
+	// WHEN
+	w := suite.post("/api/v1/auth/login", `{"email":"jack.torrence@theoverlook.com","password":"all work and no play"}`)
+
+	// THEN
+	var response svc.TokenResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Equal(suite.T(), "no-store", w.Header().Get("Cache-Control"))
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(suite.T(), "Bearer", response.TokenType)
+	assert.Equal(suite.T(), int64(15*60), response.ExpiresIn)
+	assert.NotEmpty(suite.T(), response.RefreshToken)
+
+	w = suite.getUser("Bearer " + response.AccessToken)
+
+	var user svc.UserResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &user))
+	assert.Equal(suite.T(), suite.userId, user.Id)
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_theWrongPasswordOrAnUnknownEmail_WHEN_loginEndpointIsCalled_THEN_401IsReturned() {
+	for _, body := range []string{
+		`{"email":"jack.torrence@theoverlook.com","password":"all play and no work"}`,
+		`{"email":"danny.torrence@theoverlook.com","password":"all work and no play"}`,
+		`{"email":"jack.torrence","password":"all work and no play"}`,
+		`{}`,
+	} {
+		// WHEN
+		w := suite.post("/api/v1/auth/login", body)
+
+		// THEN
+		assert.Equal(suite.T(), 401, w.Code, body)
+		assert.Contains(suite.T(), w.Body.String(), "AUTHENTICATION_FAILED", body)
+		assert.Contains(suite.T(), w.Body.String(), "Email or password is incorrect", body)
+	}
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_aRefreshToken_WHEN_refreshEndpointIsCalled_THEN_newAccessTokenAuthenticatesTheUser() {
+	// GIVEN
+	tokens := suite.login()
+
+	// WHEN
+	w := suite.post("/api/v1/auth/refresh", fmt.Sprintf(`{"refreshToken":%q}`, tokens.RefreshToken))
+
+	// THEN
+	var response svc.TokenResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotEmpty(suite.T(), response.RefreshToken)
+	assert.Equal(suite.T(), 200, suite.getUser("Bearer "+response.AccessToken).Code)
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_aRefreshTokenThatWasExchanged_WHEN_itIsExchangedAgain_THEN_401IsReturnedAndTheRotatedTokenIsRevoked() {
+	// GIVEN
+	tokens := suite.login()
+	w := suite.post("/api/v1/auth/refresh", fmt.Sprintf(`{"refreshToken":%q}`, tokens.RefreshToken))
+	assert.Equal(suite.T(), 200, w.Code)
+
+	var rotated svc.TokenResponse
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &rotated))
+	assert.NotEqual(suite.T(), tokens.RefreshToken, rotated.RefreshToken)
+
+	// WHEN
+	w = suite.post("/api/v1/auth/refresh", fmt.Sprintf(`{"refreshToken":%q}`, tokens.RefreshToken))
+
+	// THEN
+	assert.Equal(suite.T(), 401, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "ACCESS_TOKEN_INVALID")
+
+	w = suite.post("/api/v1/auth/refresh", fmt.Sprintf(`{"refreshToken":%q}`, rotated.RefreshToken))
+	assert.Equal(suite.T(), 401, w.Code)
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_aRotatedRefreshToken_WHEN_itIsExchanged_THEN_newTokensAreReturned() {
+	// GIVEN
+	tokens := suite.login()
+	for i := 0; i < 2; i++ {
+		w := suite.post("/api/v1/auth/refresh", fmt.Sprintf(`{"refreshToken":%q}`, tokens.RefreshToken))
+		assert.Equal(suite.T(), 200, w.Code)
+		assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &tokens))
+	}
+
+	// WHEN
+	w := suite.post("/api/v1/auth/refresh", fmt.Sprintf(`{"refreshToken":%q}`, tokens.RefreshToken))
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_aRefreshToken_WHEN_theDeletionOfTheUserIsConfirmed_THEN_itIsRevoked() {
+	// GIVEN
+	tokens := suite.login()
+
+	var deletion svc.UserDeletionTokenResponse
+	w := SendAsUser(suite.userId, "DELETE", "/api/v1/user", "")
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &deletion))
+	w = SendAsUser(suite.userId, "DELETE", "/api/v1/user?confirmationToken="+deletion.ConfirmationToken, "")
+	assert.Equal(suite.T(), 202, w.Code)
+
+	// WHEN
+	w = suite.post("/api/v1/auth/refresh", fmt.Sprintf(`{"refreshToken":%q}`, tokens.RefreshToken))
+
+	// THEN
+	assert.Equal(suite.T(), 401, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "ACCESS_TOKEN_INVALID")
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_anAccessToken_WHEN_itIsUsedAsARefreshToken_THEN_401IsReturned() {
+	// GIVEN
+	tokens := suite.login()
+
+	// WHEN
+	w := suite.post("/api/v1/auth/refresh", fmt.Sprintf(`{"refreshToken":%q}`, tokens.AccessToken))
+
+	// THEN
+	assert.Equal(suite.T(), 401, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "ACCESS_TOKEN_INVALID")
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_aRefreshToken_WHEN_itIsUsedAsAnAccessToken_THEN_401IsReturned() {
+	// GIVEN
+	tokens := suite.login()
+
+	// WHEN
+	w := suite.getUser("Bearer " + tokens.RefreshToken)
+
+	// THEN
+	assert.Equal(suite.T(), 401, w.Code)
+	assert.Equal(suite.T(), "Bearer", w.Header().Get("WWW-Authenticate"))
+	assert.Contains(suite.T(), w.Body.String(), "ACCESS_TOKEN_INVALID")
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_aTamperedAccessToken_WHEN_endpointIsCalled_THEN_401IsReturned() {
+	// GIVEN
+	tokens := suite.login()
+	parts := strings.Split(tokens.AccessToken, ".")
+	// A token for user 2 signed with the signature of a token for another user
+	forged := parts[0] + ".eyJzdWIiOiIyIiwidHlwIjoiYWNjZXNzIiwiaWF0IjoxNjI1MDk3NjAwLCJleHAiOjQxMDI0NDQ4MDB9." + parts[2]
+
+	// WHEN
+	w := suite.getUser("Bearer " + forged)
+
+	// THEN
+	assert.Equal(suite.T(), 401, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "ACCESS_TOKEN_INVALID")
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_aMalformedAuthorizationHeader_WHEN_endpointIsCalled_THEN_401IsReturned() {
+	for _, authorization := range []string{
+		"Bearer",
+		"Bearer not-a-token",
+		"Basic amFjazphbGwgd29yaw==",
+		"jack",
+		"0",
+		"-1",
+	} {
+		// WHEN
+		w := suite.getUser(authorization)
+
+		// THEN
+		assert.Equal(suite.T(), 401, w.Code, authorization)
+		assert.Contains(suite.T(), w.Body.String(), "ACCESS_TOKEN_INVALID", authorization)
+	}
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_noAuthorizationHeader_WHEN_endpointRequiringAUserIsCalled_THEN_401IsReturned() {
+	// WHEN
+	r, _ := http.NewRequest("GET", "/api/v1/user", nil)
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 401, w.Code)
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_theCurrentPassword_WHEN_passwordIsChanged_THEN_userLogsInWithTheNewPasswordAndRefreshTokensAreRevoked() {
+	// GIVEN
+	tokens := suite.login()
+
+	// WHEN
+	w := suite.setPassword("Bearer "+tokens.AccessToken, `{"currentPassword":"all work and no play","password":"heeeres johnny"}`)
+
+	// THEN
+	assert.Equal(suite.T(), 204, w.Code)
+	assert.Equal(suite.T(), 200, suite.post("/api/v1/auth/login", `{"email":"jack.torrence@theoverlook.com","password":"heeeres johnny"}`).Code)
+	assert.Equal(suite.T(), 401, suite.post("/api/v1/auth/login", `{"email":"jack.torrence@theoverlook.com","password":"all work and no play"}`).Code)
+	assert.Equal(suite.T(), 401, suite.post("/api/v1/auth/refresh", fmt.Sprintf(`{"refreshToken":%q}`, tokens.RefreshToken)).Code)
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_theWrongCurrentPassword_WHEN_passwordIsChanged_THEN_400IsReturned() {
+	// GIVEN
+	tokens := suite.login()
+
+	for _, body := range []string{
+		`{"currentPassword":"all play and no work","password":"heeeres johnny"}`,
+		`{"password":"heeeres johnny"}`,
+	} {
+		// WHEN
+		w := suite.setPassword("Bearer "+tokens.AccessToken, body)
+
+		// THEN
+		assert.Equal(suite.T(), 400, w.Code, body)
+		assert.Contains(suite.T(), w.Body.String(), "USER_PASSWORD_VALIDATION_FAILED", body)
+		assert.Contains(suite.T(), w.Body.String(), "currentPassword", body)
+	}
+	assert.Equal(suite.T(), 200, suite.post("/api/v1/auth/login", `{"email":"jack.torrence@theoverlook.com","password":"all work and no play"}`).Code)
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_aResetToken_WHEN_passwordIsReset_THEN_userLogsInWithTheNewPasswordAndTheTokenCanNotBeReused() {
+	// GIVEN
+	tokens := suite.login()
+	token := suite.requestPasswordReset("jack.torrence@theoverlook.com")
+
+	// WHEN
+	w := suite.post("/api/v1/auth/password-reset/confirm", fmt.Sprintf(`{"token":%q,"password":"heeeres johnny"}`, token))
+
+	// THEN
+	assert.Equal(suite.T(), 204, w.Code)
+	assert.Equal(suite.T(), 200, suite.post("/api/v1/auth/login", `{"email":"jack.torrence@theoverlook.com","password":"heeeres johnny"}`).Code)
+	assert.Equal(suite.T(), 401, suite.post("/api/v1/auth/refresh", fmt.Sprintf(`{"refreshToken":%q}`, tokens.RefreshToken)).Code)
+
+	w = suite.post("/api/v1/auth/password-reset/confirm", fmt.Sprintf(`{"token":%q,"password":"redrum redrum"}`, token))
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "PASSWORD_RESET_TOKEN_INVALID")
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_aUserWhoRegisteredWithoutAPassword_WHEN_theyResetTheirPassword_THEN_theyCanLogIn() {
+	// GIVEN
+	aUser, _ := ledger.NewUserWithEmailString(ledger.UserId(237), "wendy.torrence@theoverlook.com")
+	assert.Nil(suite.T(), UserDao.Save(aUser))
+	assert.Equal(suite.T(), 401, suite.post("/api/v1/auth/login", `{"email":"wendy.torrence@theoverlook.com","password":"heeeres johnny"}`).Code)
+
+	token := suite.requestPasswordReset("wendy.torrence@theoverlook.com")
+
+	// WHEN
+	w := suite.post("/api/v1/auth/password-reset/confirm", fmt.Sprintf(`{"token":%q,"password":"heeeres johnny"}`, token))
+
+	// THEN
+	assert.Equal(suite.T(), 204, w.Code)
+
+	w = suite.post("/api/v1/auth/login", `{"email":"wendy.torrence@theoverlook.com","password":"heeeres johnny"}`)
+	var response svc.TokenResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+
+	var user svc.UserResponse
+	w = suite.getUser("Bearer " + response.AccessToken)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &user))
+	assert.Equal(suite.T(), aUser.Id(), user.Id)
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_anUnknownEmail_WHEN_passwordResetIsRequested_THEN_202IsReturnedAndNothingIsSent() {
+	// WHEN
+	w := suite.post("/api/v1/auth/password-reset", `{"email":"danny.torrence@theoverlook.com"}`)
+
+	// THEN
+	assert.Equal(suite.T(), 202, w.Code)
+	assert.Empty(suite.T(), suite.notifier.Notices())
+}
+
+func (suite *AuthHandlerTestSuite) Test_GIVEN_anOlderOrUnknownResetToken_WHEN_passwordIsReset_THEN_400IsReturned() {
+	// GIVEN
+	older := suite.requestPasswordReset("jack.torrence@theoverlook.com")
+	_ = suite.requestPasswordReset("jack.torrence@theoverlook.com")
+
+	for _, token := range []string{older, "redrum", ""} {
+		// WHEN
+		w := suite.post("/api/v1/auth/password-reset/confirm", fmt.Sprintf(`{"token":%q,"password":"heeeres johnny"}`, token))
+
+		// THEN
+		assert.Equal(suite.T(), 400, w.Code, token)
+		assert.Contains(suite.T(), w.Body.String(), "PASSWORD_RESET_TOKEN_INVALID", token)
+	}
+	assert.Equal(suite.T(), 200, suite.post("/api/v1/auth/login", `{"email":"jack.torrence@theoverlook.com","password":"all work and no play"}`).Code)
+}
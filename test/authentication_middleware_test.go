@@ -0,0 +1,160 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	cfg "github.com/w-k-s/simple-budget-tracker/internal/config"
+	app "github.com/w-k-s/simple-budget-tracker/internal/server"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+// AuthenticationMiddlewareTestSuite runs against an application that only accepts access tokens,
+// unlike TestApp, which also accepts the id of the user in the Authorization header.
+type AuthenticationMiddlewareTestSuite struct {
+	suite.Suite
+	app    *app.App
+	userId ledger.UserId
+}
+
+func TestAuthenticationMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(AuthenticationMiddlewareTestSuite))
+}
+
+// -- SETUP
+
+func (suite *AuthenticationMiddlewareTestSuite) SetupSuite() {
+	config, err := cfg.NewConfig(
+		TestConfig.Server(),
+		TestConfig.Database(),
+		TestConfig.Gpt(),
+		TestConfig.Import(),
+		TestConfig.Record(),
+		TestConfig.User(),
+		TestConfig.Alert(),
+		*cfg.NewAuthConfigBuilder().
+			SetSigningKey(testSigningKey).
+			SetPasswordResetNotifier(cfg.PasswordResetNotifierMemory).
+			Build(),
+	)
+	if err != nil {
+		log.Fatalf("AuthenticationMiddlewareTestSuite: Failed to configure application. Reason: %s", err)
+	}
+	if suite.app, err = app.Init(config); err != nil {
+		log.Fatalf("AuthenticationMiddlewareTestSuite: Failed to initialize application. Reason: %s", err)
+	}
+}
+
+func (suite *AuthenticationMiddlewareTestSuite) SetupTest() {
+	w := suite.send("POST", "/api/v1/user", "", `{"email":"jack.torrence@theoverlook.com","password":"all work and no play"}`)
+
+	var response svc.CreateUserResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusCreated {
+		log.Fatalf("AuthenticationMiddlewareTestSuite: Test setup failed: %d %s", w.Code, w.Body.String())
+	}
+	suite.userId = response.Id
+}
+
+// -- TEARDOWN
+
+func (suite *AuthenticationMiddlewareTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down AuthenticationMiddlewareTestSuite: %s", err)
+	}
+}
+
+// -- SUITE
+
+func (suite *AuthenticationMiddlewareTestSuite) send(method, url, authorization, body string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if len(authorization) != 0 {
+		r.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	suite.app.Router().ServeHTTP(w, r)
+	return w
+}
+
+func (suite *AuthenticationMiddlewareTestSuite) assertAccessTokenInvalid(w *httptest.ResponseRecorder, authorization string) {
+	assert.Equal(suite.T(), 401, w.Code, authorization)
+	assert.Equal(suite.T(), "Bearer", w.Header().Get("WWW-Authenticate"), authorization)
+
+	var problem map[string]interface{}
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem), authorization)
+	assert.Equal(suite.T(), fmt.Sprintf("/api/v1/problems/%d", pkg.ErrAccessTokenInvalid), problem["type"], authorization)
+	assert.Equal(suite.T(), "ACCESS_TOKEN_INVALID", problem["title"], authorization)
+	assert.Equal(suite.T(), float64(401), problem["status"], authorization)
+}
+
+func (suite *AuthenticationMiddlewareTestSuite) Test_GIVEN_anAccessToken_WHEN_protectedEndpointIsCalled_THEN_userIsAuthenticated() {
+	// GIVEN
+	w := suite.send("POST", "/api/v1/auth/login", "", `{"email":"jack.torrence@theoverlook.com","password":"all work and no play"}`)
+	var tokens svc.TokenResponse
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &tokens))
+
+	// WHEN
+	w = suite.send("GET", "/api/v1/user", "Bearer "+tokens.AccessToken, "")
+
+	// THEN
+	var user svc.UserResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &user))
+	assert.Equal(suite.T(), suite.userId, user.Id)
+}
+
+func (suite *AuthenticationMiddlewareTestSuite) Test_GIVEN_theIdOfTheUser_WHEN_protectedEndpointIsCalled_THEN_401IsReturned() {
+	// GIVEN
+	authorization := strconv.FormatUint(uint64(suite.userId), 10)
+
+	// WHEN
+	w := suite.send("GET", "/api/v1/user", authorization, "")
+
+	// THEN
+	suite.assertAccessTokenInvalid(w, authorization)
+	assert.Contains(suite.T(), w.Body.String(), "Authorization header must be a Bearer access token")
+}
+
+func (suite *AuthenticationMiddlewareTestSuite) Test_GIVEN_aMalformedAuthorizationHeader_WHEN_protectedEndpointIsCalled_THEN_401IsReturned() {
+	for _, authorization := range []string{
+		"Bearer",
+		"Bearer not-a-token",
+		"Basic amFjazphbGwgd29yaw==",
+		"jack",
+	} {
+		// WHEN
+		w := suite.send("GET", "/api/v1/user", authorization, "")
+
+		// THEN
+		suite.assertAccessTokenInvalid(w, authorization)
+	}
+}
+
+func (suite *AuthenticationMiddlewareTestSuite) Test_GIVEN_anExpiredAccessToken_WHEN_protectedEndpointIsCalled_THEN_401IsReturned() {
+	// GIVEN
+	issuedAt := time.Now().Add(-time.Hour)
+	expired, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ": "access",
+		"sub": strconv.FormatUint(uint64(suite.userId), 10),
+		"iat": issuedAt.Unix(),
+		"exp": issuedAt.Add(15 * time.Minute).Unix(),
+	}).SignedString([]byte(testSigningKey))
+
+	// WHEN
+	w := suite.send("GET", "/api/v1/user", "Bearer "+expired, "")
+
+	// THEN
+	suite.assertAccessTokenInvalid(w, "Bearer "+expired)
+	assert.Contains(suite.T(), w.Body.String(), "Access token is invalid or has expired")
+}
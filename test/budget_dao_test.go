@@ -9,6 +9,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
 	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
 	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
 )
@@ -114,6 +115,137 @@ func (suite *BudgetDaoTestSuite) Test_Given_aBudget_WHEN_theBudgetIsSaved_THEN_B
 	assert.EqualValues(suite.T(), "UserId: 1", theBudget.CreatedBy().String())
 }
 
+func (suite *BudgetDaoTestSuite) Test_Given_aSavedBudget_WHEN_theBudgetIsUpdated_THEN_categoryBudgetsAreReplacedAndVersionIsIncremented() {
+	// GIVEN
+	aBudget, _ := ledger.NewBudget(
+		ledger.BudgetId(time.Now().UnixNano()),
+		ledger.AccountIds{suite.testCurrentAccount.Id()},
+		ledger.BudgetPeriodTypeMonth,
+		ledger.CategoryBudgets{ledger.MustCategoryBudget(ledger.NewCategoryBudget(
+			suite.testBillsCategory.Id(),
+			ledger.MustMoney(ledger.NewMoney("AED", 1000_00)),
+		))},
+		ledger.MustMakeUpdatedByUserId(suite.testUser.Id()),
+	)
+
+	tx := suite.accountDao.MustBeginTx()
+	assert.Nil(suite.T(), suite.budgetDao.Save(context.Background(), suite.testUser.Id(), aBudget, tx))
+	_ = tx.Commit()
+
+	// WHEN
+	editedBudget, err := aBudget.Edit(
+		ledger.AccountIds{suite.testCurrentAccount.Id(), suite.testSavingsAccount.Id()},
+		ledger.BudgetPeriodTypeWeek,
+		ledger.CategoryBudgets{ledger.MustCategoryBudget(ledger.NewCategoryBudget(
+			suite.testSavingsCategory.Id(),
+			ledger.MustMoney(ledger.NewMoney("AED", 500_00)),
+		))},
+		ledger.MustMakeUpdatedByUserId(suite.testUser.Id()),
+	)
+	assert.Nil(suite.T(), err)
+
+	tx = suite.accountDao.MustBeginTx()
+	err = suite.budgetDao.UpdateTx(context.Background(), suite.testUser.Id(), editedBudget, tx)
+	_ = tx.Commit()
+
+	// THEN
+	assert.Nil(suite.T(), err)
+
+	tx = suite.accountDao.MustBeginTx()
+	theBudget, err := suite.budgetDao.GetBudgetById(context.Background(), aBudget.Id(), suite.testUser.Id(), tx)
+	_ = tx.Commit()
+
+	assert.Nil(suite.T(), err)
+	assert.EqualValues(suite.T(), ledger.BudgetPeriodTypeWeek, theBudget.PeriodType())
+	assert.ElementsMatch(suite.T(), ledger.AccountIds{suite.testCurrentAccount.Id(), suite.testSavingsAccount.Id()}, theBudget.AccountIds())
+	assert.Len(suite.T(), theBudget.CategoryBudgets(), 1)
+	assert.EqualValues(suite.T(), suite.testSavingsCategory.Id(), theBudget.CategoryBudgets()[0].CategoryId())
+	assert.Equal(suite.T(), aBudget.Version()+1, theBudget.Version())
+}
+
+func (suite *BudgetDaoTestSuite) Test_Given_aSavedBudget_WHEN_theBudgetIsDeletedWithAStaleVersion_THEN_versionConflictIsReturned() {
+	// GIVEN
+	aBudget, _ := ledger.NewBudget(
+		ledger.BudgetId(time.Now().UnixNano()),
+		ledger.AccountIds{suite.testCurrentAccount.Id()},
+		ledger.BudgetPeriodTypeMonth,
+		ledger.CategoryBudgets{ledger.MustCategoryBudget(ledger.NewCategoryBudget(
+			suite.testBillsCategory.Id(),
+			ledger.MustMoney(ledger.NewMoney("AED", 1000_00)),
+		))},
+		ledger.MustMakeUpdatedByUserId(suite.testUser.Id()),
+	)
+
+	tx := suite.accountDao.MustBeginTx()
+	assert.Nil(suite.T(), suite.budgetDao.Save(context.Background(), suite.testUser.Id(), aBudget, tx))
+	_ = tx.Commit()
+
+	// WHEN
+	tx = suite.accountDao.MustBeginTx()
+	staleErr := suite.budgetDao.DeleteTx(context.Background(), aBudget.Id(), suite.testUser.Id(), aBudget.Version()+1, tx)
+	err := suite.budgetDao.DeleteTx(context.Background(), aBudget.Id(), suite.testUser.Id(), aBudget.Version(), tx)
+	_ = tx.Commit()
+
+	// THEN
+	assert.NotNil(suite.T(), staleErr)
+	assert.EqualValues(suite.T(), pkg.ErrBudgetVersionConflict, staleErr.(pkg.ValidationError).Code())
+	assert.Nil(suite.T(), err)
+
+	tx = suite.accountDao.MustBeginTx()
+	_, err = suite.budgetDao.GetBudgetById(context.Background(), aBudget.Id(), suite.testUser.Id(), tx)
+	_ = tx.Commit()
+	assert.EqualValues(suite.T(), pkg.ErrBudgetNotFound, err.(pkg.ValidationError).Code())
+}
+
+func (suite *BudgetDaoTestSuite) Test_Given_aLockedBudget_WHEN_anotherTransactionLocksTheBudget_THEN_itWaitsUntilTheFirstTransactionEnds() {
+	// GIVEN
+	aBudget, _ := ledger.NewBudget(
+		ledger.BudgetId(time.Now().UnixNano()),
+		ledger.AccountIds{suite.testCurrentAccount.Id()},
+		ledger.BudgetPeriodTypeMonth,
+		ledger.CategoryBudgets{ledger.MustCategoryBudget(ledger.NewCategoryBudget(
+			suite.testBillsCategory.Id(),
+			ledger.MustMoney(ledger.NewMoney("AED", 1000_00)),
+		))},
+		ledger.MustMakeUpdatedByUserId(suite.testUser.Id()),
+	)
+
+	tx := suite.accountDao.MustBeginTx()
+	assert.Nil(suite.T(), suite.budgetDao.Save(context.Background(), suite.testUser.Id(), aBudget, tx))
+	_ = tx.Commit()
+
+	first := suite.accountDao.MustBeginTx()
+	assert.Nil(suite.T(), suite.budgetDao.LockBudgetTx(context.Background(), aBudget.Id(), suite.testUser.Id(), first))
+
+	// WHEN
+	locked := make(chan error)
+	go func() {
+		second := suite.accountDao.MustBeginTx()
+		err := suite.budgetDao.LockBudgetTx(context.Background(), aBudget.Id(), suite.testUser.Id(), second)
+		_ = second.Rollback()
+		locked <- err
+	}()
+
+	// THEN
+	select {
+	case <-locked:
+		assert.Fail(suite.T(), "budget was locked by two transactions at once")
+	case <-time.After(200 * time.Millisecond):
+	}
+	_ = first.Commit()
+	assert.Nil(suite.T(), <-locked)
+}
+
+func (suite *BudgetDaoTestSuite) Test_Given_noBudget_WHEN_theBudgetIsLocked_THEN_budgetIsNotFound() {
+	// WHEN
+	tx := suite.accountDao.MustBeginTx()
+	err := suite.budgetDao.LockBudgetTx(context.Background(), ledger.BudgetId(time.Now().UnixNano()), suite.testUser.Id(), tx)
+	_ = tx.Rollback()
+
+	// THEN
+	assert.EqualValues(suite.T(), pkg.ErrBudgetNotFound, err.(pkg.ValidationError).Code())
+}
+
 func (suite *AccountDaoTestSuite) Test_Given_twoUsersCreateTwoBudgets_WHEN_aUserTriesToRetrieveTheBudgetOfTheOtherUserByBudgetId_THEN_budgetIsNotFound() {
 	// TODO
 }
@@ -0,0 +1,221 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+type BudgetEnvelopeHandlerTestSuite struct {
+	suite.Suite
+	simulatedUser           ledger.User
+	simulatedCurrentAccount ledger.Account
+	simulatedBillsCategory  ledger.Category
+	simulatedFoodCategory   ledger.Category
+	// The budgets are created in the current month, so the envelopes start in the current month
+	thisMonth time.Time
+}
+
+func TestBudgetEnvelopeHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(BudgetEnvelopeHandlerTestSuite))
+}
+
+// -- SETUP
+
+func (suite *BudgetEnvelopeHandlerTestSuite) SetupTest() {
+
+	aUser, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+
+	currentAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787222),
+		"Current",
+		ledger.AccountTypeCurrent,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	billsCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305041),
+		"Bills",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	foodCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305042),
+		"Food",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+
+	if err := UserDao.Save(aUser); err != nil {
+		log.Fatalf("BudgetEnvelopeHandlerTestSuite: Test setup failed: %s", err)
+	}
+
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), aUser.Id(), ledger.Accounts{currentAccount}, tx)
+	_ = CategoryDao.SaveTx(context.Background(), aUser.Id(), ledger.Categories{billsCategory, foodCategory}, tx)
+	_ = tx.Commit()
+
+	now := time.Now().UTC()
+	suite.simulatedUser = aUser
+	suite.simulatedCurrentAccount = currentAccount
+	suite.simulatedBillsCategory = billsCategory
+	suite.simulatedFoodCategory = foodCategory
+	suite.thisMonth = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (suite *BudgetEnvelopeHandlerTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down BudgetEnvelopeHandlerTestSuite: %s", err)
+	}
+}
+
+func (suite *BudgetEnvelopeHandlerTestSuite) createBudget() svc.BudgetResponse {
+	w := SendAsUser(suite.simulatedUser.Id(), "POST", "/api/v1/budgets", fmt.Sprintf(`{
+		"accountIds": [%d],
+		"period": "Month",
+		"categoryBudgets": [
+			{"categoryId": %d, "maxAmount": {"currency": "AED", "value": 100000}, "rollover": "SurplusOnly"},
+			{"categoryId": %d, "maxAmount": {"currency": "AED", "value": 50000}, "rollover": "SurplusAndDeficit"}
+		]
+	}`, suite.simulatedCurrentAccount.Id(), suite.simulatedBillsCategory.Id(), suite.simulatedFoodCategory.Id()))
+
+	var budgetResponse svc.BudgetResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &budgetResponse))
+	return budgetResponse
+}
+
+func (suite *BudgetEnvelopeHandlerTestSuite) createExpense(category ledger.Category, value int64) {
+	CreateRecord(suite.T(), suite.simulatedUser.Id(), suite.simulatedCurrentAccount.Id(), TestRecord{
+		Note:     "Expense",
+		Category: category,
+		Amount:   value,
+		Date:     suite.thisMonth.Format("2006-01-02T15:04:05+00:00"),
+		Type:     ledger.Expense,
+	})
+}
+
+func (suite *BudgetEnvelopeHandlerTestSuite) getEnvelopes(budgetId uint64, period time.Time) svc.BudgetEnvelopesResponse {
+	w := SendAsUser(suite.simulatedUser.Id(), "GET", fmt.Sprintf("/api/v1/budgets/%d/envelopes?period=%s", budgetId, period.Format("2006-01-02")), "")
+
+	var envelopesResponse svc.BudgetEnvelopesResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &envelopesResponse))
+	return envelopesResponse
+}
+
+// -- SUITE
+
+func (suite *BudgetEnvelopeHandlerTestSuite) Test_GIVEN_aBudgetWithRollover_WHEN_envelopesOfTheNextPeriodAreRequested_THEN_balancesAreCarriedOverAccordingToRolloverMode() {
+	// GIVEN
+	budget := suite.createBudget()
+	assert.Equal(suite.T(), "SurplusOnly", budget.CategoryBudgets[0].Rollover)
+	assert.Equal(suite.T(), "SurplusAndDeficit", budget.CategoryBudgets[1].Rollover)
+
+	suite.createExpense(suite.simulatedBillsCategory, 400_00)
+	suite.createExpense(suite.simulatedFoodCategory, 600_00)
+
+	// WHEN
+	envelopesResponse := suite.getEnvelopes(budget.Id, suite.thisMonth.AddDate(0, 1, 0))
+
+	// THEN
+	assert.Len(suite.T(), envelopesResponse.Periods, 2)
+	assert.Equal(suite.T(), suite.thisMonth.Format("2006-01-02"), envelopesResponse.Periods[0].Period.From)
+
+	thisMonthBills := envelopesResponse.Periods[0].Envelopes[0]
+	assert.Equal(suite.T(), int64(400_00), thisMonthBills.Spent.Value)
+	assert.Equal(suite.T(), int64(600_00), thisMonthBills.Balance.Value)
+
+	nextMonthBills := envelopesResponse.Periods[1].Envelopes[0]
+	assert.Equal(suite.T(), int64(600_00), nextMonthBills.CarriedOver.Value)
+	assert.Equal(suite.T(), int64(1600_00), nextMonthBills.Balance.Value)
+
+	nextMonthFood := envelopesResponse.Periods[1].Envelopes[1]
+	assert.Equal(suite.T(), int64(-100_00), nextMonthFood.CarriedOver.Value)
+	assert.Equal(suite.T(), int64(400_00), nextMonthFood.Available.Value)
+}
+
+func (suite *BudgetEnvelopeHandlerTestSuite) Test_GIVEN_aTransferBetweenEnvelopes_WHEN_transferIsCreated_THEN_balancesAreMovedAndTransferIsAudited() {
+	// GIVEN
+	budget := suite.createBudget()
+
+	// WHEN
+	w := SendAsUser(suite.simulatedUser.Id(), "POST", fmt.Sprintf("/api/v1/budgets/%d/transfers", budget.Id), fmt.Sprintf(
+		`{"fromCategoryId": %d, "toCategoryId": %d, "amount": {"currency": "AED", "value": 20000}, "period": "%s", "note": "Dinner party"}`,
+		suite.simulatedBillsCategory.Id(),
+		suite.simulatedFoodCategory.Id(),
+		suite.thisMonth.Format("2006-01-02"),
+	))
+
+	// THEN
+	var transferResponse svc.EnvelopeTransferResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &transferResponse))
+	assert.Equal(suite.T(), "Dinner party", transferResponse.Note)
+	assert.Equal(suite.T(), uint64(suite.simulatedUser.Id()), transferResponse.CreatedBy.UserId)
+
+	envelopesResponse := suite.getEnvelopes(budget.Id, suite.thisMonth)
+	assert.Len(suite.T(), envelopesResponse.Periods, 1)
+	assert.Equal(suite.T(), int64(-200_00), envelopesResponse.Periods[0].Envelopes[0].Transferred.Value)
+	assert.Equal(suite.T(), int64(800_00), envelopesResponse.Periods[0].Envelopes[0].Balance.Value)
+	assert.Equal(suite.T(), int64(200_00), envelopesResponse.Periods[0].Envelopes[1].Transferred.Value)
+	assert.Equal(suite.T(), int64(700_00), envelopesResponse.Periods[0].Envelopes[1].Balance.Value)
+
+	w = SendAsUser(suite.simulatedUser.Id(), "GET", fmt.Sprintf("/api/v1/budgets/%d/transfers", budget.Id), "")
+
+	var transfersResponse svc.EnvelopeTransfersResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &transfersResponse))
+	assert.Equal(suite.T(), []svc.EnvelopeTransferResponse{transferResponse}, transfersResponse.Transfers)
+}
+
+func (suite *BudgetEnvelopeHandlerTestSuite) Test_GIVEN_anAmountGreaterThanTheBalance_WHEN_transferIsCreated_THEN_400IsReturned() {
+	// GIVEN
+	budget := suite.createBudget()
+	suite.createExpense(suite.simulatedBillsCategory, 900_00)
+
+	// WHEN
+	w := SendAsUser(suite.simulatedUser.Id(), "POST", fmt.Sprintf("/api/v1/budgets/%d/transfers", budget.Id), fmt.Sprintf(
+		`{"fromCategoryId": %d, "toCategoryId": %d, "amount": {"currency": "AED", "value": 20000}}`,
+		suite.simulatedBillsCategory.Id(),
+		suite.simulatedFoodCategory.Id(),
+	))
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+}
+
+func (suite *BudgetEnvelopeHandlerTestSuite) Test_GIVEN_aPeriodAfterTheNextPeriod_WHEN_envelopesAreRequested_THEN_400IsReturned() {
+	// GIVEN
+	budget := suite.createBudget()
+	period := suite.thisMonth.AddDate(0, 2, 0)
+
+	// WHEN
+	w := SendAsUser(suite.simulatedUser.Id(), "GET", fmt.Sprintf("/api/v1/budgets/%d/envelopes?period=%s", budget.Id, period.Format("2006-01-02")), "")
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "is too far in the future")
+}
+
+func (suite *BudgetEnvelopeHandlerTestSuite) Test_GIVEN_aPeriodAfterTheNextPeriod_WHEN_transferIsCreated_THEN_400IsReturned() {
+	// GIVEN
+	budget := suite.createBudget()
+
+	// WHEN
+	w := SendAsUser(suite.simulatedUser.Id(), "POST", fmt.Sprintf("/api/v1/budgets/%d/transfers", budget.Id), fmt.Sprintf(
+		`{"fromCategoryId": %d, "toCategoryId": %d, "amount": {"currency": "AED", "value": 20000}, "period": "%s"}`,
+		suite.simulatedBillsCategory.Id(),
+		suite.simulatedFoodCategory.Id(),
+		suite.thisMonth.AddDate(10, 0, 0).Format("2006-01-02"),
+	))
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+}
@@ -0,0 +1,397 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+type BudgetHandlerTestSuite struct {
+	suite.Suite
+	simulatedUser             ledger.User
+	simulatedOtherUser        ledger.User
+	simulatedCurrentAccount   ledger.Account
+	simulatedOtherUserAccount ledger.Account
+	simulatedBillsCategory    ledger.Category
+	simulatedFoodCategory     ledger.Category
+}
+
+func TestBudgetHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(BudgetHandlerTestSuite))
+}
+
+// -- SETUP
+
+func (suite *BudgetHandlerTestSuite) SetupTest() {
+
+	aUser, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+	otherUser, _ := ledger.NewUserWithEmailString(2, "wendy.torrence@theoverlook.com")
+
+	currentAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787222),
+		"Current",
+		ledger.AccountTypeCurrent,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	otherUserAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787223),
+		"Current",
+		ledger.AccountTypeCurrent,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(otherUser.Id()),
+	)
+	billsCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305041),
+		"Bills",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	foodCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305042),
+		"Food",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+
+	if err := UserDao.Save(aUser); err != nil {
+		log.Fatalf("BudgetHandlerTestSuite: Test setup failed: %s", err)
+	}
+	if err := UserDao.Save(otherUser); err != nil {
+		log.Fatalf("BudgetHandlerTestSuite: Test setup failed: %s", err)
+	}
+
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), aUser.Id(), ledger.Accounts{currentAccount}, tx)
+	_ = AccountDao.SaveTx(context.Background(), otherUser.Id(), ledger.Accounts{otherUserAccount}, tx)
+	_ = CategoryDao.SaveTx(context.Background(), aUser.Id(), ledger.Categories{billsCategory, foodCategory}, tx)
+	_ = tx.Commit()
+
+	suite.simulatedUser = aUser
+	suite.simulatedOtherUser = otherUser
+	suite.simulatedCurrentAccount = currentAccount
+	suite.simulatedOtherUserAccount = otherUserAccount
+	suite.simulatedBillsCategory = billsCategory
+	suite.simulatedFoodCategory = foodCategory
+}
+
+func (suite *BudgetHandlerTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down BudgetHandlerTestSuite: %s", err)
+	}
+}
+
+func (suite *BudgetHandlerTestSuite) budgetRequest(accountId ledger.AccountId, period ledger.BudgetPeriodType, categoryBudgets ...svc.CategoryBudgetRequest) svc.CreateBudgetRequest {
+	return svc.CreateBudgetRequest{
+		AccountIds:      []uint64{uint64(accountId)},
+		PeriodType:      string(period),
+		CategoryBudgets: categoryBudgets,
+	}
+}
+
+func (suite *BudgetHandlerTestSuite) categoryBudget(category ledger.Category, value int64) svc.CategoryBudgetRequest {
+	return svc.CategoryBudgetRequest{
+		CategoryId:      uint64(category.Id()),
+		MaxAmount:       svc.AmountResponse{Currency: "AED", Value: value},
+		AlertThresholds: []uint{50, 80, 100},
+		Rollover:        "None",
+	}
+}
+
+func (suite *BudgetHandlerTestSuite) send(method string, url string, body interface{}, version uint64) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	r, _ := http.NewRequest(method, url, bytes.NewBuffer(data))
+	if version != 0 {
+		r.Header.Set("If-Match", fmt.Sprintf("%q", fmt.Sprint(version)))
+	}
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+func (suite *BudgetHandlerTestSuite) createBudget(request svc.CreateBudgetRequest) svc.BudgetResponse {
+	w := suite.send("POST", "/api/v1/budgets", request, 0)
+
+	var createResponse svc.BudgetResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &createResponse))
+	return createResponse
+}
+
+// -- SUITE
+
+func (suite *BudgetHandlerTestSuite) Test_GIVEN_aBudgetRequest_WHEN_budgetIsCreated_THEN_categoryBudgetsArePersisted() {
+	// GIVEN
+	request := suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeMonth,
+		suite.categoryBudget(suite.simulatedBillsCategory, 150000),
+		suite.categoryBudget(suite.simulatedFoodCategory, 200000),
+	)
+
+	// WHEN
+	created := suite.createBudget(request)
+
+	// THEN
+	assert.NotZero(suite.T(), created.Id)
+	assert.Equal(suite.T(), []uint64{uint64(suite.simulatedCurrentAccount.Id())}, created.AccountIds)
+	assert.Equal(suite.T(), "Month", created.PeriodType)
+	assert.ElementsMatch(suite.T(), request.CategoryBudgets, created.CategoryBudgets)
+
+	w := suite.send("GET", fmt.Sprintf("/api/v1/budgets/%d", created.Id), nil, 0)
+
+	var getResponse svc.BudgetResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &getResponse))
+	assert.Equal(suite.T(), fmt.Sprintf("%q", fmt.Sprint(getResponse.Version)), w.Header().Get("ETag"))
+	assert.Equal(suite.T(), created.Id, getResponse.Id)
+	assert.ElementsMatch(suite.T(), request.CategoryBudgets, getResponse.CategoryBudgets)
+}
+
+func (suite *BudgetHandlerTestSuite) Test_GIVEN_budgets_WHEN_budgetsAreListed_THEN_budgetsOfTheUserAreReturned() {
+	// GIVEN
+	suite.createBudget(suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeMonth,
+		suite.categoryBudget(suite.simulatedBillsCategory, 150000),
+	))
+	suite.createBudget(suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeWeek,
+		suite.categoryBudget(suite.simulatedFoodCategory, 50000),
+	))
+
+	// WHEN
+	w := suite.send("GET", "/api/v1/budgets", nil, 0)
+
+	// THEN
+	var listResponse svc.BudgetsResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &listResponse))
+	assert.Len(suite.T(), listResponse.Budgets, 2)
+}
+
+func (suite *BudgetHandlerTestSuite) Test_GIVEN_aBudget_WHEN_budgetIsUpdatedWithItsVersion_THEN_budgetIsReplaced() {
+	// GIVEN
+	created := suite.createBudget(suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeMonth,
+		suite.categoryBudget(suite.simulatedBillsCategory, 150000),
+	))
+
+	// WHEN
+	updateRequest := svc.UpdateBudgetRequest{
+		CreateBudgetRequest: suite.budgetRequest(
+			suite.simulatedCurrentAccount.Id(),
+			ledger.BudgetPeriodTypeWeek,
+			suite.categoryBudget(suite.simulatedFoodCategory, 50000),
+		),
+	}
+	w := suite.send("PUT", fmt.Sprintf("/api/v1/budgets/%d", created.Id), updateRequest, created.Version)
+
+	// THEN
+	var updateResponse svc.BudgetResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &updateResponse))
+	assert.Equal(suite.T(), "Week", updateResponse.PeriodType)
+	assert.Equal(suite.T(), updateRequest.CategoryBudgets, updateResponse.CategoryBudgets)
+	assert.Greater(suite.T(), updateResponse.Version, created.Version)
+}
+
+func (suite *BudgetHandlerTestSuite) Test_GIVEN_aStaleVersion_WHEN_budgetIsUpdated_THEN_409IsReturned() {
+	// GIVEN
+	created := suite.createBudget(suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeMonth,
+		suite.categoryBudget(suite.simulatedBillsCategory, 150000),
+	))
+	updateRequest := svc.UpdateBudgetRequest{CreateBudgetRequest: suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeWeek,
+		suite.categoryBudget(suite.simulatedBillsCategory, 150000),
+	)}
+	w := suite.send("PUT", fmt.Sprintf("/api/v1/budgets/%d", created.Id), updateRequest, created.Version)
+	assert.Equal(suite.T(), 200, w.Code)
+
+	// WHEN
+	w = suite.send("PUT", fmt.Sprintf("/api/v1/budgets/%d", created.Id), updateRequest, created.Version)
+
+	// THEN
+	assert.Equal(suite.T(), 409, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "BUDGET_VERSION_CONFLICT")
+}
+
+func (suite *BudgetHandlerTestSuite) Test_GIVEN_aBudget_WHEN_budgetIsDeleted_THEN_budgetIsNotFound() {
+	// GIVEN
+	created := suite.createBudget(suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeMonth,
+		suite.categoryBudget(suite.simulatedBillsCategory, 150000),
+	))
+
+	// WHEN
+	w := suite.send("DELETE", fmt.Sprintf("/api/v1/budgets/%d", created.Id), svc.BudgetVersionRequest{Version: created.Version}, 0)
+
+	// THEN
+	assert.Equal(suite.T(), 204, w.Code)
+
+	w = suite.send("GET", fmt.Sprintf("/api/v1/budgets/%d", created.Id), nil, 0)
+	assert.Equal(suite.T(), 404, w.Code)
+}
+
+func (suite *BudgetHandlerTestSuite) Test_GIVEN_anAccountOfAnotherUser_WHEN_budgetIsCreated_THEN_404IsReturned() {
+	// GIVEN
+	request := suite.budgetRequest(
+		suite.simulatedOtherUserAccount.Id(),
+		ledger.BudgetPeriodTypeMonth,
+		suite.categoryBudget(suite.simulatedBillsCategory, 150000),
+	)
+
+	// WHEN
+	w := suite.send("POST", "/api/v1/budgets", request, 0)
+
+	// THEN
+	assert.Equal(suite.T(), 404, w.Code)
+}
+
+func (suite *BudgetHandlerTestSuite) Test_GIVEN_aCategoryThatIsAlreadyBudgeted_WHEN_budgetIsCreated_THEN_400IsReturned() {
+	// GIVEN
+	suite.createBudget(suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeMonth,
+		suite.categoryBudget(suite.simulatedBillsCategory, 150000),
+	))
+
+	// WHEN
+	w := suite.send("POST", "/api/v1/budgets", suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeWeek,
+		suite.categoryBudget(suite.simulatedBillsCategory, 50000),
+	), 0)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+}
+
+func (suite *BudgetHandlerTestSuite) createRecord(body string) {
+	w := SendAsUser(suite.simulatedUser.Id(), "POST", fmt.Sprintf("/api/v1/accounts/%d/records", suite.simulatedCurrentAccount.Id()), body)
+	assert.Equal(suite.T(), 201, w.Code)
+}
+
+func (suite *BudgetHandlerTestSuite) Test_GIVEN_expensesInAndOutOfThePeriod_WHEN_budgetProgressIsRequested_THEN_expensesOfThePeriodAreSummedPerCategoryIncludingSplitLines() {
+	// GIVEN
+	created := suite.createBudget(suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeMonth,
+		suite.categoryBudget(suite.simulatedBillsCategory, 1000_00),
+		suite.categoryBudget(suite.simulatedFoodCategory, 500_00),
+	))
+
+	billsId := suite.simulatedBillsCategory.Id()
+	foodId := suite.simulatedFoodCategory.Id()
+	suite.createRecord(fmt.Sprintf(`{"note": "Electricity", "category": {"id": %d}, "amount": {"currency": "AED", "value": 20000}, "date": "2021-07-05T10:00:00+00:00", "type": "EXPENSE"}`, billsId))
+	suite.createRecord(fmt.Sprintf(`{
+		"note": "Supermarket",
+		"category": {"id": %d},
+		"amount": {"currency": "AED", "value": 15000},
+		"date": "2021-07-10T10:00:00+00:00",
+		"type": "EXPENSE",
+		"splits": [
+			{"note": "Vegetables", "category": {"id": %d}, "amount": {"currency": "AED", "value": 10000}},
+			{"note": "Light bulbs", "category": {"id": %d}, "amount": {"currency": "AED", "value": 5000}}
+		]
+	}`, foodId, foodId, billsId))
+	suite.createRecord(fmt.Sprintf(`{"note": "Restaurant", "category": {"id": %d}, "amount": {"currency": "AED", "value": 30000}, "date": "2021-06-30T10:00:00+00:00", "type": "EXPENSE"}`, foodId))
+	suite.createRecord(fmt.Sprintf(`{"note": "Refund", "category": {"id": %d}, "amount": {"currency": "AED", "value": 10000}, "date": "2021-07-15T10:00:00+00:00", "type": "INCOME"}`, billsId))
+
+	// WHEN
+	w := suite.send("GET", fmt.Sprintf("/api/v1/budgets/%d/progress?period=2021-07-20", created.Id), nil, 0)
+
+	// THEN
+	var progressResponse svc.BudgetProgressResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &progressResponse))
+
+	assert.Equal(suite.T(), svc.BudgetPeriodResponse{Type: "Month", From: "2021-07-01", To: "2021-07-31"}, progressResponse.Period)
+	assert.Len(suite.T(), progressResponse.CategoryBudgets, 2)
+
+	assert.Equal(suite.T(), uint64(billsId), progressResponse.CategoryBudgets[0].CategoryId)
+	assert.Equal(suite.T(), int64(250_00), progressResponse.CategoryBudgets[0].Spent.Value)
+	assert.Equal(suite.T(), int64(750_00), progressResponse.CategoryBudgets[0].Remaining.Value)
+	assert.Equal(suite.T(), 25.0, progressResponse.CategoryBudgets[0].PercentUsed)
+
+	assert.Equal(suite.T(), uint64(foodId), progressResponse.CategoryBudgets[1].CategoryId)
+	assert.Equal(suite.T(), int64(100_00), progressResponse.CategoryBudgets[1].Spent.Value)
+	assert.Equal(suite.T(), 20.0, progressResponse.CategoryBudgets[1].PercentUsed)
+
+	assert.Equal(suite.T(), int64(1500_00), progressResponse.Total.MaxAmount.Value)
+	assert.Equal(suite.T(), int64(350_00), progressResponse.Total.Spent.Value)
+	assert.Equal(suite.T(), 23.33, progressResponse.Total.PercentUsed)
+}
+
+func (suite *BudgetHandlerTestSuite) Test_GIVEN_anInvalidPeriod_WHEN_budgetProgressIsRequested_THEN_400IsReturned() {
+	// GIVEN
+	created := suite.createBudget(suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeWeek,
+		suite.categoryBudget(suite.simulatedBillsCategory, 1000_00),
+	))
+
+	// WHEN
+	w := suite.send("GET", fmt.Sprintf("/api/v1/budgets/%d/progress?period=July", created.Id), nil, 0)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+}
+
+func (suite *BudgetHandlerTestSuite) Test_GIVEN_aMonthStartingOnPayday_WHEN_budgetProgressIsRequested_THEN_expensesFromPaydayToTheDayBeforeTheNextPaydayAreSummed() {
+	// GIVEN
+	request := suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeMonthStartingOnDay,
+		suite.categoryBudget(suite.simulatedBillsCategory, 1000_00),
+	)
+	request.PeriodStartDay = 25
+	created := suite.createBudget(request)
+	assert.Equal(suite.T(), uint(25), created.PeriodStartDay)
+
+	billsId := suite.simulatedBillsCategory.Id()
+	suite.createRecord(fmt.Sprintf(`{"note": "Rent", "category": {"id": %d}, "amount": {"currency": "AED", "value": 40000}, "date": "2021-06-25T10:00:00+00:00", "type": "EXPENSE"}`, billsId))
+	suite.createRecord(fmt.Sprintf(`{"note": "Electricity", "category": {"id": %d}, "amount": {"currency": "AED", "value": 20000}, "date": "2021-07-24T10:00:00+00:00", "type": "EXPENSE"}`, billsId))
+	suite.createRecord(fmt.Sprintf(`{"note": "Internet", "category": {"id": %d}, "amount": {"currency": "AED", "value": 30000}, "date": "2021-07-25T10:00:00+00:00", "type": "EXPENSE"}`, billsId))
+
+	// WHEN
+	w := suite.send("GET", fmt.Sprintf("/api/v1/budgets/%d/progress?period=2021-07-01", created.Id), nil, 0)
+
+	// THEN
+	var progressResponse svc.BudgetProgressResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &progressResponse))
+	assert.Equal(suite.T(), svc.BudgetPeriodResponse{Type: "MonthStartingOnDay", From: "2021-06-25", To: "2021-07-24"}, progressResponse.Period)
+	assert.Equal(suite.T(), int64(600_00), progressResponse.Total.Spent.Value)
+}
+
+func (suite *BudgetHandlerTestSuite) Test_GIVEN_aBiWeeklyBudgetWithoutAnAnchor_WHEN_budgetIsCreated_THEN_400IsReturned() {
+	// WHEN
+	w := suite.send("POST", "/api/v1/budgets", suite.budgetRequest(
+		suite.simulatedCurrentAccount.Id(),
+		ledger.BudgetPeriodTypeBiWeek,
+		suite.categoryBudget(suite.simulatedBillsCategory, 1000_00),
+	), 0)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+}
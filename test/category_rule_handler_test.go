@@ -0,0 +1,257 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+type CategoryRuleHandlerTestSuite struct {
+	suite.Suite
+	simulatedUser              ledger.User
+	simulatedCurrentAccount    ledger.Account
+	simulatedTransportCategory ledger.Category
+	simulatedUncategorized     ledger.Category
+}
+
+func TestCategoryRuleHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(CategoryRuleHandlerTestSuite))
+}
+
+// -- SETUP
+
+func (suite *CategoryRuleHandlerTestSuite) SetupTest() {
+
+	aUser, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+
+	currentAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787222),
+		"Current",
+		ledger.AccountTypeCurrent,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	transportCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305041),
+		"Transport",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	uncategorized, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305042),
+		"Uncategorized",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+
+	if err := UserDao.Save(aUser); err != nil {
+		log.Fatalf("CategoryRuleHandlerTestSuite: Test setup failed: %s", err)
+	}
+
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), aUser.Id(), ledger.Accounts{currentAccount}, tx)
+	_ = CategoryDao.SaveTx(context.Background(), aUser.Id(), ledger.Categories{transportCategory, uncategorized}, tx)
+	_ = tx.Commit()
+
+	suite.simulatedUser = aUser
+	suite.simulatedCurrentAccount = currentAccount
+	suite.simulatedTransportCategory = transportCategory
+	suite.simulatedUncategorized = uncategorized
+}
+
+func (suite *CategoryRuleHandlerTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down CategoryRuleHandlerTestSuite: %s", err)
+	}
+}
+
+func (suite *CategoryRuleHandlerTestSuite) createUberRule() svc.CategoryRuleResponse {
+	var createRequest svc.CreateCategoryRuleRequest
+	createRequest.Priority = 1
+	createRequest.Conditions.NoteContains = "uber"
+	createRequest.Category.Id = uint64(suite.simulatedTransportCategory.Id())
+
+	data, _ := json.Marshal(createRequest)
+	r, _ := http.NewRequest("POST", "/api/v1/category-rules", bytes.NewBuffer(data))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	var createResponse svc.CategoryRuleResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &createResponse))
+	return createResponse
+}
+
+func (suite *CategoryRuleHandlerTestSuite) postExpenseRecord(note string, categoryId ledger.CategoryId) *httptest.ResponseRecorder {
+	var createRequest svc.CreateRecordRequest
+	createRequest.Note = note
+	createRequest.Amount.Currency = "AED"
+	createRequest.Amount.Value = -25_00
+	createRequest.Category.Id = uint64(categoryId)
+	createRequest.DateUTC = "2021-07-03T10:00:00+00:00"
+	createRequest.Type = string(ledger.Expense)
+
+	data, _ := json.Marshal(createRequest)
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records?force=true", suite.simulatedCurrentAccount.Id()), bytes.NewBuffer(data))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+func (suite *CategoryRuleHandlerTestSuite) applyRules(dryRun bool) svc.ApplyCategoryRulesResponse {
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/category-rules/apply?dryRun=%t", dryRun), nil)
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	var applyResponse svc.ApplyCategoryRulesResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &applyResponse))
+	return applyResponse
+}
+
+func (suite *CategoryRuleHandlerTestSuite) categoryOfRecord(recordId uint64) ledger.CategoryId {
+	var categoryId ledger.CategoryId
+	assert.Nil(suite.T(), TestDB.QueryRow("SELECT category_id FROM budget.record WHERE id = $1", recordId).Scan(&categoryId))
+	return categoryId
+}
+
+// -- SUITE
+
+func (suite *CategoryRuleHandlerTestSuite) Test_GIVEN_aRule_WHEN_rulesAreListed_THEN_ruleIsReturned() {
+	// GIVEN
+	rule := suite.createUberRule()
+
+	r, _ := http.NewRequest("GET", "/api/v1/category-rules", nil)
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	expected := fmt.Sprintf(`{
+		"rules": [{
+			"id": %d,
+			"priority": 1,
+			"conditions": {"noteContains": "uber"},
+			"category": {"id": %d},
+			"version": 1
+		}]
+	}`, rule.Id, suite.simulatedTransportCategory.Id())
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
+
+func (suite *CategoryRuleHandlerTestSuite) Test_GIVEN_aRuleWithoutConditions_WHEN_ruleIsCreated_THEN_400IsReturned() {
+	// GIVEN
+	var createRequest svc.CreateCategoryRuleRequest
+	createRequest.Category.Id = uint64(suite.simulatedTransportCategory.Id())
+
+	data, _ := json.Marshal(createRequest)
+	r, _ := http.NewRequest("POST", "/api/v1/category-rules", bytes.NewBuffer(data))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	var problem map[string]interface{}
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(suite.T(), "CATEGORY_RULE_VALIDATION_FAILED", problem["title"])
+}
+
+func (suite *CategoryRuleHandlerTestSuite) Test_GIVEN_aRule_WHEN_recordIsCreatedWithoutCategory_THEN_recordIsCategorisedByRule() {
+	// GIVEN
+	_ = suite.createUberRule()
+
+	// WHEN
+	w := suite.postExpenseRecord("UBER *TRIP", 0)
+
+	// THEN
+	var recordResponse svc.RecordResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &recordResponse))
+	assert.Equal(suite.T(), uint64(suite.simulatedTransportCategory.Id()), recordResponse.Category.Id)
+}
+
+func (suite *CategoryRuleHandlerTestSuite) Test_GIVEN_noRuleApplies_WHEN_recordIsCreatedWithoutCategory_THEN_categoryIsNotFound() {
+	// GIVEN
+	_ = suite.createUberRule()
+
+	// WHEN
+	w := suite.postExpenseRecord("Cinema", 0)
+
+	// THEN
+	var problem map[string]interface{}
+	assert.Equal(suite.T(), 404, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(suite.T(), fmt.Sprintf("/api/v1/problems/%d", pkg.ErrCategoriesNotFound), problem["type"])
+}
+
+func (suite *CategoryRuleHandlerTestSuite) Test_GIVEN_uncategorisedRecords_WHEN_rulesAreApplied_THEN_dryRunPreviewsAndApplyMovesMatchingRecords() {
+	// GIVEN
+	var uber, cinema svc.RecordResponse
+	assert.Nil(suite.T(), json.Unmarshal(suite.postExpenseRecord("Uber Eats", suite.simulatedUncategorized.Id()).Body.Bytes(), &uber))
+	assert.Nil(suite.T(), json.Unmarshal(suite.postExpenseRecord("Cinema", suite.simulatedUncategorized.Id()).Body.Bytes(), &cinema))
+	rule := suite.createUberRule()
+
+	// WHEN
+	dryRun := suite.applyRules(true)
+
+	// THEN
+	assert.True(suite.T(), dryRun.DryRun)
+	assert.Len(suite.T(), dryRun.Changes, 1)
+	assert.Equal(suite.T(), uber.Id, dryRun.Changes[0].Record.Id)
+	assert.Equal(suite.T(), uint64(rule.Id), dryRun.Changes[0].Rule.Id)
+	assert.Equal(suite.T(), "Uncategorized", dryRun.Changes[0].From.Name)
+	assert.Equal(suite.T(), "Transport", dryRun.Changes[0].To.Name)
+	assert.Equal(suite.T(), suite.simulatedUncategorized.Id(), suite.categoryOfRecord(uber.Id))
+
+	// WHEN
+	applied := suite.applyRules(false)
+
+	// THEN
+	assert.False(suite.T(), applied.DryRun)
+	assert.Len(suite.T(), applied.Changes, 1)
+	assert.Equal(suite.T(), suite.simulatedTransportCategory.Id(), suite.categoryOfRecord(uber.Id))
+	assert.Equal(suite.T(), suite.simulatedUncategorized.Id(), suite.categoryOfRecord(cinema.Id))
+	assert.Empty(suite.T(), suite.applyRules(false).Changes)
+}
+
+func (suite *CategoryRuleHandlerTestSuite) Test_GIVEN_aRule_WHEN_ruleIsDeleted_THEN_204IsReturnedAndRuleIsNotFoundAfterwards() {
+	// GIVEN
+	rule := suite.createUberRule()
+
+	deleteRule := func() *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/v1/category-rules/%d", rule.Id), nil)
+		AddAuthorizationHeader(r, suite.simulatedUser.Id())
+		w := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+		return w
+	}
+
+	// WHEN
+	deleted := deleteRule()
+	notFound := deleteRule()
+
+	// THEN
+	assert.Equal(suite.T(), 204, deleted.Code)
+	assert.Equal(suite.T(), 404, notFound.Code)
+}
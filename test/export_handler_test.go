@@ -0,0 +1,193 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	"github.com/w-k-s/simple-budget-tracker/pkg/statement/ofx"
+)
+
+type ExportHandlerTestSuite struct {
+	suite.Suite
+	simulatedUser           ledger.User
+	simulatedCurrentAccount ledger.Account
+	simulatedSavingAccount  ledger.Account
+	simulatedSalaryCategory ledger.Category
+}
+
+func TestExportHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportHandlerTestSuite))
+}
+
+// -- SETUP
+
+func (suite *ExportHandlerTestSuite) SetupTest() {
+
+	aUser, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+
+	currentAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787222),
+		"Current",
+		ledger.AccountTypeCurrent,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	savingAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787223),
+		"Saving",
+		ledger.AccountTypeSaving,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	salaryCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305041),
+		"Salary",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+
+	if err := UserDao.Save(aUser); err != nil {
+		log.Fatalf("ExportHandlerTestSuite: Test setup failed: %s", err)
+	}
+
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), aUser.Id(), ledger.Accounts{currentAccount, savingAccount}, tx)
+	_ = CategoryDao.SaveTx(context.Background(), aUser.Id(), ledger.Categories{salaryCategory}, tx)
+	_ = tx.Commit()
+
+	suite.simulatedUser = aUser
+	suite.simulatedCurrentAccount = currentAccount
+	suite.simulatedSavingAccount = savingAccount
+	suite.simulatedSalaryCategory = salaryCategory
+
+	for _, record := range []TestRecord{
+		{Note: "Salary", Category: salaryCategory, Amount: 1000_00, Date: "2021-07-01T10:00:00+00:00", Type: ledger.Income},
+		{Note: "Bonus", Category: salaryCategory, Amount: 200_00, Date: "2021-08-01T10:00:00+00:00", Type: ledger.Income},
+		{Note: "Savings", Category: salaryCategory, Amount: -300_00, Date: "2021-08-02T10:00:00+00:00", Type: ledger.Transfer, Beneficiary: savingAccount.Id()},
+	} {
+		CreateRecord(suite.T(), aUser.Id(), currentAccount.Id(), record)
+	}
+}
+
+func (suite *ExportHandlerTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down ExportHandlerTestSuite: %s", err)
+	}
+}
+
+func (suite *ExportHandlerTestSuite) export(path string) *httptest.ResponseRecorder {
+	return SendAsUser(suite.simulatedUser.Id(), "GET", path, "")
+}
+
+// -- SUITE
+
+func (suite *ExportHandlerTestSuite) Test_GIVEN_records_WHEN_exportedAsCsv_THEN_recordsInPeriodAreStreamedOldestFirst() {
+	// WHEN
+	w := suite.export(fmt.Sprintf("/api/v1/accounts/%d/records/export?format=csv&from=2021-08-01", suite.simulatedCurrentAccount.Id()))
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Equal(suite.T(), "text/csv;charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(suite.T(), fmt.Sprintf(`attachment; filename="%d-current.csv"`, suite.simulatedCurrentAccount.Id()), w.Header().Get("Content-Disposition"))
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), rows, 3)
+	assert.Equal(suite.T(), []string{"Bonus", "Salary", "200.00"}, rows[1][3:6])
+	assert.Equal(suite.T(), []string{"TRANSFER", "Savings", "Salary", "-300.00", "AED", "Saving"}, rows[2][2:8])
+	assert.NotEmpty(suite.T(), rows[2][8])
+	assert.Equal(suite.T(), "UserId: 1", rows[2][10])
+}
+
+func (suite *ExportHandlerTestSuite) Test_GIVEN_records_WHEN_exportedAsJson_THEN_accountAndRecordsAreReturned() {
+	// WHEN
+	w := suite.export(fmt.Sprintf("/api/v1/accounts/%d/records/export?format=json", suite.simulatedCurrentAccount.Id()))
+
+	// THEN
+	var exported struct {
+		Account struct {
+			Name string `json:"name"`
+		} `json:"account"`
+		Records []struct {
+			Note     string `json:"note"`
+			Category struct {
+				Name string `json:"name"`
+			} `json:"category"`
+			Transfer *struct {
+				Beneficiary struct {
+					Name string `json:"name"`
+				} `json:"beneficiary"`
+			} `json:"transfer"`
+		} `json:"records"`
+	}
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &exported))
+	assert.Equal(suite.T(), "Current", exported.Account.Name)
+	assert.Len(suite.T(), exported.Records, 3)
+	assert.Equal(suite.T(), "Salary", exported.Records[0].Note)
+	assert.Equal(suite.T(), "Salary", exported.Records[0].Category.Name)
+	assert.Equal(suite.T(), "Saving", exported.Records[2].Transfer.Beneficiary.Name)
+}
+
+func (suite *ExportHandlerTestSuite) Test_GIVEN_records_WHEN_exportedAsOfx_THEN_statementCanBeParsed() {
+	// WHEN
+	w := suite.export(fmt.Sprintf("/api/v1/accounts/%d/records/export?format=ofx", suite.simulatedCurrentAccount.Id()))
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+	stmt, err := ofx.Parse(w.Body)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "AED", stmt.Currency)
+	assert.Len(suite.T(), stmt.Transactions, 3)
+	assert.Equal(suite.T(), "1000.00", stmt.Transactions[0].Amount)
+}
+
+func (suite *ExportHandlerTestSuite) Test_GIVEN_severalAccounts_WHEN_allRecordsAreExported_THEN_aFilePerAccountIsZipped() {
+	// WHEN
+	w := suite.export("/api/v1/records/export?format=csv")
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Equal(suite.T(), "application/zip", w.Header().Get("Content-Type"))
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), archive.File, 2)
+	assert.Equal(suite.T(), fmt.Sprintf("%d-current.csv", suite.simulatedCurrentAccount.Id()), archive.File[0].Name)
+	assert.Equal(suite.T(), fmt.Sprintf("%d-saving.csv", suite.simulatedSavingAccount.Id()), archive.File[1].Name)
+
+	file, _ := archive.File[1].Open()
+	content, _ := ioutil.ReadAll(file)
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), rows, 2)
+	assert.Equal(suite.T(), "300.00", rows[1][5])
+}
+
+func (suite *ExportHandlerTestSuite) Test_GIVEN_anUnknownFormat_WHEN_recordsAreExported_THEN_400IsReturned() {
+	// WHEN
+	w := suite.export(fmt.Sprintf("/api/v1/accounts/%d/records/export?format=xlsx", suite.simulatedCurrentAccount.Id()))
+
+	// THEN
+	expected := fmt.Sprintf(`{
+		"type": "/api/v1/problems/%d",
+		"title": "EXPORT_VALIDATION_FAILED",
+		"status": 400,
+		"detail": "Unknown export format \"xlsx\"",
+		"instance": "/api/v1/accounts/%d/records/export",
+		"format": "format must be csv, json or ofx"
+	}`, pkg.ErrExportValidation, suite.simulatedCurrentAccount.Id())
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
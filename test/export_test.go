@@ -1,15 +1,19 @@
 package test
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	tc "github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 	cfg "github.com/w-k-s/simple-budget-tracker/internal/config"
@@ -17,6 +21,7 @@ import (
 	app "github.com/w-k-s/simple-budget-tracker/internal/server"
 	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
 	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
 )
 
 const (
@@ -24,6 +29,8 @@ const (
 	testContainerPostgresPassword = "test"
 	testContainerPostgresDB       = "simple_budget_tracker"
 	testContainerDriverName       = "postgres"
+
+	testSigningKey = "the-overlook-hotel-room-237-signing-key"
 )
 
 var testContainerContext context.Context
@@ -76,6 +83,16 @@ func init() {
 			SetName(testContainerDataSourceName).
 			Build(),
 		*cfg.NewGptConfig(""),
+		*cfg.NewImportConfig(""),
+		*cfg.NewRecordConfig(0),
+		*cfg.NewUserConfig(0),
+		*cfg.NewAlertConfig(cfg.AlertNotifierMemory),
+		// The tests authenticate with the id of the user, see AddAuthorizationHeader
+		*cfg.NewAuthConfigBuilder().
+			SetSigningKey(testSigningKey).
+			SetDevUserIdHeader(true).
+			SetPasswordResetNotifier(cfg.PasswordResetNotifierMemory).
+			Build(),
 	); err != nil {
 		log.Fatalf("Failed to configure application for tests. Reason: %s", err)
 	}
@@ -117,6 +134,15 @@ func ClearTables() error {
 	if db, err = sql.Open(testContainerDriverName, testContainerDataSourceName); err != nil {
 		return fmt.Errorf("Failed to connect to %q: %w", testContainerDataSourceName, err)
 	}
+	if _, err = db.Exec("DELETE FROM budget.recurring_record"); err != nil {
+		return fmt.Errorf("Failed to delete recurring record table: %w", err)
+	}
+	if _, err = db.Exec("DELETE FROM budget.import_profile"); err != nil {
+		return fmt.Errorf("Failed to delete import profile table: %w", err)
+	}
+	if _, err = db.Exec("DELETE FROM budget.category_rule"); err != nil {
+		return fmt.Errorf("Failed to delete category rule table: %w", err)
+	}
 	if _, err = db.Exec("DELETE FROM budget.record"); err != nil {
 		return fmt.Errorf("Failed to delete record table: %w", err)
 	}
@@ -142,6 +168,24 @@ func ClearTables() error {
 	if _, err = db.Exec("ALTER SEQUENCE budget.record_id RESTART"); err != nil {
 		return fmt.Errorf("Failed to delete record table: %w", err)
 	}
+	if _, err = db.Exec("ALTER SEQUENCE budget.recurring_record_id RESTART"); err != nil {
+		return fmt.Errorf("Failed to restart recurring record sequence: %w", err)
+	}
+	if _, err = db.Exec("ALTER SEQUENCE budget.import_profile_id RESTART"); err != nil {
+		return fmt.Errorf("Failed to restart import profile sequence: %w", err)
+	}
+	if _, err = db.Exec("ALTER SEQUENCE budget.category_rule_id RESTART"); err != nil {
+		return fmt.Errorf("Failed to restart category rule sequence: %w", err)
+	}
+	if _, err = db.Exec("ALTER SEQUENCE budget.budget_alert_id RESTART"); err != nil {
+		return fmt.Errorf("Failed to restart budget alert sequence: %w", err)
+	}
+	if _, err = db.Exec("ALTER SEQUENCE budget.envelope_transfer_id RESTART"); err != nil {
+		return fmt.Errorf("Failed to restart envelope transfer sequence: %w", err)
+	}
+	if _, err = db.Exec("ALTER SEQUENCE budget.monthly_plan_id RESTART"); err != nil {
+		return fmt.Errorf("Failed to restart monthly plan sequence: %w", err)
+	}
 	return nil
 }
 
@@ -157,6 +201,43 @@ func AddAuthorizationHeader(r *http.Request, userId ledger.UserId) {
 	r.Header.Add("Authorization", fmt.Sprintf("%d", userId))
 }
 
+// SendAsUser serves a request with the given body on behalf of the user and returns the response
+func SendAsUser(userId ledger.UserId, method string, url string, body string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	AddAuthorizationHeader(r, userId)
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+// TestRecord is a record in AED that is created through the API by CreateRecord
+type TestRecord struct {
+	Note     string
+	Category ledger.Category
+	Amount   int64
+	Date     string
+	Type     ledger.RecordType
+	// Beneficiary is only set for transfers
+	Beneficiary ledger.AccountId
+}
+
+// CreateRecord creates the record in an account of the user and asserts that it was created
+func CreateRecord(t *testing.T, userId ledger.UserId, accountId ledger.AccountId, record TestRecord) {
+	var createRequest svc.CreateRecordRequest
+	createRequest.Note = record.Note
+	createRequest.Amount.Currency = "AED"
+	createRequest.Amount.Value = record.Amount
+	createRequest.Category.Id = uint64(record.Category.Id())
+	createRequest.DateUTC = record.Date
+	createRequest.Type = string(record.Type)
+	createRequest.Transfer.Beneficiary.Id = uint64(record.Beneficiary)
+
+	data, _ := json.Marshal(createRequest)
+	w := SendAsUser(userId, "POST", fmt.Sprintf("/api/v1/accounts/%d/records", accountId), string(data))
+	assert.Equal(t, 201, w.Code, w.Body.String())
+}
+
 func errorCode(err error, defaultValue uint64) uint64 {
 	if errWithCode, ok := err.(interface {
 		Code() uint64
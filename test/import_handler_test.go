@@ -0,0 +1,326 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+const bankStatement = `Date,Description,Amount,Category
+03/07/2021,Carrefour,"-1,234.50",groceries
+04/07/2021,Refund,20.00,
+2021-07-05,Cinema,-45.00,Entertainment
+06/07/2021,Cinema,-45.00,Entertainment
+`
+
+const ofxStatement = `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>AED
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20210703<TRNAMT>-1234.50<FITID>0001<NAME>Carrefour</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20210725<TRNAMT>20.00<FITID>0002<NAME>Refund</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+type ImportHandlerTestSuite struct {
+	suite.Suite
+	simulatedUser            ledger.User
+	simulatedCurrentAccount  ledger.Account
+	simulatedGroceryCategory ledger.Category
+	simulatedUncategorized   ledger.Category
+	simulatedImportProfileId uint64
+}
+
+func TestImportHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(ImportHandlerTestSuite))
+}
+
+// -- SETUP
+
+func (suite *ImportHandlerTestSuite) SetupTest() {
+
+	aUser, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+
+	currentAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787222),
+		"Current",
+		ledger.AccountTypeCurrent,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	groceryCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305041),
+		"Groceries",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	uncategorized, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305042),
+		"Uncategorized",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+
+	if err := UserDao.Save(aUser); err != nil {
+		log.Fatalf("ImportHandlerTestSuite: Test setup failed: %s", err)
+	}
+
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), aUser.Id(), ledger.Accounts{currentAccount}, tx)
+	_ = CategoryDao.SaveTx(context.Background(), aUser.Id(), ledger.Categories{groceryCategory, uncategorized}, tx)
+	_ = tx.Commit()
+
+	suite.simulatedUser = aUser
+	suite.simulatedCurrentAccount = currentAccount
+	suite.simulatedGroceryCategory = groceryCategory
+	suite.simulatedUncategorized = uncategorized
+	suite.simulatedImportProfileId = suite.createImportProfile()
+}
+
+func (suite *ImportHandlerTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down ImportHandlerTestSuite: %s", err)
+	}
+}
+
+func (suite *ImportHandlerTestSuite) createImportProfile() uint64 {
+	note, category := 1, 3
+
+	var createRequest svc.CreateImportProfileRequest
+	createRequest.Name = "Bank"
+	createRequest.Columns.Date = 0
+	createRequest.Columns.Note = &note
+	createRequest.Columns.Amount = 2
+	createRequest.Columns.Category = &category
+	createRequest.DateFormat = "dd/MM/yyyy"
+	createRequest.DecimalSeparator = "."
+	createRequest.DebitFormat = string(ledger.DebitsNegative)
+	createRequest.HasHeader = true
+	createRequest.DefaultCategory = &struct {
+		Id uint64 `json:"id"`
+	}{Id: uint64(suite.simulatedUncategorized.Id())}
+
+	data, _ := json.Marshal(createRequest)
+	r, _ := http.NewRequest("POST", "/api/v1/import-profiles", bytes.NewBuffer(data))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	var createResponse svc.ImportProfileResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &createResponse))
+	return createResponse.Id
+}
+
+func (suite *ImportHandlerTestSuite) importFile(profileId uint64, content string, dryRun bool) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("profileId", fmt.Sprint(profileId))
+	part, _ := writer.CreateFormFile("file", "statement.csv")
+	_, _ = part.Write([]byte(content))
+	_ = writer.Close()
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/imports?dryRun=%t", suite.simulatedCurrentAccount.Id(), dryRun), body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+func (suite *ImportHandlerTestSuite) importStatement(fileName string, content string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", fileName)
+	_, _ = part.Write([]byte(content))
+	_ = writer.Close()
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/imports", suite.simulatedCurrentAccount.Id()), body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+func (suite *ImportHandlerTestSuite) countRecords() int {
+	var count int
+	assert.Nil(suite.T(), TestDB.QueryRow(
+		"SELECT COUNT(*) FROM budget.record WHERE account_id = $1",
+		suite.simulatedCurrentAccount.Id(),
+	).Scan(&count))
+	return count
+}
+
+// -- SUITE
+
+func (suite *ImportHandlerTestSuite) Test_GIVEN_aBankStatement_WHEN_importIsPreviewed_THEN_recordsAndRowErrorsAreReturnedButNotSaved() {
+	// WHEN
+	w := suite.importFile(suite.simulatedImportProfileId, bankStatement, true)
+
+	// THEN
+	var importResponse svc.ImportRecordsResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &importResponse))
+	assert.True(suite.T(), importResponse.DryRun)
+	assert.Equal(suite.T(), 2, importResponse.Imported)
+
+	assert.Len(suite.T(), importResponse.Records, 2)
+	assert.Equal(suite.T(), 2, importResponse.Records[0].Row)
+	assert.Equal(suite.T(), uint64(0), importResponse.Records[0].Record.Id)
+	assert.Equal(suite.T(), "Groceries", importResponse.Records[0].Record.Category.Name)
+	assert.Equal(suite.T(), int64(-123450), importResponse.Records[0].Record.Amount.Value)
+	assert.Equal(suite.T(), string(ledger.Expense), importResponse.Records[0].Record.Type)
+	assert.Equal(suite.T(), "Uncategorized", importResponse.Records[1].Record.Category.Name)
+	assert.Equal(suite.T(), string(ledger.Income), importResponse.Records[1].Record.Type)
+	assert.Equal(suite.T(), svc.UpdatedByResponse{Kind: "IMPORT", FileName: "statement.csv", BatchId: importResponse.BatchId}, importResponse.Records[1].Record.CreatedBy)
+
+	assert.Equal(suite.T(), []svc.ImportRowError{
+		{
+			Row:    4,
+			Code:   uint64(pkg.ErrImportValidation),
+			Title:  "IMPORT_VALIDATION_FAILED",
+			Fields: map[string]string{"date": "date '2021-07-05' does not match format 'dd/MM/yyyy'"},
+		},
+		{
+			Row:    5,
+			Code:   uint64(pkg.ErrCategoriesNotFound),
+			Title:  "CATEGORIES_NOT_FOUND",
+			Detail: "Category \"Entertainment\" not found",
+			Fields: map[string]string{"category": "category \"Entertainment\" not found"},
+		},
+	}, importResponse.Errors)
+
+	assert.Equal(suite.T(), 0, suite.countRecords())
+}
+
+func (suite *ImportHandlerTestSuite) Test_GIVEN_aBankStatement_WHEN_imported_THEN_validRowsAreSavedAsCreatedByTheImport() {
+	// WHEN
+	w := suite.importFile(suite.simulatedImportProfileId, bankStatement, false)
+
+	// THEN
+	var importResponse svc.ImportRecordsResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &importResponse))
+	assert.Equal(suite.T(), 2, importResponse.Imported)
+	assert.Len(suite.T(), importResponse.Errors, 2)
+	assert.NotEqual(suite.T(), uint64(0), importResponse.Records[0].Record.Id)
+
+	var (
+		total     int64
+		createdBy string
+	)
+	assert.Nil(suite.T(), TestDB.QueryRow(
+		"SELECT SUM(amount_minor_units), MIN(created_by) FROM budget.record WHERE account_id = $1",
+		suite.simulatedCurrentAccount.Id(),
+	).Scan(&total, &createdBy))
+	assert.Equal(suite.T(), 2, suite.countRecords())
+	assert.Equal(suite.T(), int64(-121450), total)
+	assert.Equal(suite.T(), fmt.Sprintf("Import: statement.csv; Batch: %s", importResponse.BatchId), createdBy)
+}
+
+func (suite *ImportHandlerTestSuite) Test_GIVEN_anUnknownImportProfile_WHEN_fileIsImported_THEN_404IsReturned() {
+	// WHEN
+	w := suite.importFile(suite.simulatedImportProfileId+1, bankStatement, false)
+
+	// THEN
+	assert.Equal(suite.T(), 404, w.Code)
+	assert.Equal(suite.T(), 0, suite.countRecords())
+}
+
+func (suite *ImportHandlerTestSuite) Test_GIVEN_anInvalidMapping_WHEN_importProfileIsCreated_THEN_400IsReturned() {
+	// GIVEN
+	data := []byte(`{"name": "Bank", "columns": {"date": 0, "amount": 0}, "dateFormat": "dd/MM/yyyy", "decimalSeparator": ".", "debitFormat": "NEGATIVE"}`)
+	r, _ := http.NewRequest("POST", "/api/v1/import-profiles", bytes.NewBuffer(data))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+}
+
+func (suite *ImportHandlerTestSuite) Test_GIVEN_anOfxStatement_WHEN_importedTwice_THEN_transactionsAreOnlyImportedOnce() {
+	// GIVEN
+	first := suite.importStatement("statement.ofx", ofxStatement)
+
+	// WHEN
+	second := suite.importStatement("statement.ofx", ofxStatement)
+
+	// THEN
+	var firstResponse, secondResponse svc.ImportRecordsResponse
+	assert.Equal(suite.T(), 201, first.Code)
+	assert.Nil(suite.T(), json.Unmarshal(first.Body.Bytes(), &firstResponse))
+	assert.Equal(suite.T(), 2, firstResponse.Imported)
+	assert.Equal(suite.T(), 0, firstResponse.Skipped)
+	assert.Equal(suite.T(), "Uncategorized", firstResponse.Records[0].Record.Category.Name)
+	assert.Equal(suite.T(), int64(-123450), firstResponse.Records[0].Record.Amount.Value)
+	assert.Equal(suite.T(), string(ledger.Income), firstResponse.Records[1].Record.Type)
+
+	assert.Equal(suite.T(), 201, second.Code)
+	assert.Nil(suite.T(), json.Unmarshal(second.Body.Bytes(), &secondResponse))
+	assert.Equal(suite.T(), 0, secondResponse.Imported)
+	assert.Equal(suite.T(), 2, secondResponse.Skipped)
+
+	assert.Equal(suite.T(), 2, suite.countRecords())
+}
+
+func (suite *ImportHandlerTestSuite) Test_GIVEN_anOfxStatementInAnotherCurrency_WHEN_imported_THEN_400IsReturned() {
+	// WHEN
+	w := suite.importStatement("statement.qfx", strings.Replace(ofxStatement, "<CURDEF>AED", "<CURDEF>USD", 1))
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.Equal(suite.T(), 0, suite.countRecords())
+}
+
+func (suite *ImportHandlerTestSuite) Test_GIVEN_recordsImportedFromACsv_WHEN_anOverlappingStatementIsPreviewed_THEN_exactDuplicatesAreFlagged() {
+	// GIVEN
+	assert.Equal(suite.T(), 201, suite.importFile(suite.simulatedImportProfileId, bankStatement, false).Code)
+
+	// WHEN
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "statement.ofx")
+	_, _ = part.Write([]byte(ofxStatement))
+	_ = writer.Close()
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/imports?dryRun=true", suite.simulatedCurrentAccount.Id()), body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	var importResponse svc.ImportRecordsResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &importResponse))
+	assert.Equal(suite.T(), 1, importResponse.Imported)
+	assert.Equal(suite.T(), "Refund", importResponse.Records[0].Record.Note)
+	assert.Len(suite.T(), importResponse.Errors, 1)
+	assert.Equal(suite.T(), uint64(pkg.ErrRecordDuplicated), importResponse.Errors[0].Code)
+	assert.Equal(suite.T(), 1, importResponse.Errors[0].Row)
+	assert.Equal(suite.T(), 2, suite.countRecords())
+}
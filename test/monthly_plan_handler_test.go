@@ -0,0 +1,220 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+type MonthlyPlanHandlerTestSuite struct {
+	suite.Suite
+	simulatedUser             ledger.User
+	simulatedCurrentAccount   ledger.Account
+	simulatedSalaryCategory   ledger.Category
+	simulatedBillsCategory    ledger.Category
+	simulatedShoppingCategory ledger.Category
+	thisMonth                 time.Time
+}
+
+func TestMonthlyPlanHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(MonthlyPlanHandlerTestSuite))
+}
+
+// -- SETUP
+
+func (suite *MonthlyPlanHandlerTestSuite) SetupTest() {
+
+	aUser, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+
+	currentAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787222),
+		"Current",
+		ledger.AccountTypeCurrent,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	salaryCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305040),
+		"Salary",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	billsCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305041),
+		"Bills",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	shoppingCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305042),
+		"Shopping",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+
+	if err := UserDao.Save(aUser); err != nil {
+		log.Fatalf("MonthlyPlanHandlerTestSuite: Test setup failed: %s", err)
+	}
+
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), aUser.Id(), ledger.Accounts{currentAccount}, tx)
+	_ = CategoryDao.SaveTx(context.Background(), aUser.Id(), ledger.Categories{salaryCategory, billsCategory, shoppingCategory}, tx)
+	_ = tx.Commit()
+
+	now := time.Now().UTC()
+	suite.simulatedUser = aUser
+	suite.simulatedCurrentAccount = currentAccount
+	suite.simulatedSalaryCategory = salaryCategory
+	suite.simulatedBillsCategory = billsCategory
+	suite.simulatedShoppingCategory = shoppingCategory
+	suite.thisMonth = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (suite *MonthlyPlanHandlerTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down MonthlyPlanHandlerTestSuite: %s", err)
+	}
+}
+
+func (suite *MonthlyPlanHandlerTestSuite) createPlan(expectedIncome int64, bills int64) *httptest.ResponseRecorder {
+	return SendAsUser(suite.simulatedUser.Id(), "POST", "/api/v1/plans", fmt.Sprintf(`{
+		"month": "%s",
+		"accountIds": [%d],
+		"expectedIncome": {"currency": "AED", "value": %d},
+		"assignments": [
+			{"categoryId": %d, "amount": {"currency": "AED", "value": %d}}
+		]
+	}`, suite.thisMonth.Format("2006-01"), suite.simulatedCurrentAccount.Id(), expectedIncome, suite.simulatedBillsCategory.Id(), bills))
+}
+
+func (suite *MonthlyPlanHandlerTestSuite) createRecord(category ledger.Category, recordType ledger.RecordType, value int64) {
+	CreateRecord(suite.T(), suite.simulatedUser.Id(), suite.simulatedCurrentAccount.Id(), TestRecord{
+		Note:     "Record",
+		Category: category,
+		Amount:   value,
+		Date:     suite.thisMonth.Format("2006-01-02T15:04:05+00:00"),
+		Type:     recordType,
+	})
+}
+
+// -- SUITE
+
+func (suite *MonthlyPlanHandlerTestSuite) Test_GIVEN_aPlanRequest_WHEN_planIsCreated_THEN_amountToBeAssignedIsReturned() {
+	// WHEN
+	w := suite.createPlan(10000_00, 4000_00)
+
+	// THEN
+	var planResponse svc.MonthlyPlanResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &planResponse))
+	assert.Equal(suite.T(), suite.thisMonth.Format("2006-01"), planResponse.Month)
+	assert.Equal(suite.T(), int64(4000_00), planResponse.Assigned.Value)
+	assert.Equal(suite.T(), int64(6000_00), planResponse.ToBeAssigned.Value)
+	assert.Equal(suite.T(), int64(0), planResponse.OverAssigned.Value)
+
+	// WHEN
+	w = suite.createPlan(10000_00, 4000_00)
+
+	// THEN
+	assert.Equal(suite.T(), 409, w.Code)
+}
+
+func (suite *MonthlyPlanHandlerTestSuite) Test_GIVEN_aPlan_WHEN_moreThanTheExpectedIncomeIsAssigned_THEN_overAssignmentIsReturned() {
+	// GIVEN
+	var planResponse svc.MonthlyPlanResponse
+	w := suite.createPlan(10000_00, 4000_00)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &planResponse))
+
+	// WHEN
+	r, _ := http.NewRequest("PUT", fmt.Sprintf("/api/v1/plans/%s", planResponse.Month), bytes.NewBufferString(fmt.Sprintf(`{
+		"accountIds": [%d],
+		"expectedIncome": {"currency": "AED", "value": 10000000},
+		"assignments": [
+			{"categoryId": %d, "amount": {"currency": "AED", "value": 800000}},
+			{"categoryId": %d, "amount": {"currency": "AED", "value": 300000}}
+		]
+	}`, suite.simulatedCurrentAccount.Id(), suite.simulatedBillsCategory.Id(), suite.simulatedShoppingCategory.Id())))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+	r.Header.Set("If-Match", fmt.Sprintf("%q", fmt.Sprint(planResponse.Version)))
+	w = httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &planResponse))
+	assert.Equal(suite.T(), int64(11000_00), planResponse.Assigned.Value)
+	assert.Equal(suite.T(), int64(-1000_00), planResponse.ToBeAssigned.Value)
+	assert.Equal(suite.T(), int64(1000_00), planResponse.OverAssigned.Value)
+	assert.Len(suite.T(), planResponse.Assignments, 2)
+}
+
+func (suite *MonthlyPlanHandlerTestSuite) Test_GIVEN_aStaleVersion_WHEN_planIsUpdated_THEN_409IsReturned() {
+	// GIVEN
+	var planResponse svc.MonthlyPlanResponse
+	w := suite.createPlan(10000_00, 4000_00)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &planResponse))
+
+	updateRequest := func() *httptest.ResponseRecorder {
+		return SendAsUser(suite.simulatedUser.Id(), "PUT", fmt.Sprintf("/api/v1/plans/%s", planResponse.Month), fmt.Sprintf(`{
+			"accountIds": [%d],
+			"expectedIncome": {"currency": "AED", "value": 1000000},
+			"assignments": [{"categoryId": %d, "amount": {"currency": "AED", "value": 500000}}],
+			"version": %d
+		}`, suite.simulatedCurrentAccount.Id(), suite.simulatedBillsCategory.Id(), planResponse.Version))
+	}
+	assert.Equal(suite.T(), 200, updateRequest().Code)
+
+	// WHEN
+	w = updateRequest()
+
+	// THEN
+	assert.Equal(suite.T(), 409, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "MONTHLY_PLAN_VERSION_CONFLICT")
+}
+
+func (suite *MonthlyPlanHandlerTestSuite) Test_GIVEN_aPlanAndRecords_WHEN_summaryIsRequested_THEN_actualsAreComparedToThePlan() {
+	// GIVEN
+	assert.Equal(suite.T(), 201, suite.createPlan(10000_00, 4000_00).Code)
+	suite.createRecord(suite.simulatedSalaryCategory, ledger.Income, 9500_00)
+	suite.createRecord(suite.simulatedBillsCategory, ledger.Expense, 3000_00)
+	suite.createRecord(suite.simulatedShoppingCategory, ledger.Expense, 250_00)
+
+	// WHEN
+	w := SendAsUser(suite.simulatedUser.Id(), "GET", fmt.Sprintf("/api/v1/plans/%s/summary", suite.thisMonth.Format("2006-01")), "")
+
+	// THEN
+	var summaryResponse svc.MonthlyPlanSummaryResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &summaryResponse))
+	assert.Equal(suite.T(), int64(9500_00), summaryResponse.ActualIncome.Value)
+	assert.Len(suite.T(), summaryResponse.Categories, 2)
+
+	assert.Equal(suite.T(), uint64(suite.simulatedBillsCategory.Id()), summaryResponse.Categories[0].CategoryId)
+	assert.Equal(suite.T(), int64(4000_00), summaryResponse.Categories[0].Planned.Value)
+	assert.Equal(suite.T(), int64(3000_00), summaryResponse.Categories[0].Actual.Value)
+	assert.Equal(suite.T(), int64(1000_00), summaryResponse.Categories[0].Remaining.Value)
+	assert.Equal(suite.T(), float64(75), summaryResponse.Categories[0].PercentUsed)
+
+	assert.Equal(suite.T(), uint64(suite.simulatedShoppingCategory.Id()), summaryResponse.Categories[1].CategoryId)
+	assert.Equal(suite.T(), int64(0), summaryResponse.Categories[1].Planned.Value)
+	assert.Equal(suite.T(), int64(250_00), summaryResponse.Categories[1].Actual.Value)
+
+	assert.Equal(suite.T(), int64(4000_00), summaryResponse.Total.Planned.Value)
+	assert.Equal(suite.T(), int64(3250_00), summaryResponse.Total.Actual.Value)
+}
+
+func (suite *MonthlyPlanHandlerTestSuite) Test_GIVEN_noPlan_WHEN_planIsRequested_THEN_notFoundIsReturned() {
+	// WHEN
+	w := SendAsUser(suite.simulatedUser.Id(), "GET", fmt.Sprintf("/api/v1/plans/%s", suite.thisMonth.Format("2006-01")), "")
+
+	// THEN
+	assert.Equal(suite.T(), 404, w.Code)
+}
@@ -9,6 +9,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
 	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
 	dao "github.com/w-k-s/simple-budget-tracker/pkg/persistence"
 )
@@ -111,7 +112,7 @@ func (suite *RecordDaoTestSuite) Test_Given_anIncomeRecord_WHEN_theRecordIsSaved
 	_ = suite.recordDao.SaveTx(context.Background(), suite.testCurrentAccount.Id(), aRecord, tx)
 	_ = tx.Commit()
 
-	records, err := suite.recordDao.GetRecordsForMonth(suite.testCurrentAccount.Id(), ledger.MakeCalendarMonthFromDate(suite.testRecordDate))
+	records, err := suite.recordDao.GetRecordsForPeriod(suite.testCurrentAccount.Id(), ledger.MakeCalendarMonthFromDate(suite.testRecordDate))
 
 	// THEN
 	assert.Nil(suite.T(), err)
@@ -126,6 +127,42 @@ func (suite *RecordDaoTestSuite) Test_Given_anIncomeRecord_WHEN_theRecordIsSaved
 	assert.EqualValues(suite.T(), "", records[0].BeneficiaryType())
 }
 
+func (suite *RecordDaoTestSuite) Test_Given_recordsInConsecutiveWeeks_WHEN_recordsOfAWeekAreRetrieved_THEN_onlyRecordsInThatWeekAreReturned() {
+	// GIVEN
+	tx, _ := suite.recordDao.BeginTx()
+	for i, date := range []time.Time{
+		time.Date(2021, time.July, 4, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, time.July, 5, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, time.July, 11, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, time.July, 12, 0, 0, 0, 0, time.UTC),
+	} {
+		aRecord, _ := ledger.NewRecord(
+			ledger.RecordId(i+1),
+			"Bills",
+			suite.testBillsCategory,
+			quickMoney("AED", 1000),
+			date,
+			ledger.Expense,
+			ledger.NoSourceAccount,
+			ledger.NoBeneficiaryAccount,
+			ledger.NoBeneficiaryType,
+			ledger.NoTransferReference,
+			ledger.MustMakeUpdatedByUserId(suite.testUser.Id()),
+		)
+		_ = suite.recordDao.SaveTx(context.Background(), suite.testCurrentAccount.Id(), aRecord, tx)
+	}
+	_ = tx.Commit()
+
+	// WHEN
+	records, err := suite.recordDao.GetRecordsForPeriod(suite.testCurrentAccount.Id(), ledger.MakeWeek(suite.testRecordDate, time.Monday))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, records.Len())
+	assert.EqualValues(suite.T(), ledger.RecordId(3), records[0].Id())
+	assert.EqualValues(suite.T(), ledger.RecordId(2), records[1].Id())
+}
+
 func (suite *RecordDaoTestSuite) Test_Given_anExpenseRecord_WHEN_theRecordIsSaved_THEN_recordCanBeRetrievedInMonthRange() {
 	// GIVEN
 	aRecord, _ := ledger.NewRecord(
@@ -147,7 +184,7 @@ func (suite *RecordDaoTestSuite) Test_Given_anExpenseRecord_WHEN_theRecordIsSave
 	_ = suite.recordDao.SaveTx(context.Background(), suite.testCurrentAccount.Id(), aRecord, tx)
 	_ = tx.Commit()
 
-	records, err := suite.recordDao.GetRecordsForMonth(suite.testCurrentAccount.Id(), ledger.MakeCalendarMonthFromDate(suite.testRecordDate))
+	records, err := suite.recordDao.GetRecordsForPeriod(suite.testCurrentAccount.Id(), ledger.MakeCalendarMonthFromDate(suite.testRecordDate))
 
 	// THEN
 	assert.Nil(suite.T(), err)
@@ -182,7 +219,7 @@ func (suite *RecordDaoTestSuite) Test_Given_aTransferRecord_WHEN_theRecordIsSave
 	_ = suite.recordDao.SaveTx(context.Background(), suite.testCurrentAccount.Id(), aRecord, tx)
 	_ = tx.Commit()
 
-	records, err := suite.recordDao.GetRecordsForMonth(suite.testCurrentAccount.Id(), ledger.MakeCalendarMonthFromDate(suite.testRecordDate))
+	records, err := suite.recordDao.GetRecordsForPeriod(suite.testCurrentAccount.Id(), ledger.MakeCalendarMonthFromDate(suite.testRecordDate))
 
 	// THEN
 	assert.Nil(suite.T(), err)
@@ -261,7 +298,7 @@ func (suite *RecordDaoTestSuite) Test_Given_records_WHEN_loadingRecordsForLastPe
 	_ = tx.Commit()
 
 	tx, _ = suite.recordDao.BeginTx()
-	records, err := suite.recordDao.GetRecordsForLastPeriod(context.Background(), suite.testCurrentAccount.Id(), tx)
+	records, err := suite.recordDao.GetRecordsForLastPeriod(context.Background(), suite.testCurrentAccount.Id(), ledger.DefaultUserProfile(), tx)
 	_ = tx.Commit()
 
 	// THEN
@@ -292,7 +329,7 @@ func (suite *RecordDaoTestSuite) Test_Given_records_WHEN_searchingBySearchTerm_T
 
 	// WHEN
 	userId := userAndAccounts.First()
-	fromDate := time.Date(2021, time.June, 10, 0, 0, 0, 0, time.UTC)
+	fromDate := time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)
 	toDate := time.Date(2021, time.July, 1, 0, 0, 0, 0, time.UTC)
 	records, err := suite.recordDao.Search(userAndAccounts[userId][0], dao.RecordSearch{
 		SearchTerm: "Birthday",
@@ -305,7 +342,108 @@ func (suite *RecordDaoTestSuite) Test_Given_records_WHEN_searchingBySearchTerm_T
 	assert.Equal(suite.T(), 1, records.Len())
 }
 
-func (suite *RecordDaoTestSuite) Test_Given_aRecordWithAmountInDifferentCurrencyThanAccount_WHEN_recordIsSaved_THEN_currencyIsSetToAccountsCurrency() {
+func (suite *RecordDaoTestSuite) Test_Given_records_WHEN_searchingWithLimit_THEN_summaryCoversAllMatchingRecords() {
+	// GIVEN
+	userAndAccounts, err := simulateRecords(TestDB, 1, ledger.MakeCalendarMonth(2021, time.June), ledger.MakeCalendarMonth(2021, time.July))
+	assert.Nil(suite.T(), err)
+
+	userId := userAndAccounts.First()
+	fromDate := time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(2021, time.July, 31, 0, 0, 0, 0, time.UTC)
+	search := dao.RecordSearch{
+		FromDate:    &fromDate,
+		ToDate:      &toDate,
+		RecordTypes: []ledger.RecordType{ledger.Income},
+	}
+	allRecords, _ := suite.recordDao.Search(userAndAccounts[userId][0], search)
+
+	// WHEN
+	search.Limit = 1
+	page, err := suite.recordDao.Search(userAndAccounts[userId][0], search)
+	summary, summaryErr := suite.recordDao.Summarize(userAndAccounts[userId][0], search)
+
+	// THEN
+	expectedIncome, _ := allRecords.TotalIncome()
+
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), summaryErr)
+	assert.Equal(suite.T(), 1, page.Len())
+	assert.Equal(suite.T(), expectedIncome.String(), summary.TotalIncome.String())
+}
+
+func (suite *RecordDaoTestSuite) Test_Given_aRecord_WHEN_updatedTwiceFromTheSameVersion_THEN_secondUpdateIsAConflict() {
+	// GIVEN
+	aRecord, _ := ledger.NewRecord(
+		ledger.RecordId(1),
+		"Salary",
+		suite.testSalaryCategory,
+		quickMoney("AED", 100000),
+		suite.testRecordDate,
+		ledger.Income,
+		ledger.NoSourceAccount,
+		ledger.NoBeneficiaryAccount,
+		ledger.NoBeneficiaryType,
+		ledger.NoTransferReference,
+		ledger.MustMakeUpdatedByUserId(suite.testUser.Id()),
+	)
+
+	tx, _ := suite.recordDao.BeginTx()
+	_ = suite.recordDao.SaveTx(context.Background(), suite.testCurrentAccount.Id(), aRecord, tx)
+	_ = tx.Commit()
+
+	edited, _ := aRecord.Edit("Bonus", suite.testSalaryCategory, quickMoney("AED", 200000), suite.testRecordDate, ledger.Income, ledger.NoSplits, ledger.MustMakeUpdatedByUserId(suite.testUser.Id()))
+
+	// WHEN
+	tx, _ = suite.recordDao.BeginTx()
+	firstErr := suite.recordDao.UpdateTx(context.Background(), suite.testCurrentAccount.Id(), edited, tx)
+	secondErr := suite.recordDao.UpdateTx(context.Background(), suite.testCurrentAccount.Id(), edited, tx)
+	_ = tx.Commit()
+
+	tx, _ = suite.recordDao.BeginTx()
+	record, _ := suite.recordDao.GetRecordByIdTx(context.Background(), aRecord.Id(), suite.testCurrentAccount.Id(), tx)
+	_ = tx.Commit()
+
+	// THEN
+	assert.Nil(suite.T(), firstErr)
+	assert.NotNil(suite.T(), secondErr)
+	assert.EqualValues(suite.T(), pkg.ErrRecordVersionConflict, secondErr.(pkg.ValidationError).Code())
+	assert.EqualValues(suite.T(), "Bonus", record.Note())
+	assert.EqualValues(suite.T(), "AED 2000.00", record.Amount().String())
+	assert.EqualValues(suite.T(), ledger.Version(2), record.Version())
+	assert.EqualValues(suite.T(), ledger.MustMakeUpdatedByUserId(suite.testUser.Id()), record.ModifiedBy())
+}
+
+func (suite *RecordDaoTestSuite) Test_Given_aTransfer_WHEN_oneRecordIsUpdatedAlone_THEN_updateIsRefusedAndPairIsUnchanged() {
+	// GIVEN
+	reference := ledger.MakeTransferReference()
+	debit, _ := ledger.NewRecord(ledger.RecordId(1), "Savings", suite.testSavingsCategory, quickMoney("AED", -50000), suite.testRecordDate, ledger.Transfer, suite.testCurrentAccount.Id(), suite.testSavingsAccount.Id(), suite.testSavingsAccount.Type(), reference, ledger.MustMakeUpdatedByUserId(suite.testUser.Id()))
+	credit, _ := ledger.NewRecord(ledger.RecordId(2), "Savings", suite.testSavingsCategory, quickMoney("AED", 50000), suite.testRecordDate, ledger.Transfer, suite.testCurrentAccount.Id(), suite.testSavingsAccount.Id(), suite.testSavingsAccount.Type(), reference, ledger.MustMakeUpdatedByUserId(suite.testUser.Id()))
+
+	tx, _ := suite.recordDao.BeginTx()
+	_ = suite.recordDao.SaveTx(context.Background(), suite.testCurrentAccount.Id(), debit, tx)
+	_ = suite.recordDao.SaveTx(context.Background(), suite.testSavingsAccount.Id(), credit, tx)
+	_ = tx.Commit()
+
+	edited, _ := debit.Edit("Holiday", suite.testSavingsCategory, quickMoney("AED", -10000), suite.testRecordDate, ledger.Transfer, ledger.NoSplits, ledger.MustMakeUpdatedByUserId(suite.testUser.Id()))
+
+	// WHEN
+	tx, _ = suite.recordDao.BeginTx()
+	updateErr := suite.recordDao.UpdateTx(context.Background(), suite.testCurrentAccount.Id(), edited, tx)
+	deleteErr := suite.recordDao.DeleteTx(context.Background(), suite.testCurrentAccount.Id(), debit.Id(), debit.Version(), tx)
+	transfer, err := suite.recordDao.GetTransferPairTx(context.Background(), reference, suite.testUser.Id(), tx)
+	_ = tx.Commit()
+
+	// THEN
+	assert.NotNil(suite.T(), updateErr)
+	assert.NotNil(suite.T(), deleteErr)
+	assert.Nil(suite.T(), err)
+	assert.EqualValues(suite.T(), debit.Id(), transfer.Debit().Id())
+	assert.EqualValues(suite.T(), "Savings", transfer.Debit().Note())
+	assert.EqualValues(suite.T(), credit.Id(), transfer.Credit().Id())
+	assert.EqualValues(suite.T(), "AED 500.00", transfer.Credit().Amount().String())
+}
+
+func (suite *RecordDaoTestSuite) Test_Given_aRecordWithAmountInDifferentCurrencyThanAccount_WHEN_recordIsSaved_THEN_errorIsReturned() {
 	// GIVEN
 	aRecord, _ := ledger.NewRecord(
 		ledger.RecordId(1),
@@ -324,19 +462,100 @@ func (suite *RecordDaoTestSuite) Test_Given_aRecordWithAmountInDifferentCurrency
 	// WHEN
 	tx, _ := suite.recordDao.BeginTx()
 	err := suite.recordDao.SaveTx(context.Background(), suite.testCurrentAccount.Id(), aRecord, tx)
+	_ = tx.Rollback()
+
+	records, _ := suite.recordDao.GetRecordsForPeriod(suite.testCurrentAccount.Id(), ledger.MakeCalendarMonthFromDate(suite.testRecordDate))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.EqualValues(suite.T(), pkg.ErrAmountMismatchingCurrencies, errorCode(err, 0))
+	assert.Equal(suite.T(), "amount must be in AED, the currency of the account", err.(pkg.ValidationError).InvalidFields()["amount"])
+	assert.Equal(suite.T(), 0, records.Len())
+}
+
+func (suite *RecordDaoTestSuite) Test_Given_aTransferBetweenAccountsWithDifferentCurrencies_WHEN_saved_THEN_exchangeRateIsStoredOnBothRecords() {
+	// GIVEN
+	dollarAccount, _ := ledger.NewAccount(ledger.AccountId(3), "Dollars", ledger.AccountTypeCurrent, "USD", ledger.MustMakeUpdatedByUserId(suite.testUser.Id()))
+	tx, _ := suite.recordDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), suite.testUser.Id(), ledger.Accounts{dollarAccount}, tx)
 	_ = tx.Commit()
 
-	records, _ := suite.recordDao.GetRecordsForMonth(suite.testCurrentAccount.Id(), ledger.MakeCalendarMonthFromDate(suite.testRecordDate))
+	transfer, _ := ledger.NewTransfer(
+		ledger.RecordId(1),
+		ledger.RecordId(2),
+		"Savings",
+		suite.testSavingsCategory,
+		quickMoney("USD", 10000),
+		nil,
+		ledger.ExchangeRate("3.6725"),
+		suite.testRecordDate,
+		dollarAccount,
+		suite.testSavingsAccount,
+		ledger.MustMakeUpdatedByUserId(suite.testUser.Id()),
+	)
+
+	// WHEN
+	tx, _ = suite.recordDao.BeginTx()
+	debitErr := suite.recordDao.SaveTx(context.Background(), dollarAccount.Id(), transfer.Debit(), tx)
+	creditErr := suite.recordDao.SaveTx(context.Background(), suite.testSavingsAccount.Id(), transfer.Credit(), tx)
+	_ = tx.Commit()
+
+	tx, _ = suite.recordDao.BeginTx()
+	saved, err := suite.recordDao.GetTransferPairTx(context.Background(), transfer.Reference(), suite.testUser.Id(), tx)
+	_ = tx.Rollback()
 
 	// THEN
+	assert.Nil(suite.T(), debitErr)
+	assert.Nil(suite.T(), creditErr)
 	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "USD -100.00", saved.Debit().Amount().String())
+	assert.Equal(suite.T(), "AED 367.25", saved.Credit().Amount().String())
+	assert.Equal(suite.T(), ledger.ExchangeRate("3.6725"), saved.Debit().ExchangeRate())
+	assert.Equal(suite.T(), ledger.ExchangeRate("3.6725"), saved.Credit().ExchangeRate())
+}
+
+func (suite *RecordDaoTestSuite) Test_Given_aSplitRecord_WHEN_theRecordIsSavedAndEdited_THEN_linesAreReplaced() {
+	// GIVEN
+	billsSplit, _ := ledger.NewRecordSplit("Electricity", suite.testBillsCategory, quickMoney("AED", 30000))
+	savingsSplit, _ := ledger.NewRecordSplit("Emergency fund", suite.testSavingsCategory, quickMoney("AED", 20000))
+	aRecord, _ := ledger.NewSplitRecord(
+		ledger.RecordId(1),
+		"Utilities",
+		suite.testBillsCategory,
+		quickMoney("AED", 50000),
+		suite.testRecordDate,
+		ledger.Expense,
+		ledger.RecordSplits{billsSplit, savingsSplit},
+		ledger.MustMakeUpdatedByUserId(suite.testUser.Id()),
+	)
+
+	tx, _ := suite.recordDao.BeginTx()
+	saveErr := suite.recordDao.SaveTx(context.Background(), suite.testCurrentAccount.Id(), aRecord, tx)
+	_ = tx.Commit()
+
+	records, _ := suite.recordDao.GetRecordsForPeriod(suite.testCurrentAccount.Id(), ledger.MakeCalendarMonthFromDate(suite.testRecordDate))
+
+	// WHEN
+	edited, _ := aRecord.Edit("Utilities", suite.testBillsCategory, aRecord.Amount(), suite.testRecordDate, ledger.Expense, ledger.NoSplits, ledger.MustMakeUpdatedByUserId(suite.testUser.Id()))
+
+	tx, _ = suite.recordDao.BeginTx()
+	updateErr := suite.recordDao.UpdateTx(context.Background(), suite.testCurrentAccount.Id(), edited, tx)
+	_ = tx.Commit()
+
+	tx, _ = suite.recordDao.BeginTx()
+	saved, err := suite.recordDao.GetRecordByIdTx(context.Background(), aRecord.Id(), suite.testCurrentAccount.Id(), tx)
+	_ = tx.Rollback()
+
+	// THEN
+	assert.Nil(suite.T(), saveErr)
 	assert.Equal(suite.T(), 1, records.Len())
-	assert.EqualValues(suite.T(), ledger.RecordId(1), records[0].Id())
-	assert.EqualValues(suite.T(), "Savings", records[0].Note())
-	assert.EqualValues(suite.T(), suite.testSavingsCategory.Name(), records[0].Category().Name())
-	assert.EqualValues(suite.T(), "AED -500.00", records[0].Amount().String())
-	assert.EqualValues(suite.T(), ledger.Transfer, records[0].Type())
-	assert.EqualValues(suite.T(), "2021-07-05T00:00:00+0000", records[0].DateUTCString())
-	assert.EqualValues(suite.T(), ledger.AccountId(2), records[0].BeneficiaryId())
-	assert.EqualValues(suite.T(), ledger.AccountTypeSaving, records[0].BeneficiaryType())
+	assert.Len(suite.T(), records[0].Splits(), 2)
+	assert.Equal(suite.T(), "Bills", records[0].Splits()[0].Category().Name())
+	assert.Equal(suite.T(), "AED -300.00", records[0].Splits()[0].Amount().String())
+	assert.Equal(suite.T(), "Emergency fund", records[0].Splits()[1].Note())
+	assert.Equal(suite.T(), "AED -200.00", records[0].Splits()[1].Amount().String())
+
+	assert.Nil(suite.T(), updateErr)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), saved.IsSplit())
 }
@@ -16,6 +16,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
 	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
 	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
 )
@@ -145,7 +146,10 @@ func (suite *RecordsHandlerTestSuite) Test_GIVEN_validCreateRecordRequest_WHEN_c
 			"currency": "AED",
 			"value": 10000
 		},
-		"date": "2021-01-01T22:08:41+0000",
+		"date": "2021-01-01T00:00:00+0000",
+		"occurredAt": "2021-01-01T22:08:41Z",
+		"version": 1,
+		"createdBy": {"kind": "USER", "userId": 1},
 		"type": "INCOME",
 		"account": {
 			"id": 1630067787222,
@@ -179,6 +183,9 @@ func (suite *RecordsHandlerTestSuite) Test_GIVEN_validCreateRecordRequest_WHEN_c
 				"value": 10000
 			},
 			"date": "2021-01-01T00:00:00+0000",
+			"occurredAt": "2021-01-01T22:08:41Z",
+			"version": 1,
+			"createdBy": {"kind": "USER", "userId": 1},
 			"type": "INCOME"
 		}],
 		"summary": {
@@ -296,6 +303,9 @@ func (suite *RecordsHandlerTestSuite) Test_GIVEN_aRecordRequestWithMaxIncome_WHE
 				"value": 9223372036854775807
 			},
 			"date": "2021-09-09T00:00:00+0000",
+			"occurredAt": "2021-09-09T00:00:00Z",
+			"version": 1,
+			"createdBy": {"kind": "USER", "userId": 1},
 			"type": "INCOME"
 		}],
 		"summary": {
@@ -413,6 +423,9 @@ func (suite *RecordsHandlerTestSuite) Test_GIVEN_aRecordRequestWithMaxExpense_WH
 				"value": -9223372036854775807
 			},
 			"date": "2021-09-09T00:00:00+0000",
+			"occurredAt": "2021-09-09T00:00:00Z",
+			"version": 1,
+			"createdBy": {"kind": "USER", "userId": 1},
 			"type": "EXPENSE"
 		}],
 		"summary": {
@@ -526,6 +539,9 @@ func (suite *RecordsHandlerTestSuite) Test_GIVEN_aTransferRecordWithPositiveAmou
 				"value": -10000
 			},
 			"date": "2023-01-01T00:00:00+0000",
+			"occurredAt": "2023-01-01T22:08:41Z",
+			"version": 1,
+			"createdBy": {"kind": "USER", "userId": 1},
 			"type": "TRANSFER",
             "transfer": {
                 "beneficiary": {
@@ -576,6 +592,9 @@ func (suite *RecordsHandlerTestSuite) Test_GIVEN_aTransferRecordWithPositiveAmou
 				"value": 10000
 			},
 			"date": "2023-01-01T00:00:00+0000",
+			"occurredAt": "2023-01-01T22:08:41Z",
+			"version": 1,
+			"createdBy": {"kind": "USER", "userId": 1},
 			"type": "TRANSFER",
             "transfer": {
                 "beneficiary": {
@@ -656,6 +675,9 @@ func (suite *RecordsHandlerTestSuite) Test_GIVEN_aTransferRecordWithNegativeAmou
 				"value": -10000
 			},
 			"date": "2023-01-01T00:00:00+0000",
+			"occurredAt": "2023-01-01T22:08:41Z",
+			"version": 1,
+			"createdBy": {"kind": "USER", "userId": 1},
 			"type": "TRANSFER",
             "transfer": {
                 "beneficiary": {
@@ -706,6 +728,9 @@ func (suite *RecordsHandlerTestSuite) Test_GIVEN_aTransferRecordWithNegativeAmou
 				"value": 10000
 			},
 			"date": "2023-01-01T00:00:00+0000",
+			"occurredAt": "2023-01-01T22:08:41Z",
+			"version": 1,
+			"createdBy": {"kind": "USER", "userId": 1},
 			"type": "TRANSFER",
             "transfer": {
                 "beneficiary": {
@@ -736,3 +761,975 @@ func (suite *RecordsHandlerTestSuite) Test_GIVEN_aTransferRecordWithNegativeAmou
 	assert.Equal(suite.T(), 200, w.Code)
 	assert.JSONEq(suite.T(), expected, w.Body.String())
 }
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_records_WHEN_searchingRecordsByTypeAndCategory_THEN_onlyMatchingRecordsAreReturned() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.simulatedCurrentAccount.Id()
+
+	for _, recordType := range []ledger.RecordType{ledger.Income, ledger.Expense} {
+		var createRequest svc.CreateRecordRequest
+		createRequest.Note = "Salary"
+		createRequest.Amount.Currency = "AED"
+		createRequest.Amount.Value = 100_00
+		createRequest.Category.Id = uint64(suite.simulatedSalaryCategory.Id())
+		createRequest.DateUTC = "2021-01-01T22:08:41+00:00"
+		createRequest.Type = string(recordType)
+
+		data, _ := json.Marshal(createRequest)
+
+		var buffer bytes.Buffer
+		buffer.Write(data)
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records", accountId), &buffer)
+		AddAuthorizationHeader(r, userId)
+
+		w := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+		assert.Equal(suite.T(), 201, w.Code)
+	}
+
+	// WHEN
+	r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2021-01-01&to=2021-01-31&type=INCOME&category=salary", accountId), nil)
+	AddAuthorizationHeader(r, userId)
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	expected := `{
+		"records": [{
+			"id": 1,
+			"note": "Salary",
+			"category": {
+				"id": 1630067305041,
+				"name": "Salary"
+			},
+			"amount": {
+				"currency": "AED",
+				"value": 10000
+			},
+			"date": "2021-01-01T00:00:00+0000",
+			"occurredAt": "2021-01-01T22:08:41Z",
+			"version": 1,
+			"createdBy": {"kind": "USER", "userId": 1},
+			"type": "INCOME"
+		}],
+		"summary": {
+			"totalExpenses": {
+				"currency": "AED",
+				"value": 0
+			},
+			"totalIncome": {
+				"currency": "AED",
+				"value": 10000
+			},
+			"totalSavings": {
+				"currency": "AED",
+				"value": 0
+			}
+		},
+		"search": {
+			"from": "2021-01-01T00:00:00Z",
+			"to": "2021-01-31T00:00:00Z"
+		}
+	}`
+
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_anInvalidRecordType_WHEN_searchingRecords_THEN_400IsReturned() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.simulatedCurrentAccount.Id()
+
+	r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2021-01-01&type=REFUND", accountId), nil)
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	expected := `{
+		"detail": "Invalid search parameters",
+		"instance": "/api/v1/accounts/1630067787222/records",
+		"status": 400,
+		"title": "RECORD_SEARCH_VALIDATION_FAILED",
+		"type": "/api/v1/problems/1026",
+		"invalidParams": {
+			"type": "type 'REFUND' must be one of INCOME, EXPENSE or TRANSFER"
+		}
+	}`
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_anAccountOfAnotherUser_WHEN_searchingRecords_THEN_404IsReturned() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.otherCurrentAccount.Id()
+
+	r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2021-01-01", accountId), nil)
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 404, w.Code)
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_moreRecordsThanTheLimit_WHEN_searchingRecords_THEN_recordsArePagedAndSummaryCoversAllRecords() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.simulatedCurrentAccount.Id()
+
+	for _, date := range []string{"2021-01-01T10:00:00+00:00", "2021-01-02T10:00:00+00:00", "2021-01-03T10:00:00+00:00"} {
+		var createRequest svc.CreateRecordRequest
+		createRequest.Note = "Salary"
+		createRequest.Amount.Currency = "AED"
+		createRequest.Amount.Value = 100_00
+		createRequest.Category.Id = uint64(suite.simulatedSalaryCategory.Id())
+		createRequest.DateUTC = date
+		createRequest.Type = string(ledger.Income)
+
+		data, _ := json.Marshal(createRequest)
+
+		var buffer bytes.Buffer
+		buffer.Write(data)
+		// The records are alike, so they are forced past duplicate detection
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records?force=true", accountId), &buffer)
+		AddAuthorizationHeader(r, userId)
+
+		w := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+		assert.Equal(suite.T(), 201, w.Code)
+	}
+
+	search := func(cursor string) svc.RecordsResponse {
+		r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2021-01-01&to=2021-01-31&limit=2&cursor=%s", accountId, cursor), nil)
+		AddAuthorizationHeader(r, userId)
+
+		w := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+		assert.Equal(suite.T(), 200, w.Code)
+
+		var resp svc.RecordsResponse
+		assert.Nil(suite.T(), json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	// WHEN
+	firstPage := search("")
+	secondPage := search(firstPage.NextCursor)
+	previousPage := search(secondPage.PrevCursor)
+
+	// THEN
+	assert.Len(suite.T(), firstPage.Records, 2)
+	assert.Equal(suite.T(), uint64(3), firstPage.Records[0].Id)
+	assert.Equal(suite.T(), uint64(2), firstPage.Records[1].Id)
+	assert.NotEmpty(suite.T(), firstPage.NextCursor)
+	assert.Empty(suite.T(), firstPage.PrevCursor)
+	assert.Equal(suite.T(), int64(300_00), firstPage.Summary.TotalIncome.Value)
+
+	assert.Len(suite.T(), secondPage.Records, 1)
+	assert.Equal(suite.T(), uint64(1), secondPage.Records[0].Id)
+	assert.Empty(suite.T(), secondPage.NextCursor)
+	assert.NotEmpty(suite.T(), secondPage.PrevCursor)
+	assert.Equal(suite.T(), int64(300_00), secondPage.Summary.TotalIncome.Value)
+
+	assert.Equal(suite.T(), firstPage.Records, previousPage.Records)
+	assert.NotEmpty(suite.T(), previousPage.NextCursor)
+	assert.Empty(suite.T(), previousPage.PrevCursor)
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_recordsOnTheSameDate_WHEN_searchingRecords_THEN_recordsAreListedNewestFirstByIdAcrossPages() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.simulatedCurrentAccount.Id()
+
+	for _, note := range []string{"First", "Second", "Third", "Fourth"} {
+		// The records are alike, so they are forced past duplicate detection
+		w := suite.postIncomeRecord(note, "2021-01-02T10:00:00+00:00", "?force=true")
+		assert.Equal(suite.T(), 201, w.Code)
+	}
+	assert.Equal(suite.T(), 201, suite.postIncomeRecord("Older", "2021-01-01T10:00:00+00:00", "?force=true").Code)
+
+	search := func(cursor string) svc.RecordsResponse {
+		r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2021-01-01&to=2021-01-31&limit=2&cursor=%s", accountId, cursor), nil)
+		AddAuthorizationHeader(r, userId)
+
+		w := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+		assert.Equal(suite.T(), 200, w.Code)
+
+		var resp svc.RecordsResponse
+		assert.Nil(suite.T(), json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	notes := func(resp svc.RecordsResponse) []string {
+		notes := []string{}
+		for _, record := range resp.Records {
+			notes = append(notes, record.Note)
+		}
+		return notes
+	}
+
+	// WHEN
+	firstPage := search("")
+	secondPage := search(firstPage.NextCursor)
+	thirdPage := search(secondPage.NextCursor)
+	previousPage := search(thirdPage.PrevCursor)
+
+	// THEN
+	assert.Equal(suite.T(), []string{"Fourth", "Third"}, notes(firstPage))
+	assert.Equal(suite.T(), []string{"Second", "First"}, notes(secondPage))
+	assert.Equal(suite.T(), []string{"Older"}, notes(thirdPage))
+	assert.Empty(suite.T(), thirdPage.NextCursor)
+	assert.Equal(suite.T(), notes(secondPage), notes(previousPage))
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aCursor_WHEN_searchingWithDifferentFilters_THEN_400IsReturned() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.simulatedCurrentAccount.Id()
+
+	for _, date := range []string{"2021-01-01T10:00:00+00:00", "2021-01-02T10:00:00+00:00"} {
+		assert.Equal(suite.T(), 201, suite.postIncomeRecord("Salary", date, "?force=true").Code)
+	}
+
+	search := func(query string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?%s", accountId, query), nil)
+		AddAuthorizationHeader(r, userId)
+
+		w := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+		return w
+	}
+
+	var firstPage svc.RecordsResponse
+	w := search("from=2021-01-01&to=2021-01-31&limit=1")
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.NewDecoder(w.Body).Decode(&firstPage))
+	assert.NotEmpty(suite.T(), firstPage.NextCursor)
+
+	// WHEN
+	w = search("from=2021-01-01&to=2021-01-31&limit=1&search=Bonus&cursor=" + firstPage.NextCursor)
+
+	// THEN
+	expected := fmt.Sprintf(`{
+		"detail": "Invalid search parameters",
+		"instance": "/api/v1/accounts/%d/records",
+		"status": 400,
+		"title": "RECORD_SEARCH_VALIDATION_FAILED",
+		"type": "/api/v1/problems/1026",
+		"cursor": "cursor '%s' was issued for different search parameters"
+	}`, accountId, firstPage.NextCursor)
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_anInvalidCursor_WHEN_searchingRecords_THEN_400IsReturned() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.simulatedCurrentAccount.Id()
+
+	r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?cursor=notACursor&limit=0", accountId), nil)
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	expected := `{
+		"detail": "Invalid search parameters",
+		"instance": "/api/v1/accounts/1630067787222/records",
+		"status": 400,
+		"title": "RECORD_SEARCH_VALIDATION_FAILED",
+		"type": "/api/v1/problems/1026",
+		"cursor": "cursor 'notACursor' is invalid",
+		"limit": "limit '0' must be a number between 1 and 500"
+	}`
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
+
+func (suite *RecordsHandlerTestSuite) createIncomeRecord(note string, date string) svc.RecordResponse {
+	var createRequest svc.CreateRecordRequest
+	createRequest.Note = note
+	createRequest.Amount.Currency = "AED"
+	createRequest.Amount.Value = 100_00
+	createRequest.Category.Id = uint64(suite.simulatedSalaryCategory.Id())
+	createRequest.DateUTC = date
+	createRequest.Type = string(ledger.Income)
+
+	data, _ := json.Marshal(createRequest)
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records", suite.simulatedCurrentAccount.Id()), bytes.NewBuffer(data))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	assert.Equal(suite.T(), 201, w.Code)
+
+	var resp svc.RecordResponse
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	return resp
+}
+
+func (suite *RecordsHandlerTestSuite) postIncomeRecord(note string, date string, query string) *httptest.ResponseRecorder {
+	var createRequest svc.CreateRecordRequest
+	createRequest.Note = note
+	createRequest.Amount.Currency = "AED"
+	createRequest.Amount.Value = 100_00
+	createRequest.Category.Id = uint64(suite.simulatedSalaryCategory.Id())
+	createRequest.DateUTC = date
+	createRequest.Type = string(ledger.Income)
+
+	data, _ := json.Marshal(createRequest)
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records%s", suite.simulatedCurrentAccount.Id(), query), bytes.NewBuffer(data))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aRecord_WHEN_similarRecordIsCreatedWithinTheDuplicateWindow_THEN_409IsReturnedWithTheDuplicate() {
+	// GIVEN
+	record := suite.createIncomeRecord("Salary", "2021-01-01T10:00:00+00:00")
+
+	// WHEN
+	w := suite.postIncomeRecord("salary.", "2021-01-02T10:00:00+00:00", "")
+
+	// THEN
+	expected := fmt.Sprintf(`{
+		"type": "/api/v1/problems/%d",
+		"title": "RECORD_DUPLICATED",
+		"status": 409,
+		"detail": "Record is likely a duplicate of 1 existing record(s)",
+		"instance": "/api/v1/accounts/%d/records",
+		"duplicateRecordIds": "%d"
+	}`, pkg.ErrRecordDuplicated, suite.simulatedCurrentAccount.Id(), record.Id)
+	assert.Equal(suite.T(), 409, w.Code)
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aRecord_WHEN_similarRecordIsForced_THEN_recordIsCreated() {
+	// GIVEN
+	_ = suite.createIncomeRecord("Salary", "2021-01-01T10:00:00+00:00")
+
+	// WHEN
+	forced := suite.postIncomeRecord("Salary", "2021-01-01T10:00:00+00:00", "?force=true")
+	outsideWindow := suite.postIncomeRecord("Salary", "2021-01-05T10:00:00+00:00", "")
+
+	// THEN
+	assert.Equal(suite.T(), 201, forced.Code)
+	assert.Equal(suite.T(), 201, outsideWindow.Code)
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aRecord_WHEN_recordIsReplacedWithCurrentVersion_THEN_recordIsUpdatedAndVersionIsIncremented() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.simulatedCurrentAccount.Id()
+	record := suite.createIncomeRecord("Salary", "2021-01-01T10:00:00+00:00")
+
+	var updateRequest svc.UpdateRecordRequest
+	updateRequest.Note = "Electricity"
+	updateRequest.Amount.Currency = "AED"
+	updateRequest.Amount.Value = 250_00
+	updateRequest.Category.Id = uint64(suite.simulatedSalaryCategory.Id())
+	updateRequest.DateUTC = "2021-01-05T10:00:00+00:00"
+	updateRequest.Type = string(ledger.Expense)
+
+	data, _ := json.Marshal(updateRequest)
+
+	r, _ := http.NewRequest("PUT", fmt.Sprintf("/api/v1/accounts/%d/records/%d", accountId, record.Id), bytes.NewBuffer(data))
+	r.Header.Set("If-Match", `"1"`)
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	expected := `{
+		"id": 1,
+		"note": "Electricity",
+		"category": {
+			"id": 1630067305041,
+			"name": "Salary"
+		},
+		"amount": {
+			"currency": "AED",
+			"value": -25000
+		},
+		"date": "2021-01-05T00:00:00+0000",
+		"occurredAt": "2021-01-05T10:00:00Z",
+		"version": 2,
+		"createdBy": {"kind": "USER", "userId": 1},
+		"modifiedBy": {"kind": "USER", "userId": 1},
+		"type": "EXPENSE",
+		"account": {
+			"id": 1630067787222,
+			"currentBalance": {
+				"currency": "AED",
+				"value": -25000
+			}
+		}
+	}`
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Equal(suite.T(), `"2"`, w.Header().Get("ETag"))
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aRecordThatWasChanged_WHEN_recordIsPatchedWithStaleVersion_THEN_409IsReturned() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.simulatedCurrentAccount.Id()
+	record := suite.createIncomeRecord("Salary", "2021-01-01T10:00:00+00:00")
+
+	patch := func(note string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("PATCH", fmt.Sprintf("/api/v1/accounts/%d/records/%d", accountId, record.Id), bytes.NewBufferString(fmt.Sprintf(`{"note": %q, "version": 1}`, note)))
+		AddAuthorizationHeader(r, userId)
+
+		w := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(suite.T(), 200, patch("January Salary").Code)
+
+	// WHEN
+	w := patch("February Salary")
+
+	// THEN
+	expected := `{
+		"detail": "Record 1 has been changed. Expected version 1 but found version 2",
+		"instance": "/api/v1/accounts/1630067787222/records/1",
+		"status": 409,
+		"title": "RECORD_VERSION_CONFLICT",
+		"type": "/api/v1/problems/1028"
+	}`
+	assert.Equal(suite.T(), 409, w.Code)
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aRecord_WHEN_recordIsDeletedWithoutVersion_THEN_400IsReturned() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.simulatedCurrentAccount.Id()
+	record := suite.createIncomeRecord("Salary", "2021-01-01T10:00:00+00:00")
+
+	r, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/v1/accounts/%d/records/%d", accountId, record.Id), nil)
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aRecord_WHEN_recordIsDeletedWithCurrentVersion_THEN_recordIsNoLongerListed() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.simulatedCurrentAccount.Id()
+	record := suite.createIncomeRecord("Salary", "2021-01-01T10:00:00+00:00")
+
+	r, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/v1/accounts/%d/records/%d", accountId, record.Id), nil)
+	r.Header.Set("If-Match", `W/"1"`)
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 204, w.Code)
+
+	r, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2021-01-01&to=2021-01-31", accountId), nil)
+	AddAuthorizationHeader(r, userId)
+
+	w = httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	var resp svc.RecordsResponse
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Empty(suite.T(), resp.Records)
+}
+
+func (suite *RecordsHandlerTestSuite) createTransferRecord(amount int64, date string) svc.RecordResponse {
+	var createRequest svc.CreateRecordRequest
+	createRequest.Note = "Savings"
+	createRequest.Amount.Currency = "AED"
+	createRequest.Amount.Value = amount
+	createRequest.Category.Id = uint64(suite.simulatedSalaryCategory.Id())
+	createRequest.DateUTC = date
+	createRequest.Type = string(ledger.Transfer)
+	createRequest.Transfer.Beneficiary.Id = uint64(suite.simulatedSavingAccount.Id())
+
+	data, _ := json.Marshal(createRequest)
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records", suite.simulatedCurrentAccount.Id()), bytes.NewBuffer(data))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	assert.Equal(suite.T(), 201, w.Code)
+
+	var resp svc.RecordResponse
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	return resp
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aTransfer_WHEN_creditIsPatched_THEN_bothRecordsOfTheTransferAreChanged() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	debit := suite.createTransferRecord(100_00, "2023-01-01T10:00:00+00:00")
+	creditId := debit.Id + 1
+
+	body := `{"note": "Emergency Fund", "amount": {"currency": "AED", "value": 25000}, "date": "2023-01-05T10:00:00+00:00"}`
+	r, _ := http.NewRequest("PATCH", fmt.Sprintf("/api/v1/accounts/%d/records/%d", suite.simulatedSavingAccount.Id(), creditId), bytes.NewBufferString(body))
+	r.Header.Set("If-Match", `"1"`)
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+
+	for _, account := range []ledger.Account{suite.simulatedCurrentAccount, suite.simulatedSavingAccount} {
+		r, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2023-01-01&to=2023-01-31", account.Id()), nil)
+		AddAuthorizationHeader(r, userId)
+
+		w = httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+
+		var resp svc.RecordsResponse
+		_ = json.NewDecoder(w.Body).Decode(&resp)
+		assert.Equal(suite.T(), 200, w.Code)
+		assert.Len(suite.T(), resp.Records, 1)
+		assert.Equal(suite.T(), "Emergency Fund", resp.Records[0].Note)
+		assert.Equal(suite.T(), "2023-01-05T00:00:00+0000", resp.Records[0].DateUTC)
+		assert.Equal(suite.T(), uint64(2), resp.Records[0].Version)
+		assert.Equal(suite.T(), int64(250_00), resp.Summary.TotalSavings.Value)
+	}
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aTransfer_WHEN_debitIsChangedToAnExpense_THEN_400IsReturned() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	debit := suite.createTransferRecord(100_00, "2023-01-01T10:00:00+00:00")
+
+	r, _ := http.NewRequest("PATCH", fmt.Sprintf("/api/v1/accounts/%d/records/%d", suite.simulatedCurrentAccount.Id(), debit.Id), bytes.NewBufferString(`{"type": "EXPENSE", "version": 1}`))
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aTransfer_WHEN_debitIsDeleted_THEN_bothRecordsOfTheTransferAreDeleted() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	debit := suite.createTransferRecord(100_00, "2023-01-01T10:00:00+00:00")
+
+	r, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/v1/accounts/%d/records/%d", suite.simulatedCurrentAccount.Id(), debit.Id), bytes.NewBufferString(`{"version": 1}`))
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 204, w.Code)
+
+	for _, account := range []ledger.Account{suite.simulatedCurrentAccount, suite.simulatedSavingAccount} {
+		r, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2023-01-01&to=2023-01-31", account.Id()), nil)
+		AddAuthorizationHeader(r, userId)
+
+		w = httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+
+		var resp svc.RecordsResponse
+		_ = json.NewDecoder(w.Body).Decode(&resp)
+		assert.Equal(suite.T(), 200, w.Code)
+		assert.Empty(suite.T(), resp.Records)
+	}
+}
+
+func (suite *RecordsHandlerTestSuite) createDollarAccount() ledger.Account {
+	dollarAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787225),
+		"Dollars",
+		ledger.AccountTypeCurrent,
+		"USD",
+		ledger.MustMakeUpdatedByUserId(suite.simulatedUser.Id()),
+	)
+
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), suite.simulatedUser.Id(), ledger.Accounts{dollarAccount}, tx)
+	_ = tx.Commit()
+
+	return dollarAccount
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_accountsWithDifferentCurrencies_WHEN_transferIsCreatedWithExchangeRate_THEN_beneficiaryIsCreditedInItsCurrency() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	dollarAccount := suite.createDollarAccount()
+
+	body := fmt.Sprintf(`{
+		"note": "Savings",
+		"category": {"id": %d},
+		"amount": {"currency": "USD", "value": 10000},
+		"date": "2023-01-01T10:00:00+00:00",
+		"type": "TRANSFER",
+		"transfer": {"beneficiary": {"id": %d}, "exchangeRate": "3.6725"}
+	}`, suite.simulatedSalaryCategory.Id(), suite.simulatedSavingAccount.Id())
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records", dollarAccount.Id()), bytes.NewBufferString(body))
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	var debit svc.RecordResponse
+	_ = json.NewDecoder(w.Body).Decode(&debit)
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Equal(suite.T(), svc.AmountResponse{Currency: "USD", Value: -100_00}, debit.Amount)
+	assert.Equal(suite.T(), "3.6725", debit.Transfer.ExchangeRate)
+
+	r, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2023-01-01&to=2023-01-31", suite.simulatedSavingAccount.Id()), nil)
+	AddAuthorizationHeader(r, userId)
+
+	w = httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	var resp svc.RecordsResponse
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Len(suite.T(), resp.Records, 1)
+	assert.Equal(suite.T(), svc.AmountResponse{Currency: "AED", Value: 367_25}, resp.Records[0].Amount)
+	assert.Equal(suite.T(), "3.6725", resp.Records[0].Transfer.ExchangeRate)
+}
+
+func (suite *RecordsHandlerTestSuite) createDollarTransferRecord(dollarAccount ledger.Account, receivedAmount int64) svc.RecordResponse {
+	body := fmt.Sprintf(`{
+		"note": "Savings",
+		"category": {"id": %d},
+		"amount": {"currency": "USD", "value": 10000},
+		"date": "2023-01-01T10:00:00+00:00",
+		"type": "TRANSFER",
+		"transfer": {"beneficiary": {"id": %d}, "receivedAmount": {"currency": "AED", "value": %d}}
+	}`, suite.simulatedSalaryCategory.Id(), suite.simulatedSavingAccount.Id(), receivedAmount)
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records", dollarAccount.Id()), bytes.NewBufferString(body))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	assert.Equal(suite.T(), 201, w.Code)
+
+	var resp svc.RecordResponse
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	return resp
+}
+
+func (suite *RecordsHandlerTestSuite) listTransferLegs(dollarAccount ledger.Account) (svc.RecordResponse, svc.RecordResponse) {
+	var legs []svc.RecordResponse
+	for _, accountId := range []ledger.AccountId{dollarAccount.Id(), suite.simulatedSavingAccount.Id()} {
+		r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2023-01-01&to=2023-01-31", accountId), nil)
+		AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+		w := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+
+		var resp svc.RecordsResponse
+		_ = json.NewDecoder(w.Body).Decode(&resp)
+		assert.Equal(suite.T(), 200, w.Code)
+		assert.Len(suite.T(), resp.Records, 1)
+		legs = append(legs, resp.Records[0])
+	}
+	return legs[0], legs[1]
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aCrossCurrencyTransfer_WHEN_onlyTheNoteIsPatched_THEN_amountsAndExchangeRateAreKept() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	dollarAccount := suite.createDollarAccount()
+	debit := suite.createDollarTransferRecord(dollarAccount, 366_99)
+
+	for _, leg := range []struct {
+		accountId ledger.AccountId
+		recordId  uint64
+		version   int
+	}{
+		{dollarAccount.Id(), debit.Id, 1},
+		{suite.simulatedSavingAccount.Id(), debit.Id + 1, 2},
+	} {
+		r, _ := http.NewRequest("PATCH", fmt.Sprintf("/api/v1/accounts/%d/records/%d", leg.accountId, leg.recordId), bytes.NewBufferString(fmt.Sprintf(`{"note": "Emergency Fund", "version": %d}`, leg.version)))
+		AddAuthorizationHeader(r, userId)
+
+		// WHEN
+		w := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+
+		// THEN
+		assert.Equal(suite.T(), 200, w.Code, w.Body.String())
+
+		patchedDebit, patchedCredit := suite.listTransferLegs(dollarAccount)
+		assert.Equal(suite.T(), "Emergency Fund", patchedCredit.Note)
+		assert.Equal(suite.T(), svc.AmountResponse{Currency: "USD", Value: -100_00}, patchedDebit.Amount)
+		assert.Equal(suite.T(), svc.AmountResponse{Currency: "AED", Value: 366_99}, patchedCredit.Amount)
+		assert.Equal(suite.T(), "3.6699", patchedCredit.Transfer.ExchangeRate)
+	}
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aCrossCurrencyTransfer_WHEN_amountIsPatchedFromEitherLeg_THEN_amountIsInTheCurrencyOfThatLeg() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	dollarAccount := suite.createDollarAccount()
+	created := suite.createDollarTransferRecord(dollarAccount, 367_25)
+
+	patch := func(accountId ledger.AccountId, recordId uint64, body string) {
+		r, _ := http.NewRequest("PATCH", fmt.Sprintf("/api/v1/accounts/%d/records/%d", accountId, recordId), bytes.NewBufferString(body))
+		AddAuthorizationHeader(r, userId)
+
+		w := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+		assert.Equal(suite.T(), 200, w.Code, w.Body.String())
+	}
+
+	// WHEN the amount sent is patched from the debit
+	patch(dollarAccount.Id(), created.Id, `{"amount": {"currency": "USD", "value": 20000}, "version": 1}`)
+
+	// THEN the exchange rate is kept
+	debit, credit := suite.listTransferLegs(dollarAccount)
+	assert.Equal(suite.T(), svc.AmountResponse{Currency: "USD", Value: -200_00}, debit.Amount)
+	assert.Equal(suite.T(), svc.AmountResponse{Currency: "AED", Value: 734_50}, credit.Amount)
+	assert.Equal(suite.T(), "3.6725", credit.Transfer.ExchangeRate)
+
+	// WHEN the amount received is patched from the credit
+	patch(suite.simulatedSavingAccount.Id(), created.Id+1, `{"amount": {"currency": "AED", "value": 73000}, "version": 2}`)
+
+	// THEN the amount sent is kept
+	debit, credit = suite.listTransferLegs(dollarAccount)
+	assert.Equal(suite.T(), svc.AmountResponse{Currency: "USD", Value: -200_00}, debit.Amount)
+	assert.Equal(suite.T(), svc.AmountResponse{Currency: "AED", Value: 730_00}, credit.Amount)
+	assert.Equal(suite.T(), "3.65", credit.Transfer.ExchangeRate)
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_accountsWithDifferentCurrencies_WHEN_transferIsCreatedWithoutExchangeRate_THEN_400IsReturned() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	dollarAccount := suite.createDollarAccount()
+
+	body := fmt.Sprintf(`{
+		"note": "Savings",
+		"category": {"id": %d},
+		"amount": {"currency": "USD", "value": 10000},
+		"date": "2023-01-01T10:00:00+00:00",
+		"type": "TRANSFER",
+		"transfer": {"beneficiary": {"id": %d}}
+	}`, suite.simulatedSalaryCategory.Id(), suite.simulatedSavingAccount.Id())
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records", dollarAccount.Id()), bytes.NewBufferString(body))
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "exchangeRate or receivedAmount is required when the accounts have different currencies")
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_anAmountInDifferentCurrencyThanAccount_WHEN_createRecordsEndpointIsCalled_THEN_400IsReturned() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+
+	var createRequest svc.CreateRecordRequest
+	createRequest.Note = "Salary"
+	createRequest.Amount.Currency = "USD"
+	createRequest.Amount.Value = 100_00
+	createRequest.Category.Id = uint64(suite.simulatedSalaryCategory.Id())
+	createRequest.DateUTC = "2023-01-01T10:00:00+00:00"
+	createRequest.Type = string(ledger.Income)
+
+	data, _ := json.Marshal(createRequest)
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records", suite.simulatedCurrentAccount.Id()), bytes.NewBuffer(data))
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "AMOUNT_MISMATCHING_CURRENCIES")
+}
+
+func (suite *RecordsHandlerTestSuite) createSplitCategories() (ledger.Category, ledger.Category) {
+	groceriesCategory, _ := ledger.NewCategory(ledger.CategoryId(1630067305043), "Groceries", ledger.MustMakeUpdatedByUserId(suite.simulatedUser.Id()))
+	householdCategory, _ := ledger.NewCategory(ledger.CategoryId(1630067305044), "Household", ledger.MustMakeUpdatedByUserId(suite.simulatedUser.Id()))
+
+	tx, _ := CategoryDao.BeginTx()
+	_ = CategoryDao.SaveTx(context.Background(), suite.simulatedUser.Id(), ledger.Categories{groceriesCategory, householdCategory}, tx)
+	_ = tx.Commit()
+
+	return groceriesCategory, householdCategory
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_anExpenseSplitAcrossCategories_WHEN_createRecordsEndpointIsCalled_THEN_recordCanBeFoundByTheCategoryOfALine() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	accountId := suite.simulatedCurrentAccount.Id()
+	groceriesCategory, householdCategory := suite.createSplitCategories()
+
+	body := fmt.Sprintf(`{
+		"note": "Supermarket",
+		"category": {"id": %d},
+		"amount": {"currency": "AED", "value": 15000},
+		"date": "2023-01-01T10:00:00+00:00",
+		"type": "EXPENSE",
+		"splits": [
+			{"note": "Vegetables", "category": {"id": %d}, "amount": {"currency": "AED", "value": 10000}},
+			{"note": "Detergent", "category": {"id": %d}, "amount": {"currency": "AED", "value": 5000}}
+		]
+	}`, groceriesCategory.Id(), groceriesCategory.Id(), householdCategory.Id())
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records", accountId), bytes.NewBufferString(body))
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	var created svc.RecordResponse
+	_ = json.NewDecoder(w.Body).Decode(&created)
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Equal(suite.T(), svc.AmountResponse{Currency: "AED", Value: -150_00}, created.Amount)
+	assert.Len(suite.T(), created.Splits, 2)
+	assert.Equal(suite.T(), "Groceries", created.Splits[0].Category.Name)
+	assert.Equal(suite.T(), svc.AmountResponse{Currency: "AED", Value: -100_00}, created.Splits[0].Amount)
+	assert.Equal(suite.T(), "Household", created.Splits[1].Category.Name)
+	assert.Equal(suite.T(), svc.AmountResponse{Currency: "AED", Value: -50_00}, created.Splits[1].Amount)
+
+	r, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2023-01-01&to=2023-01-31&category=household", accountId), nil)
+	AddAuthorizationHeader(r, userId)
+
+	w = httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	var resp svc.RecordsResponse
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Len(suite.T(), resp.Records, 1)
+	assert.Equal(suite.T(), created.Id, resp.Records[0].Id)
+	assert.Len(suite.T(), resp.Records[0].Splits, 2)
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_splitsThatDoNotAddUpToTheAmount_WHEN_createRecordsEndpointIsCalled_THEN_400IsReturned() {
+	// GIVEN
+	userId := suite.simulatedUser.Id()
+	groceriesCategory, householdCategory := suite.createSplitCategories()
+
+	body := fmt.Sprintf(`{
+		"note": "Supermarket",
+		"category": {"id": %d},
+		"amount": {"currency": "AED", "value": 15000},
+		"date": "2023-01-01T10:00:00+00:00",
+		"type": "EXPENSE",
+		"splits": [
+			{"note": "Vegetables", "category": {"id": %d}, "amount": {"currency": "AED", "value": 10000}},
+			{"note": "Detergent", "category": {"id": %d}, "amount": {"currency": "AED", "value": 4000}}
+		]
+	}`, groceriesCategory.Id(), groceriesCategory.Id(), householdCategory.Id())
+
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records", suite.simulatedCurrentAccount.Id()), bytes.NewBufferString(body))
+	AddAuthorizationHeader(r, userId)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "the amounts of the lines must add up to the amount of the record")
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aUserInAnotherTimezone_WHEN_recordIsCreatedBeforeMidnightUTC_THEN_recordIsOnTheLocalDay() {
+	// GIVEN
+	dubaiUser, _ := ledger.NewUserWithProfile(3, "danny.torrence@theoverlook.com", ledger.MustUserProfile("Asia/Dubai", "en-AE", "Sunday"))
+	dubaiAccount, _ := ledger.NewAccount(ledger.AccountId(1630067787225), "Current", ledger.AccountTypeCurrent, "AED", ledger.MustMakeUpdatedByUserId(dubaiUser.Id()))
+	dubaiCategory, _ := ledger.NewCategory(ledger.CategoryId(1630067305043), "Salary", ledger.MustMakeUpdatedByUserId(dubaiUser.Id()))
+
+	if err := UserDao.Save(dubaiUser); err != nil {
+		log.Fatalf("RecordsHandlerTestSuite: Test setup failed: %s", err)
+	}
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), dubaiUser.Id(), ledger.Accounts{dubaiAccount}, tx)
+	_ = CategoryDao.SaveTx(context.Background(), dubaiUser.Id(), ledger.Categories{dubaiCategory}, tx)
+	_ = tx.Commit()
+
+	body := fmt.Sprintf(`{"note": "Salary", "category": {"id": %d}, "amount": {"currency": "AED", "value": 10000}, "date": "2021-07-31T20:30:00Z", "type": "INCOME"}`, dubaiCategory.Id())
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records", dubaiAccount.Id()), bytes.NewBufferString(body))
+	AddAuthorizationHeader(r, dubaiUser.Id())
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	var created svc.RecordResponse
+	_ = json.NewDecoder(w.Body).Decode(&created)
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Equal(suite.T(), "2021-08-01T00:00:00+0400", created.DateUTC)
+	assert.Equal(suite.T(), "2021-08-01T00:30:00+04:00", created.OccurredAt)
+
+	// WHEN
+	r, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?period=2021-08", dubaiAccount.Id()), nil)
+	AddAuthorizationHeader(r, dubaiUser.Id())
+
+	w = httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	var resp svc.RecordsResponse
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Len(suite.T(), resp.Records, 1)
+	assert.Equal(suite.T(), created.Id, resp.Records[0].Id)
+}
+
+func (suite *RecordsHandlerTestSuite) Test_GIVEN_aPeriodAndADate_WHEN_searchingRecords_THEN_400IsReturned() {
+	// WHEN
+	r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?period=2021-W28&from=2021-07-01", suite.simulatedCurrentAccount.Id()), nil)
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "period can not be combined with from or to")
+}
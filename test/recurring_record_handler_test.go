@@ -0,0 +1,253 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+type RecurringRecordsHandlerTestSuite struct {
+	suite.Suite
+	simulatedUser           ledger.User
+	simulatedCurrentAccount ledger.Account
+	simulatedSavingAccount  ledger.Account
+	simulatedRentCategory   ledger.Category
+}
+
+func TestRecurringRecordsHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(RecurringRecordsHandlerTestSuite))
+}
+
+// -- SETUP
+
+func (suite *RecurringRecordsHandlerTestSuite) SetupTest() {
+
+	aUser, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+
+	currentAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787222),
+		"Current",
+		ledger.AccountTypeCurrent,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	savingAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787223),
+		"Saving",
+		ledger.AccountTypeSaving,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	rentCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305041),
+		"Rent",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+
+	if err := UserDao.Save(aUser); err != nil {
+		log.Fatalf("RecurringRecordsHandlerTestSuite: Test setup failed: %s", err)
+	}
+
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), aUser.Id(), ledger.Accounts{currentAccount, savingAccount}, tx)
+	_ = CategoryDao.SaveTx(context.Background(), aUser.Id(), ledger.Categories{rentCategory}, tx)
+	_ = tx.Commit()
+
+	suite.simulatedUser = aUser
+	suite.simulatedCurrentAccount = currentAccount
+	suite.simulatedSavingAccount = savingAccount
+	suite.simulatedRentCategory = rentCategory
+}
+
+func (suite *RecurringRecordsHandlerTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down RecurringRecordsHandlerTestSuite: %s", err)
+	}
+}
+
+func (suite *RecurringRecordsHandlerTestSuite) createRecurringRecord(frequency ledger.Frequency, dayOfMonth int, startDate string) svc.RecurringRecordResponse {
+	var createRequest svc.CreateRecurringRecordRequest
+	createRequest.Note = "Rent"
+	createRequest.Category.Id = uint64(suite.simulatedRentCategory.Id())
+	createRequest.Amount.Currency = "AED"
+	createRequest.Amount.Value = 500000
+	createRequest.Type = string(ledger.Expense)
+	createRequest.Recurrence.Frequency = string(frequency)
+	createRequest.Recurrence.DayOfMonth = dayOfMonth
+	createRequest.StartDate = startDate
+
+	data, _ := json.Marshal(createRequest)
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/recurring-records", suite.simulatedCurrentAccount.Id()), bytes.NewBuffer(data))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	var createResponse svc.RecurringRecordResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &createResponse))
+	return createResponse
+}
+
+func (suite *RecurringRecordsHandlerTestSuite) postWithVersion(accountId ledger.AccountId, id uint64, action string, version uint64) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/recurring-records/%d/%s", accountId, id, action), nil)
+	r.Header.Set("If-Match", fmt.Sprintf("%q", fmt.Sprint(version)))
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+// -- SUITE
+
+func (suite *RecurringRecordsHandlerTestSuite) Test_GIVEN_aMonthlyRecurringRecord_WHEN_dueRecordsAreCreatedTwice_THEN_eachOccurrenceIsCreatedOnceByTheScheduledTask() {
+	// GIVEN
+	created := suite.createRecurringRecord(ledger.Monthly, 1, "2021-01-01")
+	assert.Equal(suite.T(), "2021-01-01", created.NextDate)
+
+	// WHEN
+	first, err := TestApp.RecurringRecordService.CreateDueRecords(context.Background(), time.Date(2021, time.March, 15, 10, 0, 0, 0, time.UTC))
+	assert.Nil(suite.T(), err)
+	second, err := TestApp.RecurringRecordService.CreateDueRecords(context.Background(), time.Date(2021, time.March, 15, 11, 0, 0, 0, time.UTC))
+	assert.Nil(suite.T(), err)
+
+	// THEN
+	assert.Equal(suite.T(), 3, first)
+	assert.Equal(suite.T(), 0, second)
+
+	var (
+		count     int
+		total     int64
+		createdBy string
+	)
+	assert.Nil(suite.T(), TestDB.QueryRow(
+		"SELECT COUNT(*), SUM(amount_minor_units), MIN(created_by) FROM budget.record WHERE account_id = $1",
+		suite.simulatedCurrentAccount.Id(),
+	).Scan(&count, &total, &createdBy))
+	assert.Equal(suite.T(), 3, count)
+	assert.Equal(suite.T(), int64(-1500000), total)
+	assert.Equal(suite.T(), "CronTask: RecurringRecords", createdBy)
+
+	r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/recurring-records/%d", suite.simulatedCurrentAccount.Id(), created.Id), nil)
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	var getResponse svc.RecurringRecordResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &getResponse))
+	assert.Equal(suite.T(), "2021-04-01", getResponse.NextDate)
+}
+
+func (suite *RecurringRecordsHandlerTestSuite) Test_GIVEN_aUserInAnotherTimezone_WHEN_dueRecordsAreCreated_THEN_recordIsDueOnceItsDateHasStartedInTheTimezoneOfTheUser() {
+	// GIVEN
+	_, err := TestDB.Exec("UPDATE budget.user SET timezone = 'Asia/Dubai' WHERE id = $1", suite.simulatedUser.Id())
+	assert.Nil(suite.T(), err)
+	suite.createRecurringRecord(ledger.Monthly, 15, "2021-03-15")
+
+	// WHEN
+	beforeMidnight, err := TestApp.RecurringRecordService.CreateDueRecords(context.Background(), time.Date(2021, time.March, 14, 19, 59, 0, 0, time.UTC))
+	assert.Nil(suite.T(), err)
+	afterMidnight, err := TestApp.RecurringRecordService.CreateDueRecords(context.Background(), time.Date(2021, time.March, 14, 20, 0, 0, 0, time.UTC))
+	assert.Nil(suite.T(), err)
+
+	// THEN
+	assert.Equal(suite.T(), 0, beforeMidnight)
+	assert.Equal(suite.T(), 1, afterMidnight)
+}
+func (suite *RecurringRecordsHandlerTestSuite) Test_GIVEN_aSkippedAndPausedRecurringRecord_WHEN_dueRecordsAreCreated_THEN_noRecordsAreCreated() {
+	// GIVEN
+	accountId := suite.simulatedCurrentAccount.Id()
+	created := suite.createRecurringRecord(ledger.Weekly, 0, "2021-07-05")
+
+	w := suite.postWithVersion(accountId, created.Id, "skip", created.Version)
+	var skipResponse svc.RecurringRecordResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &skipResponse))
+	assert.Equal(suite.T(), "2021-07-12", skipResponse.NextDate)
+
+	w = suite.postWithVersion(accountId, created.Id, "pause", skipResponse.Version)
+	var pauseResponse svc.RecurringRecordResponse
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &pauseResponse))
+	assert.True(suite.T(), pauseResponse.Paused)
+
+	// WHEN
+	count, err := TestApp.RecurringRecordService.CreateDueRecords(context.Background(), time.Date(2021, time.July, 20, 0, 0, 0, 0, time.UTC))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, count)
+}
+
+func (suite *RecurringRecordsHandlerTestSuite) Test_GIVEN_aStaleVersion_WHEN_recurringRecordIsPaused_THEN_409IsReturned() {
+	// GIVEN
+	created := suite.createRecurringRecord(ledger.Daily, 0, "2021-07-05")
+	_ = suite.postWithVersion(suite.simulatedCurrentAccount.Id(), created.Id, "pause", created.Version)
+
+	// WHEN
+	w := suite.postWithVersion(suite.simulatedCurrentAccount.Id(), created.Id, "resume", created.Version)
+
+	// THEN
+	assert.Equal(suite.T(), 409, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "RECURRING_RECORD_VERSION_CONFLICT")
+}
+
+func (suite *RecurringRecordsHandlerTestSuite) Test_GIVEN_aRecurringRecordOfAnotherAccount_WHEN_recurringRecordIsRequested_THEN_404IsReturned() {
+	// GIVEN
+	created := suite.createRecurringRecord(ledger.Daily, 0, "2021-07-05")
+
+	r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/recurring-records/%d", suite.simulatedSavingAccount.Id(), created.Id), nil)
+	AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 404, w.Code)
+}
+
+func (suite *RecurringRecordsHandlerTestSuite) Test_GIVEN_recordsCreatedByTheScheduledTask_WHEN_recordsAreSearchedByCreatorKind_THEN_onlyRecordsOfThatKindAreReturned() {
+	// GIVEN
+	accountId := suite.simulatedCurrentAccount.Id()
+	_ = suite.createRecurringRecord(ledger.Monthly, 1, "2021-01-01")
+	_, err := TestApp.RecurringRecordService.CreateDueRecords(context.Background(), time.Date(2021, time.February, 15, 0, 0, 0, 0, time.UTC))
+	assert.Nil(suite.T(), err)
+
+	search := func(createdBy string) svc.RecordsResponse {
+		r, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d/records?from=2021-01-01&to=2021-03-31&createdBy=%s", accountId, createdBy), nil)
+		AddAuthorizationHeader(r, suite.simulatedUser.Id())
+
+		w := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(w, r)
+
+		var searchResponse svc.RecordsResponse
+		assert.Equal(suite.T(), 200, w.Code)
+		assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &searchResponse))
+		return searchResponse
+	}
+
+	// WHEN
+	byTask := search("task")
+	byUser := search("USER")
+
+	// THEN
+	assert.Len(suite.T(), byTask.Records, 2)
+	for _, record := range byTask.Records {
+		assert.Equal(suite.T(), svc.UpdatedByResponse{Kind: "TASK", TaskName: "RecurringRecords"}, record.CreatedBy)
+	}
+	assert.Len(suite.T(), byUser.Records, 0)
+}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"log"
 	"testing"
 	"time"
@@ -90,3 +91,66 @@ func (suite *UserDaoTestSuite) Test_Given_twoUsers_WHEN_theUsersHaveTheSameEmail
 
 	assert.Equal(suite.T(), uint64(1005), errorCode(err2, 0))
 }
+
+func (suite *UserDaoTestSuite) saveConfirmedDeletion(userId ledger.UserId, deleteAfter time.Time) {
+	requestedAt := deleteAfter.Add(-time.Hour)
+	tx := suite.userDao.MustBeginTx()
+	assert.Nil(suite.T(), suite.userDao.SaveDeletionTx(context.Background(), dao.UserDeletion{
+		UserId:         userId,
+		TokenHash:      "hash",
+		TokenExpiresAt: requestedAt.Add(time.Hour),
+		RequestedAt:    requestedAt,
+		ConfirmedAt:    &requestedAt,
+		DeleteAfter:    &deleteAfter,
+	}, tx))
+	assert.Nil(suite.T(), tx.Commit())
+}
+
+func (suite *UserDaoTestSuite) Test_Given_aDueDeletion_WHEN_theDueUserIsDeleted_THEN_userIsDeleted() {
+	// GIVEN
+	aUser, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+	_ = suite.userDao.Save(aUser)
+	deleteAfter := time.Now().UTC().Truncate(time.Second)
+	suite.saveConfirmedDeletion(aUser.Id(), deleteAfter)
+
+	// WHEN
+	tx := suite.userDao.MustBeginTx()
+	deleted, err := suite.userDao.DeleteDueTx(context.Background(), aUser.Id(), deleteAfter, tx)
+	assert.Nil(suite.T(), tx.Commit())
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), deleted)
+
+	_, err = suite.userDao.GetUserById(aUser.Id())
+	assert.EqualValues(suite.T(), pkg.ErrUserNotFound, errorCode(err, 0))
+}
+
+func (suite *UserDaoTestSuite) Test_Given_aCancelledDeletion_WHEN_theDueUserIsDeleted_THEN_userIsNotDeleted() {
+	// GIVEN
+	aUser, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+	_ = suite.userDao.Save(aUser)
+	deleteAfter := time.Now().UTC().Truncate(time.Second)
+	suite.saveConfirmedDeletion(aUser.Id(), deleteAfter)
+
+	tx := suite.userDao.MustBeginTx()
+	userIds, _ := suite.userDao.GetUsersDueForDeletionTx(context.Background(), deleteAfter, tx)
+	assert.Nil(suite.T(), tx.Commit())
+	assert.Equal(suite.T(), []ledger.UserId{aUser.Id()}, userIds)
+
+	tx = suite.userDao.MustBeginTx()
+	assert.Nil(suite.T(), suite.userDao.DeleteDeletionTx(context.Background(), aUser.Id(), tx))
+	assert.Nil(suite.T(), tx.Commit())
+
+	// WHEN
+	tx = suite.userDao.MustBeginTx()
+	deleted, err := suite.userDao.DeleteDueTx(context.Background(), aUser.Id(), deleteAfter, tx)
+	assert.Nil(suite.T(), tx.Commit())
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), deleted)
+
+	_, err = suite.userDao.GetUserById(aUser.Id())
+	assert.Nil(suite.T(), err)
+}
@@ -0,0 +1,365 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg"
+	"github.com/w-k-s/simple-budget-tracker/pkg/export"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
+	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
+)
+
+type UserDataHandlerTestSuite struct {
+	suite.Suite
+	simulatedUser           ledger.User
+	simulatedCurrentAccount ledger.Account
+	simulatedSavingAccount  ledger.Account
+	simulatedSalaryCategory ledger.Category
+}
+
+func TestUserDataHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(UserDataHandlerTestSuite))
+}
+
+// -- SETUP
+
+func (suite *UserDataHandlerTestSuite) SetupTest() {
+
+	aUser, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+
+	currentAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787222),
+		"Current",
+		ledger.AccountTypeCurrent,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	savingAccount, _ := ledger.NewAccount(
+		ledger.AccountId(1630067787223),
+		"Saving",
+		ledger.AccountTypeSaving,
+		"AED",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	salaryCategory, _ := ledger.NewCategory(
+		ledger.CategoryId(1630067305041),
+		"Salary",
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+	budget, _ := ledger.NewBudget(
+		ledger.BudgetId(1630067305042),
+		ledger.AccountIds{currentAccount.Id()},
+		ledger.BudgetPeriodTypeMonth,
+		ledger.CategoryBudgets{ledger.MustCategoryBudget(ledger.NewCategoryBudget(salaryCategory.Id(), quickMoney("AED", 500_00)))},
+		ledger.MustMakeUpdatedByUserId(aUser.Id()),
+	)
+
+	if err := UserDao.Save(aUser); err != nil {
+		log.Fatalf("UserDataHandlerTestSuite: Test setup failed: %s", err)
+	}
+
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), aUser.Id(), ledger.Accounts{currentAccount, savingAccount}, tx)
+	_ = CategoryDao.SaveTx(context.Background(), aUser.Id(), ledger.Categories{salaryCategory}, tx)
+	_ = BudgetDao.Save(context.Background(), aUser.Id(), budget, tx)
+	_ = tx.Commit()
+
+	suite.simulatedUser = aUser
+	suite.simulatedCurrentAccount = currentAccount
+	suite.simulatedSavingAccount = savingAccount
+	suite.simulatedSalaryCategory = salaryCategory
+
+	for _, record := range []TestRecord{
+		{Note: "Salary", Category: salaryCategory, Amount: 1000_00, Date: "2021-07-01T10:00:00+00:00", Type: ledger.Income},
+		{Note: "Savings", Category: salaryCategory, Amount: -300_00, Date: "2021-07-02T10:00:00+00:00", Type: ledger.Transfer, Beneficiary: savingAccount.Id()},
+	} {
+		CreateRecord(suite.T(), aUser.Id(), currentAccount.Id(), record)
+	}
+}
+
+func (suite *UserDataHandlerTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down UserDataHandlerTestSuite: %s", err)
+	}
+}
+
+func (suite *UserDataHandlerTestSuite) importArchive(userId ledger.UserId, archive []byte, onConflict string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("POST", "/api/v1/user/import?onConflict="+onConflict, bytes.NewBuffer(archive))
+	AddAuthorizationHeader(r, userId)
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+func (suite *UserDataHandlerTestSuite) exportArchive() []byte {
+	w := SendAsUser(suite.simulatedUser.Id(), "GET", "/api/v1/user/export", "")
+	assert.Equal(suite.T(), 200, w.Code)
+	return w.Body.Bytes()
+}
+
+func (suite *UserDataHandlerTestSuite) accountsOf(userId ledger.UserId) ledger.Accounts {
+	tx, _ := AccountDao.BeginTx()
+	defer tx.Rollback()
+
+	accounts, err := AccountDao.GetAccountsByUserId(context.Background(), userId, tx)
+	assert.Nil(suite.T(), err)
+	return accounts
+}
+
+func (suite *UserDataHandlerTestSuite) categoriesOf(userId ledger.UserId) ledger.Categories {
+	tx, _ := CategoryDao.BeginTx()
+	defer tx.Rollback()
+
+	categories, err := CategoryDao.GetCategoriesForUser(context.Background(), userId, tx)
+	assert.Nil(suite.T(), err)
+	return categories
+}
+
+func (suite *UserDataHandlerTestSuite) countOfUser(userId ledger.UserId, query string) int {
+	var count int
+	assert.Nil(suite.T(), TestDB.QueryRow(query, userId).Scan(&count))
+	return count
+}
+
+func (suite *UserDataHandlerTestSuite) confirmDeletion() svc.UserDeletionResponse {
+	var tokenResponse svc.UserDeletionTokenResponse
+	w := SendAsUser(suite.simulatedUser.Id(), "DELETE", "/api/v1/user", "")
+	assert.Equal(suite.T(), 202, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &tokenResponse))
+
+	var deletionResponse svc.UserDeletionResponse
+	w = SendAsUser(suite.simulatedUser.Id(), "DELETE", "/api/v1/user?confirmationToken="+tokenResponse.ConfirmationToken, "")
+	assert.Equal(suite.T(), 202, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &deletionResponse))
+	return deletionResponse
+}
+
+// -- SUITE
+
+func (suite *UserDataHandlerTestSuite) Test_GIVEN_aUserWithData_WHEN_userDataIsExported_THEN_versionedArchiveIsReturned() {
+	// WHEN
+	w := SendAsUser(suite.simulatedUser.Id(), "GET", "/api/v1/user/export", "")
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Equal(suite.T(), "application/json;charset=utf-8", w.Header().Get("Content-Type"))
+
+	var archive export.Archive
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &archive))
+	assert.Equal(suite.T(), export.ArchiveVersion, archive.Version)
+	assert.Equal(suite.T(), "jack.torrence@theoverlook.com", archive.User.Email)
+
+	assert.Len(suite.T(), archive.Categories, 1)
+	assert.Equal(suite.T(), "Salary", archive.Categories[0].Name)
+
+	assert.Len(suite.T(), archive.Budgets, 1)
+	assert.Equal(suite.T(), []uint64{uint64(suite.simulatedCurrentAccount.Id())}, archive.Budgets[0].AccountIds)
+	assert.Equal(suite.T(), int64(500_00), archive.Budgets[0].CategoryBudgets[0].MaxLimit.Value)
+
+	assert.Len(suite.T(), archive.Accounts, 2)
+	assert.Equal(suite.T(), "Current", archive.Accounts[0].Name)
+	assert.Len(suite.T(), archive.Accounts[0].Records, 2)
+	assert.Equal(suite.T(), "Salary", archive.Accounts[0].Records[0].Note)
+	assert.Equal(suite.T(), uint64(suite.simulatedSavingAccount.Id()), archive.Accounts[0].Records[1].Transfer.Beneficiary.Id)
+	assert.Equal(suite.T(), "Saving", archive.Accounts[1].Name)
+	assert.Len(suite.T(), archive.Accounts[1].Records, 1)
+	assert.Equal(suite.T(), archive.Accounts[0].Records[1].Transfer.Reference, archive.Accounts[1].Records[0].Transfer.Reference)
+}
+
+func (suite *UserDataHandlerTestSuite) Test_GIVEN_aConfirmedDeletion_WHEN_gracePeriodIsOver_THEN_userAndAllTheirDataAreDeleted() {
+	// GIVEN
+	deletion := suite.confirmDeletion()
+	deleteAfter, err := time.Parse(time.RFC3339, deletion.DeleteAfter)
+	assert.Nil(suite.T(), err)
+	assert.WithinDuration(suite.T(), time.Now().Add(TestConfig.User().DeletionGracePeriod()), deleteAfter, time.Minute)
+
+	// WHEN
+	deleted, err := TestApp.UserService.DeleteDueUsers(context.Background(), deleteAfter.Add(-time.Second))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, deleted)
+
+	// WHEN
+	deleted, err = TestApp.UserService.DeleteDueUsers(context.Background(), deleteAfter)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, deleted)
+
+	_, err = UserDao.GetUserById(suite.simulatedUser.Id())
+	assert.NotNil(suite.T(), err)
+
+	var records int
+	assert.Nil(suite.T(), TestDB.QueryRow("SELECT COUNT(*) FROM budget.record").Scan(&records))
+	assert.Equal(suite.T(), 0, records)
+}
+
+func (suite *UserDataHandlerTestSuite) Test_GIVEN_aWrongConfirmationToken_WHEN_userIsDeleted_THEN_400IsReturned() {
+	// GIVEN
+	w := SendAsUser(suite.simulatedUser.Id(), "DELETE", "/api/v1/user", "")
+	assert.Equal(suite.T(), 202, w.Code)
+
+	// WHEN
+	w = SendAsUser(suite.simulatedUser.Id(), "DELETE", "/api/v1/user?confirmationToken=wrong", "")
+
+	// THEN
+	expected := fmt.Sprintf(`{
+		"type": "/api/v1/problems/%d",
+		"title": "USER_DELETION_TOKEN_INVALID",
+		"status": 400,
+		"detail": "Confirmation token is invalid or has expired",
+		"instance": "/api/v1/user",
+		"confirmationToken": "confirmationToken must be the latest token returned when the deletion was requested"
+	}`, pkg.ErrUserDeletionTokenInvalid)
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
+
+func (suite *UserDataHandlerTestSuite) Test_GIVEN_aConfirmedDeletion_WHEN_deletionIsCancelled_THEN_userIsNotDeleted() {
+	// GIVEN
+	deletion := suite.confirmDeletion()
+	deleteAfter, _ := time.Parse(time.RFC3339, deletion.DeleteAfter)
+
+	// WHEN
+	w := SendAsUser(suite.simulatedUser.Id(), "DELETE", "/api/v1/user/deletion", "")
+
+	// THEN
+	assert.Equal(suite.T(), 204, w.Code)
+
+	deleted, err := TestApp.UserService.DeleteDueUsers(context.Background(), deleteAfter)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, deleted)
+
+	_, err = UserDao.GetUserById(suite.simulatedUser.Id())
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *UserDataHandlerTestSuite) Test_GIVEN_noDeletionWasRequested_WHEN_deletionIsCancelled_THEN_404IsReturned() {
+	// WHEN
+	w := SendAsUser(suite.simulatedUser.Id(), "DELETE", "/api/v1/user/deletion", "")
+
+	// THEN
+	assert.Equal(suite.T(), 404, w.Code)
+}
+
+func (suite *UserDataHandlerTestSuite) Test_GIVEN_anArchive_WHEN_importedByAnotherUser_THEN_allTheDataIsRecreatedWithNewIds() {
+	// GIVEN
+	archive := suite.exportArchive()
+	anotherUser, _ := ledger.NewUserWithEmailString(2, "wendy.torrence@theoverlook.com")
+	assert.Nil(suite.T(), UserDao.Save(anotherUser))
+
+	// WHEN
+	w := suite.importArchive(anotherUser.Id(), archive, "")
+
+	// THEN
+	var resp svc.ImportUserDataResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(suite.T(), svc.ImportConflictRename, resp.OnConflict)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Created: 2}, resp.Accounts)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Created: 1}, resp.Categories)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Created: 1}, resp.Budgets)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Created: 3}, resp.Records)
+
+	accounts := suite.accountsOf(anotherUser.Id())
+	assert.Equal(suite.T(), []string{"Current", "Saving"}, accounts.Names())
+	assert.NotEqual(suite.T(), suite.simulatedCurrentAccount.Id(), accounts[0].Id())
+	assert.Equal(suite.T(), int64(700_00), accounts[0].CurrentBalance().MustMinorUnits())
+
+	assert.Equal(suite.T(), 1, suite.countOfUser(anotherUser.Id(), `SELECT COUNT(DISTINCT r.transfer_reference) FROM budget.record r JOIN budget.account a ON r.account_id = a.id WHERE a.user_id = $1`))
+	assert.Equal(suite.T(), 2, suite.countOfUser(anotherUser.Id(), `SELECT COUNT(*) FROM budget.record r JOIN budget.account a ON r.account_id = a.id WHERE a.user_id = $1 AND r.transfer_reference IS NOT NULL`))
+	assert.Equal(suite.T(), 1, suite.countOfUser(anotherUser.Id(), `SELECT COUNT(*) FROM budget.budget WHERE user_id = $1`))
+}
+
+func (suite *UserDataHandlerTestSuite) Test_GIVEN_anArchiveOfTheUser_WHEN_importedWithRename_THEN_conflictingAccountsAndCategoriesAreRenamed() {
+	// GIVEN
+	archive := suite.exportArchive()
+
+	// WHEN
+	w := suite.importArchive(suite.simulatedUser.Id(), archive, "rename")
+
+	// THEN
+	var resp svc.ImportUserDataResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Renamed: 2}, resp.Accounts)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Renamed: 1}, resp.Categories)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Created: 1}, resp.Budgets)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Created: 3}, resp.Records)
+
+	accounts := suite.accountsOf(suite.simulatedUser.Id())
+	assert.ElementsMatch(suite.T(), []string{"Current", "Current (2)", "Saving", "Saving (2)"}, accounts.Names())
+
+	categories := suite.categoriesOf(suite.simulatedUser.Id())
+	assert.ElementsMatch(suite.T(), []string{"Salary", "Salary (2)"}, categories.Names())
+
+	assert.Equal(suite.T(), 2, suite.countOfUser(suite.simulatedUser.Id(), `SELECT COUNT(DISTINCT r.transfer_reference) FROM budget.record r JOIN budget.account a ON r.account_id = a.id WHERE a.user_id = $1`))
+}
+
+func (suite *UserDataHandlerTestSuite) Test_GIVEN_anArchiveOfTheUser_WHEN_importedWithMerge_THEN_recordsAreImportedIntoExistingAccounts() {
+	// GIVEN
+	archive := suite.exportArchive()
+
+	// WHEN
+	w := suite.importArchive(suite.simulatedUser.Id(), archive, "merge")
+
+	// THEN
+	var resp svc.ImportUserDataResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Merged: 2}, resp.Accounts)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Merged: 1}, resp.Categories)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Skipped: 1}, resp.Budgets)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Created: 3}, resp.Records)
+
+	accounts := suite.accountsOf(suite.simulatedUser.Id())
+	assert.Len(suite.T(), accounts, 2)
+	assert.Equal(suite.T(), 6, suite.countOfUser(suite.simulatedUser.Id(), `SELECT COUNT(*) FROM budget.record r JOIN budget.account a ON r.account_id = a.id WHERE a.user_id = $1`))
+}
+
+func (suite *UserDataHandlerTestSuite) Test_GIVEN_anArchiveOfTheUser_WHEN_importedWithSkip_THEN_nothingThatConflictsIsImported() {
+	// GIVEN
+	archive := suite.exportArchive()
+
+	// WHEN
+	w := suite.importArchive(suite.simulatedUser.Id(), archive, "skip")
+
+	// THEN
+	var resp svc.ImportUserDataResponse
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Skipped: 2}, resp.Accounts)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Skipped: 1}, resp.Categories)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Skipped: 1}, resp.Budgets)
+	assert.Equal(suite.T(), svc.ImportedEntitiesResponse{Skipped: 3}, resp.Records)
+	assert.Equal(suite.T(), 3, suite.countOfUser(suite.simulatedUser.Id(), `SELECT COUNT(*) FROM budget.record r JOIN budget.account a ON r.account_id = a.id WHERE a.user_id = $1`))
+}
+
+func (suite *UserDataHandlerTestSuite) Test_GIVEN_anUnknownConflictStrategy_WHEN_archiveIsImported_THEN_400IsReturned() {
+	// WHEN
+	w := suite.importArchive(suite.simulatedUser.Id(), suite.exportArchive(), "overwrite")
+
+	// THEN
+	expected := fmt.Sprintf(`{
+		"type": "/api/v1/problems/%d",
+		"title": "USER_IMPORT_VALIDATION_FAILED",
+		"status": 400,
+		"detail": "Unknown onConflict strategy \"overwrite\"",
+		"instance": "/api/v1/user/import",
+		"onConflict": "onConflict must be skip, rename or merge"
+	}`, pkg.ErrUserImportValidation)
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
@@ -2,13 +2,18 @@ package test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/simple-budget-tracker/pkg/ledger"
 	svc "github.com/w-k-s/simple-budget-tracker/pkg/services"
 	"schneider.vip/problem"
 )
@@ -21,12 +26,20 @@ func TestUserHandlerTestSuite(t *testing.T) {
 	suite.Run(t, new(UserHandlerTestSuite))
 }
 
+// -- TEARDOWN
+
+func (suite *UserHandlerTestSuite) TearDownTest() {
+	if err := ClearTables(); err != nil {
+		log.Fatalf("Failed to tear down UserHandlerTestSuite: %s", err)
+	}
+}
+
 // -- SUITE
 
 func (suite *UserHandlerTestSuite) Test_GIVEN_aRecordRequest_WHEN_createUserEndpointIsCalled_THEN_userIsCreatedAnd201IsReturned() {
 	// GIVEN
 	var request bytes.Buffer
-	request.WriteString("{\"email\":\"test@burger.com\"}")
+	request.WriteString("{\"email\":\"test@burger.com\",\"password\":\"all work and no play\"}")
 	r, _ := http.NewRequest("POST", "/api/v1/user", &request)
 
 	// WHEN
@@ -80,3 +93,218 @@ func (suite *UserHandlerTestSuite) Test_GIVEN_invalidEmail_WHEN_createUserEndpoi
 	assert.Nil(suite.T(), p.UnmarshalJSON(w.Body.Bytes()))
 	assert.Equal(suite.T(), "{\"detail\":\"mail: missing '@' or angle-addr\",\"instance\":\"/api/v1/user\",\"status\":400,\"title\":\"USER_EMAIL_INVALID\",\"type\":\"/api/v1/problems/1004\"}", p.Error())
 }
+
+func (suite *UserHandlerTestSuite) Test_GIVEN_aTimezoneLocaleAndWeekStart_WHEN_createUserEndpointIsCalled_THEN_userIsCreatedWithTheProfile() {
+	// GIVEN
+	var request bytes.Buffer
+	request.WriteString(`{"email":"profile@burger.com","password":"all work and no play","timezone":"Asia/Dubai","locale":"ar-AE","weekStart":"Sunday"}`)
+	r, _ := http.NewRequest("POST", "/api/v1/user", &request)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	var response svc.CreateUserResponse
+
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Equal(suite.T(), "Asia/Dubai", response.Timezone)
+	assert.Equal(suite.T(), "ar-AE", response.Locale)
+	assert.Equal(suite.T(), "Sunday", response.WeekStart)
+
+	user, err := UserDao.GetUserById(response.Id)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Asia/Dubai", user.Profile().Timezone())
+	assert.Equal(suite.T(), "ar-AE", user.Profile().Locale())
+	assert.Equal(suite.T(), time.Sunday, user.Profile().WeekStart())
+}
+
+func (suite *UserHandlerTestSuite) Test_GIVEN_anUnknownTimezone_WHEN_createUserEndpointIsCalled_THEN_400IsReturned() {
+	// GIVEN
+	var request bytes.Buffer
+	request.WriteString(`{"email":"profile@burger.com","timezone":"Mars/Olympus_Mons"}`)
+	r, _ := http.NewRequest("POST", "/api/v1/user", &request)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "USER_PROFILE_VALIDATION_FAILED")
+	assert.Contains(suite.T(), w.Body.String(), "No such timezone 'Mars/Olympus_Mons'")
+}
+
+func (suite *UserHandlerTestSuite) Test_GIVEN_aShortPassword_WHEN_createUserEndpointIsCalled_THEN_400IsReturned() {
+	// GIVEN
+	var request bytes.Buffer
+	request.WriteString(`{"email":"test@burger.com","password":"redrum"}`)
+	r, _ := http.NewRequest("POST", "/api/v1/user", &request)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "USER_PASSWORD_VALIDATION_FAILED")
+	assert.Contains(suite.T(), w.Body.String(), "password must be between 8 and 128 characters long")
+
+	tx := UserDao.MustBeginTx()
+	defer func() { _ = tx.Rollback() }()
+	_, found, _ := UserDao.GetCredentialByEmailTx(context.Background(), "test@burger.com", tx)
+	assert.False(suite.T(), found)
+}
+
+func (suite *UserHandlerTestSuite) givenAUserWithAnAccountAndACategory() (ledger.User, ledger.Account, ledger.Category) {
+	user, _ := ledger.NewUserWithEmailString(1, "jack.torrence@theoverlook.com")
+	account, _ := ledger.NewAccount(ledger.AccountId(1630067787222), "Current", ledger.AccountTypeCurrent, "AED", ledger.MustMakeUpdatedByUserId(user.Id()))
+	category, _ := ledger.NewCategory(ledger.CategoryId(1630067305041), "Groceries", ledger.MustMakeUpdatedByUserId(user.Id()))
+
+	if err := UserDao.Save(user); err != nil {
+		log.Fatalf("UserHandlerTestSuite: Test setup failed: %s", err)
+	}
+	tx, _ := AccountDao.BeginTx()
+	_ = AccountDao.SaveTx(context.Background(), user.Id(), ledger.Accounts{account}, tx)
+	_ = CategoryDao.SaveTx(context.Background(), user.Id(), ledger.Categories{category}, tx)
+	_ = tx.Commit()
+
+	return user, account, category
+}
+
+func (suite *UserHandlerTestSuite) patchUser(userId ledger.UserId, body string, ifMatch string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("PATCH", "/api/v1/user", bytes.NewBufferString(body))
+	r.Header.Set("If-Match", ifMatch)
+	AddAuthorizationHeader(r, userId)
+
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+	return w
+}
+
+func (suite *UserHandlerTestSuite) Test_GIVEN_aUser_WHEN_getUserEndpointIsCalled_THEN_profileIsReturnedWithDefaults() {
+	// GIVEN
+	user, _, _ := suite.givenAUserWithAnAccountAndACategory()
+	r, _ := http.NewRequest("GET", "/api/v1/user", nil)
+	AddAuthorizationHeader(r, user.Id())
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	expected := `{
+		"id": 1,
+		"email": "jack.torrence@theoverlook.com",
+		"timezone": "UTC",
+		"locale": "en-US",
+		"weekStart": "Monday",
+		"version": 1
+	}`
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Equal(suite.T(), `"1"`, w.Header().Get("ETag"))
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
+
+func (suite *UserHandlerTestSuite) Test_GIVEN_aUser_WHEN_userIsPatchedWithCurrentVersion_THEN_preferencesAreChangedAndVersionIsIncremented() {
+	// GIVEN
+	user, account, category := suite.givenAUserWithAnAccountAndACategory()
+	body := fmt.Sprintf(`{
+		"displayName": "Jack",
+		"currency": "AED",
+		"timezone": "Asia/Dubai",
+		"weekStart": "Sunday",
+		"defaultAccountId": %d,
+		"defaultCategoryId": %d
+	}`, account.Id(), category.Id())
+
+	// WHEN
+	w := suite.patchUser(user.Id(), body, `"1"`)
+
+	// THEN
+	expected := fmt.Sprintf(`{
+		"id": 1,
+		"email": "jack.torrence@theoverlook.com",
+		"displayName": "Jack",
+		"currency": "AED",
+		"timezone": "Asia/Dubai",
+		"locale": "en-US",
+		"weekStart": "Sunday",
+		"defaultAccountId": %d,
+		"defaultCategoryId": %d,
+		"version": 2
+	}`, account.Id(), category.Id())
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Equal(suite.T(), `"2"`, w.Header().Get("ETag"))
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+
+	saved, err := UserDao.GetUserById(user.Id())
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Jack", saved.Preferences().DisplayName())
+	assert.Equal(suite.T(), "Asia/Dubai", saved.Profile().Timezone())
+}
+
+func (suite *UserHandlerTestSuite) Test_GIVEN_aUserThatWasChanged_WHEN_userIsPatchedWithStaleVersion_THEN_409IsReturned() {
+	// GIVEN
+	user, _, _ := suite.givenAUserWithAnAccountAndACategory()
+	assert.Equal(suite.T(), 200, suite.patchUser(user.Id(), `{"displayName": "Jack"}`, `"1"`).Code)
+
+	// WHEN
+	w := suite.patchUser(user.Id(), `{"displayName": "Johnny"}`, `"1"`)
+
+	// THEN
+	assert.Equal(suite.T(), 409, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "USER_VERSION_CONFLICT")
+}
+
+func (suite *UserHandlerTestSuite) Test_GIVEN_anInvalidIfMatchHeader_WHEN_userIsPatched_THEN_userProfileValidationErrorIsReturned() {
+	// GIVEN
+	user, _, _ := suite.givenAUserWithAnAccountAndACategory()
+
+	// WHEN
+	w := suite.patchUser(user.Id(), `{"displayName": "Jack"}`, `"one"`)
+
+	// THEN
+	expected := `{
+		"detail": "Invalid If-Match header",
+		"instance": "/api/v1/user",
+		"status": 400,
+		"title": "USER_PROFILE_VALIDATION_FAILED",
+		"type": "/api/v1/problems/1045",
+		"version": "If-Match '\"one\"' must be the version of the user"
+	}`
+	assert.Equal(suite.T(), 400, w.Code)
+	assert.JSONEq(suite.T(), expected, w.Body.String())
+}
+
+func (suite *UserHandlerTestSuite) Test_GIVEN_anAccountOfAnotherUser_WHEN_itIsMadeTheDefaultAccount_THEN_404IsReturned() {
+	// GIVEN
+	user, _, _ := suite.givenAUserWithAnAccountAndACategory()
+
+	// WHEN
+	w := suite.patchUser(user.Id(), `{"defaultAccountId": 1630067787299}`, `"1"`)
+
+	// THEN
+	assert.Equal(suite.T(), 404, w.Code)
+}
+
+func (suite *UserHandlerTestSuite) Test_GIVEN_aDefaultCategory_WHEN_recordIsCreatedWithoutCategory_THEN_recordIsInTheDefaultCategory() {
+	// GIVEN
+	user, account, category := suite.givenAUserWithAnAccountAndACategory()
+	assert.Equal(suite.T(), 200, suite.patchUser(user.Id(), fmt.Sprintf(`{"defaultCategoryId": %d}`, category.Id()), `"1"`).Code)
+
+	body := `{"note": "Supermarket", "amount": {"currency": "AED", "value": -5000}, "date": "2021-07-05T10:00:00Z", "type": "EXPENSE"}`
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/accounts/%d/records", account.Id()), bytes.NewBufferString(body))
+	AddAuthorizationHeader(r, user.Id())
+
+	// WHEN
+	w := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	var created svc.RecordResponse
+	_ = json.NewDecoder(w.Body).Decode(&created)
+	assert.Equal(suite.T(), 201, w.Code)
+	assert.Equal(suite.T(), uint64(category.Id()), created.Category.Id)
+}